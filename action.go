@@ -0,0 +1,147 @@
+package rbac
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ErrUnknownAction is returned when creating a permission whose action is
+// not registered in Manager.Actions.
+var ErrUnknownAction = errors.New("rbac: unknown action")
+
+// ActionDef is an action applications may grant, such as approve or
+// publish.
+type ActionDef struct {
+	Name        Action `json:"name"`
+	Description string `json:"description,omitempty"`
+}
+
+// ActionGroup names a set of actions. A permission whose action is the
+// group's name grants every action in it, e.g. a "manage" group of create,
+// update and delete.
+type ActionGroup struct {
+	Name    Action   `json:"name"`
+	Actions []Action `json:"actions"`
+}
+
+// ActionRegistry holds the actions permissions may be created with. It
+// starts with the CRUD actions. Set one on Manager.Actions to have
+// CreatePermission reject unregistered actions and Can expand groups. It is
+// safe for concurrent use.
+type ActionRegistry struct {
+	mu      sync.RWMutex
+	actions map[Action]ActionDef
+	groups  map[Action][]Action
+}
+
+// NewActionRegistry returns a registry holding the CRUD actions and defs.
+func NewActionRegistry(defs ...ActionDef) (*ActionRegistry, error) {
+	r := &ActionRegistry{actions: map[Action]ActionDef{}, groups: map[Action][]Action{}}
+	for _, a := range []Action{ActionCreate, ActionRead, ActionUpdate, ActionDelete} {
+		r.actions[a] = ActionDef{Name: a}
+	}
+	for _, d := range defs {
+		if err := r.Register(d); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// Register adds d, replacing any action with the same name.
+func (r *ActionRegistry) Register(d ActionDef) error {
+	if err := checkActionName(d.Name); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.groups[d.Name]; ok {
+		return fmt.Errorf("rbac: action %q is already a group", d.Name)
+	}
+	r.actions[d.Name] = d
+	return nil
+}
+
+// RegisterGroup adds a group of registered actions, replacing any group with
+// the same name.
+func (r *ActionRegistry) RegisterGroup(g ActionGroup) error {
+	if err := checkActionName(g.Name); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.actions[g.Name]; ok {
+		return fmt.Errorf("rbac: action group %q is already an action", g.Name)
+	}
+	for _, a := range g.Actions {
+		if _, ok := r.actions[a]; !ok {
+			return fmt.Errorf("%w: %q in group %q", ErrUnknownAction, a, g.Name)
+		}
+	}
+	r.groups[g.Name] = slices.Clone(g.Actions)
+	return nil
+}
+
+// checkActionName rejects names that would read as action patterns.
+func checkActionName(a Action) error {
+	if a == "" || strings.ContainsAny(string(a), `*?[\`) {
+		return fmt.Errorf("rbac: invalid action name %q", a)
+	}
+	return nil
+}
+
+// Actions returns the registered actions, by name.
+func (r *ActionRegistry) Actions() []ActionDef {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	out := make([]ActionDef, 0, len(r.actions))
+	for _, d := range r.actions {
+		out = append(out, d)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// Groups returns the registered groups, by name.
+func (r *ActionRegistry) Groups() []ActionGroup {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	out := make([]ActionGroup, 0, len(r.groups))
+	for name, actions := range r.groups {
+		out = append(out, ActionGroup{Name: name, Actions: slices.Clone(actions)})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// Validate checks that a permission may be created with action: a
+// registered action or group, or a pattern such as "*" or "invoice.*" that
+// matches at least one registered action.
+func (r *ActionRegistry) Validate(action Action) error {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if _, ok := r.actions[action]; ok {
+		return nil
+	}
+	if _, ok := r.groups[action]; ok {
+		return nil
+	}
+	for a := range r.actions {
+		if ok, _ := path.Match(string(action), string(a)); ok {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %q", ErrUnknownAction, action)
+}
+
+// inGroup reports whether action belongs to the group named group.
+func (r *ActionRegistry) inGroup(group, action Action) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return slices.Contains(r.groups[group], action)
+}
@@ -0,0 +1,86 @@
+package rbac
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestActionRegistry(t *testing.T) {
+	reg, err := NewActionRegistry(
+		ActionDef{Name: "approve", Description: "Approve a request"},
+		ActionDef{Name: "invoice.export"},
+	)
+	if err != nil {
+		t.Fatalf("NewActionRegistry: %v", err)
+	}
+	if err := reg.RegisterGroup(ActionGroup{Name: "manage", Actions: []Action{ActionCreate, ActionUpdate, ActionDelete}}); err != nil {
+		t.Fatalf("RegisterGroup: %v", err)
+	}
+	for _, c := range []struct {
+		action Action
+		want   error
+	}{
+		{ActionRead, nil},
+		{"approve", nil},
+		{"manage", nil},
+		{ActionAll, nil},
+		{"invoice.*", nil},
+		{"aprove", ErrUnknownAction},
+		{"report.*", ErrUnknownAction},
+	} {
+		if err := reg.Validate(c.action); !errors.Is(err, c.want) {
+			t.Errorf("Validate(%q) = %v, want %v", c.action, err, c.want)
+		}
+	}
+
+	if err := reg.RegisterGroup(ActionGroup{Name: "review", Actions: []Action{"publish"}}); !errors.Is(err, ErrUnknownAction) {
+		t.Errorf("expected a group of an unknown action to be rejected, got %v", err)
+	}
+	if err := reg.Register(ActionDef{Name: "manage"}); err == nil {
+		t.Error("expected an action named like a group to be rejected")
+	}
+	if err := reg.Register(ActionDef{Name: "pub*"}); err == nil {
+		t.Error("expected a pattern as an action name to be rejected")
+	}
+	if got := reg.Actions(); len(got) != 6 || got[0].Name != "approve" {
+		t.Errorf("unexpected actions %+v", got)
+	}
+}
+
+func TestActionGroupsInCan(t *testing.T) {
+	ctx := context.Background()
+	mgr, err := NewMemoryStoreManager(ctx, "", 0)
+	if err != nil {
+		t.Fatalf("NewMemoryStoreManager: %v", err)
+	}
+	if mgr.Actions, err = NewActionRegistry(ActionDef{Name: "publish"}); err != nil {
+		t.Fatalf("NewActionRegistry: %v", err)
+	}
+	if err := mgr.Actions.RegisterGroup(ActionGroup{Name: "editorial", Actions: []Action{ActionUpdate, "publish"}}); err != nil {
+		t.Fatalf("RegisterGroup: %v", err)
+	}
+
+	if err := mgr.CreatePermission(ctx, &Permission{Resource: "posts/*", Action: "pubish"}); !errors.Is(err, ErrUnknownAction) {
+		t.Errorf("expected ErrUnknownAction for a typo, got %v", err)
+	}
+	role := &Role{Name: "editor"}
+	if err := mgr.CreateRole(ctx, role); err != nil {
+		t.Fatalf("CreateRole: %v", err)
+	}
+	perm := &Permission{Resource: "posts/*", Action: "editorial"}
+	if err := mgr.CreatePermission(ctx, perm); err != nil {
+		t.Fatalf("CreatePermission: %v", err)
+	}
+	if err := mgr.AssignPermissionToRole(ctx, role.ID, perm.ID); err != nil {
+		t.Fatalf("AssignPermissionToRole: %v", err)
+	}
+	if err := mgr.AssignRoleToUser(ctx, "eve", role.ID); err != nil {
+		t.Fatalf("AssignRoleToUser: %v", err)
+	}
+	for action, want := range map[Action]bool{"publish": true, ActionUpdate: true, ActionDelete: false} {
+		if ok, err := mgr.Can(ctx, "eve", "posts/1", action); err != nil || ok != want {
+			t.Errorf("Can(%s) = %v, %v; want %v", action, ok, err, want)
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package rbac
+
+import (
+	"context"
+	"time"
+)
+
+type actorKey struct{}
+
+// WithActor returns a context whose writes are attributed to actorID, the
+// user or service making them. The Manager records it as the CreatedBy and
+// UpdatedBy of the permissions, roles, users and memberships it creates
+// through the context, and stores record it as UpdatedBy when they change
+// one in place, e.g. for SuspendUser.
+func WithActor(ctx context.Context, actorID string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actorID)
+}
+
+// Actor returns the actor set by WithActor, or "" when there is none.
+func Actor(ctx context.Context) string {
+	a, _ := ctx.Value(actorKey{}).(string)
+	return a
+}
+
+// stampCreated fills the audit fields of an entity created at now by ctx's
+// actor. A CreatedBy the caller set is kept, e.g. when restoring an entity.
+func stampCreated(ctx context.Context, now time.Time, createdBy, updatedBy *string, updatedAt *int64) {
+	if *createdBy == "" {
+		*createdBy = Actor(ctx)
+	}
+	stampUpdated(ctx, now, updatedBy, updatedAt)
+}
+
+// stampUpdated fills the audit fields of an entity changed at now by ctx's
+// actor.
+func stampUpdated(ctx context.Context, now time.Time, updatedBy *string, updatedAt *int64) {
+	*updatedBy = Actor(ctx)
+	*updatedAt = now.Unix()
+}
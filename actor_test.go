@@ -0,0 +1,72 @@
+package rbac
+
+import (
+	"context"
+	"testing"
+)
+
+func TestActorAuditFields(t *testing.T) {
+	memory, err := NewMemoryStoreManager(context.Background(), "", 0)
+	if err != nil {
+		t.Fatalf("NewMemoryStoreManager: %v", err)
+	}
+	for name, mgr := range map[string]*Manager{"memory": memory, "mock": NewMockRepoManager(NewMockRepo())} {
+		t.Run(name, func(t *testing.T) {
+			ctx := WithActor(context.Background(), "admin")
+
+			role := &Role{Name: "reader"}
+			if err := mgr.CreateRole(ctx, role); err != nil {
+				t.Fatalf("CreateRole: %v", err)
+			}
+			perm := &Permission{Resource: "docs/*", Action: ActionRead}
+			if err := mgr.CreatePermission(ctx, perm); err != nil {
+				t.Fatalf("CreatePermission: %v", err)
+			}
+			alice := &User{Username: "alice", Email: "alice@example.com"}
+			if err := mgr.CreateUser(ctx, alice); err != nil {
+				t.Fatalf("CreateUser: %v", err)
+			}
+			if err := mgr.AddUserToGroup(ctx, &UserGroup{UserID: alice.ID, GroupName: "eng"}); err != nil {
+				t.Fatalf("AddUserToGroup: %v", err)
+			}
+
+			gotRole, _ := mgr.Roles.GetRoleByID(ctx, role.ID)
+			gotPerm, _ := mgr.Perms.GetPermissionByID(ctx, perm.ID)
+			gotUser, _ := mgr.GetUser(ctx, alice.ID)
+			groups, _ := mgr.UG.GetGroupsByUserID(ctx, alice.ID)
+			if len(groups) != 1 {
+				t.Fatalf("expected one membership, got %d", len(groups))
+			}
+			for what, fields := range map[string][3]interface{}{
+				"role":       {gotRole.CreatedBy, gotRole.UpdatedBy, gotRole.UpdatedAt},
+				"permission": {gotPerm.CreatedBy, gotPerm.UpdatedBy, gotPerm.UpdatedAt},
+				"user":       {gotUser.CreatedBy, gotUser.UpdatedBy, gotUser.UpdatedAt},
+				"membership": {groups[0].CreatedBy, groups[0].UpdatedBy, groups[0].UpdatedAt},
+			} {
+				if fields[0] != "admin" || fields[1] != "admin" || fields[2] == int64(0) {
+					t.Errorf("%s audit fields = %v; want created and updated by admin", what, fields)
+				}
+			}
+
+			if err := mgr.SuspendUser(WithActor(context.Background(), "ops"), alice.ID); err != nil {
+				t.Fatalf("SuspendUser: %v", err)
+			}
+			gotUser, _ = mgr.GetUser(ctx, alice.ID)
+			if gotUser.CreatedBy != "admin" || gotUser.UpdatedBy != "ops" {
+				t.Errorf("after SuspendUser CreatedBy, UpdatedBy = %q, %q; want admin, ops", gotUser.CreatedBy, gotUser.UpdatedBy)
+			}
+		})
+	}
+}
+
+func TestNoActor(t *testing.T) {
+	ctx := context.Background()
+	mgr := NewMockRepoManager(NewMockRepo())
+	role := &Role{Name: "reader"}
+	if err := mgr.CreateRole(ctx, role); err != nil {
+		t.Fatalf("CreateRole: %v", err)
+	}
+	if role.CreatedBy != "" || role.UpdatedBy != "" || role.UpdatedAt == 0 {
+		t.Errorf("got CreatedBy %q, UpdatedBy %q, UpdatedAt %d; want no actor and a timestamp", role.CreatedBy, role.UpdatedBy, role.UpdatedAt)
+	}
+}
@@ -0,0 +1,205 @@
+package rbac
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// KindAPIKey is passed to IDGenerator.NewID for API keys.
+const KindAPIKey = "api_key"
+
+// APIKey is a credential a service presents instead of a user ID. It acts
+// as PrincipalID, optionally narrowed to Scopes. Only a hash of its secret
+// is stored; the key itself is returned once, by MintAPIKey.
+type APIKey struct {
+	ID          string `bson:"id" json:"id"`
+	PrincipalID string `bson:"principal_id" json:"principal_id"`
+	Name        string `bson:"name,omitempty" json:"name,omitempty"`
+	// SecretHash is the hex SHA-256 of the key's secret.
+	SecretHash string `bson:"secret_hash" json:"secret_hash"`
+	// Scopes, when set, limit the key to resources matching one of the
+	// patterns, in the syntax of Permission.Resource.
+	Scopes    []string `bson:"scopes,omitempty" json:"scopes,omitempty"`
+	CreatedAt int64    `bson:"created_at" json:"created_at"`
+	// ExpiresAt, when set, is the unix time the key stops working.
+	ExpiresAt int64 `bson:"expires_at,omitempty" json:"expires_at,omitempty"`
+	RevokedAt int64 `bson:"revoked_at,omitempty" json:"revoked_at,omitempty"`
+}
+
+// APIKeyRepo stores API keys.
+type APIKeyRepo interface {
+	// SaveAPIKey creates the key or replaces the one with its ID.
+	SaveAPIKey(ctx context.Context, k *APIKey) error
+	// GetAPIKey returns the key, or nil, nil.
+	GetAPIKey(ctx context.Context, id string) (*APIKey, error)
+	// ListAPIKeys returns the principal's keys, oldest first.
+	ListAPIKeys(ctx context.Context, principalID string) ([]*APIKey, error)
+}
+
+var (
+	// ErrInvalidAPIKey is returned for a key that is malformed, unknown,
+	// revoked or expired.
+	ErrInvalidAPIKey = errors.New("rbac: invalid API key")
+	// ErrAPIKeyNotFound is returned when revoking an unknown key.
+	ErrAPIKeyNotFound = errors.New("rbac: API key not found")
+
+	errNoAPIKeyRepo = errors.New("rbac: no APIKeyRepo configured")
+)
+
+// apiKeySep separates a key's ID from its secret; the base64url secret
+// never contains it.
+const apiKeySep = "."
+
+// MintAPIKey stores k, which needs a PrincipalID, with a new random secret
+// and returns the key to hand to the service: its ID and secret joined by a
+// dot. The key cannot be recovered later.
+func (m *Manager) MintAPIKey(ctx context.Context, k *APIKey) (string, error) {
+	start := time.Now()
+	ctx, span := m.startSpan(ctx, "MintAPIKey")
+	defer span.End()
+	key, err := m.mintAPIKey(ctx, start, k)
+	m.record(ctx, start, "MintAPIKey", err)
+	return key, err
+}
+
+func (m *Manager) mintAPIKey(ctx context.Context, now time.Time, k *APIKey) (string, error) {
+	if m.APIKeys == nil {
+		return "", errNoAPIKeyRepo
+	}
+	if k.PrincipalID == "" {
+		return "", errors.New("rbac: API key needs a principal")
+	}
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	secret := base64.RawURLEncoding.EncodeToString(raw)
+
+	m.assignID(&k.ID, KindAPIKey)
+	k.SecretHash = hashAPIKeySecret(secret)
+	k.CreatedAt = now.Unix()
+	k.RevokedAt = 0
+	if err := m.APIKeys.SaveAPIKey(ctx, k); err != nil {
+		return "", err
+	}
+	return k.ID + apiKeySep + secret, nil
+}
+
+// RevokeAPIKey stops the key from verifying. The record is kept.
+func (m *Manager) RevokeAPIKey(ctx context.Context, id string) error {
+	start := time.Now()
+	ctx, span := m.startSpan(ctx, "RevokeAPIKey")
+	defer span.End()
+	err := errNoAPIKeyRepo
+	if m.APIKeys != nil {
+		var k *APIKey
+		if k, err = m.APIKeys.GetAPIKey(ctx, id); err == nil && k == nil {
+			err = fmt.Errorf("%w: %q", ErrAPIKeyNotFound, id)
+		}
+		if err == nil && k.RevokedAt == 0 {
+			k.RevokedAt = start.Unix()
+			err = m.APIKeys.SaveAPIKey(ctx, k)
+		}
+	}
+	m.record(ctx, start, "RevokeAPIKey", err)
+	return err
+}
+
+// ListAPIKeys returns the principal's keys, revoked and expired ones
+// included, oldest first.
+func (m *Manager) ListAPIKeys(ctx context.Context, principalID string) ([]*APIKey, error) {
+	start := time.Now()
+	ctx, span := m.startSpan(ctx, "ListAPIKeys")
+	defer span.End()
+	var (
+		list []*APIKey
+		err  = errNoAPIKeyRepo
+	)
+	if m.APIKeys != nil {
+		list, err = m.APIKeys.ListAPIKeys(ctx, principalID)
+	}
+	m.record(ctx, start, "ListAPIKeys", err)
+	return list, err
+}
+
+// VerifyAPIKey returns the stored key for a key minted by MintAPIKey, or
+// ErrInvalidAPIKey if it is malformed, unknown, revoked or expired.
+func (m *Manager) VerifyAPIKey(ctx context.Context, key string) (*APIKey, error) {
+	start := time.Now()
+	ctx, span := m.startSpan(ctx, "VerifyAPIKey")
+	defer span.End()
+	k, err := m.verifyAPIKey(ctx, start, key)
+	m.record(ctx, start, "VerifyAPIKey", err)
+	return k, err
+}
+
+func (m *Manager) verifyAPIKey(ctx context.Context, now time.Time, key string) (*APIKey, error) {
+	if m.APIKeys == nil {
+		return nil, errNoAPIKeyRepo
+	}
+	i := strings.LastIndex(key, apiKeySep)
+	if i <= 0 {
+		return nil, fmt.Errorf("%w: malformed", ErrInvalidAPIKey)
+	}
+	k, err := m.APIKeys.GetAPIKey(ctx, key[:i])
+	if err != nil {
+		return nil, err
+	}
+	hash := hashAPIKeySecret(key[i+1:])
+	switch {
+	case k == nil || subtle.ConstantTimeCompare([]byte(hash), []byte(k.SecretHash)) != 1:
+		return nil, fmt.Errorf("%w: unknown key", ErrInvalidAPIKey)
+	case k.RevokedAt != 0:
+		return nil, fmt.Errorf("%w: revoked", ErrInvalidAPIKey)
+	case k.ExpiresAt != 0 && now.Unix() >= k.ExpiresAt:
+		return nil, fmt.Errorf("%w: expired", ErrInvalidAPIKey)
+	}
+	return k, nil
+}
+
+// CanByAPIKey verifies key and reports whether its principal can perform
+// action on resource. A resource outside the key's scopes is denied
+// without consulting the principal's roles.
+func (m *Manager) CanByAPIKey(ctx context.Context, key, resource string, action Action) (bool, error) {
+	start := time.Now()
+	ctx, span := m.startSpan(ctx, "CanByAPIKey")
+	defer span.End()
+	k, err := m.verifyAPIKey(ctx, start, key)
+	if err != nil {
+		m.record(ctx, start, "CanByAPIKey", err)
+		return false, err
+	}
+	inScope := len(k.Scopes) == 0
+	for _, scope := range k.Scopes {
+		if inScope, err = matchResource(scope, resource); err != nil || inScope {
+			break
+		}
+	}
+	if err != nil || !inScope {
+		m.record(ctx, start, "CanByAPIKey", err)
+		return false, err
+	}
+	return m.can(ctx, "CanByAPIKey", k.PrincipalID, resource, action, nil)
+}
+
+// sortAPIKeys orders keys oldest first.
+func sortAPIKeys(list []*APIKey) {
+	sort.Slice(list, func(i, j int) bool {
+		a, b := list[i], list[j]
+		return a.CreatedAt < b.CreatedAt || (a.CreatedAt == b.CreatedAt && a.ID < b.ID)
+	})
+}
+
+func hashAPIKeySecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
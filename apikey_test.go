@@ -0,0 +1,87 @@
+package rbac
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAPIKeys(t *testing.T) {
+	ctx := context.Background()
+	mgr, err := NewMemoryStoreManager(ctx, "", 0)
+	if err != nil {
+		t.Fatalf("NewMemoryStoreManager: %v", err)
+	}
+	role := &Role{Name: "billing"}
+	if err := mgr.CreateRole(ctx, role); err != nil {
+		t.Fatalf("CreateRole: %v", err)
+	}
+	for _, res := range []string{"invoices/*", "reports/*"} {
+		p := &Permission{Resource: res, Action: ActionRead}
+		if err := mgr.CreatePermission(ctx, p); err != nil {
+			t.Fatalf("CreatePermission: %v", err)
+		}
+		if err := mgr.AssignPermissionToRole(ctx, role.ID, p.ID); err != nil {
+			t.Fatalf("AssignPermissionToRole: %v", err)
+		}
+	}
+	if err := mgr.AssignRoleToUser(ctx, "svc-billing", role.ID); err != nil {
+		t.Fatalf("AssignRoleToUser: %v", err)
+	}
+
+	k := &APIKey{PrincipalID: "svc-billing", Name: "invoicing", Scopes: []string{"invoices/**"}}
+	key, err := mgr.MintAPIKey(ctx, k)
+	if err != nil {
+		t.Fatalf("MintAPIKey: %v", err)
+	}
+	if !strings.HasPrefix(key, k.ID+".") || strings.Contains(k.SecretHash, key[len(k.ID)+1:]) {
+		t.Fatalf("unexpected key %q for %+v", key, k)
+	}
+
+	if got, err := mgr.VerifyAPIKey(ctx, key); err != nil || got.PrincipalID != "svc-billing" {
+		t.Errorf("VerifyAPIKey = %+v, %v", got, err)
+	}
+	if ok, err := mgr.CanByAPIKey(ctx, key, "invoices/1", ActionRead); err != nil || !ok {
+		t.Errorf("CanByAPIKey(invoices/1) = %v, %v; want true", ok, err)
+	}
+	// the principal can read reports, but the key is scoped to invoices
+	if ok, err := mgr.CanByAPIKey(ctx, key, "reports/1", ActionRead); err != nil || ok {
+		t.Errorf("CanByAPIKey(reports/1) = %v, %v; want false", ok, err)
+	}
+	for _, bad := range []string{"", "no-separator", k.ID + ".wrong", "missing." + key[len(k.ID)+1:]} {
+		if _, err := mgr.CanByAPIKey(ctx, bad, "invoices/1", ActionRead); !errors.Is(err, ErrInvalidAPIKey) {
+			t.Errorf("CanByAPIKey(%q): expected ErrInvalidAPIKey, got %v", bad, err)
+		}
+	}
+
+	expired := &APIKey{PrincipalID: "svc-billing", ExpiresAt: time.Now().Add(-time.Minute).Unix()}
+	expiredKey, err := mgr.MintAPIKey(ctx, expired)
+	if err != nil {
+		t.Fatalf("MintAPIKey: %v", err)
+	}
+	if _, err := mgr.VerifyAPIKey(ctx, expiredKey); !errors.Is(err, ErrInvalidAPIKey) {
+		t.Errorf("expected an expired key to be rejected, got %v", err)
+	}
+
+	if err := mgr.RevokeAPIKey(ctx, k.ID); err != nil {
+		t.Fatalf("RevokeAPIKey: %v", err)
+	}
+	if _, err := mgr.VerifyAPIKey(ctx, key); !errors.Is(err, ErrInvalidAPIKey) {
+		t.Errorf("expected a revoked key to be rejected, got %v", err)
+	}
+	if err := mgr.RevokeAPIKey(ctx, "missing"); !errors.Is(err, ErrAPIKeyNotFound) {
+		t.Errorf("expected ErrAPIKeyNotFound, got %v", err)
+	}
+
+	keys, err := mgr.ListAPIKeys(ctx, "svc-billing")
+	if err != nil || len(keys) != 2 {
+		t.Fatalf("ListAPIKeys = %+v, %v", keys, err)
+	}
+	for _, got := range keys {
+		if revoked := got.RevokedAt != 0; revoked != (got.ID == k.ID) {
+			t.Errorf("key %s: unexpected RevokedAt %d", got.ID, got.RevokedAt)
+		}
+	}
+}
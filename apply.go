@@ -0,0 +1,62 @@
+package rbac
+
+import (
+	"context"
+	"time"
+)
+
+// KindRoleParent is the kind of the ChangeEvents Apply reports for role
+// inheritance: RoleID is the inheriting role and ID its parent.
+const KindRoleParent = "role_parent"
+
+// ApplyResult reports what Apply did to converge the store on the desired
+// policy.
+type ApplyResult struct {
+	// Changes are the changes made, in order. Join record events carry the
+	// IDs of both ends, as a Watcher's do.
+	Changes []ChangeEvent `json:"changes"`
+	// Drift lists, as ChangeUpdate events, the roles and permissions whose
+	// stored fields differ from the desired ones. The repos cannot update
+	// either in place, so Apply leaves them as stored; delete the entity and
+	// apply again to recreate it.
+	Drift []ChangeEvent `json:"drift,omitempty"`
+}
+
+// Apply reconciles the store with desired, a declarative policy document:
+// the permissions, roles, groups and bindings it has are created, those it
+// lacks removed, and groups whose fields differ updated, so that applying
+// the same document again changes nothing. It is ImportPolicy in
+// ImportReplace mode, matching entities the same way, that also reports
+// what it changed; see ImportPolicy for what removal entails, and for the
+// snapshot it takes first. It runs in a transaction when the store supports
+// them. On error the result holds the
+// changes made before the failure, which such a store has rolled back.
+func (m *Manager) Apply(ctx context.Context, desired *PolicyBundle) (*ApplyResult, error) {
+	start := time.Now()
+	ctx, span := m.startSpan(ctx, "Apply")
+	defer span.End()
+	res, err := m.apply(ctx, desired, "before Apply")
+	m.record(ctx, start, "Apply", err)
+	return res, err
+}
+
+// apply is Apply, naming the snapshot it takes first with comment.
+func (m *Manager) apply(ctx context.Context, desired *PolicyBundle, comment string) (*ApplyResult, error) {
+	res := &ApplyResult{}
+	err := desired.validate()
+	if err == nil {
+		err = m.inTransaction(ctx, func(ctx context.Context) error {
+			// the store may retry the transaction
+			res.Changes = res.Changes[:0]
+			if err := m.snapshotBefore(ctx, comment); err != nil {
+				return err
+			}
+			var err error
+			res.Drift, err = m.importPolicy(ctx, desired, true, func(e ChangeEvent) {
+				res.Changes = append(res.Changes, e)
+			})
+			return err
+		})
+	}
+	return res, err
+}
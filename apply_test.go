@@ -0,0 +1,99 @@
+package rbac
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+)
+
+const desiredPolicy = `version: 1
+permissions:
+  - id: docs-read
+    resource: docs/*
+    action: read
+  - id: docs-write
+    resource: docs/*
+    action: update
+roles:
+  - id: viewer
+    name: viewer
+    permissions: [docs-read]
+  - id: editor
+    name: editor
+    permissions: [docs-write]
+    parents: [viewer]
+groups:
+  - name: writers
+    description: Docs writers
+    roles: [editor]
+`
+
+func TestApply(t *testing.T) {
+	ctx := context.Background()
+	memory, err := NewMemoryStoreManager(ctx, "", 0)
+	if err != nil {
+		t.Fatalf("NewMemoryStoreManager: %v", err)
+	}
+	for name, mgr := range map[string]*Manager{"memory": memory, "mock": NewMockRepoManager(NewMockRepo())} {
+		t.Run(name, func(t *testing.T) {
+			desired, err := ReadPolicyBundle(strings.NewReader(desiredPolicy), FileFormatYAML)
+			if err != nil {
+				t.Fatalf("ReadPolicyBundle: %v", err)
+			}
+			if err := mgr.CreateRole(ctx, &Role{ID: "legacy", Name: "legacy"}); err != nil {
+				t.Fatalf("CreateRole: %v", err)
+			}
+
+			res, err := mgr.Apply(ctx, desired)
+			if err != nil {
+				t.Fatalf("Apply: %v", err)
+			}
+			for _, want := range []ChangeEvent{
+				{Kind: KindRole, Op: ChangeDelete, ID: "legacy"},
+				{Kind: KindPermission, Op: ChangeCreate, ID: "docs-read"},
+				{Kind: KindRole, Op: ChangeCreate, ID: "editor"},
+				{Kind: KindRolePermission, Op: ChangeCreate, RoleID: "viewer", PermissionID: "docs-read"},
+				{Kind: KindRoleParent, Op: ChangeCreate, RoleID: "editor", ID: "viewer"},
+				{Kind: KindGroupRole, Op: ChangeCreate, GroupName: "writers", RoleID: "editor"},
+			} {
+				if !slices.Contains(res.Changes, want) {
+					t.Errorf("Changes = %+v; missing %+v", res.Changes, want)
+				}
+			}
+			if err := mgr.AssignRoleToUser(ctx, "alice", "editor"); err != nil {
+				t.Fatalf("AssignRoleToUser: %v", err)
+			}
+			if ok, err := mgr.Can(ctx, "alice", "docs/1", ActionRead); err != nil || !ok {
+				t.Errorf("Can(alice, read) = %v, %v; want true", ok, err)
+			}
+
+			// applying the same document again changes nothing
+			if res, err := mgr.Apply(ctx, desired); err != nil || len(res.Changes) != 0 || len(res.Drift) != 0 {
+				t.Fatalf("second Apply = %+v, %v; want no changes", res, err)
+			}
+
+			// edits converge, and role fields that cannot be updated are reported
+			desired.Roles[1].Permissions = nil
+			desired.Roles[1].Description = "Edits docs"
+			desired.Groups[0].Description = "Writers"
+			res, err = mgr.Apply(ctx, desired)
+			if err != nil {
+				t.Fatalf("Apply: %v", err)
+			}
+			want := []ChangeEvent{
+				{Kind: KindRolePermission, Op: ChangeDelete, RoleID: "editor", PermissionID: "docs-write"},
+				{Kind: KindGroup, Op: ChangeUpdate, ID: res.Changes[len(res.Changes)-1].ID, GroupName: "writers"},
+			}
+			if !slices.Equal(res.Changes, want) {
+				t.Errorf("Changes = %+v; want %+v", res.Changes, want)
+			}
+			if drift := []ChangeEvent{{Kind: KindRole, Op: ChangeUpdate, ID: "editor"}}; !slices.Equal(res.Drift, drift) {
+				t.Errorf("Drift = %+v; want %+v", res.Drift, drift)
+			}
+			if ok, err := mgr.Can(ctx, "alice", "docs/1", ActionUpdate); err != nil || ok {
+				t.Errorf("Can(alice, update) = %v, %v; want false", ok, err)
+			}
+		})
+	}
+}
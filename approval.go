@@ -0,0 +1,230 @@
+package rbac
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// KindAssignmentRequest is passed to IDGenerator.NewID for role assignment
+// requests.
+const KindAssignmentRequest = "assignment_request"
+
+// ApprovalResource prefixes the resource an approver needs ActionUpdate on:
+// approving a request for role R checks ApprovalResource + "/" + R, so a
+// permission on "rbac/role-assignments/*" approves every role.
+const ApprovalResource = "rbac/role-assignments"
+
+// AssignmentStatus is the state of an AssignmentRequest.
+type AssignmentStatus string
+
+const (
+	AssignmentPending  AssignmentStatus = "pending"
+	AssignmentApproved AssignmentStatus = "approved"
+	AssignmentRejected AssignmentStatus = "rejected"
+)
+
+// AssignmentRequest asks for RoleID to be assigned to UserID. The role is
+// only assigned once a second user approves it; see RequestRoleAssignment.
+type AssignmentRequest struct {
+	ID          string           `bson:"id" json:"id"`
+	UserID      string           `bson:"user_id" json:"user_id"`
+	RoleID      string           `bson:"role_id" json:"role_id"`
+	RequestedBy string           `bson:"requested_by" json:"requested_by"`
+	Reason      string           `bson:"reason,omitempty" json:"reason,omitempty"`
+	Status      AssignmentStatus `bson:"status" json:"status"`
+	DecidedBy   string           `bson:"decided_by,omitempty" json:"decided_by,omitempty"`
+	Comment     string           `bson:"comment,omitempty" json:"comment,omitempty"`
+	CreatedAt   int64            `bson:"created_at" json:"created_at"`
+	DecidedAt   int64            `bson:"decided_at,omitempty" json:"decided_at,omitempty"`
+}
+
+// ApprovalRepo stores role assignment requests.
+type ApprovalRepo interface {
+	// SaveAssignmentRequest creates or replaces the request.
+	SaveAssignmentRequest(ctx context.Context, r *AssignmentRequest) error
+	// GetAssignmentRequest returns the request, or nil, nil.
+	GetAssignmentRequest(ctx context.Context, id string) (*AssignmentRequest, error)
+	// ListAssignmentRequests returns the requests with the given status,
+	// every request for "", oldest first.
+	ListAssignmentRequests(ctx context.Context, status AssignmentStatus) ([]*AssignmentRequest, error)
+}
+
+var (
+	// ErrAssignmentRequestNotFound is returned for an unknown request ID.
+	ErrAssignmentRequestNotFound = errors.New("rbac: assignment request not found")
+	// ErrAssignmentRequestDecided is returned when approving or rejecting
+	// a request that is no longer pending.
+	ErrAssignmentRequestDecided = errors.New("rbac: assignment request already decided")
+	// ErrApprovalDenied is returned when the approver is the requester or
+	// the user the role is for, or may not approve the role.
+	ErrApprovalDenied = errors.New("rbac: approval denied")
+)
+
+var errNoApprovalRepo = errors.New("rbac: no ApprovalRepo configured")
+
+// RequestRoleAssignment records requestedBy's request to assign roleID to
+// userID. Nothing is assigned until ApproveRoleAssignment.
+func (m *Manager) RequestRoleAssignment(ctx context.Context, requestedBy, userID, roleID, reason string) (*AssignmentRequest, error) {
+	start := time.Now()
+	ctx, span := m.startSpan(ctx, "RequestRoleAssignment")
+	defer span.End()
+	req, err := m.requestRoleAssignment(ctx, start, requestedBy, userID, roleID, reason)
+	m.record(ctx, start, "RequestRoleAssignment", err)
+	return req, err
+}
+
+func (m *Manager) requestRoleAssignment(ctx context.Context, now time.Time, requestedBy, userID, roleID, reason string) (*AssignmentRequest, error) {
+	if m.Approvals == nil {
+		return nil, errNoApprovalRepo
+	}
+	if requestedBy == "" || userID == "" {
+		return nil, errors.New("rbac: assignment request needs a requester and a user")
+	}
+	r, err := m.Roles.GetRoleByID(ctx, roleID)
+	if err != nil {
+		return nil, err
+	}
+	if r == nil {
+		return nil, fmt.Errorf("%w: %q", ErrRoleNotFound, roleID)
+	}
+	if err := m.checkAssignable(ctx, roleID); err != nil {
+		return nil, err
+	}
+	req := &AssignmentRequest{
+		UserID:      userID,
+		RoleID:      roleID,
+		RequestedBy: requestedBy,
+		Reason:      reason,
+		Status:      AssignmentPending,
+		CreatedAt:   now.Unix(),
+	}
+	m.assignID(&req.ID, KindAssignmentRequest)
+	if err := m.Approvals.SaveAssignmentRequest(ctx, req); err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+// ApproveRoleAssignment approves a pending request and assigns the role
+// through AssignRoleToUser, so separation of duties and limits still apply;
+// if the assignment fails the request stays pending. approverID must differ
+// from the requester and the user, and be allowed ActionUpdate on the
+// role's ApprovalResource.
+func (m *Manager) ApproveRoleAssignment(ctx context.Context, requestID, approverID, comment string) (*AssignmentRequest, error) {
+	start := time.Now()
+	ctx, span := m.startSpan(ctx, "ApproveRoleAssignment")
+	defer span.End()
+	req, err := m.decideRoleAssignment(ctx, start, requestID, approverID, comment, AssignmentApproved)
+	m.record(ctx, start, "ApproveRoleAssignment", err)
+	return req, err
+}
+
+// RejectRoleAssignment rejects a pending request. Anyone who may approve it
+// may reject it, and the requester may withdraw it.
+func (m *Manager) RejectRoleAssignment(ctx context.Context, requestID, approverID, comment string) (*AssignmentRequest, error) {
+	start := time.Now()
+	ctx, span := m.startSpan(ctx, "RejectRoleAssignment")
+	defer span.End()
+	req, err := m.decideRoleAssignment(ctx, start, requestID, approverID, comment, AssignmentRejected)
+	m.record(ctx, start, "RejectRoleAssignment", err)
+	return req, err
+}
+
+func (m *Manager) decideRoleAssignment(ctx context.Context, now time.Time, requestID, approverID, comment string, status AssignmentStatus) (*AssignmentRequest, error) {
+	if m.Approvals == nil {
+		return nil, errNoApprovalRepo
+	}
+	req, err := m.Approvals.GetAssignmentRequest(ctx, requestID)
+	if err != nil {
+		return nil, err
+	}
+	if req == nil {
+		return nil, fmt.Errorf("%w: %q", ErrAssignmentRequestNotFound, requestID)
+	}
+	if req.Status != AssignmentPending {
+		return nil, fmt.Errorf("%w: %q is %s", ErrAssignmentRequestDecided, requestID, req.Status)
+	}
+	withdrawn := status == AssignmentRejected && approverID == req.RequestedBy
+	if !withdrawn {
+		if err := m.checkApprover(ctx, req, approverID); err != nil {
+			return nil, err
+		}
+	}
+	if status == AssignmentApproved {
+		if err := m.AssignRoleToUser(ctx, req.UserID, req.RoleID); err != nil {
+			return nil, err
+		}
+	}
+	req.Status = status
+	req.DecidedBy = approverID
+	req.Comment = comment
+	req.DecidedAt = now.Unix()
+	if err := m.Approvals.SaveAssignmentRequest(ctx, req); err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+// checkApprover enforces the two-person rule on req.
+func (m *Manager) checkApprover(ctx context.Context, req *AssignmentRequest, approverID string) error {
+	if approverID == "" || approverID == req.RequestedBy || approverID == req.UserID {
+		return fmt.Errorf("%w: %q cannot decide a request they made or benefit from", ErrApprovalDenied, approverID)
+	}
+	ok, err := m.Can(ctx, approverID, ApprovalResource+"/"+req.RoleID, ActionUpdate)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("%w: %q may not approve role %q", ErrApprovalDenied, approverID, req.RoleID)
+	}
+	return nil
+}
+
+// GetAssignmentRequest returns a request by ID.
+func (m *Manager) GetAssignmentRequest(ctx context.Context, id string) (*AssignmentRequest, error) {
+	start := time.Now()
+	ctx, span := m.startSpan(ctx, "GetAssignmentRequest")
+	defer span.End()
+	var (
+		req *AssignmentRequest
+		err = errNoApprovalRepo
+	)
+	if m.Approvals != nil {
+		req, err = m.Approvals.GetAssignmentRequest(ctx, id)
+		if err == nil && req == nil {
+			err = fmt.Errorf("%w: %q", ErrAssignmentRequestNotFound, id)
+		}
+	}
+	m.record(ctx, start, "GetAssignmentRequest", err)
+	return req, err
+}
+
+// ListAssignmentRequests returns the requests with the given status, every
+// request for "", oldest first.
+func (m *Manager) ListAssignmentRequests(ctx context.Context, status AssignmentStatus) ([]*AssignmentRequest, error) {
+	start := time.Now()
+	ctx, span := m.startSpan(ctx, "ListAssignmentRequests")
+	defer span.End()
+	var (
+		out []*AssignmentRequest
+		err = errNoApprovalRepo
+	)
+	if m.Approvals != nil {
+		out, err = m.Approvals.ListAssignmentRequests(ctx, status)
+	}
+	m.record(ctx, start, "ListAssignmentRequests", err)
+	return out, err
+}
+
+// sortAssignmentRequests orders requests oldest first, by ID within a second.
+func sortAssignmentRequests(out []*AssignmentRequest) {
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].CreatedAt != out[j].CreatedAt {
+			return out[i].CreatedAt < out[j].CreatedAt
+		}
+		return out[i].ID < out[j].ID
+	})
+}
@@ -0,0 +1,128 @@
+package rbac
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestRoleAssignmentApproval(t *testing.T) {
+	ctx := context.Background()
+	memory, err := NewMemoryStoreManager(ctx, "", 0)
+	if err != nil {
+		t.Fatalf("NewMemoryStoreManager: %v", err)
+	}
+	for name, mgr := range map[string]*Manager{"memory": memory, "mock": NewMockRepoManager(NewMockRepo())} {
+		t.Run(name, func(t *testing.T) {
+			admin := &Role{Name: "payments-admin"}
+			approvers := &Role{Name: "approvers"}
+			for _, r := range []*Role{admin, approvers} {
+				if err := mgr.CreateRole(ctx, r); err != nil {
+					t.Fatalf("CreateRole: %v", err)
+				}
+			}
+			perm := &Permission{Resource: ApprovalResource + "/*", Action: ActionUpdate}
+			if err := mgr.CreatePermission(ctx, perm); err != nil {
+				t.Fatalf("CreatePermission: %v", err)
+			}
+			if err := mgr.AssignPermissionToRole(ctx, approvers.ID, perm.ID); err != nil {
+				t.Fatalf("AssignPermissionToRole: %v", err)
+			}
+			for _, u := range []string{"carol", "dave"} {
+				if err := mgr.AssignRoleToUser(ctx, u, approvers.ID); err != nil {
+					t.Fatalf("AssignRoleToUser: %v", err)
+				}
+			}
+
+			if _, err := mgr.RequestRoleAssignment(ctx, "carol", "bob", "missing", ""); !errors.Is(err, ErrRoleNotFound) {
+				t.Errorf("requesting an unknown role: expected ErrRoleNotFound, got %v", err)
+			}
+			req, err := mgr.RequestRoleAssignment(ctx, "carol", "bob", admin.ID, "on-call rotation")
+			if err != nil {
+				t.Fatalf("RequestRoleAssignment: %v", err)
+			}
+			if req.ID == "" || req.Status != AssignmentPending {
+				t.Fatalf("unexpected request: %+v", req)
+			}
+			if roles, _ := mgr.UR.ListRoles(ctx, "bob"); slices.Contains(roles, admin.ID) {
+				t.Fatalf("role assigned before approval: %v", roles)
+			}
+
+			for _, approver := range []string{"carol", "bob", "erin"} {
+				if _, err := mgr.ApproveRoleAssignment(ctx, req.ID, approver, ""); !errors.Is(err, ErrApprovalDenied) {
+					t.Errorf("approval by %s: expected ErrApprovalDenied, got %v", approver, err)
+				}
+			}
+			approved, err := mgr.ApproveRoleAssignment(ctx, req.ID, "dave", "ok")
+			if err != nil {
+				t.Fatalf("ApproveRoleAssignment: %v", err)
+			}
+			if approved.Status != AssignmentApproved || approved.DecidedBy != "dave" || approved.DecidedAt == 0 {
+				t.Errorf("unexpected approved request: %+v", approved)
+			}
+			if roles, _ := mgr.UR.ListRoles(ctx, "bob"); !slices.Contains(roles, admin.ID) {
+				t.Errorf("expected bob to hold %s, got %v", admin.ID, roles)
+			}
+			if _, err := mgr.RejectRoleAssignment(ctx, req.ID, "dave", ""); !errors.Is(err, ErrAssignmentRequestDecided) {
+				t.Errorf("rejecting a decided request: expected ErrAssignmentRequestDecided, got %v", err)
+			}
+
+			withdrawn, err := mgr.RequestRoleAssignment(ctx, "carol", "frank", admin.ID, "")
+			if err != nil {
+				t.Fatalf("RequestRoleAssignment: %v", err)
+			}
+			if _, err := mgr.RejectRoleAssignment(ctx, withdrawn.ID, "carol", "not needed"); err != nil {
+				t.Errorf("withdrawing a request: %v", err)
+			}
+
+			pending, err := mgr.ListAssignmentRequests(ctx, AssignmentPending)
+			if err != nil || len(pending) != 0 {
+				t.Errorf("expected no pending requests, got %v, %v", pending, err)
+			}
+			all, err := mgr.ListAssignmentRequests(ctx, "")
+			if err != nil || len(all) != 2 {
+				t.Errorf("expected two requests, got %v, %v", all, err)
+			}
+			if _, err := mgr.GetAssignmentRequest(ctx, "missing"); !errors.Is(err, ErrAssignmentRequestNotFound) {
+				t.Errorf("expected ErrAssignmentRequestNotFound, got %v", err)
+			}
+		})
+	}
+}
+
+func TestRoleAssignmentApprovalKeepsChecks(t *testing.T) {
+	ctx := context.Background()
+	mgr := NewMockRepoManager(NewMockRepo())
+	mgr.Limits = Limits{MaxRolesPerUser: 1}
+	a, b, approvers := &Role{Name: "a"}, &Role{Name: "b"}, &Role{Name: "approvers"}
+	for _, r := range []*Role{a, b, approvers} {
+		if err := mgr.CreateRole(ctx, r); err != nil {
+			t.Fatalf("CreateRole: %v", err)
+		}
+	}
+	perm := &Permission{Resource: ApprovalResource + "/" + b.ID, Action: ActionUpdate}
+	if err := mgr.CreatePermission(ctx, perm); err != nil {
+		t.Fatalf("CreatePermission: %v", err)
+	}
+	if err := mgr.AssignPermissionToRole(ctx, approvers.ID, perm.ID); err != nil {
+		t.Fatalf("AssignPermissionToRole: %v", err)
+	}
+	if err := mgr.AssignRoleToUser(ctx, "carol", approvers.ID); err != nil {
+		t.Fatalf("AssignRoleToUser: %v", err)
+	}
+	if err := mgr.AssignRoleToUser(ctx, "bob", a.ID); err != nil {
+		t.Fatalf("AssignRoleToUser: %v", err)
+	}
+
+	req, err := mgr.RequestRoleAssignment(ctx, "dave", "bob", b.ID, "")
+	if err != nil {
+		t.Fatalf("RequestRoleAssignment: %v", err)
+	}
+	if _, err := mgr.ApproveRoleAssignment(ctx, req.ID, "carol", ""); !errors.Is(err, ErrLimitExceeded) {
+		t.Fatalf("expected the role limit to apply on approval, got %v", err)
+	}
+	if got, _ := mgr.GetAssignmentRequest(ctx, req.ID); got == nil || got.Status != AssignmentPending {
+		t.Errorf("expected the request to stay pending, got %+v", got)
+	}
+}
@@ -0,0 +1,489 @@
+package rbac
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// KindArchive is passed to IDGenerator.NewID for archives.
+const KindArchive = "archive"
+
+// Archive is the tombstone of an archived role or group: who it was, when it
+// was archived and, in Bundle, everything needed to restore it. Archives are
+// kept after a restore, with RestoredAt set.
+type Archive struct {
+	ID string `bson:"id" json:"id"`
+	// Kind is KindRole or KindGroup.
+	Kind       string        `bson:"kind" json:"kind"`
+	EntityID   string        `bson:"entity_id" json:"entity_id"`
+	Name       string        `bson:"name" json:"name"`
+	ArchivedAt int64         `bson:"archived_at" json:"archived_at"`
+	RestoredAt int64         `bson:"restored_at,omitempty" json:"restored_at,omitempty"`
+	Bundle     ArchiveBundle `bson:"bundle" json:"bundle"`
+}
+
+// ArchiveBundle is the full definition and assignments of an archived role
+// or group.
+type ArchiveBundle struct {
+	// Role, for a role archive, with its permission bindings, the roles it
+	// inherits from (Parents) and the roles inheriting from it (Children).
+	Role        *Role          `bson:"role,omitempty" json:"role,omitempty"`
+	Permissions []ArchivedEdge `bson:"permissions,omitempty" json:"permissions,omitempty"`
+	Parents     []string       `bson:"parents,omitempty" json:"parents,omitempty"`
+	Children    []string       `bson:"children,omitempty" json:"children,omitempty"`
+	// Users hold the role directly; Schedules are the windows of those
+	// assignments that have one.
+	Users     []ArchivedEdge    `bson:"users,omitempty" json:"users,omitempty"`
+	Schedules []*RoleAssignment `bson:"schedules,omitempty" json:"schedules,omitempty"`
+	// Groups are the group → role bindings: the groups holding an archived
+	// role, or the roles of an archived group.
+	Groups []ArchivedEdge `bson:"groups,omitempty" json:"groups,omitempty"`
+
+	// Group, for a group archive, with its memberships and scoped roles.
+	Group       *Group         `bson:"group,omitempty" json:"group,omitempty"`
+	Members     []*UserGroup   `bson:"members,omitempty" json:"members,omitempty"`
+	MemberEdges []ArchivedEdge `bson:"member_edges,omitempty" json:"member_edges,omitempty"`
+	ScopedRoles []ScopedRole   `bson:"scoped_roles,omitempty" json:"scoped_roles,omitempty"`
+}
+
+// ArchivedEdge is an assignment in an ArchiveBundle with the source that
+// managed it, in the order the repos take its ends.
+type ArchivedEdge struct {
+	From   string `bson:"from" json:"from"`
+	To     string `bson:"to" json:"to"`
+	Source string `bson:"source,omitempty" json:"source,omitempty"`
+}
+
+// ArchiveRepo stores archives.
+type ArchiveRepo interface {
+	// SaveArchive creates the archive or replaces the one with its ID.
+	SaveArchive(ctx context.Context, a *Archive) error
+	// GetArchive returns the archive, or nil, nil.
+	GetArchive(ctx context.Context, id string) (*Archive, error)
+	// ListArchives returns every archive, oldest first.
+	ListArchives(ctx context.Context) ([]*Archive, error)
+}
+
+var (
+	// ErrArchiveNotFound is returned for an unknown archive ID.
+	ErrArchiveNotFound = errors.New("rbac: archive not found")
+	// ErrArchiveRestored is returned when restoring an archive twice.
+	ErrArchiveRestored = errors.New("rbac: archive was already restored")
+
+	errNoArchiveRepo = errors.New("rbac: no ArchiveRepo configured")
+)
+
+// ArchiveRole exports the role's definition and every assignment of it into
+// an Archive, then removes the role and its edges so it no longer takes part
+// in checks. Inheriting roles and direct user and group assignments are
+// found through the repos' ExportPager. It runs in a transaction when the
+// store supports them.
+func (m *Manager) ArchiveRole(ctx context.Context, roleID string) (*Archive, error) {
+	start := time.Now()
+	ctx, span := m.startSpan(ctx, "ArchiveRole")
+	defer span.End()
+	var a *Archive
+	err := m.inTransaction(ctx, func(ctx context.Context) error {
+		var err error
+		a, err = m.archiveRole(ctx, start, roleID)
+		return err
+	})
+	m.record(ctx, start, "ArchiveRole", err)
+	m.changedRole(ctx, "ArchiveRole", roleID, err)
+	if err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+func (m *Manager) archiveRole(ctx context.Context, now time.Time, roleID string) (*Archive, error) {
+	if m.Archives == nil {
+		return nil, errNoArchiveRepo
+	}
+	role, err := m.Roles.GetRoleByID(ctx, roleID)
+	if err != nil {
+		return nil, err
+	}
+	if role == nil {
+		return nil, fmt.Errorf("%w: %q", ErrRoleNotFound, roleID)
+	}
+	b := ArchiveBundle{Role: role}
+
+	perms, err := m.RP.ListPermissions(ctx, roleID)
+	if err != nil {
+		return nil, err
+	}
+	for _, permID := range perms {
+		b.Permissions = append(b.Permissions, m.archivedEdge(ctx, KindRolePermission, roleID, permID))
+	}
+
+	hierarchy, _ := m.Roles.(RoleHierarchyRepo)
+	if hierarchy != nil {
+		if b.Parents, err = hierarchy.ListRoleParents(ctx, roleID); err != nil {
+			return nil, err
+		}
+		err := m.exportPages(ctx, KindRole, func(v any) error {
+			r, ok := v.(*Role)
+			if !ok {
+				return nil
+			}
+			parents, err := hierarchy.ListRoleParents(ctx, r.ID)
+			if err != nil {
+				return err
+			}
+			for _, p := range parents {
+				if p == roleID {
+					b.Children = append(b.Children, r.ID)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = m.exportPages(ctx, KindUserRole, func(v any) error {
+		if e, ok := v.(*ExportEdge); ok && e.To == roleID {
+			b.Users = append(b.Users, m.archivedEdge(ctx, KindUserRole, e.From, e.To))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if sched, ok := m.UR.(ScheduledUserRoleRepo); ok {
+		for _, e := range b.Users {
+			as, err := sched.ListRoleAssignments(ctx, e.From)
+			if err != nil {
+				return nil, err
+			}
+			for _, as := range as {
+				if as.RoleID == roleID && (as.NotBefore != 0 || as.ExpiresAt != 0) {
+					b.Schedules = append(b.Schedules, as)
+				}
+			}
+		}
+	}
+	err = m.exportPages(ctx, KindGroupRole, func(v any) error {
+		if e, ok := v.(*ExportEdge); ok && e.To == roleID {
+			b.Groups = append(b.Groups, m.archivedEdge(ctx, KindGroupRole, e.From, e.To))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, e := range b.Users {
+		if err := m.UR.RemoveUR(ctx, e.From, roleID); err != nil {
+			return nil, err
+		}
+	}
+	for _, e := range b.Groups {
+		if err := m.GR.RemoveRoleFromGroup(ctx, e.From, roleID); err != nil {
+			return nil, err
+		}
+	}
+	for _, e := range b.Permissions {
+		if err := m.RP.Remove(ctx, roleID, e.To); err != nil {
+			return nil, err
+		}
+	}
+	if hierarchy != nil {
+		for _, p := range b.Parents {
+			if err := hierarchy.RemoveRoleParent(ctx, roleID, p); err != nil {
+				return nil, err
+			}
+		}
+		for _, c := range b.Children {
+			if err := hierarchy.RemoveRoleParent(ctx, c, roleID); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := m.Roles.DeleteRole(ctx, roleID); err != nil {
+		return nil, err
+	}
+	return m.saveArchive(ctx, now, KindRole, role.ID, role.Name, b)
+}
+
+// ArchiveGroup exports the group's definition, memberships and role
+// bindings into an Archive, then deletes the group as DeleteGroup does,
+// including revoking the members' default roles. It runs in a transaction
+// when the store supports them.
+func (m *Manager) ArchiveGroup(ctx context.Context, groupID string) (*Archive, error) {
+	start := time.Now()
+	ctx, span := m.startSpan(ctx, "ArchiveGroup")
+	defer span.End()
+	var a *Archive
+	err := m.inTransaction(ctx, func(ctx context.Context) error {
+		var err error
+		a, err = m.archiveGroup(ctx, start, groupID)
+		return err
+	})
+	m.record(ctx, start, "ArchiveGroup", err)
+	m.changed(ctx, "ArchiveGroup", groupID, err)
+	if err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+func (m *Manager) archiveGroup(ctx context.Context, now time.Time, groupID string) (*Archive, error) {
+	if m.Archives == nil {
+		return nil, errNoArchiveRepo
+	}
+	g, err := m.storedGroup(ctx, groupID)
+	if err != nil {
+		return nil, err
+	}
+	b := ArchiveBundle{Group: g}
+
+	if b.Members, err = m.UG.GetUsersByGroupID(ctx, g.Name); err != nil {
+		return nil, err
+	}
+	for _, ug := range b.Members {
+		b.MemberEdges = append(b.MemberEdges, m.archivedEdge(ctx, KindUserGroup, ug.UserID, g.Name))
+	}
+	roles, err := m.GR.ListRolesForGroup(ctx, g.Name)
+	if err != nil {
+		return nil, err
+	}
+	for _, roleID := range roles {
+		b.Groups = append(b.Groups, m.archivedEdge(ctx, KindGroupRole, g.Name, roleID))
+	}
+	if scoped, ok := m.GR.(ScopedGroupRoleRepo); ok {
+		b.ScopedRoles, err = scoped.ListScopedRolesForGroup(ctx, g.Name)
+		if err != nil && !errors.Is(err, errScopeUnsupported) {
+			return nil, err
+		}
+	}
+
+	if err := m.deleteGroup(ctx, groupID); err != nil {
+		return nil, err
+	}
+	return m.saveArchive(ctx, now, KindGroup, g.ID, g.Name, b)
+}
+
+// archivedEdge captures an edge with its source, when the repo tracks one.
+func (m *Manager) archivedEdge(ctx context.Context, kind, from, to string) ArchivedEdge {
+	e := ArchivedEdge{From: from, To: to}
+	if src, err := m.EdgeSource(ctx, kind, from, to); err == nil {
+		e.Source = src
+	}
+	return e
+}
+
+func (m *Manager) saveArchive(ctx context.Context, now time.Time, kind, entityID, name string, b ArchiveBundle) (*Archive, error) {
+	a := &Archive{
+		Kind:       kind,
+		EntityID:   entityID,
+		Name:       name,
+		ArchivedAt: now.Unix(),
+		Bundle:     b,
+	}
+	m.assignID(&a.ID, KindArchive)
+	if err := m.Archives.SaveArchive(ctx, a); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// RestoreArchive re-creates an archived role or group with the same ID and
+// every assignment in its bundle, each attributed to the source it had.
+// Restoring a group grants its members' default roles again. The archive is
+// kept as a record with RestoredAt set; restoring it again fails with
+// ErrArchiveRestored. It runs in a transaction when the store supports them.
+func (m *Manager) RestoreArchive(ctx context.Context, archiveID string) error {
+	start := time.Now()
+	ctx, span := m.startSpan(ctx, "RestoreArchive")
+	defer span.End()
+	err := m.inTransaction(ctx, func(ctx context.Context) error {
+		return m.restoreArchive(ctx, start, archiveID)
+	})
+	m.record(ctx, start, "RestoreArchive", err)
+	m.changed(ctx, "RestoreArchive", archiveID, err)
+	return err
+}
+
+func (m *Manager) restoreArchive(ctx context.Context, now time.Time, archiveID string) error {
+	if m.Archives == nil {
+		return errNoArchiveRepo
+	}
+	a, err := m.Archives.GetArchive(ctx, archiveID)
+	if err != nil {
+		return err
+	}
+	if a == nil {
+		return fmt.Errorf("%w: %q", ErrArchiveNotFound, archiveID)
+	}
+	if a.RestoredAt != 0 {
+		return fmt.Errorf("%w: %q", ErrArchiveRestored, archiveID)
+	}
+
+	b := a.Bundle
+	switch {
+	case b.Role != nil:
+		err = m.restoreRole(ctx, b)
+	case b.Group != nil:
+		err = m.restoreGroup(ctx, b)
+	default:
+		err = fmt.Errorf("rbac: archive %q has nothing to restore", archiveID)
+	}
+	if err != nil {
+		return err
+	}
+	a.RestoredAt = now.Unix()
+	return m.Archives.SaveArchive(ctx, a)
+}
+
+func (m *Manager) restoreRole(ctx context.Context, b ArchiveBundle) error {
+	role := *b.Role
+	if err := m.Roles.CreateRole(ctx, &role); err != nil {
+		return err
+	}
+	for _, e := range b.Permissions {
+		if err := m.RP.AddRP(withArchivedSource(ctx, e), e.From, e.To); err != nil {
+			return err
+		}
+	}
+	if len(b.Parents) > 0 || len(b.Children) > 0 {
+		hierarchy, ok := m.Roles.(RoleHierarchyRepo)
+		if !ok {
+			return errHierarchyUnsupported
+		}
+		for _, p := range b.Parents {
+			if err := hierarchy.AddRoleParent(ctx, role.ID, p); err != nil {
+				return err
+			}
+		}
+		for _, c := range b.Children {
+			if err := hierarchy.AddRoleParent(ctx, c, role.ID); err != nil {
+				return err
+			}
+		}
+	}
+
+	windows := map[string]*RoleAssignment{}
+	for _, as := range b.Schedules {
+		windows[as.UserID] = as
+	}
+	sched, _ := m.UR.(ScheduledUserRoleRepo)
+	for _, e := range b.Users {
+		ectx := withArchivedSource(ctx, e)
+		var err error
+		if as := windows[e.From]; as != nil && sched != nil {
+			cp := *as
+			err = sched.AddScheduledUR(ectx, &cp)
+		} else {
+			err = m.UR.AddUR(ectx, e.From, e.To)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	for _, e := range b.Groups {
+		if err := m.GR.AddRoleToGroup(withArchivedSource(ctx, e), e.From, e.To); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *Manager) restoreGroup(ctx context.Context, b ArchiveBundle) error {
+	if m.Groups == nil {
+		return errNoGroupRepo
+	}
+	g := *b.Group
+	if existing, err := m.Groups.GetGroupByName(ctx, g.Name); err != nil {
+		return err
+	} else if existing != nil {
+		return fmt.Errorf("%w: %q", ErrGroupExists, g.Name)
+	}
+	if err := m.Groups.CreateGroup(ctx, &g); err != nil {
+		return err
+	}
+	for _, e := range b.Groups {
+		if err := m.GR.AddRoleToGroup(withArchivedSource(ctx, e), e.From, e.To); err != nil {
+			return err
+		}
+	}
+	if len(b.ScopedRoles) > 0 {
+		scoped, ok := m.GR.(ScopedGroupRoleRepo)
+		if !ok {
+			return errScopeUnsupported
+		}
+		for _, sr := range b.ScopedRoles {
+			if err := scoped.AddScopedRoleToGroup(ctx, g.Name, sr.RoleID, sr.Scope); err != nil {
+				return err
+			}
+		}
+	}
+	sources := map[string]ArchivedEdge{}
+	for _, e := range b.MemberEdges {
+		sources[e.From] = e
+	}
+	for _, ug := range b.Members {
+		cp := *ug
+		ectx := withArchivedSource(ctx, sources[cp.UserID])
+		if err := m.UG.AddUserToGroup(ectx, &cp); err != nil {
+			return err
+		}
+		if err := m.grantGroupDefaults(ectx, &cp); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sortArchives orders archives oldest first.
+func sortArchives(list []*Archive) {
+	sort.Slice(list, func(i, j int) bool {
+		a, b := list[i], list[j]
+		return a.ArchivedAt < b.ArchivedAt || (a.ArchivedAt == b.ArchivedAt && a.ID < b.ID)
+	})
+}
+
+// withArchivedSource attributes a restored edge to the source it had.
+func withArchivedSource(ctx context.Context, e ArchivedEdge) context.Context {
+	if e.Source == "" || e.Source == SourceManual {
+		return ctx
+	}
+	return WithAssignmentSource(ctx, e.Source)
+}
+
+// GetArchive returns an archive, or nil if there is none with the ID.
+func (m *Manager) GetArchive(ctx context.Context, id string) (*Archive, error) {
+	start := time.Now()
+	ctx, span := m.startSpan(ctx, "GetArchive")
+	defer span.End()
+	var (
+		a   *Archive
+		err = errNoArchiveRepo
+	)
+	if m.Archives != nil {
+		a, err = m.Archives.GetArchive(ctx, id)
+	}
+	m.record(ctx, start, "GetArchive", err)
+	return a, err
+}
+
+// ListArchives returns every archive, oldest first.
+func (m *Manager) ListArchives(ctx context.Context) ([]*Archive, error) {
+	start := time.Now()
+	ctx, span := m.startSpan(ctx, "ListArchives")
+	defer span.End()
+	var (
+		out []*Archive
+		err = errNoArchiveRepo
+	)
+	if m.Archives != nil {
+		out, err = m.Archives.ListArchives(ctx)
+	}
+	m.record(ctx, start, "ListArchives", err)
+	return out, err
+}
@@ -0,0 +1,158 @@
+package rbac
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestArchiveAndRestoreRole(t *testing.T) {
+	ctx := context.Background()
+	mgr, err := NewMemoryStoreManager(ctx, "", 0)
+	if err != nil {
+		t.Fatalf("NewMemoryStoreManager: %v", err)
+	}
+	base := &Role{Name: "base"}
+	editor := &Role{Name: "editor", Priority: 3}
+	lead := &Role{Name: "lead"}
+	for _, r := range []*Role{base, editor, lead} {
+		if err := mgr.CreateRole(ctx, r); err != nil {
+			t.Fatalf("CreateRole(%s): %v", r.Name, err)
+		}
+	}
+	perm := &Permission{Resource: "docs/*", Action: ActionUpdate}
+	if err := mgr.CreatePermission(ctx, perm); err != nil {
+		t.Fatalf("CreatePermission: %v", err)
+	}
+	if err := mgr.AssignPermissionToRole(ctx, editor.ID, perm.ID); err != nil {
+		t.Fatalf("AssignPermissionToRole: %v", err)
+	}
+	if err := mgr.AddRoleParent(ctx, editor.ID, base.ID); err != nil {
+		t.Fatalf("AddRoleParent(editor): %v", err)
+	}
+	if err := mgr.AddRoleParent(ctx, lead.ID, editor.ID); err != nil {
+		t.Fatalf("AddRoleParent(lead): %v", err)
+	}
+	if err := mgr.AssignRoleToUser(WithAssignmentSource(ctx, SourceBundle), "alice", editor.ID); err != nil {
+		t.Fatalf("AssignRoleToUser: %v", err)
+	}
+	expires := time.Now().Add(time.Hour)
+	if err := mgr.ScheduleRoleForUser(ctx, "bob", editor.ID, time.Time{}, expires); err != nil {
+		t.Fatalf("ScheduleRoleForUser: %v", err)
+	}
+	if err := mgr.AssignRoleToGroup(ctx, "writers", editor.ID); err != nil {
+		t.Fatalf("AssignRoleToGroup: %v", err)
+	}
+
+	a, err := mgr.ArchiveRole(ctx, editor.ID)
+	if err != nil {
+		t.Fatalf("ArchiveRole: %v", err)
+	}
+	if a.Kind != KindRole || a.EntityID != editor.ID || a.Name != "editor" || a.ArchivedAt == 0 {
+		t.Errorf("unexpected archive %+v", a)
+	}
+	b := a.Bundle
+	if len(b.Permissions) != 1 || len(b.Parents) != 1 || len(b.Children) != 1 || len(b.Users) != 2 || len(b.Schedules) != 1 || len(b.Groups) != 1 {
+		t.Errorf("incomplete bundle %+v", b)
+	}
+	if r, _ := mgr.GetRole(ctx, editor.ID); r != nil {
+		t.Errorf("expected the archived role to be gone, got %+v", r)
+	}
+	if ok, err := mgr.Can(ctx, "alice", "docs/1", ActionUpdate); err != nil || ok {
+		t.Errorf("Can after archiving = %v, %v; want false", ok, err)
+	}
+	if parents, _ := mgr.ListRoleParents(ctx, lead.ID); len(parents) != 0 {
+		t.Errorf("expected lead to lose its archived parent, got %v", parents)
+	}
+
+	if err := mgr.RestoreArchive(ctx, a.ID); err != nil {
+		t.Fatalf("RestoreArchive: %v", err)
+	}
+	if r, _ := mgr.GetRole(ctx, editor.ID); r == nil || r.Priority != 3 {
+		t.Errorf("expected the role to be restored with its ID and priority, got %+v", r)
+	}
+	for _, user := range []string{"alice", "bob"} {
+		if ok, err := mgr.Can(ctx, user, "docs/1", ActionUpdate); err != nil || !ok {
+			t.Errorf("Can(%s) after restoring = %v, %v; want true", user, ok, err)
+		}
+	}
+	if src, _ := mgr.EdgeSource(ctx, KindUserRole, "alice", editor.ID); src != SourceBundle {
+		t.Errorf("expected alice's assignment to keep source %q, got %q", SourceBundle, src)
+	}
+	if parents, _ := mgr.ListRoleParents(ctx, lead.ID); len(parents) != 1 || parents[0] != editor.ID {
+		t.Errorf("expected lead to inherit from editor again, got %v", parents)
+	}
+	if roles, _ := mgr.ListRolesForGroup(ctx, "writers"); len(roles) != 1 || roles[0] != editor.ID {
+		t.Errorf("expected writers to hold editor again, got %v", roles)
+	}
+
+	got, err := mgr.GetArchive(ctx, a.ID)
+	if err != nil || got == nil || got.RestoredAt == 0 {
+		t.Errorf("expected the tombstone to record the restore, got %+v, %v", got, err)
+	}
+	if err := mgr.RestoreArchive(ctx, a.ID); !errors.Is(err, ErrArchiveRestored) {
+		t.Errorf("expected ErrArchiveRestored, got %v", err)
+	}
+	if err := mgr.RestoreArchive(ctx, "missing"); !errors.Is(err, ErrArchiveNotFound) {
+		t.Errorf("expected ErrArchiveNotFound, got %v", err)
+	}
+}
+
+func TestArchiveAndRestoreGroup(t *testing.T) {
+	ctx := context.Background()
+	mgr, err := NewMemoryStoreManager(ctx, "", 0)
+	if err != nil {
+		t.Fatalf("NewMemoryStoreManager: %v", err)
+	}
+	role := &Role{Name: "writer"}
+	if err := mgr.CreateRole(ctx, role); err != nil {
+		t.Fatalf("CreateRole: %v", err)
+	}
+	perm := &Permission{Resource: "docs/*", Action: ActionCreate}
+	if err := mgr.CreatePermission(ctx, perm); err != nil {
+		t.Fatalf("CreatePermission: %v", err)
+	}
+	if err := mgr.AssignPermissionToRole(ctx, role.ID, perm.ID); err != nil {
+		t.Fatalf("AssignPermissionToRole: %v", err)
+	}
+	g := &Group{Name: "writers"}
+	if err := mgr.CreateGroup(ctx, g); err != nil {
+		t.Fatalf("CreateGroup: %v", err)
+	}
+	if err := mgr.AssignRoleToGroup(ctx, "writers", role.ID); err != nil {
+		t.Fatalf("AssignRoleToGroup: %v", err)
+	}
+	if err := mgr.AddUserToGroup(ctx, &UserGroup{UserID: "carol", GroupName: "writers"}); err != nil {
+		t.Fatalf("AddUserToGroup: %v", err)
+	}
+
+	a, err := mgr.ArchiveGroup(ctx, g.ID)
+	if err != nil {
+		t.Fatalf("ArchiveGroup: %v", err)
+	}
+	if a.Kind != KindGroup || len(a.Bundle.Members) != 1 || len(a.Bundle.Groups) != 1 {
+		t.Errorf("unexpected archive %+v", a)
+	}
+	if got, _ := mgr.GetGroup(ctx, g.ID); got != nil {
+		t.Errorf("expected the archived group to be gone, got %+v", got)
+	}
+	if ok, err := mgr.Can(ctx, "carol", "docs/1", ActionCreate); err != nil || ok {
+		t.Errorf("Can after archiving = %v, %v; want false", ok, err)
+	}
+
+	if err := mgr.RestoreArchive(ctx, a.ID); err != nil {
+		t.Fatalf("RestoreArchive: %v", err)
+	}
+	if got, _ := mgr.GetGroup(ctx, g.ID); got == nil || got.Name != "writers" {
+		t.Errorf("expected the group to be restored with its ID, got %+v", got)
+	}
+	if ok, err := mgr.Can(ctx, "carol", "docs/1", ActionCreate); err != nil || !ok {
+		t.Errorf("Can after restoring = %v, %v; want true", ok, err)
+	}
+
+	list, err := mgr.ListArchives(ctx)
+	if err != nil || len(list) != 1 || list[0].ID != a.ID {
+		t.Errorf("ListArchives = %v, %v", list, err)
+	}
+}
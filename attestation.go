@@ -0,0 +1,198 @@
+package rbac
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// KindAttestation is passed to IDGenerator.NewID for attestations.
+const KindAttestation = "attestation"
+
+// Attestation records that CertifiedBy, typically the user's manager,
+// confirmed at CertifiedAt (unix seconds) that the user still needs the
+// role.
+type Attestation struct {
+	ID          string `bson:"id" json:"id"`
+	UserID      string `bson:"user_id" json:"user_id"`
+	RoleID      string `bson:"role_id" json:"role_id"`
+	CertifiedBy string `bson:"certified_by" json:"certified_by"`
+	Comment     string `bson:"comment,omitempty" json:"comment,omitempty"`
+	CertifiedAt int64  `bson:"certified_at" json:"certified_at"`
+}
+
+// AttestationRepo stores attestations. They are an audit trail: they are
+// only ever added.
+type AttestationRepo interface {
+	AddAttestation(ctx context.Context, a *Attestation) error
+	// LatestAttestation returns the user's most recent attestation for the
+	// role, or nil, nil.
+	LatestAttestation(ctx context.Context, userID, roleID string) (*Attestation, error)
+	// ListAttestations returns the user's attestations, oldest first.
+	ListAttestations(ctx context.Context, userID string) ([]*Attestation, error)
+}
+
+// ErrNotAssigned is returned when certifying a role the user does not hold.
+var ErrNotAssigned = errors.New("rbac: role is not assigned to the user")
+
+var errNoAttestationRepo = errors.New("rbac: no AttestationRepo configured")
+
+// CertifyRole records that by confirmed the user still needs the role,
+// starting a new certification period for the assignment.
+func (m *Manager) CertifyRole(ctx context.Context, userID, roleID, by, comment string) (*Attestation, error) {
+	start := time.Now()
+	ctx, span := m.startSpan(ctx, "CertifyRole")
+	defer span.End()
+	a, err := m.certifyRole(ctx, start, userID, roleID, by, comment)
+	m.record(ctx, start, "CertifyRole", err)
+	return a, err
+}
+
+func (m *Manager) certifyRole(ctx context.Context, now time.Time, userID, roleID, by, comment string) (*Attestation, error) {
+	if m.Attestations == nil {
+		return nil, errNoAttestationRepo
+	}
+	if by == "" {
+		return nil, errors.New("rbac: attestation needs a certifier")
+	}
+	roles, err := m.UR.ListRoles(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !slices.Contains(roles, roleID) {
+		return nil, fmt.Errorf("%w: %s → %s", ErrNotAssigned, userID, roleID)
+	}
+	a := &Attestation{
+		UserID:      userID,
+		RoleID:      roleID,
+		CertifiedBy: by,
+		Comment:     comment,
+		CertifiedAt: now.Unix(),
+	}
+	m.assignID(&a.ID, KindAttestation)
+	if err := m.Attestations.AddAttestation(ctx, a); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// ListAttestations returns the user's attestation history, oldest first.
+func (m *Manager) ListAttestations(ctx context.Context, userID string) ([]*Attestation, error) {
+	start := time.Now()
+	ctx, span := m.startSpan(ctx, "ListAttestations")
+	defer span.End()
+	var (
+		out []*Attestation
+		err = errNoAttestationRepo
+	)
+	if m.Attestations != nil {
+		out, err = m.Attestations.ListAttestations(ctx, userID)
+	}
+	m.record(ctx, start, "ListAttestations", err)
+	return out, err
+}
+
+// RecertificationPolicy configures Manager.ReviewCertifications.
+type RecertificationPolicy struct {
+	// Period is how long an attestation certifies an assignment.
+	Period time.Duration
+	// Deadline is when assignments that were never certified become
+	// overdue.
+	Deadline time.Time
+	// Revoke revokes overdue assignments instead of only reporting them.
+	Revoke bool
+}
+
+// CertificationDue is an assignment that needs certifying, as reported by
+// ReviewCertifications.
+type CertificationDue struct {
+	UserID string `json:"user_id"`
+	RoleID string `json:"role_id"`
+	// Last is the assignment's latest attestation, nil if it never had one.
+	Last *Attestation `json:"last,omitempty"`
+	// Due is when the assignment is, or was, overdue.
+	Due     time.Time `json:"due"`
+	Overdue bool      `json:"overdue"`
+	Revoked bool      `json:"revoked,omitempty"`
+}
+
+// ReviewCertifications checks every direct user role assignment against
+// policy, for a periodic (e.g. quarterly SOX) recertification campaign. It
+// returns the assignments without a current attestation: those past due
+// are Overdue, and revoked when policy.Revoke is set; the others are
+// pending until policy.Deadline. Assignments are listed through the user
+// role repo's ExportPager.
+func (m *Manager) ReviewCertifications(ctx context.Context, policy RecertificationPolicy) ([]*CertificationDue, error) {
+	start := time.Now()
+	ctx, span := m.startSpan(ctx, "ReviewCertifications")
+	defer span.End()
+	out, err := m.reviewCertifications(ctx, start, policy)
+	m.record(ctx, start, "ReviewCertifications", err)
+	return out, err
+}
+
+func (m *Manager) reviewCertifications(ctx context.Context, now time.Time, policy RecertificationPolicy) ([]*CertificationDue, error) {
+	if m.Attestations == nil {
+		return nil, errNoAttestationRepo
+	}
+	if policy.Period <= 0 {
+		return nil, errors.New("rbac: recertification period must be positive")
+	}
+	pager, ok := m.UR.(ExportPager)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", errExportUnsupported, KindUserRole)
+	}
+
+	var out []*CertificationDue
+	after := ""
+	for {
+		items, err := pager.ExportPage(ctx, KindUserRole, after, 500)
+		if err != nil {
+			return nil, err
+		}
+		for _, it := range items {
+			edge, ok := it.Value.(*ExportEdge)
+			if !ok {
+				continue
+			}
+			last, err := m.Attestations.LatestAttestation(ctx, edge.From, edge.To)
+			if err != nil {
+				return nil, err
+			}
+			due := policy.Deadline
+			if last != nil {
+				due = time.Unix(last.CertifiedAt, 0).Add(policy.Period)
+				if due.After(now) {
+					continue
+				}
+			}
+			out = append(out, &CertificationDue{
+				UserID:  edge.From,
+				RoleID:  edge.To,
+				Last:    last,
+				Due:     due,
+				Overdue: !due.After(now),
+			})
+		}
+		if len(items) == 0 {
+			break
+		}
+		after = items[len(items)-1].Key
+	}
+
+	if policy.Revoke {
+		revokeCtx := WithAssignmentSource(ctx, SourceAny)
+		for _, d := range out {
+			if !d.Overdue {
+				continue
+			}
+			if err := m.UnassignRoleFromUser(revokeCtx, d.UserID, d.RoleID); err != nil {
+				return out, err
+			}
+			d.Revoked = true
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,76 @@
+package rbac
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestReviewCertifications(t *testing.T) {
+	ctx := context.Background()
+	mgr, err := NewMemoryStoreManager(ctx, "", 0)
+	if err != nil {
+		t.Fatalf("NewMemoryStoreManager: %v", err)
+	}
+	for _, a := range [][2]string{{"alice", "admin"}, {"bob", "admin"}, {"carol", "auditor"}} {
+		if err := mgr.AssignRoleToUser(ctx, a[0], a[1]); err != nil {
+			t.Fatalf("AssignRoleToUser(%s, %s): %v", a[0], a[1], err)
+		}
+	}
+
+	if _, err := mgr.CertifyRole(ctx, "alice", "auditor", "mgr", ""); !errors.Is(err, ErrNotAssigned) {
+		t.Fatalf("expected certifying an unassigned role to fail, got %v", err)
+	}
+	att, err := mgr.CertifyRole(ctx, "alice", "admin", "mgr", "still on call")
+	if err != nil {
+		t.Fatalf("CertifyRole: %v", err)
+	}
+	if att.ID == "" || att.CertifiedBy != "mgr" {
+		t.Fatalf("unexpected attestation %+v", att)
+	}
+	// An old attestation for carol that has since run out.
+	stale := &Attestation{UserID: "carol", RoleID: "auditor", CertifiedBy: "mgr", CertifiedAt: time.Now().Add(-100 * 24 * time.Hour).Unix()}
+	if err := mgr.Attestations.AddAttestation(ctx, stale); err != nil {
+		t.Fatalf("AddAttestation: %v", err)
+	}
+
+	policy := RecertificationPolicy{Period: 90 * 24 * time.Hour, Deadline: time.Now().Add(14 * 24 * time.Hour)}
+	due, err := mgr.ReviewCertifications(ctx, policy)
+	if err != nil {
+		t.Fatalf("ReviewCertifications: %v", err)
+	}
+	got := map[string]*CertificationDue{}
+	for _, d := range due {
+		got[d.UserID+"/"+d.RoleID] = d
+	}
+	if len(got) != 2 || got["alice/admin"] != nil {
+		t.Fatalf("expected bob and carol to need certifying, got %+v", due)
+	}
+	if d := got["bob/admin"]; d == nil || d.Overdue || d.Last != nil {
+		t.Errorf("expected bob's never-certified assignment to be pending, got %+v", d)
+	}
+	if d := got["carol/auditor"]; d == nil || !d.Overdue || d.Last == nil || d.Revoked {
+		t.Errorf("expected carol's lapsed certification to be overdue, got %+v", d)
+	}
+
+	policy.Deadline = time.Now().Add(-time.Hour)
+	policy.Revoke = true
+	if _, err := mgr.ReviewCertifications(ctx, policy); err != nil {
+		t.Fatalf("ReviewCertifications with Revoke: %v", err)
+	}
+	for user, role := range map[string]string{"bob": "admin", "carol": "auditor"} {
+		if roles, _ := mgr.ListRolesForUser(ctx, user); slices.Contains(roles, role) {
+			t.Errorf("expected %s's overdue %s role to be revoked", user, role)
+		}
+	}
+	if roles, _ := mgr.ListRolesForUser(ctx, "alice"); !slices.Contains(roles, "admin") {
+		t.Error("expected alice's certified role to be kept")
+	}
+
+	history, err := mgr.ListAttestations(ctx, "carol")
+	if err != nil || len(history) != 1 {
+		t.Errorf("expected the attestation history to survive revocation, got %+v, %v", history, err)
+	}
+}
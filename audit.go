@@ -0,0 +1,199 @@
+package rbac
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"strings"
+	"time"
+)
+
+// KindAuditEntry is the IDGenerator kind of audit entries.
+const KindAuditEntry = "audit_entry"
+
+// AuditKind tells policy changes from access decisions in the audit log.
+type AuditKind string
+
+const (
+	AuditChange   AuditKind = "change"
+	AuditDecision AuditKind = "decision"
+)
+
+// Audit outcomes: changes succeed or fail, decisions allow or deny, and
+// decisions that could not be made fail. A decision allowed by the
+// super-admin role is bypassed.
+const (
+	AuditSucceeded = "succeeded"
+	AuditFailed    = "failed"
+	AuditAllowed   = "allowed"
+	AuditDenied    = "denied"
+	AuditBypassed  = "bypassed"
+)
+
+// AuditEntry records a policy change made through the Manager, or an access
+// decision it made.
+type AuditEntry struct {
+	ID string    `bson:"id" json:"id"`
+	At time.Time `bson:"at" json:"at"`
+	// Actor is the caller's WithActor, empty when there was none.
+	Actor string    `bson:"actor" json:"actor,omitempty"`
+	Kind  AuditKind `bson:"kind" json:"kind"`
+	// Method is the Manager method, e.g. AssignRoleToUser or Can.
+	Method string `bson:"method" json:"method"`
+	// Target is the ID of the user, role, group or other entity changed, or
+	// the user a decision was made for.
+	Target string `bson:"target" json:"target"`
+	// Resource and Action are the request of a decision.
+	Resource string `bson:"resource,omitempty" json:"resource,omitempty"`
+	Action   Action `bson:"action,omitempty" json:"action,omitempty"`
+	Outcome  string `bson:"outcome" json:"outcome"`
+	Error    string `bson:"error,omitempty" json:"error,omitempty"`
+}
+
+// AuditQuery selects audit entries. Empty fields match every entry; Since
+// is inclusive and Until exclusive. Limit caps the entries returned, 0
+// meaning no cap.
+type AuditQuery struct {
+	Actor  string
+	Target string
+	Since  time.Time
+	Until  time.Time
+	Limit  int
+}
+
+// AuditRepo stores the audit log.
+type AuditRepo interface {
+	AppendAudit(ctx context.Context, e *AuditEntry) error
+	// ListAudit returns the entries matching q, oldest first.
+	ListAudit(ctx context.Context, q AuditQuery) ([]*AuditEntry, error)
+}
+
+var errNoAuditRepo = errors.New("rbac: no AuditRepo configured")
+
+// ListAuditEntries returns the audit entries matching q, oldest first.
+func (m *Manager) ListAuditEntries(ctx context.Context, q AuditQuery) ([]*AuditEntry, error) {
+	start := time.Now()
+	ctx, span := m.startSpan(ctx, "ListAuditEntries")
+	defer span.End()
+	var out []*AuditEntry
+	err := errNoAuditRepo
+	if a := m.auditor(); a.Audit != nil {
+		out, err = a.Audit.ListAudit(ctx, q)
+	}
+	m.record(ctx, start, "ListAuditEntries", err)
+	return out, err
+}
+
+// auditor returns the Manager whose Audit settings apply: the base Manager
+// for those ForTenant returns.
+func (m *Manager) auditor() *Manager {
+	if m.base != nil {
+		return m.base
+	}
+	return m
+}
+
+// auditChange records a policy change by method on target.
+func (m *Manager) auditChange(ctx context.Context, method, target string, err error) {
+	e := &AuditEntry{Kind: AuditChange, Method: method, Target: target, Outcome: AuditSucceeded}
+	if err != nil {
+		e.Outcome, e.Error = AuditFailed, err.Error()
+	}
+	m.appendAudit(ctx, e)
+}
+
+// auditDecision records AuditDecisions of the access decisions made for
+// userID, and every super-admin bypass.
+func (m *Manager) auditDecision(ctx context.Context, method, userID, resource string, action Action, d *Decision, err error) {
+	a := m.auditor()
+	bypass := err == nil && d.SuperAdmin
+	if a.Audit == nil || !bypass && (a.AuditDecisions <= 0 || (a.AuditDecisions < 1 && rand.Float64() >= a.AuditDecisions)) {
+		return
+	}
+	e := &AuditEntry{Kind: AuditDecision, Method: method, Target: userID, Resource: resource, Action: action}
+	switch {
+	case err != nil:
+		e.Outcome, e.Error = AuditFailed, err.Error()
+	case bypass:
+		e.Outcome = AuditBypassed
+	case d.Allowed:
+		e.Outcome = AuditAllowed
+	default:
+		e.Outcome = AuditDenied
+	}
+	a.appendAudit(ctx, e)
+}
+
+// appendAudit stores e. A failure is only recorded in the metrics, so an
+// unavailable audit log does not fail the change or check it describes.
+func (m *Manager) appendAudit(ctx context.Context, e *AuditEntry) {
+	if m.Audit == nil {
+		return
+	}
+	start := time.Now()
+	m.assignID(&e.ID, KindAuditEntry)
+	e.At = start.UTC()
+	e.Actor = Actor(ctx)
+	m.record(ctx, start, "AppendAudit", m.Audit.AppendAudit(ctx, e))
+}
+
+// matchesAudit reports whether e is selected by q, for stores that filter
+// in memory.
+func matchesAudit(e *AuditEntry, q AuditQuery) bool {
+	return (q.Actor == "" || e.Actor == q.Actor) &&
+		(q.Target == "" || e.Target == q.Target) &&
+		(q.Since.IsZero() || !e.At.Before(q.Since)) &&
+		(q.Until.IsZero() || e.At.Before(q.Until))
+}
+
+// auditColumns are the columns of the SQL stores' audit_log table, whose at
+// holds Unix nanoseconds.
+const auditColumns = `id, at, actor, kind, method, target, resource, action, outcome, error_msg`
+
+// auditFilter returns the WHERE clause, possibly empty, and LIMIT selecting
+// q's entries from audit_log, with param(n) naming the nth parameter.
+func auditFilter(q AuditQuery, param func(n int) string) (string, []any) {
+	var conds []string
+	var args []any
+	add := func(cond string, arg any) {
+		args = append(args, arg)
+		conds = append(conds, cond+" "+param(len(args)))
+	}
+	if q.Actor != "" {
+		add("actor =", q.Actor)
+	}
+	if q.Target != "" {
+		add("target =", q.Target)
+	}
+	if !q.Since.IsZero() {
+		add("at >=", q.Since.UnixNano())
+	}
+	if !q.Until.IsZero() {
+		add("at <", q.Until.UnixNano())
+	}
+	var clause string
+	if len(conds) > 0 {
+		clause = " WHERE " + strings.Join(conds, " AND ")
+	}
+	clause += " ORDER BY at, id"
+	if q.Limit > 0 {
+		clause += fmt.Sprintf(" LIMIT %d", q.Limit)
+	}
+	return clause, args
+}
+
+// scanAudit reads audit_log rows selected with auditColumns.
+func scanAudit(next func() bool, scan func(dest ...any) error) ([]*AuditEntry, error) {
+	var out []*AuditEntry
+	for next() {
+		var e AuditEntry
+		var at int64
+		if err := scan(&e.ID, &at, &e.Actor, &e.Kind, &e.Method, &e.Target, &e.Resource, &e.Action, &e.Outcome, &e.Error); err != nil {
+			return nil, err
+		}
+		e.At = time.Unix(0, at).UTC()
+		out = append(out, &e)
+	}
+	return out, nil
+}
@@ -0,0 +1,97 @@
+package rbac
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestAuditLog(t *testing.T) {
+	repo := NewMockRepo()
+	mgr := NewMockRepoManager(repo)
+	mgr.Audit = repo
+	ctx := WithActor(context.Background(), "admin")
+
+	if err := mgr.CreateRole(ctx, &Role{ID: "editor", Name: "editor"}); err != nil {
+		t.Fatalf("CreateRole: %v", err)
+	}
+	if err := mgr.AssignRoleToUser(ctx, "alice", "editor"); err != nil {
+		t.Fatalf("AssignRoleToUser: %v", err)
+	}
+	if err := mgr.Roles.CreateRole(ctx, &Role{ID: "tpl", Name: "tpl", Template: true}); err != nil {
+		t.Fatalf("CreateRole: %v", err)
+	}
+	if err := mgr.AssignRoleToUser(ctx, "bob", "tpl"); !errors.Is(err, ErrTemplateRole) {
+		t.Fatalf("AssignRoleToUser(template) = %v; want ErrTemplateRole", err)
+	}
+	// decisions are not recorded unless sampled
+	if _, err := mgr.Can(ctx, "alice", "docs/1", ActionRead); err != nil {
+		t.Fatalf("Can: %v", err)
+	}
+
+	entries, err := mgr.ListAuditEntries(ctx, AuditQuery{Actor: "admin"})
+	if err != nil {
+		t.Fatalf("ListAuditEntries: %v", err)
+	}
+	want := []struct{ method, target, outcome string }{
+		{"CreateRole", "editor", AuditSucceeded},
+		{"AssignRoleToUser", "alice", AuditSucceeded},
+		{"AssignRoleToUser", "bob", AuditFailed},
+	}
+	if len(entries) != len(want) {
+		t.Fatalf("got %d entries; want %d", len(entries), len(want))
+	}
+	for i, w := range want {
+		e := entries[i]
+		if e.Kind != AuditChange || e.Method != w.method || e.Target != w.target || e.Outcome != w.outcome || e.Actor != "admin" || e.At.IsZero() || e.ID == "" {
+			t.Errorf("entry %d = %+v; want %s of %s %s by admin", i, e, w.method, w.target, w.outcome)
+		}
+	}
+	if entries[2].Error == "" {
+		t.Errorf("failed change recorded without its error")
+	}
+
+	mgr.AuditDecisions = 1
+	if _, err := mgr.Can(ctx, "alice", "docs/1", ActionRead); err != nil {
+		t.Fatalf("Can: %v", err)
+	}
+	entries, err = mgr.ListAuditEntries(ctx, AuditQuery{Target: "alice"})
+	if err != nil {
+		t.Fatalf("ListAuditEntries: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("got %d entries for alice; want the assignment and the decision", len(entries))
+	}
+	if d := entries[1]; d.Kind != AuditDecision || d.Method != "Can" || d.Resource != "docs/1" || d.Action != ActionRead || d.Outcome != AuditDenied {
+		t.Errorf("decision entry = %+v; want a denied Can on docs/1", d)
+	}
+
+	// time range and limit
+	if entries, err := mgr.ListAuditEntries(ctx, AuditQuery{Since: time.Now().Add(time.Hour)}); err != nil || len(entries) != 0 {
+		t.Errorf("ListAuditEntries(future) = %d entries, %v; want none", len(entries), err)
+	}
+	if entries, err := mgr.ListAuditEntries(ctx, AuditQuery{Until: time.Now().Add(time.Hour), Limit: 2}); err != nil || len(entries) != 2 || entries[0].Method != "CreateRole" {
+		t.Errorf("ListAuditEntries(limit 2) = %v, %v; want the first two entries", entries, err)
+	}
+}
+
+func TestAuditLogNoRepo(t *testing.T) {
+	if _, err := NewMockRepoManager(NewMockRepo()).ListAuditEntries(context.Background(), AuditQuery{}); !errors.Is(err, errNoAuditRepo) {
+		t.Errorf("ListAuditEntries = %v; want errNoAuditRepo", err)
+	}
+}
+
+func TestAuditFilter(t *testing.T) {
+	since := time.Unix(100, 0)
+	clause, args := auditFilter(AuditQuery{Actor: "admin", Since: since, Limit: 10}, func(n int) string { return "$" + string(rune('0'+n)) })
+	if want := " WHERE actor = $1 AND at >= $2 ORDER BY at, id LIMIT 10"; clause != want {
+		t.Errorf("clause = %q; want %q", clause, want)
+	}
+	if len(args) != 2 || args[0] != "admin" || args[1] != since.UnixNano() {
+		t.Errorf("args = %v; want [admin %d]", args, since.UnixNano())
+	}
+	if clause, args := auditFilter(AuditQuery{}, nil); clause != " ORDER BY at, id" || len(args) != 0 {
+		t.Errorf("empty query = %q, %v", clause, args)
+	}
+}
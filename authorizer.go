@@ -0,0 +1,247 @@
+package rbac
+
+import (
+	"cmp"
+	"context"
+	"path"
+	"slices"
+	"time"
+
+	"github.com/Seann-Moser/rbac/rbaceval"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// AuthorizeRequest is an access check as the links of the authorizer chain
+// see it.
+type AuthorizeRequest struct {
+	UserID   string
+	Resource string
+	Action   Action
+	// Attrs are the request attributes of CanWithAttributes and Decide; nil
+	// for Can.
+	Attrs map[string]any
+	// Roles are the roles the user holds in every way Can counts, expanded
+	// through the role hierarchy.
+	Roles []string
+
+	method string
+	start  time.Time
+	tr     *decisionTrace
+	ranked []rankedRole // loaded on first use
+}
+
+// Authorizer is one link of the chain Can, Decide, CanBatch and
+// CanForSession run a request through; see Manager.Authorizers. Authorize
+// returns the Decision that settles the request, or nil to leave it to the
+// next link. An error fails the check.
+type Authorizer interface {
+	Authorize(ctx context.Context, m *Manager, req *AuthorizeRequest) (*Decision, error)
+}
+
+// AuthorizerFunc is an Authorizer written as a function, e.g. one that
+// allows the owner of a resource whatever their roles say. Set
+// Decision.Authorizer on the decisions it returns so Explain can name it.
+type AuthorizerFunc func(ctx context.Context, m *Manager, req *AuthorizeRequest) (*Decision, error)
+
+func (f AuthorizerFunc) Authorize(ctx context.Context, m *Manager, req *AuthorizeRequest) (*Decision, error) {
+	return f(ctx, m, req)
+}
+
+// The built-in links of the chain, in the order DefaultAuthorizers returns
+// them.
+var (
+	// ActiveUserAuthorizer denies users that are suspended or locked, and,
+	// with RequireVerifiedEmail, those who have not verified their email.
+	ActiveUserAuthorizer Authorizer = activeUserAuthorizer{}
+	// SuperAdminAuthorizer allows holders of the Manager's
+	// SuperAdminRoleName.
+	SuperAdminAuthorizer Authorizer = superAdminAuthorizer{}
+	// RoleAuthorizer matches the request against the permissions of the
+	// user's roles. Allow and deny rules are matched together, since
+	// Role.Priority ranks one against the other; a request no rule matches
+	// is left to the next link.
+	RoleAuthorizer Authorizer = roleAuthorizer{}
+)
+
+// DefaultAuthorizers returns the chain a Manager runs when its Authorizers
+// is nil. Applications insert their own links into it, e.g.
+//
+//	m.Authorizers = slices.Insert(rbac.DefaultAuthorizers(), 1, ownerAuthorizer)
+func DefaultAuthorizers() []Authorizer {
+	return []Authorizer{ActiveUserAuthorizer, SuperAdminAuthorizer, RoleAuthorizer}
+}
+
+// builtinAuthorizer reports whether a is one of the built-in links.
+func builtinAuthorizer(a Authorizer) bool {
+	switch a.(type) {
+	case activeUserAuthorizer, superAdminAuthorizer, roleAuthorizer:
+		return true
+	}
+	return false
+}
+
+type activeUserAuthorizer struct{}
+
+func (activeUserAuthorizer) Authorize(ctx context.Context, m *Manager, req *AuthorizeRequest) (*Decision, error) {
+	callStart := time.Now()
+	active, err := m.evalUserActive(ctx, req.UserID)
+	req.tr.storeCall("GetUserByID", callStart, err)
+	if err != nil {
+		return nil, err
+	}
+	if !active {
+		explainerFrom(ctx).deniedInactive()
+		return &Decision{}, nil
+	}
+	return nil, nil
+}
+
+type superAdminAuthorizer struct{}
+
+func (superAdminAuthorizer) Authorize(ctx context.Context, m *Manager, req *AuthorizeRequest) (*Decision, error) {
+	if m.SuperAdminRoleName == "" {
+		return nil, nil
+	}
+	ranked, err := req.rankedRoles(ctx, m)
+	if err != nil {
+		return nil, err
+	}
+	for _, rr := range ranked {
+		if rr.role != nil && rr.role.Name == m.SuperAdminRoleName {
+			return &Decision{Allowed: true, RoleID: rr.id, Effect: EffectAllow, Priority: rr.priority, SuperAdmin: true}, nil
+		}
+	}
+	return nil, nil
+}
+
+// rankedRole is one of the request's roles with its record and priority.
+type rankedRole struct {
+	id       string
+	role     *Role
+	priority int
+}
+
+// rankedRoles returns the request's roles that are not soft-deleted,
+// highest priority first. Roles missing from the store rank at priority 0;
+// a failed role lookup is returned, as the role's priority may be what
+// lets one of its denies win.
+func (req *AuthorizeRequest) rankedRoles(ctx context.Context, m *Manager) ([]rankedRole, error) {
+	if req.ranked != nil {
+		return req.ranked, nil
+	}
+	ranked := make([]rankedRole, 0, len(req.Roles))
+	for _, roleID := range req.Roles {
+		callStart := time.Now()
+		role, err := m.evalRole(ctx, roleID)
+		req.tr.storeCall("GetRoleByID", callStart, err, attribute.String("rbac.role_id", roleID))
+		if err != nil {
+			return nil, err
+		}
+		if role != nil && role.DeletedAt != 0 {
+			continue
+		}
+		rr := rankedRole{id: roleID, role: role}
+		if role != nil {
+			rr.priority = role.Priority
+		}
+		ranked = append(ranked, rr)
+	}
+	slices.SortStableFunc(ranked, func(a, b rankedRole) int { return cmp.Compare(b.priority, a.priority) })
+	req.ranked = ranked
+	return ranked, nil
+}
+
+type roleAuthorizer struct{}
+
+// Authorize matches the permissions of each role, highest priority first;
+// the matching rule of the highest-priority role decides, and on a tie a
+// deny wins. Once no role left can outrank the winner, their permissions
+// are not loaded, unless Explain needs every match.
+func (roleAuthorizer) Authorize(ctx context.Context, m *Manager, req *AuthorizeRequest) (*Decision, error) {
+	var (
+		winner *Decision
+		vars   map[string]any // built on the first condition or generator
+	)
+	loadVars := func() map[string]any {
+		if vars == nil {
+			vars = m.conditionVars(ctx, req.UserID, req.Resource, req.Action, req.Attrs)
+		}
+		return vars
+	}
+	ranked, err := req.rankedRoles(ctx, m)
+	if err != nil {
+		return nil, err
+	}
+	ex := explainerFrom(ctx)
+	for _, rr := range ranked {
+		roleID, role, priority := rr.id, rr.role, rr.priority
+		if winner != nil && ex == nil && !outranks(priority, true, winner) {
+			break
+		}
+		callStart := time.Now()
+		perms, err := m.evalRolePermissions(ctx, req.start, roleID)
+		req.tr.storeCall("RolePermissions", callStart, err, attribute.String("rbac.role_id", roleID))
+		if err != nil {
+			return nil, err
+		}
+		if role != nil {
+			perms = append(perms, m.generatedPermissions(ctx, req.start, role, loadVars)...)
+		}
+		for _, perm := range perms {
+			if perm.DeletedAt != 0 {
+				continue
+			}
+			deny := perm.Effect == EffectDeny
+			// Explain still reports the matching rules that cannot win
+			outranked := winner != nil && !outranks(priority, deny, winner)
+			if outranked && ex == nil {
+				continue
+			}
+			params, okRes, err := rbaceval.MatchResourceParams(perm.Resource, req.Resource)
+			if err != nil {
+				return nil, err
+			}
+			if !okRes || !rbaceval.ParamsAgree(params, req.Attrs) {
+				continue
+			}
+			okAct, err := path.Match(string(perm.Action), string(req.Action))
+			if err != nil {
+				return nil, err
+			}
+			if !okAct && m.Actions != nil {
+				okAct = m.Actions.inGroup(perm.Action, req.Action)
+			}
+			if !okAct {
+				continue
+			}
+			if outranked {
+				ex.match(roleID, perm, priority, SkippedOutranked)
+				continue
+			}
+			if perm.Condition != "" {
+				applies, err := rbaceval.CheckCondition(perm.Condition, deny, rbaceval.WithParams(loadVars(), params))
+				if err != nil {
+					return nil, err
+				}
+				if !applies {
+					ex.match(roleID, perm, priority, SkippedCondition)
+					continue
+				}
+			}
+			ex.match(roleID, perm, priority, "")
+			effect := EffectAllow
+			if deny {
+				effect = EffectDeny
+			}
+			winner = &Decision{
+				Allowed:      !deny,
+				RoleID:       roleID,
+				PermissionID: perm.ID,
+				Effect:       effect,
+				Priority:     priority,
+				Params:       params,
+			}
+		}
+	}
+	return winner, nil
+}
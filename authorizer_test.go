@@ -0,0 +1,84 @@
+package rbac
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestAuthorizerChain(t *testing.T) {
+	ctx := context.Background()
+	mgr, err := NewMemoryStoreManager(ctx, "", 0)
+	if err != nil {
+		t.Fatalf("NewMemoryStoreManager: %v", err)
+	}
+	must := func(err error) {
+		t.Helper()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	must(mgr.CreateUser(ctx, &User{ID: "alice", Username: "alice", Email: "alice@example.com"}))
+	must(mgr.CreateUser(ctx, &User{ID: "bob", Username: "bob", Email: "bob@example.com"}))
+	must(mgr.CreatePermission(ctx, &Permission{ID: "no-secrets", Resource: "public/secret", Action: ActionRead, Effect: EffectDeny}))
+	must(mgr.CreateRole(ctx, &Role{ID: "reader", Name: "reader"}))
+	must(mgr.AssignPermissionToRole(ctx, "reader", "no-secrets"))
+	must(mgr.AssignRoleToUser(ctx, "bob", "reader"))
+
+	owner := AuthorizerFunc(func(ctx context.Context, m *Manager, req *AuthorizeRequest) (*Decision, error) {
+		if strings.HasPrefix(req.Resource, "docs/"+req.UserID+"/") {
+			return &Decision{Allowed: true, Authorizer: "owner"}, nil
+		}
+		return nil, nil
+	})
+	public := AuthorizerFunc(func(ctx context.Context, m *Manager, req *AuthorizeRequest) (*Decision, error) {
+		if strings.HasPrefix(req.Resource, "public/") && req.Action == ActionRead {
+			return &Decision{Allowed: true}, nil
+		}
+		return nil, nil
+	})
+	mgr.Authorizers = slices.Insert(DefaultAuthorizers(), 1, Authorizer(owner))
+	mgr.Authorizers = append(mgr.Authorizers, public)
+
+	for _, tc := range []struct {
+		user, resource string
+		want           bool
+	}{
+		{"alice", "docs/alice/1", true},
+		{"alice", "docs/bob/1", false},
+		{"alice", "public/readme", true},
+		// the deny rule settles the request before the public link runs
+		{"bob", "public/secret", false},
+		{"alice", "public/secret", true},
+	} {
+		got, err := mgr.Can(ctx, tc.user, tc.resource, ActionRead)
+		if err != nil || got != tc.want {
+			t.Errorf("Can(%s, %s): got %v, %v; want %v", tc.user, tc.resource, got, err, tc.want)
+		}
+	}
+
+	e, err := mgr.Explain(ctx, "alice", "docs/alice/1", ActionRead)
+	if err != nil || e.Authorizer != "owner" || e.Reason != "allowed by authorizer owner" {
+		t.Errorf("Explain: got %+v, %v; want it allowed by the owner authorizer", e, err)
+	}
+
+	// links after ActiveUserAuthorizer do not see suspended users
+	must(mgr.SuspendUser(ctx, "alice"))
+	if ok, err := mgr.Can(ctx, "alice", "docs/alice/1", ActionRead); err != nil || ok {
+		t.Errorf("suspended owner: got %v, %v; want denied", ok, err)
+	}
+
+	errBroken := errors.New("broken")
+	mgr.Authorizers = []Authorizer{AuthorizerFunc(func(context.Context, *Manager, *AuthorizeRequest) (*Decision, error) {
+		return nil, errBroken
+	})}
+	if _, err := mgr.Can(ctx, "bob", "public/readme", ActionRead); !errors.Is(err, errBroken) {
+		t.Errorf("failing link: got %v; want its error", err)
+	}
+	mgr.Authorizers = []Authorizer{}
+	if ok, err := mgr.Can(ctx, "bob", "public/readme", ActionRead); err != nil || ok {
+		t.Errorf("empty chain: got %v, %v; want denied", ok, err)
+	}
+}
@@ -0,0 +1,185 @@
+package rbac
+
+import (
+	"context"
+	"time"
+)
+
+// BulkUserRoleRepo is optionally implemented by user role repos that can
+// assign several roles to a user in one round trip. AddURs must leave the
+// store as calling AddUR for each role would.
+type BulkUserRoleRepo interface {
+	AddURs(ctx context.Context, userID string, roleIDs []string) error
+}
+
+// BulkRolePermissionRepo is optionally implemented by role permission repos
+// that can bind several permissions to a role in one round trip. AddRPs must
+// leave the store as calling AddRP for each permission would.
+type BulkRolePermissionRepo interface {
+	AddRPs(ctx context.Context, roleID string, permIDs []string) error
+}
+
+// BulkUserGroupRepo is optionally implemented by user group repos that can
+// add several memberships in one round trip. AddUsersToGroups must leave the
+// store as calling AddUserToGroup for each membership would.
+type BulkUserGroupRepo interface {
+	AddUsersToGroups(ctx context.Context, ugs []*UserGroup) error
+}
+
+// AssignRolesToUser gives userID every role in roleIDs, e.g. when
+// onboarding. The roles are checked as AssignRoleToUser checks one, but
+// together, so separation-of-duties constraints and Limits.MaxRolesPerUser
+// also apply among them; when a check fails none is assigned. It runs in a
+// transaction when the store supports them.
+func (m *Manager) AssignRolesToUser(ctx context.Context, userID string, roleIDs []string) error {
+	start := time.Now()
+	ctx, span := m.startSpan(ctx, "AssignRolesToUser")
+	defer span.End()
+	roleIDs = uniqueIDs(roleIDs)
+	err := m.inTransaction(ctx, func(ctx context.Context) error {
+		return m.assignRolesToUser(ctx, userID, roleIDs)
+	})
+	m.record(ctx, start, "AssignRolesToUser", err)
+	m.changedUser(ctx, "AssignRolesToUser", userID, err)
+	for _, roleID := range roleIDs {
+		m.emit(err, func(l Listener) { l.OnRoleAssigned(ctx, userID, roleID) })
+	}
+	return err
+}
+
+func (m *Manager) assignRolesToUser(ctx context.Context, userID string, roleIDs []string) error {
+	for _, roleID := range roleIDs {
+		if err := m.checkAssignable(ctx, roleID); err != nil {
+			return err
+		}
+	}
+	if err := m.checkDuties(ctx, userID, roleIDs...); err != nil {
+		return err
+	}
+	if err := m.checkRoleLimit(ctx, userID, roleIDs...); err != nil {
+		return err
+	}
+	if bulk, ok := m.UR.(BulkUserRoleRepo); ok {
+		return bulk.AddURs(ctx, userID, roleIDs)
+	}
+	for _, roleID := range roleIDs {
+		if err := m.UR.AddUR(ctx, userID, roleID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AssignPermissionsToRole binds every permission in permIDs to roleID, as
+// AssignPermissionToRole binds one. It runs in a transaction when the store
+// supports them.
+func (m *Manager) AssignPermissionsToRole(ctx context.Context, roleID string, permIDs []string) error {
+	start := time.Now()
+	ctx, span := m.startSpan(ctx, "AssignPermissionsToRole")
+	defer span.End()
+	permIDs = uniqueIDs(permIDs)
+	err := m.inTransaction(ctx, func(ctx context.Context) error {
+		if bulk, ok := m.RP.(BulkRolePermissionRepo); ok {
+			return bulk.AddRPs(ctx, roleID, permIDs)
+		}
+		for _, permID := range permIDs {
+			if err := m.RP.AddRP(ctx, roleID, permID); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	m.record(ctx, start, "AssignPermissionsToRole", err)
+	m.changedRole(ctx, "AssignPermissionsToRole", roleID, err)
+	for _, permID := range permIDs {
+		m.emit(err, func(l Listener) { l.OnPermissionAssigned(ctx, roleID, permID) })
+	}
+	if err == nil && m.Catalog != nil {
+		for _, permID := range permIDs {
+			p, perr := m.Perms.GetPermissionByID(ctx, permID)
+			if perr != nil {
+				m.record(ctx, start, "NotifyOwners", perr)
+			}
+			m.notifyOwners(ctx, ChangePermissionAttached, p, roleID)
+		}
+	}
+	return err
+}
+
+// AddUsersToGroup adds every membership in ugs, as AddUserToGroup adds one,
+// granting group DefaultRoles too. The memberships are checked together, so
+// Limits.MaxMembersPerGroup applies to the whole batch; when a check fails
+// none is added. It runs in a transaction when the store supports them.
+func (m *Manager) AddUsersToGroup(ctx context.Context, ugs []*UserGroup) error {
+	start := time.Now()
+	ctx, span := m.startSpan(ctx, "AddUsersToGroup")
+	defer span.End()
+	err := m.inTransaction(ctx, func(ctx context.Context) error {
+		return m.addUsersToGroup(ctx, start, ugs)
+	})
+	m.record(ctx, start, "AddUsersToGroup", err)
+	for _, ug := range ugs {
+		m.changedUser(ctx, "AddUsersToGroup", ug.UserID, err)
+		m.emit(err, func(l Listener) { l.OnUserAddedToGroup(ctx, ug) })
+	}
+	return err
+}
+
+func (m *Manager) addUsersToGroup(ctx context.Context, start time.Time, ugs []*UserGroup) error {
+	byGroup := map[string][]string{}
+	var groups []string
+	for _, ug := range ugs {
+		if err := checkMembershipLevel(ug.Level); err != nil {
+			return err
+		}
+		if err := m.checkBan(ctx, ug); err != nil {
+			return err
+		}
+		if err := m.checkJoinDuties(ctx, ug); err != nil {
+			return err
+		}
+		if _, ok := byGroup[ug.GroupName]; !ok {
+			groups = append(groups, ug.GroupName)
+		}
+		byGroup[ug.GroupName] = append(byGroup[ug.GroupName], ug.UserID)
+	}
+	for _, group := range groups {
+		if err := m.checkMemberLimit(ctx, group, byGroup[group]...); err != nil {
+			return err
+		}
+	}
+	for _, ug := range ugs {
+		m.assignID(&ug.ID, KindUserGroup)
+		stampCreated(ctx, start, &ug.CreatedBy, &ug.UpdatedBy, &ug.UpdatedAt)
+	}
+	if bulk, ok := m.UG.(BulkUserGroupRepo); ok {
+		if err := bulk.AddUsersToGroups(ctx, ugs); err != nil {
+			return err
+		}
+	} else {
+		for _, ug := range ugs {
+			if err := m.UG.AddUserToGroup(ctx, ug); err != nil {
+				return err
+			}
+		}
+	}
+	for _, ug := range ugs {
+		if err := m.grantGroupDefaults(ctx, ug); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// uniqueIDs returns ids without repeats, in their first order.
+func uniqueIDs(ids []string) []string {
+	seen := make(map[string]bool, len(ids))
+	out := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			out = append(out, id)
+		}
+	}
+	return out
+}
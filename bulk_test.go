@@ -0,0 +1,100 @@
+package rbac
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestBulkAssignments(t *testing.T) {
+	ctx := context.Background()
+	memory, err := NewMemoryStoreManager(ctx, "", 0)
+	if err != nil {
+		t.Fatalf("NewMemoryStoreManager: %v", err)
+	}
+	for name, mgr := range map[string]*Manager{"memory": memory, "mock": NewMockRepoManager(NewMockRepo())} {
+		t.Run(name, func(t *testing.T) {
+			var roles []string
+			for _, n := range []string{"dev", "ops", "approver", "requester"} {
+				r := &Role{Name: n}
+				if err := mgr.CreateRole(ctx, r); err != nil {
+					t.Fatalf("CreateRole: %v", err)
+				}
+				roles = append(roles, r.ID)
+			}
+			dev, ops, approver, requester := roles[0], roles[1], roles[2], roles[3]
+
+			if err := mgr.AssignRolesToUser(ctx, "alice", []string{dev, ops, dev}); err != nil {
+				t.Fatalf("AssignRolesToUser: %v", err)
+			}
+			held, err := mgr.ListRolesForUser(ctx, "alice")
+			if err != nil || !slices.Contains(held, dev) || !slices.Contains(held, ops) {
+				t.Errorf("alice holds %v, %v; want dev and ops", held, err)
+			}
+
+			// conflicting roles in one batch are rejected together
+			if err := mgr.CreateSoDConstraint(ctx, &SoDConstraint{Name: "payments", Roles: []string{approver, requester}}); err != nil {
+				t.Fatalf("CreateSoDConstraint: %v", err)
+			}
+			if err := mgr.AssignRolesToUser(ctx, "bob", []string{dev, approver, requester}); !errors.Is(err, ErrSoDConflict) {
+				t.Errorf("AssignRolesToUser(conflicting) = %v; want ErrSoDConflict", err)
+			}
+			if held, _ := mgr.ListRolesForUser(ctx, "bob"); slices.Contains(held, dev) {
+				t.Errorf("bob holds %v after a rejected batch; want none of it", held)
+			}
+
+			read := &Permission{Resource: "docs/*", Action: ActionRead}
+			update := &Permission{Resource: "docs/*", Action: ActionUpdate}
+			for _, p := range []*Permission{read, update} {
+				if err := mgr.CreatePermission(ctx, p); err != nil {
+					t.Fatalf("CreatePermission: %v", err)
+				}
+			}
+			if err := mgr.AssignPermissionsToRole(ctx, dev, []string{read.ID, update.ID}); err != nil {
+				t.Fatalf("AssignPermissionsToRole: %v", err)
+			}
+			if ok, err := mgr.Can(ctx, "alice", "docs/1", ActionUpdate); err != nil || !ok {
+				t.Errorf("Can(alice, update) = %v, %v; want true", ok, err)
+			}
+
+			ugs := []*UserGroup{{UserID: "carol", GroupName: "team"}, {UserID: "dave", GroupName: "team"}}
+			if err := mgr.AddUsersToGroup(ctx, ugs); err != nil {
+				t.Fatalf("AddUsersToGroup: %v", err)
+			}
+			members, err := mgr.GetUsersByGroupID(ctx, "team")
+			if err != nil || len(members) != 2 {
+				t.Errorf("team has %d members, %v; want 2", len(members), err)
+			}
+			for _, ug := range ugs {
+				if ug.ID == "" {
+					t.Errorf("membership of %s has no ID", ug.UserID)
+				}
+			}
+		})
+	}
+}
+
+func TestBulkAssignmentLimits(t *testing.T) {
+	ctx := context.Background()
+	mgr := NewMockRepoManager(NewMockRepo())
+	mgr.Limits = Limits{MaxRolesPerUser: 2, MaxMembersPerGroup: 2}
+
+	if err := mgr.AssignRolesToUser(ctx, "alice", []string{"a", "b", "c"}); !errors.Is(err, ErrLimitExceeded) {
+		t.Errorf("AssignRolesToUser(3 roles) = %v; want ErrLimitExceeded", err)
+	}
+	if err := mgr.AssignRolesToUser(ctx, "alice", []string{"a", "b"}); err != nil {
+		t.Errorf("AssignRolesToUser(2 roles) = %v", err)
+	}
+	if err := mgr.AssignRolesToUser(ctx, "alice", []string{"a", "b"}); err != nil {
+		t.Errorf("AssignRolesToUser(held roles) = %v; want reassigning allowed", err)
+	}
+
+	ugs := []*UserGroup{{UserID: "u1", GroupName: "team"}, {UserID: "u2", GroupName: "team"}, {UserID: "u3", GroupName: "team"}}
+	if err := mgr.AddUsersToGroup(ctx, ugs); !errors.Is(err, ErrLimitExceeded) {
+		t.Errorf("AddUsersToGroup(3 members) = %v; want ErrLimitExceeded", err)
+	}
+	if members, _ := mgr.GetUsersByGroupID(ctx, "team"); len(members) != 0 {
+		t.Errorf("team has %d members after a rejected batch; want 0", len(members))
+	}
+}
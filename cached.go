@@ -0,0 +1,755 @@
+// file: rbac/cached_store.go
+package rbac
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	_ Store                   = (*CachedStore)(nil)
+	_ RolePermissionDetailer  = (*CachedStore)(nil)
+	_ ScheduledUserRoleRepo   = (*CachedStore)(nil)
+	_ ScopedUserRoleRepo      = (*CachedStore)(nil)
+	_ ScopedGroupRoleRepo     = (*CachedStore)(nil)
+	_ ConstrainedUserRoleRepo = (*CachedStore)(nil)
+	_ GroupBanRepo            = (*CachedStore)(nil)
+	_ RoleHierarchyRepo       = (*CachedStore)(nil)
+	_ EdgeSourceRepo          = (*CachedStore)(nil)
+	_ ExportPager             = (*CachedStore)(nil)
+	_ UserStatusRepo          = (*CachedStore)(nil)
+	_ EmailVerificationRepo   = (*CachedStore)(nil)
+	_ HealthChecker           = (*CachedStore)(nil)
+)
+
+// maxCacheEntries bounds each of a CachedStore's caches; expired entries are
+// swept when it is reached, and the cache is cleared if that is not enough.
+const maxCacheEntries = 100000
+
+// CachedStore wraps a Store with an in-process TTL cache of the reads Can
+// makes on every call: the roles of a user, the permissions and parents of a
+// role and permissions by ID. Writes made through the CachedStore invalidate the
+// entries they affect. Changes made to the backend by other processes are
+// picked up once the TTL has passed, or earlier via the Invalidate methods.
+type CachedStore struct {
+	Store
+	ttl time.Duration
+	now func() time.Time
+
+	userRoles   *ttlCache[[]string]
+	rolePerms   *ttlCache[[]string]
+	roleDetails *ttlCache[[]*Permission]
+	perms       *ttlCache[*Permission]
+	parents     *ttlCache[[]string]
+
+	hits, misses atomic.Uint64
+}
+
+// CacheStats counts a CachedStore's or DecisionCache's reads since it was
+// created.
+type CacheStats struct {
+	Hits   uint64 `json:"hits"`
+	Misses uint64 `json:"misses"`
+}
+
+// HitRate is the fraction of reads served from the cache, or 0 before the
+// first read.
+func (s CacheStats) HitRate() float64 {
+	if s.Hits+s.Misses == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(s.Hits+s.Misses)
+}
+
+// NewCachedStore wraps inner with caches whose entries live for ttl.
+func NewCachedStore(inner Store, ttl time.Duration) *CachedStore {
+	return &CachedStore{
+		Store:       inner,
+		ttl:         ttl,
+		now:         time.Now,
+		userRoles:   newTTLCache[[]string](),
+		rolePerms:   newTTLCache[[]string](),
+		roleDetails: newTTLCache[[]*Permission](),
+		perms:       newTTLCache[*Permission](),
+		parents:     newTTLCache[[]string](),
+	}
+}
+
+// NewCachedStoreManager wraps inner in a CachedStore and a Manager. Seeding
+// the default role is left to the inner store's own constructor.
+func NewCachedStoreManager(inner Store, ttl time.Duration) *Manager {
+	c := NewCachedStore(inner, ttl)
+	return &Manager{
+		Perms:           c,
+		Roles:           c,
+		Users:           c,
+		RP:              c,
+		UR:              c,
+		UG:              c,
+		GR:              c,
+		DefaultRoleName: "default",
+	}
+}
+
+// Stats returns the hits and misses of every cache so far.
+func (c *CachedStore) Stats() CacheStats {
+	return CacheStats{Hits: c.hits.Load(), Misses: c.misses.Load()}
+}
+
+// Health implements HealthChecker by checking the wrapped store; the cache
+// cannot serve without it once entries expire.
+func (c *CachedStore) Health(ctx context.Context) error {
+	return checkHealth(ctx, c.Store)
+}
+
+// InvalidateUser drops the cached roles of userID.
+func (c *CachedStore) InvalidateUser(userID string) {
+	c.userRoles.delete(userID)
+}
+
+// InvalidateRole drops the cached permissions and parents of roleID.
+func (c *CachedStore) InvalidateRole(roleID string) {
+	c.rolePerms.delete(roleID)
+	c.roleDetails.delete(roleID)
+	c.parents.delete(roleID)
+}
+
+// InvalidatePermission drops permID and every role's cached permission
+// details, since any of them may include it.
+func (c *CachedStore) InvalidatePermission(permID string) {
+	c.perms.delete(permID)
+	c.roleDetails.clear()
+}
+
+// InvalidateAll empties every cache.
+func (c *CachedStore) InvalidateAll() {
+	c.userRoles.clear()
+	c.rolePerms.clear()
+	c.roleDetails.clear()
+	c.perms.clear()
+	c.parents.clear()
+}
+
+// InvalidateChange drops the entries a store change may have made stale.
+// Events without IDs, such as MongoDB deletes without pre-images, clear
+// everything of their kind.
+func (c *CachedStore) InvalidateChange(ev ChangeEvent) {
+	switch ev.Kind {
+	case KindPermission:
+		if ev.ID == "" {
+			c.perms.clear()
+			c.roleDetails.clear()
+		} else {
+			c.InvalidatePermission(ev.ID)
+		}
+		if ev.Op == ChangeDelete {
+			c.rolePerms.clear()
+		}
+	case KindRole:
+		if ev.ID == "" {
+			c.rolePerms.clear()
+			c.roleDetails.clear()
+		} else {
+			c.InvalidateRole(ev.ID)
+		}
+		if ev.Op == ChangeDelete {
+			c.userRoles.clear()
+		}
+	case KindUser:
+		if ev.ID == "" {
+			c.userRoles.clear()
+		} else {
+			c.InvalidateUser(ev.ID)
+		}
+	case KindUserRole:
+		if ev.UserID == "" {
+			c.userRoles.clear()
+		} else {
+			c.InvalidateUser(ev.UserID)
+		}
+	case KindRolePermission:
+		if ev.RoleID == "" {
+			c.rolePerms.clear()
+			c.roleDetails.clear()
+		} else {
+			c.InvalidateRole(ev.RoleID)
+		}
+	}
+}
+
+// Follow invalidates the cache from w's change events until ctx is cancelled
+// or the stream ends, and clears the cache then since later changes would go
+// unnoticed until the TTL passes. It blocks until then.
+func (c *CachedStore) Follow(ctx context.Context, w Watcher) error {
+	events, err := w.Watch(ctx)
+	if err != nil {
+		return err
+	}
+	for ev := range events {
+		c.InvalidateChange(ev)
+	}
+	c.InvalidateAll()
+	return ctx.Err()
+}
+
+//
+// ---------- PermissionRepo ----------
+//
+
+func (c *CachedStore) CreatePermission(ctx context.Context, p *Permission) error {
+	err := c.Store.CreatePermission(ctx, p)
+	c.InvalidatePermission(p.ID)
+	return err
+}
+
+func (c *CachedStore) DeletePermission(ctx context.Context, id string) error {
+	err := c.Store.DeletePermission(ctx, id)
+	c.InvalidatePermission(id)
+	// Backends drop the permission's role bindings with it.
+	c.rolePerms.clear()
+	return err
+}
+
+func (c *CachedStore) GetPermissionByID(ctx context.Context, id string) (*Permission, error) {
+	p, err := cachedRead(ctx, c, c.perms, id, func() (*Permission, error) {
+		return c.Store.GetPermissionByID(ctx, id)
+	})
+	if p == nil {
+		return nil, err
+	}
+	cp := *p
+	return &cp, err
+}
+
+//
+// ---------- RoleRepo ----------
+//
+
+func (c *CachedStore) DeleteRole(ctx context.Context, id string) error {
+	err := c.Store.DeleteRole(ctx, id)
+	c.InvalidateRole(id)
+	// Backends drop the role's user assignments and inheritance with it.
+	c.userRoles.clear()
+	c.parents.clear()
+	return err
+}
+
+func (c *CachedStore) AddRoleParent(ctx context.Context, roleID, parentID string) error {
+	repo, ok := c.Store.(RoleHierarchyRepo)
+	if !ok {
+		return errHierarchyUnsupported
+	}
+	err := repo.AddRoleParent(ctx, roleID, parentID)
+	c.parents.delete(roleID)
+	return err
+}
+
+func (c *CachedStore) RemoveRoleParent(ctx context.Context, roleID, parentID string) error {
+	repo, ok := c.Store.(RoleHierarchyRepo)
+	if !ok {
+		return errHierarchyUnsupported
+	}
+	err := repo.RemoveRoleParent(ctx, roleID, parentID)
+	c.parents.delete(roleID)
+	return err
+}
+
+func (c *CachedStore) ListRoleParents(ctx context.Context, roleID string) ([]string, error) {
+	repo, ok := c.Store.(RoleHierarchyRepo)
+	if !ok {
+		return nil, errHierarchyUnsupported
+	}
+	parents, err := cachedRead(ctx, c, c.parents, roleID, func() ([]string, error) {
+		return repo.ListRoleParents(ctx, roleID)
+	})
+	return slices.Clone(parents), err
+}
+
+//
+// ---------- UserRepo ----------
+//
+
+func (c *CachedStore) DeleteUser(ctx context.Context, id string) error {
+	err := c.Store.DeleteUser(ctx, id)
+	c.InvalidateUser(id)
+	return err
+}
+
+// SetUserStatus passes through; users are not cached.
+func (c *CachedStore) SetUserStatus(ctx context.Context, id string, status UserStatus) error {
+	if repo, ok := c.Store.(UserStatusRepo); ok {
+		return repo.SetUserStatus(ctx, id, status)
+	}
+	return errUserStatusUnsupported
+}
+
+// SetEmailVerified passes through too.
+func (c *CachedStore) SetEmailVerified(ctx context.Context, id string, verified bool) error {
+	if repo, ok := c.Store.(EmailVerificationRepo); ok {
+		return repo.SetEmailVerified(ctx, id, verified)
+	}
+	return errEmailVerificationUnsupported
+}
+
+//
+// ---------- RolePermissionRepo ----------
+//
+
+func (c *CachedStore) AddRP(ctx context.Context, roleID, permID string) error {
+	err := c.Store.AddRP(ctx, roleID, permID)
+	c.InvalidateRole(roleID)
+	return err
+}
+
+func (c *CachedStore) Remove(ctx context.Context, roleID, permID string) error {
+	err := c.Store.Remove(ctx, roleID, permID)
+	c.InvalidateRole(roleID)
+	return err
+}
+
+func (c *CachedStore) ListPermissions(ctx context.Context, roleID string) ([]string, error) {
+	ids, err := cachedRead(ctx, c, c.rolePerms, roleID, func() ([]string, error) {
+		return c.Store.ListPermissions(ctx, roleID)
+	})
+	return slices.Clone(ids), err
+}
+
+// ListPermissionDetails serves Can's per-role permission lookup from the
+// cache, filling it in one call when the inner store implements
+// RolePermissionDetailer.
+func (c *CachedStore) ListPermissionDetails(ctx context.Context, roleID string) ([]*Permission, error) {
+	perms, err := cachedRead(ctx, c, c.roleDetails, roleID, func() ([]*Permission, error) {
+		if d, ok := c.Store.(RolePermissionDetailer); ok {
+			return d.ListPermissionDetails(ctx, roleID)
+		}
+		ids, err := c.ListPermissions(ctx, roleID)
+		if err != nil {
+			return nil, err
+		}
+		out := make([]*Permission, 0, len(ids))
+		for _, id := range ids {
+			p, err := c.GetPermissionByID(ctx, id)
+			if err != nil {
+				return nil, err
+			}
+			if p != nil {
+				out = append(out, p)
+			}
+		}
+		return out, nil
+	})
+	out := make([]*Permission, len(perms))
+	for i, p := range perms {
+		cp := *p
+		out[i] = &cp
+	}
+	return out, err
+}
+
+//
+// ---------- UserRoleRepo ----------
+//
+
+func (c *CachedStore) AddUR(ctx context.Context, userID, roleID string) error {
+	err := c.Store.AddUR(ctx, userID, roleID)
+	c.InvalidateUser(userID)
+	return err
+}
+
+func (c *CachedStore) RemoveUR(ctx context.Context, userID, roleID string) error {
+	err := c.Store.RemoveUR(ctx, userID, roleID)
+	c.InvalidateUser(userID)
+	return err
+}
+
+// ListRoles caches the user's active roles. A scheduled assignment that
+// starts or ends while cached takes effect once the entry expires.
+func (c *CachedStore) ListRoles(ctx context.Context, userID string) ([]string, error) {
+	roles, err := cachedRead(ctx, c, c.userRoles, userID, func() ([]string, error) {
+		return c.Store.ListRoles(ctx, userID)
+	})
+	return slices.Clone(roles), err
+}
+
+func (c *CachedStore) AddScheduledUR(ctx context.Context, a *RoleAssignment) error {
+	repo, ok := c.Store.(ScheduledUserRoleRepo)
+	if !ok {
+		return errSchedulingUnsupported
+	}
+	err := repo.AddScheduledUR(ctx, a)
+	c.InvalidateUser(a.UserID)
+	return err
+}
+
+func (c *CachedStore) ListRoleAssignments(ctx context.Context, userID string) ([]*RoleAssignment, error) {
+	repo, ok := c.Store.(ScheduledUserRoleRepo)
+	if !ok {
+		return nil, errSchedulingUnsupported
+	}
+	return repo.ListRoleAssignments(ctx, userID)
+}
+
+// Scoped assignments are not cached; they are read from the inner store.
+
+func (c *CachedStore) AddScopedUR(ctx context.Context, userID, roleID, scope string) error {
+	repo, ok := c.Store.(ScopedUserRoleRepo)
+	if !ok {
+		return errScopeUnsupported
+	}
+	return repo.AddScopedUR(ctx, userID, roleID, scope)
+}
+
+func (c *CachedStore) RemoveScopedUR(ctx context.Context, userID, roleID, scope string) error {
+	repo, ok := c.Store.(ScopedUserRoleRepo)
+	if !ok {
+		return errScopeUnsupported
+	}
+	return repo.RemoveScopedUR(ctx, userID, roleID, scope)
+}
+
+func (c *CachedStore) ListScopedRoles(ctx context.Context, userID string) ([]ScopedRole, error) {
+	repo, ok := c.Store.(ScopedUserRoleRepo)
+	if !ok {
+		return nil, errScopeUnsupported
+	}
+	return repo.ListScopedRoles(ctx, userID)
+}
+
+// Constrained assignments are not cached either.
+
+func (c *CachedStore) AddConstrainedUR(ctx context.Context, userID, roleID string, rc RoleConstraints) error {
+	repo, ok := c.Store.(ConstrainedUserRoleRepo)
+	if !ok {
+		return errConstraintsUnsupported
+	}
+	return repo.AddConstrainedUR(ctx, userID, roleID, rc)
+}
+
+func (c *CachedStore) RemoveConstrainedUR(ctx context.Context, userID, roleID string) error {
+	repo, ok := c.Store.(ConstrainedUserRoleRepo)
+	if !ok {
+		return errConstraintsUnsupported
+	}
+	return repo.RemoveConstrainedUR(ctx, userID, roleID)
+}
+
+func (c *CachedStore) ListConstrainedRoles(ctx context.Context, userID string) ([]ConstrainedRole, error) {
+	repo, ok := c.Store.(ConstrainedUserRoleRepo)
+	if !ok {
+		return nil, errConstraintsUnsupported
+	}
+	return repo.ListConstrainedRoles(ctx, userID)
+}
+
+// Group bans are read from the inner store too.
+
+func (c *CachedStore) AddGroupBan(ctx context.Context, b *GroupBan) error {
+	repo, ok := c.Store.(GroupBanRepo)
+	if !ok {
+		return errBansUnsupported
+	}
+	return repo.AddGroupBan(ctx, b)
+}
+
+func (c *CachedStore) RemoveGroupBan(ctx context.Context, groupName, userID string) error {
+	repo, ok := c.Store.(GroupBanRepo)
+	if !ok {
+		return errBansUnsupported
+	}
+	return repo.RemoveGroupBan(ctx, groupName, userID)
+}
+
+func (c *CachedStore) ListGroupBans(ctx context.Context, groupName string) ([]*GroupBan, error) {
+	repo, ok := c.Store.(GroupBanRepo)
+	if !ok {
+		return nil, errBansUnsupported
+	}
+	return repo.ListGroupBans(ctx, groupName)
+}
+
+func (c *CachedStore) ListUserBans(ctx context.Context, userID string) ([]*GroupBan, error) {
+	repo, ok := c.Store.(GroupBanRepo)
+	if !ok {
+		return nil, errBansUnsupported
+	}
+	return repo.ListUserBans(ctx, userID)
+}
+
+func (c *CachedStore) AddScopedRoleToGroup(ctx context.Context, groupID, roleID, scope string) error {
+	repo, ok := c.Store.(ScopedGroupRoleRepo)
+	if !ok {
+		return errScopeUnsupported
+	}
+	return repo.AddScopedRoleToGroup(ctx, groupID, roleID, scope)
+}
+
+func (c *CachedStore) RemoveScopedRoleFromGroup(ctx context.Context, groupID, roleID, scope string) error {
+	repo, ok := c.Store.(ScopedGroupRoleRepo)
+	if !ok {
+		return errScopeUnsupported
+	}
+	return repo.RemoveScopedRoleFromGroup(ctx, groupID, roleID, scope)
+}
+
+func (c *CachedStore) ListScopedRolesForGroup(ctx context.Context, groupID string) ([]ScopedRole, error) {
+	repo, ok := c.Store.(ScopedGroupRoleRepo)
+	if !ok {
+		return nil, errScopeUnsupported
+	}
+	return repo.ListScopedRolesForGroup(ctx, groupID)
+}
+
+func (c *CachedStore) EdgeSource(ctx context.Context, kind, from, to string) (string, error) {
+	repo, ok := c.Store.(EdgeSourceRepo)
+	if !ok {
+		return "", errSourceUnsupported
+	}
+	return repo.EdgeSource(ctx, kind, from, to)
+}
+
+// ExportPage reads from the inner store, bypassing the cache, so an export
+// does not evict the entries Can relies on.
+func (c *CachedStore) ExportPage(ctx context.Context, kind, after string, limit int) ([]ExportItem, error) {
+	pager, ok := c.Store.(ExportPager)
+	if !ok {
+		return nil, errExportUnsupported
+	}
+	return pager.ExportPage(ctx, kind, after, limit)
+}
+
+//
+// ---------- Persistence ----------
+//
+
+// cacheFile is the on-disk form of a CachedStore's entries. Expiry times are
+// unix milliseconds.
+type cacheFile struct {
+	SavedAt     int64                        `json:"saved_at"`
+	UserRoles   []cacheRecord[[]string]      `json:"user_roles,omitempty"`
+	RolePerms   []cacheRecord[[]string]      `json:"role_permissions,omitempty"`
+	RoleDetails []cacheRecord[[]*Permission] `json:"role_permission_details,omitempty"`
+	Perms       []cacheRecord[*Permission]   `json:"permissions,omitempty"`
+}
+
+type cacheRecord[V any] struct {
+	Key     string `json:"key"`
+	Value   V      `json:"value"`
+	Expires int64  `json:"expires"`
+}
+
+// WriteCache writes the live cache entries to w as JSON.
+func (c *CachedStore) WriteCache(w io.Writer) error {
+	now := c.now()
+	return json.NewEncoder(w).Encode(cacheFile{
+		SavedAt:     now.UnixMilli(),
+		UserRoles:   c.userRoles.records(now),
+		RolePerms:   c.rolePerms.records(now),
+		RoleDetails: c.roleDetails.records(now),
+		Perms:       c.perms.records(now),
+	})
+}
+
+// LoadCache adds the entries written by WriteCache to the cache. Entries keep
+// their original expiry, capped at the TTL from now, so a reload never serves
+// data older than the TTL allows. A file saved more than maxAge ago is
+// ignored entirely; zero disables that check.
+func (c *CachedStore) LoadCache(r io.Reader, maxAge time.Duration) error {
+	var f cacheFile
+	if err := json.NewDecoder(r).Decode(&f); err != nil {
+		return fmt.Errorf("cached_store: %w", err)
+	}
+	now := c.now()
+	if maxAge > 0 && now.Sub(time.UnixMilli(f.SavedAt)) > maxAge {
+		return nil
+	}
+	limit := now.Add(c.ttl)
+	c.userRoles.load(f.UserRoles, now, limit)
+	c.rolePerms.load(f.RolePerms, now, limit)
+	c.roleDetails.load(f.RoleDetails, now, limit)
+	c.perms.load(f.Perms, now, limit)
+	return nil
+}
+
+// SaveCacheFile writes the cache to path. The file is replaced atomically.
+func (c *CachedStore) SaveCacheFile(path string) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+"-*")
+	if err != nil {
+		return fmt.Errorf("cached_store: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if err := c.WriteCache(tmp); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("cached_store: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("cached_store: %w", err)
+	}
+	return nil
+}
+
+// LoadCacheFile loads a cache saved by SaveCacheFile. A missing file is not
+// an error, since there is nothing to warm on a first start.
+func (c *CachedStore) LoadCacheFile(path string, maxAge time.Duration) error {
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("cached_store: %w", err)
+	}
+	defer f.Close()
+	return c.LoadCache(f, maxAge)
+}
+
+// PersistCache warms the cache from path and saves it back there when ctx is
+// cancelled, so a restarted service does not start cold. Files older than
+// maxAge are ignored.
+func (c *CachedStore) PersistCache(ctx context.Context, path string, maxAge time.Duration) error {
+	if err := c.LoadCacheFile(path, maxAge); err != nil {
+		return err
+	}
+	go func() {
+		<-ctx.Done()
+		if err := c.SaveCacheFile(path); err != nil {
+			log.Printf("cached_store: save cache: %v", err)
+		}
+	}()
+	return nil
+}
+
+//
+// ---------- cache ----------
+//
+
+// cachedRead returns the live entry for key or loads it with fill. Errors
+// are not cached, and neither is a result whose load overlapped an
+// invalidation of the cache. Hits and misses are counted on the decision
+// trace in ctx, if any.
+func cachedRead[V any](ctx context.Context, c *CachedStore, tc *ttlCache[V], key string, fill func() (V, error)) (V, error) {
+	now := c.now()
+	tr := decisionTraceFrom(ctx)
+	if v, ok := tc.get(key, now); ok {
+		c.hits.Add(1)
+		tr.cacheHit()
+		return v, nil
+	}
+	c.misses.Add(1)
+	tr.cacheMiss()
+	gen := tc.generation()
+	v, err := fill()
+	if err != nil {
+		return v, err
+	}
+	tc.put(key, v, now, now.Add(c.ttl), gen)
+	return v, nil
+}
+
+type ttlEntry[V any] struct {
+	value   V
+	expires time.Time
+}
+
+type ttlCache[V any] struct {
+	mu      sync.Mutex
+	gen     uint64
+	entries map[string]ttlEntry[V]
+}
+
+func newTTLCache[V any]() *ttlCache[V] {
+	return &ttlCache[V]{entries: map[string]ttlEntry[V]{}}
+}
+
+func (t *ttlCache[V]) get(key string, now time.Time) (V, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	e, ok := t.entries[key]
+	if !ok || !now.Before(e.expires) {
+		var zero V
+		return zero, false
+	}
+	return e.value, true
+}
+
+func (t *ttlCache[V]) generation() uint64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.gen
+}
+
+// put stores value unless the cache was invalidated after gen was read.
+func (t *ttlCache[V]) put(key string, value V, now, expires time.Time, gen uint64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if gen != t.gen {
+		return
+	}
+	if len(t.entries) >= maxCacheEntries {
+		for k, e := range t.entries {
+			if !now.Before(e.expires) {
+				delete(t.entries, k)
+			}
+		}
+		if len(t.entries) >= maxCacheEntries {
+			t.entries = map[string]ttlEntry[V]{}
+		}
+	}
+	t.entries[key] = ttlEntry[V]{value: value, expires: expires}
+}
+
+func (t *ttlCache[V]) delete(key string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.gen++
+	delete(t.entries, key)
+}
+
+func (t *ttlCache[V]) clear() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.gen++
+	t.entries = map[string]ttlEntry[V]{}
+}
+
+// records returns the entries that are still live at now.
+func (t *ttlCache[V]) records(now time.Time) []cacheRecord[V] {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	out := make([]cacheRecord[V], 0, len(t.entries))
+	for k, e := range t.entries {
+		if now.Before(e.expires) {
+			out = append(out, cacheRecord[V]{Key: k, Value: e.value, Expires: e.expires.UnixMilli()})
+		}
+	}
+	return out
+}
+
+// load adds the records that are live at now, expiring no later than limit.
+// Entries already in the cache are newer and win.
+func (t *ttlCache[V]) load(recs []cacheRecord[V], now, limit time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, r := range recs {
+		expires := time.UnixMilli(r.Expires)
+		if expires.After(limit) {
+			expires = limit
+		}
+		if _, ok := t.entries[r.Key]; ok || !now.Before(expires) || len(t.entries) >= maxCacheEntries {
+			continue
+		}
+		t.entries[r.Key] = ttlEntry[V]{value: r.Value, expires: expires}
+	}
+}
@@ -0,0 +1,234 @@
+package rbac
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// countingStore counts the reads Can makes against the backend.
+type countingStore struct {
+	Store
+	listRoles, listPerms, getPerm int
+}
+
+func (s *countingStore) ListRoles(ctx context.Context, userID string) ([]string, error) {
+	s.listRoles++
+	return s.Store.ListRoles(ctx, userID)
+}
+
+func (s *countingStore) ListPermissions(ctx context.Context, roleID string) ([]string, error) {
+	s.listPerms++
+	return s.Store.ListPermissions(ctx, roleID)
+}
+
+func (s *countingStore) GetPermissionByID(ctx context.Context, id string) (*Permission, error) {
+	s.getPerm++
+	return s.Store.GetPermissionByID(ctx, id)
+}
+
+func TestCachedStore(t *testing.T) {
+	ctx := context.Background()
+	inner := &countingStore{Store: NewMockRepo()}
+	mgr := NewCachedStoreManager(inner, time.Minute)
+	cache := mgr.Perms.(*CachedStore)
+	now := time.Now()
+	cache.now = func() time.Time { return now }
+
+	perm := &Permission{ID: "p1", Resource: "survey", Action: ActionRead}
+	if err := mgr.CreatePermission(ctx, perm); err != nil {
+		t.Fatalf("CreatePermission: %v", err)
+	}
+	if err := mgr.CreateRole(ctx, &Role{ID: "r1", Name: "reader"}); err != nil {
+		t.Fatalf("CreateRole: %v", err)
+	}
+	if err := mgr.AssignPermissionToRole(ctx, "r1", "p1"); err != nil {
+		t.Fatalf("AssignPermissionToRole: %v", err)
+	}
+	if err := mgr.AssignRoleToUser(ctx, "alice", "r1"); err != nil {
+		t.Fatalf("AssignRoleToUser: %v", err)
+	}
+
+	can := func(want bool) {
+		t.Helper()
+		ok, err := mgr.Can(ctx, "alice", "survey", ActionRead)
+		if err != nil {
+			t.Fatalf("Can: %v", err)
+		}
+		if ok != want {
+			t.Fatalf("Can = %v, want %v", ok, want)
+		}
+	}
+
+	can(true)
+	reads := inner.listRoles + inner.listPerms + inner.getPerm
+	can(true)
+	if got := inner.listRoles + inner.listPerms + inner.getPerm; got != reads {
+		t.Errorf("expected a cached Can to skip the backend, reads went %d -> %d", reads, got)
+	}
+
+	// Writes through the cache invalidate what they touch.
+	if err := mgr.RemovePermissionFromRole(ctx, "r1", "p1"); err != nil {
+		t.Fatalf("RemovePermissionFromRole: %v", err)
+	}
+	can(false)
+	if err := mgr.AssignPermissionToRole(ctx, "r1", "p1"); err != nil {
+		t.Fatalf("AssignPermissionToRole: %v", err)
+	}
+	can(true)
+	if err := mgr.UnassignRoleFromUser(ctx, "alice", "r1"); err != nil {
+		t.Fatalf("UnassignRoleFromUser: %v", err)
+	}
+	can(false)
+
+	// Writes that bypass the cache show up after Invalidate or the TTL.
+	if err := inner.AddUR(ctx, "alice", "r1"); err != nil {
+		t.Fatalf("AddUR: %v", err)
+	}
+	can(false)
+	cache.InvalidateUser("alice")
+	can(true)
+
+	if err := inner.RemoveUR(ctx, "alice", "r1"); err != nil {
+		t.Fatalf("RemoveUR: %v", err)
+	}
+	can(true)
+	now = now.Add(time.Minute)
+	can(false)
+}
+
+func TestCachedStoreReturnsCopies(t *testing.T) {
+	ctx := context.Background()
+	cache := NewCachedStore(NewMockRepo(), time.Minute)
+	if err := cache.AddUR(ctx, "alice", "r1"); err != nil {
+		t.Fatalf("AddUR: %v", err)
+	}
+	roles, err := cache.ListRoles(ctx, "alice")
+	if err != nil {
+		t.Fatalf("ListRoles: %v", err)
+	}
+	roles[0] = "tampered"
+	if again, _ := cache.ListRoles(ctx, "alice"); again[0] != "r1" {
+		t.Errorf("expected the cached slice to be unaffected, got %v", again)
+	}
+}
+
+func TestCachedStorePersistence(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "rbac-cache.json")
+	now := time.Now()
+
+	inner := &countingStore{Store: NewMockRepo()}
+	if err := inner.AddUR(ctx, "alice", "r1"); err != nil {
+		t.Fatalf("AddUR: %v", err)
+	}
+	if err := inner.CreatePermission(ctx, &Permission{ID: "p1", Resource: "survey", Action: ActionRead}); err != nil {
+		t.Fatalf("CreatePermission: %v", err)
+	}
+
+	first := NewCachedStore(inner, time.Minute)
+	first.now = func() time.Time { return now }
+	runCtx, cancel := context.WithCancel(ctx)
+	if err := first.PersistCache(runCtx, path, time.Hour); err != nil {
+		t.Fatalf("PersistCache: %v", err)
+	}
+	if _, err := first.ListRoles(ctx, "alice"); err != nil {
+		t.Fatalf("ListRoles: %v", err)
+	}
+	if _, err := first.GetPermissionByID(ctx, "p1"); err != nil {
+		t.Fatalf("GetPermissionByID: %v", err)
+	}
+	cancel()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if _, err := os.Stat(path); err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("expected the cache to be saved on cancellation")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	load := func(at time.Time, maxAge time.Duration) *CachedStore {
+		t.Helper()
+		c := NewCachedStore(inner, time.Minute)
+		c.now = func() time.Time { return at }
+		if err := c.LoadCacheFile(path, maxAge); err != nil {
+			t.Fatalf("LoadCacheFile: %v", err)
+		}
+		return c
+	}
+
+	inner.listRoles, inner.getPerm = 0, 0
+	warm := load(now.Add(10*time.Second), time.Hour)
+	roles, _ := warm.ListRoles(ctx, "alice")
+	p, _ := warm.GetPermissionByID(ctx, "p1")
+	if len(roles) != 1 || roles[0] != "r1" || p == nil || p.Resource != "survey" {
+		t.Fatalf("unexpected warm entries: %v, %+v", roles, p)
+	}
+	if inner.listRoles != 0 || inner.getPerm != 0 {
+		t.Errorf("expected a warm start to skip the backend, got %d role and %d permission reads", inner.listRoles, inner.getPerm)
+	}
+
+	// Entries past their TTL, and files past maxAge, are not loaded.
+	_, _ = load(now.Add(2*time.Minute), time.Hour).ListRoles(ctx, "alice")
+	if inner.listRoles != 1 {
+		t.Errorf("expected expired entries to be dropped, backend reads = %d", inner.listRoles)
+	}
+	_, _ = load(now.Add(10*time.Second), time.Second).ListRoles(ctx, "alice")
+	if inner.listRoles != 2 {
+		t.Errorf("expected a stale file to be ignored, backend reads = %d", inner.listRoles)
+	}
+
+	if err := NewCachedStore(inner, time.Minute).LoadCacheFile(filepath.Join(t.TempDir(), "missing.json"), 0); err != nil {
+		t.Errorf("expected a missing file to be ignored, got %v", err)
+	}
+}
+
+// chanWatcher replays the events sent on its channel.
+type chanWatcher chan ChangeEvent
+
+func (w chanWatcher) Watch(ctx context.Context) (<-chan ChangeEvent, error) {
+	return w, nil
+}
+
+func TestCachedStoreInvalidateChange(t *testing.T) {
+	ctx := context.Background()
+	inner := &countingStore{Store: NewMockRepo()}
+	cache := NewCachedStore(inner, time.Hour)
+	readBoth := func() int {
+		t.Helper()
+		before := inner.listRoles
+		for _, uid := range []string{"alice", "bob"} {
+			if _, err := cache.ListRoles(ctx, uid); err != nil {
+				t.Fatalf("ListRoles: %v", err)
+			}
+		}
+		return inner.listRoles - before
+	}
+	readBoth()
+
+	cache.InvalidateChange(ChangeEvent{Kind: KindUserRole, Op: ChangeCreate, UserID: "alice", RoleID: "r1"})
+	if n := readBoth(); n != 1 {
+		t.Errorf("expected only alice to be refetched, got %d backend reads", n)
+	}
+	cache.InvalidateChange(ChangeEvent{Kind: KindUserRole, Op: ChangeDelete})
+	if n := readBoth(); n != 2 {
+		t.Errorf("expected an event without IDs to clear every user, got %d backend reads", n)
+	}
+
+	events := make(chanWatcher)
+	done := make(chan error)
+	go func() { done <- cache.Follow(ctx, events) }()
+	events <- ChangeEvent{Kind: KindUser, Op: ChangeDelete, ID: "bob"}
+	close(events)
+	if err := <-done; err != nil {
+		t.Fatalf("Follow: %v", err)
+	}
+	if n := readBoth(); n != 2 {
+		t.Errorf("expected Follow to clear the cache when the stream ended, got %d backend reads", n)
+	}
+}
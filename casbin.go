@@ -0,0 +1,665 @@
+package rbac
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Seann-Moser/rbac/rbaceval"
+)
+
+// CasbinModel is the Casbin model, in model.conf syntax, of the policies
+// ExportCasbin writes. Subjects are users, roles, and groups prefixed with
+// CasbinGroupPrefix; objects are regular expressions equivalent to the
+// permissions' resource patterns, and actions are globs as in Can.
+const CasbinModel = `[request_definition]
+r = sub, obj, act
+
+[policy_definition]
+p = sub, obj, act, eft
+
+[role_definition]
+g = _, _
+
+[policy_effect]
+e = some(where (p.eft == allow)) && !some(where (p.eft == deny))
+
+[matchers]
+m = g(r.sub, p.sub) && regexMatch(r.obj, p.obj) && globMatch(r.act, p.act)
+`
+
+// CasbinGroupPrefix marks a Casbin subject as a group: "g, alice,
+// group:writers" makes alice a member of writers, and "g, group:writers,
+// editor" binds the editor role to it.
+const CasbinGroupPrefix = "group:"
+
+// ErrUnsupportedCasbin is returned by ParseCasbin for a model or policy line
+// it cannot express as roles and permissions.
+var ErrUnsupportedCasbin = errors.New("rbac: unsupported casbin policy")
+
+// CasbinPolicy is a Casbin model and policy converted by ParseCasbin.
+type CasbinPolicy struct {
+	// Bundle holds the roles with their permissions and parents, and the
+	// groups with their roles.
+	Bundle *PolicyBundle
+	// UserRoles are the role IDs each user is assigned, and Memberships the
+	// group names each user belongs to.
+	UserRoles   map[string][]string
+	Memberships map[string][]string
+}
+
+// casbinModel is what ParseCasbin needs of a model.conf: the matcher
+// function comparing each request field with the policy's, and whether
+// deny rules count.
+type casbinModel struct {
+	sub, obj, act string
+	eft           bool // p has an eft field
+	denies        bool
+}
+
+// ParseCasbin converts a Casbin model.conf and policy CSV into a PolicyBundle
+// and user assignments, for moving a Casbin deployment onto this package.
+//
+// The model must be RBAC without domains: requests of sub, obj and act,
+// matched with g(r.sub, p.sub) or r.sub == p.sub, an object matcher of ==,
+// keyMatch, keyMatch2, keyMatch3, globMatch or regexMatch, an action
+// matcher of ==, keyMatch, globMatch or regexMatch, and the allow-override
+// or deny-override effect. Anything else fails with ErrUnsupportedCasbin.
+//
+// The second field of every g line is a role, or a group when it carries
+// CasbinGroupPrefix. A g line from one role to another makes the first
+// inherit from the second; from anything else it assigns a user. Policies
+// of a subject that is not a role are a user's own, and become a role of the
+// user's name that the user is assigned. Objects become glob patterns where
+// one is equivalent, and regular expression patterns otherwise; a
+// regexMatch action listing alternatives, such as (GET)|(POST), becomes a
+// permission per action. Permission IDs are derived from the resource,
+// action and effect.
+func ParseCasbin(model, policy io.Reader) (*CasbinPolicy, error) {
+	cm, err := parseCasbinModel(model)
+	if err != nil {
+		return nil, err
+	}
+	r := csv.NewReader(policy)
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+	r.Comment = '#'
+	var ps, gs [][]string
+	for {
+		rec, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrUnsupportedCasbin, err)
+		}
+		for i := range rec {
+			rec[i] = strings.TrimSpace(rec[i])
+		}
+		switch {
+		case rec[0] == "p" && (len(rec) == 4 || len(rec) == 5 && cm.eft):
+			ps = append(ps, rec[1:])
+		case rec[0] == "g" && len(rec) == 3:
+			if cm.sub == "g" {
+				gs = append(gs, rec[1:])
+			}
+		default:
+			return nil, fmt.Errorf("%w: policy line %q", ErrUnsupportedCasbin, strings.Join(rec, ", "))
+		}
+	}
+
+	out := &CasbinPolicy{
+		Bundle:      &PolicyBundle{Version: PolicyBundleVersion},
+		UserRoles:   map[string][]string{},
+		Memberships: map[string][]string{},
+	}
+	roles := map[string]*BundleRole{}
+	role := func(name string) *BundleRole {
+		r := roles[name]
+		if r == nil {
+			r = &BundleRole{Role: Role{ID: name, Name: name}}
+			roles[name] = r
+		}
+		return r
+	}
+	groups := map[string]*BundleGroup{}
+	group := func(name string) *BundleGroup {
+		g := groups[name]
+		if g == nil {
+			g = &BundleGroup{Group: Group{Name: name}}
+			groups[name] = g
+		}
+		return g
+	}
+	isRole := map[string]bool{}
+	for _, g := range gs {
+		if !strings.HasPrefix(g[1], CasbinGroupPrefix) {
+			isRole[g[1]] = true
+		}
+	}
+
+	for _, g := range gs {
+		sub, target := g[0], g[1]
+		subGroup, ok := strings.CutPrefix(sub, CasbinGroupPrefix)
+		targetGroup, targetIsGroup := strings.CutPrefix(target, CasbinGroupPrefix)
+		switch {
+		case ok && targetIsGroup:
+			return nil, fmt.Errorf("%w: nested group %s", ErrUnsupportedCasbin, sub)
+		case ok:
+			group(subGroup).Roles = append(group(subGroup).Roles, role(target).ID)
+		case targetIsGroup:
+			group(targetGroup)
+			out.Memberships[sub] = append(out.Memberships[sub], targetGroup)
+		case isRole[sub] && sub != target:
+			role(sub).Parents = append(role(sub).Parents, role(target).ID)
+		default:
+			out.UserRoles[sub] = append(out.UserRoles[sub], role(target).ID)
+		}
+	}
+
+	perms := map[string]*Permission{}
+	for _, p := range ps {
+		sub, obj, act, eft := p[0], p[1], p[2], "allow"
+		if len(p) == 4 {
+			eft = p[3]
+		}
+		effect := EffectAllow
+		switch {
+		case eft == "deny" && !cm.denies:
+			// the allow-override effect never looks at deny rules
+			continue
+		case eft == "deny":
+			effect = EffectDeny
+		case eft != "allow":
+			return nil, fmt.Errorf("%w: effect %q", ErrUnsupportedCasbin, eft)
+		}
+		if strings.HasPrefix(sub, CasbinGroupPrefix) {
+			return nil, fmt.Errorf("%w: policy of group %s; grant it to a role bound to the group", ErrUnsupportedCasbin, sub)
+		}
+		if !isRole[sub] {
+			if _, ok := roles[sub]; !ok {
+				role(sub).Description = "Casbin policies of user " + sub
+				out.UserRoles[sub] = append(out.UserRoles[sub], sub)
+			}
+		}
+		resource, err := casbinResource(cm.obj, obj)
+		if err != nil {
+			return nil, err
+		}
+		actions, err := casbinActions(cm.act, act)
+		if err != nil {
+			return nil, err
+		}
+		for _, action := range actions {
+			perm := &Permission{ID: casbinPermissionID(resource, action, effect), Resource: resource, Action: action}
+			if effect == EffectDeny {
+				perm.Effect = EffectDeny
+			}
+			perms[perm.ID] = perm
+			role(sub).Permissions = append(role(sub).Permissions, perm.ID)
+		}
+	}
+
+	for _, p := range perms {
+		out.Bundle.Permissions = append(out.Bundle.Permissions, p)
+	}
+	sort.Slice(out.Bundle.Permissions, func(i, j int) bool { return out.Bundle.Permissions[i].ID < out.Bundle.Permissions[j].ID })
+	for _, r := range roles {
+		r.Permissions, r.Parents = sortedIDs(r.Permissions), sortedIDs(r.Parents)
+		out.Bundle.Roles = append(out.Bundle.Roles, r)
+	}
+	sort.Slice(out.Bundle.Roles, func(i, j int) bool { return out.Bundle.Roles[i].ID < out.Bundle.Roles[j].ID })
+	for _, g := range groups {
+		g.Roles = sortedIDs(g.Roles)
+		out.Bundle.Groups = append(out.Bundle.Groups, g)
+	}
+	sort.Slice(out.Bundle.Groups, func(i, j int) bool { return out.Bundle.Groups[i].Name < out.Bundle.Groups[j].Name })
+	for user, ids := range out.UserRoles {
+		out.UserRoles[user] = sortedIDs(ids)
+	}
+	for user, names := range out.Memberships {
+		out.Memberships[user] = sortedIDs(names)
+	}
+	return out, out.Bundle.validate()
+}
+
+// casbinPermissionID derives the ID ParseCasbin gives a permission.
+func casbinPermissionID(resource string, action Action, effect Effect) string {
+	sum := sha256.Sum256([]byte(resource + "\x00" + string(action) + "\x00" + string(effect)))
+	return "casbin-" + hex.EncodeToString(sum[:8])
+}
+
+// parseCasbinModel reads the sections of a model.conf ParseCasbin needs.
+func parseCasbinModel(r io.Reader) (*casbinModel, error) {
+	unsupported := func(format string, args ...any) error {
+		return fmt.Errorf("%w: model: %s", ErrUnsupportedCasbin, fmt.Sprintf(format, args...))
+	}
+	defs := map[string]string{}
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "[") {
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, unsupported("line %q", line)
+		}
+		// whitespace is insignificant in every definition read below
+		defs[strings.TrimSpace(key)] = strings.Join(strings.Fields(value), "")
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+
+	cm := &casbinModel{}
+	for key, value := range defs {
+		switch key {
+		case "r":
+			if value != "sub,obj,act" {
+				return nil, unsupported("request definition %q", value)
+			}
+		case "p":
+			cm.eft = value == "sub,obj,act,eft"
+			if !cm.eft && value != "sub,obj,act" {
+				return nil, unsupported("policy definition %q", value)
+			}
+		case "g":
+			if value != "_,_" {
+				return nil, unsupported("role definition %q; domains are not supported", value)
+			}
+		case "e":
+			switch value {
+			case "some(where(p.eft==allow))":
+			case "some(where(p.eft==allow))&&!some(where(p.eft==deny))":
+				cm.denies = true
+			default:
+				return nil, unsupported("policy effect %q", value)
+			}
+		case "m":
+			for _, clause := range strings.Split(value, "&&") {
+				for strings.HasPrefix(clause, "(") && strings.HasSuffix(clause, ")") {
+					clause = clause[1 : len(clause)-1]
+				}
+				field, fn, ok := casbinClause(clause)
+				if !ok {
+					return nil, unsupported("matcher clause %q", clause)
+				}
+				switch {
+				case field == "sub" && cm.sub == "" && (fn == "==" || fn == "g" && defs["g"] != ""):
+					cm.sub = fn
+				case field == "obj" && cm.obj == "" && fn != "g":
+					cm.obj = fn
+				case field == "act" && cm.act == "" && fn != "g" && fn != "keyMatch2" && fn != "keyMatch3":
+					cm.act = fn
+				default:
+					return nil, unsupported("matcher clause %q", clause)
+				}
+			}
+		default:
+			return nil, unsupported("definition %q", key)
+		}
+	}
+	if defs["r"] == "" || defs["p"] == "" || defs["e"] == "" || cm.sub == "" || cm.obj == "" || cm.act == "" {
+		return nil, unsupported("requests, policies, effect and matchers of sub, obj and act are required")
+	}
+	return cm, nil
+}
+
+// casbinClause recognizes a matcher clause comparing a request field with
+// the policy's, returning the field and "==" or the function used.
+func casbinClause(clause string) (field, fn string, ok bool) {
+	for _, field := range []string{"sub", "obj", "act"} {
+		args := "(r." + field + ",p." + field + ")"
+		if clause == "r."+field+"==p."+field {
+			return field, "==", true
+		}
+		for _, fn := range []string{"g", "keyMatch", "keyMatch2", "keyMatch3", "globMatch", "regexMatch"} {
+			if clause == fn+args {
+				return field, fn, true
+			}
+		}
+	}
+	return "", "", false
+}
+
+var (
+	casbinKeyMatch2Param = regexp.MustCompile(`:[^/]+`)
+	casbinKeyMatch3Param = regexp.MustCompile(`\{[^/]+?\}`)
+)
+
+// casbinResource converts a policy object matched with fn into a resource
+// pattern.
+func casbinResource(fn, obj string) (string, error) {
+	var expr string // the anchored expression fn matches obj as
+	switch fn {
+	case "==":
+		expr = "^" + regexp.QuoteMeta(obj) + "$"
+	case "keyMatch":
+		// everything after the first * is ignored
+		if i := strings.IndexByte(obj, '*'); i >= 0 {
+			expr = "^" + regexp.QuoteMeta(obj[:i]) + ".*$"
+		} else {
+			expr = "^" + regexp.QuoteMeta(obj) + "$"
+		}
+	case "keyMatch2", "keyMatch3":
+		expr = strings.ReplaceAll(obj, "/*", "/.*")
+		param := casbinKeyMatch2Param
+		if fn == "keyMatch3" {
+			param = casbinKeyMatch3Param
+		}
+		expr = "^" + param.ReplaceAllStringFunc(expr, func(p string) string {
+			return "(?P<" + strings.Trim(p, ":{}") + ">[^/]+)"
+		}) + "$"
+		if _, err := regexp.Compile(expr); err != nil {
+			// names that are not valid group names match the same unnamed
+			expr = "^" + param.ReplaceAllString(strings.ReplaceAll(obj, "/*", "/.*"), "[^/]+") + "$"
+		}
+	case "globMatch":
+		// Casbin matches globs with path.Match, as Can does without **
+		if !strings.Contains(obj, "**") && !strings.ContainsAny(obj, "{}") && !rbaceval.IsRegex(obj) {
+			if _, err := rbaceval.PatternRegexp(obj); err == nil {
+				return obj, nil
+			}
+		}
+		return "", fmt.Errorf("%w: glob object %q", ErrUnsupportedCasbin, obj)
+	case "regexMatch":
+		// Casbin's regexMatch need not match the whole object
+		expr = "^(?:.*(?:" + obj + ").*)$"
+		if strings.HasPrefix(obj, "^") && strings.HasSuffix(obj, "$") && !strings.HasSuffix(obj, `\$`) && !strings.Contains(obj, "|") {
+			expr = obj
+		}
+	}
+	if _, err := regexp.Compile(expr); err != nil {
+		return "", fmt.Errorf("%w: object %q: %v", ErrUnsupportedCasbin, obj, err)
+	}
+	if glob, ok := globFromRegexp(expr); ok {
+		return glob, nil
+	}
+	inner := strings.TrimSuffix(strings.TrimPrefix(expr, "^"), "$")
+	return rbaceval.RegexPrefix + inner, nil
+}
+
+// globFromRegexp returns the glob pattern that rbaceval.PatternRegexp turns
+// into expr, when there is one, so objects ExportCasbin wrote come back as
+// the patterns they were exported from.
+func globFromRegexp(expr string) (string, bool) {
+	if inner, ok := strings.CutPrefix(expr, "^(?:"); ok && strings.HasSuffix(inner, ")$") {
+		if glob := rbaceval.RegexPrefix + strings.TrimSuffix(inner, ")$"); patternRegexpIs(glob, expr) {
+			return glob, true
+		}
+	}
+	var b strings.Builder
+	s := strings.TrimSuffix(strings.TrimPrefix(expr, "^"), "$")
+	for s != "" {
+		var next string
+		switch {
+		case strings.HasPrefix(s, "[^/]*"):
+			b.WriteString("*")
+			next = s[5:]
+		case strings.HasPrefix(s, "[^/]"):
+			b.WriteString("?")
+			next = s[4:]
+		case strings.HasPrefix(s, ".*"):
+			b.WriteString("**")
+			next = s[2:]
+		case strings.HasPrefix(s, "(?P<"):
+			name, rest, ok := strings.Cut(s[4:], ">[^/]+)")
+			if !ok {
+				return "", false
+			}
+			b.WriteString("{" + name + "}")
+			next = rest
+		case s[0] == '\\' && len(s) > 1:
+			if strings.IndexByte(`*?[\`, s[1]) >= 0 {
+				b.WriteByte('\\')
+			}
+			b.WriteByte(s[1])
+			next = s[2:]
+		case s[0] == '[':
+			end := strings.IndexByte(s, ']')
+			if end < 0 {
+				return "", false
+			}
+			b.WriteString(s[:end+1])
+			next = s[end+1:]
+		default:
+			b.WriteByte(s[0])
+			next = s[1:]
+		}
+		s = next
+	}
+	glob := b.String()
+	return glob, patternRegexpIs(glob, expr)
+}
+
+// patternRegexpIs reports whether rbaceval.PatternRegexp turns pattern into
+// exactly expr.
+func patternRegexpIs(pattern, expr string) bool {
+	re, err := rbaceval.PatternRegexp(pattern)
+	return err == nil && re.String() == expr
+}
+
+// casbinActions converts a policy action matched with fn into the actions
+// of the permissions it grants.
+func casbinActions(fn, act string) ([]Action, error) {
+	escape := func(s string) Action {
+		var b strings.Builder
+		for _, c := range s {
+			if strings.ContainsRune(`*?[\`, c) {
+				b.WriteByte('\\')
+			}
+			b.WriteRune(c)
+		}
+		return Action(b.String())
+	}
+	switch fn {
+	case "==":
+		return []Action{escape(act)}, nil
+	case "keyMatch":
+		if prefix, _, ok := strings.Cut(act, "*"); ok {
+			return []Action{escape(prefix) + "*"}, nil
+		}
+		return []Action{escape(act)}, nil
+	case "globMatch":
+		return []Action{Action(act)}, nil
+	}
+
+	// regexMatch: a match-all or a list of alternatives
+	expr := strings.TrimSuffix(strings.TrimPrefix(act, "^"), "$")
+	if expr == ".*" || expr == "" {
+		return []Action{ActionAll}, nil
+	}
+	var actions []Action
+	for _, alt := range strings.Split(expr, "|") {
+		alt = strings.TrimSuffix(strings.TrimPrefix(strings.Trim(alt, "()"), "^"), "$")
+		if alt == "" || regexp.QuoteMeta(alt) != alt {
+			return nil, fmt.Errorf("%w: action %q", ErrUnsupportedCasbin, act)
+		}
+		actions = append(actions, escape(alt))
+	}
+	return actions, nil
+}
+
+// ImportCasbin converts a Casbin model.conf and policy CSV with ParseCasbin
+// and imports the result: the bundle as ImportPolicy would with opts, then
+// the user assignments and memberships the store lacks. Roles are matched
+// by ID, then by name. In ImportReplace mode the roles, permissions and
+// groups not in the policy are removed, but users keep assignments the
+// policy does not mention.
+func (m *Manager) ImportCasbin(ctx context.Context, model, policy io.Reader, opts ImportOptions) error {
+	start := time.Now()
+	ctx, span := m.startSpan(ctx, "ImportCasbin")
+	defer span.End()
+	cp, err := ParseCasbin(model, policy)
+	if err == nil && opts.Mode != "" && opts.Mode != ImportMerge && opts.Mode != ImportReplace {
+		err = fmt.Errorf("rbac: unknown import mode %q", opts.Mode)
+	}
+	if err == nil {
+		err = m.inTransaction(ctx, func(ctx context.Context) error {
+			if err := m.snapshotBefore(ctx, "before ImportCasbin"); err != nil {
+				return err
+			}
+			if _, err := m.importPolicy(ctx, cp.Bundle, opts.Mode == ImportReplace, func(ChangeEvent) {}); err != nil {
+				return err
+			}
+			return m.importCasbinUsers(ctx, cp)
+		})
+	}
+	m.record(ctx, start, "ImportCasbin", err)
+	return err
+}
+
+func (m *Manager) importCasbinUsers(ctx context.Context, cp *CasbinPolicy) error {
+	users := make([]string, 0, len(cp.UserRoles))
+	for user := range cp.UserRoles {
+		users = append(users, user)
+	}
+	sort.Strings(users)
+	for _, user := range users {
+		held, err := m.UR.ListRoles(ctx, user)
+		if err != nil {
+			return err
+		}
+		for _, name := range cp.UserRoles[user] {
+			r, err := m.Roles.GetRoleByID(ctx, name)
+			if err == nil && r == nil {
+				r, err = m.Roles.GetRoleByName(ctx, name)
+			}
+			if err != nil {
+				return err
+			}
+			if r == nil {
+				return fmt.Errorf("rbac: casbin role %q was not imported", name)
+			}
+			if slices.Contains(held, r.ID) {
+				continue
+			}
+			if err := m.AssignRoleToUser(ctx, user, r.ID); err != nil {
+				return fmt.Errorf("assign %s to %s: %w", r.ID, user, err)
+			}
+		}
+	}
+
+	users = users[:0]
+	for user := range cp.Memberships {
+		users = append(users, user)
+	}
+	sort.Strings(users)
+	for _, user := range users {
+		current, err := m.UG.GetGroupsByUserID(ctx, user)
+		if err != nil {
+			return err
+		}
+		for _, name := range cp.Memberships[user] {
+			if slices.ContainsFunc(current, func(ug *UserGroup) bool { return ug.GroupName == name }) {
+				continue
+			}
+			if err := m.AddUserToGroup(ctx, &UserGroup{UserID: user, GroupName: name}); err != nil {
+				return fmt.Errorf("add %s to group %s: %w", user, name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// ExportCasbin writes the store's policy as a Casbin policy CSV for
+// CasbinModel: a p line per permission of each role, by role name, and g
+// lines for role inheritance, group roles, user roles and memberships, so
+// an application can be checked against Casbin side by side while
+// migrating either way. Role priorities have no Casbin form and are
+// dropped, as are scoped roles and generators; conditional allows are
+// skipped and conditional denies exported, so Casbin never allows more
+// than Can would. Expired memberships are left out. User roles and
+// memberships are read through ExportPager.
+func (m *Manager) ExportCasbin(ctx context.Context, w io.Writer) error {
+	start := time.Now()
+	ctx, span := m.startSpan(ctx, "ExportCasbin")
+	defer span.End()
+	err := m.exportCasbin(ctx, start, w)
+	m.record(ctx, start, "ExportCasbin", err)
+	return err
+}
+
+func (m *Manager) exportCasbin(ctx context.Context, now time.Time, w io.Writer) error {
+	b, err := m.exportPolicy(ctx)
+	if err != nil {
+		return err
+	}
+	names := make(map[string]string, len(b.Roles))
+	for _, r := range b.Roles {
+		names[r.ID] = r.Name
+		if r.Name == "" {
+			names[r.ID] = r.ID
+		}
+	}
+	perms := make(map[string]*Permission, len(b.Permissions))
+	for _, p := range b.Permissions {
+		perms[p.ID] = p
+	}
+
+	cw := csv.NewWriter(w)
+	for _, r := range b.Roles {
+		for _, id := range r.Permissions {
+			p := perms[id]
+			if p == nil || p.Condition != "" && p.Effect != EffectDeny {
+				continue
+			}
+			obj, err := rbaceval.PatternRegexp(p.Resource)
+			if err != nil {
+				return fmt.Errorf("rbac: export permission %s: %w", p.ID, err)
+			}
+			eft := "allow"
+			if p.Effect == EffectDeny {
+				eft = "deny"
+			}
+			if err := cw.Write([]string{"p", names[r.ID], obj.String(), string(p.Action), eft}); err != nil {
+				return err
+			}
+		}
+	}
+	for _, r := range b.Roles {
+		for _, parent := range r.Parents {
+			if err := cw.Write([]string{"g", names[r.ID], names[parent]}); err != nil {
+				return err
+			}
+		}
+	}
+	for _, g := range b.Groups {
+		for _, id := range g.Roles {
+			if err := cw.Write([]string{"g", CasbinGroupPrefix + g.Name, names[id]}); err != nil {
+				return err
+			}
+		}
+	}
+	err = m.exportPages(ctx, KindUserRole, func(v any) error {
+		if e, ok := v.(*ExportEdge); ok && names[e.To] != "" {
+			return cw.Write([]string{"g", e.From, names[e.To]})
+		}
+		return nil
+	})
+	if err == nil {
+		err = m.exportPages(ctx, KindUserGroup, func(v any) error {
+			ug, ok := v.(*UserGroup)
+			if !ok || ug.ExpiresAt != 0 && ug.ExpiresAt <= now.Unix() {
+				return nil
+			}
+			return cw.Write([]string{"g", ug.UserID, CasbinGroupPrefix + ug.GroupName})
+		})
+	}
+	if err != nil {
+		return err
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,171 @@
+package rbac
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+const casbinBasicModel = `
+[request_definition]
+r = sub, obj, act
+
+[policy_definition]
+p = sub, obj, act
+
+[role_definition]
+g = _, _
+
+[policy_effect]
+e = some(where (p.eft == allow))
+
+[matchers]
+m = g(r.sub, p.sub) && r.obj == p.obj && r.act == p.act
+`
+
+const casbinRESTModel = `
+[request_definition]
+r = sub, obj, act
+
+[policy_definition]
+p = sub, obj, act, eft
+
+[role_definition]
+g = _, _
+
+[policy_effect]
+e = some(where (p.eft == allow)) && !some(where (p.eft == deny))
+
+[matchers]
+m = g(r.sub, p.sub) && keyMatch2(r.obj, p.obj) && regexMatch(r.act, p.act)
+`
+
+const casbinRESTPolicy = `
+p, api_reader, /api/*, GET, allow
+p, api_reader, /api/secret, GET, deny
+p, api_writer, /api/users/:id, (PUT)|(PATCH), allow
+g, api_writer, api_reader
+g, user1, api_writer
+g, group:ops, api_reader
+g, carol, group:ops
+`
+
+func TestParseCasbin(t *testing.T) {
+	cp, err := ParseCasbin(strings.NewReader(casbinBasicModel), strings.NewReader(`
+p, alice, data1, read
+p, bob, data2, write
+p, data2_admin, data2, read
+p, data2_admin, data2, write
+g, alice, data2_admin
+`))
+	if err != nil {
+		t.Fatalf("ParseCasbin: %v", err)
+	}
+	var roles []string
+	for _, r := range cp.Bundle.Roles {
+		roles = append(roles, r.ID)
+	}
+	if !slices.Equal(roles, []string{"alice", "bob", "data2_admin"}) {
+		t.Errorf("roles: got %v; want a role for each subject with policies", roles)
+	}
+	if got := cp.UserRoles["alice"]; !slices.Equal(got, []string{"alice", "data2_admin"}) {
+		t.Errorf("alice's roles: got %v", got)
+	}
+	if len(cp.Bundle.Permissions) != 3 {
+		t.Errorf("permissions: got %d; want data1 read, data2 read and data2 write", len(cp.Bundle.Permissions))
+	}
+
+	for _, c := range []struct{ model, policy string }{
+		{strings.Replace(casbinBasicModel, "g = _, _", "g = _, _, _", 1), ""},
+		{strings.Replace(casbinBasicModel, "r.act == p.act", `r.act == p.act || r.sub == "root"`, 1), ""},
+		{casbinBasicModel, "p2, alice, data1, read"},
+		{casbinBasicModel, "p, group:ops, data1, read"},
+	} {
+		if _, err := ParseCasbin(strings.NewReader(c.model), strings.NewReader(c.policy)); !errors.Is(err, ErrUnsupportedCasbin) {
+			t.Errorf("ParseCasbin(%q): got %v; want ErrUnsupportedCasbin", c.policy, err)
+		}
+	}
+}
+
+func TestImportExportCasbin(t *testing.T) {
+	ctx := context.Background()
+	check := func(t *testing.T, mgr *Manager) {
+		t.Helper()
+		for _, c := range []struct {
+			user, resource, action string
+			want                   bool
+		}{
+			{"user1", "/api/orders", "GET", true},
+			{"user1", "/api/secret", "GET", false},
+			{"user1", "/api/users/7", "PATCH", true},
+			{"user1", "/api/users/7/keys", "PATCH", false},
+			{"carol", "/api/orders", "GET", true},
+			{"carol", "/api/users/7", "PUT", false},
+		} {
+			got, err := mgr.Can(ctx, c.user, c.resource, Action(c.action))
+			if err != nil || got != c.want {
+				t.Errorf("Can(%s, %s, %s): got %v, %v; want %v", c.user, c.resource, c.action, got, err, c.want)
+			}
+		}
+	}
+
+	mgr, err := NewMemoryStoreManager(ctx, "", 0)
+	if err != nil {
+		t.Fatalf("NewMemoryStoreManager: %v", err)
+	}
+	if err := mgr.ImportCasbin(ctx, strings.NewReader(casbinRESTModel), strings.NewReader(casbinRESTPolicy), ImportOptions{}); err != nil {
+		t.Fatalf("ImportCasbin: %v", err)
+	}
+	check(t, mgr)
+	p, err := mgr.GetPermissionByResource(ctx, "/api/users/{id}", "PUT")
+	if err != nil || p == nil {
+		t.Errorf("keyMatch2 object: got %v, %v; want the named parameter pattern", p, err)
+	}
+	// importing again changes nothing
+	if err := mgr.ImportCasbin(ctx, strings.NewReader(casbinRESTModel), strings.NewReader(casbinRESTPolicy), ImportOptions{}); err != nil {
+		t.Fatalf("second ImportCasbin: %v", err)
+	}
+	if roles, _ := mgr.UR.ListRoles(ctx, "user1"); len(roles) != 1 {
+		t.Errorf("user1's roles after a second import: got %v", roles)
+	}
+
+	var buf bytes.Buffer
+	if err := mgr.ExportCasbin(ctx, &buf); err != nil {
+		t.Fatalf("ExportCasbin: %v", err)
+	}
+	for _, line := range []string{`p,api_reader,^/api/.*$,GET,allow`, `p,api_writer,^/api/users/(?P<id>[^/]+)$,PUT,allow`, "g,api_writer,api_reader", "g,group:ops,api_reader", "g,carol,group:ops"} {
+		if !strings.Contains(buf.String(), line+"\n") {
+			t.Errorf("export lacks %q:\n%s", line, buf.String())
+		}
+	}
+
+	// the export reads back as the same policy
+	cp, err := ParseCasbin(strings.NewReader(CasbinModel), bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("ParseCasbin of the export: %v", err)
+	}
+	want, _ := mgr.ExportPolicy(ctx)
+	var got, wantResources []string
+	for _, p := range cp.Bundle.Permissions {
+		got = append(got, p.Resource+" "+string(p.Action))
+	}
+	for _, p := range want.Permissions {
+		wantResources = append(wantResources, p.Resource+" "+string(p.Action))
+	}
+	slices.Sort(got)
+	slices.Sort(wantResources)
+	if !slices.Equal(got, wantResources) {
+		t.Errorf("round trip: got %v; want %v", got, wantResources)
+	}
+	fresh, err := NewMemoryStoreManager(ctx, "", 0)
+	if err != nil {
+		t.Fatalf("NewMemoryStoreManager: %v", err)
+	}
+	if err := fresh.ImportCasbin(ctx, strings.NewReader(CasbinModel), bytes.NewReader(buf.Bytes()), ImportOptions{}); err != nil {
+		t.Fatalf("ImportCasbin of the export: %v", err)
+	}
+	check(t, fresh)
+}
@@ -0,0 +1,167 @@
+package rbac
+
+import (
+	"context"
+	"errors"
+	"slices"
+)
+
+// purgeRole deletes the role and every assignment of it in one transaction
+// when the store supports them.
+func (m *Manager) purgeRole(ctx context.Context, roleID string) error {
+	return m.inTransaction(ctx, func(ctx context.Context) error {
+		if err := m.removeRoleEdges(ctx, roleID); err != nil {
+			return err
+		}
+		return m.Roles.DeleteRole(ctx, roleID)
+	})
+}
+
+// purgePermission deletes the permission and its role bindings in one
+// transaction when the store supports them.
+func (m *Manager) purgePermission(ctx context.Context, permID string) error {
+	return m.inTransaction(ctx, func(ctx context.Context) error {
+		if m.RP != nil {
+			roles, err := m.edgeSources(ctx, KindRolePermission, permID)
+			if err != nil {
+				return err
+			}
+			for _, roleID := range roles {
+				if err := m.RP.Remove(ctx, roleID, permID); err != nil {
+					return err
+				}
+			}
+		}
+		return m.Perms.DeletePermission(ctx, permID)
+	})
+}
+
+// removeRoleEdges removes roleID's permissions, its holders among users and
+// groups, and its place in the hierarchy, so the IDs of a deleted role are
+// not left for Can to iterate over.
+func (m *Manager) removeRoleEdges(ctx context.Context, roleID string) error {
+	if m.RP != nil {
+		perms, err := m.RP.ListPermissions(ctx, roleID)
+		if err != nil {
+			return err
+		}
+		for _, permID := range perms {
+			if err := m.RP.Remove(ctx, roleID, permID); err != nil {
+				return err
+			}
+		}
+	}
+	if m.UR != nil {
+		users, err := m.edgeSources(ctx, KindUserRole, roleID)
+		if err != nil {
+			return err
+		}
+		for _, userID := range users {
+			if err := m.UR.RemoveUR(ctx, userID, roleID); err != nil {
+				return err
+			}
+		}
+	}
+	if m.GR != nil {
+		groups, err := m.edgeSources(ctx, KindGroupRole, roleID)
+		if err != nil {
+			return err
+		}
+		for _, group := range groups {
+			if err := m.GR.RemoveRoleFromGroup(ctx, group, roleID); err != nil {
+				return err
+			}
+		}
+	}
+
+	hierarchy, ok := m.Roles.(RoleHierarchyRepo)
+	if !ok {
+		return nil
+	}
+	parents, err := hierarchy.ListRoleParents(ctx, roleID)
+	if err != nil {
+		return err
+	}
+	for _, p := range parents {
+		if err := hierarchy.RemoveRoleParent(ctx, roleID, p); err != nil {
+			return err
+		}
+	}
+	roles, err := m.Roles.ListAllRoles(ctx)
+	if err != nil {
+		return err
+	}
+	for _, r := range roles {
+		parents, err := hierarchy.ListRoleParents(ctx, r.ID)
+		if err != nil {
+			return err
+		}
+		if slices.Contains(parents, roleID) {
+			if err := hierarchy.RemoveRoleParent(ctx, r.ID, roleID); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// edgeSources returns the sources of the edges of kind that point at to:
+// the roles bound to a permission, or the users or groups holding a role.
+// It reads the repo's ExportPager, and otherwise asks every role, user or
+// group the repos list, finding nothing without the repo to list them.
+func (m *Manager) edgeSources(ctx context.Context, kind, to string) ([]string, error) {
+	var from []string
+	err := m.exportPages(ctx, kind, func(v any) error {
+		if e, ok := v.(*ExportEdge); ok && e.To == to {
+			from = append(from, e.From)
+		}
+		return nil
+	})
+	if !errors.Is(err, errExportUnsupported) {
+		return from, err
+	}
+
+	var (
+		sources []string
+		list    func(ctx context.Context, id string) ([]string, error)
+	)
+	switch {
+	case kind == KindRolePermission && m.Roles != nil:
+		roles, err := m.Roles.ListAllRoles(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range roles {
+			sources = append(sources, r.ID)
+		}
+		list = m.RP.ListPermissions
+	case kind == KindUserRole && m.Users != nil:
+		users, err := m.Users.ListAllUsers(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, u := range users {
+			sources = append(sources, u.ID)
+		}
+		list = m.UR.ListRoles
+	case kind == KindGroupRole && m.Groups != nil:
+		groups, err := m.Groups.ListGroups(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, g := range groups {
+			sources = append(sources, g.Name)
+		}
+		list = m.GR.ListRolesForGroup
+	}
+	for _, id := range sources {
+		targets, err := list(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if slices.Contains(targets, to) {
+			from = append(from, id)
+		}
+	}
+	return from, nil
+}
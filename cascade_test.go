@@ -0,0 +1,76 @@
+package rbac
+
+import (
+	"context"
+	"slices"
+	"testing"
+)
+
+func TestPurgeCascades(t *testing.T) {
+	ctx := context.Background()
+	stores := map[string]func() *Manager{
+		"memory": func() *Manager {
+			mgr, err := NewMemoryStoreManager(ctx, "", 0)
+			if err != nil {
+				t.Fatalf("NewMemoryStoreManager: %v", err)
+			}
+			return mgr
+		},
+		// the mock has no paged export, so holders are found by asking
+		// every user and group
+		"mock": func() *Manager { return NewMockRepoManager(NewMockRepo()) },
+	}
+	for name, newManager := range stores {
+		t.Run(name, func(t *testing.T) {
+			mgr := newManager()
+			alice := &User{Username: "alice"}
+			if err := mgr.CreateUser(ctx, alice); err != nil {
+				t.Fatalf("CreateUser: %v", err)
+			}
+			if err := mgr.CreateGroup(ctx, &Group{Name: "writers"}); err != nil {
+				t.Fatalf("CreateGroup: %v", err)
+			}
+			editor, viewer := &Role{Name: "editor"}, &Role{Name: "viewer"}
+			for _, r := range []*Role{editor, viewer} {
+				if err := mgr.CreateRole(ctx, r); err != nil {
+					t.Fatalf("CreateRole: %v", err)
+				}
+			}
+			perm := &Permission{Resource: "docs/*", Action: ActionRead}
+			if err := mgr.CreatePermission(ctx, perm); err != nil {
+				t.Fatalf("CreatePermission: %v", err)
+			}
+			for _, r := range []*Role{editor, viewer} {
+				if err := mgr.AssignPermissionToRole(ctx, r.ID, perm.ID); err != nil {
+					t.Fatalf("AssignPermissionToRole: %v", err)
+				}
+			}
+			if err := mgr.AssignRoleToUser(ctx, alice.ID, editor.ID); err != nil {
+				t.Fatalf("AssignRoleToUser: %v", err)
+			}
+			if err := mgr.AssignRoleToGroup(ctx, "writers", editor.ID); err != nil {
+				t.Fatalf("AssignRoleToGroup: %v", err)
+			}
+
+			if err := mgr.PurgeRole(ctx, editor.ID); err != nil {
+				t.Fatalf("PurgeRole: %v", err)
+			}
+			if roles, _ := mgr.ListRolesForUser(ctx, alice.ID); slices.Contains(roles, editor.ID) {
+				t.Errorf("expected alice to lose the purged role, got %v", roles)
+			}
+			if roles, _ := mgr.ListRolesForGroup(ctx, "writers"); len(roles) != 0 {
+				t.Errorf("expected writers to lose the purged role, got %v", roles)
+			}
+			if perms, _ := mgr.ListPermissionsForRole(ctx, editor.ID); len(perms) != 0 {
+				t.Errorf("expected the purged role's bindings to go, got %v", perms)
+			}
+
+			if err := mgr.PurgePermission(ctx, perm.ID); err != nil {
+				t.Fatalf("PurgePermission: %v", err)
+			}
+			if perms, _ := mgr.ListPermissionsForRole(ctx, viewer.ID); len(perms) != 0 {
+				t.Errorf("expected the purged permission to be unbound, got %v", perms)
+			}
+		})
+	}
+}
@@ -0,0 +1,925 @@
+// file: rbac/cassandra_store.go
+package rbac
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gocql/gocql"
+)
+
+// Ensure CassandraStore implements all interfaces:
+var (
+	_ PermissionRepo           = (*CassandraStore)(nil)
+	_ RoleRepo                 = (*CassandraStore)(nil)
+	_ UserRepo                 = (*CassandraStore)(nil)
+	_ UserStatusRepo           = (*CassandraStore)(nil)
+	_ EmailVerificationRepo    = (*CassandraStore)(nil)
+	_ RolePermissionRepo       = (*CassandraStore)(nil)
+	_ RolePermissionDetailer   = (*CassandraStore)(nil)
+	_ UserRoleRepo             = (*CassandraStore)(nil)
+	_ UserGroupRepo            = (*CassandraStore)(nil)
+	_ GroupRoleRepo            = (*CassandraStore)(nil)
+	_ ExpiringMembershipLister = (*CassandraStore)(nil)
+	_ HealthChecker            = (*CassandraStore)(nil)
+)
+
+//
+// ---------- CassandraStore Core ----------
+//
+
+// CassandraStore is a Cassandra/ScyllaDB backed store for read-heavy
+// deployments. Tables are denormalized so the reads on the Can path are
+// single-partition: user_roles and user_groups are partitioned by user_id,
+// group_roles by group_name and role_permissions by role_id, with the
+// permission's resource and action copied onto the binding row.
+type CassandraStore struct {
+	session  *gocql.Session
+	keyspace string
+	ids      IDGenerator
+}
+
+// NewCassandraStore creates the store and ensures the schema is in place.
+// The keyspace is created with SimpleStrategy/RF1 only if it does not exist
+// yet; production clusters should create it up front with their own
+// replication settings.
+func NewCassandraStore(ctx context.Context, session *gocql.Session, keyspace string) (*CassandraStore, error) {
+	if keyspace == "" {
+		keyspace = "rbac"
+	}
+
+	s := &CassandraStore{session: session, keyspace: keyspace}
+	if err := s.EnsureSchema(ctx); err != nil {
+		return nil, fmt.Errorf("cassandra_store: ensure schema: %w", err)
+	}
+	return s, nil
+}
+
+// SetIDGenerator changes how the store generates IDs for new entities.
+func (s *CassandraStore) SetIDGenerator(g IDGenerator) {
+	s.ids = g
+}
+
+// Health implements HealthChecker by reading the coordinator's
+// system.local row.
+func (s *CassandraStore) Health(ctx context.Context) error {
+	if err := s.session.Query(`SELECT release_version FROM system.local`).WithContext(ctx).Exec(); err != nil {
+		return fmt.Errorf("cassandra_store: %w", err)
+	}
+	return nil
+}
+
+// NewCassandraStoreManager wraps the store in a Manager and seeds the default role.
+func NewCassandraStoreManager(ctx context.Context, session *gocql.Session, keyspace string) (*Manager, error) {
+	s, err := NewCassandraStore(ctx, session, keyspace)
+	if err != nil {
+		return nil, err
+	}
+
+	def, _ := s.GetRoleByName(ctx, "default")
+	if def == nil {
+		def = &Role{Name: "default", Description: "Default role"}
+		if createErr := s.CreateRole(ctx, def); createErr != nil {
+			return nil, fmt.Errorf("failed to create default role: %w", createErr)
+		}
+	}
+
+	return &Manager{
+		Perms:           s,
+		Roles:           s,
+		Users:           s,
+		RP:              s,
+		UR:              s,
+		UG:              s,
+		GR:              s,
+		DefaultRoleName: "default",
+	}, nil
+}
+
+// t returns the keyspace-qualified table name.
+func (s *CassandraStore) t(table string) string {
+	return s.keyspace + "." + table
+}
+
+func (s *CassandraStore) query(ctx context.Context, stmt string, args ...interface{}) *gocql.Query {
+	return s.session.Query(stmt, args...).WithContext(ctx)
+}
+
+// insertUnique writes an index row with a lightweight transaction, reporting
+// the ID already holding the key when it is taken.
+func (s *CassandraStore) insertUnique(ctx context.Context, stmt string, args ...interface{}) (bool, string, error) {
+	existing := map[string]interface{}{}
+	applied, err := s.query(ctx, stmt, args...).MapScanCAS(existing)
+	if err != nil {
+		return false, "", err
+	}
+	id, _ := existing["id"].(string)
+	return applied, id, nil
+}
+
+//
+// ---------- Schema ----------
+//
+
+// EnsureSchema creates the keyspace and all required tables if they don't exist.
+func (s *CassandraStore) EnsureSchema(ctx context.Context) error {
+	stmts := []string{
+		fmt.Sprintf(`CREATE KEYSPACE IF NOT EXISTS %s
+			WITH replication = {'class': 'SimpleStrategy', 'replication_factor': 1}`, s.keyspace),
+
+		fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+			id          text PRIMARY KEY,
+			name        text,
+			description text,
+			labels      text,
+			resource    text,
+			action      text,
+			effect      text,
+			condition   text,
+			created_at  bigint,
+			updated_at  bigint,
+			created_by  text,
+			updated_by  text,
+			tenant_id   text
+		)`, s.t("permissions")),
+
+		fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+			resource text,
+			action   text,
+			id       text,
+			PRIMARY KEY ((resource, action))
+		)`, s.t("permissions_by_resource")),
+
+		fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+			id          text PRIMARY KEY,
+			name        text,
+			description text,
+			meta        text,
+			template    boolean,
+			priority    int,
+			generators  text,
+			created_at  bigint,
+			updated_at  bigint,
+			created_by  text,
+			updated_by  text,
+			tenant_id   text
+		)`, s.t("roles")),
+
+		fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+			name text PRIMARY KEY,
+			id   text
+		)`, s.t("roles_by_name")),
+
+		fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+			id         text PRIMARY KEY,
+			username   text,
+			email      text,
+			meta       text,
+			created_at bigint,
+			updated_at bigint,
+			created_by text,
+			updated_by text,
+			email_verified boolean,
+			status     text,
+			tenant_id  text
+		)`, s.t("users")),
+
+		fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+			username text PRIMARY KEY,
+			id       text
+		)`, s.t("users_by_username")),
+
+		fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+			email text PRIMARY KEY,
+			id    text
+		)`, s.t("users_by_email")),
+
+		fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+			role_id       text,
+			permission_id text,
+			name          text,
+			description   text,
+			labels        text,
+			resource      text,
+			action        text,
+			effect        text,
+			condition     text,
+			tenant_id     text,
+			created_at    bigint,
+			PRIMARY KEY (role_id, permission_id)
+		)`, s.t("role_permissions")),
+
+		fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+			permission_id text,
+			role_id       text,
+			PRIMARY KEY (permission_id, role_id)
+		)`, s.t("permission_roles")),
+
+		fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+			user_id     text,
+			role_id     text,
+			assigned_at bigint,
+			PRIMARY KEY (user_id, role_id)
+		)`, s.t("user_roles")),
+
+		fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+			user_id    text,
+			group_name text,
+			id         text,
+			created_at bigint,
+			updated_at bigint,
+			created_by text,
+			updated_by text,
+			level      text,
+			expires_at bigint,
+			tenant_id  text,
+			PRIMARY KEY (user_id, group_name)
+		)`, s.t("user_groups")),
+
+		fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+			group_name text,
+			user_id    text,
+			id         text,
+			created_at bigint,
+			updated_at bigint,
+			created_by text,
+			updated_by text,
+			level      text,
+			expires_at bigint,
+			tenant_id  text,
+			PRIMARY KEY (group_name, user_id)
+		)`, s.t("group_users")),
+
+		fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+			group_name text,
+			role_id    text,
+			created_at bigint,
+			PRIMARY KEY (group_name, role_id)
+		)`, s.t("group_roles")),
+
+		// Finding who holds a role is an admin read, so a secondary index
+		// serves it rather than another table to keep in step.
+		fmt.Sprintf(`CREATE INDEX IF NOT EXISTS user_roles_by_role ON %s (role_id)`, s.t("user_roles")),
+		fmt.Sprintf(`CREATE INDEX IF NOT EXISTS group_roles_by_role ON %s (role_id)`, s.t("group_roles")),
+	}
+
+	for _, stmt := range stmts {
+		if err := s.query(ctx, stmt).Exec(); err != nil {
+			return err
+		}
+	}
+
+	// Columns added after the first release. Cassandra 4 has no ADD IF NOT
+	// EXISTS and rejects a column that is already there.
+	migrations := []string{
+		`ALTER TABLE ` + s.t("permissions") + ` ADD effect text`,
+		`ALTER TABLE ` + s.t("role_permissions") + ` ADD effect text`,
+		`ALTER TABLE ` + s.t("permissions") + ` ADD condition text`,
+		`ALTER TABLE ` + s.t("role_permissions") + ` ADD condition text`,
+		`ALTER TABLE ` + s.t("roles") + ` ADD meta text`,
+		`ALTER TABLE ` + s.t("permissions") + ` ADD updated_at bigint`,
+		`ALTER TABLE ` + s.t("permissions") + ` ADD created_by text`,
+		`ALTER TABLE ` + s.t("permissions") + ` ADD updated_by text`,
+		`ALTER TABLE ` + s.t("roles") + ` ADD updated_at bigint`,
+		`ALTER TABLE ` + s.t("roles") + ` ADD created_by text`,
+		`ALTER TABLE ` + s.t("roles") + ` ADD updated_by text`,
+		`ALTER TABLE ` + s.t("roles") + ` ADD template boolean`,
+		`ALTER TABLE ` + s.t("roles") + ` ADD priority int`,
+		`ALTER TABLE ` + s.t("roles") + ` ADD generators text`,
+		`ALTER TABLE ` + s.t("users") + ` ADD updated_at bigint`,
+		`ALTER TABLE ` + s.t("users") + ` ADD created_by text`,
+		`ALTER TABLE ` + s.t("users") + ` ADD updated_by text`,
+		`ALTER TABLE ` + s.t("users") + ` ADD email_verified boolean`,
+		`ALTER TABLE ` + s.t("users") + ` ADD status text`,
+		`ALTER TABLE ` + s.t("user_groups") + ` ADD updated_at bigint`,
+		`ALTER TABLE ` + s.t("user_groups") + ` ADD created_by text`,
+		`ALTER TABLE ` + s.t("user_groups") + ` ADD updated_by text`,
+		`ALTER TABLE ` + s.t("group_users") + ` ADD updated_at bigint`,
+		`ALTER TABLE ` + s.t("group_users") + ` ADD created_by text`,
+		`ALTER TABLE ` + s.t("group_users") + ` ADD updated_by text`,
+		`ALTER TABLE ` + s.t("user_groups") + ` ADD level text`,
+		`ALTER TABLE ` + s.t("group_users") + ` ADD level text`,
+		`ALTER TABLE ` + s.t("user_groups") + ` ADD expires_at bigint`,
+		`ALTER TABLE ` + s.t("group_users") + ` ADD expires_at bigint`,
+		`ALTER TABLE ` + s.t("permissions") + ` ADD name text`,
+		`ALTER TABLE ` + s.t("permissions") + ` ADD description text`,
+		`ALTER TABLE ` + s.t("permissions") + ` ADD labels text`,
+		`ALTER TABLE ` + s.t("role_permissions") + ` ADD name text`,
+		`ALTER TABLE ` + s.t("role_permissions") + ` ADD description text`,
+		`ALTER TABLE ` + s.t("role_permissions") + ` ADD labels text`,
+		`ALTER TABLE ` + s.t("permissions") + ` ADD tenant_id text`,
+		`ALTER TABLE ` + s.t("role_permissions") + ` ADD tenant_id text`,
+		`ALTER TABLE ` + s.t("roles") + ` ADD tenant_id text`,
+		`ALTER TABLE ` + s.t("users") + ` ADD tenant_id text`,
+		`ALTER TABLE ` + s.t("user_groups") + ` ADD tenant_id text`,
+		`ALTER TABLE ` + s.t("group_users") + ` ADD tenant_id text`,
+	}
+	for _, stmt := range migrations {
+		err := s.query(ctx, stmt).Exec()
+		if err != nil && !strings.Contains(err.Error(), "conflicts with an existing column") {
+			return err
+		}
+	}
+	return nil
+}
+
+//
+// ---------- UserRepo ----------
+//
+
+func (s *CassandraStore) GetUserByID(ctx context.Context, id string) (*User, error) {
+	u := &User{}
+	var meta string
+	err := s.query(ctx,
+		`SELECT id, username, email, meta, created_at, updated_at, created_by, updated_by, email_verified, status, tenant_id FROM `+s.t("users")+` WHERE id = ?`, id).
+		Scan(&u.ID, &u.Username, &u.Email, &meta, &u.CreatedAt, &u.UpdatedAt, &u.CreatedBy, &u.UpdatedBy, &u.EmailVerified, &u.Status, &u.TenantID)
+	if errors.Is(err, gocql.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if meta != "" {
+		if err := json.Unmarshal([]byte(meta), &u.Meta); err != nil {
+			return nil, fmt.Errorf("failed to decode user meta: %w", err)
+		}
+	}
+	return u, nil
+}
+
+func (s *CassandraStore) GetUserByMeta(ctx context.Context, meta map[string]interface{}) (*User, error) {
+	allowed := map[string]bool{"id": true, "username": true, "email": true}
+	want := make(map[string]string, len(meta))
+	for k, v := range meta {
+		if !allowed[k] {
+			return nil, fmt.Errorf("GetUserByMeta: unsupported field %q", k)
+		}
+		want[k] = fmt.Sprint(v)
+	}
+	if len(want) == 0 {
+		return nil, errors.New("GetUserByMeta: no filter provided")
+	}
+
+	id, ok := want["id"]
+	if !ok {
+		var err error
+		if username, ok := want["username"]; ok {
+			err = s.query(ctx, `SELECT id FROM `+s.t("users_by_username")+` WHERE username = ?`, username).Scan(&id)
+		} else {
+			err = s.query(ctx, `SELECT id FROM `+s.t("users_by_email")+` WHERE email = ?`, want["email"]).Scan(&id)
+		}
+		if errors.Is(err, gocql.ErrNotFound) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	u, err := s.GetUserByID(ctx, id)
+	if err != nil || u == nil {
+		return nil, err
+	}
+	if v, ok := want["username"]; ok && u.Username != v {
+		return nil, nil
+	}
+	if v, ok := want["email"]; ok && u.Email != v {
+		return nil, nil
+	}
+	return u, nil
+}
+
+func (s *CassandraStore) ListAllUsers(ctx context.Context) ([]*User, error) {
+	iter := s.query(ctx, `SELECT id, username, email, meta, created_at, updated_at, created_by, updated_by, email_verified, status, tenant_id FROM `+s.t("users")).Iter()
+
+	var out []*User
+	u := &User{}
+	var meta string
+	for iter.Scan(&u.ID, &u.Username, &u.Email, &meta, &u.CreatedAt, &u.UpdatedAt, &u.CreatedBy, &u.UpdatedBy, &u.EmailVerified, &u.Status, &u.TenantID) {
+		if meta != "" {
+			if err := json.Unmarshal([]byte(meta), &u.Meta); err != nil {
+				_ = iter.Close()
+				return nil, fmt.Errorf("failed to decode user meta: %w", err)
+			}
+		}
+		out = append(out, u)
+		u, meta = &User{}, ""
+	}
+	if err := iter.Close(); err != nil {
+		return nil, fmt.Errorf("failed to decode user: %w", err)
+	}
+	return out, nil
+}
+
+func (s *CassandraStore) CreateUser(ctx context.Context, u *User) error {
+	if u.ID == "" {
+		u.ID = generateID(s.ids, KindUser)
+	}
+	u.CreatedAt = time.Now().Unix()
+
+	var meta string
+	if len(u.Meta) > 0 {
+		b, err := json.Marshal(u.Meta)
+		if err != nil {
+			return err
+		}
+		meta = string(b)
+	}
+
+	applied, _, err := s.insertUnique(ctx,
+		`INSERT INTO `+s.t("users_by_username")+` (username, id) VALUES (?, ?) IF NOT EXISTS`, u.Username, u.ID)
+	if err != nil {
+		return err
+	}
+	if !applied {
+		return fmt.Errorf("cassandra_store: username %q already exists", u.Username)
+	}
+
+	if u.Email != "" {
+		applied, _, err = s.insertUnique(ctx,
+			`INSERT INTO `+s.t("users_by_email")+` (email, id) VALUES (?, ?) IF NOT EXISTS`, u.Email, u.ID)
+		if err != nil || !applied {
+			_ = s.query(ctx, `DELETE FROM `+s.t("users_by_username")+` WHERE username = ?`, u.Username).Exec()
+			if err != nil {
+				return err
+			}
+			return fmt.Errorf("cassandra_store: email %q already exists", u.Email)
+		}
+	}
+
+	return s.query(ctx,
+		`INSERT INTO `+s.t("users")+` (id, username, email, meta, created_at, updated_at, created_by, updated_by, email_verified, status, tenant_id) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		u.ID, u.Username, u.Email, meta, u.CreatedAt, u.UpdatedAt, u.CreatedBy, u.UpdatedBy, u.EmailVerified, string(u.Status), u.TenantID).Exec()
+}
+
+// SetUserStatus and SetEmailVerified update with IF EXISTS, as a plain
+// Cassandra UPDATE would create a row for an unknown id.
+func (s *CassandraStore) SetUserStatus(ctx context.Context, id string, status UserStatus) error {
+	var updatedBy string
+	var updatedAt int64
+	stampUpdated(ctx, time.Now(), &updatedBy, &updatedAt)
+	return s.query(ctx,
+		`UPDATE `+s.t("users")+` SET status = ?, updated_at = ?, updated_by = ? WHERE id = ? IF EXISTS`,
+		string(status), updatedAt, updatedBy, id).Exec()
+}
+
+func (s *CassandraStore) SetEmailVerified(ctx context.Context, id string, verified bool) error {
+	var updatedBy string
+	var updatedAt int64
+	stampUpdated(ctx, time.Now(), &updatedBy, &updatedAt)
+	return s.query(ctx,
+		`UPDATE `+s.t("users")+` SET email_verified = ?, updated_at = ?, updated_by = ? WHERE id = ? IF EXISTS`,
+		verified, updatedAt, updatedBy, id).Exec()
+}
+
+func (s *CassandraStore) DeleteUser(ctx context.Context, id string) error {
+	u, err := s.GetUserByID(ctx, id)
+	if err != nil || u == nil {
+		return err
+	}
+
+	b := s.session.NewBatch(gocql.LoggedBatch).WithContext(ctx)
+	b.Query(`DELETE FROM `+s.t("users")+` WHERE id = ?`, id)
+	b.Query(`DELETE FROM `+s.t("users_by_username")+` WHERE username = ?`, u.Username)
+	if u.Email != "" {
+		b.Query(`DELETE FROM `+s.t("users_by_email")+` WHERE email = ?`, u.Email)
+	}
+	return s.session.ExecuteBatch(b)
+}
+
+func (s *CassandraStore) GetGroupsByUserID(ctx context.Context, userID string) ([]*UserGroup, error) {
+	iter := s.query(ctx,
+		`SELECT id, user_id, group_name, created_at, updated_at, created_by, updated_by, level, expires_at, tenant_id FROM `+s.t("user_groups")+` WHERE user_id = ?`, userID).Iter()
+
+	var out []*UserGroup
+	ug := &UserGroup{}
+	for iter.Scan(&ug.ID, &ug.UserID, &ug.GroupName, &ug.CreatedAt, &ug.UpdatedAt, &ug.CreatedBy, &ug.UpdatedBy, &ug.Level, &ug.ExpiresAt, &ug.TenantID) {
+		out = append(out, ug)
+		ug = &UserGroup{}
+	}
+	return out, iter.Close()
+}
+
+//
+// ---------- PermissionRepo ----------
+//
+
+func (s *CassandraStore) GetPermissionByID(ctx context.Context, id string) (*Permission, error) {
+	p := &Permission{}
+	var labels, action, effect string
+	err := s.query(ctx,
+		`SELECT id, name, description, labels, resource, action, effect, condition, created_at, updated_at, created_by, updated_by, tenant_id FROM `+s.t("permissions")+` WHERE id = ?`, id).
+		Scan(&p.ID, &p.Name, &p.Description, &labels, &p.Resource, &action, &effect, &p.Condition, &p.CreatedAt, &p.UpdatedAt, &p.CreatedBy, &p.UpdatedBy, &p.TenantID)
+	if errors.Is(err, gocql.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	p.Action = Action(action)
+	p.Effect = Effect(effect)
+	if err := decodeLabels(labels, &p.Labels); err != nil {
+		return nil, fmt.Errorf("failed to decode permission labels: %w", err)
+	}
+	return p, nil
+}
+
+func (s *CassandraStore) ListAllPermissions(ctx context.Context) ([]*Permission, error) {
+	iter := s.query(ctx, `SELECT id, name, description, labels, resource, action, effect, condition, created_at, updated_at, created_by, updated_by, tenant_id FROM `+s.t("permissions")).Iter()
+
+	var out []*Permission
+	p := &Permission{}
+	var labels, action, effect string
+	for iter.Scan(&p.ID, &p.Name, &p.Description, &labels, &p.Resource, &action, &effect, &p.Condition, &p.CreatedAt, &p.UpdatedAt, &p.CreatedBy, &p.UpdatedBy, &p.TenantID) {
+		p.Action = Action(action)
+		p.Effect = Effect(effect)
+		if err := decodeLabels(labels, &p.Labels); err != nil {
+			_ = iter.Close()
+			return nil, fmt.Errorf("failed to decode permission labels: %w", err)
+		}
+		out = append(out, p)
+		p, labels = &Permission{}, ""
+	}
+	if err := iter.Close(); err != nil {
+		return nil, fmt.Errorf("failed to decode permission: %w", err)
+	}
+	return out, nil
+}
+
+func (s *CassandraStore) GetPermissionByResource(ctx context.Context, resource string, action Action) (*Permission, error) {
+	var id string
+	err := s.query(ctx,
+		`SELECT id FROM `+s.t("permissions_by_resource")+` WHERE resource = ? AND action = ?`,
+		resource, string(action)).Scan(&id)
+	if errors.Is(err, gocql.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s.GetPermissionByID(ctx, id)
+}
+
+func (s *CassandraStore) CreatePermission(ctx context.Context, p *Permission) error {
+	if p.ID == "" {
+		p.ID = generateID(s.ids, KindPermission)
+	}
+	p.CreatedAt = time.Now().Unix()
+
+	applied, existingID, err := s.insertUnique(ctx,
+		`INSERT INTO `+s.t("permissions_by_resource")+` (resource, action, id) VALUES (?, ?, ?) IF NOT EXISTS`,
+		p.Resource, string(p.Action), p.ID)
+	if err != nil {
+		return err
+	}
+	if !applied {
+		existing, err := s.GetPermissionByID(ctx, existingID)
+		if err != nil {
+			return err
+		}
+		if existing != nil {
+			*p = *existing
+			return nil
+		}
+		// an earlier create stopped between the two inserts: finish it
+		// under the ID the index already points to
+		if existingID == "" {
+			return fmt.Errorf("cassandra_store: permission %s on %q already exists", p.Action, p.Resource)
+		}
+		p.ID = existingID
+	}
+
+	labels, err := encodeLabels(p.Labels)
+	if err != nil {
+		return err
+	}
+	// Roles bound to the ID before the permission existed hold bindings
+	// without details; fill them in so ListPermissionDetails returns it.
+	roleIDs, err := s.scanStrings(ctx,
+		`SELECT role_id FROM `+s.t("permission_roles")+` WHERE permission_id = ?`, p.ID)
+	if err != nil {
+		return err
+	}
+
+	b := s.session.NewBatch(gocql.LoggedBatch).WithContext(ctx)
+	b.Query(`INSERT INTO `+s.t("permissions")+` (id, name, description, labels, resource, action, effect, condition, created_at, updated_at, created_by, updated_by, tenant_id) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		p.ID, p.Name, p.Description, labels, p.Resource, string(p.Action), string(p.Effect), p.Condition, p.CreatedAt, p.UpdatedAt, p.CreatedBy, p.UpdatedBy, p.TenantID)
+	for _, roleID := range roleIDs {
+		b.Query(`UPDATE `+s.t("role_permissions")+` SET name = ?, description = ?, labels = ?, resource = ?, action = ?, effect = ?, condition = ?, tenant_id = ? WHERE role_id = ? AND permission_id = ?`,
+			p.Name, p.Description, labels, p.Resource, string(p.Action), string(p.Effect), p.Condition, p.TenantID, roleID, p.ID)
+	}
+	return s.session.ExecuteBatch(b)
+}
+
+func (s *CassandraStore) DeletePermission(ctx context.Context, id string) error {
+	p, err := s.GetPermissionByID(ctx, id)
+	if err != nil || p == nil {
+		return err
+	}
+
+	// Drop the denormalized bindings as well, otherwise ListPermissionDetails
+	// would keep returning the deleted permission.
+	roleIDs, err := s.scanStrings(ctx,
+		`SELECT role_id FROM `+s.t("permission_roles")+` WHERE permission_id = ?`, id)
+	if err != nil {
+		return err
+	}
+
+	b := s.session.NewBatch(gocql.LoggedBatch).WithContext(ctx)
+	b.Query(`DELETE FROM `+s.t("permissions")+` WHERE id = ?`, id)
+	b.Query(`DELETE FROM `+s.t("permissions_by_resource")+` WHERE resource = ? AND action = ?`, p.Resource, string(p.Action))
+	b.Query(`DELETE FROM `+s.t("permission_roles")+` WHERE permission_id = ?`, id)
+	for _, roleID := range roleIDs {
+		b.Query(`DELETE FROM `+s.t("role_permissions")+` WHERE role_id = ? AND permission_id = ?`, roleID, id)
+	}
+	return s.session.ExecuteBatch(b)
+}
+
+//
+// ---------- RoleRepo ----------
+//
+
+func (s *CassandraStore) CreateRole(ctx context.Context, r *Role) error {
+	if r.ID == "" {
+		r.ID = generateID(s.ids, KindRole)
+	}
+	r.CreatedAt = time.Now().Unix()
+	meta, err := encodeMeta(r.Meta)
+	if err != nil {
+		return err
+	}
+	generators, err := encodeGenerators(r.Generators)
+	if err != nil {
+		return err
+	}
+
+	applied, _, err := s.insertUnique(ctx,
+		`INSERT INTO `+s.t("roles_by_name")+` (name, id) VALUES (?, ?) IF NOT EXISTS`, r.Name, r.ID)
+	if err != nil {
+		return err
+	}
+	if !applied {
+		return fmt.Errorf("cassandra_store: role %q already exists", r.Name)
+	}
+
+	return s.query(ctx,
+		`INSERT INTO `+s.t("roles")+` (id, name, description, meta, template, priority, generators, created_at, updated_at, created_by, updated_by, tenant_id) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		r.ID, r.Name, r.Description, meta, r.Template, r.Priority, generators, r.CreatedAt, r.UpdatedAt, r.CreatedBy, r.UpdatedBy, r.TenantID).Exec()
+}
+
+func (s *CassandraStore) GetRoleByName(ctx context.Context, name string) (*Role, error) {
+	var id string
+	err := s.query(ctx, `SELECT id FROM `+s.t("roles_by_name")+` WHERE name = ?`, name).Scan(&id)
+	if errors.Is(err, gocql.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s.GetRoleByID(ctx, id)
+}
+
+func (s *CassandraStore) GetRoleByID(ctx context.Context, id string) (*Role, error) {
+	r := &Role{}
+	var meta, generators string
+	err := s.query(ctx,
+		`SELECT id, name, description, meta, template, priority, generators, created_at, updated_at, created_by, updated_by, tenant_id FROM `+s.t("roles")+` WHERE id = ?`, id).
+		Scan(&r.ID, &r.Name, &r.Description, &meta, &r.Template, &r.Priority, &generators, &r.CreatedAt, &r.UpdatedAt, &r.CreatedBy, &r.UpdatedBy, &r.TenantID)
+	if errors.Is(err, gocql.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := decodeMeta(meta, &r.Meta); err != nil {
+		return nil, fmt.Errorf("failed to decode role meta: %w", err)
+	}
+	if err := decodeGenerators(generators, &r.Generators); err != nil {
+		return nil, fmt.Errorf("failed to decode role generators: %w", err)
+	}
+	return r, nil
+}
+
+func (s *CassandraStore) DeleteRole(ctx context.Context, id string) error {
+	r, err := s.GetRoleByID(ctx, id)
+	if err != nil || r == nil {
+		return err
+	}
+
+	b := s.session.NewBatch(gocql.LoggedBatch).WithContext(ctx)
+	b.Query(`DELETE FROM `+s.t("roles")+` WHERE id = ?`, id)
+	b.Query(`DELETE FROM `+s.t("roles_by_name")+` WHERE name = ?`, r.Name)
+	return s.session.ExecuteBatch(b)
+}
+
+func (s *CassandraStore) ListAllRoles(ctx context.Context) ([]*Role, error) {
+	iter := s.query(ctx, `SELECT id, name, description, meta, template, priority, generators, created_at, updated_at, created_by, updated_by, tenant_id FROM `+s.t("roles")).Iter()
+
+	var out []*Role
+	r := &Role{}
+	var meta, generators string
+	for iter.Scan(&r.ID, &r.Name, &r.Description, &meta, &r.Template, &r.Priority, &generators, &r.CreatedAt, &r.UpdatedAt, &r.CreatedBy, &r.UpdatedBy, &r.TenantID) {
+		if err := decodeMeta(meta, &r.Meta); err != nil {
+			_ = iter.Close()
+			return nil, fmt.Errorf("failed to decode role meta: %w", err)
+		}
+		if err := decodeGenerators(generators, &r.Generators); err != nil {
+			_ = iter.Close()
+			return nil, fmt.Errorf("failed to decode role generators: %w", err)
+		}
+		out = append(out, r)
+		r, meta, generators = &Role{}, "", ""
+	}
+	if err := iter.Close(); err != nil {
+		return nil, fmt.Errorf("failed to decode role: %w", err)
+	}
+	return out, nil
+}
+
+//
+// ---------- RolePermissionRepo ----------
+//
+
+func (s *CassandraStore) AddRP(ctx context.Context, roleID, permID string) error {
+	p, err := s.GetPermissionByID(ctx, permID)
+	if err != nil {
+		return err
+	}
+	var name, description, labels, resource, action, effect, condition, tenantID string
+	if p != nil {
+		name, description, resource, action, effect, condition, tenantID = p.Name, p.Description, p.Resource, string(p.Action), string(p.Effect), p.Condition, p.TenantID
+		if labels, err = encodeLabels(p.Labels); err != nil {
+			return err
+		}
+	}
+
+	b := s.session.NewBatch(gocql.LoggedBatch).WithContext(ctx)
+	b.Query(`INSERT INTO `+s.t("role_permissions")+` (role_id, permission_id, name, description, labels, resource, action, effect, condition, tenant_id, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		roleID, permID, name, description, labels, resource, action, effect, condition, tenantID, time.Now().Unix())
+	b.Query(`INSERT INTO `+s.t("permission_roles")+` (permission_id, role_id) VALUES (?, ?)`, permID, roleID)
+	return s.session.ExecuteBatch(b)
+}
+
+func (s *CassandraStore) Remove(ctx context.Context, roleID, permID string) error {
+	b := s.session.NewBatch(gocql.LoggedBatch).WithContext(ctx)
+	b.Query(`DELETE FROM `+s.t("role_permissions")+` WHERE role_id = ? AND permission_id = ?`, roleID, permID)
+	b.Query(`DELETE FROM `+s.t("permission_roles")+` WHERE permission_id = ? AND role_id = ?`, permID, roleID)
+	return s.session.ExecuteBatch(b)
+}
+
+func (s *CassandraStore) ListPermissions(ctx context.Context, roleID string) ([]string, error) {
+	return s.scanStrings(ctx,
+		`SELECT permission_id FROM `+s.t("role_permissions")+` WHERE role_id = ?`, roleID)
+}
+
+// ListPermissionDetails returns every permission bound to roleID from the
+// role's partition alone, without a per-permission lookup.
+func (s *CassandraStore) ListPermissionDetails(ctx context.Context, roleID string) ([]*Permission, error) {
+	iter := s.query(ctx,
+		`SELECT permission_id, name, description, labels, resource, action, effect, condition, tenant_id FROM `+s.t("role_permissions")+` WHERE role_id = ?`, roleID).Iter()
+
+	var out []*Permission
+	var id, name, description, labels, resource, action, effect, condition, tenantID string
+	for iter.Scan(&id, &name, &description, &labels, &resource, &action, &effect, &condition, &tenantID) {
+		// A binding to a permission that was never created carries no
+		// details; CreatePermission fills them in once it is.
+		if resource == "" {
+			continue
+		}
+		p := &Permission{ID: id, Name: name, Description: description, Resource: resource, Action: Action(action), Effect: Effect(effect), Condition: condition, TenantID: tenantID}
+		if err := decodeLabels(labels, &p.Labels); err != nil {
+			_ = iter.Close()
+			return nil, fmt.Errorf("failed to decode permission labels: %w", err)
+		}
+		out = append(out, p)
+	}
+	return out, iter.Close()
+}
+
+//
+// ---------- UserRoleRepo ----------
+//
+
+func (s *CassandraStore) AddUR(ctx context.Context, userID, roleID string) error {
+	return s.query(ctx,
+		`INSERT INTO `+s.t("user_roles")+` (user_id, role_id, assigned_at) VALUES (?, ?, ?)`,
+		userID, roleID, time.Now().Unix()).Exec()
+}
+
+func (s *CassandraStore) RemoveUR(ctx context.Context, userID, roleID string) error {
+	return s.query(ctx,
+		`DELETE FROM `+s.t("user_roles")+` WHERE user_id = ? AND role_id = ?`, userID, roleID).Exec()
+}
+
+func (s *CassandraStore) ListRoles(ctx context.Context, userID string) ([]string, error) {
+	return s.scanStrings(ctx,
+		`SELECT role_id FROM `+s.t("user_roles")+` WHERE user_id = ?`, userID)
+}
+
+func (s *CassandraStore) ListUsersForRole(ctx context.Context, roleID string) ([]string, error) {
+	return s.scanStrings(ctx,
+		`SELECT user_id FROM `+s.t("user_roles")+` WHERE role_id = ?`, roleID)
+}
+
+//
+// ---------- UserGroupRepo ----------
+//
+
+func (s *CassandraStore) AddUserToGroup(ctx context.Context, ug *UserGroup) error {
+	if ug.UserID == "" {
+		return errors.New("user id is empty")
+	}
+
+	if ug.ID == "" {
+		ug.ID = generateID(s.ids, KindUserGroup)
+	}
+	ug.CreatedAt = time.Now().Unix()
+
+	b := s.session.NewBatch(gocql.LoggedBatch).WithContext(ctx)
+	b.Query(`INSERT INTO `+s.t("user_groups")+` (user_id, group_name, id, created_at, updated_at, created_by, updated_by, level, expires_at, tenant_id) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		ug.UserID, ug.GroupName, ug.ID, ug.CreatedAt, ug.UpdatedAt, ug.CreatedBy, ug.UpdatedBy, string(ug.Level), ug.ExpiresAt, ug.TenantID)
+	b.Query(`INSERT INTO `+s.t("group_users")+` (group_name, user_id, id, created_at, updated_at, created_by, updated_by, level, expires_at, tenant_id) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		ug.GroupName, ug.UserID, ug.ID, ug.CreatedAt, ug.UpdatedAt, ug.CreatedBy, ug.UpdatedBy, string(ug.Level), ug.ExpiresAt, ug.TenantID)
+	return s.session.ExecuteBatch(b)
+}
+
+func (s *CassandraStore) RemoveUserFromGroup(ctx context.Context, groupName string, ug *UserGroup) error {
+	if ug.UserID == "" {
+		return errors.New("user id is empty")
+	}
+
+	b := s.session.NewBatch(gocql.LoggedBatch).WithContext(ctx)
+	b.Query(`DELETE FROM `+s.t("user_groups")+` WHERE user_id = ? AND group_name = ?`, ug.UserID, groupName)
+	b.Query(`DELETE FROM `+s.t("group_users")+` WHERE group_name = ? AND user_id = ?`, groupName, ug.UserID)
+	return s.session.ExecuteBatch(b)
+}
+
+func (s *CassandraStore) GetUsersByGroupID(ctx context.Context, groupName string) ([]*UserGroup, error) {
+	iter := s.query(ctx,
+		`SELECT id, user_id, group_name, created_at, updated_at, created_by, updated_by, level, expires_at, tenant_id FROM `+s.t("group_users")+` WHERE group_name = ?`, groupName).Iter()
+
+	var out []*UserGroup
+	ug := &UserGroup{}
+	for iter.Scan(&ug.ID, &ug.UserID, &ug.GroupName, &ug.CreatedAt, &ug.UpdatedAt, &ug.CreatedBy, &ug.UpdatedBy, &ug.Level, &ug.ExpiresAt, &ug.TenantID) {
+		out = append(out, ug)
+		ug = &UserGroup{}
+	}
+	return out, iter.Close()
+}
+
+// ListExpiringMemberships scans every partition of user_groups, so it is
+// meant for periodic checks such as Manager.NotifyExpiringEvery rather than
+// the request path.
+func (s *CassandraStore) ListExpiringMemberships(ctx context.Context, after, before int64) ([]*UserGroup, error) {
+	iter := s.query(ctx,
+		`SELECT id, user_id, group_name, created_at, updated_at, created_by, updated_by, level, expires_at, tenant_id FROM `+s.t("user_groups")+`
+		 WHERE expires_at > ? AND expires_at <= ? ALLOW FILTERING`, after, before).Iter()
+
+	var out []*UserGroup
+	ug := &UserGroup{}
+	for iter.Scan(&ug.ID, &ug.UserID, &ug.GroupName, &ug.CreatedAt, &ug.UpdatedAt, &ug.CreatedBy, &ug.UpdatedBy, &ug.Level, &ug.ExpiresAt, &ug.TenantID) {
+		out = append(out, ug)
+		ug = &UserGroup{}
+	}
+	return out, iter.Close()
+}
+
+//
+// ---------- GroupRoleRepo ----------
+//
+
+func (s *CassandraStore) AddRoleToGroup(ctx context.Context, groupID, roleID string) error {
+	return s.query(ctx,
+		`INSERT INTO `+s.t("group_roles")+` (group_name, role_id, created_at) VALUES (?, ?, ?)`,
+		groupID, roleID, time.Now().Unix()).Exec()
+}
+
+func (s *CassandraStore) RemoveRoleFromGroup(ctx context.Context, groupID, roleID string) error {
+	return s.query(ctx,
+		`DELETE FROM `+s.t("group_roles")+` WHERE group_name = ? AND role_id = ?`, groupID, roleID).Exec()
+}
+
+func (s *CassandraStore) ListRolesForGroup(ctx context.Context, groupID string) ([]string, error) {
+	return s.scanStrings(ctx,
+		`SELECT role_id FROM `+s.t("group_roles")+` WHERE group_name = ?`, groupID)
+}
+
+func (s *CassandraStore) ListGroupsForRole(ctx context.Context, roleID string) ([]string, error) {
+	return s.scanStrings(ctx,
+		`SELECT group_name FROM `+s.t("group_roles")+` WHERE role_id = ?`, roleID)
+}
+
+func (s *CassandraStore) scanStrings(ctx context.Context, stmt string, args ...interface{}) ([]string, error) {
+	iter := s.query(ctx, stmt, args...).Iter()
+
+	var out []string
+	var v string
+	for iter.Scan(&v) {
+		out = append(out, v)
+	}
+	return out, iter.Close()
+}
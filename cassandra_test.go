@@ -0,0 +1,76 @@
+package rbac
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gocql/gocql"
+	"github.com/testcontainers/testcontainers-go/modules/cassandra"
+)
+
+func newCassandraStore(t *testing.T) *CassandraStore {
+	t.Helper()
+	ctx := context.Background()
+
+	ctr, err := cassandra.Run(ctx, "cassandra:4.1.3")
+	if err != nil {
+		t.Fatalf("start cassandra container: %v", err)
+	}
+	t.Cleanup(func() { _ = ctr.Terminate(ctx) })
+
+	host, err := ctr.ConnectionHost(ctx)
+	if err != nil {
+		t.Fatalf("cassandra host: %v", err)
+	}
+
+	cluster := gocql.NewCluster(host)
+	cluster.Timeout = 30 * time.Second
+	cluster.ConnectTimeout = 30 * time.Second
+	session, err := cluster.CreateSession()
+	if err != nil {
+		t.Fatalf("create session: %v", err)
+	}
+	t.Cleanup(session.Close)
+
+	store, err := NewCassandraStore(ctx, session, "rbac_test")
+	if err != nil {
+		t.Fatalf("NewCassandraStore: %v", err)
+	}
+	return store
+}
+
+func TestCassandraStore(t *testing.T) {
+	s := newCassandraStore(t)
+	runSuite(t, s)
+
+	t.Run("ListPermissionDetails", func(t *testing.T) {
+		ctx := context.Background()
+		p := &Permission{Resource: "details", Action: ActionRead}
+		if err := s.CreatePermission(ctx, p); err != nil {
+			t.Fatalf("CreatePermission: %v", err)
+		}
+		if err := s.AddRP(ctx, "details-role", p.ID); err != nil {
+			t.Fatalf("AddRP: %v", err)
+		}
+
+		perms, err := s.ListPermissionDetails(ctx, "details-role")
+		if err != nil {
+			t.Fatalf("ListPermissionDetails: %v", err)
+		}
+		if len(perms) != 1 || perms[0].Resource != "details" || perms[0].Action != ActionRead {
+			t.Fatalf("unexpected permissions: %+v", perms)
+		}
+
+		if err := s.DeletePermission(ctx, p.ID); err != nil {
+			t.Fatalf("DeletePermission: %v", err)
+		}
+		perms, err = s.ListPermissionDetails(ctx, "details-role")
+		if err != nil {
+			t.Fatalf("ListPermissionDetails: %v", err)
+		}
+		if len(perms) != 0 {
+			t.Fatalf("expected bindings to be removed with the permission, got %+v", perms)
+		}
+	})
+}
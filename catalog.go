@@ -0,0 +1,184 @@
+package rbac
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// KindNotification is passed to IDGenerator.NewID for owner notifications.
+const KindNotification = "notification"
+
+// Changes reported in an OwnerNotification.
+const (
+	ChangePermissionCreated  = "permission_created"
+	ChangePermissionAttached = "permission_attached"
+)
+
+// ErrNotificationNotFound is returned by Acknowledge for an ID that is not
+// pending.
+var ErrNotificationNotFound = errors.New("rbac: notification not found")
+
+// CatalogEntry makes Team the owner of the resources matching Pattern.
+type CatalogEntry struct {
+	Pattern string `json:"pattern"`
+	Team    string `json:"team"`
+	// RequireAck keeps each notification sent to the team pending until
+	// someone acknowledges it.
+	RequireAck bool `json:"require_ack,omitempty"`
+}
+
+// OwnerNotification tells a resource's owning team that a permission on
+// its resources was created or attached to a role.
+type OwnerNotification struct {
+	ID         string     `json:"id"`
+	Team       string     `json:"team"`
+	Change     string     `json:"change"`
+	Permission Permission `json:"permission"`
+	// RoleID is the role the permission was attached to, for
+	// ChangePermissionAttached.
+	RoleID     string    `json:"role_id,omitempty"`
+	Source     string    `json:"source"`
+	At         time.Time `json:"at"`
+	RequireAck bool      `json:"require_ack,omitempty"`
+	AckedBy    string    `json:"acked_by,omitempty"`
+	AckedAt    time.Time `json:"acked_at,omitzero"`
+}
+
+// Notifier delivers owner notifications, e.g. to a chat channel or a ticket
+// queue. Set one on Manager.Notifier.
+type Notifier interface {
+	Notify(ctx context.Context, n *OwnerNotification) error
+}
+
+// NotifierFunc adapts a function to Notifier.
+type NotifierFunc func(ctx context.Context, n *OwnerNotification) error
+
+func (f NotifierFunc) Notify(ctx context.Context, n *OwnerNotification) error { return f(ctx, n) }
+
+// ResourceCatalog records which team owns which resources, and the owner
+// notifications still waiting for acknowledgment. It is safe for concurrent
+// use; pending notifications live in memory only.
+type ResourceCatalog struct {
+	mu      sync.RWMutex
+	entries []CatalogEntry
+	pending map[string]*OwnerNotification
+}
+
+// NewResourceCatalog returns a catalog holding entries.
+func NewResourceCatalog(entries ...CatalogEntry) (*ResourceCatalog, error) {
+	c := &ResourceCatalog{pending: map[string]*OwnerNotification{}}
+	for _, e := range entries {
+		if err := c.Register(e); err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// Register adds e, replacing any entry with the same pattern.
+func (c *ResourceCatalog) Register(e CatalogEntry) error {
+	if e.Pattern == "" || e.Team == "" {
+		return errors.New("rbac: catalog entry needs a pattern and a team")
+	}
+	if _, err := matchResource(e.Pattern, ""); err != nil {
+		return fmt.Errorf("rbac: catalog pattern %q: %w", e.Pattern, err)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i := range c.entries {
+		if c.entries[i].Pattern == e.Pattern {
+			c.entries[i] = e
+			return nil
+		}
+	}
+	c.entries = append(c.entries, e)
+	return nil
+}
+
+// Entries returns the catalog's entries.
+func (c *ResourceCatalog) Entries() []CatalogEntry {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return append([]CatalogEntry(nil), c.entries...)
+}
+
+// Owners returns the entries whose resources overlap a permission's
+// resource: the entry's pattern matches it, or it matches the entry's
+// pattern, so both billing/* and ** concern the owner of billing/**.
+func (c *ResourceCatalog) Owners(resource string) []CatalogEntry {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	var out []CatalogEntry
+	for _, e := range c.entries {
+		a, _ := matchResource(e.Pattern, resource)
+		b, _ := matchResource(resource, e.Pattern)
+		if a || b {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+// Pending returns the notifications awaiting acknowledgment, oldest first,
+// for team or, when team is "", for every team.
+func (c *ResourceCatalog) Pending(team string) []*OwnerNotification {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	var out []*OwnerNotification
+	for _, n := range c.pending {
+		if team == "" || n.Team == team {
+			cp := *n
+			out = append(out, &cp)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].At.Before(out[j].At) })
+	return out
+}
+
+// Acknowledge marks the pending notification id as seen by by and returns
+// it.
+func (c *ResourceCatalog) Acknowledge(id, by string) (*OwnerNotification, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	n, ok := c.pending[id]
+	if !ok {
+		return nil, ErrNotificationNotFound
+	}
+	delete(c.pending, id)
+	n.AckedBy, n.AckedAt = by, time.Now()
+	return n, nil
+}
+
+// notifyOwners tells the owners of p's resources about change. Delivery
+// failures are recorded but never fail the write that caused them.
+func (m *Manager) notifyOwners(ctx context.Context, change string, p *Permission, roleID string) {
+	if m.Catalog == nil || p == nil {
+		return
+	}
+	for _, e := range m.Catalog.Owners(p.Resource) {
+		n := &OwnerNotification{
+			ID:         generateID(m.IDs, KindNotification),
+			Team:       e.Team,
+			Change:     change,
+			Permission: *p,
+			RoleID:     roleID,
+			Source:     AssignmentSource(ctx),
+			At:         time.Now(),
+			RequireAck: e.RequireAck,
+		}
+		if e.RequireAck {
+			m.Catalog.mu.Lock()
+			m.Catalog.pending[n.ID] = n
+			m.Catalog.mu.Unlock()
+		}
+		if m.Notifier != nil {
+			start := time.Now()
+			err := m.Notifier.Notify(ctx, n)
+			m.record(ctx, start, "NotifyOwners", err)
+		}
+	}
+}
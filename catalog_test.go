@@ -0,0 +1,78 @@
+package rbac
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestOwnerNotifications(t *testing.T) {
+	ctx := context.Background()
+	mgr, err := NewMemoryStoreManager(ctx, "", 0)
+	if err != nil {
+		t.Fatalf("NewMemoryStoreManager: %v", err)
+	}
+	mgr.Catalog, err = NewResourceCatalog(
+		CatalogEntry{Pattern: "billing/**", Team: "finance", RequireAck: true},
+		CatalogEntry{Pattern: "docs/**", Team: "docs"},
+	)
+	if err != nil {
+		t.Fatalf("NewResourceCatalog: %v", err)
+	}
+	var sent []*OwnerNotification
+	mgr.Notifier = NotifierFunc(func(ctx context.Context, n *OwnerNotification) error {
+		sent = append(sent, n)
+		return errors.New("chat is down") // must not fail the write
+	})
+
+	invoices := &Permission{Resource: "billing/invoices", Action: ActionRead}
+	if err := mgr.CreatePermission(ctx, invoices); err != nil {
+		t.Fatalf("CreatePermission: %v", err)
+	}
+	role := &Role{Name: "auditor"}
+	_ = mgr.CreateRole(ctx, role)
+	if err := mgr.AssignPermissionToRole(ctx, role.ID, invoices.ID); err != nil {
+		t.Fatalf("AssignPermissionToRole: %v", err)
+	}
+	// unowned resources notify nobody; a global grant concerns every owner
+	_ = mgr.CreatePermission(ctx, &Permission{Resource: "profile", Action: ActionRead})
+	_ = mgr.CreatePermission(ctx, &Permission{Resource: "**", Action: ActionAll})
+
+	var changes []string
+	for _, n := range sent {
+		changes = append(changes, n.Team+":"+n.Change)
+	}
+	want := []string{
+		"finance:" + ChangePermissionCreated,
+		"finance:" + ChangePermissionAttached,
+		"finance:" + ChangePermissionCreated,
+		"docs:" + ChangePermissionCreated,
+	}
+	if len(changes) != len(want) {
+		t.Fatalf("sent %v, want %v", changes, want)
+	}
+	for i := range want {
+		if changes[i] != want[i] {
+			t.Errorf("notification %d: %s, want %s", i, changes[i], want[i])
+		}
+	}
+	if sent[1].RoleID != role.ID || sent[1].Permission.ID != invoices.ID {
+		t.Errorf("attach notification lacks the diff: %+v", sent[1])
+	}
+
+	// only finance asked for acknowledgments
+	pending := mgr.Catalog.Pending("finance")
+	if len(pending) != 3 || len(mgr.Catalog.Pending("docs")) != 0 {
+		t.Fatalf("unexpected pending notifications: %d finance, %d docs", len(pending), len(mgr.Catalog.Pending("docs")))
+	}
+	n, err := mgr.Catalog.Acknowledge(pending[0].ID, "fiona")
+	if err != nil || n.AckedBy != "fiona" || n.AckedAt.IsZero() {
+		t.Fatalf("Acknowledge: %+v, %v", n, err)
+	}
+	if len(mgr.Catalog.Pending("")) != 2 {
+		t.Errorf("expected two notifications left pending")
+	}
+	if _, err := mgr.Catalog.Acknowledge(pending[0].ID, "fiona"); !errors.Is(err, ErrNotificationNotFound) {
+		t.Errorf("expected ErrNotificationNotFound on a second acknowledgment, got %v", err)
+	}
+}
@@ -0,0 +1,207 @@
+package rbac
+
+import (
+	"context"
+	"slices"
+	"time"
+)
+
+// Check is one resource and action to decide for CanAny and CanAll.
+type Check struct {
+	Resource string `json:"resource"`
+	Action   Action `json:"action"`
+}
+
+// CanAny reports whether userID may perform at least one of checks. The
+// user's roles are resolved once, and each role and its permissions are read
+// once, however many checks there are. It stops at the first allowed check
+// and returns false for an empty list.
+func (m *Manager) CanAny(ctx context.Context, userID string, checks []Check) (bool, error) {
+	start := time.Now()
+	ctx, span := m.startSpan(ctx, "CanAny")
+	defer span.End()
+	ok, err := m.canEach(ctx, start, "CanAny", userID, checks, true)
+	m.record(ctx, start, "CanAny", err)
+	return ok, err
+}
+
+// CanAll reports whether userID may perform every one of checks, resolving
+// the user's roles and permissions once as CanAny does. It stops at the
+// first denied check and returns true for an empty list.
+func (m *Manager) CanAll(ctx context.Context, userID string, checks []Check) (bool, error) {
+	start := time.Now()
+	ctx, span := m.startSpan(ctx, "CanAll")
+	defer span.End()
+	ok, err := m.canEach(ctx, start, "CanAll", userID, checks, false)
+	m.record(ctx, start, "CanAll", err)
+	return ok, err
+}
+
+// BatchCan decides every check for userID as Decide does without
+// attributes, resolving the user's roles and reading their permissions once
+// as CanAny does. Decisions line up with checks. Unlike CanBatch, which runs
+// independent Can calls for any users, it answers for one user, e.g. to find
+// which of a page's actions to offer.
+func (m *Manager) BatchCan(ctx context.Context, userID string, checks []Check) ([]Decision, error) {
+	start := time.Now()
+	ctx, span := m.startSpan(ctx, "BatchCan")
+	defer span.End()
+	out, err := m.batchCan(ctx, start, userID, checks)
+	m.record(ctx, start, "BatchCan", err)
+	return out, err
+}
+
+func (m *Manager) batchCan(ctx context.Context, start time.Time, userID string, checks []Check) ([]Decision, error) {
+	out := make([]Decision, len(checks))
+	if len(checks) == 0 {
+		return out, nil
+	}
+	b, err := m.resolveBatch(ctx, start, "BatchCan", userID)
+	if err != nil {
+		return nil, err
+	}
+	ctx = withEvalCache(ctx)
+	for i, c := range checks {
+		d, err := m.decideBatch(ctx, start, "BatchCan", b, c)
+		if err != nil {
+			return nil, err
+		}
+		out[i] = *d
+	}
+	return out, nil
+}
+
+// canEach decides checks in order until one is allowed, when want is true,
+// or denied, when it is false, and reports whether that happened; with no
+// such check it returns !want.
+func (m *Manager) canEach(ctx context.Context, start time.Time, method, userID string, checks []Check, want bool) (bool, error) {
+	if len(checks) == 0 {
+		return !want, nil
+	}
+	b, err := m.resolveBatch(ctx, start, method, userID)
+	if err != nil {
+		return false, err
+	}
+	ctx = withEvalCache(ctx)
+	for _, c := range checks {
+		d, err := m.decideBatch(ctx, start, method, b, c)
+		if err != nil {
+			return false, err
+		}
+		if d.Allowed == want {
+			return want, nil
+		}
+	}
+	return !want, nil
+}
+
+// batchRoles are a user's roles resolved once for a batch of checks.
+type batchRoles struct {
+	userID string
+	// roles are the roles Can would collect for any resource, expanded
+	roles []string
+	// scoped are matched against each check's resource
+	scoped []ScopedRole
+}
+
+func (m *Manager) resolveBatch(ctx context.Context, start time.Time, method, userID string) (*batchRoles, error) {
+	roles, groups, err := m.resolveRoles(ctx, nil, start, method, userID, "")
+	if err != nil {
+		return nil, err
+	}
+	if err := m.strictCheck(ctx, userID, roles); err != nil {
+		return nil, err
+	}
+	scoped, err := m.listScopedRoles(ctx, userID, groups)
+	if err != nil {
+		m.record(ctx, start, method, err)
+		return nil, err
+	}
+	return &batchRoles{userID: userID, roles: roles, scoped: scoped}, nil
+}
+
+// decideBatch decides one check against b, adding the scoped roles that
+// cover its resource.
+func (m *Manager) decideBatch(ctx context.Context, start time.Time, method string, b *batchRoles, c Check) (*Decision, error) {
+	roles := b.roles
+	var inScope []string
+	for _, sr := range b.scoped {
+		ok, err := matchResource(sr.Scope, c.Resource)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			inScope = append(inScope, sr.RoleID)
+		}
+	}
+	if len(inScope) > 0 {
+		inScope, err := m.expandRoles(ctx, inScope)
+		if err != nil {
+			m.record(ctx, start, method, err)
+			return nil, err
+		}
+		if err := m.strictCheck(ctx, b.userID, inScope); err != nil {
+			return nil, err
+		}
+		roles = append(slices.Clip(roles), inScope...)
+	}
+	return m.evaluate(ctx, time.Now(), nil, "Can", b.userID, roles, c.Resource, c.Action, nil)
+}
+
+type evalCacheKey struct{}
+
+// evalCache holds the reads evaluate makes for one user while a batch of
+// checks is decided, so each is made once.
+type evalCache struct {
+	active *bool
+	roles  map[string]*Role
+	perms  map[string][]*Permission
+}
+
+func withEvalCache(ctx context.Context) context.Context {
+	return context.WithValue(ctx, evalCacheKey{}, &evalCache{
+		roles: map[string]*Role{},
+		perms: map[string][]*Permission{},
+	})
+}
+
+func (m *Manager) evalUserActive(ctx context.Context, userID string) (bool, error) {
+	c, _ := ctx.Value(evalCacheKey{}).(*evalCache)
+	if c != nil && c.active != nil {
+		return *c.active, nil
+	}
+	active, err := m.userActive(ctx, userID)
+	if c != nil && err == nil {
+		c.active = &active
+	}
+	return active, err
+}
+
+func (m *Manager) evalRole(ctx context.Context, roleID string) (*Role, error) {
+	c, _ := ctx.Value(evalCacheKey{}).(*evalCache)
+	if c != nil {
+		if r, ok := c.roles[roleID]; ok {
+			return r, nil
+		}
+	}
+	r, err := m.Roles.GetRoleByID(ctx, roleID)
+	if c != nil && err == nil {
+		c.roles[roleID] = r
+	}
+	return r, err
+}
+
+func (m *Manager) evalRolePermissions(ctx context.Context, start time.Time, roleID string) ([]*Permission, error) {
+	c, _ := ctx.Value(evalCacheKey{}).(*evalCache)
+	if c != nil {
+		if perms, ok := c.perms[roleID]; ok {
+			// evaluate appends generated permissions to the slice
+			return slices.Clip(perms), nil
+		}
+	}
+	perms, err := m.rolePermissions(ctx, start, roleID)
+	if c != nil && err == nil {
+		c.perms[roleID] = slices.Clip(perms)
+	}
+	return perms, err
+}
@@ -0,0 +1,261 @@
+package rbac
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCanAnyCanAll(t *testing.T) {
+	ctx := context.Background()
+	memory, err := NewMemoryStoreManager(ctx, "", 0)
+	if err != nil {
+		t.Fatalf("NewMemoryStoreManager: %v", err)
+	}
+	for name, mgr := range map[string]*Manager{"memory": memory, "mock": NewMockRepoManager(NewMockRepo())} {
+		t.Run(name, func(t *testing.T) {
+			role := &Role{Name: "editor"}
+			if err := mgr.CreateRole(ctx, role); err != nil {
+				t.Fatalf("CreateRole: %v", err)
+			}
+			for _, p := range []*Permission{
+				{Resource: "docs/*", Action: ActionRead},
+				{Resource: "docs/*", Action: ActionUpdate},
+			} {
+				if err := mgr.CreatePermission(ctx, p); err != nil {
+					t.Fatalf("CreatePermission: %v", err)
+				}
+				if err := mgr.AssignPermissionToRole(ctx, role.ID, p.ID); err != nil {
+					t.Fatalf("AssignPermissionToRole: %v", err)
+				}
+			}
+			if err := mgr.AssignRoleToUser(ctx, "alice", role.ID); err != nil {
+				t.Fatalf("AssignRoleToUser: %v", err)
+			}
+
+			read := Check{Resource: "docs/1", Action: ActionRead}
+			update := Check{Resource: "docs/1", Action: ActionUpdate}
+			del := Check{Resource: "docs/1", Action: ActionDelete}
+			for _, tc := range []struct {
+				name     string
+				checks   []Check
+				any, all bool
+			}{
+				{"all allowed", []Check{read, update}, true, true},
+				{"some allowed", []Check{del, read}, true, false},
+				{"none allowed", []Check{del}, false, false},
+				{"empty", nil, false, true},
+			} {
+				if got, err := mgr.CanAny(ctx, "alice", tc.checks); err != nil || got != tc.any {
+					t.Errorf("%s: CanAny = %v, %v; want %v", tc.name, got, err, tc.any)
+				}
+				if got, err := mgr.CanAll(ctx, "alice", tc.checks); err != nil || got != tc.all {
+					t.Errorf("%s: CanAll = %v, %v; want %v", tc.name, got, err, tc.all)
+				}
+			}
+		})
+	}
+}
+
+func TestCanAllScoped(t *testing.T) {
+	ctx := context.Background()
+	mgr, err := NewMemoryStoreManager(ctx, "", 0)
+	if err != nil {
+		t.Fatalf("NewMemoryStoreManager: %v", err)
+	}
+	role := &Role{Name: "project-reader"}
+	if err := mgr.CreateRole(ctx, role); err != nil {
+		t.Fatalf("CreateRole: %v", err)
+	}
+	perm := &Permission{Resource: "projects/**", Action: ActionRead}
+	if err := mgr.CreatePermission(ctx, perm); err != nil {
+		t.Fatalf("CreatePermission: %v", err)
+	}
+	if err := mgr.AssignPermissionToRole(ctx, role.ID, perm.ID); err != nil {
+		t.Fatalf("AssignPermissionToRole: %v", err)
+	}
+	if err := mgr.AssignScopedRoleToUser(ctx, "alice", role.ID, "projects/42/**"); err != nil {
+		t.Fatalf("AssignScopedRoleToUser: %v", err)
+	}
+
+	in := Check{Resource: "projects/42/tasks", Action: ActionRead}
+	out := Check{Resource: "projects/7/tasks", Action: ActionRead}
+	if ok, err := mgr.CanAll(ctx, "alice", []Check{in}); err != nil || !ok {
+		t.Errorf("CanAll(in scope) = %v, %v; want true", ok, err)
+	}
+	if ok, err := mgr.CanAll(ctx, "alice", []Check{in, out}); err != nil || ok {
+		t.Errorf("CanAll(in and out of scope) = %v, %v; want false", ok, err)
+	}
+}
+
+func TestCanAllReadsOnce(t *testing.T) {
+	ctx := context.Background()
+	inner := &countingStore{Store: NewMockRepo()}
+	mgr := NewMockRepoManager(NewMockRepo())
+	mgr.Perms, mgr.Roles, mgr.Users, mgr.RP, mgr.UR, mgr.UG, mgr.GR = inner, inner, inner, inner, inner, inner, inner
+
+	if err := mgr.CreateRole(ctx, &Role{ID: "r1", Name: "reader"}); err != nil {
+		t.Fatalf("CreateRole: %v", err)
+	}
+	var checks []Check
+	for _, res := range []string{"a", "b", "c", "d"} {
+		p := &Permission{Resource: res, Action: ActionRead}
+		if err := mgr.CreatePermission(ctx, p); err != nil {
+			t.Fatalf("CreatePermission: %v", err)
+		}
+		if err := mgr.AssignPermissionToRole(ctx, "r1", p.ID); err != nil {
+			t.Fatalf("AssignPermissionToRole: %v", err)
+		}
+		checks = append(checks, Check{Resource: res, Action: ActionRead})
+	}
+	if err := mgr.AssignRoleToUser(ctx, "alice", "r1"); err != nil {
+		t.Fatalf("AssignRoleToUser: %v", err)
+	}
+
+	ok, err := mgr.CanAll(ctx, "alice", checks)
+	if err != nil || !ok {
+		t.Fatalf("CanAll = %v, %v; want true", ok, err)
+	}
+	if inner.listRoles != 1 || inner.listPerms != 1 || inner.getPerm != len(checks) {
+		t.Errorf("expected one role lookup, one permission list and one read per permission, got %d, %d, %d",
+			inner.listRoles, inner.listPerms, inner.getPerm)
+	}
+}
+
+func TestBatchCan(t *testing.T) {
+	ctx := context.Background()
+	mgr := NewMockRepoManager(NewMockRepo())
+	role := &Role{Name: "reader"}
+	if err := mgr.CreateRole(ctx, role); err != nil {
+		t.Fatalf("CreateRole: %v", err)
+	}
+	allow := &Permission{Resource: "docs/*", Action: ActionRead}
+	deny := &Permission{Resource: "docs/secret", Action: ActionRead, Effect: EffectDeny}
+	for _, p := range []*Permission{allow, deny} {
+		if err := mgr.CreatePermission(ctx, p); err != nil {
+			t.Fatalf("CreatePermission: %v", err)
+		}
+		if err := mgr.AssignPermissionToRole(ctx, role.ID, p.ID); err != nil {
+			t.Fatalf("AssignPermissionToRole: %v", err)
+		}
+	}
+	if err := mgr.AssignRoleToUser(ctx, "alice", role.ID); err != nil {
+		t.Fatalf("AssignRoleToUser: %v", err)
+	}
+
+	got, err := mgr.BatchCan(ctx, "alice", []Check{
+		{Resource: "docs/1", Action: ActionRead},
+		{Resource: "docs/secret", Action: ActionRead},
+		{Resource: "docs/1", Action: ActionDelete},
+	})
+	if err != nil {
+		t.Fatalf("BatchCan: %v", err)
+	}
+	if len(got) != 3 {
+		t.Fatalf("expected 3 decisions, got %d", len(got))
+	}
+	if !got[0].Allowed || got[0].PermissionID != allow.ID {
+		t.Errorf("docs/1 read = %+v; want allowed by %s", got[0], allow.ID)
+	}
+	if got[1].Allowed || got[1].PermissionID != deny.ID {
+		t.Errorf("docs/secret read = %+v; want denied by %s", got[1], deny.ID)
+	}
+	if got[2].Allowed || got[2].PermissionID != "" {
+		t.Errorf("docs/1 delete = %+v; want denied by default", got[2])
+	}
+
+	if got, err := mgr.BatchCan(ctx, "alice", nil); err != nil || len(got) != 0 {
+		t.Errorf("BatchCan(nil) = %v, %v; want no decisions", got, err)
+	}
+}
+
+var errStoreDown = errors.New("store down")
+
+type failingRolePerms struct {
+	RolePermissionRepo
+	roleID string
+}
+
+func (f failingRolePerms) ListPermissions(ctx context.Context, roleID string) ([]string, error) {
+	if roleID == f.roleID {
+		return nil, errStoreDown
+	}
+	return f.RolePermissionRepo.ListPermissions(ctx, roleID)
+}
+
+type failingGroupRoles struct{ GroupRoleRepo }
+
+func (failingGroupRoles) ListRolesForGroup(context.Context, string) ([]string, error) {
+	return nil, errStoreDown
+}
+
+type failingRoleNames struct{ RoleRepo }
+
+func (failingRoleNames) GetRoleByName(context.Context, string) (*Role, error) {
+	return nil, errStoreDown
+}
+
+type failingBans struct {
+	UserGroupRepo
+	GroupBanRepo
+}
+
+func (failingBans) ListUserBans(context.Context, string) ([]*GroupBan, error) {
+	return nil, errStoreDown
+}
+
+// A lookup that fails may hide a deny, so it must fail the check rather
+// than let the remaining roles decide.
+func TestChecksFailClosed(t *testing.T) {
+	ctx := context.Background()
+	mgr := NewMockRepoManager(NewMockRepo())
+	mgr.Decisions = NewDecisionCache(time.Minute)
+	must := func(err error) {
+		t.Helper()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	must(mgr.CreatePermission(ctx, &Permission{ID: "docs-read", Resource: "docs/*", Action: ActionRead}))
+	must(mgr.CreatePermission(ctx, &Permission{ID: "no-secrets", Resource: "docs/secret", Action: ActionRead, Effect: EffectDeny}))
+	must(mgr.CreateRole(ctx, &Role{ID: "reader", Name: "reader"}))
+	must(mgr.CreateRole(ctx, &Role{ID: "blocked", Name: "blocked"}))
+	must(mgr.AssignPermissionToRole(ctx, "reader", "docs-read"))
+	must(mgr.AssignPermissionToRole(ctx, "blocked", "no-secrets"))
+	must(mgr.AssignRoleToUser(ctx, "alice", "reader"))
+	must(mgr.AssignRoleToUser(ctx, "alice", "blocked"))
+	must(mgr.AssignRoleToGroup(ctx, "eng", "reader"))
+	must(mgr.AssignRoleToGroup(ctx, "audited", "blocked"))
+	must(mgr.AddUserToGroup(ctx, &UserGroup{UserID: "bob", GroupName: "eng"}))
+	must(mgr.AddUserToGroup(ctx, &UserGroup{UserID: "bob", GroupName: "audited"}))
+	must(mgr.AddUserToGroup(ctx, &UserGroup{UserID: "eve", GroupName: "eng"}))
+	must(mgr.BanUserFromGroup(ctx, "eng", "eve", "contractor"))
+	// a membership written past the ban, e.g. by a sync job
+	must(mgr.UG.AddUserToGroup(ctx, &UserGroup{UserID: "eve", GroupName: "eng"}))
+
+	rp, gr, ug, roles := mgr.RP, mgr.GR, mgr.UG, mgr.Roles
+	for _, tc := range []struct {
+		name string
+		fail func()
+		user string
+	}{
+		{"role permissions", func() { mgr.RP = failingRolePerms{rp, "blocked"} }, "alice"},
+		{"group roles", func() { mgr.GR = failingGroupRoles{gr} }, "bob"},
+		{"group bans", func() { mgr.UG = failingBans{ug, ug.(GroupBanRepo)} }, "eve"},
+		{"default role", func() { mgr.Roles = failingRoleNames{roles} }, "dave"},
+	} {
+		tc.fail()
+		if ok, err := mgr.Can(ctx, tc.user, "docs/secret", ActionRead); !errors.Is(err, errStoreDown) || ok {
+			t.Errorf("%s failing: Can = %v, %v; want the store's error", tc.name, ok, err)
+		}
+		if ok, err := mgr.HasPermission(ctx, tc.user, "docs-read"); tc.name != "role permissions" && (!errors.Is(err, errStoreDown) || ok) {
+			t.Errorf("%s failing: HasPermission = %v, %v; want the store's error", tc.name, ok, err)
+		}
+		mgr.RP, mgr.GR, mgr.UG, mgr.Roles = rp, gr, ug, roles
+		// nothing was cached while the store failed
+		if ok, err := mgr.Can(ctx, tc.user, "docs/secret", ActionRead); err != nil || ok {
+			t.Errorf("%s restored: Can = %v, %v; want denied", tc.name, ok, err)
+		}
+	}
+}
@@ -0,0 +1,159 @@
+// file: rbac/cockroach_store.go
+package rbac
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Ensure CockroachStore implements all interfaces:
+var (
+	_ PermissionRepo         = (*CockroachStore)(nil)
+	_ RoleRepo               = (*CockroachStore)(nil)
+	_ UserRepo               = (*CockroachStore)(nil)
+	_ RolePermissionRepo     = (*CockroachStore)(nil)
+	_ UserRoleRepo           = (*CockroachStore)(nil)
+	_ UserGroupRepo          = (*CockroachStore)(nil)
+	_ GroupRoleRepo          = (*CockroachStore)(nil)
+	_ BulkUserRoleRepo       = (*CockroachStore)(nil)
+	_ BulkRolePermissionRepo = (*CockroachStore)(nil)
+)
+
+//
+// ---------- CockroachStore Core ----------
+//
+
+// CockroachStore runs the PostgreSQL schema and queries against
+// CockroachDB. CockroachDB executes every transaction as SERIALIZABLE and
+// asks clients to retry those it aborts with SQLSTATE 40001; the join-table
+// writes, which are the ones that contend under concurrent assignment, are
+// retried here with jittered exponential backoff.
+type CockroachStore struct {
+	*PostgresStore
+
+	// MaxRetries bounds the attempts after the first. Defaults to 5.
+	MaxRetries int
+	// RetryBackoff is the delay before the first retry; it doubles on each
+	// further attempt. Defaults to 10ms.
+	RetryBackoff time.Duration
+}
+
+// NewCockroachStore creates the store and ensures the schema is in place.
+func NewCockroachStore(ctx context.Context, db *pgxpool.Pool) (*CockroachStore, error) {
+	s := &CockroachStore{PostgresStore: &PostgresStore{db: db}, MaxRetries: 5, RetryBackoff: 10 * time.Millisecond}
+	if err := s.retry(ctx, func() error { return s.EnsureSchema(ctx) }); err != nil {
+		return nil, fmt.Errorf("cockroach_store: ensure schema: %w", err)
+	}
+	return s, nil
+}
+
+// NewCockroachStoreManager wraps the store in a Manager and seeds the default role.
+func NewCockroachStoreManager(ctx context.Context, db *pgxpool.Pool) (*Manager, error) {
+	s, err := NewCockroachStore(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+
+	def, _ := s.GetRoleByName(ctx, "default")
+	if def == nil {
+		def = &Role{Name: "default", Description: "Default role"}
+		if createErr := s.CreateRole(ctx, def); createErr != nil {
+			return nil, fmt.Errorf("failed to create default role: %w", createErr)
+		}
+	}
+
+	return &Manager{
+		Perms:           s,
+		Roles:           s,
+		Users:           s,
+		RP:              s,
+		UR:              s,
+		UG:              s,
+		GR:              s,
+		DefaultRoleName: "default",
+	}, nil
+}
+
+//
+// ---------- Retries ----------
+//
+
+// isRetryable reports whether err is a serialization failure the client
+// should retry.
+func isRetryable(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "40001"
+}
+
+// retry runs fn until it succeeds, fails with a non-retryable error, ctx is
+// done or MaxRetries retries have been spent.
+func (s *CockroachStore) retry(ctx context.Context, fn func() error) error {
+	backoff := s.RetryBackoff
+	for attempt := 0; ; attempt++ {
+		err := fn()
+		if err == nil || !isRetryable(err) || attempt >= s.MaxRetries {
+			if err != nil && attempt > 0 {
+				return fmt.Errorf("cockroach_store: after %d retries: %w", attempt, err)
+			}
+			return err
+		}
+		// Full jitter keeps competing writers from retrying in lockstep.
+		delay := time.Duration(rand.Int64N(int64(backoff) + 1))
+		select {
+		case <-ctx.Done():
+			return errors.Join(ctx.Err(), err)
+		case <-time.After(delay):
+		}
+		backoff *= 2
+	}
+}
+
+//
+// ---------- Join-table writes ----------
+//
+
+func (s *CockroachStore) AddRP(ctx context.Context, roleID, permID string) error {
+	return s.retry(ctx, func() error { return s.PostgresStore.AddRP(ctx, roleID, permID) })
+}
+
+func (s *CockroachStore) AddRPs(ctx context.Context, roleID string, permIDs []string) error {
+	return s.retry(ctx, func() error { return s.PostgresStore.AddRPs(ctx, roleID, permIDs) })
+}
+
+func (s *CockroachStore) Remove(ctx context.Context, roleID, permID string) error {
+	return s.retry(ctx, func() error { return s.PostgresStore.Remove(ctx, roleID, permID) })
+}
+
+func (s *CockroachStore) AddUR(ctx context.Context, userID, roleID string) error {
+	return s.retry(ctx, func() error { return s.PostgresStore.AddUR(ctx, userID, roleID) })
+}
+
+func (s *CockroachStore) AddURs(ctx context.Context, userID string, roleIDs []string) error {
+	return s.retry(ctx, func() error { return s.PostgresStore.AddURs(ctx, userID, roleIDs) })
+}
+
+func (s *CockroachStore) RemoveUR(ctx context.Context, userID, roleID string) error {
+	return s.retry(ctx, func() error { return s.PostgresStore.RemoveUR(ctx, userID, roleID) })
+}
+
+func (s *CockroachStore) AddUserToGroup(ctx context.Context, ug *UserGroup) error {
+	return s.retry(ctx, func() error { return s.PostgresStore.AddUserToGroup(ctx, ug) })
+}
+
+func (s *CockroachStore) RemoveUserFromGroup(ctx context.Context, groupName string, ug *UserGroup) error {
+	return s.retry(ctx, func() error { return s.PostgresStore.RemoveUserFromGroup(ctx, groupName, ug) })
+}
+
+func (s *CockroachStore) AddRoleToGroup(ctx context.Context, groupID, roleID string) error {
+	return s.retry(ctx, func() error { return s.PostgresStore.AddRoleToGroup(ctx, groupID, roleID) })
+}
+
+func (s *CockroachStore) RemoveRoleFromGroup(ctx context.Context, groupID, roleID string) error {
+	return s.retry(ctx, func() error { return s.PostgresStore.RemoveRoleFromGroup(ctx, groupID, roleID) })
+}
@@ -0,0 +1,138 @@
+package rbac
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync/atomic"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgproto3"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+func TestCockroachRetry(t *testing.T) {
+	ctx := context.Background()
+	s := &CockroachStore{MaxRetries: 3}
+	serialization := fmt.Errorf("exec: %w", &pgconn.PgError{Code: "40001", Message: "restart transaction"})
+
+	calls := 0
+	err := s.retry(ctx, func() error {
+		calls++
+		if calls < 3 {
+			return serialization
+		}
+		return nil
+	})
+	if err != nil || calls != 3 {
+		t.Fatalf("expected success on the third attempt, got %v after %d calls", err, calls)
+	}
+
+	calls = 0
+	err = s.retry(ctx, func() error { calls++; return serialization })
+	if !isRetryable(err) || calls != 4 {
+		t.Errorf("expected the 40001 error after 4 attempts, got %v after %d calls", err, calls)
+	}
+
+	calls = 0
+	unique := &pgconn.PgError{Code: "23505"}
+	err = s.retry(ctx, func() error { calls++; return unique })
+	if !errors.Is(err, unique) || calls != 1 {
+		t.Errorf("expected a unique violation to fail without retrying, got %v after %d calls", err, calls)
+	}
+}
+
+// TestCockroachBulkWritesRetry runs AddRPs and AddURs against a fake server
+// that aborts the first two statements of each with 40001, as CockroachDB
+// does under contention.
+func TestCockroachBulkWritesRetry(t *testing.T) {
+	ctx := context.Background()
+	addr, statements := fakeContendedServer(t, 2)
+
+	cfg, err := pgxpool.ParseConfig("postgres://rbac@" + addr + "/rbac?sslmode=disable")
+	if err != nil {
+		t.Fatalf("ParseConfig: %v", err)
+	}
+	// The simple protocol keeps the fake server to one message per statement.
+	cfg.ConnConfig.DefaultQueryExecMode = pgx.QueryExecModeSimpleProtocol
+	pool, err := pgxpool.NewWithConfig(ctx, cfg)
+	if err != nil {
+		t.Fatalf("NewWithConfig: %v", err)
+	}
+	defer pool.Close()
+	s := &CockroachStore{PostgresStore: &PostgresStore{db: pool}, MaxRetries: 3}
+
+	if err := s.AddRPs(ctx, "role-1", []string{"perm-1", "perm-2"}); err != nil {
+		t.Errorf("AddRPs: expected success after retrying, got %v", err)
+	}
+	if n := statements.Swap(0); n != 3 {
+		t.Errorf("AddRPs: expected 3 attempts, got %d", n)
+	}
+	if err := s.AddURs(ctx, "user-1", []string{"role-1", "role-2"}); err != nil {
+		t.Errorf("AddURs: expected success after retrying, got %v", err)
+	}
+	if n := statements.Swap(0); n != 3 {
+		t.Errorf("AddURs: expected 3 attempts, got %d", n)
+	}
+}
+
+// fakeContendedServer speaks just enough of the PostgreSQL protocol to
+// accept connections and answer simple queries. Of every failures+1
+// statements it counts, the first failures fail with 40001.
+func fakeContendedServer(t *testing.T, failures int64) (string, *atomic.Int64) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+	t.Cleanup(func() { _ = ln.Close() })
+
+	statements := &atomic.Int64{}
+	serve := func(conn net.Conn) {
+		defer conn.Close()
+		backend := pgproto3.NewBackend(conn, conn)
+		if _, err := backend.ReceiveStartupMessage(); err != nil {
+			return
+		}
+		backend.Send(&pgproto3.AuthenticationOk{})
+		backend.Send(&pgproto3.ParameterStatus{Name: "client_encoding", Value: "UTF8"})
+		backend.Send(&pgproto3.ParameterStatus{Name: "standard_conforming_strings", Value: "on"})
+		backend.Send(&pgproto3.ReadyForQuery{TxStatus: 'I'})
+		if err := backend.Flush(); err != nil {
+			return
+		}
+		for {
+			msg, err := backend.Receive()
+			if err != nil {
+				return
+			}
+			switch msg.(type) {
+			case *pgproto3.Query:
+				if n := statements.Add(1); n%(failures+1) != 0 {
+					backend.Send(&pgproto3.ErrorResponse{Severity: "ERROR", Code: "40001", Message: "restart transaction"})
+				} else {
+					backend.Send(&pgproto3.CommandComplete{CommandTag: []byte("INSERT 0 2")})
+				}
+				backend.Send(&pgproto3.ReadyForQuery{TxStatus: 'I'})
+				if err := backend.Flush(); err != nil {
+					return
+				}
+			case *pgproto3.Terminate:
+				return
+			}
+		}
+	}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go serve(conn)
+		}
+	}()
+	return ln.Addr().String(), statements
+}
@@ -0,0 +1,46 @@
+package rbac
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/Seann-Moser/rbac/rbaceval"
+)
+
+// ErrInvalidCondition is returned by CreatePermission for a Condition that
+// does not parse.
+var ErrInvalidCondition = errors.New("rbac: invalid permission condition")
+
+// CanWithAttributes is Can for permissions with a Condition: attrs are the
+// request's attributes, visible to conditions as attrs, next to the user's
+// id, username, email, tenant_id and meta as user, and the named parameters
+// of the permission's resource as params. Can evaluates conditions without
+// attrs, so conditions that read them never grant access there. A permission
+// whose resource has a parameter named like an attribute only applies when
+// the two agree.
+func (m *Manager) CanWithAttributes(ctx context.Context, userID, resource string, action Action, attrs map[string]any) (bool, error) {
+	if attrs == nil {
+		attrs = map[string]any{}
+	}
+	return m.can(ctx, "CanWithAttributes", userID, resource, action, attrs)
+}
+
+// conditionVars loads the user for permission conditions. A user that cannot
+// be loaded leaves only user.id set.
+func (m *Manager) conditionVars(ctx context.Context, userID, resource string, action Action, attrs map[string]any) map[string]any {
+	user := map[string]any{"id": userID}
+	u, err := m.Users.GetUserByID(ctx, userID)
+	if err != nil {
+		m.record(ctx, time.Now(), "GetUserByID", err)
+	}
+	if u != nil {
+		user["username"] = u.Username
+		user["email"] = u.Email
+		user["tenant_id"] = u.TenantID
+		if u.Meta != nil {
+			user["meta"] = u.Meta
+		}
+	}
+	return rbaceval.ConditionVars(resource, string(action), user, attrs)
+}
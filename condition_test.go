@@ -0,0 +1,75 @@
+package rbac
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestCanWithAttributes(t *testing.T) {
+	ctx := context.Background()
+	mgr, err := NewMemoryStoreManager(ctx, "", 0)
+	if err != nil {
+		t.Fatalf("NewMemoryStoreManager: %v", err)
+	}
+
+	user := &User{Username: "alice", Meta: map[string]interface{}{"region": "eu"}}
+	if err := mgr.CreateUser(ctx, user); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	role := &Role{Name: "clerk"}
+	if err := mgr.CreateRole(ctx, role); err != nil {
+		t.Fatalf("CreateRole: %v", err)
+	}
+	perms := []*Permission{
+		{Resource: "reports", Action: ActionRead, Condition: `attrs.region == user.meta.region`},
+		{Resource: "orders", Action: ActionAll},
+		{Resource: "orders", Action: ActionDelete, Effect: EffectDeny, Condition: `attrs.amount > 100`},
+	}
+	for _, p := range perms {
+		if err := mgr.CreatePermission(ctx, p); err != nil {
+			t.Fatalf("CreatePermission: %v", err)
+		}
+		if err := mgr.AssignPermissionToRole(ctx, role.ID, p.ID); err != nil {
+			t.Fatalf("AssignPermissionToRole: %v", err)
+		}
+	}
+	if err := mgr.AssignRoleToUser(ctx, user.ID, role.ID); err != nil {
+		t.Fatalf("AssignRoleToUser: %v", err)
+	}
+
+	cases := []struct {
+		resource string
+		action   Action
+		attrs    map[string]any
+		want     bool
+	}{
+		{"reports", ActionRead, map[string]any{"region": "eu"}, true},
+		{"reports", ActionRead, map[string]any{"region": "us"}, false},
+		{"reports", ActionRead, nil, false},
+		{"orders", ActionDelete, map[string]any{"amount": 50}, true},
+		{"orders", ActionDelete, map[string]any{"amount": 500}, false},
+		// the deny cannot tell the amount, so it applies
+		{"orders", ActionDelete, nil, false},
+		{"orders", ActionUpdate, nil, true},
+	}
+	for _, c := range cases {
+		ok, err := mgr.CanWithAttributes(ctx, user.ID, c.resource, c.action, c.attrs)
+		if err != nil {
+			t.Fatalf("CanWithAttributes(%s, %s, %v): %v", c.resource, c.action, c.attrs, err)
+		}
+		if ok != c.want {
+			t.Errorf("CanWithAttributes(%s, %s, %v) = %v, want %v", c.resource, c.action, c.attrs, ok, c.want)
+		}
+	}
+
+	// Can has no attributes, so the conditional deny on delete always applies
+	if ok, err := mgr.Can(ctx, user.ID, "orders", ActionDelete); err != nil || ok {
+		t.Errorf("Can(delete) = %v, %v, want false", ok, err)
+	}
+
+	err = mgr.CreatePermission(ctx, &Permission{Resource: "invoices", Action: ActionUpdate, Condition: `attrs.region ==`})
+	if !errors.Is(err, ErrInvalidCondition) {
+		t.Errorf("expected ErrInvalidCondition, got %v", err)
+	}
+}
@@ -0,0 +1,263 @@
+package rbac
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/netip"
+	"strings"
+	"time"
+)
+
+// RoleConstraints limit when and from where a role assignment applies, e.g.
+// admin roles usable only during office hours from the corporate network.
+// Empty fields do not constrain.
+type RoleConstraints struct {
+	// ValidHours is a daily window such as "09:00-18:00"; a window such as
+	// "22:00-06:00" spans midnight. The end is exclusive.
+	ValidHours string `bson:"valid_hours,omitempty" json:"valid_hours,omitempty"`
+	// TimeZone is the IANA zone ValidHours is read in; UTC by default.
+	TimeZone string `bson:"time_zone,omitempty" json:"time_zone,omitempty"`
+	// AllowedCIDRs are the networks the client must connect from, such as
+	// 10.0.0.0/8.
+	AllowedCIDRs []string `bson:"allowed_cidrs,omitempty" json:"allowed_cidrs,omitempty"`
+}
+
+// ConstrainedRole is a role assignment that only applies to requests meeting
+// its Constraints.
+type ConstrainedRole struct {
+	RoleID      string          `bson:"role_id" json:"role_id"`
+	Constraints RoleConstraints `bson:"constraints" json:"constraints"`
+}
+
+// ConstrainedUserRoleRepo is optionally implemented by a UserRoleRepo that
+// stores constrained role assignments. Like scoped assignments they are kept
+// apart from plain ones, so ListRoles does not return them.
+type ConstrainedUserRoleRepo interface {
+	// AddConstrainedUR creates or replaces the user's constrained
+	// assignment of roleID.
+	AddConstrainedUR(ctx context.Context, userID, roleID string, c RoleConstraints) error
+	RemoveConstrainedUR(ctx context.Context, userID, roleID string) error
+	ListConstrainedRoles(ctx context.Context, userID string) ([]ConstrainedRole, error)
+}
+
+// RequestContext describes the request being authorized, for
+// RoleConstraints.
+type RequestContext struct {
+	// Time is when the request was made; the zero time meets no
+	// ValidHours.
+	Time time.Time
+	// ClientIP is the address the request came from; the zero Addr meets
+	// no AllowedCIDRs.
+	ClientIP netip.Addr
+}
+
+var errConstraintsUnsupported = errors.New("rbac: repo does not support constrained role assignments")
+
+type requestContextKey struct{}
+
+// WithRequestContext returns a context whose checks consider the
+// constrained roles that rc meets. Can, Decide and the other checks ignore
+// constrained roles without one.
+func WithRequestContext(ctx context.Context, rc RequestContext) context.Context {
+	return context.WithValue(ctx, requestContextKey{}, rc)
+}
+
+// withoutRequestContext hides any RequestContext in ctx, for the lookups
+// that snapshot or list a user's roles rather than decide one request.
+func withoutRequestContext(ctx context.Context) context.Context {
+	return context.WithValue(ctx, requestContextKey{}, nil)
+}
+
+// CanWithContext is Can for a request made at rc.Time from rc.ClientIP: the
+// user's constrained roles count when rc meets their constraints.
+func (m *Manager) CanWithContext(ctx context.Context, userID, resource string, action Action, rc RequestContext) (bool, error) {
+	return m.can(WithRequestContext(ctx, rc), "CanWithContext", userID, resource, action, nil)
+}
+
+// AssignConstrainedRoleToUser gives userID roleID for the requests meeting
+// c only. Only checks given a RequestContext, such as CanWithContext,
+// consider the role; HasPermission and sessions never do.
+func (m *Manager) AssignConstrainedRoleToUser(ctx context.Context, userID, roleID string, c RoleConstraints) error {
+	start := time.Now()
+	ctx, span := m.startSpan(ctx, "AssignConstrainedRoleToUser")
+	defer span.End()
+	err := c.Validate()
+	if err == nil {
+		err = m.checkAssignable(ctx, roleID)
+	}
+	if err == nil {
+		err = m.checkDuties(ctx, userID, roleID)
+	}
+	if err == nil {
+		err = m.checkRoleLimit(ctx, userID, roleID)
+	}
+	if err == nil {
+		err = errConstraintsUnsupported
+		if repo, ok := m.UR.(ConstrainedUserRoleRepo); ok {
+			err = repo.AddConstrainedUR(ctx, userID, roleID, c)
+		}
+	}
+	m.record(ctx, start, "AssignConstrainedRoleToUser", err)
+	m.changedUser(ctx, "AssignConstrainedRoleToUser", userID, err)
+	return err
+}
+
+// UnassignConstrainedRoleFromUser removes a constrained assignment; a plain
+// assignment of the same role is kept.
+func (m *Manager) UnassignConstrainedRoleFromUser(ctx context.Context, userID, roleID string) error {
+	start := time.Now()
+	ctx, span := m.startSpan(ctx, "UnassignConstrainedRoleFromUser")
+	defer span.End()
+	err := errConstraintsUnsupported
+	if repo, ok := m.UR.(ConstrainedUserRoleRepo); ok {
+		err = repo.RemoveConstrainedUR(ctx, userID, roleID)
+	}
+	m.record(ctx, start, "UnassignConstrainedRoleFromUser", err)
+	m.changedUser(ctx, "UnassignConstrainedRoleFromUser", userID, err)
+	return err
+}
+
+// ListConstrainedRolesForUser returns the user's constrained role
+// assignments.
+func (m *Manager) ListConstrainedRolesForUser(ctx context.Context, userID string) ([]ConstrainedRole, error) {
+	start := time.Now()
+	ctx, span := m.startSpan(ctx, "ListConstrainedRolesForUser")
+	defer span.End()
+	var (
+		out []ConstrainedRole
+		err = errConstraintsUnsupported
+	)
+	if repo, ok := m.UR.(ConstrainedUserRoleRepo); ok {
+		out, err = repo.ListConstrainedRoles(ctx, userID)
+	}
+	m.record(ctx, start, "ListConstrainedRolesForUser", err)
+	return out, err
+}
+
+// constrainedRoles returns the user's constrained roles whose constraints
+// the context's RequestContext meets. Without one it returns none.
+func (m *Manager) constrainedRoles(ctx context.Context, userID string) ([]string, error) {
+	rc, ok := ctx.Value(requestContextKey{}).(RequestContext)
+	if !ok {
+		return nil, nil
+	}
+	repo, ok := m.UR.(ConstrainedUserRoleRepo)
+	if !ok {
+		return nil, nil
+	}
+	list, err := repo.ListConstrainedRoles(ctx, userID)
+	if errors.Is(err, errConstraintsUnsupported) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var out []string
+	for _, cr := range list {
+		met, err := cr.Constraints.Allows(rc)
+		if err != nil {
+			return out, err
+		}
+		if met {
+			out = append(out, cr.RoleID)
+		}
+	}
+	return out, nil
+}
+
+// Validate checks that the constraints parse.
+func (c RoleConstraints) Validate() error {
+	if _, err := c.location(); err != nil {
+		return err
+	}
+	if c.ValidHours != "" {
+		if _, _, err := parseHours(c.ValidHours); err != nil {
+			return err
+		}
+	}
+	for _, cidr := range c.AllowedCIDRs {
+		if _, err := netip.ParsePrefix(cidr); err != nil {
+			return fmt.Errorf("rbac: allowed CIDR %q: %w", cidr, err)
+		}
+	}
+	return nil
+}
+
+// Allows reports whether a request described by rc meets the constraints.
+func (c RoleConstraints) Allows(rc RequestContext) (bool, error) {
+	if c.ValidHours != "" {
+		if rc.Time.IsZero() {
+			return false, nil
+		}
+		from, to, err := parseHours(c.ValidHours)
+		if err != nil {
+			return false, err
+		}
+		loc, err := c.location()
+		if err != nil {
+			return false, err
+		}
+		t := rc.Time.In(loc)
+		now := time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute
+		in := from <= now && now < to
+		if to < from {
+			in = now >= from || now < to
+		}
+		if !in {
+			return false, nil
+		}
+	}
+	if len(c.AllowedCIDRs) == 0 {
+		return true, nil
+	}
+	if !rc.ClientIP.IsValid() {
+		return false, nil
+	}
+	ip := rc.ClientIP.Unmap()
+	for _, cidr := range c.AllowedCIDRs {
+		prefix, err := netip.ParsePrefix(cidr)
+		if err != nil {
+			return false, fmt.Errorf("rbac: allowed CIDR %q: %w", cidr, err)
+		}
+		if prefix.Contains(ip) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (c RoleConstraints) location() (*time.Location, error) {
+	if c.TimeZone == "" {
+		return time.UTC, nil
+	}
+	loc, err := time.LoadLocation(c.TimeZone)
+	if err != nil {
+		return nil, fmt.Errorf("rbac: time zone %q: %w", c.TimeZone, err)
+	}
+	return loc, nil
+}
+
+// parseHours parses a window such as "09:00-18:00" into offsets from
+// midnight.
+func parseHours(window string) (from, to time.Duration, err error) {
+	start, end, ok := strings.Cut(window, "-")
+	if ok {
+		from, err = parseClock(start)
+	}
+	if ok && err == nil {
+		to, err = parseClock(end)
+	}
+	if !ok || err != nil || from == to {
+		return 0, 0, fmt.Errorf("rbac: invalid valid hours %q, want HH:MM-HH:MM", window)
+	}
+	return from, to, nil
+}
+
+func parseClock(s string) (time.Duration, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(s))
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
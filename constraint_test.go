@@ -0,0 +1,120 @@
+package rbac
+
+import (
+	"bytes"
+	"context"
+	"net/netip"
+	"testing"
+	"time"
+)
+
+func TestConstrainedRoles(t *testing.T) {
+	ctx := context.Background()
+	memory, err := NewMemoryStoreManager(ctx, "", 0)
+	if err != nil {
+		t.Fatalf("NewMemoryStoreManager: %v", err)
+	}
+	for name, mgr := range map[string]*Manager{"memory": memory, "mock": NewMockRepoManager(NewMockRepo())} {
+		t.Run(name, func(t *testing.T) {
+			user := &User{Username: "alice"}
+			if err := mgr.CreateUser(ctx, user); err != nil {
+				t.Fatalf("CreateUser: %v", err)
+			}
+			role := &Role{Name: "admin"}
+			if err := mgr.CreateRole(ctx, role); err != nil {
+				t.Fatalf("CreateRole: %v", err)
+			}
+			perm := &Permission{Resource: "settings/*", Action: ActionUpdate}
+			if err := mgr.CreatePermission(ctx, perm); err != nil {
+				t.Fatalf("CreatePermission: %v", err)
+			}
+			if err := mgr.AssignPermissionToRole(ctx, role.ID, perm.ID); err != nil {
+				t.Fatalf("AssignPermissionToRole: %v", err)
+			}
+			limits := RoleConstraints{ValidHours: "09:00-18:00", TimeZone: "America/New_York", AllowedCIDRs: []string{"10.0.0.0/8", "2001:db8::/32"}}
+			if err := mgr.AssignConstrainedRoleToUser(ctx, user.ID, role.ID, limits); err != nil {
+				t.Fatalf("AssignConstrainedRoleToUser: %v", err)
+			}
+
+			office := netip.MustParseAddr("10.1.2.3")
+			ny, _ := time.LoadLocation("America/New_York")
+			noon := time.Date(2024, 3, 4, 12, 0, 0, 0, ny)
+			for _, c := range []struct {
+				name string
+				rc   RequestContext
+				want bool
+			}{
+				{"office hours on the network", RequestContext{Time: noon, ClientIP: office}, true},
+				{"ipv6 on the network", RequestContext{Time: noon, ClientIP: netip.MustParseAddr("2001:db8::1")}, true},
+				{"ipv4-mapped address", RequestContext{Time: noon, ClientIP: netip.MustParseAddr("::ffff:10.1.2.3")}, true},
+				{"outside the network", RequestContext{Time: noon, ClientIP: netip.MustParseAddr("203.0.113.9")}, false},
+				{"after hours", RequestContext{Time: noon.Add(7 * time.Hour), ClientIP: office}, false},
+				{"no client ip", RequestContext{Time: noon}, false},
+				{"no time", RequestContext{ClientIP: office}, false},
+			} {
+				if ok, err := mgr.CanWithContext(ctx, user.ID, "settings/billing", ActionUpdate, c.rc); err != nil || ok != c.want {
+					t.Errorf("%s: CanWithContext = %v, %v; want %v", c.name, ok, err, c.want)
+				}
+			}
+			if ok, err := mgr.Can(ctx, user.ID, "settings/billing", ActionUpdate); err != nil || ok {
+				t.Errorf("Can = %v, %v; constrained roles need a request context", ok, err)
+			}
+			if ok, err := mgr.HasPermission(ctx, user.ID, perm.ID); err != nil || ok {
+				t.Errorf("HasPermission = %v, %v; constrained roles need a request context", ok, err)
+			}
+
+			list, err := mgr.ListConstrainedRolesForUser(ctx, user.ID)
+			if err != nil || len(list) != 1 || list[0].RoleID != role.ID || list[0].Constraints.ValidHours != "09:00-18:00" {
+				t.Fatalf("ListConstrainedRolesForUser = %+v, %v", list, err)
+			}
+			if err := mgr.UnassignConstrainedRoleFromUser(ctx, user.ID, role.ID); err != nil {
+				t.Fatalf("UnassignConstrainedRoleFromUser: %v", err)
+			}
+			if ok, _ := mgr.CanWithContext(ctx, user.ID, "settings/billing", ActionUpdate, RequestContext{Time: noon, ClientIP: office}); ok {
+				t.Error("expected no access after unassigning the constrained role")
+			}
+
+			for _, bad := range []RoleConstraints{
+				{ValidHours: "9-18"},
+				{ValidHours: "09:00-09:00"},
+				{TimeZone: "Mars/Olympus"},
+				{AllowedCIDRs: []string{"10.0.0.1"}},
+			} {
+				if err := mgr.AssignConstrainedRoleToUser(ctx, user.ID, role.ID, bad); err == nil {
+					t.Errorf("AssignConstrainedRoleToUser(%+v): expected an error", bad)
+				}
+			}
+		})
+	}
+}
+
+func TestRoleConstraintsOvernight(t *testing.T) {
+	c := RoleConstraints{ValidHours: "22:00-06:00"}
+	for hour, want := range map[int]bool{21: false, 22: true, 23: true, 0: true, 5: true, 6: false, 12: false} {
+		rc := RequestContext{Time: time.Date(2024, 3, 4, hour, 0, 0, 0, time.UTC)}
+		if ok, err := c.Allows(rc); err != nil || ok != want {
+			t.Errorf("%02d:00: Allows = %v, %v; want %v", hour, ok, err, want)
+		}
+	}
+}
+
+func TestMemoryStoreSnapshotsConstrainedRoles(t *testing.T) {
+	ctx := context.Background()
+	s, _ := NewMemoryStore(ctx, "")
+	limits := RoleConstraints{AllowedCIDRs: []string{"10.0.0.0/8"}}
+	if err := s.AddConstrainedUR(ctx, "alice", "admin", limits); err != nil {
+		t.Fatalf("AddConstrainedUR: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := s.WriteSnapshot(&buf); err != nil {
+		t.Fatalf("WriteSnapshot: %v", err)
+	}
+	loaded, _ := NewMemoryStore(ctx, "")
+	if err := loaded.LoadSnapshot(&buf); err != nil {
+		t.Fatalf("LoadSnapshot: %v", err)
+	}
+	list, _ := loaded.ListConstrainedRoles(ctx, "alice")
+	if len(list) != 1 || list[0].RoleID != "admin" || len(list[0].Constraints.AllowedCIDRs) != 1 {
+		t.Fatalf("constrained roles after reload = %+v", list)
+	}
+}
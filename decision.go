@@ -0,0 +1,47 @@
+package rbac
+
+import (
+	"context"
+)
+
+// Decision is the outcome of an access check together with the rule that
+// decided it.
+type Decision struct {
+	Allowed bool `json:"allowed"`
+	// RoleID and PermissionID identify the deciding rule; both are empty when
+	// no permission matched and access was denied by default.
+	RoleID       string `json:"role_id,omitempty"`
+	PermissionID string `json:"permission_id,omitempty"`
+	Effect       Effect `json:"effect,omitempty"`
+	// Priority is the deciding role's Role.Priority.
+	Priority int `json:"priority,omitempty"`
+	// Params holds the named parameters the deciding permission's resource
+	// captured, e.g. {"project_id": "42"} for projects/{project_id}/**
+	// checked against projects/42/docs/1.
+	Params map[string]string `json:"params,omitempty"`
+	// SuperAdmin is set when access was allowed because RoleID is the
+	// Manager's SuperAdminRoleName; PermissionID is then empty.
+	SuperAdmin bool `json:"super_admin,omitempty"`
+	// Authorizer names the application Authorizer that decided, when it
+	// set it; see Manager.Authorizers.
+	Authorizer string `json:"authorizer,omitempty"`
+}
+
+// Decide is CanWithAttributes returning the deciding rule as well. attrs may
+// be nil, in which case conditions are evaluated as for Can. When matching
+// rules of several roles disagree, the role with the highest Priority
+// decides; among equal priorities a deny wins, so with no priorities set
+// Decide agrees with Can.
+func (m *Manager) Decide(ctx context.Context, userID, resource string, action Action, attrs map[string]any) (*Decision, error) {
+	return m.decide(ctx, "Decide", userID, resource, action, attrs)
+}
+
+// outranks reports whether a rule of a role with the given priority would
+// replace the current winner: a higher priority always does, and on a tie
+// only a deny over an allow does.
+func outranks(priority int, deny bool, winner *Decision) bool {
+	if priority != winner.Priority {
+		return priority > winner.Priority
+	}
+	return deny && winner.Allowed
+}
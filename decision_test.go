@@ -0,0 +1,272 @@
+package rbac
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestDecideRolePriority(t *testing.T) {
+	ctx := context.Background()
+	mgr, err := NewMemoryStoreManager(ctx, "", 0)
+	if err != nil {
+		t.Fatalf("NewMemoryStoreManager: %v", err)
+	}
+	bind := func(role *Role, perms ...*Permission) {
+		t.Helper()
+		if err := mgr.CreateRole(ctx, role); err != nil {
+			t.Fatalf("CreateRole(%s): %v", role.Name, err)
+		}
+		for _, p := range perms {
+			if err := mgr.CreatePermission(ctx, p); err != nil {
+				t.Fatalf("CreatePermission: %v", err)
+			}
+			if err := mgr.AssignPermissionToRole(ctx, role.ID, p.ID); err != nil {
+				t.Fatalf("AssignPermissionToRole: %v", err)
+			}
+		}
+	}
+	frozen := &Role{Name: "frozen"}
+	bind(frozen, &Permission{Resource: "payments/*", Action: ActionAll, Effect: EffectDeny})
+	clerk := &Role{Name: "clerk"}
+	bind(clerk, &Permission{Resource: "payments/*", Action: ActionRead})
+	breakGlass := &Role{Name: "break-glass", Priority: 10}
+	bind(breakGlass, &Permission{Resource: "payments/*", Action: ActionUpdate})
+	lockdown := &Role{Name: "lockdown", Priority: 20}
+	bind(lockdown, &Permission{Resource: "payments/audit", Action: ActionAll, Effect: EffectDeny})
+
+	for _, r := range []*Role{frozen, clerk, breakGlass, lockdown} {
+		if err := mgr.AssignRoleToUser(ctx, "alice", r.ID); err != nil {
+			t.Fatalf("AssignRoleToUser: %v", err)
+		}
+	}
+
+	cases := []struct {
+		resource string
+		action   Action
+		allowed  bool
+		roleID   string
+	}{
+		// equal priorities: the deny wins as before
+		{"payments/1", ActionRead, false, frozen.ID},
+		// the higher-priority allow overrides the deny
+		{"payments/1", ActionUpdate, true, breakGlass.ID},
+		// and a higher-priority deny overrides it again
+		{"payments/audit", ActionUpdate, false, lockdown.ID},
+		{"invoices/1", ActionRead, false, ""},
+	}
+	for _, c := range cases {
+		d, err := mgr.Decide(ctx, "alice", c.resource, c.action, nil)
+		if err != nil {
+			t.Fatalf("Decide(%s, %s): %v", c.resource, c.action, err)
+		}
+		if d.Allowed != c.allowed || d.RoleID != c.roleID {
+			t.Errorf("Decide(%s, %s) = %+v, want allowed=%v role=%q", c.resource, c.action, d, c.allowed, c.roleID)
+		}
+		if ok, _ := mgr.Can(ctx, "alice", c.resource, c.action); ok != c.allowed {
+			t.Errorf("Can(%s, %s) = %v, want %v", c.resource, c.action, ok, c.allowed)
+		}
+	}
+}
+
+func TestDecideLoadsEachRoleOnce(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMockRepo()
+	mgr := NewMockRepoManager(repo)
+	counting := &countingStore{Store: repo}
+	mgr.RP = counting
+	bind := func(role *Role, perms ...*Permission) {
+		t.Helper()
+		if err := mgr.CreateRole(ctx, role); err != nil {
+			t.Fatalf("CreateRole(%s): %v", role.ID, err)
+		}
+		for _, p := range perms {
+			if err := mgr.CreatePermission(ctx, p); err != nil {
+				t.Fatalf("CreatePermission: %v", err)
+			}
+			if err := mgr.AssignPermissionToRole(ctx, role.ID, p.ID); err != nil {
+				t.Fatalf("AssignPermissionToRole: %v", err)
+			}
+		}
+	}
+	bind(&Role{ID: "reader", Name: "reader"}, &Permission{ID: "read", Resource: "docs/*", Action: ActionRead})
+	bind(&Role{ID: "intern", Name: "intern"})
+	bind(&Role{ID: "lockdown", Name: "lockdown", Priority: 10},
+		&Permission{ID: "deny-secret", Resource: "docs/secret", Action: ActionAll, Effect: EffectDeny})
+
+	// a role held directly and through several groups is loaded once
+	if err := mgr.AssignRoleToUser(ctx, "alice", "reader"); err != nil {
+		t.Fatalf("AssignRoleToUser: %v", err)
+	}
+	for _, g := range []string{"a", "b", "c"} {
+		if err := mgr.AssignRoleToGroup(ctx, g, "reader"); err != nil {
+			t.Fatalf("AssignRoleToGroup: %v", err)
+		}
+		if err := mgr.AddUserToGroup(ctx, &UserGroup{UserID: "alice", GroupName: g}); err != nil {
+			t.Fatalf("AddUserToGroup: %v", err)
+		}
+	}
+	counting.listPerms = 0
+	if ok, err := mgr.Can(ctx, "alice", "docs/1", ActionRead); err != nil || !ok {
+		t.Fatalf("Can(alice) = %v, %v; want true", ok, err)
+	}
+	if counting.listPerms != 1 {
+		t.Errorf("Can loaded role permissions %d times; want 1", counting.listPerms)
+	}
+
+	// roles that cannot outrank the decision are not loaded
+	if err := mgr.AssignRolesToUser(ctx, "bob", []string{"reader", "intern", "lockdown"}); err != nil {
+		t.Fatalf("AssignRolesToUser: %v", err)
+	}
+	counting.listPerms = 0
+	if d, err := mgr.Decide(ctx, "bob", "docs/secret", ActionRead, nil); err != nil || d.Allowed || d.RoleID != "lockdown" {
+		t.Fatalf("Decide(secret) = %+v, %v; want denied by lockdown", d, err)
+	}
+	if counting.listPerms != 1 {
+		t.Errorf("a top-priority deny loaded role permissions %d times; want 1", counting.listPerms)
+	}
+	// but an allow still looks for a deny among roles of its priority
+	counting.listPerms = 0
+	if d, err := mgr.Decide(ctx, "bob", "docs/1", ActionRead, nil); err != nil || !d.Allowed || d.RoleID != "reader" {
+		t.Fatalf("Decide(docs/1) = %+v, %v; want allowed by reader", d, err)
+	}
+	if counting.listPerms != 3 {
+		t.Errorf("an allow loaded role permissions %d times; want 3", counting.listPerms)
+	}
+}
+
+func TestDecideNamedParams(t *testing.T) {
+	ctx := context.Background()
+	mgr, err := NewMemoryStoreManager(ctx, "", 0)
+	if err != nil {
+		t.Fatalf("NewMemoryStoreManager: %v", err)
+	}
+	if err := mgr.CreateUser(ctx, &User{ID: "alice", Meta: map[string]interface{}{"project": "42"}}); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	role := &Role{Name: "member"}
+	if err := mgr.CreateRole(ctx, role); err != nil {
+		t.Fatalf("CreateRole: %v", err)
+	}
+	perm := &Permission{Resource: "projects/{project_id}/docs/*", Action: ActionRead, Condition: `params.project_id == user.meta.project`}
+	if err := mgr.CreatePermission(ctx, perm); err != nil {
+		t.Fatalf("CreatePermission: %v", err)
+	}
+	if err := mgr.AssignPermissionToRole(ctx, role.ID, perm.ID); err != nil {
+		t.Fatalf("AssignPermissionToRole: %v", err)
+	}
+	if err := mgr.AssignRoleToUser(ctx, "alice", role.ID); err != nil {
+		t.Fatalf("AssignRoleToUser: %v", err)
+	}
+
+	d, err := mgr.Decide(ctx, "alice", "projects/42/docs/spec", ActionRead, nil)
+	if err != nil || !d.Allowed || d.Params["project_id"] != "42" {
+		t.Fatalf("Decide = %+v, %v; want allowed with project_id 42", d, err)
+	}
+	if ok, _ := mgr.Can(ctx, "alice", "projects/7/docs/spec", ActionRead); ok {
+		t.Error("expected the condition to deny another project")
+	}
+	if ok, _ := mgr.CanWithAttributes(ctx, "alice", "projects/42/docs/spec", ActionRead, map[string]any{"project_id": "7"}); ok {
+		t.Error("expected a disagreeing project_id attribute to deny")
+	}
+
+	if err := mgr.CreatePermission(ctx, &Permission{Resource: "projects/{id/docs", Action: ActionRead}); err == nil {
+		t.Error("expected a malformed parameter to be rejected")
+	}
+}
+
+func TestDecideRegexResource(t *testing.T) {
+	ctx := context.Background()
+	mgr, err := NewMemoryStoreManager(ctx, "", 0)
+	if err != nil {
+		t.Fatalf("NewMemoryStoreManager: %v", err)
+	}
+	if err := mgr.CreateUser(ctx, &User{ID: "alice"}); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	role := &Role{Name: "analyst"}
+	if err := mgr.CreateRole(ctx, role); err != nil {
+		t.Fatalf("CreateRole: %v", err)
+	}
+	perm := &Permission{Resource: `re:^survey\.[0-9]+\.results$`, Action: ActionRead}
+	if err := mgr.CreatePermission(ctx, perm); err != nil {
+		t.Fatalf("CreatePermission: %v", err)
+	}
+	if err := mgr.AssignPermissionToRole(ctx, role.ID, perm.ID); err != nil {
+		t.Fatalf("AssignPermissionToRole: %v", err)
+	}
+	if err := mgr.AssignRoleToUser(ctx, "alice", role.ID); err != nil {
+		t.Fatalf("AssignRoleToUser: %v", err)
+	}
+
+	if ok, err := mgr.Can(ctx, "alice", "survey.12.results", ActionRead); err != nil || !ok {
+		t.Fatalf("Can = %v, %v; want allowed", ok, err)
+	}
+	if ok, _ := mgr.Can(ctx, "alice", "survey.12.answers", ActionRead); ok {
+		t.Error("expected a resource outside the expression to be denied")
+	}
+	if err := mgr.CreatePermission(ctx, &Permission{Resource: "re:survey(", Action: ActionRead}); err == nil {
+		t.Error("expected an invalid expression to be rejected")
+	}
+}
+
+func TestDecideSuperAdmin(t *testing.T) {
+	repo := NewMockRepo()
+	mgr := NewMockRepoManager(repo)
+	mgr.Audit = repo
+	mgr.SuperAdminRoleName = "root"
+	ctx := context.Background()
+	if err := mgr.CreateRole(ctx, &Role{ID: "r-root", Name: "root"}); err != nil {
+		t.Fatalf("CreateRole: %v", err)
+	}
+	if err := mgr.CreateRole(ctx, &Role{ID: "r-ops", Name: "ops"}); err != nil {
+		t.Fatalf("CreateRole: %v", err)
+	}
+	deny := &Permission{ID: "no-billing", Resource: "billing/**", Action: "*", Effect: EffectDeny}
+	if err := mgr.CreatePermission(ctx, deny); err != nil {
+		t.Fatalf("CreatePermission: %v", err)
+	}
+	if err := mgr.AssignPermissionToRole(ctx, "r-ops", deny.ID); err != nil {
+		t.Fatalf("AssignPermissionToRole: %v", err)
+	}
+	if err := mgr.AddRoleParent(ctx, "r-ops", "r-root"); err != nil {
+		t.Fatalf("AddRoleParent: %v", err)
+	}
+	if err := mgr.AssignRoleToUser(ctx, "alice", "r-ops"); err != nil {
+		t.Fatalf("AssignRoleToUser: %v", err)
+	}
+
+	// the role is held through inheritance and overrides a deny
+	d, err := mgr.Decide(ctx, "alice", "billing/invoices/1", ActionDelete, nil)
+	if err != nil {
+		t.Fatalf("Decide: %v", err)
+	}
+	if !d.Allowed || !d.SuperAdmin || d.RoleID != "r-root" || d.PermissionID != "" {
+		t.Errorf("Decide = %+v; want a super-admin allow by r-root", d)
+	}
+	ex, err := mgr.Explain(ctx, "alice", "billing/invoices/1", ActionDelete)
+	if err != nil {
+		t.Fatalf("Explain: %v", err)
+	}
+	if !ex.SuperAdmin || !strings.Contains(ex.Reason, "super-admin") {
+		t.Errorf("Explain = %+v; want the bypass reported", ex)
+	}
+	entries, err := mgr.ListAuditEntries(ctx, AuditQuery{Target: "alice"})
+	if err != nil {
+		t.Fatalf("ListAuditEntries: %v", err)
+	}
+	var bypassed int
+	for _, e := range entries {
+		if e.Kind == AuditDecision && e.Outcome == AuditBypassed {
+			bypassed++
+		}
+	}
+	if bypassed != 2 {
+		t.Errorf("audit log has %d bypassed decisions; want 2 though decisions are not sampled", bypassed)
+	}
+
+	mgr.SuperAdminRoleName = ""
+	if ok, err := mgr.Can(ctx, "alice", "billing/invoices/1", ActionDelete); err != nil || ok {
+		t.Errorf("Can = %v, %v without a super-admin role; want the deny", ok, err)
+	}
+}
@@ -0,0 +1,167 @@
+package rbac
+
+import (
+	"maps"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DecisionCache remembers the decisions of Can, Decide without attributes
+// and WhoCan for a while, keyed by user, resource and action, so repeated
+// identical checks skip the store. Set it as Manager.Decisions.
+//
+// Changes made through the Manager invalidate the entries they affect: a
+// user's decisions when that user's roles, groups or status change, the
+// decisions that considered a role when that role's permissions or parents
+// change, and everything on other policy changes. Changes made by other
+// processes, and assignments or delegations that lapse, are seen once the
+// TTL has passed, or earlier via the Invalidate methods.
+type DecisionCache struct {
+	ttl time.Duration
+	now func() time.Time
+
+	mu      sync.Mutex
+	gen     uint64
+	size    int
+	entries map[string]map[decisionKey]decisionEntry // by user ID
+
+	hits, misses atomic.Uint64
+}
+
+type decisionKey struct {
+	resource string
+	action   Action
+}
+
+type decisionEntry struct {
+	decision Decision
+	// roles are the expanded roles the decision considered
+	roles   []string
+	expires time.Time
+}
+
+// NewDecisionCache returns a cache whose decisions live for ttl.
+func NewDecisionCache(ttl time.Duration) *DecisionCache {
+	return &DecisionCache{
+		ttl:     ttl,
+		now:     time.Now,
+		entries: map[string]map[decisionKey]decisionEntry{},
+	}
+}
+
+// Stats returns the cache's hits and misses so far.
+func (c *DecisionCache) Stats() CacheStats {
+	return CacheStats{Hits: c.hits.Load(), Misses: c.misses.Load()}
+}
+
+// InvalidateUser drops the decisions made for userID.
+func (c *DecisionCache) InvalidateUser(userID string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.gen++
+	c.size -= len(c.entries[userID])
+	delete(c.entries, userID)
+}
+
+// InvalidateRole drops the decisions that considered roleID, whether the
+// user held it directly, through a group, by delegation or by inheritance.
+func (c *DecisionCache) InvalidateRole(roleID string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.gen++
+	for userID, byKey := range c.entries {
+		for k, e := range byKey {
+			if slices.Contains(e.roles, roleID) {
+				delete(byKey, k)
+				c.size--
+			}
+		}
+		if len(byKey) == 0 {
+			delete(c.entries, userID)
+		}
+	}
+}
+
+// InvalidateAll drops every decision.
+func (c *DecisionCache) InvalidateAll() {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.gen++
+	c.size = 0
+	c.entries = map[string]map[decisionKey]decisionEntry{}
+}
+
+func (c *DecisionCache) get(userID, resource string, action Action) (*Decision, bool) {
+	c.mu.Lock()
+	e, ok := c.entries[userID][decisionKey{resource, action}]
+	c.mu.Unlock()
+	if !ok || !c.now().Before(e.expires) {
+		c.misses.Add(1)
+		return nil, false
+	}
+	c.hits.Add(1)
+	d := e.decision
+	d.Params = maps.Clone(d.Params)
+	return &d, true
+}
+
+func (c *DecisionCache) generation() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.gen
+}
+
+// put stores d unless the cache was invalidated after gen was read, so a
+// decision made from data a concurrent change replaced is not kept.
+func (c *DecisionCache) put(userID, resource string, action Action, d *Decision, roles []string, gen uint64) {
+	now := c.now()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if gen != c.gen {
+		return
+	}
+	if c.size >= maxCacheEntries {
+		c.sweep(now)
+	}
+	byKey := c.entries[userID]
+	if byKey == nil {
+		byKey = map[decisionKey]decisionEntry{}
+		c.entries[userID] = byKey
+	}
+	k := decisionKey{resource, action}
+	if _, ok := byKey[k]; !ok {
+		c.size++
+	}
+	byKey[k] = decisionEntry{decision: *d, roles: slices.Clone(roles), expires: now.Add(c.ttl)}
+}
+
+// sweep drops the expired decisions, and every decision if that is not
+// enough to make room.
+func (c *DecisionCache) sweep(now time.Time) {
+	for userID, byKey := range c.entries {
+		for k, e := range byKey {
+			if !now.Before(e.expires) {
+				delete(byKey, k)
+				c.size--
+			}
+		}
+		if len(byKey) == 0 {
+			delete(c.entries, userID)
+		}
+	}
+	if c.size >= maxCacheEntries {
+		c.size = 0
+		c.entries = map[string]map[decisionKey]decisionEntry{}
+	}
+}
@@ -0,0 +1,129 @@
+package rbac
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestDecisionCache(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMockRepo()
+	mgr := NewMockRepoManager(repo)
+	counting := &countingStore{Store: repo}
+	mgr.UR = counting
+	mgr.Decisions = NewDecisionCache(time.Minute)
+	now := time.Now()
+	mgr.Decisions.now = func() time.Time { return now }
+
+	reader := &Role{ID: "reader", Name: "reader"}
+	writer := &Role{ID: "writer", Name: "writer"}
+	for _, r := range []*Role{reader, writer} {
+		if err := mgr.CreateRole(ctx, r); err != nil {
+			t.Fatalf("CreateRole: %v", err)
+		}
+	}
+	read := &Permission{ID: "read", Resource: "docs/*", Action: ActionRead}
+	write := &Permission{ID: "write", Resource: "docs/*", Action: ActionUpdate}
+	for _, p := range []*Permission{read, write} {
+		if err := mgr.CreatePermission(ctx, p); err != nil {
+			t.Fatalf("CreatePermission: %v", err)
+		}
+	}
+	if err := mgr.AssignPermissionToRole(ctx, "reader", "read"); err != nil {
+		t.Fatalf("AssignPermissionToRole: %v", err)
+	}
+	if err := mgr.AssignRoleToUser(ctx, "alice", "reader"); err != nil {
+		t.Fatalf("AssignRoleToUser: %v", err)
+	}
+
+	can := func(action Action, want bool) {
+		t.Helper()
+		ok, err := mgr.Can(ctx, "alice", "docs/1", action)
+		if err != nil {
+			t.Fatalf("Can: %v", err)
+		}
+		if ok != want {
+			t.Fatalf("Can(%s) = %v; want %v", action, ok, want)
+		}
+	}
+
+	can(ActionRead, true)
+	reads := counting.listRoles
+	can(ActionRead, true)
+	if counting.listRoles != reads {
+		t.Errorf("cached Can read the store %d times; want none", counting.listRoles-reads)
+	}
+	if s := mgr.Decisions.Stats(); s.Hits != 1 || s.Misses != 1 {
+		t.Errorf("Stats = %+v; want 1 hit and 1 miss", s)
+	}
+
+	// assigning a role drops the user's decisions
+	can(ActionUpdate, false)
+	if err := mgr.AssignRoleToUser(ctx, "alice", "writer"); err != nil {
+		t.Fatalf("AssignRoleToUser: %v", err)
+	}
+	if err := mgr.AssignPermissionToRole(ctx, "writer", "write"); err != nil {
+		t.Fatalf("AssignPermissionToRole: %v", err)
+	}
+	can(ActionUpdate, true)
+
+	// changing a role's permissions drops the decisions that considered it
+	if err := mgr.RemovePermissionFromRole(ctx, "reader", "read"); err != nil {
+		t.Fatalf("RemovePermissionFromRole: %v", err)
+	}
+	can(ActionRead, false)
+
+	// attributes and Explain bypass the cache
+	reads = counting.listRoles
+	if _, err := mgr.CanWithAttributes(ctx, "alice", "docs/1", ActionUpdate, map[string]any{"ip": "10.0.0.1"}); err != nil {
+		t.Fatalf("CanWithAttributes: %v", err)
+	}
+	if _, err := mgr.Explain(ctx, "alice", "docs/1", ActionUpdate); err != nil {
+		t.Fatalf("Explain: %v", err)
+	}
+	if counting.listRoles != reads+2 {
+		t.Errorf("attribute and Explain checks read the store %d times; want 2", counting.listRoles-reads)
+	}
+
+	// entries expire after the TTL
+	reads = counting.listRoles
+	now = now.Add(time.Minute)
+	can(ActionUpdate, true)
+	if counting.listRoles != reads+1 {
+		t.Errorf("expired decision read the store %d times; want 1", counting.listRoles-reads)
+	}
+}
+
+func TestDecisionCacheInvalidate(t *testing.T) {
+	c := NewDecisionCache(time.Minute)
+	d := &Decision{Allowed: true}
+	c.put("alice", "docs/1", ActionRead, d, []string{"reader"}, c.generation())
+	c.put("bob", "docs/1", ActionRead, d, []string{"writer"}, c.generation())
+	c.put("carol", "docs/1", ActionRead, d, []string{"reader"}, c.generation())
+
+	c.InvalidateRole("reader")
+	if _, ok := c.get("alice", "docs/1", ActionRead); ok {
+		t.Errorf("alice's decision survived InvalidateRole(reader)")
+	}
+	if _, ok := c.get("bob", "docs/1", ActionRead); !ok {
+		t.Errorf("bob's decision was dropped by InvalidateRole(reader)")
+	}
+	c.InvalidateUser("bob")
+	if _, ok := c.get("bob", "docs/1", ActionRead); ok {
+		t.Errorf("bob's decision survived InvalidateUser(bob)")
+	}
+
+	// a decision made before an invalidation is not stored
+	gen := c.generation()
+	c.InvalidateAll()
+	c.put("dave", "docs/1", ActionRead, d, nil, gen)
+	if _, ok := c.get("dave", "docs/1", ActionRead); ok {
+		t.Errorf("stale decision was stored")
+	}
+
+	var nilCache *DecisionCache
+	nilCache.InvalidateUser("alice")
+	nilCache.InvalidateRole("reader")
+	nilCache.InvalidateAll()
+}
@@ -0,0 +1,129 @@
+package rbac
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestDelegateRole(t *testing.T) {
+	ctx := context.Background()
+	memory, err := NewMemoryStoreManager(ctx, "", 0)
+	if err != nil {
+		t.Fatalf("NewMemoryStoreManager: %v", err)
+	}
+	for name, mgr := range map[string]*Manager{"memory": memory, "mock": NewMockRepoManager(NewMockRepo())} {
+		t.Run(name, func(t *testing.T) {
+			approver := &Role{Name: "invoice-approver"}
+			if err := mgr.CreateRole(ctx, approver); err != nil {
+				t.Fatalf("CreateRole: %v", err)
+			}
+			perm := &Permission{Resource: "invoices/*", Action: ActionUpdate}
+			if err := mgr.CreatePermission(ctx, perm); err != nil {
+				t.Fatalf("CreatePermission: %v", err)
+			}
+			if err := mgr.AssignPermissionToRole(ctx, approver.ID, perm.ID); err != nil {
+				t.Fatalf("AssignPermissionToRole: %v", err)
+			}
+			if err := mgr.AssignRoleToUser(ctx, "alice", approver.ID); err != nil {
+				t.Fatalf("AssignRoleToUser: %v", err)
+			}
+			until := time.Now().Add(time.Hour)
+
+			if _, err := mgr.DelegateRole(ctx, "bob", "carol", approver.ID, until); !errors.Is(err, ErrNotAssigned) {
+				t.Errorf("delegating a role not held: expected ErrNotAssigned, got %v", err)
+			}
+			if _, err := mgr.DelegateRole(ctx, "alice", "alice", approver.ID, until); !errors.Is(err, ErrInvalidDelegation) {
+				t.Errorf("delegating to oneself: expected ErrInvalidDelegation, got %v", err)
+			}
+			if _, err := mgr.DelegateRole(ctx, "alice", "bob", approver.ID, time.Now().Add(-time.Minute)); !errors.Is(err, ErrInvalidDelegation) {
+				t.Errorf("delegating into the past: expected ErrInvalidDelegation, got %v", err)
+			}
+
+			d, err := mgr.DelegateRole(ctx, "alice", "bob", approver.ID, until)
+			if err != nil {
+				t.Fatalf("DelegateRole: %v", err)
+			}
+			if ok, err := mgr.Can(ctx, "bob", "invoices/42", ActionUpdate); err != nil || !ok {
+				t.Errorf("expected bob to be allowed through the delegation, got %v, %v", ok, err)
+			}
+			if _, err := mgr.DelegateRole(ctx, "bob", "carol", approver.ID, until); !errors.Is(err, ErrNotAssigned) {
+				t.Errorf("re-delegating: expected ErrNotAssigned, got %v", err)
+			}
+			for _, u := range []string{"alice", "bob"} {
+				if list, err := mgr.ListDelegations(ctx, u); err != nil || len(list) != 1 || list[0].ID != d.ID {
+					t.Errorf("ListDelegations(%s): got %v, %v", u, list, err)
+				}
+			}
+
+			if err := mgr.RevokeDelegation(ctx, d.ID); err != nil {
+				t.Fatalf("RevokeDelegation: %v", err)
+			}
+			if ok, _ := mgr.Can(ctx, "bob", "invoices/42", ActionUpdate); ok {
+				t.Error("expected the revoked delegation to no longer apply")
+			}
+			if err := mgr.RevokeDelegation(ctx, d.ID); !errors.Is(err, ErrDelegationNotFound) {
+				t.Errorf("expected ErrDelegationNotFound, got %v", err)
+			}
+		})
+	}
+}
+
+func TestDelegationExpires(t *testing.T) {
+	ctx := context.Background()
+	mgr := NewMockRepoManager(NewMockRepo())
+	role := &Role{Name: "on-call"}
+	if err := mgr.CreateRole(ctx, role); err != nil {
+		t.Fatalf("CreateRole: %v", err)
+	}
+	perm := &Permission{Resource: "pager", Action: ActionRead}
+	if err := mgr.CreatePermission(ctx, perm); err != nil {
+		t.Fatalf("CreatePermission: %v", err)
+	}
+	if err := mgr.AssignPermissionToRole(ctx, role.ID, perm.ID); err != nil {
+		t.Fatalf("AssignPermissionToRole: %v", err)
+	}
+	now := time.Now()
+	if err := mgr.Delegations.SaveDelegation(ctx, &Delegation{
+		FromUserID: "alice", ToUserID: "bob", RoleID: role.ID,
+		Until: now.Add(-time.Second).Unix(), CreatedAt: now.Add(-time.Hour).Unix(),
+	}); err != nil {
+		t.Fatalf("SaveDelegation: %v", err)
+	}
+	if ok, _ := mgr.Can(ctx, "bob", "pager", ActionRead); ok {
+		t.Error("expected the expired delegation to no longer apply")
+	}
+	if list, err := mgr.ListDelegations(ctx, "bob"); err != nil || len(list) != 0 {
+		t.Errorf("expected no unexpired delegations, got %v, %v", list, err)
+	}
+}
+
+func TestDelegationSeparationOfDuties(t *testing.T) {
+	ctx := context.Background()
+	mgr := NewMockRepoManager(NewMockRepo())
+	requester, approver := &Role{Name: "requester"}, &Role{Name: "approver"}
+	for _, r := range []*Role{requester, approver} {
+		if err := mgr.CreateRole(ctx, r); err != nil {
+			t.Fatalf("CreateRole: %v", err)
+		}
+	}
+	if err := mgr.CreateSoDConstraint(ctx, &SoDConstraint{Name: "payments", Roles: []string{requester.ID, approver.ID}}); err != nil {
+		t.Fatalf("CreateSoDConstraint: %v", err)
+	}
+	if err := mgr.AssignRoleToUser(ctx, "alice", approver.ID); err != nil {
+		t.Fatalf("AssignRoleToUser: %v", err)
+	}
+	if err := mgr.AssignRoleToUser(ctx, "bob", requester.ID); err != nil {
+		t.Fatalf("AssignRoleToUser: %v", err)
+	}
+	if _, err := mgr.DelegateRole(ctx, "alice", "bob", approver.ID, time.Now().Add(time.Hour)); !errors.Is(err, ErrSoDConflict) {
+		t.Fatalf("expected ErrSoDConflict, got %v", err)
+	}
+	if _, err := mgr.DelegateRole(ctx, "bob", "dave", requester.ID, time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("DelegateRole: %v", err)
+	}
+	if err := mgr.AssignRoleToUser(ctx, "dave", approver.ID); !errors.Is(err, ErrSoDConflict) {
+		t.Errorf("expected the delegated role to count towards separation of duties, got %v", err)
+	}
+}
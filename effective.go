@@ -0,0 +1,51 @@
+package rbac
+
+import (
+	"context"
+	"time"
+)
+
+// ListEffectivePermissions returns the permissions userID holds through its
+// direct roles, its group roles, the roles delegated to it and every role
+// those inherit, each listed once. Soft-deleted roles and permissions are
+// left out. Scoped and constrained roles, which apply only to some requests,
+// and generated permissions, which depend on the request, are not included.
+func (m *Manager) ListEffectivePermissions(ctx context.Context, userID string) ([]*Permission, error) {
+	start := time.Now()
+	ctx, span := m.startSpan(ctx, "ListEffectivePermissions")
+	defer span.End()
+	out, err := m.effectivePermissions(ctx, start, userID)
+	m.record(ctx, start, "ListEffectivePermissions", err)
+	return out, err
+}
+
+func (m *Manager) effectivePermissions(ctx context.Context, start time.Time, userID string) ([]*Permission, error) {
+	roles, _, err := m.resolveRoles(withoutRequestContext(ctx), nil, start, "ListEffectivePermissions", userID, "")
+	if err != nil {
+		return nil, err
+	}
+
+	out := []*Permission{}
+	seen := map[string]bool{}
+	for _, roleID := range roles {
+		role, err := m.Roles.GetRoleByID(ctx, roleID)
+		if err != nil {
+			return nil, err
+		}
+		if role != nil && role.DeletedAt != 0 {
+			continue
+		}
+		perms, err := m.rolePermissions(ctx, start, roleID)
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range perms {
+			if p.DeletedAt != 0 || seen[p.ID] {
+				continue
+			}
+			seen[p.ID] = true
+			out = append(out, p)
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,91 @@
+package rbac
+
+import (
+	"context"
+	"sort"
+	"testing"
+)
+
+func TestListEffectivePermissions(t *testing.T) {
+	ctx := context.Background()
+	memory, err := NewMemoryStoreManager(ctx, "", 0)
+	if err != nil {
+		t.Fatalf("NewMemoryStoreManager: %v", err)
+	}
+	for name, mgr := range map[string]*Manager{"memory": memory, "mock": NewMockRepoManager(NewMockRepo())} {
+		t.Run(name, func(t *testing.T) {
+			viewer := &Role{Name: "viewer"}
+			editor := &Role{Name: "editor"}
+			auditor := &Role{Name: "auditor"}
+			for _, r := range []*Role{viewer, editor, auditor} {
+				if err := mgr.CreateRole(ctx, r); err != nil {
+					t.Fatalf("CreateRole: %v", err)
+				}
+			}
+			if err := mgr.AddRoleParent(ctx, editor.ID, viewer.ID); err != nil {
+				t.Fatalf("AddRoleParent: %v", err)
+			}
+			read := &Permission{Resource: "docs/*", Action: ActionRead}
+			update := &Permission{Resource: "docs/*", Action: ActionUpdate}
+			logs := &Permission{Resource: "logs/*", Action: ActionRead}
+			gone := &Permission{Resource: "old/*", Action: ActionRead}
+			for _, p := range []*Permission{read, update, logs, gone} {
+				if err := mgr.CreatePermission(ctx, p); err != nil {
+					t.Fatalf("CreatePermission: %v", err)
+				}
+			}
+			for roleID, perms := range map[string][]*Permission{
+				viewer.ID:  {read},
+				editor.ID:  {update, gone},
+				auditor.ID: {logs, read},
+			} {
+				for _, p := range perms {
+					if err := mgr.AssignPermissionToRole(ctx, roleID, p.ID); err != nil {
+						t.Fatalf("AssignPermissionToRole: %v", err)
+					}
+				}
+			}
+			if err := mgr.DeletePermission(ctx, gone.ID); err != nil {
+				t.Fatalf("DeletePermission: %v", err)
+			}
+			if err := mgr.AssignRoleToUser(ctx, "alice", editor.ID); err != nil {
+				t.Fatalf("AssignRoleToUser: %v", err)
+			}
+			if err := mgr.AssignRoleToGroup(ctx, "ops", auditor.ID); err != nil {
+				t.Fatalf("AssignRoleToGroup: %v", err)
+			}
+			if err := mgr.AddUserToGroup(ctx, &UserGroup{UserID: "alice", GroupName: "ops"}); err != nil {
+				t.Fatalf("AddUserToGroup: %v", err)
+			}
+
+			perms, err := mgr.ListEffectivePermissions(ctx, "alice")
+			if err != nil {
+				t.Fatalf("ListEffectivePermissions: %v", err)
+			}
+			var got []string
+			for _, p := range perms {
+				if p.ID == read.ID || p.ID == update.ID || p.ID == logs.ID || p.ID == gone.ID {
+					got = append(got, p.ID)
+				}
+			}
+			want := []string{read.ID, update.ID, logs.ID}
+			sort.Strings(got)
+			sort.Strings(want)
+			if len(got) != len(want) {
+				t.Fatalf("got permissions %v; want %v once each", got, want)
+			}
+			for i := range want {
+				if got[i] != want[i] {
+					t.Fatalf("got permissions %v; want %v once each", got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestListEffectivePermissionsNoRoles(t *testing.T) {
+	perms, err := NewMockRepoManager(NewMockRepo()).ListEffectivePermissions(context.Background(), "nobody")
+	if err != nil || perms == nil || len(perms) != 0 {
+		t.Errorf("ListEffectivePermissions = %v, %v; want an empty list", perms, err)
+	}
+}
@@ -0,0 +1,48 @@
+package rbac
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// EmailVerificationRepo is optionally implemented by a UserRepo that can
+// change a stored user's EmailVerified flag.
+type EmailVerificationRepo interface {
+	SetEmailVerified(ctx context.Context, id string, verified bool) error
+}
+
+var errEmailVerificationUnsupported = errors.New("rbac: repo does not support email verification")
+
+// GetUserByEmail returns the user with the email, or nil, nil. Stores
+// implementing UserLookup answer it directly, others through GetUserByMeta.
+func (m *Manager) GetUserByEmail(ctx context.Context, email string) (*User, error) {
+	start := time.Now()
+	ctx, span := m.startSpan(ctx, "GetUserByEmail")
+	defer span.End()
+	u, err := m.lookupUser(ctx, "email", email)
+	m.record(ctx, start, "GetUserByEmail", err)
+	return u, err
+}
+
+// SetEmailVerified records whether the user proved they own their email,
+// which Can requires when RequireVerifiedEmail is set.
+func (m *Manager) SetEmailVerified(ctx context.Context, id string, verified bool) error {
+	start := time.Now()
+	ctx, span := m.startSpan(ctx, "SetEmailVerified")
+	defer span.End()
+	err := errEmailVerificationUnsupported
+	if repo, ok := m.Users.(EmailVerificationRepo); ok {
+		var u *User
+		if u, err = m.Users.GetUserByID(ctx, id); err == nil && u == nil {
+			err = fmt.Errorf("%w: %q", ErrUserNotFound, id)
+		}
+		if err == nil {
+			err = repo.SetEmailVerified(ctx, id, verified)
+		}
+	}
+	m.record(ctx, start, "SetEmailVerified", err)
+	m.changedUser(ctx, "SetEmailVerified", id, err)
+	return err
+}
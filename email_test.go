@@ -0,0 +1,84 @@
+package rbac
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestGetUserByEmail(t *testing.T) {
+	ctx := context.Background()
+	memory, err := NewMemoryStoreManager(ctx, "", 0)
+	if err != nil {
+		t.Fatalf("NewMemoryStoreManager: %v", err)
+	}
+	for name, mgr := range map[string]*Manager{"memory": memory, "mock": NewMockRepoManager(NewMockRepo())} {
+		t.Run(name, func(t *testing.T) {
+			alice := &User{Username: "alice", Email: "alice@example.com"}
+			if err := mgr.CreateUser(ctx, alice); err != nil {
+				t.Fatalf("CreateUser: %v", err)
+			}
+			if u, err := mgr.GetUserByEmail(ctx, "alice@example.com"); err != nil || u == nil || u.ID != alice.ID {
+				t.Errorf("GetUserByEmail = %+v, %v; want alice", u, err)
+			}
+			if u, err := mgr.GetUserByEmail(ctx, "nobody@example.com"); err != nil || u != nil {
+				t.Errorf("GetUserByEmail(unknown) = %+v, %v; want nil, nil", u, err)
+			}
+			if u, err := mgr.ForTenant("acme").GetUserByEmail(ctx, "alice@example.com"); err != nil || u != nil {
+				t.Errorf("expected another tenant not to find alice, got %+v, %v", u, err)
+			}
+		})
+	}
+}
+
+func TestRequireVerifiedEmail(t *testing.T) {
+	ctx := context.Background()
+	memory, err := NewMemoryStoreManager(ctx, "", 0)
+	if err != nil {
+		t.Fatalf("NewMemoryStoreManager: %v", err)
+	}
+	for name, mgr := range map[string]*Manager{"memory": memory, "mock": NewMockRepoManager(NewMockRepo())} {
+		t.Run(name, func(t *testing.T) {
+			role := &Role{Name: "reader"}
+			if err := mgr.CreateRole(ctx, role); err != nil {
+				t.Fatalf("CreateRole: %v", err)
+			}
+			perm := &Permission{Resource: "docs/*", Action: ActionRead}
+			if err := mgr.CreatePermission(ctx, perm); err != nil {
+				t.Fatalf("CreatePermission: %v", err)
+			}
+			if err := mgr.AssignPermissionToRole(ctx, role.ID, perm.ID); err != nil {
+				t.Fatalf("AssignPermissionToRole: %v", err)
+			}
+			alice := &User{Username: "alice", Email: "alice@example.com"}
+			if err := mgr.CreateUser(ctx, alice); err != nil {
+				t.Fatalf("CreateUser: %v", err)
+			}
+			if err := mgr.AssignRoleToUser(ctx, alice.ID, role.ID); err != nil {
+				t.Fatalf("AssignRoleToUser: %v", err)
+			}
+
+			if ok, _ := mgr.Can(ctx, alice.ID, "docs/1", ActionRead); !ok {
+				t.Fatal("expected an unverified user to be allowed by default")
+			}
+			mgr.RequireVerifiedEmail = true
+			defer func() { mgr.RequireVerifiedEmail = false }()
+			if ok, _ := mgr.Can(ctx, alice.ID, "docs/1", ActionRead); ok {
+				t.Error("expected an unverified user to be denied")
+			}
+			if ok, _ := mgr.HasPermission(ctx, alice.ID, perm.ID); ok {
+				t.Error("expected HasPermission to deny an unverified user")
+			}
+
+			if err := mgr.SetEmailVerified(ctx, alice.ID, true); err != nil {
+				t.Fatalf("SetEmailVerified: %v", err)
+			}
+			if ok, _ := mgr.Can(ctx, alice.ID, "docs/1", ActionRead); !ok {
+				t.Error("expected a verified user to be allowed")
+			}
+			if err := mgr.SetEmailVerified(ctx, "missing", true); !errors.Is(err, ErrUserNotFound) {
+				t.Errorf("expected ErrUserNotFound, got %v", err)
+			}
+		})
+	}
+}
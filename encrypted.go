@@ -0,0 +1,270 @@
+// file: rbac/encrypted_store.go
+package rbac
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"strings"
+)
+
+var _ Store = (*EncryptedStore)(nil)
+var _ HealthChecker = (*EncryptedStore)(nil)
+
+// encryptedPrefix marks a value sealed by a FieldEncryptor. The full format
+// is "enc:v1:<key id>:<base64 nonce||ciphertext>".
+const encryptedPrefix = "enc:v1:"
+
+// KeyProvider supplies AES keys (16, 24 or 32 bytes) to a FieldEncryptor.
+// Implementations backed by a KMS typically unwrap data keys on first use
+// and cache them. Keys that have been rotated out must stay available
+// through Key for as long as values sealed with them exist.
+type KeyProvider interface {
+	// CurrentKey returns the key new values are sealed with.
+	CurrentKey(ctx context.Context) (id string, key []byte, err error)
+	// Key returns the key with the given id.
+	Key(ctx context.Context, id string) ([]byte, error)
+}
+
+// StaticKeys is a KeyProvider holding its keys in memory.
+type StaticKeys struct {
+	Current string
+	Keys    map[string][]byte
+}
+
+func (k StaticKeys) CurrentKey(ctx context.Context) (string, []byte, error) {
+	key, err := k.Key(ctx, k.Current)
+	return k.Current, key, err
+}
+
+func (k StaticKeys) Key(_ context.Context, id string) ([]byte, error) {
+	key, ok := k.Keys[id]
+	if !ok {
+		return nil, fmt.Errorf("encrypted_store: unknown key %q", id)
+	}
+	return key, nil
+}
+
+// FieldEncryptor seals individual values with AES-GCM. The additional data
+// passed to Seal must be passed to Open again, which ties a ciphertext to
+// the field it was written to.
+type FieldEncryptor struct {
+	Keys KeyProvider
+}
+
+// Seal encrypts plaintext with the provider's current key.
+func (e *FieldEncryptor) Seal(ctx context.Context, plaintext, aad []byte) (string, error) {
+	id, key, err := e.Keys.CurrentKey(ctx)
+	if err != nil {
+		return "", err
+	}
+	if strings.Contains(id, ":") {
+		return "", fmt.Errorf("encrypted_store: key id %q must not contain ':'", id)
+	}
+	aead, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := aead.Seal(nonce, nonce, plaintext, aad)
+	return encryptedPrefix + id + ":" + base64.RawStdEncoding.EncodeToString(sealed), nil
+}
+
+// Open decrypts a value produced by Seal.
+func (e *FieldEncryptor) Open(ctx context.Context, value string, aad []byte) ([]byte, error) {
+	id, payload, ok := strings.Cut(strings.TrimPrefix(value, encryptedPrefix), ":")
+	if !ok || !IsEncrypted(value) {
+		return nil, errors.New("encrypted_store: value is not encrypted")
+	}
+	sealed, err := base64.RawStdEncoding.DecodeString(payload)
+	if err != nil {
+		return nil, fmt.Errorf("encrypted_store: decode: %w", err)
+	}
+	key, err := e.Keys.Key(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < aead.NonceSize() {
+		return nil, errors.New("encrypted_store: ciphertext too short")
+	}
+	nonce, ct := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	out, err := aead.Open(nil, nonce, ct, aad)
+	if err != nil {
+		return nil, fmt.Errorf("encrypted_store: decrypt with key %q: %w", id, err)
+	}
+	return out, nil
+}
+
+// IsEncrypted reports whether value was produced by a FieldEncryptor.
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, encryptedPrefix)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("encrypted_store: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
+
+//
+// ---------- EncryptedStore ----------
+//
+
+// EncryptedStore wraps a Store and encrypts the listed user Meta keys
+// before they reach the backend, decrypting them again on read. Values are
+// JSON-encoded first, so any Meta value type round-trips. Values the store
+// finds unencrypted, such as those written before it was introduced, are
+// returned as they are.
+//
+// Because every encryption uses a fresh nonce, users cannot be looked up
+// by an encrypted key: GetUserByMeta returns an error when asked to.
+type EncryptedStore struct {
+	Store
+	enc       *FieldEncryptor
+	sensitive map[string]bool
+}
+
+// NewEncryptedStore wraps inner, encrypting the Meta values under metaKeys
+// with keys from keys.
+func NewEncryptedStore(inner Store, keys KeyProvider, metaKeys ...string) *EncryptedStore {
+	s := &EncryptedStore{Store: inner, enc: &FieldEncryptor{Keys: keys}, sensitive: map[string]bool{}}
+	for _, k := range metaKeys {
+		s.sensitive[k] = true
+	}
+	return s
+}
+
+// NewEncryptedStoreManager wraps inner in an EncryptedStore and a Manager.
+// Seeding the default role is left to the inner store's own constructor.
+func NewEncryptedStoreManager(inner Store, keys KeyProvider, metaKeys ...string) *Manager {
+	s := NewEncryptedStore(inner, keys, metaKeys...)
+	return &Manager{
+		Perms:           s,
+		Roles:           s,
+		Users:           s,
+		RP:              s,
+		UR:              s,
+		UG:              s,
+		GR:              s,
+		DefaultRoleName: "default",
+	}
+}
+
+// Encryptor returns the store's FieldEncryptor, for sealing other secrets
+// with the same keys.
+func (s *EncryptedStore) Encryptor() *FieldEncryptor {
+	return s.enc
+}
+
+// Health implements HealthChecker by checking the wrapped store.
+func (s *EncryptedStore) Health(ctx context.Context) error {
+	return checkHealth(ctx, s.Store)
+}
+
+// CreateUser persists a copy of u with its sensitive Meta values encrypted;
+// u itself keeps the plaintext.
+func (s *EncryptedStore) CreateUser(ctx context.Context, u *User) error {
+	cp := *u
+	meta, err := s.sealMeta(ctx, u.Meta)
+	if err != nil {
+		return err
+	}
+	cp.Meta = meta
+	err = s.Store.CreateUser(ctx, &cp)
+	u.ID, u.CreatedAt = cp.ID, cp.CreatedAt
+	return err
+}
+
+func (s *EncryptedStore) GetUserByID(ctx context.Context, id string) (*User, error) {
+	u, err := s.Store.GetUserByID(ctx, id)
+	if u == nil || err != nil {
+		return u, err
+	}
+	return s.openUser(ctx, u)
+}
+
+func (s *EncryptedStore) GetUserByMeta(ctx context.Context, meta map[string]interface{}) (*User, error) {
+	for k := range meta {
+		key, _, _ := strings.Cut(strings.TrimPrefix(k, MetaPrefix), ".")
+		if s.sensitive[key] {
+			return nil, fmt.Errorf("encrypted_store: cannot look users up by encrypted meta key %q", k)
+		}
+	}
+	u, err := s.Store.GetUserByMeta(ctx, meta)
+	if u == nil || err != nil {
+		return u, err
+	}
+	return s.openUser(ctx, u)
+}
+
+func (s *EncryptedStore) ListAllUsers(ctx context.Context) ([]*User, error) {
+	users, err := s.Store.ListAllUsers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for i, u := range users {
+		if users[i], err = s.openUser(ctx, u); err != nil {
+			return nil, err
+		}
+	}
+	return users, nil
+}
+
+func (s *EncryptedStore) sealMeta(ctx context.Context, meta map[string]interface{}) (map[string]interface{}, error) {
+	if len(meta) == 0 {
+		return meta, nil
+	}
+	out := maps.Clone(meta)
+	for k, v := range meta {
+		if !s.sensitive[k] {
+			continue
+		}
+		raw, err := json.Marshal(v)
+		if err != nil {
+			return nil, fmt.Errorf("encrypted_store: encode meta %q: %w", k, err)
+		}
+		sealed, err := s.enc.Seal(ctx, raw, []byte("meta:"+k))
+		if err != nil {
+			return nil, err
+		}
+		out[k] = sealed
+	}
+	return out, nil
+}
+
+// openUser returns a copy of u with its sensitive Meta values decrypted.
+func (s *EncryptedStore) openUser(ctx context.Context, u *User) (*User, error) {
+	cp := *u
+	cp.Meta = maps.Clone(u.Meta)
+	for k, v := range u.Meta {
+		str, ok := v.(string)
+		if !s.sensitive[k] || !ok || !IsEncrypted(str) {
+			continue
+		}
+		raw, err := s.enc.Open(ctx, str, []byte("meta:"+k))
+		if err != nil {
+			return nil, fmt.Errorf("user %s meta %q: %w", u.ID, k, err)
+		}
+		var val interface{}
+		if err := json.Unmarshal(raw, &val); err != nil {
+			return nil, fmt.Errorf("encrypted_store: decode meta %q: %w", k, err)
+		}
+		cp.Meta[k] = val
+	}
+	return &cp, nil
+}
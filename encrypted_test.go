@@ -0,0 +1,57 @@
+package rbac
+
+import (
+	"bytes"
+	"context"
+	"testing"
+)
+
+func TestEncryptedStore(t *testing.T) {
+	ctx := context.Background()
+	keys := StaticKeys{Current: "k1", Keys: map[string][]byte{
+		"k1": bytes.Repeat([]byte{1}, 32),
+		"k2": bytes.Repeat([]byte{2}, 32),
+	}}
+	inner := NewMockRepo()
+	mgr := NewEncryptedStoreManager(inner, keys, "ssn")
+
+	u := &User{ID: "alice", Meta: map[string]interface{}{"ssn": "123-45-6789", "team": "core"}}
+	if err := mgr.CreateUser(ctx, u); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	if u.Meta["ssn"] != "123-45-6789" {
+		t.Errorf("expected the caller's user to keep the plaintext, got %v", u.Meta["ssn"])
+	}
+
+	stored, _ := inner.GetUserByID(ctx, "alice")
+	if s, _ := stored.Meta["ssn"].(string); !IsEncrypted(s) {
+		t.Fatalf("expected ssn to be encrypted at rest, got %v", stored.Meta["ssn"])
+	}
+	if stored.Meta["team"] != "core" {
+		t.Errorf("expected team to be stored in plaintext, got %v", stored.Meta["team"])
+	}
+
+	// Rotating the current key leaves old values readable.
+	mgr.Users.(*EncryptedStore).enc.Keys = StaticKeys{Current: "k2", Keys: keys.Keys}
+	got, err := mgr.GetUser(ctx, "alice")
+	if err != nil {
+		t.Fatalf("GetUser: %v", err)
+	}
+	if got.Meta["ssn"] != "123-45-6789" {
+		t.Errorf("expected ssn to be decrypted on read, got %v", got.Meta["ssn"])
+	}
+
+	if _, err := mgr.Users.GetUserByMeta(ctx, map[string]interface{}{"ssn": "123-45-6789"}); err == nil {
+		t.Errorf("expected a lookup by an encrypted key to fail")
+	}
+
+	// A ciphertext moved to another field does not decrypt.
+	enc := &FieldEncryptor{Keys: keys}
+	sealed, err := enc.Seal(ctx, []byte(`"x"`), []byte("meta:ssn"))
+	if err != nil {
+		t.Fatalf("Seal: %v", err)
+	}
+	if _, err := enc.Open(ctx, sealed, []byte("meta:other")); err == nil {
+		t.Errorf("expected Open with different additional data to fail")
+	}
+}
@@ -0,0 +1,700 @@
+// file: rbac/etcd_store.go
+package rbac
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// Ensure EtcdStore implements all interfaces:
+var (
+	_ PermissionRepo     = (*EtcdStore)(nil)
+	_ RoleRepo           = (*EtcdStore)(nil)
+	_ UserRepo           = (*EtcdStore)(nil)
+	_ RolePermissionRepo = (*EtcdStore)(nil)
+	_ UserRoleRepo       = (*EtcdStore)(nil)
+	_ UserGroupRepo      = (*EtcdStore)(nil)
+	_ GroupRoleRepo      = (*EtcdStore)(nil)
+	_ Watcher            = (*EtcdStore)(nil)
+	_ PolicyVersioner    = (*EtcdStore)(nil)
+	_ HealthChecker      = (*EtcdStore)(nil)
+)
+
+// Key layout below the store prefix. Entities are JSON documents, the *_by_*
+// keys are unique indexes holding the entity ID, and every join record is a
+// key of its own so listing one side is a single prefix scan.
+const (
+	etcdPermissions           = "permissions"
+	etcdPermissionsByResource = "permissions_by_resource"
+	etcdRoles                 = "roles"
+	etcdRolesByName           = "roles_by_name"
+	etcdUsers                 = "users"
+	etcdUsersByUsername       = "users_by_username"
+	etcdUsersByEmail          = "users_by_email"
+	etcdRolePermissions       = "role_permissions"
+	etcdUserRoles             = "user_roles"
+	etcdUserGroups            = "user_groups"
+	etcdGroupUsers            = "group_users"
+	etcdGroupRoles            = "group_roles"
+)
+
+//
+// ---------- EtcdStore Core ----------
+//
+
+// EtcdStore keeps all RBAC data under a single key prefix in etcd, so several
+// processes (e.g. Kubernetes controllers) can share one consistent policy
+// store and react to changes through Watch.
+type EtcdStore struct {
+	cli    *clientv3.Client
+	prefix string
+	ids    IDGenerator
+}
+
+// NewEtcdStore creates a store rooted at prefix (default "/rbac/") and checks
+// that the cluster is reachable.
+func NewEtcdStore(ctx context.Context, cli *clientv3.Client, prefix string) (*EtcdStore, error) {
+	if prefix == "" {
+		prefix = "/rbac/"
+	}
+	if !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+
+	s := &EtcdStore{cli: cli, prefix: prefix}
+	if _, err := cli.Get(ctx, prefix, clientv3.WithPrefix(), clientv3.WithCountOnly()); err != nil {
+		return nil, fmt.Errorf("etcd_store: %w", err)
+	}
+	return s, nil
+}
+
+// SetIDGenerator changes how the store generates IDs for new entities.
+func (s *EtcdStore) SetIDGenerator(g IDGenerator) {
+	s.ids = g
+}
+
+// Health implements HealthChecker by counting the keys under the prefix,
+// as NewEtcdStore does.
+func (s *EtcdStore) Health(ctx context.Context) error {
+	if _, err := s.cli.Get(ctx, s.prefix, clientv3.WithPrefix(), clientv3.WithCountOnly()); err != nil {
+		return fmt.Errorf("etcd_store: %w", err)
+	}
+	return nil
+}
+
+// NewEtcdStoreManager wraps the store in a Manager and seeds the default role.
+func NewEtcdStoreManager(ctx context.Context, cli *clientv3.Client, prefix string) (*Manager, error) {
+	s, err := NewEtcdStore(ctx, cli, prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	def, _ := s.GetRoleByName(ctx, "default")
+	if def == nil {
+		def = &Role{Name: "default", Description: "Default role"}
+		if createErr := s.CreateRole(ctx, def); createErr != nil {
+			return nil, fmt.Errorf("failed to create default role: %w", createErr)
+		}
+	}
+
+	return &Manager{
+		Perms:           s,
+		Roles:           s,
+		Users:           s,
+		RP:              s,
+		UR:              s,
+		UG:              s,
+		GR:              s,
+		DefaultRoleName: "default",
+	}, nil
+}
+
+// key joins the escaped parts below the store prefix.
+func (s *EtcdStore) key(parts ...string) string {
+	escaped := make([]string, len(parts))
+	for i, p := range parts {
+		escaped[i] = url.PathEscape(p)
+	}
+	return s.prefix + strings.Join(escaped, "/")
+}
+
+func (s *EtcdStore) getJSON(ctx context.Context, key string, v interface{}) (bool, error) {
+	resp, err := s.cli.Get(ctx, key)
+	if err != nil {
+		return false, err
+	}
+	if len(resp.Kvs) == 0 {
+		return false, nil
+	}
+	return true, json.Unmarshal(resp.Kvs[0].Value, v)
+}
+
+func (s *EtcdStore) getString(ctx context.Context, key string) (string, error) {
+	resp, err := s.cli.Get(ctx, key)
+	if err != nil {
+		return "", err
+	}
+	if len(resp.Kvs) == 0 {
+		return "", nil
+	}
+	return string(resp.Kvs[0].Value), nil
+}
+
+// listEdges returns the last key segment of every key below parent.
+func (s *EtcdStore) listEdges(ctx context.Context, parts ...string) ([]string, error) {
+	prefix := s.key(parts...) + "/"
+	resp, err := s.cli.Get(ctx, prefix, clientv3.WithPrefix(), clientv3.WithKeysOnly())
+	if err != nil {
+		return nil, err
+	}
+
+	var out []string
+	for _, kv := range resp.Kvs {
+		id, err := url.PathUnescape(strings.TrimPrefix(string(kv.Key), prefix))
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, id)
+	}
+	return out, nil
+}
+
+// listEdgeSources returns the first segment of every kind/from/to key
+// whose last segment is to. Join records are keyed by their first side, so
+// this scans every key of the kind.
+func (s *EtcdStore) listEdgeSources(ctx context.Context, kind, to string) ([]string, error) {
+	prefix := s.key(kind) + "/"
+	resp, err := s.cli.Get(ctx, prefix, clientv3.WithPrefix(), clientv3.WithKeysOnly())
+	if err != nil {
+		return nil, err
+	}
+
+	var out []string
+	for _, kv := range resp.Kvs {
+		from, last, ok := strings.Cut(strings.TrimPrefix(string(kv.Key), prefix), "/")
+		if !ok {
+			continue
+		}
+		if last, err = url.PathUnescape(last); err != nil {
+			return nil, err
+		}
+		if last != to {
+			continue
+		}
+		if from, err = url.PathUnescape(from); err != nil {
+			return nil, err
+		}
+		out = append(out, from)
+	}
+	return out, nil
+}
+
+func unixString() string {
+	return strconv.FormatInt(time.Now().Unix(), 10)
+}
+
+//
+// ---------- UserRepo ----------
+//
+
+func (s *EtcdStore) GetUserByID(ctx context.Context, id string) (*User, error) {
+	u := &User{}
+	found, err := s.getJSON(ctx, s.key(etcdUsers, id), u)
+	if err != nil || !found {
+		return nil, err
+	}
+	return u, nil
+}
+
+func (s *EtcdStore) GetUserByMeta(ctx context.Context, meta map[string]interface{}) (*User, error) {
+	allowed := map[string]bool{"id": true, "username": true, "email": true}
+	want := make(map[string]string, len(meta))
+	for k, v := range meta {
+		if !allowed[k] {
+			return nil, fmt.Errorf("GetUserByMeta: unsupported field %q", k)
+		}
+		want[k] = fmt.Sprint(v)
+	}
+	if len(want) == 0 {
+		return nil, errors.New("GetUserByMeta: no filter provided")
+	}
+
+	id, ok := want["id"]
+	var err error
+	switch {
+	case ok:
+	case want["username"] != "":
+		id, err = s.getString(ctx, s.key(etcdUsersByUsername, want["username"]))
+	default:
+		id, err = s.getString(ctx, s.key(etcdUsersByEmail, want["email"]))
+	}
+	if err != nil || id == "" {
+		return nil, err
+	}
+
+	u, err := s.GetUserByID(ctx, id)
+	if err != nil || u == nil {
+		return nil, err
+	}
+	if v, ok := want["username"]; ok && u.Username != v {
+		return nil, nil
+	}
+	if v, ok := want["email"]; ok && u.Email != v {
+		return nil, nil
+	}
+	return u, nil
+}
+
+func (s *EtcdStore) ListAllUsers(ctx context.Context) ([]*User, error) {
+	resp, err := s.cli.Get(ctx, s.key(etcdUsers)+"/", clientv3.WithPrefix())
+	if err != nil {
+		return nil, err
+	}
+
+	var out []*User
+	for _, kv := range resp.Kvs {
+		u := &User{}
+		if err := json.Unmarshal(kv.Value, u); err != nil {
+			return nil, fmt.Errorf("failed to decode user: %w", err)
+		}
+		out = append(out, u)
+	}
+	return out, nil
+}
+
+func (s *EtcdStore) CreateUser(ctx context.Context, u *User) error {
+	if u.ID == "" {
+		u.ID = generateID(s.ids, KindUser)
+	}
+	u.CreatedAt = time.Now().Unix()
+
+	data, err := json.Marshal(u)
+	if err != nil {
+		return err
+	}
+
+	conds := []clientv3.Cmp{clientv3.Compare(clientv3.CreateRevision(s.key(etcdUsers, u.ID)), "=", 0)}
+	ops := []clientv3.Op{clientv3.OpPut(s.key(etcdUsers, u.ID), string(data))}
+	if u.Username != "" {
+		k := s.key(etcdUsersByUsername, u.Username)
+		conds = append(conds, clientv3.Compare(clientv3.CreateRevision(k), "=", 0))
+		ops = append(ops, clientv3.OpPut(k, u.ID))
+	}
+	if u.Email != "" {
+		k := s.key(etcdUsersByEmail, u.Email)
+		conds = append(conds, clientv3.Compare(clientv3.CreateRevision(k), "=", 0))
+		ops = append(ops, clientv3.OpPut(k, u.ID))
+	}
+
+	resp, err := s.cli.Txn(ctx).If(conds...).Then(ops...).Commit()
+	if err != nil {
+		return err
+	}
+	if !resp.Succeeded {
+		return fmt.Errorf("etcd_store: user %q already exists", u.Username)
+	}
+	return nil
+}
+
+func (s *EtcdStore) DeleteUser(ctx context.Context, id string) error {
+	u, err := s.GetUserByID(ctx, id)
+	if err != nil || u == nil {
+		return err
+	}
+
+	ops := []clientv3.Op{clientv3.OpDelete(s.key(etcdUsers, id))}
+	if u.Username != "" {
+		ops = append(ops, clientv3.OpDelete(s.key(etcdUsersByUsername, u.Username)))
+	}
+	if u.Email != "" {
+		ops = append(ops, clientv3.OpDelete(s.key(etcdUsersByEmail, u.Email)))
+	}
+	_, err = s.cli.Txn(ctx).Then(ops...).Commit()
+	return err
+}
+
+func (s *EtcdStore) GetGroupsByUserID(ctx context.Context, userID string) ([]*UserGroup, error) {
+	return s.listUserGroups(ctx, etcdUserGroups, userID)
+}
+
+//
+// ---------- PermissionRepo ----------
+//
+
+func (s *EtcdStore) GetPermissionByID(ctx context.Context, id string) (*Permission, error) {
+	p := &Permission{}
+	found, err := s.getJSON(ctx, s.key(etcdPermissions, id), p)
+	if err != nil || !found {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (s *EtcdStore) ListAllPermissions(ctx context.Context) ([]*Permission, error) {
+	resp, err := s.cli.Get(ctx, s.key(etcdPermissions)+"/", clientv3.WithPrefix())
+	if err != nil {
+		return nil, err
+	}
+
+	var out []*Permission
+	for _, kv := range resp.Kvs {
+		p := &Permission{}
+		if err := json.Unmarshal(kv.Value, p); err != nil {
+			return nil, fmt.Errorf("failed to decode permission: %w", err)
+		}
+		out = append(out, p)
+	}
+	return out, nil
+}
+
+func (s *EtcdStore) GetPermissionByResource(ctx context.Context, resource string, action Action) (*Permission, error) {
+	id, err := s.getString(ctx, s.key(etcdPermissionsByResource, string(action), resource))
+	if err != nil || id == "" {
+		return nil, err
+	}
+	return s.GetPermissionByID(ctx, id)
+}
+
+func (s *EtcdStore) CreatePermission(ctx context.Context, p *Permission) error {
+	existing, err := s.GetPermissionByResource(ctx, p.Resource, p.Action)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		*p = *existing
+		return nil
+	}
+
+	if p.ID == "" {
+		p.ID = generateID(s.ids, KindPermission)
+	}
+	p.CreatedAt = time.Now().Unix()
+
+	data, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+
+	idx := s.key(etcdPermissionsByResource, string(p.Action), p.Resource)
+	resp, err := s.cli.Txn(ctx).
+		If(clientv3.Compare(clientv3.CreateRevision(idx), "=", 0)).
+		Then(clientv3.OpPut(s.key(etcdPermissions, p.ID), string(data)), clientv3.OpPut(idx, p.ID)).
+		Commit()
+	if err != nil {
+		return err
+	}
+	if !resp.Succeeded {
+		// Lost a race with a concurrent create; hand back the winner.
+		existing, err = s.GetPermissionByResource(ctx, p.Resource, p.Action)
+		if err != nil {
+			return err
+		}
+		if existing != nil {
+			*p = *existing
+		}
+	}
+	return nil
+}
+
+func (s *EtcdStore) DeletePermission(ctx context.Context, id string) error {
+	p, err := s.GetPermissionByID(ctx, id)
+	if err != nil || p == nil {
+		return err
+	}
+
+	_, err = s.cli.Txn(ctx).Then(
+		clientv3.OpDelete(s.key(etcdPermissions, id)),
+		clientv3.OpDelete(s.key(etcdPermissionsByResource, string(p.Action), p.Resource)),
+	).Commit()
+	return err
+}
+
+//
+// ---------- RoleRepo ----------
+//
+
+func (s *EtcdStore) CreateRole(ctx context.Context, r *Role) error {
+	if r.ID == "" {
+		r.ID = generateID(s.ids, KindRole)
+	}
+	r.CreatedAt = time.Now().Unix()
+
+	data, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+
+	idx := s.key(etcdRolesByName, r.Name)
+	resp, err := s.cli.Txn(ctx).
+		If(clientv3.Compare(clientv3.CreateRevision(idx), "=", 0)).
+		Then(clientv3.OpPut(s.key(etcdRoles, r.ID), string(data)), clientv3.OpPut(idx, r.ID)).
+		Commit()
+	if err != nil {
+		return err
+	}
+	if !resp.Succeeded {
+		return fmt.Errorf("etcd_store: role %q already exists", r.Name)
+	}
+	return nil
+}
+
+func (s *EtcdStore) GetRoleByName(ctx context.Context, name string) (*Role, error) {
+	id, err := s.getString(ctx, s.key(etcdRolesByName, name))
+	if err != nil || id == "" {
+		return nil, err
+	}
+	return s.GetRoleByID(ctx, id)
+}
+
+func (s *EtcdStore) GetRoleByID(ctx context.Context, id string) (*Role, error) {
+	r := &Role{}
+	found, err := s.getJSON(ctx, s.key(etcdRoles, id), r)
+	if err != nil || !found {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (s *EtcdStore) DeleteRole(ctx context.Context, id string) error {
+	r, err := s.GetRoleByID(ctx, id)
+	if err != nil || r == nil {
+		return err
+	}
+
+	_, err = s.cli.Txn(ctx).Then(
+		clientv3.OpDelete(s.key(etcdRoles, id)),
+		clientv3.OpDelete(s.key(etcdRolesByName, r.Name)),
+	).Commit()
+	return err
+}
+
+func (s *EtcdStore) ListAllRoles(ctx context.Context) ([]*Role, error) {
+	resp, err := s.cli.Get(ctx, s.key(etcdRoles)+"/", clientv3.WithPrefix())
+	if err != nil {
+		return nil, err
+	}
+
+	var out []*Role
+	for _, kv := range resp.Kvs {
+		r := &Role{}
+		if err := json.Unmarshal(kv.Value, r); err != nil {
+			return nil, fmt.Errorf("failed to decode role: %w", err)
+		}
+		out = append(out, r)
+	}
+	return out, nil
+}
+
+//
+// ---------- RolePermissionRepo ----------
+//
+
+func (s *EtcdStore) AddRP(ctx context.Context, roleID, permID string) error {
+	_, err := s.cli.Put(ctx, s.key(etcdRolePermissions, roleID, permID), unixString())
+	return err
+}
+
+func (s *EtcdStore) Remove(ctx context.Context, roleID, permID string) error {
+	_, err := s.cli.Delete(ctx, s.key(etcdRolePermissions, roleID, permID))
+	return err
+}
+
+func (s *EtcdStore) ListPermissions(ctx context.Context, roleID string) ([]string, error) {
+	return s.listEdges(ctx, etcdRolePermissions, roleID)
+}
+
+//
+// ---------- UserRoleRepo ----------
+//
+
+func (s *EtcdStore) AddUR(ctx context.Context, userID, roleID string) error {
+	_, err := s.cli.Put(ctx, s.key(etcdUserRoles, userID, roleID), unixString())
+	return err
+}
+
+func (s *EtcdStore) RemoveUR(ctx context.Context, userID, roleID string) error {
+	_, err := s.cli.Delete(ctx, s.key(etcdUserRoles, userID, roleID))
+	return err
+}
+
+func (s *EtcdStore) ListRoles(ctx context.Context, userID string) ([]string, error) {
+	return s.listEdges(ctx, etcdUserRoles, userID)
+}
+
+func (s *EtcdStore) ListUsersForRole(ctx context.Context, roleID string) ([]string, error) {
+	return s.listEdgeSources(ctx, etcdUserRoles, roleID)
+}
+
+//
+// ---------- UserGroupRepo ----------
+//
+
+func (s *EtcdStore) AddUserToGroup(ctx context.Context, ug *UserGroup) error {
+	if ug.UserID == "" {
+		return errors.New("user id is empty")
+	}
+
+	if ug.ID == "" {
+		ug.ID = generateID(s.ids, KindUserGroup)
+	}
+	ug.CreatedAt = time.Now().Unix()
+
+	data, err := json.Marshal(ug)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.cli.Txn(ctx).Then(
+		clientv3.OpPut(s.key(etcdUserGroups, ug.UserID, ug.GroupName), string(data)),
+		clientv3.OpPut(s.key(etcdGroupUsers, ug.GroupName, ug.UserID), string(data)),
+	).Commit()
+	return err
+}
+
+func (s *EtcdStore) RemoveUserFromGroup(ctx context.Context, groupName string, ug *UserGroup) error {
+	if ug.UserID == "" {
+		return errors.New("user id is empty")
+	}
+
+	_, err := s.cli.Txn(ctx).Then(
+		clientv3.OpDelete(s.key(etcdUserGroups, ug.UserID, groupName)),
+		clientv3.OpDelete(s.key(etcdGroupUsers, groupName, ug.UserID)),
+	).Commit()
+	return err
+}
+
+func (s *EtcdStore) GetUsersByGroupID(ctx context.Context, groupName string) ([]*UserGroup, error) {
+	return s.listUserGroups(ctx, etcdGroupUsers, groupName)
+}
+
+func (s *EtcdStore) listUserGroups(ctx context.Context, collection, parent string) ([]*UserGroup, error) {
+	resp, err := s.cli.Get(ctx, s.key(collection, parent)+"/", clientv3.WithPrefix())
+	if err != nil {
+		return nil, err
+	}
+
+	var out []*UserGroup
+	for _, kv := range resp.Kvs {
+		ug := &UserGroup{}
+		if err := json.Unmarshal(kv.Value, ug); err != nil {
+			return nil, err
+		}
+		out = append(out, ug)
+	}
+	return out, nil
+}
+
+//
+// ---------- GroupRoleRepo ----------
+//
+
+func (s *EtcdStore) AddRoleToGroup(ctx context.Context, groupID, roleID string) error {
+	_, err := s.cli.Put(ctx, s.key(etcdGroupRoles, groupID, roleID), unixString())
+	return err
+}
+
+func (s *EtcdStore) RemoveRoleFromGroup(ctx context.Context, groupID, roleID string) error {
+	_, err := s.cli.Delete(ctx, s.key(etcdGroupRoles, groupID, roleID))
+	return err
+}
+
+func (s *EtcdStore) ListRolesForGroup(ctx context.Context, groupID string) ([]string, error) {
+	return s.listEdges(ctx, etcdGroupRoles, groupID)
+}
+
+func (s *EtcdStore) ListGroupsForRole(ctx context.Context, roleID string) ([]string, error) {
+	return s.listEdgeSources(ctx, etcdGroupRoles, roleID)
+}
+
+//
+// ---------- Watch ----------
+//
+
+// Watch streams changes to entities and join records below the store prefix.
+// Index keys are not reported.
+func (s *EtcdStore) Watch(ctx context.Context) (<-chan ChangeEvent, error) {
+	wc := s.cli.Watch(ctx, s.prefix, clientv3.WithPrefix())
+	out := make(chan ChangeEvent)
+
+	go func() {
+		defer close(out)
+		for resp := range wc {
+			if resp.Err() != nil {
+				return
+			}
+			for _, ev := range resp.Events {
+				ce, ok := s.changeEvent(ev)
+				if !ok {
+					continue
+				}
+				select {
+				case out <- ce:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	return out, nil
+}
+
+// PolicyVersion reports the cluster revision. The revision advances on any
+// write to the cluster, not just below the store prefix, so it can change
+// while the policy has not; it never stays the same when the policy changed.
+func (s *EtcdStore) PolicyVersion(ctx context.Context) (string, error) {
+	resp, err := s.cli.Get(ctx, s.prefix, clientv3.WithPrefix(), clientv3.WithCountOnly())
+	if err != nil {
+		return "", err
+	}
+	return strconv.FormatInt(resp.Header.Revision, 10), nil
+}
+
+// changeEvent maps a raw etcd event onto a ChangeEvent, reporting false for
+// keys that are not entities or join records.
+func (s *EtcdStore) changeEvent(ev *clientv3.Event) (ChangeEvent, bool) {
+	parts := strings.Split(strings.TrimPrefix(string(ev.Kv.Key), s.prefix), "/")
+	for i, p := range parts {
+		unescaped, err := url.PathUnescape(p)
+		if err != nil {
+			return ChangeEvent{}, false
+		}
+		parts[i] = unescaped
+	}
+
+	ce := ChangeEvent{Op: ChangeUpdate}
+	switch {
+	case ev.Type == clientv3.EventTypeDelete:
+		ce.Op = ChangeDelete
+	case ev.IsCreate():
+		ce.Op = ChangeCreate
+	}
+
+	switch {
+	case len(parts) == 2 && parts[0] == etcdPermissions:
+		ce.Kind, ce.ID = KindPermission, parts[1]
+	case len(parts) == 2 && parts[0] == etcdRoles:
+		ce.Kind, ce.ID = KindRole, parts[1]
+	case len(parts) == 2 && parts[0] == etcdUsers:
+		ce.Kind, ce.ID = KindUser, parts[1]
+	case len(parts) == 3 && parts[0] == etcdRolePermissions:
+		ce.Kind, ce.RoleID, ce.PermissionID = KindRolePermission, parts[1], parts[2]
+	case len(parts) == 3 && parts[0] == etcdUserRoles:
+		ce.Kind, ce.UserID, ce.RoleID = KindUserRole, parts[1], parts[2]
+	case len(parts) == 3 && parts[0] == etcdUserGroups:
+		ce.Kind, ce.UserID, ce.GroupName = KindUserGroup, parts[1], parts[2]
+	case len(parts) == 3 && parts[0] == etcdGroupRoles:
+		ce.Kind, ce.GroupName, ce.RoleID = KindGroupRole, parts[1], parts[2]
+	default:
+		return ChangeEvent{}, false
+	}
+	return ce, true
+}
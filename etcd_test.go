@@ -0,0 +1,66 @@
+package rbac
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/testcontainers/testcontainers-go/modules/etcd"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+func newEtcdStore(t *testing.T) *EtcdStore {
+	t.Helper()
+	ctx := context.Background()
+
+	ctr, err := etcd.Run(ctx, "gcr.io/etcd-development/etcd:v3.5.14")
+	if err != nil {
+		t.Fatalf("start etcd container: %v", err)
+	}
+	t.Cleanup(func() { _ = ctr.Terminate(ctx) })
+
+	endpoint, err := ctr.ClientEndpoint(ctx)
+	if err != nil {
+		t.Fatalf("etcd endpoint: %v", err)
+	}
+
+	cli, err := clientv3.New(clientv3.Config{Endpoints: []string{endpoint}, DialTimeout: 5 * time.Second})
+	if err != nil {
+		t.Fatalf("clientv3.New: %v", err)
+	}
+	t.Cleanup(func() { _ = cli.Close() })
+
+	store, err := NewEtcdStore(ctx, cli, "/rbac-test/")
+	if err != nil {
+		t.Fatalf("NewEtcdStore: %v", err)
+	}
+	return store
+}
+
+func TestEtcdStore(t *testing.T) {
+	s := newEtcdStore(t)
+	runSuite(t, s)
+
+	t.Run("Watch", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		events, err := s.Watch(ctx)
+		if err != nil {
+			t.Fatalf("Watch: %v", err)
+		}
+
+		if err := s.AddUR(ctx, "watch-user", "watch-role"); err != nil {
+			t.Fatalf("AddUR: %v", err)
+		}
+
+		select {
+		case ev := <-events:
+			if ev.Kind != KindUserRole || ev.Op != ChangeCreate || ev.UserID != "watch-user" || ev.RoleID != "watch-role" {
+				t.Errorf("unexpected event: %+v", ev)
+			}
+		case <-ctx.Done():
+			t.Fatal("timed out waiting for change event")
+		}
+	})
+}
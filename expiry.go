@@ -0,0 +1,165 @@
+package rbac
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sort"
+	"time"
+)
+
+// ExpiringAssignment is a time-limited assignment reported by
+// ListExpiringAssignments: a scheduled role (KindUserRole, with RoleID) or a
+// group membership (KindUserGroup, with GroupName).
+type ExpiringAssignment struct {
+	Kind      string    `json:"kind"`
+	UserID    string    `json:"user_id"`
+	RoleID    string    `json:"role_id,omitempty"`
+	GroupName string    `json:"group_name,omitempty"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// ExpiringRoleLister is optionally implemented by a ScheduledUserRoleRepo
+// that can find role assignments by their end.
+type ExpiringRoleLister interface {
+	// ListExpiringRoleAssignments returns every user's assignments whose
+	// ExpiresAt is after after and at or before before (unix seconds).
+	ListExpiringRoleAssignments(ctx context.Context, after, before int64) ([]*RoleAssignment, error)
+}
+
+// ExpiringMembershipLister is optionally implemented by a UserGroupRepo that
+// can find memberships by UserGroup.ExpiresAt.
+type ExpiringMembershipLister interface {
+	// ListExpiringMemberships returns every membership whose ExpiresAt is
+	// after after and at or before before (unix seconds).
+	ListExpiringMemberships(ctx context.Context, after, before int64) ([]*UserGroup, error)
+}
+
+var errExpiryUnsupported = errors.New("rbac: repos cannot list assignments by expiry")
+
+// ListExpiringAssignments returns the assignments that are still in effect
+// and lapse within the given duration, soonest first, so they can be renewed
+// or left to expire knowingly. It covers temporary role grants made with
+// ScheduleRoleForUser and memberships with a UserGroup.ExpiresAt. Each repo
+// that cannot list by expiry is skipped; if neither can, it fails.
+func (m *Manager) ListExpiringAssignments(ctx context.Context, within time.Duration) ([]*ExpiringAssignment, error) {
+	start := time.Now()
+	ctx, span := m.startSpan(ctx, "ListExpiringAssignments")
+	defer span.End()
+	out, err := m.listExpiringAssignments(ctx, start, within)
+	m.record(ctx, start, "ListExpiringAssignments", err)
+	return out, err
+}
+
+func (m *Manager) listExpiringAssignments(ctx context.Context, now time.Time, within time.Duration) ([]*ExpiringAssignment, error) {
+	after, before := now.Unix(), now.Add(within).Unix()
+	roles, rolesOK := m.UR.(ExpiringRoleLister)
+	members, membersOK := m.UG.(ExpiringMembershipLister)
+	if !rolesOK && !membersOK {
+		return nil, errExpiryUnsupported
+	}
+
+	var out []*ExpiringAssignment
+	if rolesOK {
+		list, err := roles.ListExpiringRoleAssignments(ctx, after, before)
+		if err != nil {
+			return nil, err
+		}
+		for _, a := range list {
+			out = append(out, &ExpiringAssignment{
+				Kind:      KindUserRole,
+				UserID:    a.UserID,
+				RoleID:    a.RoleID,
+				ExpiresAt: time.Unix(a.ExpiresAt, 0),
+			})
+		}
+	}
+	if membersOK {
+		list, err := members.ListExpiringMemberships(ctx, after, before)
+		if err != nil {
+			return nil, err
+		}
+		for _, ug := range list {
+			out = append(out, &ExpiringAssignment{
+				Kind:      KindUserGroup,
+				UserID:    ug.UserID,
+				GroupName: ug.GroupName,
+				ExpiresAt: time.Unix(ug.ExpiresAt, 0),
+			})
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].ExpiresAt.Before(out[j].ExpiresAt) })
+	return out, nil
+}
+
+// NotifyExpiringEvery checks every interval for assignments lapsing within
+// the given duration and passes the ones not reported before to notify. An
+// assignment is reported again after it is renewed. When notify fails, its
+// assignments are retried on the next check. It blocks until ctx is
+// cancelled.
+func (m *Manager) NotifyExpiringEvery(ctx context.Context, interval, within time.Duration, notify func(ctx context.Context, expiring []*ExpiringAssignment) error) {
+	reported := map[expiryKey]bool{}
+	check := func() {
+		list, err := m.ListExpiringAssignments(ctx, within)
+		if err != nil {
+			log.Printf("rbac: list expiring assignments: %v", err)
+			return
+		}
+		seen := make(map[expiryKey]bool, len(list))
+		var fresh []*ExpiringAssignment
+		for _, a := range list {
+			seen[a.key()] = true
+			if !reported[a.key()] {
+				fresh = append(fresh, a)
+			}
+		}
+		// Forget assignments that lapsed, were removed or were renewed.
+		for a := range reported {
+			if !seen[a] {
+				delete(reported, a)
+			}
+		}
+		if len(fresh) == 0 {
+			return
+		}
+		if err := notify(ctx, fresh); err != nil {
+			log.Printf("rbac: notify expiring assignments: %v", err)
+			return
+		}
+		for _, a := range fresh {
+			reported[a.key()] = true
+		}
+	}
+
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	check()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			check()
+		}
+	}
+}
+
+type expiryKey struct {
+	kind, userID, to string
+	at               int64
+}
+
+func (a *ExpiringAssignment) key() expiryKey {
+	return expiryKey{a.Kind, a.UserID, a.RoleID + a.GroupName, a.ExpiresAt.Unix()}
+}
+
+// activeMemberships returns the memberships in effect at now.
+func activeMemberships(groups []*UserGroup, now time.Time) []*UserGroup {
+	out := groups[:0:0]
+	for _, ug := range groups {
+		if ug.ExpiresAt == 0 || now.Unix() < ug.ExpiresAt {
+			out = append(out, ug)
+		}
+	}
+	return out
+}
@@ -0,0 +1,139 @@
+package rbac
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestListExpiringAssignments(t *testing.T) {
+	ctx := context.Background()
+	stores := map[string]func() *Manager{
+		"mock": func() *Manager { return NewMockRepoManager(NewMockRepo()) },
+		"memory": func() *Manager {
+			m, err := NewMemoryStoreManager(ctx, "", 0)
+			if err != nil {
+				t.Fatalf("NewMemoryStoreManager: %v", err)
+			}
+			return m
+		},
+	}
+	for name, newManager := range stores {
+		t.Run(name, func(t *testing.T) {
+			mgr := newManager()
+			now := time.Now()
+			if err := mgr.ScheduleRoleForUser(ctx, "alice", "oncall", time.Time{}, now.Add(2*time.Hour)); err != nil {
+				t.Fatalf("ScheduleRoleForUser: %v", err)
+			}
+			if err := mgr.ScheduleRoleForUser(ctx, "alice", "auditor", time.Time{}, now.Add(30*24*time.Hour)); err != nil {
+				t.Fatalf("ScheduleRoleForUser: %v", err)
+			}
+			if err := mgr.ScheduleRoleForUser(ctx, "carol", "oncall", time.Time{}, now.Add(-time.Hour)); err != nil {
+				t.Fatalf("ScheduleRoleForUser: %v", err)
+			}
+			if err := mgr.AssignRoleToUser(ctx, "dave", "oncall"); err != nil {
+				t.Fatalf("AssignRoleToUser: %v", err)
+			}
+			if err := mgr.AddUserToGroup(ctx, &UserGroup{UserID: "bob", GroupName: "contractors", ExpiresAt: now.Add(time.Hour).Unix()}); err != nil {
+				t.Fatalf("AddUserToGroup: %v", err)
+			}
+			if err := mgr.AddUserToGroup(ctx, &UserGroup{UserID: "bob", GroupName: "staff"}); err != nil {
+				t.Fatalf("AddUserToGroup: %v", err)
+			}
+
+			got, err := mgr.ListExpiringAssignments(ctx, 24*time.Hour)
+			if err != nil {
+				t.Fatalf("ListExpiringAssignments: %v", err)
+			}
+			if len(got) != 2 {
+				t.Fatalf("expected 2 expiring assignments, got %+v", got)
+			}
+			if got[0].Kind != KindUserGroup || got[0].UserID != "bob" || got[0].GroupName != "contractors" {
+				t.Errorf("expected bob's membership first, got %+v", got[0])
+			}
+			if got[1].Kind != KindUserRole || got[1].UserID != "alice" || got[1].RoleID != "oncall" {
+				t.Errorf("expected alice's oncall grant second, got %+v", got[1])
+			}
+		})
+	}
+}
+
+func TestExpiredMembershipIgnoredByCan(t *testing.T) {
+	ctx := context.Background()
+	mgr, err := NewMemoryStoreManager(ctx, "", 0)
+	if err != nil {
+		t.Fatalf("NewMemoryStoreManager: %v", err)
+	}
+	role := &Role{Name: "editor"}
+	if err := mgr.CreateRole(ctx, role); err != nil {
+		t.Fatalf("CreateRole: %v", err)
+	}
+	perm := &Permission{Resource: "docs/*", Action: ActionUpdate}
+	if err := mgr.CreatePermission(ctx, perm); err != nil {
+		t.Fatalf("CreatePermission: %v", err)
+	}
+	if err := mgr.AssignPermissionToRole(ctx, role.ID, perm.ID); err != nil {
+		t.Fatalf("AssignPermissionToRole: %v", err)
+	}
+	if err := mgr.AssignRoleToGroup(ctx, "writers", role.ID); err != nil {
+		t.Fatalf("AssignRoleToGroup: %v", err)
+	}
+
+	ug := &UserGroup{UserID: "bob", GroupName: "writers", ExpiresAt: time.Now().Add(-time.Minute).Unix()}
+	if err := mgr.AddUserToGroup(ctx, ug); err != nil {
+		t.Fatalf("AddUserToGroup: %v", err)
+	}
+	if ok, _ := mgr.Can(ctx, "bob", "docs/readme", ActionUpdate); ok {
+		t.Error("expected a lapsed membership to grant nothing")
+	}
+
+	ug.ExpiresAt = time.Now().Add(time.Hour).Unix()
+	if err := mgr.AddUserToGroup(ctx, ug); err != nil {
+		t.Fatalf("AddUserToGroup: %v", err)
+	}
+	if ok, err := mgr.Can(ctx, "bob", "docs/readme", ActionUpdate); err != nil || !ok {
+		t.Errorf("Can after renewal = %v, %v; want true", ok, err)
+	}
+}
+
+func TestNotifyExpiringEvery(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	mgr := NewMockRepoManager(NewMockRepo())
+	if err := mgr.ScheduleRoleForUser(ctx, "alice", "oncall", time.Time{}, time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("ScheduleRoleForUser: %v", err)
+	}
+
+	batches := make(chan []*ExpiringAssignment, 10)
+	fail := true
+	go mgr.NotifyExpiringEvery(ctx, 10*time.Millisecond, 24*time.Hour, func(ctx context.Context, expiring []*ExpiringAssignment) error {
+		if fail {
+			fail = false
+			return errors.New("channel unavailable")
+		}
+		batches <- expiring
+		return nil
+	})
+
+	select {
+	case got := <-batches:
+		if len(got) != 1 || got[0].RoleID != "oncall" {
+			t.Fatalf("unexpected batch %+v", got)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected the failed notification to be retried")
+	}
+	select {
+	case got := <-batches:
+		t.Fatalf("expected the assignment to be reported once, got %+v", got)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestListExpiringAssignmentsUnsupported(t *testing.T) {
+	mgr := NewCachedStoreManager(NewMockRepo(), time.Minute)
+	if _, err := mgr.ListExpiringAssignments(context.Background(), time.Hour); !errors.Is(err, errExpiryUnsupported) {
+		t.Fatalf("expected errExpiryUnsupported, got %v", err)
+	}
+}
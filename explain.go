@@ -0,0 +1,201 @@
+package rbac
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Explanation is a Decision together with how it was reached, for
+// debugging why a user was allowed or denied.
+type Explanation struct {
+	Decision
+	// Reason says in one sentence why access was allowed or denied.
+	Reason string `json:"reason"`
+	// Roles are the roles the decision considered and how the user holds
+	// each. A role held in several ways is listed once for each.
+	Roles []RoleGrant `json:"roles"`
+	// Rules are the permissions whose resource and action matched the
+	// request, in the order they were evaluated.
+	Rules []RuleMatch `json:"rules"`
+}
+
+// How a user holds a role, as reported in RoleGrant.Via.
+const (
+	ViaDirect      = "direct"
+	ViaGroup       = "group"
+	ViaScope       = "scope"
+	ViaConstraint  = "constraint"
+	ViaDelegation  = "delegation"
+	ViaInheritance = "inheritance"
+)
+
+// RoleGrant is one way a user holds a role.
+type RoleGrant struct {
+	RoleID string `json:"role_id"`
+	Via    string `json:"via"`
+	// Group names the group the role comes from, for ViaGroup.
+	Group string `json:"group,omitempty"`
+}
+
+// Why a matching rule did not decide, as reported in RuleMatch.Skipped.
+const (
+	// SkippedOutranked marks a rule that could not beat the one already
+	// matched: its role's priority was lower, or it was an allow tied with a
+	// deny.
+	SkippedOutranked = "outranked"
+	// SkippedCondition marks a rule whose Condition did not hold.
+	SkippedCondition = "condition not met"
+)
+
+// RuleMatch is a permission whose resource and action matched the request.
+type RuleMatch struct {
+	RoleID       string `json:"role_id"`
+	PermissionID string `json:"permission_id"`
+	// Resource and Action are the permission's patterns.
+	Resource string `json:"resource"`
+	Action   Action `json:"action"`
+	Effect   Effect `json:"effect,omitempty"`
+	Priority int    `json:"priority,omitempty"`
+	// Skipped is why the rule was passed over, or empty when it applied.
+	Skipped string `json:"skipped,omitempty"`
+}
+
+// Explain decides the request as Can does and reports the path to the
+// decision: the roles the user holds and where they come from, the
+// permissions that matched, and which one decided or why none did.
+func (m *Manager) Explain(ctx context.Context, userID, resource string, action Action) (*Explanation, error) {
+	ex := &explainer{}
+	d, err := m.decide(context.WithValue(ctx, explainerKey{}, ex), "Explain", userID, resource, action, nil)
+	if err != nil {
+		return nil, err
+	}
+	out := &Explanation{Decision: *d, Roles: ex.roles, Rules: ex.rules}
+	if out.Roles == nil {
+		out.Roles = []RoleGrant{}
+	}
+	if out.Rules == nil {
+		out.Rules = []RuleMatch{}
+	}
+	out.Reason, err = m.explainReason(ctx, userID, resource, action, ex, out)
+	return out, err
+}
+
+func (m *Manager) explainReason(ctx context.Context, userID, resource string, action Action, ex *explainer, e *Explanation) (string, error) {
+	switch {
+	case ex.inactive:
+		start := time.Now()
+		u, err := m.Users.GetUserByID(ctx, userID)
+		m.record(ctx, start, "Explain", err)
+		if err != nil {
+			return "", err
+		}
+		if u != nil && !u.Status.Active() {
+			return fmt.Sprintf("denied: the user is %s", u.Status), nil
+		}
+		return "denied: the user has not verified their email", nil
+	case ex.custom:
+		verdict, name := "allowed", e.Authorizer
+		if !e.Allowed {
+			verdict = "denied"
+		}
+		if name == "" {
+			name = "an application authorizer"
+		} else {
+			name = "authorizer " + name
+		}
+		return fmt.Sprintf("%s by %s", verdict, name), nil
+	case e.SuperAdmin:
+		return fmt.Sprintf("allowed by role %s, the super-admin role, without checking permissions", e.RoleID), nil
+	case e.PermissionID != "":
+		verdict := "allowed"
+		if !e.Allowed {
+			verdict = "denied"
+		}
+		for _, r := range e.Rules {
+			if r.RoleID == e.RoleID && r.PermissionID == e.PermissionID && r.Skipped == "" {
+				return fmt.Sprintf("%s by permission %s (%s on %s) of role %s", verdict, r.PermissionID, r.Action, r.Resource, r.RoleID), nil
+			}
+		}
+		return fmt.Sprintf("%s by permission %s of role %s", verdict, e.PermissionID, e.RoleID), nil
+	case len(e.Roles) == 0:
+		return "denied: the user holds no roles", nil
+	case len(e.Rules) == 0:
+		return fmt.Sprintf("denied: no permission of the user's roles matches %s on %s", action, resource), nil
+	}
+	return "denied: no matching permission's condition held", nil
+}
+
+type explainerKey struct{}
+
+// explainer collects what Explain reports while decide runs. Its methods
+// do nothing on a nil explainer, as for every other check.
+type explainer struct {
+	roles    []RoleGrant
+	rules    []RuleMatch
+	inactive bool
+	// custom is set when an application Authorizer decided
+	custom bool
+}
+
+func explainerFrom(ctx context.Context) *explainer {
+	ex, _ := ctx.Value(explainerKey{}).(*explainer)
+	return ex
+}
+
+func (ex *explainer) grant(via, group string, roleIDs []string) {
+	if ex == nil {
+		return
+	}
+	for _, id := range roleIDs {
+		ex.roles = append(ex.roles, RoleGrant{RoleID: id, Via: via, Group: group})
+	}
+}
+
+// inherited records the roles of the expanded list not granted otherwise.
+func (ex *explainer) inherited(roleIDs []string) {
+	if ex == nil {
+		return
+	}
+	held := make(map[string]bool, len(ex.roles))
+	for _, g := range ex.roles {
+		held[g.RoleID] = true
+	}
+	for _, id := range roleIDs {
+		if !held[id] {
+			held[id] = true
+			ex.roles = append(ex.roles, RoleGrant{RoleID: id, Via: ViaInheritance})
+		}
+	}
+}
+
+func (ex *explainer) deniedInactive() {
+	if ex != nil {
+		ex.inactive = true
+	}
+}
+
+func (ex *explainer) decidedByAuthorizer() {
+	if ex != nil {
+		ex.custom = true
+	}
+}
+
+func (ex *explainer) match(roleID string, p *Permission, priority int, skipped string) {
+	if ex == nil {
+		return
+	}
+	effect := EffectAllow
+	if p.Effect == EffectDeny {
+		effect = EffectDeny
+	}
+	ex.rules = append(ex.rules, RuleMatch{
+		RoleID:       roleID,
+		PermissionID: p.ID,
+		Resource:     p.Resource,
+		Action:       p.Action,
+		Effect:       effect,
+		Priority:     priority,
+		Skipped:      skipped,
+	})
+}
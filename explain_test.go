@@ -0,0 +1,127 @@
+package rbac
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestExplain(t *testing.T) {
+	ctx := context.Background()
+	mgr, err := NewMemoryStoreManager(ctx, "", 0)
+	if err != nil {
+		t.Fatalf("NewMemoryStoreManager: %v", err)
+	}
+	viewer := &Role{Name: "viewer"}
+	editor := &Role{Name: "editor"}
+	for _, r := range []*Role{viewer, editor} {
+		if err := mgr.CreateRole(ctx, r); err != nil {
+			t.Fatalf("CreateRole: %v", err)
+		}
+	}
+	if err := mgr.AddRoleParent(ctx, editor.ID, viewer.ID); err != nil {
+		t.Fatalf("AddRoleParent: %v", err)
+	}
+	read := &Permission{Resource: "docs/*", Action: ActionRead}
+	secret := &Permission{Resource: "docs/secret", Action: ActionRead, Effect: EffectDeny}
+	for _, p := range []*Permission{read, secret} {
+		if err := mgr.CreatePermission(ctx, p); err != nil {
+			t.Fatalf("CreatePermission: %v", err)
+		}
+	}
+	if err := mgr.AssignPermissionToRole(ctx, viewer.ID, read.ID); err != nil {
+		t.Fatalf("AssignPermissionToRole: %v", err)
+	}
+	if err := mgr.AssignPermissionToRole(ctx, editor.ID, secret.ID); err != nil {
+		t.Fatalf("AssignPermissionToRole: %v", err)
+	}
+	if err := mgr.AssignRoleToGroup(ctx, "eng", editor.ID); err != nil {
+		t.Fatalf("AssignRoleToGroup: %v", err)
+	}
+	alice := &User{Username: "alice", Email: "alice@example.com"}
+	if err := mgr.CreateUser(ctx, alice); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	if err := mgr.AddUserToGroup(ctx, &UserGroup{UserID: alice.ID, GroupName: "eng"}); err != nil {
+		t.Fatalf("AddUserToGroup: %v", err)
+	}
+
+	t.Run("AllowedThroughGroupAndInheritance", func(t *testing.T) {
+		e, err := mgr.Explain(ctx, alice.ID, "docs/1", ActionRead)
+		if err != nil {
+			t.Fatalf("Explain: %v", err)
+		}
+		if !e.Allowed || e.RoleID != viewer.ID || e.PermissionID != read.ID {
+			t.Fatalf("unexpected decision %+v", e.Decision)
+		}
+		want := map[RoleGrant]bool{
+			{RoleID: editor.ID, Via: ViaGroup, Group: "eng"}: true,
+			{RoleID: viewer.ID, Via: ViaInheritance}:         true,
+		}
+		for _, g := range e.Roles {
+			delete(want, g)
+		}
+		if len(want) != 0 {
+			t.Errorf("expected grants %v among %+v", want, e.Roles)
+		}
+		if !strings.HasPrefix(e.Reason, "allowed by permission "+read.ID) {
+			t.Errorf("unexpected reason %q", e.Reason)
+		}
+	})
+
+	t.Run("DeniedByRule", func(t *testing.T) {
+		e, err := mgr.Explain(ctx, alice.ID, "docs/secret", ActionRead)
+		if err != nil {
+			t.Fatalf("Explain: %v", err)
+		}
+		if e.Allowed || e.PermissionID != secret.ID {
+			t.Fatalf("unexpected decision %+v", e.Decision)
+		}
+		if len(e.Rules) != 2 {
+			t.Fatalf("expected both matching rules, got %+v", e.Rules)
+		}
+		for _, r := range e.Rules {
+			if r.PermissionID == read.ID && r.Skipped != SkippedOutranked {
+				t.Errorf("expected the allow to be outranked by the deny, got %+v", r)
+			}
+		}
+		if !strings.HasPrefix(e.Reason, "denied by permission "+secret.ID) {
+			t.Errorf("unexpected reason %q", e.Reason)
+		}
+	})
+
+	t.Run("NoMatch", func(t *testing.T) {
+		e, err := mgr.Explain(ctx, alice.ID, "docs/1", ActionDelete)
+		if err != nil {
+			t.Fatalf("Explain: %v", err)
+		}
+		if e.Allowed || len(e.Rules) != 0 || !strings.Contains(e.Reason, "no permission") {
+			t.Errorf("unexpected explanation %+v", e)
+		}
+	})
+
+	t.Run("NoRoles", func(t *testing.T) {
+		// the memory store gives every user its default role
+		e, err := NewMockRepoManager(NewMockRepo()).Explain(ctx, "nobody", "docs/1", ActionRead)
+		if err != nil {
+			t.Fatalf("Explain: %v", err)
+		}
+		if e.Allowed || len(e.Roles) != 0 || e.Reason != "denied: the user holds no roles" {
+			t.Errorf("unexpected explanation %+v", e)
+		}
+	})
+
+	t.Run("Suspended", func(t *testing.T) {
+		if err := mgr.SuspendUser(ctx, alice.ID); err != nil {
+			t.Fatalf("SuspendUser: %v", err)
+		}
+		defer mgr.ReactivateUser(ctx, alice.ID)
+		e, err := mgr.Explain(ctx, alice.ID, "docs/1", ActionRead)
+		if err != nil {
+			t.Fatalf("Explain: %v", err)
+		}
+		if e.Allowed || e.Reason != "denied: the user is suspended" {
+			t.Errorf("unexpected explanation %+v", e)
+		}
+	})
+}
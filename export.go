@@ -0,0 +1,266 @@
+package rbac
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// ExportPager is optionally implemented by repos that can list every record
+// of a kind in pages, so an export never holds a whole store in memory.
+type ExportPager interface {
+	// ExportPage returns up to limit records of kind whose key sorts after
+	// after, in key order; after is "" for the first page. Entities are
+	// keyed by ID and edges by their two ends joined with ExportKeySep.
+	// Records are *Permission, *Role, *User, *UserGroup or, for role
+	// permissions, user roles and group roles, *ExportEdge.
+	ExportPage(ctx context.Context, kind, after string, limit int) ([]ExportItem, error)
+}
+
+// ExportItem is a record returned by ExportPager with its key.
+type ExportItem struct {
+	Key   string
+	Value any
+}
+
+// ExportEdge is an assignment in an export, in the order the repos take its
+// ends: role → permission, user → role, group → role.
+type ExportEdge struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// ExportKeySep joins the two ends of an edge's key.
+const ExportKeySep = "\x00"
+
+// ExportKinds lists the kinds of record in an export, in the order they are
+// written.
+var ExportKinds = []string{
+	KindPermission, KindRole, KindUser,
+	KindRolePermission, KindUserRole, KindUserGroup, KindGroupRole,
+}
+
+// KindExportEnd marks the last line of a complete export.
+const KindExportEnd = "end"
+
+// ExportLine is one line of an NDJSON export. Cursor resumes the export
+// after the line; the final line has Kind KindExportEnd and no data, so a
+// reader can tell a finished export from a broken connection.
+type ExportLine struct {
+	Kind   string          `json:"kind"`
+	Cursor string          `json:"cursor,omitempty"`
+	Data   json.RawMessage `json:"data,omitempty"`
+}
+
+// ExportOptions configures Manager.Export.
+type ExportOptions struct {
+	// Cursor resumes after the line that carried it; empty starts at the
+	// beginning.
+	Cursor string
+	// PageSize is how many records are read and written at a time.
+	// Defaults to 500.
+	PageSize int
+	// Rate caps how many records are written per second; zero means no cap.
+	Rate float64
+}
+
+var (
+	// ErrInvalidCursor is returned by Export for a cursor it did not issue.
+	ErrInvalidCursor = errors.New("rbac: invalid export cursor")
+
+	errExportUnsupported = errors.New("rbac: repo does not support paged export")
+)
+
+// Export writes every permission, role, user and assignment to w as NDJSON,
+// one ExportLine per record, reading and writing a page at a time. Each page
+// is handed to w in a single Write, so a writer that flushes on Write (such
+// as an HTTP response) streams page by page. The records are read live, not
+// from a snapshot, so writes made during a long export may or may not be
+// included. Every repo must implement ExportPager.
+func (m *Manager) Export(ctx context.Context, w io.Writer, opts ExportOptions) error {
+	start := time.Now()
+	ctx, span := m.startSpan(ctx, "Export")
+	defer span.End()
+	err := m.export(ctx, w, opts)
+	m.record(ctx, start, "Export", err)
+	return err
+}
+
+func (m *Manager) export(ctx context.Context, w io.Writer, opts ExportOptions) error {
+	if opts.PageSize <= 0 {
+		opts.PageSize = 500
+	}
+	var limiter *rate.Limiter
+	if opts.Rate > 0 {
+		limiter = rate.NewLimiter(rate.Limit(opts.Rate), max(1, int(opts.Rate)))
+	}
+	kind, after, err := decodeExportCursor(opts.Cursor)
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	for _, k := range ExportKinds {
+		if kind != "" && k != kind {
+			continue
+		}
+		kind = ""
+		pager, ok := m.exportRepo(k).(ExportPager)
+		if !ok {
+			return fmt.Errorf("%w: %s", errExportUnsupported, k)
+		}
+		for {
+			items, err := pager.ExportPage(ctx, k, after, opts.PageSize)
+			if err != nil {
+				return fmt.Errorf("rbac: export %s: %w", k, err)
+			}
+			buf.Reset()
+			for _, it := range items {
+				if limiter != nil {
+					if err := limiter.Wait(ctx); err != nil {
+						return err
+					}
+				}
+				data, err := json.Marshal(it.Value)
+				if err != nil {
+					return err
+				}
+				line, err := json.Marshal(ExportLine{Kind: k, Cursor: encodeExportCursor(k, it.Key), Data: data})
+				if err != nil {
+					return err
+				}
+				buf.Write(line)
+				buf.WriteByte('\n')
+			}
+			if buf.Len() > 0 {
+				if _, err := w.Write(buf.Bytes()); err != nil {
+					return err
+				}
+			}
+			if len(items) < opts.PageSize {
+				break
+			}
+			after = items[len(items)-1].Key
+		}
+		after = ""
+	}
+	_, err = io.WriteString(w, `{"kind":"`+KindExportEnd+`"}`+"\n")
+	return err
+}
+
+func (m *Manager) exportRepo(kind string) any {
+	switch kind {
+	case KindPermission:
+		return m.Perms
+	case KindRole:
+		return m.Roles
+	case KindUser:
+		return m.Users
+	}
+	return m.edgeRepo(kind)
+}
+
+func encodeExportCursor(kind, key string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(kind + "\n" + key))
+}
+
+func decodeExportCursor(cursor string) (kind, key string, err error) {
+	if cursor == "" {
+		return "", "", nil
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return "", "", ErrInvalidCursor
+	}
+	kind, key, ok := strings.Cut(string(raw), "\n")
+	if !ok {
+		return "", "", ErrInvalidCursor
+	}
+	for _, k := range ExportKinds {
+		if k == kind {
+			return kind, key, nil
+		}
+	}
+	return "", "", ErrInvalidCursor
+}
+
+// splitExportKey splits an edge key into its two ends.
+func splitExportKey(key string) (string, string) {
+	from, to, _ := strings.Cut(key, ExportKeySep)
+	return from, to
+}
+
+// pageItems sorts the items of an in-memory store by key and returns up to
+// limit of those after after.
+func pageItems(items []ExportItem, after string, limit int) []ExportItem {
+	sort.Slice(items, func(i, j int) bool { return items[i].Key < items[j].Key })
+	i := sort.Search(len(items), func(i int) bool { return items[i].Key > after })
+	items = items[i:]
+	if len(items) > limit {
+		items = items[:limit]
+	}
+	return items
+}
+
+// eachExportItem passes every record of kind that pager lists to fn, a page
+// at a time, stopping at the first error.
+func eachExportItem(ctx context.Context, pager ExportPager, kind string, fn func(it ExportItem) error) error {
+	const pageSize = 500
+	after := ""
+	for {
+		items, err := pager.ExportPage(ctx, kind, after, pageSize)
+		if err != nil {
+			return err
+		}
+		for _, it := range items {
+			if err := fn(it); err != nil {
+				return err
+			}
+		}
+		if len(items) < pageSize {
+			return nil
+		}
+		after = items[len(items)-1].Key
+	}
+}
+
+// edgeItems lists the edges of an in-memory join map as ExportItems.
+func edgeItems(m map[string]map[string]struct{}, after string) []ExportItem {
+	var out []ExportItem
+	for from, tos := range m {
+		for to := range tos {
+			if key := from + ExportKeySep + to; key > after {
+				out = append(out, ExportItem{Key: key, Value: &ExportEdge{From: from, To: to}})
+			}
+		}
+	}
+	return out
+}
+
+// exportEntities pairs records read from a database with their keys.
+func exportEntities[T any](docs []*T, key func(*T) string) []ExportItem {
+	out := make([]ExportItem, len(docs))
+	for i, d := range docs {
+		out[i] = ExportItem{Key: key(d), Value: d}
+	}
+	return out
+}
+
+// exportEdges converts join rows read from a database into ExportEdges.
+func exportEdges[T any](docs []*T, ends func(*T) (string, string)) []ExportItem {
+	out := make([]ExportItem, len(docs))
+	for i, d := range docs {
+		from, to := ends(d)
+		out[i] = ExportItem{Key: from + ExportKeySep + to, Value: &ExportEdge{From: from, To: to}}
+	}
+	return out
+}
@@ -0,0 +1,359 @@
+// file: rbac/failover_store.go
+package rbac
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var _ Store = (*FailoverStore)(nil)
+var _ HealthChecker = (*FailoverStore)(nil)
+
+// FailoverWriteMode controls where a FailoverStore sends writes.
+type FailoverWriteMode int
+
+const (
+	// FailoverWritePrimary sends writes to the primary only, so they fail
+	// while the primary is down. Use it when the secondary is a replica the
+	// database keeps in sync on its own.
+	FailoverWritePrimary FailoverWriteMode = iota
+	// FailoverWriteActive sends writes to whichever store is serving reads.
+	FailoverWriteActive
+	// FailoverWriteBoth sends writes to the primary and, once it accepts
+	// them, to the secondary, and fails if either does. Use it for two
+	// independent stores that nothing else keeps in sync.
+	FailoverWriteBoth
+	// FailoverWriteThrough sends writes to the primary and, once it accepts
+	// them, mirrors them to the secondary. Only the primary's error is
+	// returned; use it to keep a local fallback such as a MemoryStore
+	// current without making its failures visible to callers.
+	FailoverWriteThrough
+)
+
+// FailoverConfig configures a FailoverStore. Zero values select the defaults.
+type FailoverConfig struct {
+	// ProbeInterval is how often the primary is probed. Defaults to 5s.
+	ProbeInterval time.Duration
+	// ProbeTimeout bounds a single probe. Defaults to 2s.
+	ProbeTimeout time.Duration
+	// FailureThreshold is the number of consecutive failed probes after which
+	// reads move to the secondary. Defaults to 3. A single successful probe
+	// moves them back.
+	FailureThreshold int
+	// Probe checks a store's health. Defaults to a by-ID role lookup.
+	Probe func(ctx context.Context, s Store) error
+	// WriteMode selects where writes go. Defaults to FailoverWritePrimary.
+	WriteMode FailoverWriteMode
+	// ReadTimeout bounds each read from the primary; a read that takes
+	// longer is served by the secondary instead. Zero disables it.
+	ReadTimeout time.Duration
+	// OnMirrorError, if set, receives the secondary's errors in
+	// FailoverWriteThrough mode.
+	OnMirrorError func(err error)
+}
+
+// FailoverStore serves reads from a primary store and fails over to a
+// secondary, typically in another region, when the primary stops answering.
+// The primary is probed in the background; while it is considered healthy a
+// failed read is still retried on the secondary.
+type FailoverStore struct {
+	primary   Store
+	secondary Store
+	cfg       FailoverConfig
+
+	healthy  atomic.Bool
+	mu       sync.Mutex
+	failures int
+}
+
+// NewFailoverStore wraps primary and secondary and starts probing the primary
+// until ctx is cancelled.
+func NewFailoverStore(ctx context.Context, primary, secondary Store, cfg FailoverConfig) *FailoverStore {
+	if cfg.ProbeInterval <= 0 {
+		cfg.ProbeInterval = 5 * time.Second
+	}
+	if cfg.ProbeTimeout <= 0 {
+		cfg.ProbeTimeout = 2 * time.Second
+	}
+	if cfg.FailureThreshold <= 0 {
+		cfg.FailureThreshold = 3
+	}
+	if cfg.Probe == nil {
+		cfg.Probe = func(ctx context.Context, s Store) error {
+			_, err := s.GetRoleByID(ctx, "")
+			return err
+		}
+	}
+
+	f := &FailoverStore{primary: primary, secondary: secondary, cfg: cfg}
+	f.healthy.Store(true)
+	go f.probeLoop(ctx)
+	return f
+}
+
+// NewFailoverStoreManager wraps the pair in a Manager. Seeding the default
+// role is left to the underlying stores' own constructors.
+func NewFailoverStoreManager(ctx context.Context, primary, secondary Store, cfg FailoverConfig) *Manager {
+	f := NewFailoverStore(ctx, primary, secondary, cfg)
+	return &Manager{
+		Perms:           f,
+		Roles:           f,
+		Users:           f,
+		RP:              f,
+		UR:              f,
+		UG:              f,
+		GR:              f,
+		DefaultRoleName: "default",
+	}
+}
+
+// Healthy reports whether the primary is currently serving reads.
+func (f *FailoverStore) Healthy() bool {
+	return f.healthy.Load()
+}
+
+// CheckHealth probes the primary once, updates the health state and
+// returns the new state.
+func (f *FailoverStore) CheckHealth(ctx context.Context) bool {
+	ctx, cancel := context.WithTimeout(ctx, f.cfg.ProbeTimeout)
+	defer cancel()
+	err := f.cfg.Probe(ctx, f.primary)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err == nil {
+		f.failures = 0
+		f.healthy.Store(true)
+	} else if f.failures++; f.failures >= f.cfg.FailureThreshold {
+		f.healthy.Store(false)
+	}
+	return f.healthy.Load()
+}
+
+// Health implements HealthChecker. Reads are served while either store
+// answers, so it fails only when both do. Unlike CheckHealth it does not
+// change which store serves reads.
+func (f *FailoverStore) Health(ctx context.Context) error {
+	perr := checkHealth(ctx, f.primary)
+	if perr == nil {
+		return nil
+	}
+	if serr := checkHealth(ctx, f.secondary); serr != nil {
+		return errors.Join(fmt.Errorf("primary: %w", perr), fmt.Errorf("secondary: %w", serr))
+	}
+	return nil
+}
+
+func (f *FailoverStore) probeLoop(ctx context.Context) {
+	t := time.NewTicker(f.cfg.ProbeInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			f.CheckHealth(ctx)
+		}
+	}
+}
+
+// failoverRead runs fn against the primary when it is healthy and against the
+// secondary otherwise, or when the primary call fails or exceeds ReadTimeout
+// for a reason other than the caller's context ending.
+func failoverRead[T any](ctx context.Context, f *FailoverStore, fn func(context.Context, Store) (T, error)) (T, error) {
+	if f.Healthy() {
+		pctx := ctx
+		if f.cfg.ReadTimeout > 0 {
+			var cancel context.CancelFunc
+			pctx, cancel = context.WithTimeout(ctx, f.cfg.ReadTimeout)
+			defer cancel()
+		}
+		v, err := fn(pctx, f.primary)
+		if err == nil || ctx.Err() != nil {
+			return v, err
+		}
+	}
+	return fn(ctx, f.secondary)
+}
+
+// write applies fn according to the configured FailoverWriteMode.
+func (f *FailoverStore) write(fn func(Store) error) error {
+	switch f.cfg.WriteMode {
+	case FailoverWriteActive:
+		if f.Healthy() {
+			return fn(f.primary)
+		}
+		return fn(f.secondary)
+	case FailoverWriteBoth:
+		// a write the primary refused is not sent on, so the secondary
+		// does not get ahead of it
+		if err := fn(f.primary); err != nil {
+			return err
+		}
+		return fn(f.secondary)
+	case FailoverWriteThrough:
+		if err := fn(f.primary); err != nil {
+			return err
+		}
+		if err := fn(f.secondary); err != nil && f.cfg.OnMirrorError != nil {
+			f.cfg.OnMirrorError(err)
+		}
+		return nil
+	default:
+		return fn(f.primary)
+	}
+}
+
+//
+// ---------- PermissionRepo ----------
+//
+
+func (f *FailoverStore) CreatePermission(ctx context.Context, p *Permission) error {
+	return f.write(func(s Store) error { return s.CreatePermission(ctx, p) })
+}
+
+func (f *FailoverStore) DeletePermission(ctx context.Context, id string) error {
+	return f.write(func(s Store) error { return s.DeletePermission(ctx, id) })
+}
+
+func (f *FailoverStore) GetPermissionByID(ctx context.Context, id string) (*Permission, error) {
+	return failoverRead(ctx, f, func(ctx context.Context, s Store) (*Permission, error) { return s.GetPermissionByID(ctx, id) })
+}
+
+func (f *FailoverStore) ListAllPermissions(ctx context.Context) ([]*Permission, error) {
+	return failoverRead(ctx, f, func(ctx context.Context, s Store) ([]*Permission, error) { return s.ListAllPermissions(ctx) })
+}
+
+func (f *FailoverStore) GetPermissionByResource(ctx context.Context, resource string, action Action) (*Permission, error) {
+	return failoverRead(ctx, f, func(ctx context.Context, s Store) (*Permission, error) {
+		return s.GetPermissionByResource(ctx, resource, action)
+	})
+}
+
+//
+// ---------- RoleRepo ----------
+//
+
+func (f *FailoverStore) CreateRole(ctx context.Context, r *Role) error {
+	return f.write(func(s Store) error { return s.CreateRole(ctx, r) })
+}
+
+func (f *FailoverStore) DeleteRole(ctx context.Context, id string) error {
+	return f.write(func(s Store) error { return s.DeleteRole(ctx, id) })
+}
+
+func (f *FailoverStore) GetRoleByID(ctx context.Context, id string) (*Role, error) {
+	return failoverRead(ctx, f, func(ctx context.Context, s Store) (*Role, error) { return s.GetRoleByID(ctx, id) })
+}
+
+func (f *FailoverStore) GetRoleByName(ctx context.Context, name string) (*Role, error) {
+	return failoverRead(ctx, f, func(ctx context.Context, s Store) (*Role, error) { return s.GetRoleByName(ctx, name) })
+}
+
+func (f *FailoverStore) ListAllRoles(ctx context.Context) ([]*Role, error) {
+	return failoverRead(ctx, f, func(ctx context.Context, s Store) ([]*Role, error) { return s.ListAllRoles(ctx) })
+}
+
+//
+// ---------- UserRepo ----------
+//
+
+func (f *FailoverStore) CreateUser(ctx context.Context, u *User) error {
+	return f.write(func(s Store) error { return s.CreateUser(ctx, u) })
+}
+
+func (f *FailoverStore) DeleteUser(ctx context.Context, id string) error {
+	return f.write(func(s Store) error { return s.DeleteUser(ctx, id) })
+}
+
+func (f *FailoverStore) GetUserByID(ctx context.Context, id string) (*User, error) {
+	return failoverRead(ctx, f, func(ctx context.Context, s Store) (*User, error) { return s.GetUserByID(ctx, id) })
+}
+
+func (f *FailoverStore) ListAllUsers(ctx context.Context) ([]*User, error) {
+	return failoverRead(ctx, f, func(ctx context.Context, s Store) ([]*User, error) { return s.ListAllUsers(ctx) })
+}
+
+func (f *FailoverStore) GetUserByMeta(ctx context.Context, meta map[string]interface{}) (*User, error) {
+	return failoverRead(ctx, f, func(ctx context.Context, s Store) (*User, error) { return s.GetUserByMeta(ctx, meta) })
+}
+
+//
+// ---------- UserGroupRepo ----------
+//
+
+func (f *FailoverStore) AddUserToGroup(ctx context.Context, ug *UserGroup) error {
+	return f.write(func(s Store) error { return s.AddUserToGroup(ctx, ug) })
+}
+
+func (f *FailoverStore) RemoveUserFromGroup(ctx context.Context, groupName string, ug *UserGroup) error {
+	return f.write(func(s Store) error { return s.RemoveUserFromGroup(ctx, groupName, ug) })
+}
+
+func (f *FailoverStore) GetGroupsByUserID(ctx context.Context, id string) ([]*UserGroup, error) {
+	return failoverRead(ctx, f, func(ctx context.Context, s Store) ([]*UserGroup, error) { return s.GetGroupsByUserID(ctx, id) })
+}
+
+func (f *FailoverStore) GetUsersByGroupID(ctx context.Context, id string) ([]*UserGroup, error) {
+	return failoverRead(ctx, f, func(ctx context.Context, s Store) ([]*UserGroup, error) { return s.GetUsersByGroupID(ctx, id) })
+}
+
+//
+// ---------- RolePermissionRepo ----------
+//
+
+func (f *FailoverStore) AddRP(ctx context.Context, roleID, permID string) error {
+	return f.write(func(s Store) error { return s.AddRP(ctx, roleID, permID) })
+}
+
+func (f *FailoverStore) Remove(ctx context.Context, roleID, permID string) error {
+	return f.write(func(s Store) error { return s.Remove(ctx, roleID, permID) })
+}
+
+func (f *FailoverStore) ListPermissions(ctx context.Context, roleID string) ([]string, error) {
+	return failoverRead(ctx, f, func(ctx context.Context, s Store) ([]string, error) { return s.ListPermissions(ctx, roleID) })
+}
+
+//
+// ---------- UserRoleRepo ----------
+//
+
+func (f *FailoverStore) AddUR(ctx context.Context, userID, roleID string) error {
+	return f.write(func(s Store) error { return s.AddUR(ctx, userID, roleID) })
+}
+
+func (f *FailoverStore) RemoveUR(ctx context.Context, userID, roleID string) error {
+	return f.write(func(s Store) error { return s.RemoveUR(ctx, userID, roleID) })
+}
+
+func (f *FailoverStore) ListRoles(ctx context.Context, userID string) ([]string, error) {
+	return failoverRead(ctx, f, func(ctx context.Context, s Store) ([]string, error) { return s.ListRoles(ctx, userID) })
+}
+
+func (f *FailoverStore) ListUsersForRole(ctx context.Context, roleID string) ([]string, error) {
+	return failoverRead(ctx, f, func(ctx context.Context, s Store) ([]string, error) { return s.ListUsersForRole(ctx, roleID) })
+}
+
+//
+// ---------- GroupRoleRepo ----------
+//
+
+func (f *FailoverStore) AddRoleToGroup(ctx context.Context, groupID, roleID string) error {
+	return f.write(func(s Store) error { return s.AddRoleToGroup(ctx, groupID, roleID) })
+}
+
+func (f *FailoverStore) RemoveRoleFromGroup(ctx context.Context, groupID, roleID string) error {
+	return f.write(func(s Store) error { return s.RemoveRoleFromGroup(ctx, groupID, roleID) })
+}
+
+func (f *FailoverStore) ListRolesForGroup(ctx context.Context, groupID string) ([]string, error) {
+	return failoverRead(ctx, f, func(ctx context.Context, s Store) ([]string, error) { return s.ListRolesForGroup(ctx, groupID) })
+}
+
+func (f *FailoverStore) ListGroupsForRole(ctx context.Context, roleID string) ([]string, error) {
+	return failoverRead(ctx, f, func(ctx context.Context, s Store) ([]string, error) { return s.ListGroupsForRole(ctx, roleID) })
+}
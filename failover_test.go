@@ -0,0 +1,194 @@
+package rbac
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// outageStore fails every call it intercepts while down is set.
+type outageStore struct {
+	Store
+	down  atomic.Bool
+	delay atomic.Int64 // nanoseconds GetRoleByName stalls for
+}
+
+var errOutage = errors.New("region unavailable")
+
+func (o *outageStore) GetRoleByID(ctx context.Context, id string) (*Role, error) {
+	if o.down.Load() {
+		return nil, errOutage
+	}
+	return o.Store.GetRoleByID(ctx, id)
+}
+
+func (o *outageStore) GetRoleByName(ctx context.Context, name string) (*Role, error) {
+	if d := o.delay.Load(); d > 0 {
+		select {
+		case <-time.After(time.Duration(d)):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	return o.Store.GetRoleByName(ctx, name)
+}
+
+func (o *outageStore) CreateRole(ctx context.Context, r *Role) error {
+	if o.down.Load() {
+		return errOutage
+	}
+	return o.Store.CreateRole(ctx, r)
+}
+
+func newFailoverPair(t *testing.T, mode FailoverWriteMode) (*FailoverStore, *outageStore, *MockRepo) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	primary := &outageStore{Store: NewMockRepo()}
+	secondary := NewMockRepo()
+	f := NewFailoverStore(ctx, primary, secondary, FailoverConfig{
+		ProbeInterval:    time.Hour, // probes are driven by the test
+		FailureThreshold: 2,
+		WriteMode:        mode,
+	})
+	return f, primary, secondary
+}
+
+func TestFailoverStoreReads(t *testing.T) {
+	ctx := context.Background()
+	f, primary, secondary := newFailoverPair(t, FailoverWritePrimary)
+
+	if err := primary.CreateRole(ctx, &Role{ID: "r1", Name: "primary"}); err != nil {
+		t.Fatalf("CreateRole primary: %v", err)
+	}
+	if err := secondary.CreateRole(ctx, &Role{ID: "r1", Name: "secondary"}); err != nil {
+		t.Fatalf("CreateRole secondary: %v", err)
+	}
+
+	got, err := f.GetRoleByID(ctx, "r1")
+	if err != nil || got.Name != "primary" {
+		t.Fatalf("expected primary role, got %+v, %v", got, err)
+	}
+
+	// A failed read is retried on the secondary even before the probe notices.
+	primary.down.Store(true)
+	got, err = f.GetRoleByID(ctx, "r1")
+	if err != nil || got.Name != "secondary" {
+		t.Fatalf("expected secondary role, got %+v, %v", got, err)
+	}
+
+	if !f.CheckHealth(ctx) {
+		t.Fatal("one failed probe should not trip the threshold")
+	}
+	if f.CheckHealth(ctx) {
+		t.Fatal("expected primary to be marked unhealthy")
+	}
+
+	primary.down.Store(false)
+	if !f.CheckHealth(ctx) {
+		t.Fatal("expected primary to recover after a successful probe")
+	}
+}
+
+func TestFailoverStoreWriteModes(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Primary", func(t *testing.T) {
+		f, primary, _ := newFailoverPair(t, FailoverWritePrimary)
+		primary.down.Store(true)
+		if err := f.CreateRole(ctx, &Role{Name: "admin"}); !errors.Is(err, errOutage) {
+			t.Fatalf("expected outage error, got %v", err)
+		}
+	})
+
+	t.Run("Active", func(t *testing.T) {
+		f, primary, secondary := newFailoverPair(t, FailoverWriteActive)
+		primary.down.Store(true)
+		f.CheckHealth(ctx)
+		f.CheckHealth(ctx)
+
+		r := &Role{Name: "admin"}
+		if err := f.CreateRole(ctx, r); err != nil {
+			t.Fatalf("CreateRole: %v", err)
+		}
+		if got, _ := secondary.GetRoleByID(ctx, r.ID); got == nil {
+			t.Fatal("expected write to land on the secondary")
+		}
+	})
+
+	t.Run("Through", func(t *testing.T) {
+		f, primary, secondary := newFailoverPair(t, FailoverWriteThrough)
+		r := &Role{Name: "admin"}
+		if err := f.CreateRole(ctx, r); err != nil {
+			t.Fatalf("CreateRole: %v", err)
+		}
+		if got, _ := secondary.GetRoleByID(ctx, r.ID); got == nil {
+			t.Fatal("expected the write to be mirrored to the secondary")
+		}
+
+		primary.down.Store(true)
+		if err := f.CreateRole(ctx, &Role{ID: "r2", Name: "viewer"}); !errors.Is(err, errOutage) {
+			t.Fatalf("expected outage error, got %v", err)
+		}
+		if got, _ := secondary.GetRoleByID(ctx, "r2"); got != nil {
+			t.Fatal("expected a rejected write not to reach the secondary")
+		}
+	})
+
+	t.Run("Both", func(t *testing.T) {
+		f, primary, secondary := newFailoverPair(t, FailoverWriteBoth)
+		r := &Role{Name: "admin"}
+		if err := f.CreateRole(ctx, r); err != nil {
+			t.Fatalf("CreateRole: %v", err)
+		}
+		for name, s := range map[string]Store{"primary": primary, "secondary": secondary} {
+			if got, _ := s.GetRoleByID(ctx, r.ID); got == nil {
+				t.Errorf("expected role %s on the %s", r.ID, name)
+			}
+		}
+
+		primary.down.Store(true)
+		if err := f.CreateRole(ctx, &Role{ID: "r2", Name: "viewer"}); !errors.Is(err, errOutage) {
+			t.Fatalf("expected outage error, got %v", err)
+		}
+		if got, _ := secondary.GetRoleByID(ctx, "r2"); got != nil {
+			t.Fatal("expected a rejected write not to reach the secondary")
+		}
+	})
+}
+
+func TestFailoverStoreReadTimeout(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	primary := &outageStore{Store: NewMockRepo()}
+	secondary := NewMockRepo()
+	f := NewFailoverStore(ctx, primary, secondary, FailoverConfig{
+		ProbeInterval: time.Hour,
+		ReadTimeout:   20 * time.Millisecond,
+	})
+	if err := primary.CreateRole(ctx, &Role{ID: "r1", Name: "admin"}); err != nil {
+		t.Fatalf("CreateRole primary: %v", err)
+	}
+	if err := secondary.CreateRole(ctx, &Role{ID: "r1-replica", Name: "admin"}); err != nil {
+		t.Fatalf("CreateRole secondary: %v", err)
+	}
+
+	got, err := f.GetRoleByName(ctx, "admin")
+	if err != nil || got.ID != "r1" {
+		t.Fatalf("expected the primary's role, got %+v, %v", got, err)
+	}
+
+	primary.delay.Store(int64(time.Second))
+	start := time.Now()
+	got, err = f.GetRoleByName(ctx, "admin")
+	if err != nil || got.ID != "r1-replica" {
+		t.Fatalf("expected a slow primary to fall back to the secondary, got %+v, %v", got, err)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("expected the read to be cut off at the timeout, took %v", elapsed)
+	}
+}
@@ -0,0 +1,1053 @@
+// file: rbac/file_store.go
+package rbac
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Ensure FileStore implements all interfaces:
+var (
+	_ PermissionRepo     = (*FileStore)(nil)
+	_ RoleRepo           = (*FileStore)(nil)
+	_ UserRepo           = (*FileStore)(nil)
+	_ RolePermissionRepo = (*FileStore)(nil)
+	_ UserRoleRepo       = (*FileStore)(nil)
+	_ UserGroupRepo      = (*FileStore)(nil)
+	_ GroupRoleRepo      = (*FileStore)(nil)
+	_ PolicyVersioner    = (*FileStore)(nil)
+	_ HealthChecker      = (*FileStore)(nil)
+)
+
+// FileFormat selects the encoding of the policy files a FileStore writes.
+type FileFormat string
+
+const (
+	FileFormatYAML FileFormat = "yaml"
+	FileFormatJSON FileFormat = "json"
+)
+
+// Policy files in a FileStore directory, one per section. Each may be
+// written as <name>.yaml, <name>.yml or <name>.json.
+const (
+	filePermissions = "permissions"
+	fileRoles       = "roles"
+	fileUsers       = "users"
+	fileGroups      = "groups"
+)
+
+var fileSections = []string{filePermissions, fileRoles, fileUsers, fileGroups}
+
+// fileRole is a role as written in roles.yaml, with the IDs of the
+// permissions it grants.
+type fileRole struct {
+	Role        `yaml:",inline"`
+	Permissions []string `json:"permissions,omitempty" yaml:"permissions,omitempty"`
+}
+
+// fileUser is a user as written in users.yaml, with the IDs of the roles
+// assigned to it directly.
+type fileUser struct {
+	User  `yaml:",inline"`
+	Roles []string `json:"roles,omitempty" yaml:"roles,omitempty"`
+}
+
+// fileGroup is a group as written in groups.yaml: its members and the IDs of
+// the roles bound to it. Members do not repeat the group name.
+type fileGroup struct {
+	Name    string       `json:"name" yaml:"name"`
+	Roles   []string     `json:"roles,omitempty" yaml:"roles,omitempty"`
+	Members []*UserGroup `json:"members,omitempty" yaml:"members,omitempty"`
+}
+
+//
+// ---------- FileStore Core ----------
+//
+
+// FileStore keeps the policy in a directory of YAML or JSON files so it can be
+// versioned in git and reviewed like code. Every write rewrites the affected
+// file with entries sorted by ID, which keeps diffs small and stable.
+//
+// Assignments are nested in the entity they belong to: a role lists its
+// permissions, a user its roles, and a group its roles and members. Deleting
+// an entity therefore also removes the references to it.
+type FileStore struct {
+	mu     sync.RWMutex
+	dir    string
+	format FileFormat
+	ids    IDGenerator
+
+	// paths holds the file each section was read from, so writes go back to
+	// the same file whatever its extension.
+	paths   map[string]string
+	version string
+
+	perms  []*Permission
+	roles  []*fileRole
+	users  []*fileUser
+	groups []*fileGroup
+}
+
+// NewFileStore loads the policy files in dir, creating the directory when it
+// does not exist. Files that are created later use format (YAML by default).
+func NewFileStore(ctx context.Context, dir string, format FileFormat) (*FileStore, error) {
+	switch format {
+	case "":
+		format = FileFormatYAML
+	case FileFormatYAML, FileFormatJSON:
+	default:
+		return nil, fmt.Errorf("file_store: unsupported format %q", format)
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("file_store: %w", err)
+	}
+
+	s := &FileStore{dir: dir, format: format}
+	if err := s.Reload(ctx); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// SetIDGenerator changes how the store generates IDs for new entities.
+func (s *FileStore) SetIDGenerator(g IDGenerator) {
+	s.ids = g
+}
+
+// Health implements HealthChecker by checking that the store's directory
+// still exists.
+func (s *FileStore) Health(ctx context.Context) error {
+	fi, err := os.Stat(s.dir)
+	if err == nil && !fi.IsDir() {
+		err = fmt.Errorf("%s is not a directory", s.dir)
+	}
+	if err != nil {
+		return fmt.Errorf("file_store: %w", err)
+	}
+	return nil
+}
+
+// NewFileStoreManager wraps the store in a Manager and seeds the default role.
+func NewFileStoreManager(ctx context.Context, dir string, format FileFormat) (*Manager, error) {
+	s, err := NewFileStore(ctx, dir, format)
+	if err != nil {
+		return nil, err
+	}
+
+	def, _ := s.GetRoleByName(ctx, "default")
+	if def == nil {
+		def = &Role{Name: "default", Description: "Default role"}
+		if createErr := s.CreateRole(ctx, def); createErr != nil {
+			return nil, fmt.Errorf("failed to create default role: %w", createErr)
+		}
+	}
+
+	return &Manager{
+		Perms:           s,
+		Roles:           s,
+		Users:           s,
+		RP:              s,
+		UR:              s,
+		UG:              s,
+		GR:              s,
+		DefaultRoleName: "default",
+	}, nil
+}
+
+// Reload discards the in-memory policy and reads the files again, e.g. after
+// a git pull. The files are validated first; on error the current policy is
+// kept.
+func (s *FileStore) Reload(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.load()
+}
+
+// PolicyVersion is a hash of the policy files as last read or written.
+func (s *FileStore) PolicyVersion(ctx context.Context) (string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.version, nil
+}
+
+func (s *FileStore) load() error {
+	var (
+		next  = &FileStore{paths: map[string]string{}}
+		h     = sha256.New()
+		bound = map[string]interface{}{
+			filePermissions: &next.perms,
+			fileRoles:       &next.roles,
+			fileUsers:       &next.users,
+			fileGroups:      &next.groups,
+		}
+	)
+	for _, section := range fileSections {
+		path, err := s.findFile(section)
+		if err != nil {
+			return err
+		}
+		if path == "" {
+			continue
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("file_store: %w", err)
+		}
+		if err := decodeFile(path, data, bound[section]); err != nil {
+			return fmt.Errorf("file_store: %s: %w", filepath.Base(path), err)
+		}
+		next.paths[section] = path
+		h.Write(data)
+	}
+	if err := next.validate(); err != nil {
+		return fmt.Errorf("file_store: %w", err)
+	}
+
+	s.paths = next.paths
+	s.perms, s.roles, s.users, s.groups = next.perms, next.roles, next.users, next.groups
+	for _, g := range s.groups {
+		for _, m := range g.Members {
+			m.GroupName = g.Name
+		}
+	}
+	s.version = hex.EncodeToString(h.Sum(nil))
+	return nil
+}
+
+// findFile returns the file holding section, or "" when there is none.
+func (s *FileStore) findFile(section string) (string, error) {
+	var found string
+	for _, ext := range []string{".yaml", ".yml", ".json"} {
+		path := filepath.Join(s.dir, section+ext)
+		if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+			continue
+		} else if err != nil {
+			return "", fmt.Errorf("file_store: %w", err)
+		}
+		if found != "" {
+			return "", fmt.Errorf("file_store: both %s and %s define %s", filepath.Base(found), filepath.Base(path), section)
+		}
+		found = path
+	}
+	return found, nil
+}
+
+// decodeFile decodes by extension and rejects unknown fields, so typos in a
+// reviewed policy fail loudly instead of being dropped.
+func decodeFile(path string, data []byte, v interface{}) error {
+	return decodeFormat(fileFormat(path), data, v)
+}
+
+// fileFormat returns the format of a policy file by its extension.
+func fileFormat(path string) FileFormat {
+	if filepath.Ext(path) == ".json" {
+		return FileFormatJSON
+	}
+	return FileFormatYAML
+}
+
+func decodeFormat(format FileFormat, data []byte, v interface{}) error {
+	if format == FileFormatJSON {
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		return dec.Decode(v)
+	}
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(v); err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+	return nil
+}
+
+// validate checks the uniqueness rules the other stores enforce with indexes,
+// and that every assignment refers to an entity that exists.
+func (s *FileStore) validate() error {
+	perms := map[string]bool{}
+	resources := map[string]bool{}
+	for _, p := range s.perms {
+		if p.ID == "" {
+			return fmt.Errorf("permission %s,%s has no id", p.Resource, p.Action)
+		}
+		if perms[p.ID] {
+			return fmt.Errorf("duplicate permission id %q", p.ID)
+		}
+		perms[p.ID] = true
+		key := string(p.Action) + "\x00" + p.Resource
+		if resources[key] {
+			return fmt.Errorf("duplicate permission %s,%s", p.Resource, p.Action)
+		}
+		resources[key] = true
+	}
+
+	roles := map[string]bool{}
+	names := map[string]bool{}
+	for _, r := range s.roles {
+		if r.ID == "" {
+			return fmt.Errorf("role %q has no id", r.Name)
+		}
+		if roles[r.ID] || names[r.Name] {
+			return fmt.Errorf("duplicate role %q", r.ID)
+		}
+		roles[r.ID], names[r.Name] = true, true
+		for _, pid := range r.Permissions {
+			if !perms[pid] {
+				return fmt.Errorf("role %q grants unknown permission %q", r.ID, pid)
+			}
+		}
+	}
+
+	users := map[string]bool{}
+	logins := map[string]bool{}
+	for _, u := range s.users {
+		if u.ID == "" {
+			return fmt.Errorf("user %q has no id", u.Username)
+		}
+		if users[u.ID] {
+			return fmt.Errorf("duplicate user id %q", u.ID)
+		}
+		users[u.ID] = true
+		for _, login := range []string{"u:" + u.Username, "e:" + u.Email} {
+			if len(login) > 2 && logins[login] {
+				return fmt.Errorf("user %q duplicates username or email %q", u.ID, login[2:])
+			}
+			logins[login] = true
+		}
+		for _, rid := range u.Roles {
+			if !roles[rid] {
+				return fmt.Errorf("user %q has unknown role %q", u.ID, rid)
+			}
+		}
+	}
+
+	groups := map[string]bool{}
+	for _, g := range s.groups {
+		if g.Name == "" {
+			return errors.New("group has no name")
+		}
+		if groups[g.Name] {
+			return fmt.Errorf("duplicate group %q", g.Name)
+		}
+		groups[g.Name] = true
+		for _, rid := range g.Roles {
+			if !roles[rid] {
+				return fmt.Errorf("group %q has unknown role %q", g.Name, rid)
+			}
+		}
+		for _, m := range g.Members {
+			if !users[m.UserID] {
+				return fmt.Errorf("group %q has unknown member %q", g.Name, m.UserID)
+			}
+		}
+	}
+	return nil
+}
+
+// save rewrites the given sections. If a write fails the store reloads what
+// is on disk, so memory never holds changes the files do not.
+func (s *FileStore) save(sections ...string) error {
+	for _, section := range sections {
+		if err := s.writeSection(section); err != nil {
+			_ = s.load()
+			return fmt.Errorf("file_store: write %s: %w", section, err)
+		}
+	}
+	return s.rehash()
+}
+
+func (s *FileStore) writeSection(section string) error {
+	var v interface{}
+	switch section {
+	case filePermissions:
+		sort.Slice(s.perms, func(i, j int) bool { return s.perms[i].ID < s.perms[j].ID })
+		v = s.perms
+	case fileRoles:
+		sort.Slice(s.roles, func(i, j int) bool { return s.roles[i].ID < s.roles[j].ID })
+		for _, r := range s.roles {
+			sort.Strings(r.Permissions)
+		}
+		v = s.roles
+	case fileUsers:
+		sort.Slice(s.users, func(i, j int) bool { return s.users[i].ID < s.users[j].ID })
+		for _, u := range s.users {
+			sort.Strings(u.Roles)
+		}
+		v = s.users
+	case fileGroups:
+		sort.Slice(s.groups, func(i, j int) bool { return s.groups[i].Name < s.groups[j].Name })
+		out := make([]*fileGroup, len(s.groups))
+		for i, g := range s.groups {
+			sort.Strings(g.Roles)
+			sort.Slice(g.Members, func(a, b int) bool { return g.Members[a].UserID < g.Members[b].UserID })
+			cp := &fileGroup{Name: g.Name, Roles: g.Roles}
+			for _, m := range g.Members {
+				mc := *m
+				mc.GroupName = ""
+				cp.Members = append(cp.Members, &mc)
+			}
+			out[i] = cp
+		}
+		v = out
+	}
+
+	path := s.paths[section]
+	if path == "" {
+		path = filepath.Join(s.dir, section+"."+string(s.format))
+	}
+	data, err := encodeFile(path, v)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(s.dir, "."+section+"-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+	s.paths[section] = path
+	return nil
+}
+
+func encodeFile(path string, v interface{}) ([]byte, error) {
+	return encodeFormat(fileFormat(path), v)
+}
+
+func encodeFormat(format FileFormat, v interface{}) ([]byte, error) {
+	if format == FileFormatJSON {
+		data, err := json.MarshalIndent(v, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		return append(data, '\n'), nil
+	}
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// rehash recomputes the policy version from the files on disk.
+func (s *FileStore) rehash() error {
+	h := sha256.New()
+	for _, section := range fileSections {
+		path := s.paths[section]
+		if path == "" {
+			continue
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("file_store: %w", err)
+		}
+		h.Write(data)
+	}
+	s.version = hex.EncodeToString(h.Sum(nil))
+	return nil
+}
+
+func (s *FileStore) role(id string) *fileRole {
+	for _, r := range s.roles {
+		if r.ID == id {
+			return r
+		}
+	}
+	return nil
+}
+
+func (s *FileStore) user(id string) *fileUser {
+	for _, u := range s.users {
+		if u.ID == id {
+			return u
+		}
+	}
+	return nil
+}
+
+func (s *FileStore) group(name string) *fileGroup {
+	for _, g := range s.groups {
+		if g.Name == name {
+			return g
+		}
+	}
+	return nil
+}
+
+// addStr appends v to list unless it is already present.
+func addStr(list []string, v string) []string {
+	for _, x := range list {
+		if x == v {
+			return list
+		}
+	}
+	return append(list, v)
+}
+
+//
+// ---------- UserRepo ----------
+//
+
+func (s *FileStore) GetUserByID(ctx context.Context, id string) (*User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if u := s.user(id); u != nil {
+		cp := u.User
+		return &cp, nil
+	}
+	return nil, nil
+}
+
+func (s *FileStore) GetUserByMeta(ctx context.Context, meta map[string]interface{}) (*User, error) {
+	allowed := map[string]bool{"id": true, "username": true, "email": true}
+	want := make(map[string]string, len(meta))
+	for k, v := range meta {
+		if !allowed[k] {
+			return nil, fmt.Errorf("GetUserByMeta: unsupported field %q", k)
+		}
+		want[k] = fmt.Sprint(v)
+	}
+	if len(want) == 0 {
+		return nil, errors.New("GetUserByMeta: no filter provided")
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, u := range s.users {
+		if v, ok := want["id"]; ok && u.ID != v {
+			continue
+		}
+		if v, ok := want["username"]; ok && u.Username != v {
+			continue
+		}
+		if v, ok := want["email"]; ok && u.Email != v {
+			continue
+		}
+		cp := u.User
+		return &cp, nil
+	}
+	return nil, nil
+}
+
+func (s *FileStore) ListAllUsers(ctx context.Context) ([]*User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	out := make([]*User, 0, len(s.users))
+	for _, u := range s.users {
+		cp := u.User
+		out = append(out, &cp)
+	}
+	return out, nil
+}
+
+func (s *FileStore) CreateUser(ctx context.Context, u *User) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if u.ID == "" {
+		u.ID = generateID(s.ids, KindUser)
+	}
+	for _, existing := range s.users {
+		if existing.ID == u.ID ||
+			(u.Username != "" && existing.Username == u.Username) ||
+			(u.Email != "" && existing.Email == u.Email) {
+			return fmt.Errorf("file_store: user %q already exists", u.Username)
+		}
+	}
+	u.CreatedAt = time.Now().Unix()
+
+	s.users = append(s.users, &fileUser{User: *u})
+	return s.save(fileUsers)
+}
+
+// DeleteUser removes the user together with its role assignments and group
+// memberships.
+func (s *FileStore) DeleteUser(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.user(id) == nil {
+		return nil
+	}
+	users := s.users[:0]
+	for _, u := range s.users {
+		if u.ID != id {
+			users = append(users, u)
+		}
+	}
+	s.users = users
+
+	membership := false
+	for _, g := range s.groups {
+		members := g.Members[:0]
+		for _, m := range g.Members {
+			if m.UserID != id {
+				members = append(members, m)
+			}
+		}
+		membership = membership || len(members) != len(g.Members)
+		g.Members = members
+	}
+	if membership {
+		return s.save(fileGroups, fileUsers)
+	}
+	return s.save(fileUsers)
+}
+
+func (s *FileStore) GetGroupsByUserID(ctx context.Context, userID string) ([]*UserGroup, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var out []*UserGroup
+	for _, g := range s.groups {
+		for _, m := range g.Members {
+			if m.UserID == userID {
+				cp := *m
+				out = append(out, &cp)
+			}
+		}
+	}
+	return out, nil
+}
+
+//
+// ---------- PermissionRepo ----------
+//
+
+func (s *FileStore) GetPermissionByID(ctx context.Context, id string) (*Permission, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, p := range s.perms {
+		if p.ID == id {
+			cp := *p
+			return &cp, nil
+		}
+	}
+	return nil, nil
+}
+
+func (s *FileStore) ListAllPermissions(ctx context.Context) ([]*Permission, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	out := make([]*Permission, 0, len(s.perms))
+	for _, p := range s.perms {
+		cp := *p
+		out = append(out, &cp)
+	}
+	return out, nil
+}
+
+func (s *FileStore) GetPermissionByResource(ctx context.Context, resource string, action Action) (*Permission, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, p := range s.perms {
+		if p.Resource == resource && p.Action == action {
+			cp := *p
+			return &cp, nil
+		}
+	}
+	return nil, nil
+}
+
+func (s *FileStore) CreatePermission(ctx context.Context, p *Permission) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, existing := range s.perms {
+		if existing.Resource == p.Resource && existing.Action == p.Action {
+			*p = *existing
+			return nil
+		}
+	}
+
+	if p.ID == "" {
+		p.ID = generateID(s.ids, KindPermission)
+	}
+	for _, existing := range s.perms {
+		if existing.ID == p.ID {
+			return fmt.Errorf("file_store: permission %q already exists", p.ID)
+		}
+	}
+	p.CreatedAt = time.Now().Unix()
+
+	cp := *p
+	s.perms = append(s.perms, &cp)
+	return s.save(filePermissions)
+}
+
+// DeletePermission removes the permission and revokes it from every role.
+func (s *FileStore) DeletePermission(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	perms := s.perms[:0]
+	for _, p := range s.perms {
+		if p.ID != id {
+			perms = append(perms, p)
+		}
+	}
+	if len(perms) == len(s.perms) {
+		return nil
+	}
+	s.perms = perms
+
+	for _, r := range s.roles {
+		r.Permissions = removeStr(r.Permissions, id)
+	}
+	// Roles first: a crash in between leaves an unused permission, not a
+	// role granting one that no longer exists.
+	return s.save(fileRoles, filePermissions)
+}
+
+//
+// ---------- RoleRepo ----------
+//
+
+func (s *FileStore) CreateRole(ctx context.Context, r *Role) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if r.ID == "" {
+		r.ID = generateID(s.ids, KindRole)
+	}
+	for _, existing := range s.roles {
+		if existing.ID == r.ID || existing.Name == r.Name {
+			return fmt.Errorf("file_store: role %q already exists", r.Name)
+		}
+	}
+	r.CreatedAt = time.Now().Unix()
+
+	s.roles = append(s.roles, &fileRole{Role: *r})
+	return s.save(fileRoles)
+}
+
+func (s *FileStore) GetRoleByName(ctx context.Context, name string) (*Role, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, r := range s.roles {
+		if r.Name == name {
+			cp := r.Role
+			return &cp, nil
+		}
+	}
+	return nil, nil
+}
+
+func (s *FileStore) GetRoleByID(ctx context.Context, id string) (*Role, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if r := s.role(id); r != nil {
+		cp := r.Role
+		return &cp, nil
+	}
+	return nil, nil
+}
+
+// DeleteRole removes the role and unassigns it from every user and group.
+func (s *FileStore) DeleteRole(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	roles := s.roles[:0]
+	for _, r := range s.roles {
+		if r.ID != id {
+			roles = append(roles, r)
+		}
+	}
+	if len(roles) == len(s.roles) {
+		return nil
+	}
+	s.roles = roles
+
+	for _, u := range s.users {
+		u.Roles = removeStr(u.Roles, id)
+	}
+	for _, g := range s.groups {
+		g.Roles = removeStr(g.Roles, id)
+	}
+	return s.save(fileUsers, fileGroups, fileRoles)
+}
+
+func (s *FileStore) ListAllRoles(ctx context.Context) ([]*Role, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	out := make([]*Role, 0, len(s.roles))
+	for _, r := range s.roles {
+		cp := r.Role
+		out = append(out, &cp)
+	}
+	return out, nil
+}
+
+//
+// ---------- RolePermissionRepo ----------
+//
+
+func (s *FileStore) AddRP(ctx context.Context, roleID, permID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r := s.role(roleID)
+	if r == nil {
+		return fmt.Errorf("file_store: role %q not found", roleID)
+	}
+	n := len(r.Permissions)
+	if r.Permissions = addStr(r.Permissions, permID); len(r.Permissions) == n {
+		return nil
+	}
+	return s.save(fileRoles)
+}
+
+func (s *FileStore) Remove(ctx context.Context, roleID, permID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r := s.role(roleID)
+	if r == nil {
+		return nil
+	}
+	r.Permissions = removeStr(r.Permissions, permID)
+	return s.save(fileRoles)
+}
+
+func (s *FileStore) ListPermissions(ctx context.Context, roleID string) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if r := s.role(roleID); r != nil {
+		return append([]string(nil), r.Permissions...), nil
+	}
+	return nil, nil
+}
+
+//
+// ---------- UserRoleRepo ----------
+//
+
+func (s *FileStore) AddUR(ctx context.Context, userID, roleID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u := s.user(userID)
+	if u == nil {
+		return fmt.Errorf("file_store: user %q not found", userID)
+	}
+	n := len(u.Roles)
+	if u.Roles = addStr(u.Roles, roleID); len(u.Roles) == n {
+		return nil
+	}
+	return s.save(fileUsers)
+}
+
+func (s *FileStore) RemoveUR(ctx context.Context, userID, roleID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u := s.user(userID)
+	if u == nil {
+		return nil
+	}
+	u.Roles = removeStr(u.Roles, roleID)
+	return s.save(fileUsers)
+}
+
+func (s *FileStore) ListRoles(ctx context.Context, userID string) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var out []string
+	if u := s.user(userID); u != nil {
+		out = append(out, u.Roles...)
+	}
+	return out, nil
+}
+
+func (s *FileStore) ListUsersForRole(ctx context.Context, roleID string) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var out []string
+	for _, u := range s.users {
+		if slices.Contains(u.Roles, roleID) {
+			out = append(out, u.ID)
+		}
+	}
+	return out, nil
+}
+
+//
+// ---------- UserGroupRepo ----------
+//
+
+func (s *FileStore) AddUserToGroup(ctx context.Context, ug *UserGroup) error {
+	if ug.UserID == "" {
+		return errors.New("user id is empty")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.user(ug.UserID) == nil {
+		return fmt.Errorf("file_store: user %q not found", ug.UserID)
+	}
+	if ug.ID == "" {
+		ug.ID = generateID(s.ids, KindUserGroup)
+	}
+	ug.CreatedAt = time.Now().Unix()
+
+	g := s.group(ug.GroupName)
+	if g == nil {
+		g = &fileGroup{Name: ug.GroupName}
+		s.groups = append(s.groups, g)
+	}
+	cp := *ug
+	members := g.Members[:0]
+	for _, m := range g.Members {
+		if m.UserID != ug.UserID {
+			members = append(members, m)
+		}
+	}
+	g.Members = append(members, &cp)
+	return s.save(fileGroups)
+}
+
+// RemoveUserFromGroup drops the membership; a group left without members or
+// roles is removed from the file.
+func (s *FileStore) RemoveUserFromGroup(ctx context.Context, groupName string, ug *UserGroup) error {
+	if ug.UserID == "" {
+		return errors.New("user id is empty")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	g := s.group(groupName)
+	if g == nil {
+		return nil
+	}
+	members := g.Members[:0]
+	for _, m := range g.Members {
+		if m.UserID != ug.UserID {
+			members = append(members, m)
+		}
+	}
+	g.Members = members
+	s.pruneGroup(g)
+	return s.save(fileGroups)
+}
+
+func (s *FileStore) GetUsersByGroupID(ctx context.Context, groupName string) ([]*UserGroup, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	g := s.group(groupName)
+	if g == nil {
+		return nil, nil
+	}
+	out := make([]*UserGroup, 0, len(g.Members))
+	for _, m := range g.Members {
+		cp := *m
+		out = append(out, &cp)
+	}
+	return out, nil
+}
+
+func (s *FileStore) pruneGroup(g *fileGroup) {
+	if len(g.Members) > 0 || len(g.Roles) > 0 {
+		return
+	}
+	groups := s.groups[:0]
+	for _, x := range s.groups {
+		if x != g {
+			groups = append(groups, x)
+		}
+	}
+	s.groups = groups
+}
+
+//
+// ---------- GroupRoleRepo ----------
+//
+
+func (s *FileStore) AddRoleToGroup(ctx context.Context, groupID, roleID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.role(roleID) == nil {
+		return fmt.Errorf("file_store: role %q not found", roleID)
+	}
+	g := s.group(groupID)
+	if g == nil {
+		g = &fileGroup{Name: groupID}
+		s.groups = append(s.groups, g)
+	}
+	n := len(g.Roles)
+	if g.Roles = addStr(g.Roles, roleID); len(g.Roles) == n {
+		return nil
+	}
+	return s.save(fileGroups)
+}
+
+func (s *FileStore) RemoveRoleFromGroup(ctx context.Context, groupID, roleID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	g := s.group(groupID)
+	if g == nil {
+		return nil
+	}
+	g.Roles = removeStr(g.Roles, roleID)
+	s.pruneGroup(g)
+	return s.save(fileGroups)
+}
+
+func (s *FileStore) ListRolesForGroup(ctx context.Context, groupID string) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if g := s.group(groupID); g != nil {
+		return append([]string(nil), g.Roles...), nil
+	}
+	return nil, nil
+}
+
+func (s *FileStore) ListGroupsForRole(ctx context.Context, roleID string) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var out []string
+	for _, g := range s.groups {
+		if slices.Contains(g.Roles, roleID) {
+			out = append(out, g.Name)
+		}
+	}
+	return out, nil
+}
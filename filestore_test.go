@@ -0,0 +1,110 @@
+package rbac
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFileStore(t *testing.T) {
+	dir := t.TempDir()
+	s, err := NewFileStore(context.Background(), dir, FileFormatYAML)
+	if err != nil {
+		t.Fatalf("NewFileStore: %v", err)
+	}
+	runSuite(t, s)
+
+	t.Run("Reopen", func(t *testing.T) {
+		ctx := context.Background()
+		reopened, err := NewFileStore(ctx, dir, "")
+		if err != nil {
+			t.Fatalf("reopen: %v", err)
+		}
+		want, _ := s.GetRoleByName(ctx, "admin")
+		got, err := reopened.GetRoleByName(ctx, "admin")
+		if err != nil || got == nil || got.ID != want.ID {
+			t.Fatalf("expected role %+v after reopen, got %+v (%v)", want, got, err)
+		}
+		members, _ := reopened.GetUsersByGroupID(ctx, "design")
+		if len(members) != 1 || members[0].GroupName != "design" {
+			t.Errorf("expected group membership after reopen, got %+v", members)
+		}
+		v1, _ := s.PolicyVersion(ctx)
+		v2, _ := reopened.PolicyVersion(ctx)
+		if v1 == "" || v1 != v2 {
+			t.Errorf("expected matching policy versions, got %q and %q", v1, v2)
+		}
+	})
+}
+
+func TestFileStoreHandWritten(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	write := func(name, data string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("permissions.yaml", `
+- id: surveys-read
+  resource: survey.**
+  action: read
+`)
+	write("roles.yml", `
+- id: viewer
+  name: viewer
+  permissions: [surveys-read]
+`)
+	write("users.json", `[{"id": "alice", "username": "alice", "roles": ["viewer"]}]`)
+
+	mgr, err := NewFileStoreManager(ctx, dir, FileFormatJSON)
+	if err != nil {
+		t.Fatalf("NewFileStoreManager: %v", err)
+	}
+	ok, err := mgr.Can(ctx, "alice", "survey.1", ActionRead)
+	if err != nil || !ok {
+		t.Fatalf("expected alice to read survey.1, got %v (%v)", ok, err)
+	}
+
+	// Writes go back to the file each section came from.
+	if err := mgr.AssignRoleToGroup(ctx, "ops", "viewer"); err != nil {
+		t.Fatalf("AssignRoleToGroup: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "groups.json")); err != nil {
+		t.Errorf("expected new section in the configured format: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "roles.yml"))
+	if err != nil || !strings.Contains(string(data), "name: default") {
+		t.Errorf("expected the default role in roles.yml, got %q (%v)", data, err)
+	}
+
+	t.Run("RejectsInvalidFiles", func(t *testing.T) {
+		for name, body := range map[string]string{
+			"unknown field":      "- id: x\n  resource: a\n  action: read\n  acton: write\n",
+			"dangling reference": "- id: x\n  resource: a\n  action: read\n",
+		} {
+			bad := t.TempDir()
+			_ = os.WriteFile(filepath.Join(bad, "permissions.yaml"), []byte(body), 0o644)
+			if name == "dangling reference" {
+				_ = os.WriteFile(filepath.Join(bad, "roles.yaml"), []byte("- id: r\n  name: r\n  permissions: [y]\n"), 0o644)
+			}
+			if _, err := NewFileStore(ctx, bad, ""); err == nil {
+				t.Errorf("%s: expected an error", name)
+			}
+		}
+	})
+
+	t.Run("ReloadKeepsPolicyOnError", func(t *testing.T) {
+		write("users.json", `[{"id": "alice", "roles": ["missing"]}]`)
+		store := mgr.Users.(*FileStore)
+		if err := store.Reload(ctx); err == nil {
+			t.Fatal("expected Reload to reject an unknown role")
+		}
+		if u, _ := store.GetUserByID(ctx, "alice"); u == nil {
+			t.Error("expected the previous policy to be kept")
+		}
+	})
+}
@@ -0,0 +1,810 @@
+// file: rbac/firestore_store.go
+package rbac
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Ensure FirestoreStore implements all interfaces:
+var (
+	_ PermissionRepo           = (*FirestoreStore)(nil)
+	_ RoleRepo                 = (*FirestoreStore)(nil)
+	_ UserRepo                 = (*FirestoreStore)(nil)
+	_ UserStatusRepo           = (*FirestoreStore)(nil)
+	_ EmailVerificationRepo    = (*FirestoreStore)(nil)
+	_ RolePermissionRepo       = (*FirestoreStore)(nil)
+	_ UserRoleRepo             = (*FirestoreStore)(nil)
+	_ UserGroupRepo            = (*FirestoreStore)(nil)
+	_ GroupRoleRepo            = (*FirestoreStore)(nil)
+	_ ExpiringMembershipLister = (*FirestoreStore)(nil)
+	_ HealthChecker            = (*FirestoreStore)(nil)
+)
+
+//
+// ---------- Firestore Documents ----------
+//
+
+type firestorePermission struct {
+	ID          string            `firestore:"id"`
+	Name        string            `firestore:"name,omitempty"`
+	Description string            `firestore:"description,omitempty"`
+	Labels      map[string]string `firestore:"labels,omitempty"`
+	Resource    string            `firestore:"resource"`
+	Action      string            `firestore:"action"`
+	Effect      string            `firestore:"effect,omitempty"`
+	Condition   string            `firestore:"condition,omitempty"`
+	CreatedAt   int64             `firestore:"created_at"`
+	UpdatedAt   int64             `firestore:"updated_at,omitempty"`
+	CreatedBy   string            `firestore:"created_by,omitempty"`
+	UpdatedBy   string            `firestore:"updated_by,omitempty"`
+	TenantID    string            `firestore:"tenant_id,omitempty"`
+}
+
+type firestoreRole struct {
+	ID          string                 `firestore:"id"`
+	Name        string                 `firestore:"name"`
+	Description string                 `firestore:"description"`
+	Meta        map[string]interface{} `firestore:"meta,omitempty"`
+	Template    bool                   `firestore:"template,omitempty"`
+	Priority    int                    `firestore:"priority,omitempty"`
+	Generators  []firestorePermission  `firestore:"generators,omitempty"`
+	CreatedAt   int64                  `firestore:"created_at"`
+	UpdatedAt   int64                  `firestore:"updated_at,omitempty"`
+	CreatedBy   string                 `firestore:"created_by,omitempty"`
+	UpdatedBy   string                 `firestore:"updated_by,omitempty"`
+	TenantID    string                 `firestore:"tenant_id,omitempty"`
+}
+
+type firestoreUser struct {
+	ID            string                 `firestore:"id"`
+	Username      string                 `firestore:"username"`
+	Email         string                 `firestore:"email"`
+	EmailVerified bool                   `firestore:"email_verified,omitempty"`
+	Status        string                 `firestore:"status,omitempty"`
+	Meta          map[string]interface{} `firestore:"meta,omitempty"`
+	CreatedAt     int64                  `firestore:"created_at"`
+	UpdatedAt     int64                  `firestore:"updated_at,omitempty"`
+	CreatedBy     string                 `firestore:"created_by,omitempty"`
+	UpdatedBy     string                 `firestore:"updated_by,omitempty"`
+	TenantID      string                 `firestore:"tenant_id,omitempty"`
+}
+
+type firestoreRolePermission struct {
+	RoleID       string `firestore:"role_id"`
+	PermissionID string `firestore:"permission_id"`
+	CreatedAt    int64  `firestore:"created_at"`
+}
+
+type firestoreUserRole struct {
+	UserID     string `firestore:"user_id"`
+	RoleID     string `firestore:"role_id"`
+	AssignedAt int64  `firestore:"assigned_at"`
+}
+
+type firestoreUserGroup struct {
+	ID        string `firestore:"id"`
+	GroupName string `firestore:"group_name"`
+	UserID    string `firestore:"user_id"`
+	CreatedAt int64  `firestore:"created_at"`
+	UpdatedAt int64  `firestore:"updated_at,omitempty"`
+	CreatedBy string `firestore:"created_by,omitempty"`
+	UpdatedBy string `firestore:"updated_by,omitempty"`
+	Level     string `firestore:"level,omitempty"`
+	ExpiresAt int64  `firestore:"expires_at,omitempty"`
+	TenantID  string `firestore:"tenant_id,omitempty"`
+}
+
+type firestoreGroupRole struct {
+	GroupName string `firestore:"group_name"`
+	RoleID    string `firestore:"role_id"`
+	CreatedAt int64  `firestore:"created_at"`
+}
+
+//
+// ---------- Indexes ----------
+//
+
+// FirestoreIndex describes a composite index the store's queries rely on.
+// Firestore indexes cannot be created from the client library, so they are
+// listed here and checked by ValidateIndexes instead.
+type FirestoreIndex struct {
+	Collection string
+	Fields     []string // all ascending
+}
+
+// GcloudCommand returns the gcloud invocation that creates the index.
+func (i FirestoreIndex) GcloudCommand() string {
+	var b strings.Builder
+	b.WriteString("gcloud firestore indexes composite create --collection-group=")
+	b.WriteString(i.Collection)
+	for _, f := range i.Fields {
+		b.WriteString(" --field-config=field-path=")
+		b.WriteString(f)
+		b.WriteString(",order=ascending")
+	}
+	return b.String()
+}
+
+// FirestoreIndexes lists the composite indexes needed by FirestoreStore.
+// Join listings are ordered by creation time, which is what requires them.
+var FirestoreIndexes = []FirestoreIndex{
+	{Collection: "permissions", Fields: []string{"resource", "action"}},
+	{Collection: "role_permissions", Fields: []string{"role_id", "created_at"}},
+	{Collection: "user_roles", Fields: []string{"user_id", "assigned_at"}},
+	{Collection: "user_groups", Fields: []string{"user_id", "created_at"}},
+	{Collection: "user_groups", Fields: []string{"group_name", "created_at"}},
+	{Collection: "group_roles", Fields: []string{"group_name", "created_at"}},
+}
+
+// ValidateIndexes runs one query per entry in FirestoreIndexes and reports
+// every index Firestore says is missing, together with the command that
+// creates it. The emulator does not enforce indexes, so this always passes
+// there.
+func (s *FirestoreStore) ValidateIndexes(ctx context.Context) error {
+	var missing []string
+	for _, idx := range FirestoreIndexes {
+		q := s.client.Collection(idx.Collection).Query
+		last := len(idx.Fields) - 1
+		for _, f := range idx.Fields[:last] {
+			q = q.Where(f, "==", "")
+		}
+		q = q.OrderBy(idx.Fields[last], firestore.Asc).Limit(1)
+
+		_, err := q.Documents(ctx).GetAll()
+		if status.Code(err) == codes.FailedPrecondition {
+			missing = append(missing, idx.GcloudCommand())
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("firestore_store: validate index on %s: %w", idx.Collection, err)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("firestore_store: missing composite indexes, create them with:\n  %s",
+			strings.Join(missing, "\n  "))
+	}
+	return nil
+}
+
+//
+// ---------- FirestoreStore Core ----------
+//
+
+// FirestoreStore keeps the same collections as MongoStore. Entities are
+// stored under their ID and join records under a key derived from both ends,
+// so re-adding an existing pair is a no-op rather than a duplicate.
+type FirestoreStore struct {
+	client *firestore.Client
+	ids    IDGenerator
+}
+
+// NewFirestoreStore creates the store and validates that the composite
+// indexes in FirestoreIndexes exist.
+func NewFirestoreStore(ctx context.Context, client *firestore.Client) (*FirestoreStore, error) {
+	s := &FirestoreStore{client: client}
+	if err := s.ValidateIndexes(ctx); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// SetIDGenerator changes how the store generates IDs for new entities.
+func (s *FirestoreStore) SetIDGenerator(g IDGenerator) {
+	s.ids = g
+}
+
+// Health implements HealthChecker with ValidateIndexes, whose queries also
+// show that Firestore answers.
+func (s *FirestoreStore) Health(ctx context.Context) error {
+	return s.ValidateIndexes(ctx)
+}
+
+// NewFirestoreStoreManager wraps the store in a Manager and seeds the default role.
+func NewFirestoreStoreManager(ctx context.Context, client *firestore.Client) (*Manager, error) {
+	s, err := NewFirestoreStore(ctx, client)
+	if err != nil {
+		return nil, err
+	}
+
+	def, _ := s.GetRoleByName(ctx, "default")
+	if def == nil {
+		def = &Role{Name: "default", Description: "Default role"}
+		if createErr := s.CreateRole(ctx, def); createErr != nil {
+			return nil, fmt.Errorf("failed to create default role: %w", createErr)
+		}
+	}
+
+	return &Manager{
+		Perms:           s,
+		Roles:           s,
+		Users:           s,
+		RP:              s,
+		UR:              s,
+		UG:              s,
+		GR:              s,
+		DefaultRoleName: "default",
+	}, nil
+}
+
+func (s *FirestoreStore) col(name string) *firestore.CollectionRef {
+	return s.client.Collection(name)
+}
+
+// docID escapes parts into a valid document ID; joined parts are separated
+// by "|", which PathEscape always escapes inside a part.
+func docID(parts ...string) string {
+	for i, p := range parts {
+		parts[i] = url.PathEscape(p)
+	}
+	return strings.Join(parts, "|")
+}
+
+// getDoc loads the document at ref into v, reporting false when it does not exist.
+func getDoc(ctx context.Context, ref *firestore.DocumentRef, v interface{}) (bool, error) {
+	snap, err := ref.Get(ctx)
+	if status.Code(err) == codes.NotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, snap.DataTo(v)
+}
+
+// first loads the first document matched by q into v, reporting false when
+// nothing matches.
+func first(ctx context.Context, q firestore.Query, v interface{}) (bool, error) {
+	docs, err := q.Limit(1).Documents(ctx).GetAll()
+	if err != nil || len(docs) == 0 {
+		return false, err
+	}
+	return true, docs[0].DataTo(v)
+}
+
+//
+// ---------- UserRepo ----------
+//
+
+func (s *FirestoreStore) GetUserByID(ctx context.Context, id string) (*User, error) {
+	var doc firestoreUser
+	ok, err := getDoc(ctx, s.col("users").Doc(docID(id)), &doc)
+	if err != nil || !ok {
+		return nil, err
+	}
+	return doc.user(), nil
+}
+
+func (s *FirestoreStore) GetUserByMeta(ctx context.Context, meta map[string]interface{}) (*User, error) {
+	allowed := map[string]bool{"id": true, "username": true, "email": true}
+	if len(meta) == 0 {
+		return nil, errors.New("GetUserByMeta: no filter provided")
+	}
+
+	q := s.col("users").Query
+	for k, v := range meta {
+		if !allowed[k] {
+			return nil, fmt.Errorf("GetUserByMeta: unsupported field %q", k)
+		}
+		q = q.Where(k, "==", v)
+	}
+
+	var doc firestoreUser
+	ok, err := first(ctx, q, &doc)
+	if err != nil || !ok {
+		return nil, err
+	}
+	return doc.user(), nil
+}
+
+func (s *FirestoreStore) ListAllUsers(ctx context.Context) ([]*User, error) {
+	docs, err := s.col("users").Documents(ctx).GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]*User, 0, len(docs))
+	for _, d := range docs {
+		var doc firestoreUser
+		if err := d.DataTo(&doc); err != nil {
+			return nil, fmt.Errorf("failed to decode user: %w", err)
+		}
+		out = append(out, doc.user())
+	}
+	return out, nil
+}
+
+func (s *FirestoreStore) CreateUser(ctx context.Context, u *User) error {
+	if u.ID == "" {
+		u.ID = generateID(s.ids, KindUser)
+	}
+	u.CreatedAt = time.Now().Unix()
+
+	users := s.col("users")
+	return s.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		taken, err := tx.Documents(users.Where("username", "==", u.Username).Limit(1)).GetAll()
+		if err != nil {
+			return err
+		}
+		if len(taken) > 0 {
+			return fmt.Errorf("firestore_store: username %q already exists", u.Username)
+		}
+		if u.Email != "" {
+			taken, err = tx.Documents(users.Where("email", "==", u.Email).Limit(1)).GetAll()
+			if err != nil {
+				return err
+			}
+			if len(taken) > 0 {
+				return fmt.Errorf("firestore_store: email %q already exists", u.Email)
+			}
+		}
+		return tx.Create(users.Doc(docID(u.ID)), firestoreUser{
+			ID:            u.ID,
+			Username:      u.Username,
+			Email:         u.Email,
+			EmailVerified: u.EmailVerified,
+			Status:        string(u.Status),
+			Meta:          u.Meta,
+			CreatedAt:     u.CreatedAt,
+			UpdatedAt:     u.UpdatedAt,
+			CreatedBy:     u.CreatedBy,
+			UpdatedBy:     u.UpdatedBy,
+			TenantID:      u.TenantID,
+		})
+	})
+}
+
+func (s *FirestoreStore) SetUserStatus(ctx context.Context, id string, status UserStatus) error {
+	return s.updateUser(ctx, id, "status", string(status))
+}
+
+func (s *FirestoreStore) SetEmailVerified(ctx context.Context, id string, verified bool) error {
+	return s.updateUser(ctx, id, "email_verified", verified)
+}
+
+// updateUser sets one field of a user along with updated_at and updated_by.
+func (s *FirestoreStore) updateUser(ctx context.Context, id, field string, v interface{}) error {
+	var updatedBy string
+	var updatedAt int64
+	stampUpdated(ctx, time.Now(), &updatedBy, &updatedAt)
+	_, err := s.col("users").Doc(docID(id)).Update(ctx, []firestore.Update{
+		{Path: field, Value: v},
+		{Path: "updated_at", Value: updatedAt},
+		{Path: "updated_by", Value: updatedBy},
+	})
+	return err
+}
+
+func (s *FirestoreStore) DeleteUser(ctx context.Context, id string) error {
+	_, err := s.col("users").Doc(docID(id)).Delete(ctx)
+	return err
+}
+
+func (s *FirestoreStore) GetGroupsByUserID(ctx context.Context, userID string) ([]*UserGroup, error) {
+	return s.listUserGroups(ctx, s.col("user_groups").Where("user_id", "==", userID))
+}
+
+func (d firestoreUser) user() *User {
+	return &User{ID: d.ID, Username: d.Username, Email: d.Email, EmailVerified: d.EmailVerified, Status: UserStatus(d.Status),
+		Meta: d.Meta, CreatedAt: d.CreatedAt, UpdatedAt: d.UpdatedAt, CreatedBy: d.CreatedBy, UpdatedBy: d.UpdatedBy, TenantID: d.TenantID}
+}
+
+//
+// ---------- PermissionRepo ----------
+//
+
+func (s *FirestoreStore) GetPermissionByID(ctx context.Context, id string) (*Permission, error) {
+	var doc firestorePermission
+	ok, err := getDoc(ctx, s.col("permissions").Doc(docID(id)), &doc)
+	if err != nil || !ok {
+		return nil, err
+	}
+	return doc.permission(), nil
+}
+
+func (s *FirestoreStore) ListAllPermissions(ctx context.Context) ([]*Permission, error) {
+	docs, err := s.col("permissions").Documents(ctx).GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]*Permission, 0, len(docs))
+	for _, d := range docs {
+		var doc firestorePermission
+		if err := d.DataTo(&doc); err != nil {
+			return nil, fmt.Errorf("failed to decode permission: %w", err)
+		}
+		out = append(out, doc.permission())
+	}
+	return out, nil
+}
+
+func (s *FirestoreStore) GetPermissionByResource(ctx context.Context, resource string, action Action) (*Permission, error) {
+	var doc firestorePermission
+	ok, err := first(ctx, s.col("permissions").
+		Where("resource", "==", resource).
+		Where("action", "==", string(action)), &doc)
+	if err != nil || !ok {
+		return nil, err
+	}
+	return doc.permission(), nil
+}
+
+func (s *FirestoreStore) CreatePermission(ctx context.Context, p *Permission) error {
+	if p.ID == "" {
+		p.ID = generateID(s.ids, KindPermission)
+	}
+	p.CreatedAt = time.Now().Unix()
+
+	perms := s.col("permissions")
+	return s.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		existing, err := tx.Documents(perms.
+			Where("resource", "==", p.Resource).
+			Where("action", "==", string(p.Action)).
+			Limit(1)).GetAll()
+		if err != nil {
+			return err
+		}
+		if len(existing) > 0 {
+			var doc firestorePermission
+			if err := existing[0].DataTo(&doc); err != nil {
+				return err
+			}
+			*p = *doc.permission()
+			return nil
+		}
+		return tx.Create(perms.Doc(docID(p.ID)), newFirestorePermission(p))
+	})
+}
+
+func (s *FirestoreStore) DeletePermission(ctx context.Context, id string) error {
+	_, err := s.col("permissions").Doc(docID(id)).Delete(ctx)
+	return err
+}
+
+func newFirestorePermission(p *Permission) firestorePermission {
+	return firestorePermission{
+		ID:          p.ID,
+		Name:        p.Name,
+		Description: p.Description,
+		Labels:      p.Labels,
+		Resource:    p.Resource,
+		Action:      string(p.Action),
+		Effect:      string(p.Effect),
+		Condition:   p.Condition,
+		CreatedAt:   p.CreatedAt,
+		UpdatedAt:   p.UpdatedAt,
+		CreatedBy:   p.CreatedBy,
+		UpdatedBy:   p.UpdatedBy,
+		TenantID:    p.TenantID,
+	}
+}
+
+func (d firestorePermission) permission() *Permission {
+	return &Permission{ID: d.ID, Name: d.Name, Description: d.Description, Labels: d.Labels, Resource: d.Resource, Action: Action(d.Action), Effect: Effect(d.Effect), Condition: d.Condition, CreatedAt: d.CreatedAt,
+		UpdatedAt: d.UpdatedAt, CreatedBy: d.CreatedBy, UpdatedBy: d.UpdatedBy, TenantID: d.TenantID}
+}
+
+//
+// ---------- RoleRepo ----------
+//
+
+func (s *FirestoreStore) CreateRole(ctx context.Context, r *Role) error {
+	if r.ID == "" {
+		r.ID = generateID(s.ids, KindRole)
+	}
+	r.CreatedAt = time.Now().Unix()
+
+	roles := s.col("roles")
+	return s.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		taken, err := tx.Documents(roles.Where("name", "==", r.Name).Limit(1)).GetAll()
+		if err != nil {
+			return err
+		}
+		if len(taken) > 0 {
+			return fmt.Errorf("firestore_store: role %q already exists", r.Name)
+		}
+		doc := firestoreRole{
+			ID:          r.ID,
+			Name:        r.Name,
+			Description: r.Description,
+			Meta:        r.Meta,
+			Template:    r.Template,
+			Priority:    r.Priority,
+			CreatedAt:   r.CreatedAt,
+			UpdatedAt:   r.UpdatedAt,
+			CreatedBy:   r.CreatedBy,
+			UpdatedBy:   r.UpdatedBy,
+			TenantID:    r.TenantID,
+		}
+		for i := range r.Generators {
+			doc.Generators = append(doc.Generators, newFirestorePermission(&r.Generators[i]))
+		}
+		return tx.Create(roles.Doc(docID(r.ID)), doc)
+	})
+}
+
+func (s *FirestoreStore) GetRoleByName(ctx context.Context, name string) (*Role, error) {
+	var doc firestoreRole
+	ok, err := first(ctx, s.col("roles").Where("name", "==", name), &doc)
+	if err != nil || !ok {
+		return nil, err
+	}
+	return doc.role(), nil
+}
+
+func (s *FirestoreStore) GetRoleByID(ctx context.Context, id string) (*Role, error) {
+	var doc firestoreRole
+	ok, err := getDoc(ctx, s.col("roles").Doc(docID(id)), &doc)
+	if err != nil || !ok {
+		return nil, err
+	}
+	return doc.role(), nil
+}
+
+func (s *FirestoreStore) DeleteRole(ctx context.Context, id string) error {
+	_, err := s.col("roles").Doc(docID(id)).Delete(ctx)
+	return err
+}
+
+func (s *FirestoreStore) ListAllRoles(ctx context.Context) ([]*Role, error) {
+	docs, err := s.col("roles").Documents(ctx).GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]*Role, 0, len(docs))
+	for _, d := range docs {
+		var doc firestoreRole
+		if err := d.DataTo(&doc); err != nil {
+			return nil, fmt.Errorf("failed to decode role: %w", err)
+		}
+		out = append(out, doc.role())
+	}
+	return out, nil
+}
+
+func (d firestoreRole) role() *Role {
+	r := &Role{ID: d.ID, Name: d.Name, Description: d.Description, Meta: d.Meta, Template: d.Template, Priority: d.Priority,
+		CreatedAt: d.CreatedAt, UpdatedAt: d.UpdatedAt, CreatedBy: d.CreatedBy, UpdatedBy: d.UpdatedBy, TenantID: d.TenantID}
+	for _, g := range d.Generators {
+		r.Generators = append(r.Generators, *g.permission())
+	}
+	return r
+}
+
+//
+// ---------- RolePermissionRepo ----------
+//
+
+func (s *FirestoreStore) AddRP(ctx context.Context, roleID, permID string) error {
+	_, err := s.col("role_permissions").Doc(docID(roleID, permID)).Set(ctx, firestoreRolePermission{
+		RoleID:       roleID,
+		PermissionID: permID,
+		CreatedAt:    time.Now().Unix(),
+	})
+	return err
+}
+
+func (s *FirestoreStore) Remove(ctx context.Context, roleID, permID string) error {
+	_, err := s.col("role_permissions").Doc(docID(roleID, permID)).Delete(ctx)
+	return err
+}
+
+func (s *FirestoreStore) ListPermissions(ctx context.Context, roleID string) ([]string, error) {
+	docs, err := s.col("role_permissions").
+		Where("role_id", "==", roleID).
+		OrderBy("created_at", firestore.Asc).
+		Documents(ctx).GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]string, 0, len(docs))
+	for _, d := range docs {
+		var rec firestoreRolePermission
+		if err := d.DataTo(&rec); err != nil {
+			return nil, err
+		}
+		out = append(out, rec.PermissionID)
+	}
+	return out, nil
+}
+
+//
+// ---------- UserRoleRepo ----------
+//
+
+func (s *FirestoreStore) AddUR(ctx context.Context, userID, roleID string) error {
+	_, err := s.col("user_roles").Doc(docID(userID, roleID)).Set(ctx, firestoreUserRole{
+		UserID:     userID,
+		RoleID:     roleID,
+		AssignedAt: time.Now().Unix(),
+	})
+	return err
+}
+
+func (s *FirestoreStore) RemoveUR(ctx context.Context, userID, roleID string) error {
+	_, err := s.col("user_roles").Doc(docID(userID, roleID)).Delete(ctx)
+	return err
+}
+
+func (s *FirestoreStore) ListRoles(ctx context.Context, userID string) ([]string, error) {
+	docs, err := s.col("user_roles").
+		Where("user_id", "==", userID).
+		OrderBy("assigned_at", firestore.Asc).
+		Documents(ctx).GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]string, 0, len(docs))
+	for _, d := range docs {
+		var rec firestoreUserRole
+		if err := d.DataTo(&rec); err != nil {
+			return nil, err
+		}
+		out = append(out, rec.RoleID)
+	}
+	return out, nil
+}
+
+// ListUsersForRole filters on role_id alone, which Firestore's automatic
+// single-field index serves, and sorts in memory so no composite index is
+// needed.
+func (s *FirestoreStore) ListUsersForRole(ctx context.Context, roleID string) ([]string, error) {
+	docs, err := s.col("user_roles").Where("role_id", "==", roleID).Documents(ctx).GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]string, 0, len(docs))
+	for _, d := range docs {
+		var rec firestoreUserRole
+		if err := d.DataTo(&rec); err != nil {
+			return nil, err
+		}
+		out = append(out, rec.UserID)
+	}
+	sort.Strings(out)
+	return out, nil
+}
+
+//
+// ---------- UserGroupRepo ----------
+//
+
+func (s *FirestoreStore) AddUserToGroup(ctx context.Context, ug *UserGroup) error {
+	if ug.UserID == "" {
+		return errors.New("user id is empty")
+	}
+
+	if ug.ID == "" {
+		ug.ID = generateID(s.ids, KindUserGroup)
+	}
+	ug.CreatedAt = time.Now().Unix()
+
+	_, err := s.col("user_groups").Doc(docID(ug.UserID, ug.GroupName)).Set(ctx, firestoreUserGroup{
+		ID:        ug.ID,
+		GroupName: ug.GroupName,
+		UserID:    ug.UserID,
+		CreatedAt: ug.CreatedAt,
+		UpdatedAt: ug.UpdatedAt,
+		CreatedBy: ug.CreatedBy,
+		UpdatedBy: ug.UpdatedBy,
+		Level:     string(ug.Level),
+		ExpiresAt: ug.ExpiresAt,
+		TenantID:  ug.TenantID,
+	})
+	return err
+}
+
+func (s *FirestoreStore) RemoveUserFromGroup(ctx context.Context, groupName string, ug *UserGroup) error {
+	if ug.UserID == "" {
+		return errors.New("user id is empty")
+	}
+
+	_, err := s.col("user_groups").Doc(docID(ug.UserID, groupName)).Delete(ctx)
+	return err
+}
+
+func (s *FirestoreStore) GetUsersByGroupID(ctx context.Context, groupName string) ([]*UserGroup, error) {
+	return s.listUserGroups(ctx, s.col("user_groups").Where("group_name", "==", groupName))
+}
+
+func (s *FirestoreStore) listUserGroups(ctx context.Context, q firestore.Query) ([]*UserGroup, error) {
+	docs, err := q.OrderBy("created_at", firestore.Asc).Documents(ctx).GetAll()
+	if err != nil {
+		return nil, err
+	}
+	return userGroupDocs(docs)
+}
+
+// ListExpiringMemberships filters on expires_at alone, which Firestore's
+// single-field index serves without a composite one.
+func (s *FirestoreStore) ListExpiringMemberships(ctx context.Context, after, before int64) ([]*UserGroup, error) {
+	docs, err := s.col("user_groups").Where("expires_at", ">", after).Where("expires_at", "<=", before).Documents(ctx).GetAll()
+	if err != nil {
+		return nil, err
+	}
+	return userGroupDocs(docs)
+}
+
+func userGroupDocs(docs []*firestore.DocumentSnapshot) ([]*UserGroup, error) {
+	var out []*UserGroup
+	for _, d := range docs {
+		var doc firestoreUserGroup
+		if err := d.DataTo(&doc); err != nil {
+			return nil, err
+		}
+		out = append(out, &UserGroup{ID: doc.ID, GroupName: doc.GroupName, UserID: doc.UserID, CreatedAt: doc.CreatedAt,
+			UpdatedAt: doc.UpdatedAt, CreatedBy: doc.CreatedBy, UpdatedBy: doc.UpdatedBy, Level: MembershipLevel(doc.Level),
+			ExpiresAt: doc.ExpiresAt, TenantID: doc.TenantID})
+	}
+	return out, nil
+}
+
+//
+// ---------- GroupRoleRepo ----------
+//
+
+func (s *FirestoreStore) AddRoleToGroup(ctx context.Context, groupID, roleID string) error {
+	_, err := s.col("group_roles").Doc(docID(groupID, roleID)).Set(ctx, firestoreGroupRole{
+		GroupName: groupID,
+		RoleID:    roleID,
+		CreatedAt: time.Now().Unix(),
+	})
+	return err
+}
+
+func (s *FirestoreStore) RemoveRoleFromGroup(ctx context.Context, groupID, roleID string) error {
+	_, err := s.col("group_roles").Doc(docID(groupID, roleID)).Delete(ctx)
+	return err
+}
+
+func (s *FirestoreStore) ListRolesForGroup(ctx context.Context, groupID string) ([]string, error) {
+	docs, err := s.col("group_roles").
+		Where("group_name", "==", groupID).
+		OrderBy("created_at", firestore.Asc).
+		Documents(ctx).GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]string, 0, len(docs))
+	for _, d := range docs {
+		var rec firestoreGroupRole
+		if err := d.DataTo(&rec); err != nil {
+			return nil, err
+		}
+		out = append(out, rec.RoleID)
+	}
+	return out, nil
+}
+
+func (s *FirestoreStore) ListGroupsForRole(ctx context.Context, roleID string) ([]string, error) {
+	docs, err := s.col("group_roles").Where("role_id", "==", roleID).Documents(ctx).GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]string, 0, len(docs))
+	for _, d := range docs {
+		var rec firestoreGroupRole
+		if err := d.DataTo(&rec); err != nil {
+			return nil, err
+		}
+		out = append(out, rec.GroupName)
+	}
+	sort.Strings(out)
+	return out, nil
+}
@@ -0,0 +1,78 @@
+package rbac
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"cloud.google.com/go/firestore"
+	tcfirestore "github.com/testcontainers/testcontainers-go/modules/gcloud/firestore"
+	"google.golang.org/api/option"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// emulatorCreds authenticates against the Firestore emulator.
+type emulatorCreds struct{}
+
+func (emulatorCreds) GetRequestMetadata(context.Context, ...string) (map[string]string, error) {
+	return map[string]string{"authorization": "Bearer owner"}, nil
+}
+
+func (emulatorCreds) RequireTransportSecurity() bool { return false }
+
+func newFirestoreStore(t *testing.T) *FirestoreStore {
+	t.Helper()
+	ctx := context.Background()
+
+	ctr, err := tcfirestore.Run(ctx, "gcr.io/google.com/cloudsdktool/cloud-sdk:367.0.0-emulators",
+		tcfirestore.WithProjectID("rbac-test"))
+	if err != nil {
+		t.Fatalf("start firestore emulator: %v", err)
+	}
+	t.Cleanup(func() { _ = ctr.Terminate(ctx) })
+
+	conn, err := grpc.NewClient(ctr.URI(),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithPerRPCCredentials(emulatorCreds{}))
+	if err != nil {
+		t.Fatalf("grpc.NewClient: %v", err)
+	}
+
+	client, err := firestore.NewClient(ctx, ctr.ProjectID(), option.WithGRPCConn(conn))
+	if err != nil {
+		t.Fatalf("firestore.NewClient: %v", err)
+	}
+	t.Cleanup(func() { _ = client.Close() })
+
+	store, err := NewFirestoreStore(ctx, client)
+	if err != nil {
+		t.Fatalf("NewFirestoreStore: %v", err)
+	}
+	return store
+}
+
+func TestFirestoreStore(t *testing.T) {
+	s := newFirestoreStore(t)
+	runSuite(t, s)
+}
+
+func TestFirestoreIndexGcloudCommand(t *testing.T) {
+	got := FirestoreIndex{Collection: "user_roles", Fields: []string{"user_id", "assigned_at"}}.GcloudCommand()
+	want := "gcloud firestore indexes composite create --collection-group=user_roles" +
+		" --field-config=field-path=user_id,order=ascending" +
+		" --field-config=field-path=assigned_at,order=ascending"
+	if got != want {
+		t.Errorf("GcloudCommand() = %q, want %q", got, want)
+	}
+}
+
+func TestFirestoreDocID(t *testing.T) {
+	// IDs containing the separator or a slash must not collide or nest.
+	if a, b := docID("a|b", "c"), docID("a", "b|c"); a == b {
+		t.Errorf("docID collision: %q", a)
+	}
+	if id := docID("role/1", "perm"); strings.Contains(id, "/") {
+		t.Errorf("docID %q contains a path separator", id)
+	}
+}
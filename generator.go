@@ -0,0 +1,56 @@
+package rbac
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Seann-Moser/rbac/rbaceval"
+)
+
+// ErrInvalidGenerator is returned by CreateRole for a generator whose
+// resource template or condition does not parse.
+var ErrInvalidGenerator = errors.New("rbac: invalid role generator")
+
+// checkGenerators validates a role's generators.
+func checkGenerators(r *Role) error {
+	for _, g := range r.Generators {
+		if g.Resource == "" || g.Action == "" {
+			return fmt.Errorf("%w: resource and action are required", ErrInvalidGenerator)
+		}
+		if err := rbaceval.ParseTemplate(g.Resource); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidGenerator, err)
+		}
+		if g.Condition != "" {
+			if _, err := rbaceval.ParseCondition(g.Condition); err != nil {
+				return fmt.Errorf("%w: %v", ErrInvalidGenerator, err)
+			}
+		}
+	}
+	return nil
+}
+
+// generatedPermissions expands the role's generators for the user being
+// checked. Generators whose placeholders have no usable value are dropped,
+// so a user without meta.team gets nothing from "teams/{team}/**".
+func (m *Manager) generatedPermissions(ctx context.Context, start time.Time, role *Role, vars func() map[string]any) []*Permission {
+	if len(role.Generators) == 0 {
+		return nil
+	}
+	out := make([]*Permission, 0, len(role.Generators))
+	for _, g := range role.Generators {
+		resource, ok, err := rbaceval.ExpandTemplate(g.Resource, vars())
+		if err != nil {
+			m.record(ctx, start, "Can", err)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		p := g
+		p.Resource = resource
+		out = append(out, &p)
+	}
+	return out
+}
@@ -0,0 +1,61 @@
+package rbac
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestRoleGenerators(t *testing.T) {
+	ctx := context.Background()
+	mgr, err := NewMemoryStoreManager(ctx, "", 0)
+	if err != nil {
+		t.Fatalf("NewMemoryStoreManager: %v", err)
+	}
+
+	if err := mgr.CreateRole(ctx, &Role{Name: "broken", Generators: []Permission{{Resource: "teams/{team/**", Action: ActionRead}}}); !errors.Is(err, ErrInvalidGenerator) {
+		t.Fatalf("expected an unclosed placeholder to fail with ErrInvalidGenerator, got %v", err)
+	}
+	member := &Role{Name: "team-member", Generators: []Permission{
+		{Resource: "teams/{team}/**", Action: ActionRead},
+		{Resource: "teams/{team}/secrets/*", Action: ActionAll, Effect: EffectDeny},
+	}}
+	if err := mgr.CreateRole(ctx, member); err != nil {
+		t.Fatalf("CreateRole: %v", err)
+	}
+
+	users := map[string]map[string]interface{}{
+		"alice":   {"team": "alpha"},
+		"bob":     nil,
+		"mallory": {"team": "*"},
+	}
+	for name, meta := range users {
+		u := &User{ID: name, Username: name, Meta: meta}
+		if err := mgr.CreateUser(ctx, u); err != nil {
+			t.Fatalf("CreateUser(%s): %v", name, err)
+		}
+		if err := mgr.AssignRoleToUser(ctx, name, member.ID); err != nil {
+			t.Fatalf("AssignRoleToUser(%s): %v", name, err)
+		}
+	}
+
+	cases := []struct {
+		user, resource string
+		want           bool
+	}{
+		{"alice", "teams/alpha/docs/readme", true},
+		{"alice", "teams/beta/docs/readme", false},
+		{"alice", "teams/alpha/secrets/key", false},
+		{"bob", "teams/alpha/docs/readme", false},
+		{"mallory", "teams/alpha/docs/readme", false},
+	}
+	for _, c := range cases {
+		ok, err := mgr.Can(ctx, c.user, c.resource, ActionRead)
+		if err != nil {
+			t.Fatalf("Can(%s, %s): %v", c.user, c.resource, err)
+		}
+		if ok != c.want {
+			t.Errorf("Can(%s, %s) = %v, want %v", c.user, c.resource, ok, c.want)
+		}
+	}
+}
@@ -0,0 +1,264 @@
+package rbac
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// GraphFormat selects the output of Manager.WriteGraph.
+type GraphFormat string
+
+const (
+	GraphDOT     GraphFormat = "dot"     // Graphviz
+	GraphMermaid GraphFormat = "mermaid" // Mermaid flowchart
+)
+
+// GraphFilter narrows the graph written by Manager.WriteGraph.
+type GraphFilter struct {
+	// UserIDs limits the graph to these users, their groups and whatever
+	// they can reach. Stores cannot enumerate users, so without it the
+	// graph has every role and its permissions but no users or groups.
+	UserIDs []string
+	// ResourcePrefix keeps only permissions whose resource starts with it,
+	// and the roles, groups and users that lead to one.
+	ResourcePrefix string
+}
+
+type graphNode struct {
+	key   string // kind + ":" + id, unique across kinds
+	kind  string
+	label string
+}
+
+type rbacGraph struct {
+	nodes map[string]*graphNode
+	edges map[string][]string // from key -> to keys
+}
+
+// WriteGraph writes the user–group–role–permission graph in the given format
+// so complex policies can be rendered and reviewed.
+func (m *Manager) WriteGraph(ctx context.Context, w io.Writer, format GraphFormat, filter GraphFilter) error {
+	start := time.Now()
+	ctx, span := m.startSpan(ctx, "WriteGraph")
+	defer span.End()
+	err := func() error {
+		if format != GraphDOT && format != GraphMermaid {
+			return fmt.Errorf("rbac: unknown graph format %q", format)
+		}
+		g, err := m.buildGraph(ctx, filter)
+		if err != nil {
+			return err
+		}
+		if format == GraphDOT {
+			return g.writeDOT(w)
+		}
+		return g.writeMermaid(w)
+	}()
+	m.record(ctx, start, "WriteGraph", err)
+	return err
+}
+
+func (m *Manager) buildGraph(ctx context.Context, filter GraphFilter) (*rbacGraph, error) {
+	g := &rbacGraph{nodes: map[string]*graphNode{}, edges: map[string][]string{}}
+
+	// Role -> permission edges for the roles in scope.
+	var roleIDs []string
+	if len(filter.UserIDs) == 0 {
+		roles, err := m.Roles.ListAllRoles(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range roles {
+			roleIDs = append(roleIDs, r.ID)
+		}
+	} else {
+		for _, uid := range filter.UserIDs {
+			user := g.node("user", uid, uid)
+			if u, err := m.Users.GetUserByID(ctx, uid); err == nil && u != nil && u.Username != "" {
+				user.label = u.Username
+			}
+			// the roles come from the resolver Can uses, which reports how
+			// the user holds each
+			ex := &explainer{}
+			rctx := context.WithValue(withoutRequestContext(ctx), explainerKey{}, ex)
+			_, groups, err := m.resolveRoles(rctx, nil, time.Now(), "WriteGraph", uid, "")
+			if err != nil {
+				return nil, err
+			}
+			for _, ug := range groups {
+				group := g.node("group", ug.GroupName, ug.GroupName)
+				g.edge(user.key, group.key)
+			}
+			for _, rg := range ex.roles {
+				switch rg.Via {
+				case ViaDirect, ViaDelegation:
+					g.edge(user.key, "role:"+rg.RoleID)
+				case ViaGroup:
+					g.edge("group:"+rg.Group, "role:"+rg.RoleID)
+				default:
+					continue
+				}
+				roleIDs = append(roleIDs, rg.RoleID)
+			}
+		}
+	}
+
+	for _, rid := range roleIDs {
+		if _, ok := g.nodes["role:"+rid]; ok {
+			continue
+		}
+		role := g.node("role", rid, rid)
+		if r, err := m.Roles.GetRoleByID(ctx, rid); err == nil && r != nil && r.Name != "" {
+			role.label = r.Name
+		}
+		perms, err := m.rolePermissions(ctx, time.Now(), rid)
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range perms {
+			if !strings.HasPrefix(p.Resource, filter.ResourcePrefix) {
+				continue
+			}
+			label := p.Resource + " " + string(p.Action)
+			if p.Effect == EffectDeny {
+				label = "deny " + label
+			}
+			perm := g.node("perm", p.ID, label)
+			g.edge(role.key, perm.key)
+		}
+	}
+
+	if filter.ResourcePrefix != "" {
+		g.pruneToPermissions()
+	}
+	return g, nil
+}
+
+func (g *rbacGraph) node(kind, id, label string) *graphNode {
+	key := kind + ":" + id
+	n, ok := g.nodes[key]
+	if !ok {
+		n = &graphNode{key: key, kind: kind, label: label}
+		g.nodes[key] = n
+	}
+	return n
+}
+
+func (g *rbacGraph) edge(from, to string) {
+	for _, e := range g.edges[from] {
+		if e == to {
+			return
+		}
+	}
+	g.edges[from] = append(g.edges[from], to)
+}
+
+// pruneToPermissions drops every node that does not lead to a permission.
+func (g *rbacGraph) pruneToPermissions() {
+	keep := map[string]bool{}
+	var reaches func(key string) bool
+	reaches = func(key string) bool {
+		if v, ok := keep[key]; ok {
+			return v
+		}
+		keep[key] = false // guards against cycles
+		ok := g.nodes[key] != nil && g.nodes[key].kind == "perm"
+		for _, to := range g.edges[key] {
+			if reaches(to) {
+				ok = true
+			}
+		}
+		keep[key] = ok
+		return ok
+	}
+	for key := range g.nodes {
+		reaches(key)
+	}
+	for key := range g.nodes {
+		if !keep[key] {
+			delete(g.nodes, key)
+			delete(g.edges, key)
+		}
+	}
+	for from, tos := range g.edges {
+		kept := tos[:0]
+		for _, to := range tos {
+			if keep[to] {
+				kept = append(kept, to)
+			}
+		}
+		g.edges[from] = kept
+	}
+}
+
+// sorted returns the nodes ordered by kind, then label, with stable
+// identifiers n0, n1, ... for the writers.
+func (g *rbacGraph) sorted() ([]*graphNode, map[string]string) {
+	rank := map[string]int{"user": 0, "group": 1, "role": 2, "perm": 3}
+	nodes := make([]*graphNode, 0, len(g.nodes))
+	for _, n := range g.nodes {
+		nodes = append(nodes, n)
+	}
+	sort.Slice(nodes, func(i, j int) bool {
+		a, b := nodes[i], nodes[j]
+		if rank[a.kind] != rank[b.kind] {
+			return rank[a.kind] < rank[b.kind]
+		}
+		if a.label != b.label {
+			return a.label < b.label
+		}
+		return a.key < b.key
+	})
+	ids := make(map[string]string, len(nodes))
+	for i, n := range nodes {
+		ids[n.key] = fmt.Sprintf("n%d", i)
+	}
+	return nodes, ids
+}
+
+func (g *rbacGraph) writeDOT(w io.Writer) error {
+	shapes := map[string]string{"user": "ellipse", "group": "folder", "role": "box", "perm": "note"}
+	nodes, ids := g.sorted()
+
+	var b strings.Builder
+	b.WriteString("digraph rbac {\n  rankdir=LR;\n")
+	for _, n := range nodes {
+		fmt.Fprintf(&b, "  %s [label=%q, shape=%s];\n", ids[n.key], n.kind+": "+n.label, shapes[n.kind])
+	}
+	for _, n := range nodes {
+		for _, to := range g.edges[n.key] {
+			if id, ok := ids[to]; ok {
+				fmt.Fprintf(&b, "  %s -> %s;\n", ids[n.key], id)
+			}
+		}
+	}
+	b.WriteString("}\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func (g *rbacGraph) writeMermaid(w io.Writer) error {
+	shapes := map[string][2]string{"user": {"([", "])"}, "group": {"[[", "]]"}, "role": {"[", "]"}, "perm": {"{{", "}}"}}
+	escape := strings.NewReplacer(`"`, "#quot;", "\n", " ")
+	nodes, ids := g.sorted()
+
+	var b strings.Builder
+	b.WriteString("flowchart LR\n")
+	for _, n := range nodes {
+		s := shapes[n.kind]
+		fmt.Fprintf(&b, "  %s%s\"%s: %s\"%s\n", ids[n.key], s[0], n.kind, escape.Replace(n.label), s[1])
+	}
+	for _, n := range nodes {
+		for _, to := range g.edges[n.key] {
+			if id, ok := ids[to]; ok {
+				fmt.Fprintf(&b, "  %s --> %s\n", ids[n.key], id)
+			}
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,98 @@
+package rbac
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteGraph(t *testing.T) {
+	ctx := context.Background()
+	mgr, err := NewMemoryStoreManager(ctx, "", 0)
+	if err != nil {
+		t.Fatalf("NewMemoryStoreManager: %v", err)
+	}
+	for _, p := range []*Permission{
+		{ID: "p-survey", Resource: "survey", Action: ActionRead},
+		{ID: "p-billing", Resource: "billing", Action: ActionUpdate},
+	} {
+		if err := mgr.CreatePermission(ctx, p); err != nil {
+			t.Fatalf("CreatePermission: %v", err)
+		}
+	}
+	for _, r := range []*Role{{ID: "r-reader", Name: "reader"}, {ID: "r-billing", Name: "billing-admin"}} {
+		if err := mgr.CreateRole(ctx, r); err != nil {
+			t.Fatalf("CreateRole: %v", err)
+		}
+	}
+	_ = mgr.AssignPermissionToRole(ctx, "r-reader", "p-survey")
+	_ = mgr.AssignPermissionToRole(ctx, "r-billing", "p-billing")
+	if err := mgr.CreateUser(ctx, &User{ID: "u1", Username: "alice"}); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	_ = mgr.AssignRoleToUser(ctx, "u1", "r-reader")
+	_ = mgr.AddUserToGroup(ctx, &UserGroup{UserID: "u1", GroupName: "finance"})
+	_ = mgr.AssignRoleToGroup(ctx, "finance", "r-billing")
+	// an expired membership is not drawn; a delegated role is
+	_ = mgr.AddUserToGroup(ctx, &UserGroup{UserID: "u1", GroupName: "alumni", ExpiresAt: time.Now().Add(-time.Hour).Unix()})
+	if err := mgr.CreateRole(ctx, &Role{ID: "r-approver", Name: "approver"}); err != nil {
+		t.Fatalf("CreateRole: %v", err)
+	}
+	_ = mgr.AssignRoleToUser(ctx, "u2", "r-approver")
+	if _, err := mgr.DelegateRole(ctx, "u2", "u1", "r-approver", time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("DelegateRole: %v", err)
+	}
+
+	write := func(format GraphFormat, filter GraphFilter) string {
+		t.Helper()
+		var buf bytes.Buffer
+		if err := mgr.WriteGraph(ctx, &buf, format, filter); err != nil {
+			t.Fatalf("WriteGraph: %v", err)
+		}
+		return buf.String()
+	}
+
+	dot := write(GraphDOT, GraphFilter{UserIDs: []string{"u1"}})
+	for _, want := range []string{
+		"digraph rbac {",
+		`[label="user: alice", shape=ellipse]`,
+		`[label="group: finance", shape=folder]`,
+		`[label="role: billing-admin", shape=box]`,
+		`[label="perm: survey read", shape=note]`,
+		`[label="role: approver", shape=box]`,
+	} {
+		if !strings.Contains(dot, want) {
+			t.Errorf("DOT output missing %q:\n%s", want, dot)
+		}
+	}
+	if strings.Contains(dot, "alumni") {
+		t.Errorf("expected the expired membership to be left out:\n%s", dot)
+	}
+	if again := write(GraphDOT, GraphFilter{UserIDs: []string{"u1"}}); again != dot {
+		t.Error("expected deterministic output")
+	}
+
+	mermaid := write(GraphMermaid, GraphFilter{UserIDs: []string{"u1"}, ResourcePrefix: "bill"})
+	if !strings.HasPrefix(mermaid, "flowchart LR\n") {
+		t.Errorf("unexpected Mermaid header:\n%s", mermaid)
+	}
+	for _, want := range []string{`"user: alice"`, `"group: finance"`, `"perm: billing update"`, "-->"} {
+		if !strings.Contains(mermaid, want) {
+			t.Errorf("Mermaid output missing %q:\n%s", want, mermaid)
+		}
+	}
+	if strings.Contains(mermaid, "reader") || strings.Contains(mermaid, "survey") {
+		t.Errorf("expected the prefix filter to drop the reader branch:\n%s", mermaid)
+	}
+
+	// Without users the graph covers every role.
+	if all := write(GraphDOT, GraphFilter{}); !strings.Contains(all, "role: reader") || strings.Contains(all, "user:") {
+		t.Errorf("expected roles only:\n%s", all)
+	}
+
+	if err := mgr.WriteGraph(ctx, &bytes.Buffer{}, "svg", GraphFilter{}); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+}
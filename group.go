@@ -0,0 +1,496 @@
+package rbac
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// GroupRepo stores groups. Lookups of a missing group return nil, nil.
+// Group names are unique per store.
+type GroupRepo interface {
+	CreateGroup(ctx context.Context, g *Group) error
+	// UpdateGroup replaces the stored group with g.ID.
+	UpdateGroup(ctx context.Context, g *Group) error
+	DeleteGroup(ctx context.Context, id string) error
+	GetGroupByID(ctx context.Context, id string) (*Group, error)
+	GetGroupByName(ctx context.Context, name string) (*Group, error)
+	ListGroups(ctx context.Context) ([]*Group, error)
+}
+
+var (
+	// ErrGroupNotFound is returned for a group ID that does not exist.
+	ErrGroupNotFound = errors.New("rbac: group not found")
+	// ErrGroupExists is returned when creating or renaming a group to a
+	// name that is already taken.
+	ErrGroupExists = errors.New("rbac: group name already taken")
+
+	errNoGroupRepo = errors.New("rbac: no GroupRepo configured")
+)
+
+// CreateGroup stores g. Groups are optional: users can still join, and roles
+// be assigned to, a group name that has no Group, but creating one first
+// gives it an ID, an owner and a safe rename.
+func (m *Manager) CreateGroup(ctx context.Context, g *Group) error {
+	start := time.Now()
+	ctx, span := m.startSpan(ctx, "CreateGroup")
+	defer span.End()
+	err := m.createGroup(ctx, g)
+	m.record(ctx, start, "CreateGroup", err)
+	m.changed(ctx, "CreateGroup", g.ID, err)
+	return err
+}
+
+func (m *Manager) createGroup(ctx context.Context, g *Group) error {
+	if m.Groups == nil {
+		return errNoGroupRepo
+	}
+	if g.Name == "" {
+		return errors.New("rbac: group name is empty")
+	}
+	if existing, err := m.Groups.GetGroupByName(ctx, g.Name); err != nil {
+		return err
+	} else if existing != nil {
+		return fmt.Errorf("%w: %q", ErrGroupExists, g.Name)
+	}
+	if err := m.checkDefaultRoles(ctx, g.DefaultRoles); err != nil {
+		return err
+	}
+	m.assignID(&g.ID, KindGroup)
+	return m.Groups.CreateGroup(ctx, g)
+}
+
+func (m *Manager) GetGroup(ctx context.Context, id string) (*Group, error) {
+	start := time.Now()
+	ctx, span := m.startSpan(ctx, "GetGroup")
+	defer span.End()
+	var (
+		g   *Group
+		err = errNoGroupRepo
+	)
+	if m.Groups != nil {
+		g, err = m.Groups.GetGroupByID(ctx, id)
+	}
+	m.record(ctx, start, "GetGroup", err)
+	return g, err
+}
+
+func (m *Manager) GetGroupByName(ctx context.Context, name string) (*Group, error) {
+	start := time.Now()
+	ctx, span := m.startSpan(ctx, "GetGroupByName")
+	defer span.End()
+	var (
+		g   *Group
+		err = errNoGroupRepo
+	)
+	if m.Groups != nil {
+		g, err = m.Groups.GetGroupByName(ctx, name)
+	}
+	m.record(ctx, start, "GetGroupByName", err)
+	return g, err
+}
+
+func (m *Manager) ListGroups(ctx context.Context) ([]*Group, error) {
+	start := time.Now()
+	ctx, span := m.startSpan(ctx, "ListGroups")
+	defer span.End()
+	var (
+		list []*Group
+		err  = errNoGroupRepo
+	)
+	if m.Groups != nil {
+		list, err = m.Groups.ListGroups(ctx)
+	}
+	m.record(ctx, start, "ListGroups", err)
+	return list, err
+}
+
+// UpdateGroup saves g's description, metadata, owner and default roles. Its
+// name must be unchanged; use RenameGroup, which also moves memberships and
+// roles. Current members gain the added default roles and lose the removed
+// ones.
+func (m *Manager) UpdateGroup(ctx context.Context, g *Group) error {
+	start := time.Now()
+	ctx, span := m.startSpan(ctx, "UpdateGroup")
+	defer span.End()
+	err := m.updateGroup(ctx, g)
+	m.record(ctx, start, "UpdateGroup", err)
+	m.changed(ctx, "UpdateGroup", g.ID, err)
+	return err
+}
+
+func (m *Manager) updateGroup(ctx context.Context, g *Group) error {
+	cur, err := m.storedGroup(ctx, g.ID)
+	if err != nil {
+		return err
+	}
+	if g.Name != cur.Name {
+		return errors.New("rbac: UpdateGroup cannot change a group's name; use RenameGroup")
+	}
+	if err := m.checkDefaultRoles(ctx, g.DefaultRoles); err != nil {
+		return err
+	}
+	g.CreatedAt = cur.CreatedAt
+	if err := m.Groups.UpdateGroup(ctx, g); err != nil {
+		return err
+	}
+
+	added, removed := diffRoles(cur.DefaultRoles, g.DefaultRoles)
+	if len(added) == 0 && len(removed) == 0 {
+		return nil
+	}
+	members, err := m.UG.GetUsersByGroupID(ctx, g.Name)
+	if err != nil {
+		return err
+	}
+	for _, ug := range members {
+		if err := m.grantRoles(ctx, ug, added); err != nil {
+			return err
+		}
+		if err := m.revokeDefaultRoles(ctx, ug.UserID, removed); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RenameGroup renames a group and moves its memberships and role bindings,
+// scoped ones included, to the new name, keeping their assignment sources.
+// With a store that supports transactions the move is atomic.
+func (m *Manager) RenameGroup(ctx context.Context, id, newName string) error {
+	start := time.Now()
+	ctx, span := m.startSpan(ctx, "RenameGroup")
+	defer span.End()
+	err := m.inTransaction(ctx, func(ctx context.Context) error {
+		return m.renameGroup(ctx, id, newName)
+	})
+	m.record(ctx, start, "RenameGroup", err)
+	m.changed(ctx, "RenameGroup", id, err)
+	return err
+}
+
+func (m *Manager) renameGroup(ctx context.Context, id, newName string) error {
+	g, err := m.storedGroup(ctx, id)
+	if err != nil {
+		return err
+	}
+	if newName == "" {
+		return errors.New("rbac: group name is empty")
+	}
+	if newName == g.Name {
+		return nil
+	}
+	if existing, err := m.Groups.GetGroupByName(ctx, newName); err != nil {
+		return err
+	} else if existing != nil {
+		return fmt.Errorf("%w: %q", ErrGroupExists, newName)
+	}
+	old := g.Name
+
+	members, err := m.UG.GetUsersByGroupID(ctx, old)
+	if err != nil {
+		return err
+	}
+	for _, ug := range members {
+		sctx := m.sourceContext(ctx, KindUserGroup, ug.UserID, old)
+		if err := m.UG.RemoveUserFromGroup(ctx, old, ug); err != nil {
+			return err
+		}
+		moved := *ug
+		moved.GroupName = newName
+		stampUpdated(ctx, time.Now(), &moved.UpdatedBy, &moved.UpdatedAt)
+		if err := m.UG.AddUserToGroup(sctx, &moved); err != nil {
+			return err
+		}
+	}
+
+	if m.GR != nil {
+		roles, err := m.GR.ListRolesForGroup(ctx, old)
+		if err != nil {
+			return err
+		}
+		for _, roleID := range roles {
+			sctx := m.sourceContext(ctx, KindGroupRole, old, roleID)
+			if err := m.GR.RemoveRoleFromGroup(ctx, old, roleID); err != nil {
+				return err
+			}
+			if err := m.GR.AddRoleToGroup(sctx, newName, roleID); err != nil {
+				return err
+			}
+		}
+		if scoped, ok := m.GR.(ScopedGroupRoleRepo); ok {
+			list, err := scoped.ListScopedRolesForGroup(ctx, old)
+			if err != nil && !errors.Is(err, errScopeUnsupported) {
+				return err
+			}
+			for _, sr := range list {
+				if err := scoped.RemoveScopedRoleFromGroup(ctx, old, sr.RoleID, sr.Scope); err != nil {
+					return err
+				}
+				if err := scoped.AddScopedRoleToGroup(ctx, newName, sr.RoleID, sr.Scope); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	g.Name = newName
+	return m.Groups.UpdateGroup(ctx, g)
+}
+
+// DeleteGroup deletes a group together with its memberships and role
+// bindings.
+func (m *Manager) DeleteGroup(ctx context.Context, id string) error {
+	start := time.Now()
+	ctx, span := m.startSpan(ctx, "DeleteGroup")
+	defer span.End()
+	err := m.inTransaction(ctx, func(ctx context.Context) error {
+		return m.deleteGroup(ctx, id)
+	})
+	m.record(ctx, start, "DeleteGroup", err)
+	m.changed(ctx, "DeleteGroup", id, err)
+	return err
+}
+
+func (m *Manager) deleteGroup(ctx context.Context, id string) error {
+	g, err := m.storedGroup(ctx, id)
+	if err != nil {
+		return err
+	}
+	members, err := m.UG.GetUsersByGroupID(ctx, g.Name)
+	if err != nil {
+		return err
+	}
+	for _, ug := range members {
+		if err := m.UG.RemoveUserFromGroup(ctx, g.Name, ug); err != nil {
+			return err
+		}
+		if err := m.revokeGroupDefaults(ctx, ug.UserID, g.Name, g); err != nil {
+			return err
+		}
+	}
+	if m.GR != nil {
+		roles, err := m.GR.ListRolesForGroup(ctx, g.Name)
+		if err != nil {
+			return err
+		}
+		for _, roleID := range roles {
+			if err := m.GR.RemoveRoleFromGroup(ctx, g.Name, roleID); err != nil {
+				return err
+			}
+		}
+		if scoped, ok := m.GR.(ScopedGroupRoleRepo); ok {
+			list, err := scoped.ListScopedRolesForGroup(ctx, g.Name)
+			if err != nil && !errors.Is(err, errScopeUnsupported) {
+				return err
+			}
+			for _, sr := range list {
+				if err := scoped.RemoveScopedRoleFromGroup(ctx, g.Name, sr.RoleID, sr.Scope); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return m.Groups.DeleteGroup(ctx, id)
+}
+
+//
+// ---------- Default roles ----------
+//
+
+// checkDefaultRoles fails when one of roles does not exist or is a
+// template.
+func (m *Manager) checkDefaultRoles(ctx context.Context, roles []string) error {
+	for _, id := range roles {
+		r, err := m.Roles.GetRoleByID(ctx, id)
+		if err != nil {
+			return err
+		}
+		if r == nil {
+			return fmt.Errorf("rbac: default role %q not found", id)
+		}
+		if r.Template {
+			return fmt.Errorf("%w: %q", ErrTemplateRole, r.Name)
+		}
+	}
+	return nil
+}
+
+// grantGroupDefaults grants a new member the DefaultRoles of their group.
+func (m *Manager) grantGroupDefaults(ctx context.Context, ug *UserGroup) error {
+	if m.Groups == nil {
+		return nil
+	}
+	g, err := m.Groups.GetGroupByName(ctx, ug.GroupName)
+	if err != nil || g == nil {
+		return err
+	}
+	return m.grantRoles(ctx, ug, g.DefaultRoles)
+}
+
+// grantRoles grants roles to ug's user as SourceGroupDefault, lapsing with
+// the membership when it expires and the user role repo can schedule.
+// Roles the user already holds are left alone, except that a default grant
+// is extended to cover the membership.
+func (m *Manager) grantRoles(ctx context.Context, ug *UserGroup, roles []string) error {
+	if len(roles) == 0 {
+		return nil
+	}
+	ctx = WithAssignmentSource(ctx, SourceGroupDefault)
+	scheduler, canSchedule := m.UR.(ScheduledUserRoleRepo)
+	held := map[string]*RoleAssignment{}
+	if canSchedule {
+		list, err := scheduler.ListRoleAssignments(ctx, ug.UserID)
+		if err != nil {
+			return err
+		}
+		for _, a := range list {
+			held[a.RoleID] = a
+		}
+	} else {
+		list, err := m.UR.ListRoles(ctx, ug.UserID)
+		if err != nil {
+			return err
+		}
+		for _, id := range list {
+			held[id] = &RoleAssignment{UserID: ug.UserID, RoleID: id}
+		}
+	}
+
+	for _, roleID := range roles {
+		a, ok := held[roleID]
+		switch {
+		case !ok && canSchedule && ug.ExpiresAt != 0:
+			err := scheduler.AddScheduledUR(ctx, &RoleAssignment{UserID: ug.UserID, RoleID: roleID, ExpiresAt: ug.ExpiresAt})
+			if err != nil {
+				return err
+			}
+		case !ok:
+			if err := m.UR.AddUR(ctx, ug.UserID, roleID); err != nil {
+				return err
+			}
+		case canSchedule && a.ExpiresAt != 0 && (ug.ExpiresAt == 0 || ug.ExpiresAt > a.ExpiresAt):
+			src, err := m.EdgeSource(ctx, KindUserRole, ug.UserID, roleID)
+			if err != nil && !errors.Is(err, errSourceUnsupported) {
+				return err
+			}
+			if src != SourceGroupDefault {
+				continue
+			}
+			ext := *a
+			ext.ExpiresAt = ug.ExpiresAt
+			if err := scheduler.AddScheduledUR(ctx, &ext); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// revokeGroupDefaults revokes the DefaultRoles of a group the user left. g
+// is the group when the caller already has it.
+func (m *Manager) revokeGroupDefaults(ctx context.Context, userID, groupName string, g *Group) error {
+	if m.Groups == nil {
+		return nil
+	}
+	if g == nil {
+		var err error
+		if g, err = m.Groups.GetGroupByName(ctx, groupName); err != nil || g == nil {
+			return err
+		}
+	}
+	return m.revokeDefaultRoles(ctx, userID, g.DefaultRoles)
+}
+
+// revokeDefaultRoles revokes roles granted to the user as group defaults,
+// keeping those a remaining group of theirs still grants. With a user role
+// repo that does not track sources every such role is revoked.
+func (m *Manager) revokeDefaultRoles(ctx context.Context, userID string, roles []string) error {
+	if len(roles) == 0 {
+		return nil
+	}
+	still := map[string]bool{}
+	groups, err := m.UG.GetGroupsByUserID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	for _, ug := range activeMemberships(groups, time.Now()) {
+		g, err := m.Groups.GetGroupByName(ctx, ug.GroupName)
+		if err != nil {
+			return err
+		}
+		if g != nil {
+			for _, id := range g.DefaultRoles {
+				still[id] = true
+			}
+		}
+	}
+
+	for _, roleID := range roles {
+		if still[roleID] {
+			continue
+		}
+		src, err := m.EdgeSource(ctx, KindUserRole, userID, roleID)
+		switch {
+		case errors.Is(err, errSourceUnsupported):
+		case err != nil:
+			return err
+		case src != SourceGroupDefault:
+			continue
+		}
+		if err := m.UR.RemoveUR(ctx, userID, roleID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// diffRoles returns the roles in next but not prev, and in prev but not next.
+func diffRoles(prev, next []string) (added, removed []string) {
+	for _, id := range next {
+		if !slices.Contains(prev, id) {
+			added = append(added, id)
+		}
+	}
+	for _, id := range prev {
+		if !slices.Contains(next, id) {
+			removed = append(removed, id)
+		}
+	}
+	return added, removed
+}
+
+// storedGroup returns the stored group id or ErrGroupNotFound.
+func (m *Manager) storedGroup(ctx context.Context, id string) (*Group, error) {
+	if m.Groups == nil {
+		return nil, errNoGroupRepo
+	}
+	g, err := m.Groups.GetGroupByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if g == nil {
+		return nil, fmt.Errorf("%w: %q", ErrGroupNotFound, id)
+	}
+	return g, nil
+}
+
+// sourceContext returns ctx attributed to the source managing an edge, so a
+// re-created edge keeps it.
+func (m *Manager) sourceContext(ctx context.Context, kind, from, to string) context.Context {
+	if src, err := m.EdgeSource(ctx, kind, from, to); err == nil && src != "" {
+		return WithAssignmentSource(ctx, src)
+	}
+	return ctx
+}
+
+// inTransaction runs fn in a transaction when the store supports them, and
+// directly otherwise.
+func (m *Manager) inTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if tx, ok := m.Perms.(Transactor); ok {
+		return tx.WithTransaction(ctx, fn)
+	}
+	return fn(ctx)
+}
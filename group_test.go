@@ -0,0 +1,291 @@
+package rbac
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestGroupLifecycle(t *testing.T) {
+	ctx := context.Background()
+	mgr, err := NewMemoryStoreManager(ctx, "", 0)
+	if err != nil {
+		t.Fatalf("NewMemoryStoreManager: %v", err)
+	}
+
+	writers := &Group{Name: "writers", Description: "Docs team", Owner: "alice"}
+	if err := mgr.CreateGroup(ctx, writers); err != nil {
+		t.Fatalf("CreateGroup: %v", err)
+	}
+	if writers.ID == "" {
+		t.Fatal("expected CreateGroup to assign an ID")
+	}
+	if err := mgr.CreateGroup(ctx, &Group{Name: "writers"}); !errors.Is(err, ErrGroupExists) {
+		t.Fatalf("expected a duplicate name to fail with ErrGroupExists, got %v", err)
+	}
+	if err := mgr.CreateGroup(ctx, &Group{Name: "readers"}); err != nil {
+		t.Fatalf("CreateGroup(readers): %v", err)
+	}
+	if list, err := mgr.ListGroups(ctx); err != nil || len(list) != 2 {
+		t.Fatalf("ListGroups = %v, %v; want 2 groups", list, err)
+	}
+
+	role := &Role{Name: "editor"}
+	if err := mgr.CreateRole(ctx, role); err != nil {
+		t.Fatalf("CreateRole: %v", err)
+	}
+	perm := &Permission{Resource: "docs/*", Action: ActionUpdate}
+	if err := mgr.CreatePermission(ctx, perm); err != nil {
+		t.Fatalf("CreatePermission: %v", err)
+	}
+	if err := mgr.AssignPermissionToRole(ctx, role.ID, perm.ID); err != nil {
+		t.Fatalf("AssignPermissionToRole: %v", err)
+	}
+	if err := mgr.AssignRoleToGroup(ctx, "writers", role.ID); err != nil {
+		t.Fatalf("AssignRoleToGroup: %v", err)
+	}
+	if err := mgr.AssignScopedRoleToGroup(ctx, "writers", role.ID, "wiki/*"); err != nil {
+		t.Fatalf("AssignScopedRoleToGroup: %v", err)
+	}
+	bob := &User{Username: "bob"}
+	if err := mgr.CreateUser(ctx, bob); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	if err := mgr.AddUserToGroup(ctx, &UserGroup{UserID: bob.ID, GroupName: "writers"}); err != nil {
+		t.Fatalf("AddUserToGroup: %v", err)
+	}
+
+	update := *writers
+	update.Description = "Documentation team"
+	if err := mgr.UpdateGroup(ctx, &update); err != nil {
+		t.Fatalf("UpdateGroup: %v", err)
+	}
+	update.Name = "authors"
+	if err := mgr.UpdateGroup(ctx, &update); err == nil {
+		t.Fatal("expected UpdateGroup to refuse a rename")
+	}
+
+	if err := mgr.RenameGroup(ctx, writers.ID, "readers"); !errors.Is(err, ErrGroupExists) {
+		t.Fatalf("expected renaming onto a taken name to fail, got %v", err)
+	}
+	if err := mgr.RenameGroup(ctx, writers.ID, "authors"); err != nil {
+		t.Fatalf("RenameGroup: %v", err)
+	}
+	got, err := mgr.GetGroup(ctx, writers.ID)
+	if err != nil || got == nil || got.Name != "authors" || got.Description != "Documentation team" {
+		t.Fatalf("GetGroup = %+v, %v", got, err)
+	}
+	if old, _ := mgr.GetGroupByName(ctx, "writers"); old != nil {
+		t.Error("expected the old name to be free after the rename")
+	}
+	if groups, _ := mgr.GetGroupsByUserID(ctx, bob.ID); len(groups) != 1 || groups[0].GroupName != "authors" {
+		t.Errorf("expected bob's membership to move, got %+v", groups)
+	}
+	if roles, _ := mgr.ListRolesForGroup(ctx, "authors"); len(roles) != 1 || roles[0] != role.ID {
+		t.Errorf("expected the group's roles to move, got %v", roles)
+	}
+	if scoped, _ := mgr.ListScopedRolesForGroup(ctx, "authors"); len(scoped) != 1 {
+		t.Errorf("expected the group's scoped roles to move, got %v", scoped)
+	}
+	if ok, err := mgr.Can(ctx, bob.ID, "docs/readme", ActionUpdate); err != nil || !ok {
+		t.Errorf("Can after rename = %v, %v; want true", ok, err)
+	}
+
+	if err := mgr.DeleteGroup(ctx, writers.ID); err != nil {
+		t.Fatalf("DeleteGroup: %v", err)
+	}
+	if g, _ := mgr.GetGroup(ctx, writers.ID); g != nil {
+		t.Error("expected the group to be gone")
+	}
+	if groups, _ := mgr.GetGroupsByUserID(ctx, bob.ID); len(groups) != 0 {
+		t.Errorf("expected memberships to be removed, got %+v", groups)
+	}
+	if roles, _ := mgr.ListRolesForGroup(ctx, "authors"); len(roles) != 0 {
+		t.Errorf("expected role bindings to be removed, got %v", roles)
+	}
+	if ok, _ := mgr.Can(ctx, bob.ID, "docs/readme", ActionUpdate); ok {
+		t.Error("expected access through the deleted group to end")
+	}
+	if err := mgr.DeleteGroup(ctx, writers.ID); !errors.Is(err, ErrGroupNotFound) {
+		t.Errorf("expected ErrGroupNotFound, got %v", err)
+	}
+}
+
+func TestGroupsPerTenant(t *testing.T) {
+	ctx := context.Background()
+	mgr, err := NewMemoryStoreManager(ctx, "", 0)
+	if err != nil {
+		t.Fatalf("NewMemoryStoreManager: %v", err)
+	}
+	acme, globex := mgr.ForTenant("acme"), mgr.ForTenant("globex")
+
+	a, g := &Group{Name: "writers"}, &Group{Name: "writers"}
+	if err := acme.CreateGroup(ctx, a); err != nil {
+		t.Fatalf("acme CreateGroup: %v", err)
+	}
+	if err := globex.CreateGroup(ctx, g); err != nil {
+		t.Fatalf("globex CreateGroup: %v", err)
+	}
+	if got, _ := acme.GetGroupByName(ctx, "writers"); got == nil || got.ID != a.ID || got.Name != "writers" {
+		t.Fatalf("acme GetGroupByName = %+v", got)
+	}
+	if got, _ := globex.GetGroup(ctx, a.ID); got != nil {
+		t.Error("expected another tenant's group to be invisible")
+	}
+	if err := globex.DeleteGroup(ctx, a.ID); !errors.Is(err, ErrGroupNotFound) {
+		t.Errorf("expected deleting another tenant's group to fail, got %v", err)
+	}
+	if list, _ := acme.ListGroups(ctx); len(list) != 1 {
+		t.Errorf("expected acme to list only its group, got %v", list)
+	}
+}
+
+func TestGroupsWithoutRepo(t *testing.T) {
+	mgr := NewMockRepoManager(NewMockRepo())
+	mgr.Groups = nil
+	if err := mgr.CreateGroup(context.Background(), &Group{Name: "writers"}); err == nil {
+		t.Fatal("expected CreateGroup to fail without a GroupRepo")
+	}
+}
+
+func TestGroupDefaultRoles(t *testing.T) {
+	ctx := context.Background()
+	mgr, err := NewMemoryStoreManager(ctx, "", 0)
+	if err != nil {
+		t.Fatalf("NewMemoryStoreManager: %v", err)
+	}
+	roles := map[string]*Role{}
+	for _, name := range []string{"viewer", "commenter", "reviewer"} {
+		r := &Role{Name: name}
+		if err := mgr.CreateRole(ctx, r); err != nil {
+			t.Fatalf("CreateRole(%s): %v", name, err)
+		}
+		roles[name] = r
+	}
+	viewer, commenter, reviewer := roles["viewer"].ID, roles["commenter"].ID, roles["reviewer"].ID
+
+	if err := mgr.CreateGroup(ctx, &Group{Name: "broken", DefaultRoles: []string{"missing"}}); err == nil {
+		t.Fatal("expected an unknown default role to be rejected")
+	}
+	staff := &Group{Name: "staff", DefaultRoles: []string{viewer, commenter}}
+	if err := mgr.CreateGroup(ctx, staff); err != nil {
+		t.Fatalf("CreateGroup(staff): %v", err)
+	}
+	if err := mgr.CreateGroup(ctx, &Group{Name: "readers", DefaultRoles: []string{viewer}}); err != nil {
+		t.Fatalf("CreateGroup(readers): %v", err)
+	}
+
+	has := func(userID, roleID string) bool {
+		t.Helper()
+		held, err := mgr.ListRolesForUser(ctx, userID)
+		if err != nil {
+			t.Fatalf("ListRolesForUser: %v", err)
+		}
+		return slices.Contains(held, roleID)
+	}
+
+	if err := mgr.AssignRoleToUser(ctx, "bob", commenter); err != nil {
+		t.Fatalf("AssignRoleToUser: %v", err)
+	}
+	for _, g := range []string{"staff", "readers"} {
+		if err := mgr.AddUserToGroup(ctx, &UserGroup{UserID: "bob", GroupName: g}); err != nil {
+			t.Fatalf("AddUserToGroup(%s): %v", g, err)
+		}
+	}
+	if !has("bob", viewer) || !has("bob", commenter) {
+		t.Fatal("expected joining staff to grant its default roles")
+	}
+	if src, _ := mgr.EdgeSource(ctx, KindUserRole, "bob", viewer); src != SourceGroupDefault {
+		t.Errorf("expected the default grant to be attributed to %s, got %q", SourceGroupDefault, src)
+	}
+
+	if err := mgr.RemoveUserFromGroup(ctx, "staff", &UserGroup{UserID: "bob", GroupName: "staff"}); err != nil {
+		t.Fatalf("RemoveUserFromGroup(staff): %v", err)
+	}
+	if !has("bob", viewer) {
+		t.Error("expected viewer to stay while readers still grants it")
+	}
+	if !has("bob", commenter) {
+		t.Error("expected the manual commenter assignment to survive leaving staff")
+	}
+	if err := mgr.RemoveUserFromGroup(ctx, "readers", &UserGroup{UserID: "bob", GroupName: "readers"}); err != nil {
+		t.Fatalf("RemoveUserFromGroup(readers): %v", err)
+	}
+	if has("bob", viewer) {
+		t.Error("expected viewer to be revoked once no group grants it")
+	}
+
+	if err := mgr.AddUserToGroup(ctx, &UserGroup{UserID: "carol", GroupName: "staff"}); err != nil {
+		t.Fatalf("AddUserToGroup(carol): %v", err)
+	}
+	staff.DefaultRoles = []string{viewer, reviewer}
+	if err := mgr.UpdateGroup(ctx, staff); err != nil {
+		t.Fatalf("UpdateGroup: %v", err)
+	}
+	if !has("carol", reviewer) || has("carol", commenter) {
+		t.Error("expected current members to follow the updated default roles")
+	}
+
+	expires := time.Now().Add(time.Hour).Unix()
+	if err := mgr.AddUserToGroup(ctx, &UserGroup{UserID: "dave", GroupName: "readers", ExpiresAt: expires}); err != nil {
+		t.Fatalf("AddUserToGroup(dave): %v", err)
+	}
+	as, err := mgr.ListRoleAssignments(ctx, "dave")
+	if err != nil || len(as) != 1 || as[0].RoleID != viewer || as[0].ExpiresAt != expires {
+		t.Errorf("expected dave's default role to lapse with the membership, got %+v, %v", as, err)
+	}
+
+	if err := mgr.DeleteGroup(ctx, staff.ID); err != nil {
+		t.Fatalf("DeleteGroup: %v", err)
+	}
+	if has("carol", viewer) || has("carol", reviewer) {
+		t.Error("expected deleting the group to revoke its default roles")
+	}
+}
+
+func TestHasPermissionGroupRoles(t *testing.T) {
+	ctx := context.Background()
+	memory, err := NewMemoryStoreManager(ctx, "", 0)
+	if err != nil {
+		t.Fatalf("NewMemoryStoreManager: %v", err)
+	}
+	for name, mgr := range map[string]*Manager{
+		"memory": memory,
+		"mock":   NewMockRepoManager(NewMockRepo()),
+	} {
+		t.Run(name, func(t *testing.T) {
+			for _, r := range []*Role{{ID: "reader", Name: "reader"}, {ID: "writer", Name: "writer"}} {
+				if err := mgr.CreateRole(ctx, r); err != nil {
+					t.Fatalf("CreateRole: %v", err)
+				}
+			}
+			read, err := mgr.AssignPermissionToRoleByResource(ctx, "reader", "docs/*", ActionRead)
+			if err != nil {
+				t.Fatalf("AssignPermissionToRoleByResource: %v", err)
+			}
+			if err := mgr.AddRoleParent(ctx, "writer", "reader"); err != nil {
+				t.Fatalf("AddRoleParent: %v", err)
+			}
+			if err := mgr.AssignRoleToGroup(ctx, "writers", "writer"); err != nil {
+				t.Fatalf("AssignRoleToGroup: %v", err)
+			}
+			if ok, err := mgr.HasPermission(ctx, "bob", read.ID); err != nil || ok {
+				t.Fatalf("HasPermission(bob) = %v, %v before joining; want false", ok, err)
+			}
+			if err := mgr.AddUserToGroup(ctx, &UserGroup{UserID: "bob", GroupName: "writers"}); err != nil {
+				t.Fatalf("AddUserToGroup: %v", err)
+			}
+
+			// the group's role, and the role it inherits, count as for Can
+			can, err := mgr.Can(ctx, "bob", "docs/1", ActionRead)
+			if err != nil || !can {
+				t.Fatalf("Can(bob) = %v, %v; want true", can, err)
+			}
+			if ok, err := mgr.HasPermission(ctx, "bob", read.ID); err != nil || !ok {
+				t.Errorf("HasPermission(bob) = %v, %v; want true like Can", ok, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,183 @@
+package rbac
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// GroupBan keeps UserID out of GroupName, e.g. a contractor out of a group
+// synced from a directory, until it is lifted.
+type GroupBan struct {
+	GroupName string `bson:"group_name" json:"group_name"`
+	UserID    string `bson:"user_id" json:"user_id"`
+	Reason    string `bson:"reason,omitempty" json:"reason,omitempty"`
+	CreatedAt int64  `bson:"created_at" json:"created_at"`
+}
+
+// GroupBanRepo is optionally implemented by a UserGroupRepo that stores
+// group bans.
+type GroupBanRepo interface {
+	// AddGroupBan creates or replaces the ban of b.UserID from b.GroupName.
+	AddGroupBan(ctx context.Context, b *GroupBan) error
+	RemoveGroupBan(ctx context.Context, groupName, userID string) error
+	// ListGroupBans returns the bans from the group.
+	ListGroupBans(ctx context.Context, groupName string) ([]*GroupBan, error)
+	// ListUserBans returns the user's bans.
+	ListUserBans(ctx context.Context, userID string) ([]*GroupBan, error)
+}
+
+// ErrBannedFromGroup is returned when adding a user to a group they are
+// banned from.
+var ErrBannedFromGroup = errors.New("rbac: user is banned from the group")
+
+var errBansUnsupported = errors.New("rbac: repo does not support group bans")
+
+// BanUserFromGroup removes the user from the group, whoever manages the
+// membership, and refuses to add them back until UnbanUserFromGroup. A
+// membership written to the store some other way, such as by an import,
+// is ignored by GetUsersByGroupID, Can and sessions.
+func (m *Manager) BanUserFromGroup(ctx context.Context, groupName, userID, reason string) error {
+	start := time.Now()
+	ctx, span := m.startSpan(ctx, "BanUserFromGroup")
+	defer span.End()
+	err := m.banUserFromGroup(ctx, start, groupName, userID, reason)
+	m.record(ctx, start, "BanUserFromGroup", err)
+	m.changedUser(ctx, "BanUserFromGroup", userID, err)
+	return err
+}
+
+func (m *Manager) banUserFromGroup(ctx context.Context, now time.Time, groupName, userID, reason string) error {
+	repo, ok := m.UG.(GroupBanRepo)
+	if !ok {
+		return errBansUnsupported
+	}
+	if groupName == "" || userID == "" {
+		return errors.New("rbac: group ban needs a group and a user")
+	}
+	err := repo.AddGroupBan(ctx, &GroupBan{GroupName: groupName, UserID: userID, Reason: reason, CreatedAt: now.Unix()})
+	if err != nil {
+		return err
+	}
+	groups, err := m.UG.GetGroupsByUserID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	for _, ug := range groups {
+		if ug.GroupName != groupName {
+			continue
+		}
+		if err := m.UG.RemoveUserFromGroup(ctx, groupName, ug); err != nil {
+			return err
+		}
+		return m.revokeGroupDefaults(ctx, userID, groupName, nil)
+	}
+	return nil
+}
+
+// UnbanUserFromGroup lifts a ban. The user is not added back.
+func (m *Manager) UnbanUserFromGroup(ctx context.Context, groupName, userID string) error {
+	start := time.Now()
+	ctx, span := m.startSpan(ctx, "UnbanUserFromGroup")
+	defer span.End()
+	err := errBansUnsupported
+	if repo, ok := m.UG.(GroupBanRepo); ok {
+		err = repo.RemoveGroupBan(ctx, groupName, userID)
+	}
+	m.record(ctx, start, "UnbanUserFromGroup", err)
+	m.changedUser(ctx, "UnbanUserFromGroup", userID, err)
+	return err
+}
+
+// ListGroupBans returns the bans from a group.
+func (m *Manager) ListGroupBans(ctx context.Context, groupName string) ([]*GroupBan, error) {
+	start := time.Now()
+	ctx, span := m.startSpan(ctx, "ListGroupBans")
+	defer span.End()
+	var (
+		out []*GroupBan
+		err = errBansUnsupported
+	)
+	if repo, ok := m.UG.(GroupBanRepo); ok {
+		out, err = repo.ListGroupBans(ctx, groupName)
+	}
+	m.record(ctx, start, "ListGroupBans", err)
+	return out, err
+}
+
+// checkBan returns ErrBannedFromGroup when ug's user is banned from its
+// group.
+func (m *Manager) checkBan(ctx context.Context, ug *UserGroup) error {
+	repo, ok := m.UG.(GroupBanRepo)
+	if !ok {
+		return nil
+	}
+	bans, err := repo.ListUserBans(ctx, ug.UserID)
+	if err != nil && !errors.Is(err, errBansUnsupported) {
+		return err
+	}
+	for _, b := range bans {
+		if b.GroupName == ug.GroupName {
+			return fmt.Errorf("%w: %s from %s", ErrBannedFromGroup, ug.UserID, ug.GroupName)
+		}
+	}
+	return nil
+}
+
+// withoutBannedGroups drops the memberships of userID in groups they are
+// banned from. When the bans cannot be read it returns no groups, so a
+// banned user does not keep a group's roles.
+func (m *Manager) withoutBannedGroups(ctx context.Context, userID string, groups []*UserGroup) ([]*UserGroup, error) {
+	repo, ok := m.UG.(GroupBanRepo)
+	if !ok || len(groups) == 0 {
+		return groups, nil
+	}
+	bans, err := repo.ListUserBans(ctx, userID)
+	if errors.Is(err, errBansUnsupported) {
+		return groups, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(bans) == 0 {
+		return groups, nil
+	}
+	banned := map[string]bool{}
+	for _, b := range bans {
+		banned[b.GroupName] = true
+	}
+	out := groups[:0:0]
+	for _, ug := range groups {
+		if !banned[ug.GroupName] {
+			out = append(out, ug)
+		}
+	}
+	return out, nil
+}
+
+// withoutBannedMembers drops the memberships of groupName's banned users.
+func (m *Manager) withoutBannedMembers(ctx context.Context, groupName string, members []*UserGroup) ([]*UserGroup, error) {
+	repo, ok := m.UG.(GroupBanRepo)
+	if !ok || len(members) == 0 {
+		return members, nil
+	}
+	bans, err := repo.ListGroupBans(ctx, groupName)
+	if errors.Is(err, errBansUnsupported) {
+		return members, nil
+	}
+	if err != nil || len(bans) == 0 {
+		return members, err
+	}
+	banned := map[string]bool{}
+	for _, b := range bans {
+		banned[b.UserID] = true
+	}
+	out := members[:0:0]
+	for _, ug := range members {
+		if !banned[ug.UserID] {
+			out = append(out, ug)
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,75 @@
+package rbac
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestGroupBans(t *testing.T) {
+	ctx := context.Background()
+	memory, err := NewMemoryStoreManager(ctx, "", 0)
+	if err != nil {
+		t.Fatalf("NewMemoryStoreManager: %v", err)
+	}
+	for name, mgr := range map[string]*Manager{"memory": memory, "mock": NewMockRepoManager(NewMockRepo())} {
+		t.Run(name, func(t *testing.T) {
+			role := &Role{Name: "engineer"}
+			if err := mgr.CreateRole(ctx, role); err != nil {
+				t.Fatalf("CreateRole: %v", err)
+			}
+			perm := &Permission{Resource: "repos/*", Action: ActionRead}
+			if err := mgr.CreatePermission(ctx, perm); err != nil {
+				t.Fatalf("CreatePermission: %v", err)
+			}
+			if err := mgr.AssignPermissionToRole(ctx, role.ID, perm.ID); err != nil {
+				t.Fatalf("AssignPermissionToRole: %v", err)
+			}
+			if err := mgr.AssignRoleToGroup(ctx, "eng", role.ID); err != nil {
+				t.Fatalf("AssignRoleToGroup: %v", err)
+			}
+			for _, u := range []string{"alice", "eve"} {
+				if err := mgr.AddUserToGroup(ctx, &UserGroup{UserID: u, GroupName: "eng"}); err != nil {
+					t.Fatalf("AddUserToGroup: %v", err)
+				}
+			}
+
+			if err := mgr.BanUserFromGroup(ctx, "eng", "eve", "contractor"); err != nil {
+				t.Fatalf("BanUserFromGroup: %v", err)
+			}
+			if members, err := mgr.GetUsersByGroupID(ctx, "eng"); err != nil || len(members) != 1 || members[0].UserID != "alice" {
+				t.Errorf("expected only alice in eng, got %v, %v", members, err)
+			}
+			if ok, _ := mgr.Can(ctx, "eve", "repos/api", ActionRead); ok {
+				t.Error("expected the banned user to lose the group's roles")
+			}
+			if err := mgr.AddUserToGroup(ctx, &UserGroup{UserID: "eve", GroupName: "eng"}); !errors.Is(err, ErrBannedFromGroup) {
+				t.Errorf("re-adding a banned user: expected ErrBannedFromGroup, got %v", err)
+			}
+
+			// a membership written straight to the store is still ignored
+			if err := mgr.UG.AddUserToGroup(ctx, &UserGroup{UserID: "eve", GroupName: "eng"}); err != nil {
+				t.Fatalf("UG.AddUserToGroup: %v", err)
+			}
+			if members, _ := mgr.GetUsersByGroupID(ctx, "eng"); len(members) != 1 {
+				t.Errorf("expected the banned membership to be filtered, got %v", members)
+			}
+			if ok, _ := mgr.Can(ctx, "eve", "repos/api", ActionRead); ok {
+				t.Error("expected role resolution to ignore the banned membership")
+			}
+			if bans, err := mgr.ListGroupBans(ctx, "eng"); err != nil || len(bans) != 1 || bans[0].Reason != "contractor" {
+				t.Errorf("ListGroupBans: got %v, %v", bans, err)
+			}
+
+			if err := mgr.UnbanUserFromGroup(ctx, "eng", "eve"); err != nil {
+				t.Fatalf("UnbanUserFromGroup: %v", err)
+			}
+			if ok, _ := mgr.Can(ctx, "eve", "repos/api", ActionRead); !ok {
+				t.Error("expected the membership to apply again once the ban is lifted")
+			}
+			if err := mgr.AddUserToGroup(ctx, &UserGroup{UserID: "eve", GroupName: "eng"}); err != nil {
+				t.Errorf("AddUserToGroup after unban: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package rbac
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+)
+
+// HealthChecker is implemented by stores that can check they are able to
+// serve requests, e.g. that the database answers and has the indexes the
+// store relies on.
+type HealthChecker interface {
+	// Health returns nil when the store is ready.
+	Health(ctx context.Context) error
+}
+
+// ErrStoreUnhealthy is returned by Manager.Health when a store failed its
+// check.
+var ErrStoreUnhealthy = errors.New("rbac: store unhealthy")
+
+// StoreHealth is the outcome of one store's health check.
+type StoreHealth struct {
+	// Store is the store's Go type, e.g. *rbac.MongoStore.
+	Store   string        `json:"store"`
+	Healthy bool          `json:"healthy"`
+	Error   string        `json:"error,omitempty"`
+	Latency time.Duration `json:"latency_ns"`
+}
+
+// HealthReport is the outcome of Manager.Health.
+type HealthReport struct {
+	Healthy bool          `json:"healthy"`
+	Stores  []StoreHealth `json:"stores"`
+}
+
+// Health checks each distinct store behind the Manager's repos once, in the
+// order Perms, Roles, Users, RP, UR, UG, GR. Stores that implement
+// HealthChecker check themselves; others are probed with a by-ID role
+// lookup, as FailoverStore does. When one fails, the report is returned
+// with an error wrapping ErrStoreUnhealthy and the stores' errors.
+func (m *Manager) Health(ctx context.Context) (*HealthReport, error) {
+	start := time.Now()
+	ctx, span := m.startSpan(ctx, "Health")
+	defer span.End()
+	report := &HealthReport{Healthy: true, Stores: []StoreHealth{}}
+	var errs []error
+	for _, repo := range distinctRepos(m.Perms, m.Roles, m.Users, m.RP, m.UR, m.UG, m.GR) {
+		callStart := time.Now()
+		err := checkHealth(ctx, repo)
+		h := StoreHealth{Store: fmt.Sprintf("%T", repo), Healthy: err == nil, Latency: time.Since(callStart)}
+		if err != nil {
+			h.Error = err.Error()
+			report.Healthy = false
+			errs = append(errs, err)
+		}
+		report.Stores = append(report.Stores, h)
+	}
+	var err error
+	if len(errs) > 0 {
+		err = fmt.Errorf("%w: %w", ErrStoreUnhealthy, errors.Join(errs...))
+	}
+	m.record(ctx, start, "Health", err)
+	return report, err
+}
+
+// checkHealth runs the HealthChecker of s, or looks up a role by ID when s
+// has none.
+func checkHealth(ctx context.Context, s any) error {
+	switch s := s.(type) {
+	case HealthChecker:
+		return s.Health(ctx)
+	case RoleRepo:
+		_, err := s.GetRoleByID(ctx, "")
+		return err
+	}
+	return nil
+}
+
+// distinctRepos returns the non-nil repos, each store once.
+func distinctRepos(repos ...any) []any {
+	var out []any
+	seen := map[any]bool{}
+	for _, r := range repos {
+		if r == nil {
+			continue
+		}
+		if reflect.TypeOf(r).Comparable() {
+			if seen[r] {
+				continue
+			}
+			seen[r] = true
+		}
+		out = append(out, r)
+	}
+	return out
+}
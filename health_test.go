@@ -0,0 +1,55 @@
+package rbac
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// unhealthyStore is a MockRepo whose health check fails with err.
+type unhealthyStore struct {
+	*MockRepo
+	err error
+}
+
+func (s *unhealthyStore) Health(context.Context) error { return s.err }
+
+func TestManagerHealth(t *testing.T) {
+	ctx := context.Background()
+	mgr := NewMockRepoManager(NewMockRepo())
+	report, err := mgr.Health(ctx)
+	if err != nil || !report.Healthy || len(report.Stores) != 1 || report.Stores[0].Store != "*rbac.MockRepo" {
+		t.Fatalf("Health = %+v, %v; want one healthy *rbac.MockRepo", report, err)
+	}
+
+	down := errors.New("connection refused")
+	bad := &unhealthyStore{MockRepo: NewMockRepo(), err: down}
+	mgr.Roles = bad
+	report, err = mgr.Health(ctx)
+	if !errors.Is(err, ErrStoreUnhealthy) || !errors.Is(err, down) {
+		t.Errorf("Health error = %v; want ErrStoreUnhealthy wrapping the store's", err)
+	}
+	if report.Healthy || len(report.Stores) != 2 || !report.Stores[0].Healthy ||
+		report.Stores[1].Healthy || report.Stores[1].Error != down.Error() {
+		t.Errorf("Health = %+v; want the mock healthy and the unhealthy store failing", report)
+	}
+
+	// wrappers check the store they wrap
+	if err := NewCachedStore(bad, 0).Health(ctx); !errors.Is(err, down) {
+		t.Errorf("CachedStore.Health = %v; want the inner store's error", err)
+	}
+}
+
+func TestFailoverStoreHealth(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	primary := &unhealthyStore{MockRepo: NewMockRepo(), err: errors.New("primary down")}
+	f := NewFailoverStore(ctx, primary, NewMockRepo(), FailoverConfig{})
+	if err := f.Health(ctx); err != nil {
+		t.Errorf("Health with a working secondary = %v", err)
+	}
+	f = NewFailoverStore(ctx, primary, &unhealthyStore{MockRepo: NewMockRepo(), err: errors.New("secondary down")}, FailoverConfig{})
+	if err := f.Health(ctx); err == nil {
+		t.Error("Health with both stores down succeeded")
+	}
+}
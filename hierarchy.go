@@ -0,0 +1,124 @@
+package rbac
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// RoleHierarchyRepo is optionally implemented by a RoleRepo that stores
+// inheritance between roles. A role inherits every permission of its
+// parents, and of their parents in turn, so for admin → editor → viewer the
+// parent of admin is editor and the parent of editor is viewer.
+type RoleHierarchyRepo interface {
+	// AddRoleParent makes roleID inherit the permissions of parentID.
+	AddRoleParent(ctx context.Context, roleID, parentID string) error
+	RemoveRoleParent(ctx context.Context, roleID, parentID string) error
+	// ListRoleParents returns the direct parents of roleID.
+	ListRoleParents(ctx context.Context, roleID string) ([]string, error)
+}
+
+// ErrRoleCycle is returned by AddRoleParent when the new parent already
+// inherits from the role.
+var ErrRoleCycle = errors.New("rbac: role inheritance cycle")
+
+var errHierarchyUnsupported = errors.New("rbac: role repo does not support role hierarchy")
+
+// AddRoleParent makes roleID inherit the permissions of parentID. It fails
+// with ErrRoleCycle when parentID is roleID or already inherits from it.
+func (m *Manager) AddRoleParent(ctx context.Context, roleID, parentID string) error {
+	start := time.Now()
+	ctx, span := m.startSpan(ctx, "AddRoleParent")
+	defer span.End()
+	err := m.addRoleParent(ctx, roleID, parentID)
+	m.record(ctx, start, "AddRoleParent", err)
+	m.changedRole(ctx, "AddRoleParent", roleID, err)
+	m.emit(err, func(l Listener) { l.OnRoleParentAdded(ctx, roleID, parentID) })
+	return err
+}
+
+func (m *Manager) addRoleParent(ctx context.Context, roleID, parentID string) error {
+	repo, ok := m.Roles.(RoleHierarchyRepo)
+	if !ok {
+		return errHierarchyUnsupported
+	}
+	ancestors, err := m.expandRoles(ctx, []string{parentID})
+	if err != nil {
+		return err
+	}
+	for _, id := range ancestors {
+		if id == roleID {
+			return fmt.Errorf("%w: %s already inherits from %s", ErrRoleCycle, parentID, roleID)
+		}
+	}
+	return repo.AddRoleParent(ctx, roleID, parentID)
+}
+
+// RemoveRoleParent stops roleID inheriting from parentID.
+func (m *Manager) RemoveRoleParent(ctx context.Context, roleID, parentID string) error {
+	start := time.Now()
+	ctx, span := m.startSpan(ctx, "RemoveRoleParent")
+	defer span.End()
+	err := errHierarchyUnsupported
+	if repo, ok := m.Roles.(RoleHierarchyRepo); ok {
+		err = repo.RemoveRoleParent(ctx, roleID, parentID)
+	}
+	m.record(ctx, start, "RemoveRoleParent", err)
+	m.changedRole(ctx, "RemoveRoleParent", roleID, err)
+	m.emit(err, func(l Listener) { l.OnRoleParentRemoved(ctx, roleID, parentID) })
+	return err
+}
+
+// ListRoleParents returns the roles roleID inherits from directly.
+func (m *Manager) ListRoleParents(ctx context.Context, roleID string) ([]string, error) {
+	start := time.Now()
+	ctx, span := m.startSpan(ctx, "ListRoleParents")
+	defer span.End()
+	var (
+		out []string
+		err = errHierarchyUnsupported
+	)
+	if repo, ok := m.Roles.(RoleHierarchyRepo); ok {
+		out, err = repo.ListRoleParents(ctx, roleID)
+	}
+	m.record(ctx, start, "ListRoleParents", err)
+	return out, err
+}
+
+// expandRoles returns roleIDs followed by every role they inherit from,
+// without duplicates. Each role is visited once, so cycles written to the
+// store behind the Manager's back cannot loop. Without a RoleHierarchyRepo
+// roleIDs is returned unchanged. On error the roles resolved so far are
+// returned with it.
+func (m *Manager) expandRoles(ctx context.Context, roleIDs []string) ([]string, error) {
+	repo, ok := m.Roles.(RoleHierarchyRepo)
+	if !ok {
+		return roleIDs, nil
+	}
+	seen := make(map[string]bool, len(roleIDs))
+	out := make([]string, 0, len(roleIDs))
+	for _, id := range roleIDs {
+		if !seen[id] {
+			seen[id] = true
+			out = append(out, id)
+		}
+	}
+	for i := 0; i < len(out); i++ {
+		parents, err := repo.ListRoleParents(ctx, out[i])
+		if errors.Is(err, errHierarchyUnsupported) {
+			// A wrapper such as CachedStore over a store without hierarchy.
+			return out, nil
+		}
+		if err != nil {
+			return out, err
+		}
+		for _, p := range parents {
+			if !seen[p] {
+				seen[p] = true
+				out = append(out, p)
+			}
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,89 @@
+package rbac
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRoleHierarchy(t *testing.T) {
+	ctx := context.Background()
+	mgr, err := NewMemoryStoreManager(ctx, "", 0)
+	if err != nil {
+		t.Fatalf("NewMemoryStoreManager: %v", err)
+	}
+	for _, p := range []*Permission{
+		{ID: "p-read", Resource: "doc", Action: ActionRead},
+		{ID: "p-update", Resource: "doc", Action: ActionUpdate},
+		{ID: "p-delete", Resource: "doc", Action: ActionDelete},
+	} {
+		if err := mgr.CreatePermission(ctx, p); err != nil {
+			t.Fatalf("CreatePermission: %v", err)
+		}
+	}
+	for _, r := range []*Role{{ID: "viewer", Name: "viewer"}, {ID: "editor", Name: "editor"}, {ID: "admin", Name: "admin"}} {
+		if err := mgr.CreateRole(ctx, r); err != nil {
+			t.Fatalf("CreateRole: %v", err)
+		}
+	}
+	_ = mgr.AssignPermissionToRole(ctx, "viewer", "p-read")
+	_ = mgr.AssignPermissionToRole(ctx, "editor", "p-update")
+	_ = mgr.AssignPermissionToRole(ctx, "admin", "p-delete")
+	if err := mgr.AddRoleParent(ctx, "admin", "editor"); err != nil {
+		t.Fatalf("AddRoleParent: %v", err)
+	}
+	if err := mgr.AddRoleParent(ctx, "editor", "viewer"); err != nil {
+		t.Fatalf("AddRoleParent: %v", err)
+	}
+	_ = mgr.AssignRoleToUser(ctx, "alice", "admin")
+	_ = mgr.AssignRoleToUser(ctx, "bob", "editor")
+
+	can := func(user string, action Action, want bool) {
+		t.Helper()
+		ok, err := mgr.Can(ctx, user, "doc", action)
+		if err != nil || ok != want {
+			t.Errorf("Can(%s, %s) = %v, %v; want %v", user, action, ok, err, want)
+		}
+	}
+	can("alice", ActionRead, true)
+	can("alice", ActionDelete, true)
+	can("bob", ActionRead, true)
+	can("bob", ActionDelete, false)
+
+	if ok, err := mgr.HasPermission(ctx, "alice", "p-read"); !ok || err != nil {
+		t.Errorf("expected alice to inherit p-read, got %v, %v", ok, err)
+	}
+
+	for _, tc := range [][2]string{{"viewer", "admin"}, {"viewer", "viewer"}} {
+		if err := mgr.AddRoleParent(ctx, tc[0], tc[1]); !errors.Is(err, ErrRoleCycle) {
+			t.Errorf("AddRoleParent(%s, %s): expected ErrRoleCycle, got %v", tc[0], tc[1], err)
+		}
+	}
+
+	// A cycle written directly to the store does not hang resolution.
+	_ = mgr.Roles.(RoleHierarchyRepo).AddRoleParent(ctx, "viewer", "admin")
+	can("bob", ActionDelete, true)
+
+	if err := mgr.RemoveRoleParent(ctx, "editor", "viewer"); err != nil {
+		t.Fatalf("RemoveRoleParent: %v", err)
+	}
+	can("bob", ActionRead, false)
+}
+
+func TestRoleHierarchyCachedWithoutSupport(t *testing.T) {
+	ctx := context.Background()
+	inner := &countingStore{Store: NewMockRepo()}
+	mgr := NewCachedStoreManager(inner, time.Minute)
+	_ = mgr.CreatePermission(ctx, &Permission{ID: "p1", Resource: "doc", Action: ActionRead})
+	_ = mgr.CreateRole(ctx, &Role{ID: "r1", Name: "reader"})
+	_ = mgr.AssignPermissionToRole(ctx, "r1", "p1")
+	_ = mgr.AssignRoleToUser(ctx, "alice", "r1")
+
+	if ok, err := mgr.HasPermission(ctx, "alice", "p1"); !ok || err != nil {
+		t.Errorf("expected HasPermission to ignore the missing hierarchy, got %v, %v", ok, err)
+	}
+	if err := mgr.AddRoleParent(ctx, "r1", "r2"); err == nil {
+		t.Errorf("expected AddRoleParent to fail without hierarchy support")
+	}
+}
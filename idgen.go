@@ -0,0 +1,122 @@
+package rbac
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"math/big"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Entity kinds passed to IDGenerator.NewID so generators can namespace IDs.
+const (
+	KindPermission = "permission"
+	KindRole       = "role"
+	KindUser       = "user"
+	KindUserGroup  = "user_group"
+	KindGroup      = "group"
+)
+
+// IDGenerator produces identifiers for newly created entities. Stores use it
+// when an entity is created without an ID, and the Manager uses it (when set)
+// to assign IDs before handing entities to a store, so IDs stay portable
+// across backends.
+type IDGenerator interface {
+	NewID(kind string) string
+}
+
+// IDGeneratorFunc adapts a plain function to the IDGenerator interface.
+type IDGeneratorFunc func(kind string) string
+
+func (f IDGeneratorFunc) NewID(kind string) string { return f(kind) }
+
+// DefaultIDGenerator is used by stores that have not been given a generator.
+var DefaultIDGenerator IDGenerator = UUIDGenerator{}
+
+// generateID returns a new ID from g, falling back to DefaultIDGenerator.
+func generateID(g IDGenerator, kind string) string {
+	if g == nil {
+		g = DefaultIDGenerator
+	}
+	return g.NewID(kind)
+}
+
+// UUIDGenerator generates random (version 4) UUIDs.
+type UUIDGenerator struct{}
+
+func (UUIDGenerator) NewID(string) string { return uuid.New().String() }
+
+// UUIDv7Generator generates time-ordered (version 7) UUIDs, which index
+// better than random UUIDs in B-tree backed stores.
+type UUIDv7Generator struct{}
+
+func (UUIDv7Generator) NewID(string) string {
+	id, err := uuid.NewV7()
+	if err != nil {
+		return uuid.New().String()
+	}
+	return id.String()
+}
+
+// ksuidEpoch is the KSUID epoch (2014-05-13T16:53:20Z) in unix seconds.
+const ksuidEpoch = 1400000000
+
+const base62Alphabet = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+// KSUIDGenerator generates 27 character, K-sortable unique IDs: a 32-bit
+// timestamp followed by 128 random bits, base62 encoded.
+type KSUIDGenerator struct{}
+
+func (KSUIDGenerator) NewID(string) string {
+	return newKSUID(time.Now())
+}
+
+func newKSUID(t time.Time) string {
+	var raw [20]byte
+	binary.BigEndian.PutUint32(raw[:4], uint32(t.Unix()-ksuidEpoch))
+	if _, err := rand.Read(raw[4:]); err != nil {
+		panic("rbac: reading random bytes for ksuid: " + err.Error())
+	}
+
+	n := new(big.Int).SetBytes(raw[:])
+	base := big.NewInt(62)
+	mod := new(big.Int)
+	out := make([]byte, 27)
+	for i := len(out) - 1; i >= 0; i-- {
+		n.DivMod(n, base, mod)
+		out[i] = base62Alphabet[mod.Int64()]
+	}
+	return string(out)
+}
+
+// PrefixedIDGenerator prepends a per-kind prefix (e.g. "role_") to the IDs
+// produced by Base. Kinds missing from Prefixes use the kind name followed
+// by an underscore.
+type PrefixedIDGenerator struct {
+	Base     IDGenerator
+	Prefixes map[string]string
+}
+
+// NewPrefixedIDGenerator returns a generator producing IDs such as
+// "perm_…", "role_…", "user_…" and "ug_…" on top of base.
+func NewPrefixedIDGenerator(base IDGenerator) *PrefixedIDGenerator {
+	return &PrefixedIDGenerator{
+		Base: base,
+		Prefixes: map[string]string{
+			KindPermission: "perm_",
+			KindRole:       "role_",
+			KindUser:       "user_",
+			KindUserGroup:  "ug_",
+			KindGroup:      "grp_",
+		},
+	}
+}
+
+func (g *PrefixedIDGenerator) NewID(kind string) string {
+	prefix, ok := g.Prefixes[kind]
+	if !ok {
+		prefix = kind + "_"
+	}
+	return prefix + generateID(g.Base, kind)
+}
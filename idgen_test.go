@@ -0,0 +1,53 @@
+package rbac
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestKSUIDGenerator(t *testing.T) {
+	a := newKSUID(time.Unix(1700000000, 0))
+	b := newKSUID(time.Unix(1700000001, 0))
+	if len(a) != 27 || len(b) != 27 {
+		t.Fatalf("expected 27 character ksuids, got %q and %q", a, b)
+	}
+	if a >= b {
+		t.Errorf("expected ksuids to sort by time, got %q >= %q", a, b)
+	}
+}
+
+func TestPrefixedIDGenerator(t *testing.T) {
+	g := NewPrefixedIDGenerator(UUIDv7Generator{})
+	if id := g.NewID(KindRole); !strings.HasPrefix(id, "role_") {
+		t.Errorf("expected role_ prefix, got %q", id)
+	}
+	if id := g.NewID("widget"); !strings.HasPrefix(id, "widget_") {
+		t.Errorf("expected widget_ prefix for unknown kind, got %q", id)
+	}
+}
+
+func TestManagerAssignsIDs(t *testing.T) {
+	ctx := context.Background()
+	fake := NewMockRepo()
+	mgr := NewMockRepoManager(fake)
+	mgr.IDs = NewPrefixedIDGenerator(KSUIDGenerator{})
+
+	r := &Role{Name: "editor"}
+	if err := mgr.CreateRole(ctx, r); err != nil {
+		t.Fatalf("CreateRole failed: %v", err)
+	}
+	if !strings.HasPrefix(r.ID, "role_") {
+		t.Errorf("expected generated role ID, got %q", r.ID)
+	}
+
+	// Caller supplied IDs are kept as-is.
+	u := &User{ID: "alice", Username: "alice"}
+	if err := mgr.CreateUser(ctx, u); err != nil {
+		t.Fatalf("CreateUser failed: %v", err)
+	}
+	if u.ID != "alice" {
+		t.Errorf("expected caller ID to be kept, got %q", u.ID)
+	}
+}
@@ -0,0 +1,221 @@
+package rbac
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+)
+
+// IntegrityOptions configures Manager.CheckIntegrity.
+type IntegrityOptions struct {
+	// Repair removes the dangling assignments found.
+	Repair bool
+	// CheckUsers also reports user roles and group memberships of users
+	// without a record in the user store. Leave it off when users live in an
+	// identity provider and only their assignments are stored here.
+	CheckUsers bool
+}
+
+// IntegrityIssue is an assignment with an end that does not exist.
+type IntegrityIssue struct {
+	// Kind is KindRolePermission, KindUserRole, KindGroupRole or
+	// KindUserGroup.
+	Kind string `json:"kind"`
+	// From and To are the assignment's ends in the order of ExportEdge; a
+	// membership goes from the user to the group name.
+	From string `json:"from"`
+	To   string `json:"to"`
+	// Missing is the kind of the end that does not exist: KindPermission,
+	// KindRole or KindUser.
+	Missing  string `json:"missing"`
+	Repaired bool   `json:"repaired,omitempty"`
+}
+
+// IntegrityReport is the outcome of Manager.CheckIntegrity.
+type IntegrityReport struct {
+	// Checked is the number of assignments checked.
+	Checked int               `json:"checked"`
+	Issues  []*IntegrityIssue `json:"issues"`
+}
+
+// CheckIntegrity finds dangling assignments: role permissions whose role or
+// permission is gone, and user and group roles whose role is gone, plus,
+// with CheckUsers, those of missing users. Soft-deleted roles and
+// permissions still exist. Each end reported missing is looked up again
+// first, so an entity created during the check is not reported. With Repair
+// the assignments are removed as the Unassign methods would, and each
+// removal is reported as Repaired; the first failed removal ends the check.
+// Every repo must implement ExportPager.
+func (m *Manager) CheckIntegrity(ctx context.Context, opts IntegrityOptions) (*IntegrityReport, error) {
+	start := time.Now()
+	ctx, span := m.startSpan(ctx, "CheckIntegrity")
+	defer span.End()
+	report, err := m.checkIntegrity(ctx, opts)
+	m.record(ctx, start, "CheckIntegrity", err)
+	return report, err
+}
+
+func (m *Manager) checkIntegrity(ctx context.Context, opts IntegrityOptions) (*IntegrityReport, error) {
+	exists := map[string]map[string]bool{KindPermission: {}, KindRole: {}, KindUser: {}}
+	perms, err := m.Perms.ListAllPermissions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range perms {
+		exists[KindPermission][p.ID] = true
+	}
+	roles, err := m.Roles.ListAllRoles(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range roles {
+		exists[KindRole][r.ID] = true
+	}
+	kinds := []string{KindRolePermission, KindUserRole, KindGroupRole}
+	if opts.CheckUsers {
+		users, err := m.Users.ListAllUsers(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, u := range users {
+			exists[KindUser][u.ID] = true
+		}
+		kinds = append(kinds, KindUserGroup)
+	}
+
+	// missing returns the kind of the end of an assignment that does not
+	// exist, or "".
+	missing := func(kind, from, to string) (string, error) {
+		var ends [][2]string
+		switch kind {
+		case KindRolePermission:
+			ends = [][2]string{{KindRole, from}, {KindPermission, to}}
+		case KindUserRole:
+			ends = [][2]string{{KindRole, to}}
+			if opts.CheckUsers {
+				ends = append(ends, [2]string{KindUser, from})
+			}
+		case KindGroupRole:
+			ends = [][2]string{{KindRole, to}}
+		case KindUserGroup:
+			ends = [][2]string{{KindUser, from}}
+		}
+		for _, end := range ends {
+			if exists[end[0]][end[1]] {
+				continue
+			}
+			found, err := m.integrityLookup(ctx, end[0], end[1])
+			if err != nil || !found {
+				return end[0], err
+			}
+			exists[end[0]][end[1]] = true
+		}
+		return "", nil
+	}
+
+	report := &IntegrityReport{Issues: []*IntegrityIssue{}}
+	for _, kind := range kinds {
+		pager, ok := m.edgeRepo(kind).(ExportPager)
+		if !ok {
+			return report, fmt.Errorf("%w: %s", errExportUnsupported, kind)
+		}
+		err := eachExportItem(ctx, pager, kind, func(it ExportItem) error {
+			issue, ug := &IntegrityIssue{Kind: kind}, (*UserGroup)(nil)
+			switch v := it.Value.(type) {
+			case *ExportEdge:
+				issue.From, issue.To = v.From, v.To
+			case *UserGroup:
+				issue.From, issue.To, ug = v.UserID, v.GroupName, v
+			default:
+				return nil
+			}
+			report.Checked++
+			var err error
+			if issue.Missing, err = missing(kind, issue.From, issue.To); err != nil || issue.Missing == "" {
+				return err
+			}
+			report.Issues = append(report.Issues, issue)
+			if opts.Repair {
+				if err := m.removeDangling(ctx, issue, ug); err != nil {
+					return fmt.Errorf("repair %s -> %s: %w", issue.From, issue.To, err)
+				}
+				issue.Repaired = true
+			}
+			return nil
+		})
+		if err != nil {
+			return report, fmt.Errorf("rbac: check %s: %w", kind, err)
+		}
+	}
+	return report, nil
+}
+
+// integrityLookup reports whether the entity of kind with id exists.
+func (m *Manager) integrityLookup(ctx context.Context, kind, id string) (bool, error) {
+	switch kind {
+	case KindPermission:
+		p, err := m.Perms.GetPermissionByID(ctx, id)
+		return p != nil, err
+	case KindRole:
+		r, err := m.Roles.GetRoleByID(ctx, id)
+		return r != nil, err
+	default:
+		u, err := m.Users.GetUserByID(ctx, id)
+		return u != nil, err
+	}
+}
+
+// removeDangling removes the assignment of issue; ug is the membership for
+// a KindUserGroup issue.
+func (m *Manager) removeDangling(ctx context.Context, issue *IntegrityIssue, ug *UserGroup) error {
+	const method = "CheckIntegrity"
+	from, to := issue.From, issue.To
+	var err error
+	switch issue.Kind {
+	case KindRolePermission:
+		err = m.RP.Remove(ctx, from, to)
+		m.changedRole(ctx, method, from, err)
+		m.emit(err, func(l Listener) { l.OnPermissionRemoved(ctx, from, to) })
+	case KindUserRole:
+		err = m.UR.RemoveUR(ctx, from, to)
+		m.changedUser(ctx, method, from, err)
+		m.emit(err, func(l Listener) { l.OnRoleUnassigned(ctx, from, to) })
+	case KindGroupRole:
+		err = m.GR.RemoveRoleFromGroup(ctx, from, to)
+		m.changed(ctx, method, from, err)
+		m.emit(err, func(l Listener) { l.OnGroupRoleUnassigned(ctx, from, to) })
+	case KindUserGroup:
+		err = m.UG.RemoveUserFromGroup(ctx, to, ug)
+		m.changedUser(ctx, method, from, err)
+		m.emit(err, func(l Listener) { l.OnUserRemovedFromGroup(ctx, to, from) })
+	}
+	return err
+}
+
+// CheckIntegrityEvery runs CheckIntegrity with opts now and every interval,
+// passing each report that found issues to onIssues, if set, and logging
+// failed checks. It blocks until ctx is cancelled.
+func (m *Manager) CheckIntegrityEvery(ctx context.Context, interval time.Duration, opts IntegrityOptions, onIssues func(ctx context.Context, report *IntegrityReport)) {
+	check := func() {
+		report, err := m.CheckIntegrity(ctx, opts)
+		if err != nil {
+			log.Printf("rbac: check integrity: %v", err)
+		}
+		if report != nil && len(report.Issues) > 0 && onIssues != nil {
+			onIssues(ctx, report)
+		}
+	}
+
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	check()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			check()
+		}
+	}
+}
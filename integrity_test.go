@@ -0,0 +1,106 @@
+package rbac
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestCheckIntegrity(t *testing.T) {
+	ctx := context.Background()
+	mgr, err := NewMemoryStoreManager(ctx, "", 0)
+	if err != nil {
+		t.Fatalf("NewMemoryStoreManager: %v", err)
+	}
+	if err := mgr.CreatePermission(ctx, &Permission{ID: "docs-read", Resource: "docs/*", Action: ActionRead}); err != nil {
+		t.Fatalf("CreatePermission: %v", err)
+	}
+	for _, id := range []string{"viewer", "old"} {
+		if err := mgr.CreateRole(ctx, &Role{ID: id, Name: id}); err != nil {
+			t.Fatalf("CreateRole: %v", err)
+		}
+	}
+	if err := mgr.CreateUser(ctx, &User{ID: "alice", Username: "alice", Email: "alice@example.com"}); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	if err := mgr.AssignPermissionToRole(ctx, "viewer", "docs-read"); err != nil {
+		t.Fatalf("AssignPermissionToRole: %v", err)
+	}
+	for _, roleID := range []string{"viewer", "old"} {
+		if err := mgr.AssignRoleToUser(ctx, "alice", roleID); err != nil {
+			t.Fatalf("AssignRoleToUser: %v", err)
+		}
+	}
+	// a soft-deleted role still exists
+	if err := mgr.DeleteRole(ctx, "old"); err != nil {
+		t.Fatalf("DeleteRole: %v", err)
+	}
+
+	// dangling assignments written straight to the repos
+	must := func(err error) {
+		t.Helper()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	must(mgr.RP.AddRP(ctx, "viewer", "gone-perm"))
+	must(mgr.UR.AddUR(ctx, "alice", "gone-role"))
+	must(mgr.UR.AddUR(ctx, "bob", "viewer"))
+	must(mgr.GR.AddRoleToGroup(ctx, "staff", "gone-role"))
+	must(mgr.UG.AddUserToGroup(ctx, &UserGroup{UserID: "carol", GroupName: "staff"}))
+
+	type key struct{ kind, from, to, missing string }
+	issues := func(r *IntegrityReport) map[key]bool {
+		out := map[key]bool{}
+		for _, i := range r.Issues {
+			out[key{i.Kind, i.From, i.To, i.Missing}] = true
+		}
+		return out
+	}
+	report, err := mgr.CheckIntegrity(ctx, IntegrityOptions{})
+	if err != nil {
+		t.Fatalf("CheckIntegrity: %v", err)
+	}
+	want := map[key]bool{
+		{KindRolePermission, "viewer", "gone-perm", KindPermission}: true,
+		{KindUserRole, "alice", "gone-role", KindRole}:              true,
+		{KindGroupRole, "staff", "gone-role", KindRole}:             true,
+	}
+	if got := issues(report); len(got) != len(want) || len(report.Issues) != len(want) {
+		t.Errorf("issues = %v; want %v", got, want)
+	} else {
+		for k := range want {
+			if !got[k] {
+				t.Errorf("issue %v not reported", k)
+			}
+		}
+	}
+	if report.Checked != 7 {
+		t.Errorf("Checked = %d; want the 7 role assignments", report.Checked)
+	}
+
+	report, err = mgr.CheckIntegrity(ctx, IntegrityOptions{Repair: true, CheckUsers: true})
+	if err != nil {
+		t.Fatalf("CheckIntegrity(Repair): %v", err)
+	}
+	want[key{KindUserRole, "bob", "viewer", KindUser}] = true
+	want[key{KindUserGroup, "carol", "staff", KindUser}] = true
+	if got := issues(report); len(got) != len(want) {
+		t.Errorf("issues with CheckUsers = %v; want %v", got, want)
+	}
+	for _, i := range report.Issues {
+		if !i.Repaired {
+			t.Errorf("issue %+v not repaired", i)
+		}
+	}
+	if report, err := mgr.CheckIntegrity(ctx, IntegrityOptions{CheckUsers: true}); err != nil || len(report.Issues) != 0 {
+		t.Errorf("after repair: %+v, %v; want no issues", report, err)
+	}
+	if ok, err := mgr.Can(ctx, "alice", "docs/1", ActionRead); err != nil || !ok {
+		t.Errorf("Can(alice) = %v, %v after repair; want the valid assignments kept", ok, err)
+	}
+
+	if _, err := NewMockRepoManager(NewMockRepo()).CheckIntegrity(ctx, IntegrityOptions{}); !errors.Is(err, errExportUnsupported) {
+		t.Errorf("CheckIntegrity without an ExportPager = %v", err)
+	}
+}
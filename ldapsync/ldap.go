@@ -0,0 +1,158 @@
+package ldapsync
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"strings"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+// LDAPConfig configures an LDAPDirectory. Zero values select defaults that
+// suit OpenLDAP; see the field comments for Active Directory.
+type LDAPConfig struct {
+	// URL of the server, e.g. "ldaps://ldap.example.com:636".
+	URL          string
+	BindDN       string
+	BindPassword string
+	// TLSConfig is used for ldaps:// URLs and StartTLS.
+	TLSConfig *tls.Config
+	// StartTLS upgrades an ldap:// connection before binding.
+	StartTLS bool
+
+	// BaseDN is searched for groups.
+	BaseDN string
+	// GroupFilter selects groups. Defaults to groupOfNames, groupOfUniqueNames
+	// and AD group objects.
+	GroupFilter string
+	// GroupNameAttr names a group. Defaults to "cn".
+	GroupNameAttr string
+	// MemberAttr lists member DNs. Defaults to "member".
+	MemberAttr string
+	// UserAttr identifies a member; it is matched against the Syncer's
+	// UserField. Defaults to "uid"; use "sAMAccountName" or
+	// "userPrincipalName" for Active Directory.
+	UserAttr string
+	// PageSize for group searches. Defaults to 500.
+	PageSize uint32
+}
+
+// LDAPDirectory reads groups from an LDAP or Active Directory server. Each
+// call to Groups opens and closes its own connection.
+type LDAPDirectory struct {
+	cfg LDAPConfig
+}
+
+var _ Directory = (*LDAPDirectory)(nil)
+
+// NewLDAPDirectory returns a Directory backed by the server in cfg.
+func NewLDAPDirectory(cfg LDAPConfig) *LDAPDirectory {
+	if cfg.GroupFilter == "" {
+		cfg.GroupFilter = "(|(objectClass=groupOfNames)(objectClass=groupOfUniqueNames)(objectClass=group))"
+	}
+	if cfg.GroupNameAttr == "" {
+		cfg.GroupNameAttr = "cn"
+	}
+	if cfg.MemberAttr == "" {
+		cfg.MemberAttr = "member"
+	}
+	if cfg.UserAttr == "" {
+		cfg.UserAttr = "uid"
+	}
+	if cfg.PageSize == 0 {
+		cfg.PageSize = 500
+	}
+	return &LDAPDirectory{cfg: cfg}
+}
+
+// Groups returns every group matching GroupFilter under BaseDN with its
+// members' UserAttr values. Members whose DN does not start with UserAttr
+// are looked up individually. Nested groups are not expanded.
+func (d *LDAPDirectory) Groups(ctx context.Context) ([]Group, error) {
+	conn, err := ldap.DialURL(d.cfg.URL, ldap.DialWithTLSConfig(d.cfg.TLSConfig))
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	// The client has no context support; closing the connection aborts
+	// whatever request is in flight.
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	if d.cfg.StartTLS {
+		if err := conn.StartTLS(d.cfg.TLSConfig); err != nil {
+			return nil, err
+		}
+	}
+	if d.cfg.BindDN != "" {
+		if err := conn.Bind(d.cfg.BindDN, d.cfg.BindPassword); err != nil {
+			return nil, err
+		}
+	}
+
+	res, err := conn.SearchWithPaging(ldap.NewSearchRequest(
+		d.cfg.BaseDN, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 0, 0, false,
+		d.cfg.GroupFilter, []string{d.cfg.GroupNameAttr, d.cfg.MemberAttr}, nil,
+	), d.cfg.PageSize)
+	if err != nil {
+		return nil, err
+	}
+
+	resolved := map[string]string{}
+	groups := make([]Group, 0, len(res.Entries))
+	for _, e := range res.Entries {
+		g := Group{DN: e.DN, Name: e.GetAttributeValue(d.cfg.GroupNameAttr)}
+		for _, dn := range e.GetAttributeValues(d.cfg.MemberAttr) {
+			id, ok := resolved[dn]
+			if !ok {
+				if id, err = d.memberID(conn, dn); err != nil {
+					return nil, err
+				}
+				resolved[dn] = id
+			}
+			if id != "" {
+				g.Members = append(g.Members, id)
+			}
+		}
+		groups = append(groups, g)
+	}
+	return groups, ctx.Err()
+}
+
+// memberID returns the member's UserAttr value, reading it from the DN when
+// its first RDN is UserAttr and from the entry otherwise. Members that no
+// longer exist resolve to "".
+func (d *LDAPDirectory) memberID(conn *ldap.Conn, dn string) (string, error) {
+	if v, ok := rdnValue(dn, d.cfg.UserAttr); ok {
+		return v, nil
+	}
+	res, err := conn.Search(ldap.NewSearchRequest(
+		dn, ldap.ScopeBaseObject, ldap.NeverDerefAliases, 1, 0, false,
+		"(objectClass=*)", []string{d.cfg.UserAttr}, nil,
+	))
+	if ldap.IsErrorWithCode(err, ldap.LDAPResultNoSuchObject) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("look up member %s: %w", dn, err)
+	}
+	if len(res.Entries) == 0 {
+		return "", nil
+	}
+	return res.Entries[0].GetAttributeValue(d.cfg.UserAttr), nil
+}
+
+// rdnValue returns the value of attr when it is the only attribute of dn's
+// first RDN, as in "uid=alice,ou=people,dc=example,dc=com".
+func rdnValue(dn, attr string) (string, bool) {
+	parsed, err := ldap.ParseDN(dn)
+	if err != nil || len(parsed.RDNs) == 0 || len(parsed.RDNs[0].Attributes) != 1 {
+		return "", false
+	}
+	a := parsed.RDNs[0].Attributes[0]
+	if !strings.EqualFold(a.Type, attr) {
+		return "", false
+	}
+	return a.Value, true
+}
@@ -0,0 +1,295 @@
+// Package ldapsync drives rbac group membership from an LDAP or Active
+// Directory server. A Syncer periodically reads the configured directory
+// groups, adds and removes UserGroup records so each rbac group has the same
+// members as its directory group, and binds the mapped roles to the group:
+//
+//	dir := ldapsync.NewLDAPDirectory(ldapsync.LDAPConfig{
+//		URL:    "ldaps://ldap.example.com",
+//		BindDN: "cn=rbac,ou=services,dc=example,dc=com", BindPassword: pw,
+//		BaseDN: "ou=groups,dc=example,dc=com",
+//	})
+//	s := ldapsync.New(mgr, dir, ldapsync.Config{
+//		Mappings: []ldapsync.Mapping{{Group: "engineering", Roles: []string{"developer"}}},
+//		Interval: 5 * time.Minute,
+//	})
+//	go s.Run(ctx)
+package ldapsync
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/Seann-Moser/rbac"
+)
+
+// Group is a directory group. Members holds the members' identifiers
+// (LDAPConfig.UserAttr for an LDAPDirectory), which a Syncer matches against
+// Config.UserField.
+type Group struct {
+	DN      string
+	Name    string
+	Members []string
+}
+
+// Directory lists the groups a Syncer maps.
+type Directory interface {
+	Groups(ctx context.Context) ([]Group, error)
+}
+
+// Mapping ties a directory group to an rbac group and the roles its members
+// get through it.
+type Mapping struct {
+	// Group is the directory group's name or DN.
+	Group string
+	// RBACGroup is the rbac group name. Defaults to Group.
+	RBACGroup string
+	// Roles are role names (or IDs) bound to the rbac group.
+	Roles []string
+}
+
+// Config configures a Syncer.
+type Config struct {
+	Mappings []Mapping
+	// Interval between syncs in Run. Defaults to 15 minutes.
+	Interval time.Duration
+	// UserField is the rbac user field ("id", "username" or "email") that
+	// directory member identifiers are matched against. Defaults to
+	// "username". Members without an rbac user are skipped.
+	UserField string
+	// KeepUnlisted leaves rbac group members and role bindings that the
+	// directory does not list in place instead of removing them. Members
+	// and bindings managed by another source, such as ones added by hand,
+	// are only removed when Authoritative is set.
+	KeepUnlisted bool
+	// Authoritative makes the directory the only source of truth for the
+	// mapped groups: unlisted members and bindings are removed whoever
+	// manages them.
+	Authoritative bool
+}
+
+// Result summarises one sync.
+type Result struct {
+	MembersAdded   int
+	MembersRemoved int
+	RolesBound     int
+	RolesUnbound   int
+	// UnknownUsers counts directory members without an rbac user.
+	UnknownUsers int
+	// NotOwned counts unlisted members and bindings left in place because
+	// another source manages them.
+	NotOwned int
+	// Banned counts directory members not added because they are banned
+	// from the rbac group.
+	Banned int
+	// MissingGroups lists mappings whose directory group was not found.
+	// Their rbac groups are left untouched.
+	MissingGroups []string
+}
+
+// Syncer reconciles rbac groups with directory groups.
+type Syncer struct {
+	mgr *rbac.Manager
+	dir Directory
+	cfg Config
+}
+
+// New returns a Syncer that applies cfg's mappings from dir to mgr.
+func New(mgr *rbac.Manager, dir Directory, cfg Config) *Syncer {
+	if cfg.Interval <= 0 {
+		cfg.Interval = 15 * time.Minute
+	}
+	if cfg.UserField == "" {
+		cfg.UserField = "username"
+	}
+	return &Syncer{mgr: mgr, dir: dir, cfg: cfg}
+}
+
+// Run syncs immediately and then every Interval until ctx is cancelled.
+// Failed syncs are logged and retried on the next tick.
+func (s *Syncer) Run(ctx context.Context) {
+	t := time.NewTicker(s.cfg.Interval)
+	defer t.Stop()
+	for {
+		if _, err := s.Sync(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("ldapsync: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// Sync reads the directory once and applies every mapping. Its writes are
+// attributed to rbac.SourceDirectorySync.
+func (s *Syncer) Sync(ctx context.Context) (*Result, error) {
+	ctx = rbac.WithAssignmentSource(ctx, rbac.SourceDirectorySync)
+	groups, err := s.dir.Groups(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("ldapsync: read directory: %w", err)
+	}
+	byKey := make(map[string]*Group, 2*len(groups))
+	for i := range groups {
+		byKey[groups[i].Name] = &groups[i]
+		byKey[groups[i].DN] = &groups[i]
+	}
+
+	res := &Result{}
+	users := map[string]string{} // directory identifier -> rbac user ID, "" when unknown
+	var errs []error
+	for _, mp := range s.cfg.Mappings {
+		g, ok := byKey[mp.Group]
+		if !ok || mp.Group == "" {
+			res.MissingGroups = append(res.MissingGroups, mp.Group)
+			continue
+		}
+		name := mp.RBACGroup
+		if name == "" {
+			name = mp.Group
+		}
+		if err := s.syncMembers(ctx, name, g, users, res); err != nil {
+			errs = append(errs, fmt.Errorf("ldapsync: group %s: %w", name, err))
+			continue
+		}
+		if err := s.syncRoles(ctx, name, mp.Roles, res); err != nil {
+			errs = append(errs, fmt.Errorf("ldapsync: group %s: %w", name, err))
+		}
+	}
+	return res, errors.Join(errs...)
+}
+
+func (s *Syncer) syncMembers(ctx context.Context, name string, g *Group, users map[string]string, res *Result) error {
+	want := map[string]bool{}
+	for _, member := range g.Members {
+		uid, seen := users[member]
+		if !seen {
+			u, err := s.lookupUser(ctx, member)
+			if err != nil {
+				return err
+			}
+			if u != nil {
+				uid = u.ID
+			}
+			users[member] = uid
+			if uid == "" {
+				res.UnknownUsers++
+			}
+		}
+		if uid != "" {
+			want[uid] = true
+		}
+	}
+
+	current, err := s.mgr.GetUsersByGroupID(ctx, name)
+	if err != nil {
+		return err
+	}
+	have := map[string]bool{}
+	for _, ug := range current {
+		have[ug.UserID] = true
+		if want[ug.UserID] || s.cfg.KeepUnlisted {
+			continue
+		}
+		err := s.mgr.RemoveUserFromGroup(s.removeCtx(ctx), name, ug)
+		if errors.Is(err, rbac.ErrManagedElsewhere) {
+			res.NotOwned++
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		res.MembersRemoved++
+	}
+	for uid := range want {
+		if have[uid] {
+			continue
+		}
+		err := s.mgr.AddUserToGroup(ctx, &rbac.UserGroup{UserID: uid, GroupName: name})
+		if errors.Is(err, rbac.ErrBannedFromGroup) {
+			res.Banned++
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		res.MembersAdded++
+	}
+	return nil
+}
+
+// removeCtx returns the context removals are made with.
+func (s *Syncer) removeCtx(ctx context.Context) context.Context {
+	if s.cfg.Authoritative {
+		return rbac.WithAssignmentSource(ctx, rbac.SourceAny)
+	}
+	return ctx
+}
+
+func (s *Syncer) lookupUser(ctx context.Context, value string) (*rbac.User, error) {
+	if s.cfg.UserField == "id" {
+		return s.mgr.GetUser(ctx, value)
+	}
+	return s.mgr.Users.GetUserByMeta(ctx, map[string]interface{}{s.cfg.UserField: value})
+}
+
+func (s *Syncer) syncRoles(ctx context.Context, name string, roles []string, res *Result) error {
+	want := map[string]bool{}
+	for _, r := range roles {
+		id, err := s.roleID(ctx, r)
+		if err != nil {
+			return err
+		}
+		want[id] = true
+	}
+
+	current, err := s.mgr.ListRolesForGroup(ctx, name)
+	if err != nil {
+		return err
+	}
+	have := map[string]bool{}
+	for _, id := range current {
+		have[id] = true
+		if want[id] || s.cfg.KeepUnlisted {
+			continue
+		}
+		err := s.mgr.UnassignRoleFromGroup(s.removeCtx(ctx), name, id)
+		if errors.Is(err, rbac.ErrManagedElsewhere) {
+			res.NotOwned++
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		res.RolesUnbound++
+	}
+	for id := range want {
+		if have[id] {
+			continue
+		}
+		if err := s.mgr.AssignRoleToGroup(ctx, name, id); err != nil {
+			return err
+		}
+		res.RolesBound++
+	}
+	return nil
+}
+
+// roleID resolves a mapped role by name, falling back to treating it as an
+// ID.
+func (s *Syncer) roleID(ctx context.Context, nameOrID string) (string, error) {
+	if r, err := s.mgr.Roles.GetRoleByName(ctx, nameOrID); err == nil && r != nil {
+		return r.ID, nil
+	}
+	r, err := s.mgr.GetRole(ctx, nameOrID)
+	if err != nil {
+		return "", err
+	}
+	if r == nil {
+		return "", fmt.Errorf("unknown role %q", nameOrID)
+	}
+	return r.ID, nil
+}
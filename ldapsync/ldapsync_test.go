@@ -0,0 +1,184 @@
+package ldapsync
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/Seann-Moser/rbac"
+)
+
+type staticDirectory []Group
+
+func (d staticDirectory) Groups(ctx context.Context) ([]Group, error) {
+	return d, nil
+}
+
+func TestSync(t *testing.T) {
+	ctx := context.Background()
+	mgr, err := rbac.NewMemoryStoreManager(ctx, "", 0)
+	if err != nil {
+		t.Fatalf("NewMemoryStoreManager: %v", err)
+	}
+	for _, u := range []*rbac.User{{ID: "u1", Username: "alice"}, {ID: "u2", Username: "bob"}, {ID: "u3", Username: "carol"}} {
+		if err := mgr.CreateUser(ctx, u); err != nil {
+			t.Fatalf("CreateUser: %v", err)
+		}
+	}
+	for _, r := range []*rbac.Role{{ID: "r-dev", Name: "developer"}, {ID: "r-old", Name: "legacy"}} {
+		if err := mgr.CreateRole(ctx, r); err != nil {
+			t.Fatalf("CreateRole: %v", err)
+		}
+	}
+	// carol was added by hand and the legacy role bound by hand; the
+	// directory is authoritative, so both go.
+	_ = mgr.AddUserToGroup(ctx, &rbac.UserGroup{UserID: "u3", GroupName: "eng"})
+	_ = mgr.AssignRoleToGroup(ctx, "eng", "r-old")
+
+	dir := staticDirectory{
+		{DN: "cn=engineering,ou=groups,dc=example,dc=com", Name: "engineering", Members: []string{"alice", "bob", "mallory"}},
+	}
+	s := New(mgr, dir, Config{Mappings: []Mapping{
+		{Group: "cn=engineering,ou=groups,dc=example,dc=com", RBACGroup: "eng", Roles: []string{"developer"}},
+		{Group: "sales"},
+	}, Authoritative: true})
+
+	res, err := s.Sync(ctx)
+	if err != nil {
+		t.Fatalf("Sync: %v", err)
+	}
+	want := Result{MembersAdded: 2, MembersRemoved: 1, RolesBound: 1, RolesUnbound: 1, UnknownUsers: 1, MissingGroups: []string{"sales"}}
+	if res.MembersAdded != want.MembersAdded || res.MembersRemoved != want.MembersRemoved ||
+		res.RolesBound != want.RolesBound || res.RolesUnbound != want.RolesUnbound ||
+		res.UnknownUsers != want.UnknownUsers || !slices.Equal(res.MissingGroups, want.MissingGroups) {
+		t.Errorf("got %+v, want %+v", *res, want)
+	}
+
+	members, _ := mgr.GetUsersByGroupID(ctx, "eng")
+	var ids []string
+	for _, ug := range members {
+		ids = append(ids, ug.UserID)
+	}
+	slices.Sort(ids)
+	if !slices.Equal(ids, []string{"u1", "u2"}) {
+		t.Errorf("expected eng to mirror the directory, got %v", ids)
+	}
+	if roles, _ := mgr.ListRolesForGroup(ctx, "eng"); !slices.Equal(roles, []string{"r-dev"}) {
+		t.Errorf("expected only the mapped role, got %v", roles)
+	}
+
+	// A second sync has nothing to do.
+	res, err = s.Sync(ctx)
+	if err != nil {
+		t.Fatalf("Sync: %v", err)
+	}
+	if res.MembersAdded+res.MembersRemoved+res.RolesBound+res.RolesUnbound != 0 {
+		t.Errorf("expected an idempotent sync, got %+v", *res)
+	}
+}
+
+func TestSyncLeavesManualEdges(t *testing.T) {
+	ctx := context.Background()
+	mgr, err := rbac.NewMemoryStoreManager(ctx, "", 0)
+	if err != nil {
+		t.Fatalf("NewMemoryStoreManager: %v", err)
+	}
+	_ = mgr.CreateUser(ctx, &rbac.User{ID: "u1", Username: "alice"})
+	_ = mgr.CreateUser(ctx, &rbac.User{ID: "u2", Username: "bob"})
+	_ = mgr.AddUserToGroup(ctx, &rbac.UserGroup{UserID: "manual", GroupName: "eng"})
+
+	dir := staticDirectory{{Name: "eng", Members: []string{"u1", "u2"}}}
+	s := New(mgr, dir, Config{Mappings: []Mapping{{Group: "eng"}}, UserField: "id"})
+	if _, err := s.Sync(ctx); err != nil {
+		t.Fatalf("Sync: %v", err)
+	}
+	if src, _ := mgr.EdgeSource(ctx, rbac.KindUserGroup, "u1", "eng"); src != rbac.SourceDirectorySync {
+		t.Errorf("expected u1's membership to be managed by the sync, got %q", src)
+	}
+
+	// bob leaves the directory group; the hand-added member stays.
+	dir[0].Members = []string{"u1"}
+	res, err := s.Sync(ctx)
+	if err != nil {
+		t.Fatalf("Sync: %v", err)
+	}
+	if res.MembersRemoved != 1 || res.NotOwned != 1 {
+		t.Errorf("expected bob to be removed and the manual member kept, got %+v", *res)
+	}
+	if members, _ := mgr.GetUsersByGroupID(ctx, "eng"); len(members) != 2 {
+		t.Errorf("expected 2 members, got %d", len(members))
+	}
+}
+
+func TestSyncKeepUnlisted(t *testing.T) {
+	ctx := context.Background()
+	mgr, err := rbac.NewMemoryStoreManager(ctx, "", 0)
+	if err != nil {
+		t.Fatalf("NewMemoryStoreManager: %v", err)
+	}
+	_ = mgr.CreateUser(ctx, &rbac.User{ID: "u1", Username: "alice"})
+	_ = mgr.AddUserToGroup(ctx, &rbac.UserGroup{UserID: "manual", GroupName: "eng"})
+
+	s := New(mgr, staticDirectory{{Name: "eng", Members: []string{"u1"}}}, Config{
+		Mappings:     []Mapping{{Group: "eng"}},
+		UserField:    "id",
+		KeepUnlisted: true,
+	})
+	if _, err := s.Sync(ctx); err != nil {
+		t.Fatalf("Sync: %v", err)
+	}
+	if members, _ := mgr.GetUsersByGroupID(ctx, "eng"); len(members) != 2 {
+		t.Errorf("expected the manual member to be kept, got %d members", len(members))
+	}
+}
+
+func TestSyncSkipsBannedUsers(t *testing.T) {
+	ctx := context.Background()
+	mgr, err := rbac.NewMemoryStoreManager(ctx, "", 0)
+	if err != nil {
+		t.Fatalf("NewMemoryStoreManager: %v", err)
+	}
+	_ = mgr.CreateUser(ctx, &rbac.User{ID: "u1", Username: "alice"})
+	_ = mgr.CreateUser(ctx, &rbac.User{ID: "contractor", Username: "eve"})
+
+	s := New(mgr, staticDirectory{{Name: "eng", Members: []string{"u1", "contractor"}}}, Config{
+		Mappings:  []Mapping{{Group: "eng"}},
+		UserField: "id",
+	})
+	if _, err := s.Sync(ctx); err != nil {
+		t.Fatalf("Sync: %v", err)
+	}
+	if err := mgr.BanUserFromGroup(ctx, "eng", "contractor", "contractors stay out of eng"); err != nil {
+		t.Fatalf("BanUserFromGroup: %v", err)
+	}
+
+	res, err := s.Sync(ctx)
+	if err != nil {
+		t.Fatalf("Sync: %v", err)
+	}
+	if res.MembersAdded != 0 || res.Banned != 1 {
+		t.Errorf("expected the banned user to be skipped, got %+v", *res)
+	}
+	if members, _ := mgr.GetUsersByGroupID(ctx, "eng"); len(members) != 1 || members[0].UserID != "u1" {
+		t.Errorf("expected only u1 in eng, got %v", members)
+	}
+}
+
+func TestRDNValue(t *testing.T) {
+	cases := []struct {
+		dn, attr, want string
+		ok             bool
+	}{
+		{"uid=alice,ou=people,dc=example,dc=com", "uid", "alice", true},
+		{"UID=alice,ou=people,dc=example,dc=com", "uid", "alice", true},
+		{"cn=Alice Smith,ou=people,dc=example,dc=com", "uid", "", false},
+		{"uid=alice+cn=Alice,ou=people", "uid", "", false},
+		{"not a dn", "uid", "", false},
+	}
+	for _, tc := range cases {
+		got, ok := rdnValue(tc.dn, tc.attr)
+		if got != tc.want || ok != tc.ok {
+			t.Errorf("rdnValue(%q, %q) = %q, %v; want %q, %v", tc.dn, tc.attr, got, ok, tc.want, tc.ok)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package rbac
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// Limits caps assignments made through the Manager. Zero leaves a limit
+// off.
+type Limits struct {
+	// MaxRolesPerUser caps the distinct roles assigned to a user directly,
+	// counting scheduled, scoped and constrained assignments but not the
+	// default role or roles held through groups.
+	MaxRolesPerUser int `json:"max_roles_per_user,omitempty" yaml:"max_roles_per_user,omitempty"`
+	// MaxMembersPerGroup caps the unexpired memberships of a group.
+	MaxMembersPerGroup int `json:"max_members_per_group,omitempty" yaml:"max_members_per_group,omitempty"`
+}
+
+// Limit names reported in a LimitError.
+const (
+	LimitRolesPerUser    = "max_roles_per_user"
+	LimitMembersPerGroup = "max_members_per_group"
+)
+
+// ErrLimitExceeded is wrapped by LimitError; match it with errors.Is.
+var ErrLimitExceeded = errors.New("rbac: assignment limit exceeded")
+
+// LimitError is returned when an assignment would take Subject, a user or
+// group, past the Max of the named Limit.
+type LimitError struct {
+	Limit   string
+	Subject string
+	Max     int
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("%v: %s %d reached by %q", ErrLimitExceeded, e.Limit, e.Max, e.Subject)
+}
+
+func (e *LimitError) Unwrap() error { return ErrLimitExceeded }
+
+// checkRoleLimit returns a LimitError when assigning roleIDs would give
+// userID more than Limits.MaxRolesPerUser roles. Reassigning a held role
+// is always allowed.
+func (m *Manager) checkRoleLimit(ctx context.Context, userID string, roleIDs ...string) error {
+	max := m.Limits.MaxRolesPerUser
+	if max <= 0 {
+		return nil
+	}
+	held, err := m.assignedRoles(ctx, userID)
+	if err != nil {
+		return err
+	}
+	var defaultID string
+	if m.DefaultRoleName != "" {
+		if r, err := m.Roles.GetRoleByName(ctx, m.DefaultRoleName); err == nil && r != nil {
+			defaultID = r.ID
+		}
+	}
+	distinct := map[string]bool{}
+	for _, id := range held {
+		if id != defaultID {
+			distinct[id] = true
+		}
+	}
+	var adding int
+	for _, id := range roleIDs {
+		if !slices.Contains(held, id) && !distinct[id] {
+			distinct[id] = true
+			adding++
+		}
+	}
+	if adding > 0 && len(distinct) > max {
+		return &LimitError{Limit: LimitRolesPerUser, Subject: userID, Max: max}
+	}
+	return nil
+}
+
+// checkMemberLimit returns a LimitError when adding userIDs would give group
+// more than Limits.MaxMembersPerGroup members. Renewing a membership is
+// always allowed.
+func (m *Manager) checkMemberLimit(ctx context.Context, group string, userIDs ...string) error {
+	max := m.Limits.MaxMembersPerGroup
+	if max <= 0 {
+		return nil
+	}
+	members, err := m.UG.GetUsersByGroupID(ctx, group)
+	if err != nil {
+		return err
+	}
+	distinct := map[string]bool{}
+	for _, member := range activeMemberships(members, time.Now()) {
+		distinct[member.UserID] = true
+	}
+	var adding int
+	for _, id := range userIDs {
+		if !distinct[id] {
+			distinct[id] = true
+			adding++
+		}
+	}
+	if adding > 0 && len(distinct) > max {
+		return &LimitError{Limit: LimitMembersPerGroup, Subject: group, Max: max}
+	}
+	return nil
+}
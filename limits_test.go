@@ -0,0 +1,93 @@
+package rbac
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestAssignmentLimits(t *testing.T) {
+	ctx := context.Background()
+	memory, err := NewMemoryStoreManager(ctx, "", 0)
+	if err != nil {
+		t.Fatalf("NewMemoryStoreManager: %v", err)
+	}
+	for name, mgr := range map[string]*Manager{"memory": memory, "mock": NewMockRepoManager(NewMockRepo())} {
+		t.Run(name, func(t *testing.T) {
+			mgr.Limits = Limits{MaxRolesPerUser: 2, MaxMembersPerGroup: 1}
+			var roles []*Role
+			for _, n := range []string{"a", "b", "c"} {
+				r := &Role{Name: n}
+				if err := mgr.CreateRole(ctx, r); err != nil {
+					t.Fatalf("CreateRole: %v", err)
+				}
+				roles = append(roles, r)
+			}
+
+			if err := mgr.AssignRoleToUser(ctx, "alice", roles[0].ID); err != nil {
+				t.Fatalf("AssignRoleToUser: %v", err)
+			}
+			if err := mgr.ScheduleRoleForUser(ctx, "alice", roles[1].ID, time.Now().Add(time.Hour), time.Time{}); err != nil {
+				t.Fatalf("ScheduleRoleForUser: %v", err)
+			}
+			err := mgr.AssignRoleToUser(ctx, "alice", roles[2].ID)
+			var limit *LimitError
+			if !errors.As(err, &limit) || !errors.Is(err, ErrLimitExceeded) {
+				t.Fatalf("expected a LimitError counting the pending role, got %v", err)
+			}
+			if limit.Limit != LimitRolesPerUser || limit.Subject != "alice" || limit.Max != 2 {
+				t.Errorf("unexpected limit error: %+v", limit)
+			}
+			if err := mgr.AssignRoleToUser(ctx, "alice", roles[0].ID); err != nil {
+				t.Errorf("reassigning a held role: %v", err)
+			}
+
+			if err := mgr.AddUserToGroup(ctx, &UserGroup{UserID: "alice", GroupName: "ops"}); err != nil {
+				t.Fatalf("AddUserToGroup: %v", err)
+			}
+			if err := mgr.AddUserToGroup(ctx, &UserGroup{UserID: "alice", GroupName: "ops"}); err != nil {
+				t.Errorf("renewing a membership: %v", err)
+			}
+			if err := mgr.AddUserToGroup(ctx, &UserGroup{UserID: "bob", GroupName: "ops"}); !errors.As(err, &limit) || limit.Limit != LimitMembersPerGroup {
+				t.Errorf("expected a %s LimitError, got %v", LimitMembersPerGroup, err)
+			}
+
+			mgr.Limits = Limits{}
+			if err := mgr.AssignRoleToUser(ctx, "alice", roles[2].ID); err != nil {
+				t.Errorf("AssignRoleToUser without limits: %v", err)
+			}
+		})
+	}
+}
+
+func TestTenantLimits(t *testing.T) {
+	ctx := context.Background()
+	mgr, err := NewMemoryStoreManager(ctx, "", 0)
+	if err != nil {
+		t.Fatalf("NewMemoryStoreManager: %v", err)
+	}
+	mgr.Limits = Limits{MaxRolesPerUser: 5}
+	mgr.TenantLimits = map[string]Limits{"acme": {MaxRolesPerUser: 1}}
+
+	acme := mgr.ForTenant("acme")
+	if acme.Limits.MaxRolesPerUser != 1 || mgr.ForTenant("globex").Limits.MaxRolesPerUser != 5 {
+		t.Fatalf("unexpected tenant limits: acme %+v, globex %+v", acme.Limits, mgr.ForTenant("globex").Limits)
+	}
+	alice := &User{Username: "alice"}
+	if err := acme.CreateUser(ctx, alice); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	a, b := &Role{Name: "a"}, &Role{Name: "b"}
+	for _, r := range []*Role{a, b} {
+		if err := acme.CreateRole(ctx, r); err != nil {
+			t.Fatalf("CreateRole: %v", err)
+		}
+	}
+	if err := acme.AssignRoleToUser(ctx, alice.ID, a.ID); err != nil {
+		t.Fatalf("AssignRoleToUser: %v", err)
+	}
+	if err := acme.AssignRoleToUser(ctx, alice.ID, b.ID); !errors.Is(err, ErrLimitExceeded) {
+		t.Errorf("expected the tenant override to apply, got %v", err)
+	}
+}
@@ -0,0 +1,64 @@
+package rbac
+
+import "context"
+
+// Listener is told about mutations made through the Manager after they
+// succeed, e.g. to drop entries from a downstream cache or post to a chat
+// channel. Register listeners on Manager.Listeners; they are called in
+// order, synchronously, on the goroutine that made the change, so slow work
+// belongs on a queue. Changes made by other processes, or directly on the
+// repos, are not reported.
+//
+// Embed NopListener to implement only the methods of interest; methods may
+// be added as the Manager reports more changes.
+type Listener interface {
+	OnRoleCreated(ctx context.Context, r *Role)
+	// OnRoleDeleted is called for DeleteRole and PurgeRole.
+	OnRoleDeleted(ctx context.Context, roleID string)
+	OnPermissionCreated(ctx context.Context, p *Permission)
+	// OnPermissionDeleted is called for DeletePermission and PurgePermission.
+	OnPermissionDeleted(ctx context.Context, permID string)
+	OnUserCreated(ctx context.Context, u *User)
+	OnUserDeleted(ctx context.Context, userID string)
+
+	OnRoleAssigned(ctx context.Context, userID, roleID string)
+	OnRoleUnassigned(ctx context.Context, userID, roleID string)
+	OnGroupRoleAssigned(ctx context.Context, groupID, roleID string)
+	OnGroupRoleUnassigned(ctx context.Context, groupID, roleID string)
+	OnPermissionAssigned(ctx context.Context, roleID, permID string)
+	OnPermissionRemoved(ctx context.Context, roleID, permID string)
+	OnUserAddedToGroup(ctx context.Context, ug *UserGroup)
+	OnUserRemovedFromGroup(ctx context.Context, groupID, userID string)
+	OnRoleParentAdded(ctx context.Context, roleID, parentID string)
+	OnRoleParentRemoved(ctx context.Context, roleID, parentID string)
+}
+
+// NopListener implements Listener by ignoring every change.
+type NopListener struct{}
+
+func (NopListener) OnRoleCreated(context.Context, *Role)                   {}
+func (NopListener) OnRoleDeleted(context.Context, string)                  {}
+func (NopListener) OnPermissionCreated(context.Context, *Permission)       {}
+func (NopListener) OnPermissionDeleted(context.Context, string)            {}
+func (NopListener) OnUserCreated(context.Context, *User)                   {}
+func (NopListener) OnUserDeleted(context.Context, string)                  {}
+func (NopListener) OnRoleAssigned(context.Context, string, string)         {}
+func (NopListener) OnRoleUnassigned(context.Context, string, string)       {}
+func (NopListener) OnGroupRoleAssigned(context.Context, string, string)    {}
+func (NopListener) OnGroupRoleUnassigned(context.Context, string, string)  {}
+func (NopListener) OnPermissionAssigned(context.Context, string, string)   {}
+func (NopListener) OnPermissionRemoved(context.Context, string, string)    {}
+func (NopListener) OnUserAddedToGroup(context.Context, *UserGroup)         {}
+func (NopListener) OnUserRemovedFromGroup(context.Context, string, string) {}
+func (NopListener) OnRoleParentAdded(context.Context, string, string)      {}
+func (NopListener) OnRoleParentRemoved(context.Context, string, string)    {}
+
+// emit calls fn for each listener when err is nil.
+func (m *Manager) emit(err error, fn func(Listener)) {
+	if err != nil {
+		return
+	}
+	for _, l := range m.Listeners {
+		fn(l)
+	}
+}
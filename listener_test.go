@@ -0,0 +1,92 @@
+package rbac
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+)
+
+// recordingListener records the changes it is told about.
+type recordingListener struct {
+	NopListener
+	events []string
+}
+
+func (l *recordingListener) OnRoleCreated(_ context.Context, r *Role) {
+	l.events = append(l.events, "role created "+r.ID)
+}
+
+func (l *recordingListener) OnRoleAssigned(_ context.Context, userID, roleID string) {
+	l.events = append(l.events, "role assigned "+userID+" "+roleID)
+}
+
+func (l *recordingListener) OnRoleUnassigned(_ context.Context, userID, roleID string) {
+	l.events = append(l.events, "role unassigned "+userID+" "+roleID)
+}
+
+func (l *recordingListener) OnPermissionAssigned(_ context.Context, roleID, permID string) {
+	l.events = append(l.events, "permission assigned "+roleID+" "+permID)
+}
+
+func (l *recordingListener) OnPermissionRemoved(_ context.Context, roleID, permID string) {
+	l.events = append(l.events, "permission removed "+roleID+" "+permID)
+}
+
+func (l *recordingListener) OnUserRemovedFromGroup(_ context.Context, groupID, userID string) {
+	l.events = append(l.events, "left group "+userID+" "+groupID)
+}
+
+func TestListeners(t *testing.T) {
+	ctx := context.Background()
+	mgr := NewMockRepoManager(NewMockRepo())
+	first, second := &recordingListener{}, &recordingListener{}
+	mgr.Listeners = []Listener{first, second}
+
+	if err := mgr.CreateRole(ctx, &Role{ID: "editor", Name: "editor"}); err != nil {
+		t.Fatalf("CreateRole: %v", err)
+	}
+	if err := mgr.CreatePermission(ctx, &Permission{ID: "p1", Resource: "docs/*", Action: ActionUpdate}); err != nil {
+		t.Fatalf("CreatePermission: %v", err)
+	}
+	if err := mgr.AssignPermissionToRole(ctx, "editor", "p1"); err != nil {
+		t.Fatalf("AssignPermissionToRole: %v", err)
+	}
+	if err := mgr.AssignRoleToUser(ctx, "alice", "editor"); err != nil {
+		t.Fatalf("AssignRoleToUser: %v", err)
+	}
+	if err := mgr.AddUserToGroup(ctx, &UserGroup{UserID: "alice", GroupName: "ops"}); err != nil {
+		t.Fatalf("AddUserToGroup: %v", err)
+	}
+	if err := mgr.RemoveUserFromGroup(ctx, "ops", &UserGroup{UserID: "alice", GroupName: "ops"}); err != nil {
+		t.Fatalf("RemoveUserFromGroup: %v", err)
+	}
+	if err := mgr.UnassignRoleFromUser(ctx, "alice", "editor"); err != nil {
+		t.Fatalf("UnassignRoleFromUser: %v", err)
+	}
+	if err := mgr.RemovePermissionFromRole(ctx, "editor", "p1"); err != nil {
+		t.Fatalf("RemovePermissionFromRole: %v", err)
+	}
+
+	// failed changes are not reported
+	if err := mgr.Roles.CreateRole(ctx, &Role{ID: "tpl", Name: "tpl", Template: true}); err != nil {
+		t.Fatalf("CreateRole: %v", err)
+	}
+	if err := mgr.AssignRoleToUser(ctx, "bob", "tpl"); !errors.Is(err, ErrTemplateRole) {
+		t.Fatalf("AssignRoleToUser(template) = %v; want ErrTemplateRole", err)
+	}
+
+	want := []string{
+		"role created editor",
+		"permission assigned editor p1",
+		"role assigned alice editor",
+		"left group alice ops",
+		"role unassigned alice editor",
+		"permission removed editor p1",
+	}
+	for name, l := range map[string]*recordingListener{"first": first, "second": second} {
+		if !slices.Equal(l.events, want) {
+			t.Errorf("%s listener got %q; want %q", name, l.events, want)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/Seann-Moser/rbac"
+	"github.com/Seann-Moser/rbac/loadtest"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// main seeds a synthetic policy into the chosen store and reports how fast
+// Can runs against it.
+func main() {
+	var cfg loadtest.Config
+	store := flag.String("store", "memory", "store to test: memory or mongo")
+	mongoURI := flag.String("mongo-uri", "mongodb://localhost:27017", "MongoDB connection string")
+	mongoDB := flag.String("mongo-db", "rbac_loadtest", "MongoDB database to seed")
+	cache := flag.Duration("cache", 0, "wrap the store in a CachedStore with this TTL")
+	asJSON := flag.Bool("json", false, "print the report as JSON")
+	flag.IntVar(&cfg.Users, "users", 1000, "users to create")
+	flag.IntVar(&cfg.Roles, "roles", 100, "roles to create")
+	flag.IntVar(&cfg.Permissions, "permissions", 500, "permissions to create")
+	flag.IntVar(&cfg.RolesPerUser, "roles-per-user", 3, "roles assigned to each user")
+	flag.IntVar(&cfg.PermissionsPerRole, "permissions-per-role", 10, "permissions granted to each role")
+	flag.IntVar(&cfg.Concurrency, "concurrency", 0, "concurrent workers (default GOMAXPROCS)")
+	flag.DurationVar(&cfg.Duration, "duration", 10*time.Second, "how long to run")
+	flag.IntVar(&cfg.Requests, "requests", 0, "stop after this many checks (0 for no limit)")
+	flag.Int64Var(&cfg.Seed, "seed", time.Now().UnixNano(), "random seed")
+	flag.StringVar(&cfg.Prefix, "prefix", "loadtest", "prefix for the names of seeded entities")
+	flag.Parse()
+
+	ctx := context.Background()
+	var (
+		mgr *rbac.Manager
+		err error
+	)
+	switch *store {
+	case "memory":
+		mgr, err = rbac.NewMemoryStoreManager(ctx, "", 0)
+	case "mongo":
+		var client *mongo.Client
+		if client, err = mongo.Connect(ctx, options.Client().ApplyURI(*mongoURI)); err != nil {
+			log.Fatal(err)
+		}
+		defer client.Disconnect(ctx)
+		mgr, err = rbac.NewMongoStoreManager(ctx, client.Database(*mongoDB))
+	default:
+		log.Fatalf("unknown store %q", *store)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *cache > 0 {
+		// every repo of a store manager is the same Store
+		mgr = rbac.NewCachedStoreManager(mgr.Perms.(rbac.Store), *cache)
+	}
+
+	ds, err := loadtest.Seed(ctx, mgr, cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Fprintf(os.Stderr, "seeded %d users and %d permissions in %s\n", len(ds.Users), len(ds.Permissions), ds.SeedTime.Round(time.Millisecond))
+
+	report, err := loadtest.Run(ctx, mgr, ds, cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *asJSON {
+		err = json.NewEncoder(os.Stdout).Encode(report)
+	} else {
+		err = report.WriteText(os.Stdout)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
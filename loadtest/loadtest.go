@@ -0,0 +1,255 @@
+// Package loadtest measures how many access checks a Manager sustains on a
+// given store, so deployments can be sized before they reach production.
+// Seed writes a synthetic policy of users, roles and permissions at the
+// configured scale; Run then calls Can from concurrent workers and reports
+// throughput, latency percentiles and, for a CachedStore, its hit rate:
+//
+//	cfg := loadtest.Config{Users: 10000, Roles: 200, Duration: time.Minute}
+//	ds, err := loadtest.Seed(ctx, mgr, cfg)
+//	if err != nil {
+//		return err
+//	}
+//	report, err := loadtest.Run(ctx, mgr, ds, cfg)
+//	if err != nil {
+//		return err
+//	}
+//	report.WriteText(os.Stdout)
+//
+// The rbac-loadtest command under cmd runs the same against a memory or
+// MongoDB store.
+package loadtest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"runtime"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Seann-Moser/rbac"
+)
+
+// Config sizes the synthetic policy and the load put on it. Zero values
+// take the defaults noted on each field.
+type Config struct {
+	// Users, Roles and Permissions to create. Default 1000, 100 and 500.
+	Users       int
+	Roles       int
+	Permissions int
+	// RolesPerUser and PermissionsPerRole are drawn at random from the
+	// created roles and permissions. Default 3 and 10.
+	RolesPerUser       int
+	PermissionsPerRole int
+	// Prefix namespaces the names of everything Seed creates, so runs can
+	// share a store. Default "loadtest".
+	Prefix string
+
+	// Concurrency is the number of workers calling Can. Default GOMAXPROCS.
+	Concurrency int
+	// Duration bounds the run. Default 10 seconds.
+	Duration time.Duration
+	// Requests, when positive, ends the run after that many checks, even
+	// before Duration has passed.
+	Requests int
+	// Seed makes the policy and the checks reproducible.
+	Seed int64
+}
+
+func (c Config) withDefaults() Config {
+	def := func(v *int, d int) {
+		if *v <= 0 {
+			*v = d
+		}
+	}
+	def(&c.Users, 1000)
+	def(&c.Roles, 100)
+	def(&c.Permissions, 500)
+	def(&c.RolesPerUser, 3)
+	def(&c.PermissionsPerRole, 10)
+	def(&c.Concurrency, runtime.GOMAXPROCS(0))
+	if c.Prefix == "" {
+		c.Prefix = "loadtest"
+	}
+	if c.Duration <= 0 {
+		c.Duration = 10 * time.Second
+	}
+	return c
+}
+
+// Dataset is what Seed created: the IDs of the users and the permissions
+// checks are drawn from.
+type Dataset struct {
+	Users       []string
+	Permissions []*rbac.Permission
+	// SeedTime is how long Seed took.
+	SeedTime time.Duration
+}
+
+// Seed creates the users, roles and permissions described by cfg through
+// mgr and wires them together at random.
+func Seed(ctx context.Context, mgr *rbac.Manager, cfg Config) (*Dataset, error) {
+	cfg = cfg.withDefaults()
+	start := time.Now()
+	rng := rand.New(rand.NewPCG(uint64(cfg.Seed), 1))
+	ds := &Dataset{}
+
+	for i := range cfg.Permissions {
+		p := &rbac.Permission{Resource: fmt.Sprintf("%s/resource-%d", cfg.Prefix, i), Action: rbac.ActionRead}
+		if err := mgr.CreatePermission(ctx, p); err != nil {
+			return nil, fmt.Errorf("loadtest: create permission: %w", err)
+		}
+		ds.Permissions = append(ds.Permissions, p)
+	}
+
+	roles := make([]string, 0, cfg.Roles)
+	for i := range cfg.Roles {
+		r := &rbac.Role{Name: fmt.Sprintf("%s-role-%d", cfg.Prefix, i)}
+		if err := mgr.CreateRole(ctx, r); err != nil {
+			return nil, fmt.Errorf("loadtest: create role: %w", err)
+		}
+		for _, j := range pick(rng, len(ds.Permissions), cfg.PermissionsPerRole) {
+			if err := mgr.AssignPermissionToRole(ctx, r.ID, ds.Permissions[j].ID); err != nil {
+				return nil, fmt.Errorf("loadtest: assign permission: %w", err)
+			}
+		}
+		roles = append(roles, r.ID)
+	}
+
+	for i := range cfg.Users {
+		u := &rbac.User{Username: fmt.Sprintf("%s-user-%d", cfg.Prefix, i)}
+		if err := mgr.CreateUser(ctx, u); err != nil {
+			return nil, fmt.Errorf("loadtest: create user: %w", err)
+		}
+		for _, j := range pick(rng, len(roles), cfg.RolesPerUser) {
+			if err := mgr.AssignRoleToUser(ctx, u.ID, roles[j]); err != nil {
+				return nil, fmt.Errorf("loadtest: assign role: %w", err)
+			}
+		}
+		ds.Users = append(ds.Users, u.ID)
+	}
+
+	ds.SeedTime = time.Since(start)
+	return ds, nil
+}
+
+// pick returns k distinct indexes below n, or all of them when k >= n.
+func pick(rng *rand.Rand, n, k int) []int {
+	perm := rng.Perm(n)
+	if k < n {
+		perm = perm[:k]
+	}
+	return perm
+}
+
+// Report is the outcome of a Run.
+type Report struct {
+	Checks  int64 `json:"checks"`
+	Allowed int64 `json:"allowed"`
+	Errors  int64 `json:"errors"`
+
+	Elapsed time.Duration `json:"elapsed"`
+	// QPS is Checks per second of Elapsed.
+	QPS float64 `json:"qps"`
+	// Latencies of the checks, errors included.
+	P50 time.Duration `json:"p50"`
+	P99 time.Duration `json:"p99"`
+	Max time.Duration `json:"max"`
+
+	// Cache holds the hits and misses during the run when the Manager's
+	// repos are a CachedStore, and is nil otherwise.
+	Cache *rbac.CacheStats `json:"cache,omitempty"`
+}
+
+// WriteText writes the report in a human-readable form.
+func (r *Report) WriteText(w io.Writer) error {
+	_, err := fmt.Fprintf(w, "checks:   %d (%d allowed, %d errors)\nelapsed:  %s\nqps:      %.0f\nlatency:  p50 %s  p99 %s  max %s\n",
+		r.Checks, r.Allowed, r.Errors, r.Elapsed.Round(time.Millisecond), r.QPS, r.P50, r.P99, r.Max)
+	if err == nil && r.Cache != nil {
+		_, err = fmt.Fprintf(w, "cache:    %.1f%% hits (%d hits, %d misses)\n", 100*r.Cache.HitRate(), r.Cache.Hits, r.Cache.Misses)
+	}
+	return err
+}
+
+// Run checks random users against random permissions of ds from
+// cfg.Concurrency workers until cfg.Duration has passed, cfg.Requests checks
+// were made or ctx is done.
+func Run(ctx context.Context, mgr *rbac.Manager, ds *Dataset, cfg Config) (*Report, error) {
+	cfg = cfg.withDefaults()
+	if len(ds.Users) == 0 || len(ds.Permissions) == 0 {
+		return nil, errors.New("loadtest: dataset has no users or permissions")
+	}
+	ctx, cancel := context.WithTimeout(ctx, cfg.Duration)
+	defer cancel()
+
+	cache, _ := mgr.UR.(interface{ Stats() rbac.CacheStats })
+	var before rbac.CacheStats
+	if cache != nil {
+		before = cache.Stats()
+	}
+
+	var (
+		issued    atomic.Int64
+		allowed   atomic.Int64
+		failed    atomic.Int64
+		wg        sync.WaitGroup
+		latencies = make([][]time.Duration, cfg.Concurrency)
+	)
+	start := time.Now()
+	for w := range cfg.Concurrency {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			rng := rand.New(rand.NewPCG(uint64(cfg.Seed), uint64(w)+2))
+			for ctx.Err() == nil {
+				if cfg.Requests > 0 && issued.Add(1) > int64(cfg.Requests) {
+					return
+				}
+				user := ds.Users[rng.IntN(len(ds.Users))]
+				p := ds.Permissions[rng.IntN(len(ds.Permissions))]
+				t0 := time.Now()
+				ok, err := mgr.Can(ctx, user, p.Resource, p.Action)
+				took := time.Since(t0)
+				if err != nil && ctx.Err() != nil {
+					// cut short by the end of the run
+					return
+				}
+				latencies[w] = append(latencies[w], took)
+				switch {
+				case err != nil:
+					failed.Add(1)
+				case ok:
+					allowed.Add(1)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+
+	all := slices.Concat(latencies...)
+	slices.Sort(all)
+	r := &Report{
+		Checks:  int64(len(all)),
+		Allowed: allowed.Load(),
+		Errors:  failed.Load(),
+		Elapsed: elapsed,
+	}
+	if elapsed > 0 {
+		r.QPS = float64(r.Checks) / elapsed.Seconds()
+	}
+	if n := len(all); n > 0 {
+		r.P50 = all[(n-1)*50/100]
+		r.P99 = all[(n-1)*99/100]
+		r.Max = all[n-1]
+	}
+	if cache != nil {
+		after := cache.Stats()
+		r.Cache = &rbac.CacheStats{Hits: after.Hits - before.Hits, Misses: after.Misses - before.Misses}
+	}
+	return r, nil
+}
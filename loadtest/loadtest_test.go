@@ -0,0 +1,51 @@
+package loadtest
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Seann-Moser/rbac"
+)
+
+func TestSeedAndRun(t *testing.T) {
+	ctx := context.Background()
+	store, err := rbac.NewMemoryStore(ctx, "")
+	if err != nil {
+		t.Fatalf("NewMemoryStore: %v", err)
+	}
+	mgr := rbac.NewCachedStoreManager(store, time.Minute)
+	cfg := Config{Users: 20, Roles: 5, Permissions: 10, PermissionsPerRole: 4, RolesPerUser: 2, Concurrency: 4, Requests: 200, Seed: 7}
+
+	ds, err := Seed(ctx, mgr, cfg)
+	if err != nil {
+		t.Fatalf("Seed: %v", err)
+	}
+	if len(ds.Users) != 20 || len(ds.Permissions) != 10 {
+		t.Fatalf("unexpected dataset: %d users, %d permissions", len(ds.Users), len(ds.Permissions))
+	}
+
+	r, err := Run(ctx, mgr, ds, cfg)
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if r.Checks != 200 || r.Errors != 0 {
+		t.Errorf("expected 200 checks without errors, got %d and %d", r.Checks, r.Errors)
+	}
+	if r.Allowed == 0 || r.Allowed == r.Checks {
+		t.Errorf("expected a mix of allowed and denied checks, got %d of %d allowed", r.Allowed, r.Checks)
+	}
+	if r.QPS <= 0 || r.P50 > r.P99 || r.P99 > r.Max {
+		t.Errorf("inconsistent timings %+v", r)
+	}
+	if r.Cache == nil || r.Cache.Hits == 0 {
+		t.Errorf("expected cache hits, got %+v", r.Cache)
+	}
+
+	var buf bytes.Buffer
+	if err := r.WriteText(&buf); err != nil || !strings.Contains(buf.String(), "cache:") {
+		t.Errorf("WriteText = %q, %v", buf.String(), err)
+	}
+}
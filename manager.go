@@ -2,10 +2,12 @@ package rbac
 
 import (
 	"context"
-	"path"
-	"strings"
+	"fmt"
+	"slices"
+	"sync/atomic"
 	"time"
 
+	"github.com/Seann-Moser/rbac/rbaceval"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/metric"
@@ -37,75 +39,260 @@ func init() {
 }
 
 type Manager struct {
-	Perms           PermissionRepo
-	Roles           RoleRepo
-	Users           UserRepo
-	RP              RolePermissionRepo
-	UR              UserRoleRepo
-	UG              UserGroupRepo
-	GR              GroupRoleRepo
+	Perms PermissionRepo
+	Roles RoleRepo
+	Users UserRepo
+	RP    RolePermissionRepo
+	UR    UserRoleRepo
+	UG    UserGroupRepo
+	GR    GroupRoleRepo
+
+	// DefaultRoleName names the role every user holds without being assigned
+	// it, once a role of that name exists. The Manager adds it to the roles
+	// the store lists for a user, so it counts in every decision; leave it
+	// empty to disable it. A Manager returned by ForTenant looks the name up
+	// among the tenant's roles.
 	DefaultRoleName string
+
+	// Groups, when set, stores groups as entities; see CreateGroup.
+	Groups GroupRepo
+	// Attestations, when set, stores role certifications; see CertifyRole.
+	Attestations AttestationRepo
+	// Archives, when set, stores archived roles and groups; see ArchiveRole.
+	Archives ArchiveRepo
+	// PolicyVersions, when set, stores policy snapshots; see SnapshotPolicy.
+	PolicyVersions PolicyVersionRepo
+	// APIKeys, when set, stores API keys; see MintAPIKey.
+	APIKeys APIKeyRepo
+	// Sessions, when set, stores login sessions; see CreateSession.
+	Sessions SessionRepo
+	// SoD, when set, stores separation-of-duties constraints, which role
+	// assignments are checked against; see CreateSoDConstraint.
+	SoD SoDRepo
+	// Approvals, when set, stores role assignment requests awaiting a
+	// second user's approval; see RequestRoleAssignment.
+	Approvals ApprovalRepo
+	// Delegations, when set, stores roles users lend each other for a
+	// while; see DelegateRole.
+	Delegations DelegationRepo
+	// PermSets, when set, stores permission sets, whose permissions the
+	// roles bound to them grant; see CreatePermissionSet.
+	PermSets PermissionSetRepo
+
+	// IDs, when set, assigns IDs to entities created through the Manager
+	// before they reach the store, so IDs look the same on every backend.
+	// When nil each store falls back to its own generator.
+	IDs IDGenerator
+
+	// Tenants enables CreateTenant, ExportTenant and DeleteTenant.
+	// TenantTemplate overrides DefaultTenantTemplate for new tenants.
+	Tenants        TenantRepo
+	TenantTemplate *TenantTemplate
+
+	// Usage, when set, counts the permissions that allow Can decisions;
+	// see PermissionUsage.
+	Usage *UsageTracker
+
+	// Pool runs batch operations such as CanBatch. When nil each batch gets
+	// a default pool with GOMAXPROCS workers and no rate limit.
+	Pool *WorkerPool
+
+	// Catalog, when set, names the teams that own resources; creating a
+	// permission on their resources or attaching one to a role notifies
+	// them through Notifier, which may be nil when acknowledgments are
+	// collected from Catalog.Pending alone.
+	Catalog  *ResourceCatalog
+	Notifier Notifier
+
+	// Resources, when set, lists the resource types permissions may be
+	// created on; see ResourceRegistry.
+	Resources *ResourceRegistry
+	// Actions, when set, lists the actions permissions may be created with
+	// and the groups Can expands; see ActionRegistry.
+	Actions *ActionRegistry
+
+	// Limits caps role assignments and group memberships; exceeding one
+	// fails with a LimitError. TenantLimits overrides Limits, as a whole,
+	// for the Managers ForTenant returns.
+	Limits       Limits
+	TenantLimits map[string]Limits
+
+	// Strict makes Can and HasPermission fail with a StrictError when the
+	// user, one of their roles, or a permission bound to those roles does not
+	// exist, instead of quietly evaluating to false.
+	Strict bool
+
+	// RequireVerifiedEmail makes Can, CanForSession and HasPermission deny
+	// users whose EmailVerified is false, as they do suspended users.
+	RequireVerifiedEmail bool
+
+	// SuperAdminRoleName, when set, names a role whose holders Can allows
+	// everything, whatever their roles' permissions say, so bootstrap admins
+	// need no */* permission. Only inactive users are still denied. The
+	// Decision reports the bypass in SuperAdmin, and Audit records every such
+	// decision, sampled or not. Managers returned by ForTenant have none.
+	SuperAdminRoleName string
+
+	// TraceStoreCalls adds a span event for each store read an access check
+	// makes to the caller's span. Checks always annotate a recording span in
+	// their context with the decision, the deciding permission and role, and
+	// CachedStore hits, and record the reads as child spans of their own.
+	TraceStoreCalls bool
+
+	// Authorizers is the chain access checks run through, in order: the
+	// first link to return a Decision settles the request. Nil runs
+	// DefaultAuthorizers. Add application rules, e.g. that a resource's
+	// owner is always allowed, as links of their own rather than roles;
+	// put them after ActiveUserAuthorizer unless suspended users should
+	// pass them too. Decisions caches whatever the chain decides, so links
+	// that look at more than the user, resource and action need attrs or
+	// no DecisionCache.
+	Authorizers []Authorizer
+
+	// Decisions, when set, caches access decisions; see DecisionCache.
+	Decisions *DecisionCache
+
+	// Listeners are told about changes made through the Manager; see
+	// Listener.
+	Listeners []Listener
+
+	// Audit, when set, records every policy change made through the Manager,
+	// and the fraction AuditDecisions, from 0 to 1, of its access decisions;
+	// see AuditEntry.
+	Audit          AuditRepo
+	AuditDecisions float64
+
+	// version counts policy changes made through this Manager; see PolicyVersion.
+	version atomic.Uint64
+
+	// base is the Manager a ForTenant Manager was derived from.
+	base *Manager
 }
 
+// assignID fills *id from the Manager's IDGenerator when one is configured
+// and the caller did not supply an ID.
+func (m *Manager) assignID(id *string, kind string) {
+	if m.IDs != nil && *id == "" {
+		*id = m.IDs.NewID(kind)
+	}
+}
+
+// AssignRoleToGroup gives every member of groupID roleID. Template roles are
+// rejected with ErrTemplateRole.
 func (m *Manager) AssignRoleToGroup(ctx context.Context, groupID, roleID string) error {
 	start := time.Now()
-	err := m.GR.AddRoleToGroup(ctx, groupID, roleID)
+	ctx, span := m.startSpan(ctx, "AssignRoleToGroup")
+	defer span.End()
+	err := m.checkAssignable(ctx, roleID)
+	if err == nil {
+		err = m.checkGroupDuties(ctx, groupID, roleID)
+	}
+	if err == nil {
+		err = m.GR.AddRoleToGroup(ctx, groupID, roleID)
+	}
 	m.record(ctx, start, "AssignRoleToGroup", err)
+	m.changed(ctx, "AssignRoleToGroup", groupID, err)
+	m.emit(err, func(l Listener) { l.OnGroupRoleAssigned(ctx, groupID, roleID) })
 	return err
 }
 
 func (m *Manager) UnassignRoleFromGroup(ctx context.Context, groupID, roleID string) error {
 	start := time.Now()
-	err := m.GR.RemoveRoleFromGroup(ctx, groupID, roleID)
+	ctx, span := m.startSpan(ctx, "UnassignRoleFromGroup")
+	defer span.End()
+	err := m.checkSource(ctx, KindGroupRole, groupID, roleID)
+	if err == nil {
+		err = m.GR.RemoveRoleFromGroup(ctx, groupID, roleID)
+	}
 	m.record(ctx, start, "UnassignRoleFromGroup", err)
+	m.changed(ctx, "UnassignRoleFromGroup", groupID, err)
+	m.emit(err, func(l Listener) { l.OnGroupRoleUnassigned(ctx, groupID, roleID) })
 	return err
 }
 
 func (m *Manager) ListRolesForGroup(ctx context.Context, groupID string) ([]string, error) {
 	start := time.Now()
+	ctx, span := m.startSpan(ctx, "ListRolesForGroup")
+	defer span.End()
 	roles, err := m.GR.ListRolesForGroup(ctx, groupID)
 	m.record(ctx, start, "ListRolesForGroup", err)
 	return roles, err
 }
 
-// CreateRole instruments the CreateRole call.
-func (m *Manager) CreateRole(ctx context.Context, r *Role) error {
+// ListGroupsForRole returns the groups roleID is assigned to.
+func (m *Manager) ListGroupsForRole(ctx context.Context, roleID string) ([]string, error) {
 	start := time.Now()
-	err := m.Roles.CreateRole(ctx, r)
-	m.record(ctx, start, "CreateRole", err)
-	return err
+	ctx, span := m.startSpan(ctx, "ListGroupsForRole")
+	defer span.End()
+	groups, err := m.GR.ListGroupsForRole(ctx, roleID)
+	m.record(ctx, start, "ListGroupsForRole", err)
+	return groups, err
 }
 
-func (m *Manager) DeleteRole(ctx context.Context, id string) error {
+// CreateRole instruments the CreateRole call. Generators that do not parse
+// are rejected with ErrInvalidGenerator, and a name another role has with
+// ErrRoleNameTaken.
+func (m *Manager) CreateRole(ctx context.Context, r *Role) error {
 	start := time.Now()
-	err := m.Roles.DeleteRole(ctx, id)
-	m.record(ctx, start, "DeleteRole", err)
+	ctx, span := m.startSpan(ctx, "CreateRole")
+	defer span.End()
+	err := checkGenerators(r)
+	if err == nil {
+		err = m.checkRoleName(ctx, r)
+	}
+	if err == nil {
+		m.assignID(&r.ID, KindRole)
+		stampCreated(ctx, start, &r.CreatedBy, &r.UpdatedBy, &r.UpdatedAt)
+		err = m.Roles.CreateRole(ctx, r)
+	}
+	m.record(ctx, start, "CreateRole", err)
+	m.changed(ctx, "CreateRole", r.ID, err)
+	m.emit(err, func(l Listener) { l.OnRoleCreated(ctx, r) })
 	return err
 }
 
 func (m *Manager) GetRole(ctx context.Context, id string) (*Role, error) {
 	start := time.Now()
+	ctx, span := m.startSpan(ctx, "GetRole")
+	defer span.End()
 	role, err := m.Roles.GetRoleByID(ctx, id)
 	m.record(ctx, start, "GetRole", err)
 	return role, err
 }
 
+// CreateUser instruments the CreateUser call. A username or email another
+// user has is rejected with ErrUsernameTaken or ErrEmailTaken.
 func (m *Manager) CreateUser(ctx context.Context, u *User) error {
 	start := time.Now()
-	err := m.Users.CreateUser(ctx, u)
+	ctx, span := m.startSpan(ctx, "CreateUser")
+	defer span.End()
+	err := m.checkUserUnique(ctx, u)
+	if err == nil {
+		m.assignID(&u.ID, KindUser)
+		stampCreated(ctx, start, &u.CreatedBy, &u.UpdatedBy, &u.UpdatedAt)
+		err = m.Users.CreateUser(ctx, u)
+	}
 	m.record(ctx, start, "CreateUser", err)
+	m.changedUser(ctx, "CreateUser", u.ID, err)
+	m.emit(err, func(l Listener) { l.OnUserCreated(ctx, u) })
 	return err
 }
 
 func (m *Manager) DeleteUser(ctx context.Context, id string) error {
 	start := time.Now()
+	ctx, span := m.startSpan(ctx, "DeleteUser")
+	defer span.End()
 	err := m.Users.DeleteUser(ctx, id)
 	m.record(ctx, start, "DeleteUser", err)
+	m.changedUser(ctx, "DeleteUser", id, err)
+	m.emit(err, func(l Listener) { l.OnUserDeleted(ctx, id) })
 	return err
 }
 
 func (m *Manager) GetUser(ctx context.Context, id string) (*User, error) {
 	start := time.Now()
+	ctx, span := m.startSpan(ctx, "GetUser")
+	defer span.End()
 	user, err := m.Users.GetUserByID(ctx, id)
 	m.record(ctx, start, "GetUser", err)
 	return user, err
@@ -113,71 +300,242 @@ func (m *Manager) GetUser(ctx context.Context, id string) (*User, error) {
 
 func (m *Manager) AssignPermissionToRole(ctx context.Context, roleID, permID string) error {
 	start := time.Now()
+	ctx, span := m.startSpan(ctx, "AssignPermissionToRole")
+	defer span.End()
 	err := m.RP.AddRP(ctx, roleID, permID)
 	m.record(ctx, start, "AssignPermissionToRole", err)
+	m.changedRole(ctx, "AssignPermissionToRole", roleID, err)
+	m.emit(err, func(l Listener) { l.OnPermissionAssigned(ctx, roleID, permID) })
+	if err == nil && m.Catalog != nil {
+		p, perr := m.Perms.GetPermissionByID(ctx, permID)
+		if perr != nil {
+			m.record(ctx, start, "NotifyOwners", perr)
+		}
+		m.notifyOwners(ctx, ChangePermissionAttached, p, roleID)
+	}
 	return err
 }
 
 func (m *Manager) RemovePermissionFromRole(ctx context.Context, roleID, permID string) error {
 	start := time.Now()
-	err := m.RP.Remove(ctx, roleID, permID)
+	ctx, span := m.startSpan(ctx, "RemovePermissionFromRole")
+	defer span.End()
+	err := m.checkSource(ctx, KindRolePermission, roleID, permID)
+	if err == nil {
+		err = m.RP.Remove(ctx, roleID, permID)
+	}
 	m.record(ctx, start, "RemovePermissionFromRole", err)
+	m.changedRole(ctx, "RemovePermissionFromRole", roleID, err)
+	m.emit(err, func(l Listener) { l.OnPermissionRemoved(ctx, roleID, permID) })
 	return err
 }
 
 func (m *Manager) ListPermissionsForRole(ctx context.Context, roleID string) ([]string, error) {
 	start := time.Now()
+	ctx, span := m.startSpan(ctx, "ListPermissionsForRole")
+	defer span.End()
 	perms, err := m.RP.ListPermissions(ctx, roleID)
 	m.record(ctx, start, "ListPermissionsForRole", err)
 	return perms, err
 }
 
+// AssignRoleToUser gives userID roleID. Template roles are rejected with
+// ErrTemplateRole.
 func (m *Manager) AssignRoleToUser(ctx context.Context, userID, roleID string) error {
 	start := time.Now()
-	err := m.UR.AddUR(ctx, userID, roleID)
+	ctx, span := m.startSpan(ctx, "AssignRoleToUser")
+	defer span.End()
+	err := m.checkAssignable(ctx, roleID)
+	if err == nil {
+		err = m.checkDuties(ctx, userID, roleID)
+	}
+	if err == nil {
+		err = m.checkRoleLimit(ctx, userID, roleID)
+	}
+	if err == nil {
+		err = m.UR.AddUR(ctx, userID, roleID)
+	}
 	m.record(ctx, start, "AssignRoleToUser", err)
+	m.changedUser(ctx, "AssignRoleToUser", userID, err)
+	m.emit(err, func(l Listener) { l.OnRoleAssigned(ctx, userID, roleID) })
 	return err
 }
 
 func (m *Manager) UnassignRoleFromUser(ctx context.Context, userID, roleID string) error {
 	start := time.Now()
-	err := m.UR.RemoveUR(ctx, userID, roleID)
+	ctx, span := m.startSpan(ctx, "UnassignRoleFromUser")
+	defer span.End()
+	err := m.checkSource(ctx, KindUserRole, userID, roleID)
+	if err == nil {
+		err = m.UR.RemoveUR(ctx, userID, roleID)
+	}
 	m.record(ctx, start, "UnassignRoleFromUser", err)
+	m.changedUser(ctx, "UnassignRoleFromUser", userID, err)
+	m.emit(err, func(l Listener) { l.OnRoleUnassigned(ctx, userID, roleID) })
 	return err
 }
 
+// ListRolesForUser returns the roles assigned to userID directly, and the
+// default role (see DefaultRoleName).
 func (m *Manager) ListRolesForUser(ctx context.Context, userID string) ([]string, error) {
 	start := time.Now()
-	roles, err := m.UR.ListRoles(ctx, userID)
+	ctx, span := m.startSpan(ctx, "ListRolesForUser")
+	defer span.End()
+	roles, err := m.userRoles(ctx, userID)
 	m.record(ctx, start, "ListRolesForUser", err)
 	return roles, err
 }
 
+// userRoles returns the roles assigned to userID directly, with the default
+// role.
+func (m *Manager) userRoles(ctx context.Context, userID string) ([]string, error) {
+	roles, err := m.UR.ListRoles(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return m.withDefaultRole(ctx, roles)
+}
+
+// withDefaultRole appends the role named DefaultRoleName to roles, unless it
+// is there already or does not exist. A failed lookup is returned, so checks
+// fail rather than silently losing the default role's permissions.
+func (m *Manager) withDefaultRole(ctx context.Context, roles []string) ([]string, error) {
+	if m.DefaultRoleName == "" {
+		return roles, nil
+	}
+	r, err := m.Roles.GetRoleByName(ctx, m.DefaultRoleName)
+	if err != nil {
+		return nil, err
+	}
+	if r == nil || slices.Contains(roles, r.ID) {
+		return roles, nil
+	}
+	return append(roles, r.ID), nil
+}
+
+// ListUsersForRole returns the users assigned roleID directly, e.g. to see
+// who is affected before changing or deleting it. Users who hold the role
+// only through a group are found with ListGroupsForRole, and the default role
+// every user holds is listed only for users assigned it.
+func (m *Manager) ListUsersForRole(ctx context.Context, roleID string) ([]string, error) {
+	start := time.Now()
+	ctx, span := m.startSpan(ctx, "ListUsersForRole")
+	defer span.End()
+	users, err := m.UR.ListUsersForRole(ctx, roleID)
+	m.record(ctx, start, "ListUsersForRole", err)
+	return users, err
+}
+
+// AddUserToGroup adds a member to a group. If the group exists as a Group
+// with DefaultRoles, the user is also granted those roles, until the
+// membership's ExpiresAt when it has one. Adding a current member again
+// replaces their membership, level included.
 func (m *Manager) AddUserToGroup(ctx context.Context, ug *UserGroup) error {
 	start := time.Now()
-	err := m.UG.AddUserToGroup(ctx, ug)
+	ctx, span := m.startSpan(ctx, "AddUserToGroup")
+	defer span.End()
+	m.assignID(&ug.ID, KindUserGroup)
+	err := checkMembershipLevel(ug.Level)
+	if err == nil {
+		err = m.checkBan(ctx, ug)
+	}
+	if err == nil {
+		err = m.checkMemberLimit(ctx, ug.GroupName, ug.UserID)
+	}
+	if err == nil {
+		err = m.checkJoinDuties(ctx, ug)
+	}
+	if err == nil {
+		stampCreated(ctx, start, &ug.CreatedBy, &ug.UpdatedBy, &ug.UpdatedAt)
+		err = m.UG.AddUserToGroup(ctx, ug)
+	}
+	if err == nil {
+		err = m.grantGroupDefaults(ctx, ug)
+	}
 	m.record(ctx, start, "AddUserToGroup", err)
+	m.changedUser(ctx, "AddUserToGroup", ug.UserID, err)
+	m.emit(err, func(l Listener) { l.OnUserAddedToGroup(ctx, ug) })
 	return err
 }
 
+// RemoveUserFromGroup removes a member from a group and revokes the group's
+// DefaultRoles from them, except those another of their groups also grants
+// and those assigned to them by other means.
 func (m *Manager) RemoveUserFromGroup(ctx context.Context, groupID string, ug *UserGroup) error {
 	start := time.Now()
-	err := m.UG.RemoveUserFromGroup(ctx, groupID, ug)
+	ctx, span := m.startSpan(ctx, "RemoveUserFromGroup")
+	defer span.End()
+	err := m.checkSource(ctx, KindUserGroup, ug.UserID, groupID)
+	if err == nil {
+		err = m.UG.RemoveUserFromGroup(ctx, groupID, ug)
+	}
+	if err == nil {
+		err = m.revokeGroupDefaults(ctx, ug.UserID, groupID, nil)
+	}
 	m.record(ctx, start, "RemoveUserFromGroup", err)
+	m.changedUser(ctx, "RemoveUserFromGroup", ug.UserID, err)
+	m.emit(err, func(l Listener) { l.OnUserRemovedFromGroup(ctx, groupID, ug.UserID) })
 	return err
 }
 
 func (m *Manager) GetUsersByGroupID(ctx context.Context, groupID string) ([]*UserGroup, error) {
 	start := time.Now()
+	ctx, span := m.startSpan(ctx, "GetUsersByGroupID")
+	defer span.End()
 	list, err := m.UG.GetUsersByGroupID(ctx, groupID)
+	if err == nil {
+		list, err = m.withoutBannedMembers(ctx, groupID, list)
+	}
 	m.record(ctx, start, "GetUsersByGroupID", err)
 	return list, err
 }
 
-// CreatePermission instruments the underlying repo call.
+// CreatePermission instruments the underlying repo call. Creating a
+// resource and action that already have a permission fills p with that
+// permission instead. A Name held by a different permission is rejected
+// with ErrPermissionNameTaken when the repo implements PermissionNameGetter.
 func (m *Manager) CreatePermission(ctx context.Context, p *Permission) error {
 	start := time.Now()
-	err := m.Perms.CreatePermission(ctx, p)
+	ctx, span := m.startSpan(ctx, "CreatePermission")
+	defer span.End()
+	existing, err := m.Perms.GetPermissionByResource(ctx, p.Resource, p.Action)
+	if err == nil && existing != nil {
+		// creating the same resource and action again is idempotent, and
+		// brings back a soft-deleted permission
+		if sd, ok := m.Perms.(SoftDeleteRepo); ok && existing.DeletedAt != 0 {
+			if err = sd.SetPermissionDeletedAt(ctx, existing.ID, 0); err != nil {
+				m.record(ctx, start, "CreatePermission", err)
+				return err
+			}
+			existing.DeletedAt = 0
+			m.changed(ctx, "CreatePermission", existing.ID, nil)
+		}
+		*p = *existing
+		m.record(ctx, start, "CreatePermission", nil)
+		return nil
+	}
+	if err == nil && (rbaceval.HasParams(p.Resource) || rbaceval.IsRegex(p.Resource)) {
+		_, err = matchResource(p.Resource, "")
+	}
+	if err == nil && m.Actions != nil {
+		err = m.Actions.Validate(p.Action)
+	}
+	if err == nil && m.Resources != nil {
+		err = m.Resources.Validate(p.Resource, p.Action)
+	}
+	if err == nil && p.Condition != "" {
+		if _, perr := rbaceval.ParseCondition(p.Condition); perr != nil {
+			err = fmt.Errorf("%w: %v", ErrInvalidCondition, perr)
+		}
+	}
+	if err == nil {
+		err = m.checkPermissionName(ctx, p)
+	}
+	if err == nil {
+		m.assignID(&p.ID, KindPermission)
+		stampCreated(ctx, start, &p.CreatedBy, &p.UpdatedBy, &p.UpdatedAt)
+		err = m.Perms.CreatePermission(ctx, p)
+	}
 
 	// common attributes
 	attrs := []attribute.KeyValue{
@@ -188,6 +546,11 @@ func (m *Manager) CreatePermission(ctx context.Context, p *Permission) error {
 	if err != nil {
 		errorCounter.Add(ctx, 1, metric.WithAttributes(attrs...))
 	}
+	m.changed(ctx, "CreatePermission", p.ID, err)
+	m.emit(err, func(l Listener) { l.OnPermissionCreated(ctx, p) })
+	if err == nil {
+		m.notifyOwners(ctx, ChangePermissionCreated, p, "")
+	}
 	return err
 }
 
@@ -199,47 +562,64 @@ func (m *Manager) record(ctx context.Context, start time.Time, method string, er
 	latencyRecorder.Record(ctx, time.Since(start).Seconds(), metric.WithAttributes(attrs...))
 	if err != nil {
 		errorCounter.Add(ctx, 1, metric.WithAttributes(attrs...))
+		spanError(ctx, method, err)
 	}
 }
 
-func (m *Manager) DeletePermission(ctx context.Context, id string) error {
-	start := time.Now()
-	err := m.Perms.DeletePermission(ctx, id)
-	attrs := []attribute.KeyValue{attribute.String("method", "DeletePermission")}
-	requestCounter.Add(ctx, 1, metric.WithAttributes(attrs...))
-	latencyRecorder.Record(ctx, time.Since(start).Seconds(), metric.WithAttributes(attrs...))
-	if err != nil {
-		errorCounter.Add(ctx, 1, metric.WithAttributes(attrs...))
-	}
-	return err
-}
-
 func (m *Manager) GetPermission(ctx context.Context, id string) (*Permission, error) {
 	start := time.Now()
+	ctx, span := m.startSpan(ctx, "GetPermission")
+	defer span.End()
 	perm, err := m.Perms.GetPermissionByID(ctx, id)
 	attrs := []attribute.KeyValue{attribute.String("method", "GetPermission")}
 	requestCounter.Add(ctx, 1, metric.WithAttributes(attrs...))
 	latencyRecorder.Record(ctx, time.Since(start).Seconds(), metric.WithAttributes(attrs...))
 	if err != nil {
 		errorCounter.Add(ctx, 1, metric.WithAttributes(attrs...))
+		spanError(ctx, "GetPermission", err)
 	}
 	return perm, err
 }
 
 // ... repeat the same wrapping for CreateRole, DeleteRole, GetRole, etc. ...
 
+// HasPermission reports whether one of userID's roles is bound to permID.
+// It sees the roles Can does, held directly, through groups, by delegation
+// or by inheritance, except roles held in a scope, which need a resource.
 func (m *Manager) HasPermission(ctx context.Context, userID, permID string) (bool, error) {
 	start := time.Now()
+	ctx, span := m.startSpan(ctx, "HasPermission")
+	defer span.End()
 	ok, err := func() (bool, error) {
-		roles, err := m.UR.ListRoles(ctx, userID)
+		if active, err := m.userActive(ctx, userID); err != nil || !active {
+			return false, err
+		}
+		roles, _, err := m.resolveRoles(withoutRequestContext(ctx), nil, start, "HasPermission", userID, "")
 		if err != nil {
 			return false, err
 		}
+		if err := m.strictCheck(ctx, userID, roles); err != nil {
+			return false, err
+		}
+		if m.Strict {
+			p, err := m.Perms.GetPermissionByID(ctx, permID)
+			if err != nil {
+				return false, err
+			}
+			if p == nil {
+				return false, m.strictViolation(ctx, &StrictError{Err: ErrUnknownPermission, ID: permID})
+			}
+		}
 		for _, r := range roles {
 			perms, err := m.RP.ListPermissions(ctx, r)
 			if err != nil {
 				return false, err
 			}
+			fromSets, err := m.setPermissions(ctx, r)
+			if err != nil {
+				return false, err
+			}
+			perms = append(perms, fromSets...)
 			for _, p := range perms {
 				if p == permID {
 					return true, nil
@@ -254,12 +634,15 @@ func (m *Manager) HasPermission(ctx context.Context, userID, permID string) (boo
 	latencyRecorder.Record(ctx, time.Since(start).Seconds(), metric.WithAttributes(attrs...))
 	if err != nil {
 		errorCounter.Add(ctx, 1, metric.WithAttributes(attrs...))
+		spanError(ctx, "HasPermission", err)
 	}
 	return ok, err
 }
 
 func (m *Manager) GetGroupsByUserID(ctx context.Context, userID string) ([]*UserGroup, error) {
 	start := time.Now()
+	ctx, span := m.startSpan(ctx, "GetGroupsByUserID")
+	defer span.End()
 	groups, err := m.UG.GetGroupsByUserID(ctx, userID)
 	m.record(ctx, start, "GetGroupsByUserID", err)
 	return groups, err
@@ -267,91 +650,274 @@ func (m *Manager) GetGroupsByUserID(ctx context.Context, userID string) ([]*User
 
 // manager.go (update)
 func (m *Manager) Can(ctx context.Context, userID, resource string, action Action) (bool, error) {
+	return m.can(ctx, "Can", userID, resource, action, nil)
+}
+
+func (m *Manager) can(ctx context.Context, method, userID, resource string, action Action, attrs map[string]any) (bool, error) {
+	d, err := m.decide(ctx, method, userID, resource, action, attrs)
+	if err != nil {
+		return false, err
+	}
+	return d.Allowed, nil
+}
+
+func (m *Manager) decide(ctx context.Context, method, userID, resource string, action Action, attrs map[string]any) (*Decision, error) {
+	ctx, span := m.startSpan(ctx, method)
+	defer span.End()
+	d, err := m.cachedDecide(ctx, method, userID, resource, action, attrs)
+	m.auditDecision(ctx, method, userID, resource, action, d, err)
+	span.SetAttributes(decisionAttributes(resource, action, d, err)...)
+	return d, err
+}
+
+// cachedDecide serves the decision from Decisions when it may.
+func (m *Manager) cachedDecide(ctx context.Context, method, userID, resource string, action Action, attrs map[string]any) (*Decision, error) {
+	// attributes can change the outcome and Explain needs the whole path,
+	// so neither is served from the cache
+	c := m.Decisions
+	if c == nil || attrs != nil || explainerFrom(ctx) != nil {
+		d, _, err := m.resolve(ctx, method, userID, resource, action, attrs)
+		return d, err
+	}
 	start := time.Now()
+	if d, ok := c.get(userID, resource, action); ok {
+		if d.Allowed && d.PermissionID != "" && m.Usage != nil {
+			m.Usage.Record(d.PermissionID)
+		}
+		m.record(ctx, start, method, nil)
+		return d, nil
+	}
+	gen := c.generation()
+	d, roles, err := m.resolve(ctx, method, userID, resource, action, attrs)
+	if err == nil {
+		c.put(userID, resource, action, d, roles, gen)
+	}
+	return d, err
+}
+
+// resolve decides the request from the store and also returns the expanded
+// roles it considered.
+func (m *Manager) resolve(ctx context.Context, method, userID, resource string, action Action, attrs map[string]any) (d *Decision, roles []string, err error) {
+	start := time.Now()
+	ctx, tr := m.startDecisionTrace(ctx)
+	defer func() { tr.finish(resource, action, d, err) }()
+
+	roles, _, err = m.resolveRoles(ctx, tr, start, method, userID, resource)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := m.strictCheck(ctx, userID, roles); err != nil {
+		m.record(ctx, start, method, err)
+		return nil, nil, err
+	}
+
+	d, err = m.evaluate(ctx, start, tr, method, userID, roles, resource, action, attrs)
+	return d, roles, err
+}
+
+// resolveRoles returns the roles userID holds for a check on resource,
+// expanded through the role hierarchy, and their current groups. Can,
+// CanBatch, HasPermission, sessions and ListEffectivePermissions all take a
+// user's roles from it, so they see the same set. Without a resource, roles
+// held in a scope are left out.
+// A failed lookup is recorded against method and fails the check: a role
+// it would have found may hold a deny rule.
+func (m *Manager) resolveRoles(ctx context.Context, tr *decisionTrace, start time.Time, method, userID, resource string) ([]string, []*UserGroup, error) {
+	// 1) and 2) collect direct user roles and those of their groups
+	roles, groups, err := m.memberRoles(ctx, tr, start, method, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	ex := explainerFrom(ctx)
+
+	// 3) add the roles they hold in a scope covering the resource
+	if resource != "" {
+		callStart := time.Now()
+		scoped, err := m.scopedRoles(ctx, userID, groups, resource)
+		tr.storeCall("ScopedRoles", callStart, err)
+		if err != nil {
+			m.record(ctx, start, method, err)
+			return nil, nil, err
+		}
+		roles = append(roles, scoped...)
+		ex.grant(ViaScope, "", scoped)
+	}
+
+	// and the constrained roles the request meets
+	callStart := time.Now()
+	constrained, err := m.constrainedRoles(ctx, userID)
+	tr.storeCall("ConstrainedRoles", callStart, err)
+	if err != nil {
+		m.record(ctx, start, method, err)
+		return nil, nil, err
+	}
+	roles = append(roles, constrained...)
+	ex.grant(ViaConstraint, "", constrained)
+
+	// and the roles other users delegated to them
+	callStart = time.Now()
+	delegated, err := m.delegatedRoles(ctx, userID, start)
+	tr.storeCall("DelegatedRoles", callStart, err)
+	if err != nil {
+		m.record(ctx, start, method, err)
+		return nil, nil, err
+	}
+	roles = append(roles, delegated...)
+	ex.grant(ViaDelegation, "", delegated)
+
+	// a role held several ways is evaluated once
+	roles = uniqueIDs(roles)
+
+	// 4) add the roles they inherit from
+	callStart = time.Now()
+	roles, err = m.expandRoles(ctx, roles)
+	tr.storeCall("ExpandRoles", callStart, err)
+	if err != nil {
+		m.record(ctx, start, method, err)
+		return nil, nil, err
+	}
+	ex.inherited(roles)
+	return roles, groups, nil
+}
 
-	// 1) collect direct user roles
+// memberRoles returns the roles userID holds directly and through their
+// current groups, and those groups. A failed lookup is recorded against
+// method and returned.
+func (m *Manager) memberRoles(ctx context.Context, tr *decisionTrace, start time.Time, method, userID string) ([]string, []*UserGroup, error) {
 	roles, err := m.UR.ListRoles(ctx, userID)
+	tr.storeCall("ListRoles", start, err)
 	if err != nil {
-		m.record(ctx, start, "Can", err)
-	} else if roles == nil {
+		m.record(ctx, start, method, err)
+		return nil, nil, err
+	}
+	if roles, err = m.withDefaultRole(ctx, roles); err != nil {
+		m.record(ctx, start, method, err)
+		return nil, nil, err
+	}
+	if roles == nil {
 		roles = []string{}
 	}
+	ex := explainerFrom(ctx)
+	ex.grant(ViaDirect, "", roles)
 
-	// 2) collect groups this user belongs to
+	callStart := time.Now()
 	groups, err := m.UG.GetGroupsByUserID(ctx, userID)
+	tr.storeCall("GetGroupsByUserID", callStart, err)
+	if err != nil {
+		m.record(ctx, start, method, err)
+		return nil, nil, err
+	}
+	groups = activeMemberships(groups, start)
+	callStart = time.Now()
+	groups, err = m.withoutBannedGroups(ctx, userID, groups)
+	tr.storeCall("ListUserBans", callStart, err)
 	if err != nil {
-		m.record(ctx, start, "Can", err)
+		m.record(ctx, start, method, err)
+		return nil, nil, err
 	}
+	seen := make(map[string]bool, len(groups))
 	for _, ug := range groups {
+		// several memberships of one group grant its roles once
+		if seen[ug.GroupName] {
+			continue
+		}
+		seen[ug.GroupName] = true
+		callStart = time.Now()
 		grpRoles, err := m.GR.ListRolesForGroup(ctx, ug.GroupName)
+		tr.storeCall("ListRolesForGroup", callStart, err, attribute.String("rbac.group", ug.GroupName))
 		if err != nil {
-			m.record(ctx, start, "Can", err)
-		} else {
-			roles = append(roles, grpRoles...)
+			m.record(ctx, start, method, err)
+			return nil, nil, err
 		}
+		roles = append(roles, grpRoles...)
+		ex.grant(ViaGroup, ug.GroupName, grpRoles)
 	}
+	return roles, groups, nil
+}
 
-	// 3) dedupe roles (optional)
-
-	// 4) the old perm‐matching logic over all roles
-	var allow bool
-	for _, roleID := range roles {
-		permIDs, err := m.RP.ListPermissions(ctx, roleID)
+// evaluate runs the request through the Manager's authorizer chain; roles
+// are the fully expanded roles of userID. A request no link settles is
+// denied.
+func (m *Manager) evaluate(ctx context.Context, start time.Time, tr *decisionTrace, method, userID string, roles []string, resource string, action Action, attrs map[string]any) (*Decision, error) {
+	req := &AuthorizeRequest{
+		UserID:   userID,
+		Resource: resource,
+		Action:   action,
+		Attrs:    attrs,
+		Roles:    roles,
+		method:   method,
+		start:    start,
+		tr:       tr,
+	}
+	chain := m.Authorizers
+	if chain == nil {
+		chain = DefaultAuthorizers()
+	}
+	d := &Decision{}
+	for _, a := range chain {
+		settled, err := a.Authorize(ctx, m, req)
 		if err != nil {
-			m.record(ctx, start, "Can", err)
-			continue
+			m.record(ctx, start, method, err)
+			return nil, err
 		}
-		for _, pid := range permIDs {
-			perm, err := m.Perms.GetPermissionByID(ctx, pid)
-			if err != nil {
-				m.record(ctx, start, "Can", err)
-				continue
-			}
-			if perm == nil {
-				continue
-			}
-			okRes, err := matchResource(perm.Resource, resource)
-			if err != nil {
-				m.record(ctx, start, "Can", err)
-				return false, err
-			}
-			if !okRes {
-				continue
+		if settled != nil {
+			if !builtinAuthorizer(a) {
+				explainerFrom(ctx).decidedByAuthorizer()
 			}
-			okAct, err := path.Match(string(perm.Action), string(action))
-			if err != nil {
-				m.record(ctx, start, "Can", err)
-				return false, err
-			}
-			if okAct {
-				allow = true
-				break
-			}
-		}
-		if allow {
+			d = settled
 			break
 		}
 	}
-
-	m.record(ctx, start, "Can", nil)
-	return allow, nil
+	if d.Allowed && d.PermissionID != "" && m.Usage != nil {
+		m.Usage.Record(d.PermissionID)
+	}
+	m.record(ctx, start, method, nil)
+	return d, nil
 }
 
-// matchResource remains unchanged...
-func matchResource(pattern, resource string) (bool, error) {
-	if strings.Contains(pattern, "**") {
-		parts := strings.SplitN(pattern, "**", 2)
-		prefix, suffix := parts[0], parts[1]
-		if !strings.HasPrefix(resource, prefix) {
-			return false, nil
+// rolePermissions loads the permissions bound to roleID, directly or through
+// its permission sets. The direct ones are loaded in one call when the
+// RolePermissionRepo implements RolePermissionDetailer. Permissions that no
+// longer exist are skipped; any failed lookup is returned, since the
+// permission it misses may be a deny.
+func (m *Manager) rolePermissions(ctx context.Context, start time.Time, roleID string) ([]*Permission, error) {
+	var (
+		perms   []*Permission
+		permIDs []string
+		err     error
+	)
+	if d, ok := m.RP.(RolePermissionDetailer); ok {
+		if perms, err = d.ListPermissionDetails(ctx, roleID); err != nil {
+			return nil, err
 		}
-		if suffix != "" && !strings.HasSuffix(resource, suffix) {
-			return false, nil
+	} else if permIDs, err = m.RP.ListPermissions(ctx, roleID); err != nil {
+		return nil, err
+	}
+
+	// permissions from the role's sets are resolved on every call, so
+	// changing a set changes every role bound to it
+	fromSets, err := m.setPermissions(ctx, roleID)
+	if err != nil {
+		return nil, err
+	}
+	for _, pid := range fromSets {
+		if !slices.Contains(permIDs, pid) && !slices.ContainsFunc(perms, func(p *Permission) bool { return p.ID == pid }) {
+			permIDs = append(permIDs, pid)
 		}
-		if len(resource) < len(prefix)+len(suffix) {
-			return false, nil
+	}
+	for _, pid := range permIDs {
+		perm, err := m.Perms.GetPermissionByID(ctx, pid)
+		if err != nil {
+			return nil, err
+		}
+		if perm != nil {
+			perms = append(perms, perm)
 		}
-		return true, nil
 	}
-	return path.Match(pattern, resource)
+	return perms, nil
+}
+
+// matchResource is shared with rbaceval so both evaluate patterns identically.
+func matchResource(pattern, resource string) (bool, error) {
+	return rbaceval.MatchResource(pattern, resource)
 }
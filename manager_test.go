@@ -124,6 +124,13 @@ func runSuite(t *testing.T, s storeAdapter) {
 	t.Run("UserRole", func(t *testing.T) { testUserRoles(t, s) })
 	t.Run("UserGroup", func(t *testing.T) { testUserGroups(t, s) })
 	t.Run("GroupRole", func(t *testing.T) { testGroupRoles(t, s) })
+	t.Run("TenantID", func(t *testing.T) { testTenantIDs(t, s) })
+	if pager, ok := s.(ExportPager); ok {
+		t.Run("Export", func(t *testing.T) { testExportPages(t, s, pager) })
+	}
+	if scoped, ok := s.(ScopedUserRoleRepo); ok {
+		t.Run("ScopedRoles", func(t *testing.T) { testScopedRoles(t, s, scoped) })
+	}
 }
 
 // -----------------------------------------------------------------------
@@ -181,6 +188,42 @@ func testPermissions(t *testing.T, s storeAdapter) {
 		}
 	})
 
+	t.Run("EffectAndCondition", func(t *testing.T) {
+		p := &Permission{Resource: "billing", Action: ActionAll, Effect: EffectDeny, Condition: `attrs.region == "eu"`}
+		if err := s.CreatePermission(ctx, p); err != nil {
+			t.Fatalf("CreatePermission: %v", err)
+		}
+		got, err := s.GetPermissionByID(ctx, p.ID)
+		if err != nil {
+			t.Fatalf("GetPermissionByID: %v", err)
+		}
+		if got == nil || got.Effect != EffectDeny || got.Condition != p.Condition {
+			t.Errorf("expected effect and condition to round-trip, got %+v", got)
+		}
+	})
+
+	t.Run("NameDescriptionAndLabels", func(t *testing.T) {
+		p := &Permission{Name: "reports.read", Description: "Read reports", Labels: map[string]string{"owner": "finance"},
+			Resource: "reports", Action: ActionRead}
+		if err := s.CreatePermission(ctx, p); err != nil {
+			t.Fatalf("CreatePermission: %v", err)
+		}
+		got, err := s.GetPermissionByID(ctx, p.ID)
+		if err != nil {
+			t.Fatalf("GetPermissionByID: %v", err)
+		}
+		if got == nil || got.Name != p.Name || got.Description != p.Description || got.Labels["owner"] != "finance" {
+			t.Errorf("expected name, description and labels to round-trip, got %+v", got)
+		}
+		got, err = s.GetPermissionByResource(ctx, "reports", ActionRead)
+		if err != nil {
+			t.Fatalf("GetPermissionByResource: %v", err)
+		}
+		if got == nil || got.Name != p.Name || got.Labels["owner"] != "finance" {
+			t.Errorf("expected name and labels from GetPermissionByResource, got %+v", got)
+		}
+	})
+
 	t.Run("CreateIdempotent", func(t *testing.T) {
 		p1 := &Permission{Resource: "videos", Action: ActionDelete}
 		if err := s.CreatePermission(ctx, p1); err != nil {
@@ -197,6 +240,17 @@ func testPermissions(t *testing.T, s storeAdapter) {
 		}
 	})
 
+	t.Run("ListAllPermissions", func(t *testing.T) {
+		perms, err := s.ListAllPermissions(ctx)
+		if err != nil {
+			t.Fatalf("ListAllPermissions: %v", err)
+		}
+		// articles, posts, billing and videos were created above
+		if len(perms) < 4 {
+			t.Errorf("expected at least 4 permissions, got %d", len(perms))
+		}
+	})
+
 	t.Run("GetByIDNotFound", func(t *testing.T) {
 		got, err := s.GetPermissionByID(ctx, "nonexistent-id")
 		if err != nil {
@@ -266,6 +320,101 @@ func testRoles(t *testing.T, s storeAdapter) {
 		}
 	})
 
+	t.Run("Meta", func(t *testing.T) {
+		r := &Role{Name: "auditor", Meta: map[string]interface{}{"team": "security", "ticket": "SEC-12"}}
+		if err := s.CreateRole(ctx, r); err != nil {
+			t.Fatalf("CreateRole: %v", err)
+		}
+
+		got, err := s.GetRoleByID(ctx, r.ID)
+		if err != nil {
+			t.Fatalf("GetRoleByID: %v", err)
+		}
+		if got == nil || got.Meta["team"] != "security" || got.Meta["ticket"] != "SEC-12" {
+			t.Errorf("expected the role's meta to round-trip, got %+v", got)
+		}
+		if got, _ := s.GetRoleByName(ctx, "editor"); got == nil || len(got.Meta) != 0 {
+			t.Errorf("expected a role without meta to have none, got %+v", got)
+		}
+	})
+
+	t.Run("AuditFields", func(t *testing.T) {
+		r := &Role{Name: "operator", UpdatedAt: 1700000000, CreatedBy: "admin", UpdatedBy: "ops"}
+		if err := s.CreateRole(ctx, r); err != nil {
+			t.Fatalf("CreateRole: %v", err)
+		}
+
+		got, err := s.GetRoleByID(ctx, r.ID)
+		if err != nil {
+			t.Fatalf("GetRoleByID: %v", err)
+		}
+		if got == nil || got.UpdatedAt != 1700000000 || got.CreatedBy != "admin" || got.UpdatedBy != "ops" {
+			t.Errorf("expected the role's audit fields to round-trip, got %+v", got)
+		}
+	})
+
+	t.Run("Template", func(t *testing.T) {
+		r := &Role{Name: "blueprint", Template: true}
+		if err := s.CreateRole(ctx, r); err != nil {
+			t.Fatalf("CreateRole: %v", err)
+		}
+
+		got, err := s.GetRoleByID(ctx, r.ID)
+		if err != nil {
+			t.Fatalf("GetRoleByID: %v", err)
+		}
+		if got == nil || !got.Template {
+			t.Errorf("expected the role to stay a template, got %+v", got)
+		}
+		if got, _ := s.GetRoleByName(ctx, "editor"); got == nil || got.Template {
+			t.Errorf("expected a plain role not to be a template, got %+v", got)
+		}
+	})
+
+	t.Run("Priority", func(t *testing.T) {
+		r := &Role{Name: "incident-lockdown", Priority: 100}
+		if err := s.CreateRole(ctx, r); err != nil {
+			t.Fatalf("CreateRole: %v", err)
+		}
+
+		got, err := s.GetRoleByID(ctx, r.ID)
+		if err != nil {
+			t.Fatalf("GetRoleByID: %v", err)
+		}
+		if got == nil || got.Priority != 100 {
+			t.Errorf("expected the role's priority to round-trip, got %+v", got)
+		}
+		roles, err := s.ListAllRoles(ctx)
+		if err != nil {
+			t.Fatalf("ListAllRoles: %v", err)
+		}
+		for _, listed := range roles {
+			if listed.ID == r.ID && listed.Priority != 100 {
+				t.Errorf("expected ListAllRoles to carry the priority, got %+v", listed)
+			}
+		}
+	})
+
+	t.Run("Generators", func(t *testing.T) {
+		r := &Role{Name: "team-member", Generators: []Permission{
+			{Resource: "teams/{user.meta.team}/**", Action: ActionRead},
+			{Resource: "teams/{user.meta.team}/billing", Action: ActionUpdate, Effect: EffectDeny},
+		}}
+		if err := s.CreateRole(ctx, r); err != nil {
+			t.Fatalf("CreateRole: %v", err)
+		}
+
+		got, err := s.GetRoleByName(ctx, "team-member")
+		if err != nil {
+			t.Fatalf("GetRoleByName: %v", err)
+		}
+		if got == nil || len(got.Generators) != 2 ||
+			got.Generators[0].Resource != "teams/{user.meta.team}/**" || got.Generators[0].Action != ActionRead ||
+			got.Generators[1].Effect != EffectDeny {
+			t.Errorf("expected the role's generators to round-trip, got %+v", got)
+		}
+	})
+
 	t.Run("GetByNameNotFound", func(t *testing.T) {
 		got, err := s.GetRoleByName(ctx, "nonexistent-role")
 		if err != nil {
@@ -377,6 +526,22 @@ func testUsers(t *testing.T, s storeAdapter) {
 		}
 	})
 
+	t.Run("ListAllUsers", func(t *testing.T) {
+		users, err := s.ListAllUsers(ctx)
+		if err != nil {
+			t.Fatalf("ListAllUsers: %v", err)
+		}
+		names := map[string]bool{}
+		for _, u := range users {
+			names[u.Username] = true
+		}
+		for _, name := range []string{"alice", "bob", "carol"} {
+			if !names[name] {
+				t.Errorf("expected %s among %d users", name, len(users))
+			}
+		}
+	})
+
 	t.Run("GetByMeta_InvalidField", func(t *testing.T) {
 		_, err := s.GetUserByMeta(ctx, map[string]interface{}{"bad_field": "x"})
 		if err == nil {
@@ -408,6 +573,43 @@ func testUsers(t *testing.T, s storeAdapter) {
 			t.Error("expected nil after delete")
 		}
 	})
+
+	t.Run("EmailVerifiedAndStatus", func(t *testing.T) {
+		u := &User{Username: "verified-user", Email: "verified@example.com", EmailVerified: true}
+		if err := s.CreateUser(ctx, u); err != nil {
+			t.Fatalf("CreateUser: %v", err)
+		}
+		got, err := s.GetUserByID(ctx, u.ID)
+		if err != nil {
+			t.Fatalf("GetUserByID: %v", err)
+		}
+		if got == nil || !got.EmailVerified || !got.Status.Active() {
+			t.Fatalf("expected a verified, active user, got %+v", got)
+		}
+
+		if repo, ok := s.(EmailVerificationRepo); ok {
+			if err := repo.SetEmailVerified(ctx, u.ID, false); err != nil {
+				t.Fatalf("SetEmailVerified: %v", err)
+			}
+			if got, _ = s.GetUserByID(ctx, u.ID); got == nil || got.EmailVerified {
+				t.Errorf("expected the user to be unverified, got %+v", got)
+			}
+		}
+		if repo, ok := s.(UserStatusRepo); ok {
+			if err := repo.SetUserStatus(ctx, u.ID, UserSuspended); err != nil {
+				t.Fatalf("SetUserStatus: %v", err)
+			}
+			users, err := s.ListAllUsers(ctx)
+			if err != nil {
+				t.Fatalf("ListAllUsers: %v", err)
+			}
+			for _, listed := range users {
+				if listed.ID == u.ID && listed.Status != UserSuspended {
+					t.Errorf("expected the user to be suspended, got %+v", listed)
+				}
+			}
+		}
+	})
 }
 
 // -----------------------------------------------------------------------
@@ -470,6 +672,40 @@ func testRolePermissions(t *testing.T, s storeAdapter) {
 			t.Errorf("expected empty list, got %v", ids)
 		}
 	})
+
+	t.Run("BindBeforeCreate", func(t *testing.T) {
+		// Imports may bind a permission ID before creating the permission.
+		late := &Permission{ID: "rp-late-perm", Resource: "rp-late", Action: ActionUpdate}
+		if err := s.AddRP(ctx, role.ID, late.ID); err != nil {
+			t.Fatalf("AddRP: %v", err)
+		}
+		if err := s.CreatePermission(ctx, late); err != nil {
+			t.Fatalf("CreatePermission: %v", err)
+		}
+
+		ids, err := s.ListPermissions(ctx, role.ID)
+		if err != nil {
+			t.Fatalf("ListPermissions: %v", err)
+		}
+		if !containsStr(ids, late.ID) {
+			t.Errorf("expected perm %s in list %v", late.ID, ids)
+		}
+		detailer, ok := s.(RolePermissionDetailer)
+		if !ok {
+			return
+		}
+		perms, err := detailer.ListPermissionDetails(ctx, role.ID)
+		if err != nil {
+			t.Fatalf("ListPermissionDetails: %v", err)
+		}
+		var found bool
+		for _, p := range perms {
+			found = found || (p.ID == late.ID && p.Resource == late.Resource && p.Action == late.Action)
+		}
+		if !found {
+			t.Errorf("expected %s with its details in %+v", late.ID, perms)
+		}
+	})
 }
 
 // -----------------------------------------------------------------------
@@ -488,10 +724,8 @@ func testUserRoles(t *testing.T, s storeAdapter) {
 		t.Fatalf("setup CreateRole: %v", err)
 	}
 
-	// NewPostgresStore / NewMySQLStore do NOT seed a default role automatically;
-	// only NewPostgresStoreManager / NewMySQLStoreManager do. So ListRoles
-	// may or may not include "default" depending on how the store was created.
-	// We just test the explicit assignment here.
+	// ListRoles lists explicit assignments only; the Manager adds the default
+	// role.
 
 	t.Run("AddAndList", func(t *testing.T) {
 		if err := s.AddUR(ctx, user.ID, role.ID); err != nil {
@@ -505,6 +739,14 @@ func testUserRoles(t *testing.T, s storeAdapter) {
 		if !containsStr(ids, role.ID) {
 			t.Errorf("expected role %s in list %v", role.ID, ids)
 		}
+
+		users, err := s.ListUsersForRole(ctx, role.ID)
+		if err != nil {
+			t.Fatalf("ListUsersForRole: %v", err)
+		}
+		if len(users) != 1 || users[0] != user.ID {
+			t.Errorf("ListUsersForRole = %v; want [%s]", users, user.ID)
+		}
 	})
 
 	t.Run("AddIdempotent", func(t *testing.T) {
@@ -525,6 +767,14 @@ func testUserRoles(t *testing.T, s storeAdapter) {
 		if containsStr(ids, role.ID) {
 			t.Errorf("role %s still in list after remove", role.ID)
 		}
+
+		users, err := s.ListUsersForRole(ctx, role.ID)
+		if err != nil {
+			t.Fatalf("ListUsersForRole after remove: %v", err)
+		}
+		if len(users) != 0 {
+			t.Errorf("ListUsersForRole after remove = %v; want none", users)
+		}
 	})
 }
 
@@ -595,6 +845,73 @@ func testUserGroups(t *testing.T, s storeAdapter) {
 		}
 	})
 
+	t.Run("Level", func(t *testing.T) {
+		ug := &UserGroup{UserID: user.ID, GroupName: "platform", Level: MembershipAdmin}
+		if err := s.AddUserToGroup(ctx, ug); err != nil {
+			t.Fatalf("AddUserToGroup: %v", err)
+		}
+		members, err := s.GetUsersByGroupID(ctx, "platform")
+		if err != nil {
+			t.Fatalf("GetUsersByGroupID: %v", err)
+		}
+		if len(members) != 1 || members[0].Level != MembershipAdmin {
+			t.Errorf("expected the member's level to round-trip, got %+v", members)
+		}
+
+		// re-adding a member replaces their level
+		cp := *ug
+		cp.Level = MembershipOwner
+		if err := s.AddUserToGroup(ctx, &cp); err != nil {
+			t.Fatalf("AddUserToGroup again: %v", err)
+		}
+		groups, err := s.GetGroupsByUserID(ctx, user.ID)
+		if err != nil {
+			t.Fatalf("GetGroupsByUserID: %v", err)
+		}
+		var n int
+		for _, g := range groups {
+			if g.GroupName == "platform" {
+				n++
+				if g.Level != MembershipOwner {
+					t.Errorf("expected the new level, got %+v", g)
+				}
+			}
+		}
+		if n != 1 {
+			t.Errorf("expected one platform membership, got %d", n)
+		}
+	})
+
+	t.Run("ExpiresAt", func(t *testing.T) {
+		expires := time.Now().Add(time.Hour).Unix()
+		ug := &UserGroup{UserID: user.ID, GroupName: "contractors", ExpiresAt: expires}
+		if err := s.AddUserToGroup(ctx, ug); err != nil {
+			t.Fatalf("AddUserToGroup: %v", err)
+		}
+		members, err := s.GetUsersByGroupID(ctx, "contractors")
+		if err != nil {
+			t.Fatalf("GetUsersByGroupID: %v", err)
+		}
+		if len(members) != 1 || members[0].ExpiresAt != expires {
+			t.Errorf("expected the membership's expiry to round-trip, got %+v", members)
+		}
+
+		lister, ok := s.(ExpiringMembershipLister)
+		if !ok {
+			return
+		}
+		expiring, err := lister.ListExpiringMemberships(ctx, expires-1, expires)
+		if err != nil {
+			t.Fatalf("ListExpiringMemberships: %v", err)
+		}
+		if !containsGroup(expiring, "contractors") || containsGroup(expiring, "engineering") {
+			t.Errorf("expected only the expiring membership, got %+v", expiring)
+		}
+		if expiring, _ = lister.ListExpiringMemberships(ctx, expires, expires+60); containsGroup(expiring, "contractors") {
+			t.Errorf("expected the membership outside (after, before], got %+v", expiring)
+		}
+	})
+
 	t.Run("EmptyUserIDReturnsError", func(t *testing.T) {
 		err := s.AddUserToGroup(ctx, &UserGroup{GroupName: "x"})
 		if err == nil {
@@ -627,6 +944,14 @@ func testGroupRoles(t *testing.T, s storeAdapter) {
 		if !containsStr(ids, role.ID) {
 			t.Errorf("expected role %s in list %v", role.ID, ids)
 		}
+
+		groups, err := s.ListGroupsForRole(ctx, role.ID)
+		if err != nil {
+			t.Fatalf("ListGroupsForRole: %v", err)
+		}
+		if len(groups) != 1 || groups[0] != "ops" {
+			t.Errorf("ListGroupsForRole = %v; want [ops]", groups)
+		}
 	})
 
 	t.Run("AddIdempotent", func(t *testing.T) {
@@ -647,6 +972,14 @@ func testGroupRoles(t *testing.T, s storeAdapter) {
 		if containsStr(ids, role.ID) {
 			t.Errorf("role %s still in list after remove", role.ID)
 		}
+
+		groups, err := s.ListGroupsForRole(ctx, role.ID)
+		if err != nil {
+			t.Fatalf("ListGroupsForRole after remove: %v", err)
+		}
+		if len(groups) != 0 {
+			t.Errorf("ListGroupsForRole after remove = %v; want none", groups)
+		}
 	})
 
 	t.Run("ListEmpty", func(t *testing.T) {
@@ -690,3 +1023,165 @@ func containsUserID(groups []*UserGroup, userID string) bool {
 	}
 	return false
 }
+
+// -----------------------------------------------------------------------
+// TenantID tests
+// -----------------------------------------------------------------------
+
+// testTenantIDs checks that every entity keeps its TenantID, which the
+// ForTenant scope relies on to tell one tenant's records from another's.
+func testTenantIDs(t *testing.T, s storeAdapter) {
+	ctx := context.Background()
+
+	perm := &Permission{Resource: "tenant-docs", Action: ActionRead, TenantID: "acme"}
+	if err := s.CreatePermission(ctx, perm); err != nil {
+		t.Fatalf("CreatePermission: %v", err)
+	}
+	if got, err := s.GetPermissionByID(ctx, perm.ID); err != nil || got == nil || got.TenantID != "acme" {
+		t.Errorf("expected the permission's tenant to round-trip, got %+v (err %v)", got, err)
+	}
+
+	role := &Role{Name: "tenant-role", TenantID: "acme"}
+	if err := s.CreateRole(ctx, role); err != nil {
+		t.Fatalf("CreateRole: %v", err)
+	}
+	if got, err := s.GetRoleByID(ctx, role.ID); err != nil || got == nil || got.TenantID != "acme" {
+		t.Errorf("expected the role's tenant to round-trip, got %+v (err %v)", got, err)
+	}
+	if got, err := s.GetRoleByName(ctx, role.Name); err != nil || got == nil || got.TenantID != "acme" {
+		t.Errorf("expected the role's tenant from GetRoleByName, got %+v (err %v)", got, err)
+	}
+
+	if err := s.AddRP(ctx, role.ID, perm.ID); err != nil {
+		t.Fatalf("AddRP: %v", err)
+	}
+	if detailer, ok := s.(RolePermissionDetailer); ok {
+		perms, err := detailer.ListPermissionDetails(ctx, role.ID)
+		if err != nil {
+			t.Fatalf("ListPermissionDetails: %v", err)
+		}
+		if len(perms) != 1 || perms[0].TenantID != "acme" {
+			t.Errorf("expected the bound permission's tenant, got %+v", perms)
+		}
+	}
+
+	user := &User{Username: "tenant-user", Email: "tenant-user@example.com", TenantID: "acme"}
+	if err := s.CreateUser(ctx, user); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	if got, err := s.GetUserByID(ctx, user.ID); err != nil || got == nil || got.TenantID != "acme" {
+		t.Errorf("expected the user's tenant to round-trip, got %+v (err %v)", got, err)
+	}
+
+	ug := &UserGroup{UserID: user.ID, GroupName: "acme/engineering", TenantID: "acme"}
+	if err := s.AddUserToGroup(ctx, ug); err != nil {
+		t.Fatalf("AddUserToGroup: %v", err)
+	}
+	groups, err := s.GetGroupsByUserID(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("GetGroupsByUserID: %v", err)
+	}
+	if len(groups) != 1 || groups[0].TenantID != "acme" {
+		t.Errorf("expected the membership's tenant to round-trip, got %+v", groups)
+	}
+}
+
+// -----------------------------------------------------------------------
+// Export tests
+// -----------------------------------------------------------------------
+
+func testExportPages(t *testing.T, s storeAdapter, pager ExportPager) {
+	ctx := context.Background()
+
+	role := &Role{Name: "export-role"}
+	if err := s.CreateRole(ctx, role); err != nil {
+		t.Fatalf("CreateRole: %v", err)
+	}
+	want := map[string]bool{}
+	for _, res := range []string{"export-a", "export-b", "export-c"} {
+		p := &Permission{Resource: res, Action: ActionRead}
+		if err := s.CreatePermission(ctx, p); err != nil {
+			t.Fatalf("CreatePermission: %v", err)
+		}
+		if err := s.AddRP(ctx, role.ID, p.ID); err != nil {
+			t.Fatalf("AddRP: %v", err)
+		}
+		want[p.ID] = true
+	}
+
+	// page through one record at a time; no key may come back twice
+	for _, kind := range []string{KindPermission, KindRolePermission} {
+		seen := map[string]bool{}
+		keys := map[string]bool{}
+		after := ""
+		for {
+			items, err := pager.ExportPage(ctx, kind, after, 1)
+			if err != nil {
+				t.Fatalf("ExportPage(%s): %v", kind, err)
+			}
+			if len(items) == 0 {
+				break
+			}
+			if keys[items[0].Key] {
+				t.Fatalf("ExportPage(%s): key %q returned twice", kind, items[0].Key)
+			}
+			keys[items[0].Key] = true
+			after = items[0].Key
+			switch v := items[0].Value.(type) {
+			case *Permission:
+				seen[v.ID] = true
+			case *ExportEdge:
+				if v.From == role.ID {
+					seen[v.To] = true
+				}
+			}
+		}
+		for id := range want {
+			if !seen[id] {
+				t.Errorf("ExportPage(%s): %s missing", kind, id)
+			}
+		}
+	}
+}
+
+// -----------------------------------------------------------------------
+// Scoped role tests
+// -----------------------------------------------------------------------
+
+func testScopedRoles(t *testing.T, s storeAdapter, scoped ScopedUserRoleRepo) {
+	ctx := context.Background()
+	const user = "scoped-user"
+
+	for _, scope := range []string{"projects/42/**", "projects/7/**", "projects/42/**"} {
+		if err := scoped.AddScopedUR(ctx, user, "editor", scope); err != nil {
+			t.Fatalf("AddScopedUR(%s): %v", scope, err)
+		}
+	}
+	list, err := scoped.ListScopedRoles(ctx, user)
+	if err != nil {
+		t.Fatalf("ListScopedRoles: %v", err)
+	}
+	if len(list) != 2 {
+		t.Fatalf("expected 2 scoped roles, got %+v", list)
+	}
+	roles, err := s.ListRoles(ctx, user)
+	if err != nil {
+		t.Fatalf("ListRoles: %v", err)
+	}
+	for _, r := range roles {
+		if r == "editor" {
+			t.Error("expected scoped roles to stay out of ListRoles")
+		}
+	}
+
+	if err := scoped.RemoveScopedUR(ctx, user, "editor", "projects/7/**"); err != nil {
+		t.Fatalf("RemoveScopedUR: %v", err)
+	}
+	list, err = scoped.ListScopedRoles(ctx, user)
+	if err != nil {
+		t.Fatalf("ListScopedRoles: %v", err)
+	}
+	if len(list) != 1 || list[0] != (ScopedRole{RoleID: "editor", Scope: "projects/42/**"}) {
+		t.Fatalf("expected only the projects/42 scope to remain, got %+v", list)
+	}
+}
@@ -0,0 +1,80 @@
+package rbac
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestMembershipLevels(t *testing.T) {
+	ctx := context.Background()
+	memory, err := NewMemoryStoreManager(ctx, "", 0)
+	if err != nil {
+		t.Fatalf("NewMemoryStoreManager: %v", err)
+	}
+	for name, mgr := range map[string]*Manager{"memory": memory, "mock": NewMockRepoManager(NewMockRepo())} {
+		t.Run(name, func(t *testing.T) {
+			if err := mgr.CreateGroup(ctx, &Group{Name: "docs", Owner: "olga"}); err != nil {
+				t.Fatalf("CreateGroup: %v", err)
+			}
+			for user, level := range map[string]MembershipLevel{"alice": MembershipOwner, "bob": MembershipAdmin, "carol": ""} {
+				if err := mgr.AddUserToGroup(ctx, &UserGroup{UserID: user, GroupName: "docs", Level: level}); err != nil {
+					t.Fatalf("AddUserToGroup(%s): %v", user, err)
+				}
+			}
+			if err := mgr.AddUserToGroup(ctx, &UserGroup{UserID: "dan", GroupName: "docs", Level: "boss"}); !errors.Is(err, ErrInvalidMembershipLevel) {
+				t.Errorf("expected ErrInvalidMembershipLevel, got %v", err)
+			}
+
+			for user, want := range map[string]MembershipLevel{"alice": MembershipOwner, "bob": MembershipAdmin, "carol": MembershipMember, "olga": MembershipOwner, "dan": ""} {
+				if got, err := mgr.GetMembershipLevel(ctx, "docs", user); err != nil || got != want {
+					t.Errorf("GetMembershipLevel(%s) = %q, %v; want %q", user, got, err, want)
+				}
+			}
+			for user, want := range map[string]bool{"alice": true, "bob": true, "carol": false, "olga": true, "dan": false} {
+				if got, err := mgr.IsGroupAdmin(ctx, "docs", user); err != nil || got != want {
+					t.Errorf("IsGroupAdmin(%s) = %v, %v; want %v", user, got, err, want)
+				}
+			}
+			if ok, _ := mgr.IsGroupOwner(ctx, "docs", "bob"); ok {
+				t.Error("expected an admin not to be an owner")
+			}
+
+			cases := []struct {
+				actor string
+				level MembershipLevel
+				want  bool
+			}{
+				{"alice", MembershipOwner, true},
+				{"alice", MembershipAdmin, true},
+				{"bob", MembershipMember, true},
+				{"bob", "", true},
+				{"bob", MembershipAdmin, false},
+				{"carol", MembershipMember, false},
+				{"dan", MembershipMember, false},
+			}
+			for _, c := range cases {
+				if got, err := mgr.CanManageMembership(ctx, c.actor, "docs", c.level); err != nil || got != c.want {
+					t.Errorf("CanManageMembership(%s, %q) = %v, %v; want %v", c.actor, c.level, got, err, c.want)
+				}
+			}
+
+			if err := mgr.SetMembershipLevel(ctx, "docs", "carol", MembershipAdmin); err != nil {
+				t.Fatalf("SetMembershipLevel: %v", err)
+			}
+			if ok, _ := mgr.IsGroupAdmin(ctx, "docs", "carol"); !ok {
+				t.Error("expected carol to be promoted to admin")
+			}
+			if err := mgr.SetMembershipLevel(ctx, "docs", "dan", MembershipAdmin); !errors.Is(err, ErrNotMember) {
+				t.Errorf("expected ErrNotMember, got %v", err)
+			}
+
+			if err := mgr.BanUserFromGroup(ctx, "docs", "bob", ""); err != nil {
+				t.Fatalf("BanUserFromGroup: %v", err)
+			}
+			if ok, _ := mgr.IsGroupAdmin(ctx, "docs", "bob"); ok {
+				t.Error("expected a banned admin to lose their standing")
+			}
+		})
+	}
+}
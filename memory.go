@@ -0,0 +1,2102 @@
+// file: rbac/memory_store.go
+package rbac
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Ensure MemoryStore implements all interfaces:
+var (
+	_ Store                    = (*MemoryStore)(nil)
+	_ TenantRepo               = (*MemoryStore)(nil)
+	_ GroupRepo                = (*MemoryStore)(nil)
+	_ PermissionNameGetter     = (*MemoryStore)(nil)
+	_ UserLookup               = (*MemoryStore)(nil)
+	_ AttestationRepo          = (*MemoryStore)(nil)
+	_ ArchiveRepo              = (*MemoryStore)(nil)
+	_ PolicyVersionRepo        = (*MemoryStore)(nil)
+	_ APIKeyRepo               = (*MemoryStore)(nil)
+	_ SessionRepo              = (*MemoryStore)(nil)
+	_ SoDRepo                  = (*MemoryStore)(nil)
+	_ ApprovalRepo             = (*MemoryStore)(nil)
+	_ DelegationRepo           = (*MemoryStore)(nil)
+	_ PermissionSetRepo        = (*MemoryStore)(nil)
+	_ GroupBanRepo             = (*MemoryStore)(nil)
+	_ SoftDeleteRepo           = (*MemoryStore)(nil)
+	_ UserStatusRepo           = (*MemoryStore)(nil)
+	_ EmailVerificationRepo    = (*MemoryStore)(nil)
+	_ RolePermissionDetailer   = (*MemoryStore)(nil)
+	_ ScheduledUserRoleRepo    = (*MemoryStore)(nil)
+	_ ExpiringRoleLister       = (*MemoryStore)(nil)
+	_ ExpiringMembershipLister = (*MemoryStore)(nil)
+	_ ScopedUserRoleRepo       = (*MemoryStore)(nil)
+	_ ScopedGroupRoleRepo      = (*MemoryStore)(nil)
+	_ ConstrainedUserRoleRepo  = (*MemoryStore)(nil)
+	_ RoleHierarchyRepo        = (*MemoryStore)(nil)
+	_ EdgeSourceRepo           = (*MemoryStore)(nil)
+	_ ExportPager              = (*MemoryStore)(nil)
+)
+
+// MemorySnapshot is the on-disk form of a MemoryStore. Edge maps are keyed
+// by role, user, group and role respectively.
+type MemorySnapshot struct {
+	Permissions        []*Permission                `json:"permissions"`
+	Roles              []*Role                      `json:"roles"`
+	Users              []*User                      `json:"users"`
+	Tenants            []*Tenant                    `json:"tenants,omitempty"`
+	Groups             []*Group                     `json:"groups,omitempty"`
+	Attestations       []*Attestation               `json:"attestations,omitempty"`
+	Archives           []*Archive                   `json:"archives,omitempty"`
+	PolicySnapshots    []*PolicySnapshot            `json:"policy_snapshots,omitempty"`
+	APIKeys            []*APIKey                    `json:"api_keys,omitempty"`
+	Sessions           []*Session                   `json:"sessions,omitempty"`
+	SoDConstraints     []*SoDConstraint             `json:"sod_constraints,omitempty"`
+	AssignmentRequests []*AssignmentRequest         `json:"assignment_requests,omitempty"`
+	Delegations        []*Delegation                `json:"delegations,omitempty"`
+	PermissionSets     []*PermissionSet             `json:"permission_sets,omitempty"`
+	RolePermissionSets map[string][]string          `json:"role_permission_sets,omitempty"`
+	GroupBans          []*GroupBan                  `json:"group_bans,omitempty"`
+	RolePermissions    map[string][]string          `json:"role_permissions"`
+	UserRoles          map[string][]string          `json:"user_roles"`
+	ScheduledRoles     []*RoleAssignment            `json:"scheduled_roles,omitempty"`
+	ScopedRoles        map[string][]ScopedRole      `json:"scoped_roles,omitempty"`
+	ConstrainedRoles   map[string][]ConstrainedRole `json:"constrained_roles,omitempty"`
+	UserGroups         []*UserGroup                 `json:"user_groups"`
+	GroupRoles         map[string][]string          `json:"group_roles"`
+	ScopedGroupRoles   map[string][]ScopedRole      `json:"scoped_group_roles,omitempty"`
+	RoleParents        map[string][]string          `json:"role_parents,omitempty"`
+	ManagedEdges       []*ManagedEdge               `json:"managed_edges,omitempty"`
+	TakenAt            int64                        `json:"taken_at"`
+}
+
+//
+// ---------- MemoryStore Core ----------
+//
+
+// MemoryStore is a concurrency-safe in-memory store for services too small
+// to warrant a database. With a snapshot path it reloads its contents on
+// startup and can write them back periodically; anything changed after the
+// last snapshot is lost on a crash.
+type MemoryStore struct {
+	mu   sync.RWMutex
+	ids  IDGenerator
+	path string
+	// snapMu serialises snapshot writers so an older snapshot never
+	// replaces a newer one.
+	snapMu sync.Mutex
+
+	// changes counts mutations; saved is its value at the last snapshot.
+	changes uint64
+	saved   uint64
+
+	perms      map[string]*Permission
+	roles      map[string]*Role
+	users      map[string]*User
+	tenants    map[string]*Tenant
+	groups     map[string]*Group
+	attests    map[string][]*Attestation             // userID -> attestations, oldest first
+	archives   map[string]*Archive                   // archiveID -> archive
+	snapshots  map[string]*PolicySnapshot            // snapshotID -> snapshot
+	apiKeys    map[string]*APIKey                    // keyID -> key
+	sessions   map[string]*Session                   // sessionID -> session
+	sods       map[string]*SoDConstraint             // constraintID -> constraint
+	requests   map[string]*AssignmentRequest         // requestID -> request
+	delegs     map[string]*Delegation                // delegationID -> delegation
+	permSets   map[string]*PermissionSet             // setID -> set
+	roleSets   map[string]map[string]struct{}        // roleID -> set of setIDs
+	bans       map[string]map[string]*GroupBan       // groupName -> userID -> ban
+	rolePerms  map[string]map[string]struct{}        // roleID -> set of permIDs
+	userRoles  map[string]map[string]struct{}        // userID -> set of roleIDs
+	urWindows  map[string]map[string]*RoleAssignment // userID -> roleID -> window of scheduled assignments
+	userGroups map[string]map[string]*UserGroup      // userID -> groupName -> membership
+	groupRoles map[string]map[string]struct{}        // groupName -> set of roleIDs
+	urScoped   map[string]map[ScopedRole]struct{}    // userID -> set of scoped roles
+	grScoped   map[string]map[ScopedRole]struct{}    // groupName -> set of scoped roles
+	urLimits   map[string]map[string]RoleConstraints // userID -> roleID -> constraints of constrained roles
+	parents    map[string]map[string]struct{}        // roleID -> set of parent roleIDs
+	sources    map[edgeKey]string                    // edge -> source, for edges not managed manually
+}
+
+// NewMemoryStore creates a store that snapshots to path. An existing snapshot
+// at path is loaded; an empty path keeps everything in memory only.
+func NewMemoryStore(ctx context.Context, path string) (*MemoryStore, error) {
+	s := &MemoryStore{path: path}
+	s.reset()
+	if path == "" {
+		return s, nil
+	}
+
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("memory_store: %w", err)
+	}
+	defer f.Close()
+	if err := s.LoadSnapshot(f); err != nil {
+		return nil, fmt.Errorf("memory_store: load %s: %w", path, err)
+	}
+	return s, nil
+}
+
+// SetIDGenerator changes how the store generates IDs for new entities.
+func (s *MemoryStore) SetIDGenerator(g IDGenerator) {
+	s.ids = g
+}
+
+// NewMemoryStoreManager wraps the store in a Manager and seeds the default
+// role. When both path and interval are set, the store is snapshotted every
+// interval until ctx is cancelled.
+func NewMemoryStoreManager(ctx context.Context, path string, interval time.Duration) (*Manager, error) {
+	s, err := NewMemoryStore(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+
+	def, _ := s.GetRoleByName(ctx, "default")
+	if def == nil {
+		def = &Role{Name: "default", Description: "Default role"}
+		if createErr := s.CreateRole(ctx, def); createErr != nil {
+			return nil, fmt.Errorf("failed to create default role: %w", createErr)
+		}
+	}
+	if path != "" && interval > 0 {
+		go s.SnapshotEvery(ctx, interval)
+	}
+
+	return &Manager{
+		Perms:           s,
+		Roles:           s,
+		Users:           s,
+		RP:              s,
+		UR:              s,
+		UG:              s,
+		GR:              s,
+		Tenants:         s,
+		Groups:          s,
+		Attestations:    s,
+		Archives:        s,
+		PolicyVersions:  s,
+		APIKeys:         s,
+		Sessions:        s,
+		SoD:             s,
+		Approvals:       s,
+		Delegations:     s,
+		PermSets:        s,
+		DefaultRoleName: "default",
+	}, nil
+}
+
+func (s *MemoryStore) reset() {
+	s.perms = map[string]*Permission{}
+	s.roles = map[string]*Role{}
+	s.users = map[string]*User{}
+	s.tenants = map[string]*Tenant{}
+	s.groups = map[string]*Group{}
+	s.attests = map[string][]*Attestation{}
+	s.archives = map[string]*Archive{}
+	s.snapshots = map[string]*PolicySnapshot{}
+	s.apiKeys = map[string]*APIKey{}
+	s.sessions = map[string]*Session{}
+	s.sods = map[string]*SoDConstraint{}
+	s.requests = map[string]*AssignmentRequest{}
+	s.delegs = map[string]*Delegation{}
+	s.permSets = map[string]*PermissionSet{}
+	s.roleSets = map[string]map[string]struct{}{}
+	s.bans = map[string]map[string]*GroupBan{}
+	s.rolePerms = map[string]map[string]struct{}{}
+	s.userRoles = map[string]map[string]struct{}{}
+	s.urWindows = map[string]map[string]*RoleAssignment{}
+	s.userGroups = map[string]map[string]*UserGroup{}
+	s.groupRoles = map[string]map[string]struct{}{}
+	s.urScoped = map[string]map[ScopedRole]struct{}{}
+	s.grScoped = map[string]map[ScopedRole]struct{}{}
+	s.urLimits = map[string]map[string]RoleConstraints{}
+	s.parents = map[string]map[string]struct{}{}
+	s.sources = map[edgeKey]string{}
+}
+
+//
+// ---------- Snapshots ----------
+//
+
+// WriteSnapshot writes the store's contents to w as JSON.
+func (s *MemoryStore) WriteSnapshot(w io.Writer) error {
+	s.mu.RLock()
+	snap := s.snapshot()
+	s.mu.RUnlock()
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(snap)
+}
+
+// LoadSnapshot replaces the store's contents with a snapshot read from r.
+func (s *MemoryStore) LoadSnapshot(r io.Reader) error {
+	var snap MemorySnapshot
+	if err := json.NewDecoder(r).Decode(&snap); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reset()
+	for _, p := range snap.Permissions {
+		s.perms[p.ID] = p
+	}
+	for _, r := range snap.Roles {
+		s.roles[r.ID] = r
+	}
+	for _, u := range snap.Users {
+		s.users[u.ID] = u
+	}
+	for _, t := range snap.Tenants {
+		s.tenants[t.ID] = t
+	}
+	for _, g := range snap.Groups {
+		s.groups[g.ID] = g
+	}
+	for _, a := range snap.Attestations {
+		s.attests[a.UserID] = append(s.attests[a.UserID], a)
+	}
+	for _, a := range snap.Archives {
+		s.archives[a.ID] = a
+	}
+	for _, ps := range snap.PolicySnapshots {
+		s.snapshots[ps.ID] = ps
+	}
+	for _, k := range snap.APIKeys {
+		s.apiKeys[k.ID] = k
+	}
+	for _, sess := range snap.Sessions {
+		s.sessions[sess.ID] = sess
+	}
+	for _, c := range snap.SoDConstraints {
+		s.sods[c.ID] = c
+	}
+	for _, r := range snap.AssignmentRequests {
+		s.requests[r.ID] = r
+	}
+	for _, d := range snap.Delegations {
+		s.delegs[d.ID] = d
+	}
+	for _, ps := range snap.PermissionSets {
+		s.permSets[ps.ID] = ps
+	}
+	for _, b := range snap.GroupBans {
+		if s.bans[b.GroupName] == nil {
+			s.bans[b.GroupName] = map[string]*GroupBan{}
+		}
+		s.bans[b.GroupName][b.UserID] = b
+	}
+	for rid, ids := range snap.RolePermissionSets {
+		for _, id := range ids {
+			addEdge(s.roleSets, rid, id)
+		}
+	}
+	for rid, ids := range snap.RolePermissions {
+		for _, id := range ids {
+			addEdge(s.rolePerms, rid, id)
+		}
+	}
+	for uid, ids := range snap.UserRoles {
+		for _, id := range ids {
+			addEdge(s.userRoles, uid, id)
+		}
+	}
+	for _, a := range snap.ScheduledRoles {
+		s.setWindow(a)
+	}
+	for uid, list := range snap.ScopedRoles {
+		for _, sr := range list {
+			addScoped(s.urScoped, uid, sr)
+		}
+	}
+	for uid, list := range snap.ConstrainedRoles {
+		for _, cr := range list {
+			s.setConstraints(uid, cr)
+		}
+	}
+	for g, list := range snap.ScopedGroupRoles {
+		for _, sr := range list {
+			addScoped(s.grScoped, g, sr)
+		}
+	}
+	for _, ug := range snap.UserGroups {
+		if s.userGroups[ug.UserID] == nil {
+			s.userGroups[ug.UserID] = map[string]*UserGroup{}
+		}
+		s.userGroups[ug.UserID][ug.GroupName] = ug
+	}
+	for g, ids := range snap.GroupRoles {
+		for _, id := range ids {
+			addEdge(s.groupRoles, g, id)
+		}
+	}
+	for rid, ids := range snap.RoleParents {
+		for _, id := range ids {
+			addEdge(s.parents, rid, id)
+		}
+	}
+	for _, e := range snap.ManagedEdges {
+		s.sources[edgeKey{e.Kind, e.From, e.To}] = e.Source
+	}
+	s.saved = s.changes
+	return nil
+}
+
+// Snapshot writes the store to its snapshot path. The file is replaced
+// atomically, so a crash mid-write leaves the previous snapshot intact.
+func (s *MemoryStore) Snapshot(ctx context.Context) error {
+	if s.path == "" {
+		return errors.New("memory_store: no snapshot path configured")
+	}
+	s.snapMu.Lock()
+	defer s.snapMu.Unlock()
+
+	s.mu.RLock()
+	changes := s.changes
+	s.mu.RUnlock()
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), "."+filepath.Base(s.path)+"-*")
+	if err != nil {
+		return fmt.Errorf("memory_store: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if err := s.WriteSnapshot(tmp); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("memory_store: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("memory_store: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return fmt.Errorf("memory_store: %w", err)
+	}
+
+	s.mu.Lock()
+	if changes > s.saved {
+		s.saved = changes
+	}
+	s.mu.Unlock()
+	return nil
+}
+
+// SnapshotEvery snapshots the store every interval while it has unsaved
+// changes, and once more when ctx is cancelled. It blocks until then.
+func (s *MemoryStore) SnapshotEvery(ctx context.Context, interval time.Duration) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			if s.dirty() {
+				if err := s.Snapshot(context.Background()); err != nil {
+					log.Printf("memory_store: final snapshot: %v", err)
+				}
+			}
+			return
+		case <-t.C:
+			if s.dirty() {
+				if err := s.Snapshot(ctx); err != nil {
+					log.Printf("memory_store: snapshot: %v", err)
+				}
+			}
+		}
+	}
+}
+
+func (s *MemoryStore) dirty() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.changes != s.saved
+}
+
+// snapshot copies the store's contents; the caller holds the read lock.
+func (s *MemoryStore) snapshot() *MemorySnapshot {
+	snap := &MemorySnapshot{
+		RolePermissions:    edgeLists(s.rolePerms),
+		RolePermissionSets: edgeLists(s.roleSets),
+		UserRoles:          edgeLists(s.userRoles),
+		GroupRoles:         edgeLists(s.groupRoles),
+		RoleParents:        edgeLists(s.parents),
+		ScopedRoles:        scopedLists(s.urScoped),
+		ScopedGroupRoles:   scopedLists(s.grScoped),
+		ConstrainedRoles:   s.constrainedLists(),
+		TakenAt:            time.Now().Unix(),
+	}
+	for _, p := range s.perms {
+		cp := *p
+		snap.Permissions = append(snap.Permissions, &cp)
+	}
+	for _, r := range s.roles {
+		cp := *r
+		snap.Roles = append(snap.Roles, &cp)
+	}
+	for _, u := range s.users {
+		cp := *u
+		snap.Users = append(snap.Users, &cp)
+	}
+	for _, t := range s.tenants {
+		cp := *t
+		snap.Tenants = append(snap.Tenants, &cp)
+	}
+	for _, g := range s.groups {
+		cp := *g
+		snap.Groups = append(snap.Groups, &cp)
+	}
+	for _, list := range s.attests {
+		for _, a := range list {
+			cp := *a
+			snap.Attestations = append(snap.Attestations, &cp)
+		}
+	}
+	for _, a := range s.archives {
+		cp := *a
+		snap.Archives = append(snap.Archives, &cp)
+	}
+	for _, ps := range s.snapshots {
+		cp := *ps
+		snap.PolicySnapshots = append(snap.PolicySnapshots, &cp)
+	}
+	for _, k := range s.apiKeys {
+		cp := *k
+		snap.APIKeys = append(snap.APIKeys, &cp)
+	}
+	for _, sess := range s.sessions {
+		cp := *sess
+		snap.Sessions = append(snap.Sessions, &cp)
+	}
+	for _, c := range s.sods {
+		cp := *c
+		snap.SoDConstraints = append(snap.SoDConstraints, &cp)
+	}
+	for _, r := range s.requests {
+		cp := *r
+		snap.AssignmentRequests = append(snap.AssignmentRequests, &cp)
+	}
+	for _, d := range s.delegs {
+		cp := *d
+		snap.Delegations = append(snap.Delegations, &cp)
+	}
+	for _, ps := range s.permSets {
+		cp := *ps
+		cp.Permissions = slices.Clone(ps.Permissions)
+		snap.PermissionSets = append(snap.PermissionSets, &cp)
+	}
+	for _, bans := range s.bans {
+		for _, b := range bans {
+			cp := *b
+			snap.GroupBans = append(snap.GroupBans, &cp)
+		}
+	}
+	for _, groups := range s.userGroups {
+		for _, ug := range groups {
+			cp := *ug
+			snap.UserGroups = append(snap.UserGroups, &cp)
+		}
+	}
+	for k, src := range s.sources {
+		snap.ManagedEdges = append(snap.ManagedEdges, &ManagedEdge{Kind: k.Kind, From: k.From, To: k.To, Source: src})
+	}
+	for _, windows := range s.urWindows {
+		for _, a := range windows {
+			cp := *a
+			snap.ScheduledRoles = append(snap.ScheduledRoles, &cp)
+		}
+	}
+
+	sort.Slice(snap.Permissions, func(i, j int) bool { return snap.Permissions[i].ID < snap.Permissions[j].ID })
+	sort.Slice(snap.Roles, func(i, j int) bool { return snap.Roles[i].ID < snap.Roles[j].ID })
+	sort.Slice(snap.Users, func(i, j int) bool { return snap.Users[i].ID < snap.Users[j].ID })
+	sort.Slice(snap.Tenants, func(i, j int) bool { return snap.Tenants[i].ID < snap.Tenants[j].ID })
+	sort.Slice(snap.Groups, func(i, j int) bool { return snap.Groups[i].ID < snap.Groups[j].ID })
+	sort.SliceStable(snap.Attestations, func(i, j int) bool {
+		return snap.Attestations[i].CertifiedAt < snap.Attestations[j].CertifiedAt
+	})
+	sortArchives(snap.Archives)
+	sortPolicySnapshots(snap.PolicySnapshots)
+	sortAPIKeys(snap.APIKeys)
+	sort.Slice(snap.Sessions, func(i, j int) bool { return snap.Sessions[i].ID < snap.Sessions[j].ID })
+	sort.Slice(snap.SoDConstraints, func(i, j int) bool { return snap.SoDConstraints[i].ID < snap.SoDConstraints[j].ID })
+	sort.Slice(snap.AssignmentRequests, func(i, j int) bool { return snap.AssignmentRequests[i].ID < snap.AssignmentRequests[j].ID })
+	sort.Slice(snap.Delegations, func(i, j int) bool { return snap.Delegations[i].ID < snap.Delegations[j].ID })
+	sort.Slice(snap.PermissionSets, func(i, j int) bool { return snap.PermissionSets[i].ID < snap.PermissionSets[j].ID })
+	sort.Slice(snap.GroupBans, func(i, j int) bool {
+		a, b := snap.GroupBans[i], snap.GroupBans[j]
+		return a.GroupName < b.GroupName || a.GroupName == b.GroupName && a.UserID < b.UserID
+	})
+	sort.Slice(snap.UserGroups, func(i, j int) bool {
+		a, b := snap.UserGroups[i], snap.UserGroups[j]
+		return a.UserID < b.UserID || (a.UserID == b.UserID && a.GroupName < b.GroupName)
+	})
+	sort.Slice(snap.ManagedEdges, func(i, j int) bool {
+		a, b := snap.ManagedEdges[i], snap.ManagedEdges[j]
+		return a.Kind+"\x00"+a.From+"\x00"+a.To < b.Kind+"\x00"+b.From+"\x00"+b.To
+	})
+	sort.Slice(snap.ScheduledRoles, func(i, j int) bool {
+		a, b := snap.ScheduledRoles[i], snap.ScheduledRoles[j]
+		return a.UserID < b.UserID || (a.UserID == b.UserID && a.RoleID < b.RoleID)
+	})
+	return snap
+}
+
+func addEdge(m map[string]map[string]struct{}, from, to string) {
+	if m[from] == nil {
+		m[from] = map[string]struct{}{}
+	}
+	m[from][to] = struct{}{}
+}
+
+func removeEdge(m map[string]map[string]struct{}, from, to string) {
+	delete(m[from], to)
+	if len(m[from]) == 0 {
+		delete(m, from)
+	}
+}
+
+func hasEdge(m map[string]map[string]struct{}, from, to string) bool {
+	_, ok := m[from][to]
+	return ok
+}
+
+func edgeList(m map[string]map[string]struct{}, from string) []string {
+	out := make([]string, 0, len(m[from]))
+	for to := range m[from] {
+		out = append(out, to)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// edgeSources returns the sorted keys whose edge sets hold to.
+func edgeSources(m map[string]map[string]struct{}, to string) []string {
+	var out []string
+	for from, set := range m {
+		if _, ok := set[to]; ok {
+			out = append(out, from)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+func edgeLists(m map[string]map[string]struct{}) map[string][]string {
+	out := make(map[string][]string, len(m))
+	for from := range m {
+		out[from] = edgeList(m, from)
+	}
+	return out
+}
+
+func addScoped(m map[string]map[ScopedRole]struct{}, from string, sr ScopedRole) {
+	if m[from] == nil {
+		m[from] = map[ScopedRole]struct{}{}
+	}
+	m[from][sr] = struct{}{}
+}
+
+func removeScoped(m map[string]map[ScopedRole]struct{}, from string, sr ScopedRole) {
+	delete(m[from], sr)
+	if len(m[from]) == 0 {
+		delete(m, from)
+	}
+}
+
+func scopedList(m map[string]map[ScopedRole]struct{}, from string) []ScopedRole {
+	out := make([]ScopedRole, 0, len(m[from]))
+	for sr := range m[from] {
+		out = append(out, sr)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].RoleID < out[j].RoleID || (out[i].RoleID == out[j].RoleID && out[i].Scope < out[j].Scope)
+	})
+	return out
+}
+
+func scopedLists(m map[string]map[ScopedRole]struct{}) map[string][]ScopedRole {
+	if len(m) == 0 {
+		return nil
+	}
+	out := make(map[string][]ScopedRole, len(m))
+	for from := range m {
+		out[from] = scopedList(m, from)
+	}
+	return out
+}
+
+//
+// ---------- UserRepo ----------
+//
+
+func (s *MemoryStore) GetUserByID(ctx context.Context, id string) (*User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if u, ok := s.users[id]; ok {
+		cp := *u
+		return &cp, nil
+	}
+	return nil, nil
+}
+
+func (s *MemoryStore) SetUserStatus(ctx context.Context, id string, status UserStatus) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if u, ok := s.users[id]; ok {
+		u.Status = status
+		stampUpdated(ctx, time.Now(), &u.UpdatedBy, &u.UpdatedAt)
+		s.changes++
+	}
+	return nil
+}
+
+func (s *MemoryStore) SetEmailVerified(ctx context.Context, id string, verified bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if u, ok := s.users[id]; ok {
+		u.EmailVerified = verified
+		stampUpdated(ctx, time.Now(), &u.UpdatedBy, &u.UpdatedAt)
+		s.changes++
+	}
+	return nil
+}
+
+func (s *MemoryStore) GetUserByMeta(ctx context.Context, meta map[string]interface{}) (*User, error) {
+	terms, err := parseUserFilter(meta)
+	if err != nil {
+		return nil, err
+	}
+	return s.userWhere(func(u *User) bool { return matchUser(u, terms) }), nil
+}
+
+func (s *MemoryStore) GetUserByUsername(ctx context.Context, username string) (*User, error) {
+	return s.userWhere(func(u *User) bool { return u.Username == username }), nil
+}
+
+func (s *MemoryStore) GetUserByEmail(ctx context.Context, email string) (*User, error) {
+	return s.userWhere(func(u *User) bool { return u.Email == email }), nil
+}
+
+// userWhere returns a copy of the first user matching fn, or nil.
+func (s *MemoryStore) userWhere(fn func(u *User) bool) *User {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, u := range s.users {
+		if fn(u) {
+			cp := *u
+			return &cp
+		}
+	}
+	return nil
+}
+
+func (s *MemoryStore) ListAllUsers(ctx context.Context) ([]*User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	out := make([]*User, 0, len(s.users))
+	for _, u := range s.users {
+		cp := *u
+		out = append(out, &cp)
+	}
+	return out, nil
+}
+
+func (s *MemoryStore) CreateUser(ctx context.Context, u *User) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if u.ID == "" {
+		u.ID = generateID(s.ids, KindUser)
+	}
+	if _, ok := s.users[u.ID]; ok {
+		return fmt.Errorf("memory_store: user %q already exists", u.ID)
+	}
+	for _, existing := range s.users {
+		if (u.Username != "" && existing.Username == u.Username) || (u.Email != "" && existing.Email == u.Email) {
+			return fmt.Errorf("memory_store: user %q already exists", u.Username)
+		}
+	}
+	u.CreatedAt = time.Now().Unix()
+
+	cp := *u
+	s.users[u.ID] = &cp
+	s.changes++
+	return nil
+}
+
+func (s *MemoryStore) DeleteUser(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.users, id)
+	s.changes++
+	return nil
+}
+
+func (s *MemoryStore) GetGroupsByUserID(ctx context.Context, userID string) ([]*UserGroup, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var out []*UserGroup
+	for _, ug := range s.userGroups[userID] {
+		cp := *ug
+		out = append(out, &cp)
+	}
+	return out, nil
+}
+
+//
+// ---------- PermissionRepo ----------
+//
+
+func (s *MemoryStore) GetPermissionByID(ctx context.Context, id string) (*Permission, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if p, ok := s.perms[id]; ok {
+		cp := *p
+		return &cp, nil
+	}
+	return nil, nil
+}
+
+func (s *MemoryStore) ListAllPermissions(ctx context.Context) ([]*Permission, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	out := make([]*Permission, 0, len(s.perms))
+	for _, p := range s.perms {
+		cp := *p
+		out = append(out, &cp)
+	}
+	return out, nil
+}
+
+func (s *MemoryStore) GetPermissionByResource(ctx context.Context, resource string, action Action) (*Permission, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if p := s.permissionByResource(resource, action); p != nil {
+		cp := *p
+		return &cp, nil
+	}
+	return nil, nil
+}
+
+// GetPermissionByName implements PermissionNameGetter.
+func (s *MemoryStore) GetPermissionByName(ctx context.Context, name string) (*Permission, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if p := s.permissionByName(name); p != nil {
+		cp := *p
+		return &cp, nil
+	}
+	return nil, nil
+}
+
+func (s *MemoryStore) permissionByName(name string) *Permission {
+	if name == "" {
+		return nil
+	}
+	for _, p := range s.perms {
+		if p.Name == name {
+			return p
+		}
+	}
+	return nil
+}
+
+func (s *MemoryStore) permissionByResource(resource string, action Action) *Permission {
+	for _, p := range s.perms {
+		if p.Resource == resource && p.Action == action {
+			return p
+		}
+	}
+	return nil
+}
+
+func (s *MemoryStore) CreatePermission(ctx context.Context, p *Permission) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if existing := s.permissionByResource(p.Resource, p.Action); existing != nil {
+		*p = *existing
+		return nil
+	}
+	if p.ID == "" {
+		p.ID = generateID(s.ids, KindPermission)
+	}
+	if _, ok := s.perms[p.ID]; ok {
+		return fmt.Errorf("memory_store: permission %q already exists", p.ID)
+	}
+	if s.permissionByName(p.Name) != nil {
+		return fmt.Errorf("memory_store: %w: %q", ErrPermissionNameTaken, p.Name)
+	}
+	p.CreatedAt = time.Now().Unix()
+
+	cp := *p
+	s.perms[p.ID] = &cp
+	s.changes++
+	return nil
+}
+
+func (s *MemoryStore) DeletePermission(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.perms, id)
+	s.changes++
+	return nil
+}
+
+//
+// ---------- RoleRepo ----------
+//
+
+func (s *MemoryStore) CreateRole(ctx context.Context, r *Role) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if r.ID == "" {
+		r.ID = generateID(s.ids, KindRole)
+	}
+	if _, ok := s.roles[r.ID]; ok {
+		return fmt.Errorf("memory_store: role %q already exists", r.ID)
+	}
+	if s.roleByName(r.Name) != nil {
+		return fmt.Errorf("memory_store: role %q already exists", r.Name)
+	}
+	r.CreatedAt = time.Now().Unix()
+
+	cp := *r
+	s.roles[r.ID] = &cp
+	s.changes++
+	return nil
+}
+
+func (s *MemoryStore) roleByName(name string) *Role {
+	for _, r := range s.roles {
+		if r.Name == name {
+			return r
+		}
+	}
+	return nil
+}
+
+func (s *MemoryStore) GetRoleByName(ctx context.Context, name string) (*Role, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if r := s.roleByName(name); r != nil {
+		cp := *r
+		return &cp, nil
+	}
+	return nil, nil
+}
+
+func (s *MemoryStore) GetRoleByID(ctx context.Context, id string) (*Role, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if r, ok := s.roles[id]; ok {
+		cp := *r
+		return &cp, nil
+	}
+	return nil, nil
+}
+
+func (s *MemoryStore) DeleteRole(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.roles, id)
+	delete(s.parents, id)
+	for child := range s.parents {
+		removeEdge(s.parents, child, id)
+	}
+	s.changes++
+	return nil
+}
+
+func (s *MemoryStore) ListAllRoles(ctx context.Context) ([]*Role, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	out := make([]*Role, 0, len(s.roles))
+	for _, r := range s.roles {
+		cp := *r
+		out = append(out, &cp)
+	}
+	return out, nil
+}
+
+//
+// ---------- SoftDeleteRepo ----------
+//
+
+func (s *MemoryStore) SetRoleDeletedAt(ctx context.Context, id string, at int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if r, ok := s.roles[id]; ok {
+		r.DeletedAt = at
+		stampUpdated(ctx, time.Now(), &r.UpdatedBy, &r.UpdatedAt)
+		s.changes++
+	}
+	return nil
+}
+
+func (s *MemoryStore) SetPermissionDeletedAt(ctx context.Context, id string, at int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if p, ok := s.perms[id]; ok {
+		p.DeletedAt = at
+		stampUpdated(ctx, time.Now(), &p.UpdatedBy, &p.UpdatedAt)
+		s.changes++
+	}
+	return nil
+}
+
+//
+// ---------- RoleHierarchyRepo ----------
+//
+
+func (s *MemoryStore) AddRoleParent(ctx context.Context, roleID, parentID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	addEdge(s.parents, roleID, parentID)
+	s.changes++
+	return nil
+}
+
+func (s *MemoryStore) RemoveRoleParent(ctx context.Context, roleID, parentID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	removeEdge(s.parents, roleID, parentID)
+	s.changes++
+	return nil
+}
+
+func (s *MemoryStore) ListRoleParents(ctx context.Context, roleID string) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return edgeList(s.parents, roleID), nil
+}
+
+//
+// ---------- RolePermissionRepo ----------
+//
+
+func (s *MemoryStore) AddRP(ctx context.Context, roleID, permID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	recordSource(ctx, s.sources, edgeKey{KindRolePermission, roleID, permID}, hasEdge(s.rolePerms, roleID, permID))
+	addEdge(s.rolePerms, roleID, permID)
+	s.changes++
+	return nil
+}
+
+func (s *MemoryStore) Remove(ctx context.Context, roleID, permID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	removeEdge(s.rolePerms, roleID, permID)
+	delete(s.sources, edgeKey{KindRolePermission, roleID, permID})
+	s.changes++
+	return nil
+}
+
+func (s *MemoryStore) ListPermissions(ctx context.Context, roleID string) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return edgeList(s.rolePerms, roleID), nil
+}
+
+// ListPermissionDetails returns the role's permissions under a single lock.
+func (s *MemoryStore) ListPermissionDetails(ctx context.Context, roleID string) ([]*Permission, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var out []*Permission
+	for id := range s.rolePerms[roleID] {
+		if p, ok := s.perms[id]; ok {
+			cp := *p
+			out = append(out, &cp)
+		}
+	}
+	return out, nil
+}
+
+//
+// ---------- UserRoleRepo ----------
+//
+
+func (s *MemoryStore) AddUR(ctx context.Context, userID, roleID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	recordSource(ctx, s.sources, edgeKey{KindUserRole, userID, roleID}, hasEdge(s.userRoles, userID, roleID))
+	addEdge(s.userRoles, userID, roleID)
+	s.clearWindow(userID, roleID)
+	s.changes++
+	return nil
+}
+
+func (s *MemoryStore) RemoveUR(ctx context.Context, userID, roleID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	removeEdge(s.userRoles, userID, roleID)
+	delete(s.sources, edgeKey{KindUserRole, userID, roleID})
+	s.clearWindow(userID, roleID)
+	s.changes++
+	return nil
+}
+
+// ListRoles returns the user's roles that are active now.
+func (s *MemoryStore) ListRoles(ctx context.Context, userID string) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	now := time.Now()
+	out := edgeList(s.userRoles, userID)[:0]
+	for _, rid := range edgeList(s.userRoles, userID) {
+		if w, ok := s.urWindows[userID][rid]; ok && !w.ActiveAt(now) {
+			continue
+		}
+		out = append(out, rid)
+	}
+	return out, nil
+}
+
+func (s *MemoryStore) ListUsersForRole(ctx context.Context, roleID string) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return edgeSources(s.userRoles, roleID), nil
+}
+
+func (s *MemoryStore) AddScheduledUR(ctx context.Context, a *RoleAssignment) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	recordSource(ctx, s.sources, edgeKey{KindUserRole, a.UserID, a.RoleID}, hasEdge(s.userRoles, a.UserID, a.RoleID))
+	addEdge(s.userRoles, a.UserID, a.RoleID)
+	s.setWindow(a)
+	s.changes++
+	return nil
+}
+
+func (s *MemoryStore) ListRoleAssignments(ctx context.Context, userID string) ([]*RoleAssignment, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var out []*RoleAssignment
+	for _, rid := range edgeList(s.userRoles, userID) {
+		a := &RoleAssignment{UserID: userID, RoleID: rid}
+		if w, ok := s.urWindows[userID][rid]; ok {
+			*a = *w
+		}
+		out = append(out, a)
+	}
+	return out, nil
+}
+
+func (s *MemoryStore) ListExpiringRoleAssignments(ctx context.Context, after, before int64) ([]*RoleAssignment, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var out []*RoleAssignment
+	for _, windows := range s.urWindows {
+		for _, w := range windows {
+			if w.ExpiresAt > after && w.ExpiresAt <= before {
+				cp := *w
+				out = append(out, &cp)
+			}
+		}
+	}
+	return out, nil
+}
+
+func (s *MemoryStore) setWindow(a *RoleAssignment) {
+	if s.urWindows[a.UserID] == nil {
+		s.urWindows[a.UserID] = map[string]*RoleAssignment{}
+	}
+	cp := *a
+	s.urWindows[a.UserID][a.RoleID] = &cp
+}
+
+func (s *MemoryStore) clearWindow(userID, roleID string) {
+	delete(s.urWindows[userID], roleID)
+	if len(s.urWindows[userID]) == 0 {
+		delete(s.urWindows, userID)
+	}
+}
+
+func (s *MemoryStore) AddScopedUR(ctx context.Context, userID, roleID, scope string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	addScoped(s.urScoped, userID, ScopedRole{RoleID: roleID, Scope: scope})
+	s.changes++
+	return nil
+}
+
+func (s *MemoryStore) RemoveScopedUR(ctx context.Context, userID, roleID, scope string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	removeScoped(s.urScoped, userID, ScopedRole{RoleID: roleID, Scope: scope})
+	s.changes++
+	return nil
+}
+
+func (s *MemoryStore) ListScopedRoles(ctx context.Context, userID string) ([]ScopedRole, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return scopedList(s.urScoped, userID), nil
+}
+
+func (s *MemoryStore) AddConstrainedUR(ctx context.Context, userID, roleID string, c RoleConstraints) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.setConstraints(userID, ConstrainedRole{RoleID: roleID, Constraints: c})
+	s.changes++
+	return nil
+}
+
+func (s *MemoryStore) RemoveConstrainedUR(ctx context.Context, userID, roleID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.urLimits[userID], roleID)
+	if len(s.urLimits[userID]) == 0 {
+		delete(s.urLimits, userID)
+	}
+	s.changes++
+	return nil
+}
+
+func (s *MemoryStore) ListConstrainedRoles(ctx context.Context, userID string) ([]ConstrainedRole, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return constrainedList(s.urLimits[userID]), nil
+}
+
+func (s *MemoryStore) setConstraints(userID string, cr ConstrainedRole) {
+	if s.urLimits[userID] == nil {
+		s.urLimits[userID] = map[string]RoleConstraints{}
+	}
+	cr.Constraints.AllowedCIDRs = slices.Clone(cr.Constraints.AllowedCIDRs)
+	s.urLimits[userID][cr.RoleID] = cr.Constraints
+}
+
+func (s *MemoryStore) constrainedLists() map[string][]ConstrainedRole {
+	if len(s.urLimits) == 0 {
+		return nil
+	}
+	out := make(map[string][]ConstrainedRole, len(s.urLimits))
+	for uid, limits := range s.urLimits {
+		out[uid] = constrainedList(limits)
+	}
+	return out
+}
+
+// constrainedList copies a user's constrained roles, ordered by role.
+func constrainedList(limits map[string]RoleConstraints) []ConstrainedRole {
+	out := make([]ConstrainedRole, 0, len(limits))
+	for rid, c := range limits {
+		c.AllowedCIDRs = slices.Clone(c.AllowedCIDRs)
+		out = append(out, ConstrainedRole{RoleID: rid, Constraints: c})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].RoleID < out[j].RoleID })
+	return out
+}
+
+//
+// ---------- UserGroupRepo ----------
+//
+
+func (s *MemoryStore) AddUserToGroup(ctx context.Context, ug *UserGroup) error {
+	if ug.UserID == "" {
+		return errors.New("user id is empty")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if ug.ID == "" {
+		ug.ID = generateID(s.ids, KindUserGroup)
+	}
+	ug.CreatedAt = time.Now().Unix()
+
+	if s.userGroups[ug.UserID] == nil {
+		s.userGroups[ug.UserID] = map[string]*UserGroup{}
+	}
+	_, existed := s.userGroups[ug.UserID][ug.GroupName]
+	recordSource(ctx, s.sources, edgeKey{KindUserGroup, ug.UserID, ug.GroupName}, existed)
+	cp := *ug
+	s.userGroups[ug.UserID][ug.GroupName] = &cp
+	s.changes++
+	return nil
+}
+
+func (s *MemoryStore) RemoveUserFromGroup(ctx context.Context, groupName string, ug *UserGroup) error {
+	if ug.UserID == "" {
+		return errors.New("user id is empty")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.userGroups[ug.UserID], groupName)
+	delete(s.sources, edgeKey{KindUserGroup, ug.UserID, groupName})
+	if len(s.userGroups[ug.UserID]) == 0 {
+		delete(s.userGroups, ug.UserID)
+	}
+	s.changes++
+	return nil
+}
+
+func (s *MemoryStore) GetUsersByGroupID(ctx context.Context, groupName string) ([]*UserGroup, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var out []*UserGroup
+	for _, groups := range s.userGroups {
+		if ug, ok := groups[groupName]; ok {
+			cp := *ug
+			out = append(out, &cp)
+		}
+	}
+	return out, nil
+}
+
+func (s *MemoryStore) ListExpiringMemberships(ctx context.Context, after, before int64) ([]*UserGroup, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var out []*UserGroup
+	for _, groups := range s.userGroups {
+		for _, ug := range groups {
+			if ug.ExpiresAt > after && ug.ExpiresAt <= before {
+				cp := *ug
+				out = append(out, &cp)
+			}
+		}
+	}
+	return out, nil
+}
+
+//
+// ---------- GroupBanRepo ----------
+//
+
+func (s *MemoryStore) AddGroupBan(ctx context.Context, b *GroupBan) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.bans[b.GroupName] == nil {
+		s.bans[b.GroupName] = map[string]*GroupBan{}
+	}
+	cp := *b
+	s.bans[b.GroupName][b.UserID] = &cp
+	s.changes++
+	return nil
+}
+
+func (s *MemoryStore) RemoveGroupBan(ctx context.Context, groupName, userID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.bans[groupName][userID]; ok {
+		delete(s.bans[groupName], userID)
+		if len(s.bans[groupName]) == 0 {
+			delete(s.bans, groupName)
+		}
+		s.changes++
+	}
+	return nil
+}
+
+func (s *MemoryStore) ListGroupBans(ctx context.Context, groupName string) ([]*GroupBan, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var out []*GroupBan
+	for _, b := range s.bans[groupName] {
+		cp := *b
+		out = append(out, &cp)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].UserID < out[j].UserID })
+	return out, nil
+}
+
+func (s *MemoryStore) ListUserBans(ctx context.Context, userID string) ([]*GroupBan, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var out []*GroupBan
+	for _, bans := range s.bans {
+		if b, ok := bans[userID]; ok {
+			cp := *b
+			out = append(out, &cp)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].GroupName < out[j].GroupName })
+	return out, nil
+}
+
+//
+// ---------- GroupRepo ----------
+//
+
+func (s *MemoryStore) CreateGroup(ctx context.Context, g *Group) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if g.ID == "" {
+		g.ID = generateID(s.ids, KindGroup)
+	}
+	if _, ok := s.groups[g.ID]; ok {
+		return fmt.Errorf("memory_store: group %q already exists", g.ID)
+	}
+	if s.groupByName(g.Name) != nil {
+		return fmt.Errorf("memory_store: group %q already exists", g.Name)
+	}
+	g.CreatedAt = time.Now().Unix()
+
+	cp := *g
+	s.groups[g.ID] = &cp
+	s.changes++
+	return nil
+}
+
+func (s *MemoryStore) UpdateGroup(ctx context.Context, g *Group) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.groups[g.ID]; !ok {
+		return fmt.Errorf("memory_store: group %q not found", g.ID)
+	}
+	if other := s.groupByName(g.Name); other != nil && other.ID != g.ID {
+		return fmt.Errorf("memory_store: group %q already exists", g.Name)
+	}
+	cp := *g
+	s.groups[g.ID] = &cp
+	s.changes++
+	return nil
+}
+
+func (s *MemoryStore) DeleteGroup(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.groups, id)
+	s.changes++
+	return nil
+}
+
+func (s *MemoryStore) groupByName(name string) *Group {
+	for _, g := range s.groups {
+		if g.Name == name {
+			return g
+		}
+	}
+	return nil
+}
+
+func (s *MemoryStore) GetGroupByID(ctx context.Context, id string) (*Group, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if g, ok := s.groups[id]; ok {
+		cp := *g
+		return &cp, nil
+	}
+	return nil, nil
+}
+
+func (s *MemoryStore) GetGroupByName(ctx context.Context, name string) (*Group, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if g := s.groupByName(name); g != nil {
+		cp := *g
+		return &cp, nil
+	}
+	return nil, nil
+}
+
+func (s *MemoryStore) ListGroups(ctx context.Context) ([]*Group, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	out := make([]*Group, 0, len(s.groups))
+	for _, g := range s.groups {
+		cp := *g
+		out = append(out, &cp)
+	}
+	return out, nil
+}
+
+//
+// ---------- AttestationRepo ----------
+//
+
+func (s *MemoryStore) AddAttestation(ctx context.Context, a *Attestation) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if a.ID == "" {
+		a.ID = generateID(s.ids, KindAttestation)
+	}
+	cp := *a
+	s.attests[a.UserID] = append(s.attests[a.UserID], &cp)
+	s.changes++
+	return nil
+}
+
+func (s *MemoryStore) LatestAttestation(ctx context.Context, userID, roleID string) (*Attestation, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	list := s.attests[userID]
+	for i := len(list) - 1; i >= 0; i-- {
+		if list[i].RoleID == roleID {
+			cp := *list[i]
+			return &cp, nil
+		}
+	}
+	return nil, nil
+}
+
+func (s *MemoryStore) ListAttestations(ctx context.Context, userID string) ([]*Attestation, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	out := make([]*Attestation, 0, len(s.attests[userID]))
+	for _, a := range s.attests[userID] {
+		cp := *a
+		out = append(out, &cp)
+	}
+	return out, nil
+}
+
+//
+// ---------- ArchiveRepo ----------
+//
+
+func (s *MemoryStore) SaveArchive(ctx context.Context, a *Archive) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if a.ID == "" {
+		a.ID = generateID(s.ids, KindArchive)
+	}
+	cp := *a
+	s.archives[a.ID] = &cp
+	s.changes++
+	return nil
+}
+
+func (s *MemoryStore) GetArchive(ctx context.Context, id string) (*Archive, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if a, ok := s.archives[id]; ok {
+		cp := *a
+		return &cp, nil
+	}
+	return nil, nil
+}
+
+func (s *MemoryStore) ListArchives(ctx context.Context) ([]*Archive, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	out := make([]*Archive, 0, len(s.archives))
+	for _, a := range s.archives {
+		cp := *a
+		out = append(out, &cp)
+	}
+	sortArchives(out)
+	return out, nil
+}
+
+//
+// ---------- PolicyVersionRepo ----------
+//
+
+func (s *MemoryStore) SavePolicySnapshot(ctx context.Context, ps *PolicySnapshot) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if ps.ID == "" {
+		ps.ID = generateID(s.ids, KindPolicySnapshot)
+	}
+	cp := *ps
+	s.snapshots[ps.ID] = &cp
+	s.changes++
+	return nil
+}
+
+func (s *MemoryStore) GetPolicySnapshot(ctx context.Context, id string) (*PolicySnapshot, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if ps, ok := s.snapshots[id]; ok {
+		cp := *ps
+		return &cp, nil
+	}
+	return nil, nil
+}
+
+func (s *MemoryStore) ListPolicySnapshots(ctx context.Context) ([]*PolicySnapshot, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	out := make([]*PolicySnapshot, 0, len(s.snapshots))
+	for _, ps := range s.snapshots {
+		cp := *ps
+		out = append(out, &cp)
+	}
+	sortPolicySnapshots(out)
+	return out, nil
+}
+
+//
+// ---------- APIKeyRepo ----------
+//
+
+func (s *MemoryStore) SaveAPIKey(ctx context.Context, k *APIKey) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if k.ID == "" {
+		k.ID = generateID(s.ids, KindAPIKey)
+	}
+	cp := *k
+	s.apiKeys[k.ID] = &cp
+	s.changes++
+	return nil
+}
+
+func (s *MemoryStore) GetAPIKey(ctx context.Context, id string) (*APIKey, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if k, ok := s.apiKeys[id]; ok {
+		cp := *k
+		return &cp, nil
+	}
+	return nil, nil
+}
+
+func (s *MemoryStore) ListAPIKeys(ctx context.Context, principalID string) ([]*APIKey, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var out []*APIKey
+	for _, k := range s.apiKeys {
+		if k.PrincipalID == principalID {
+			cp := *k
+			out = append(out, &cp)
+		}
+	}
+	sortAPIKeys(out)
+	return out, nil
+}
+
+//
+// ---------- SessionRepo ----------
+//
+
+func (s *MemoryStore) SaveSession(ctx context.Context, sess *Session) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if sess.ID == "" {
+		sess.ID = generateID(s.ids, KindSession)
+	}
+	cp := *sess
+	cp.Roles = slices.Clone(sess.Roles)
+	cp.Scoped = slices.Clone(sess.Scoped)
+	s.sessions[sess.ID] = &cp
+	s.changes++
+	return nil
+}
+
+func (s *MemoryStore) GetSession(ctx context.Context, id string) (*Session, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if sess, ok := s.sessions[id]; ok {
+		cp := *sess
+		cp.Roles = slices.Clone(sess.Roles)
+		cp.Scoped = slices.Clone(sess.Scoped)
+		return &cp, nil
+	}
+	return nil, nil
+}
+
+func (s *MemoryStore) DeleteSession(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.sessions[id]; ok {
+		delete(s.sessions, id)
+		s.changes++
+	}
+	return nil
+}
+
+//
+// ---------- SoDRepo ----------
+//
+
+func (s *MemoryStore) CreateSoDConstraint(ctx context.Context, c *SoDConstraint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if c.ID == "" {
+		c.ID = generateID(s.ids, KindSoDConstraint)
+	}
+	cp := *c
+	cp.Roles = slices.Clone(c.Roles)
+	s.sods[c.ID] = &cp
+	s.changes++
+	return nil
+}
+
+func (s *MemoryStore) GetSoDConstraint(ctx context.Context, id string) (*SoDConstraint, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if c, ok := s.sods[id]; ok {
+		cp := *c
+		cp.Roles = slices.Clone(c.Roles)
+		return &cp, nil
+	}
+	return nil, nil
+}
+
+func (s *MemoryStore) ListSoDConstraints(ctx context.Context) ([]*SoDConstraint, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	out := make([]*SoDConstraint, 0, len(s.sods))
+	for _, c := range s.sods {
+		cp := *c
+		cp.Roles = slices.Clone(c.Roles)
+		out = append(out, &cp)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out, nil
+}
+
+func (s *MemoryStore) DeleteSoDConstraint(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.sods[id]; ok {
+		delete(s.sods, id)
+		s.changes++
+	}
+	return nil
+}
+
+//
+// ---------- ApprovalRepo ----------
+//
+
+func (s *MemoryStore) SaveAssignmentRequest(ctx context.Context, r *AssignmentRequest) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if r.ID == "" {
+		r.ID = generateID(s.ids, KindAssignmentRequest)
+	}
+	cp := *r
+	s.requests[r.ID] = &cp
+	s.changes++
+	return nil
+}
+
+func (s *MemoryStore) GetAssignmentRequest(ctx context.Context, id string) (*AssignmentRequest, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if r, ok := s.requests[id]; ok {
+		cp := *r
+		return &cp, nil
+	}
+	return nil, nil
+}
+
+func (s *MemoryStore) ListAssignmentRequests(ctx context.Context, status AssignmentStatus) ([]*AssignmentRequest, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var out []*AssignmentRequest
+	for _, r := range s.requests {
+		if status == "" || r.Status == status {
+			cp := *r
+			out = append(out, &cp)
+		}
+	}
+	sortAssignmentRequests(out)
+	return out, nil
+}
+
+//
+// ---------- DelegationRepo ----------
+//
+
+func (s *MemoryStore) SaveDelegation(ctx context.Context, d *Delegation) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if d.ID == "" {
+		d.ID = generateID(s.ids, KindDelegation)
+	}
+	cp := *d
+	s.delegs[d.ID] = &cp
+	s.changes++
+	return nil
+}
+
+func (s *MemoryStore) GetDelegation(ctx context.Context, id string) (*Delegation, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if d, ok := s.delegs[id]; ok {
+		cp := *d
+		return &cp, nil
+	}
+	return nil, nil
+}
+
+func (s *MemoryStore) DeleteDelegation(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.delegs[id]; ok {
+		delete(s.delegs, id)
+		s.changes++
+	}
+	return nil
+}
+
+func (s *MemoryStore) ListDelegations(ctx context.Context, userID string) ([]*Delegation, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var out []*Delegation
+	for _, d := range s.delegs {
+		if d.FromUserID == userID || d.ToUserID == userID {
+			cp := *d
+			out = append(out, &cp)
+		}
+	}
+	sortDelegations(out)
+	return out, nil
+}
+
+//
+// ---------- PermissionSetRepo ----------
+//
+
+func (s *MemoryStore) SavePermissionSet(ctx context.Context, ps *PermissionSet) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if ps.ID == "" {
+		ps.ID = generateID(s.ids, KindPermissionSet)
+	}
+	cp := *ps
+	cp.Permissions = slices.Clone(ps.Permissions)
+	s.permSets[ps.ID] = &cp
+	s.changes++
+	return nil
+}
+
+func (s *MemoryStore) GetPermissionSet(ctx context.Context, id string) (*PermissionSet, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if ps, ok := s.permSets[id]; ok {
+		cp := *ps
+		cp.Permissions = slices.Clone(ps.Permissions)
+		return &cp, nil
+	}
+	return nil, nil
+}
+
+func (s *MemoryStore) ListPermissionSets(ctx context.Context) ([]*PermissionSet, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	out := make([]*PermissionSet, 0, len(s.permSets))
+	for _, ps := range s.permSets {
+		cp := *ps
+		cp.Permissions = slices.Clone(ps.Permissions)
+		out = append(out, &cp)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out, nil
+}
+
+func (s *MemoryStore) DeletePermissionSet(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.permSets[id]; !ok {
+		return nil
+	}
+	delete(s.permSets, id)
+	for roleID := range s.roleSets {
+		removeEdge(s.roleSets, roleID, id)
+	}
+	s.changes++
+	return nil
+}
+
+func (s *MemoryStore) AddSetToRole(ctx context.Context, roleID, setID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	addEdge(s.roleSets, roleID, setID)
+	s.changes++
+	return nil
+}
+
+func (s *MemoryStore) RemoveSetFromRole(ctx context.Context, roleID, setID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	removeEdge(s.roleSets, roleID, setID)
+	s.changes++
+	return nil
+}
+
+func (s *MemoryStore) ListSetsForRole(ctx context.Context, roleID string) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return edgeList(s.roleSets, roleID), nil
+}
+
+//
+// ---------- GroupRoleRepo ----------
+//
+
+func (s *MemoryStore) AddRoleToGroup(ctx context.Context, groupID, roleID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	recordSource(ctx, s.sources, edgeKey{KindGroupRole, groupID, roleID}, hasEdge(s.groupRoles, groupID, roleID))
+	addEdge(s.groupRoles, groupID, roleID)
+	s.changes++
+	return nil
+}
+
+func (s *MemoryStore) RemoveRoleFromGroup(ctx context.Context, groupID, roleID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	removeEdge(s.groupRoles, groupID, roleID)
+	delete(s.sources, edgeKey{KindGroupRole, groupID, roleID})
+	s.changes++
+	return nil
+}
+
+func (s *MemoryStore) ListRolesForGroup(ctx context.Context, groupID string) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return edgeList(s.groupRoles, groupID), nil
+}
+
+func (s *MemoryStore) ListGroupsForRole(ctx context.Context, roleID string) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return edgeSources(s.groupRoles, roleID), nil
+}
+
+func (s *MemoryStore) AddScopedRoleToGroup(ctx context.Context, groupID, roleID, scope string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	addScoped(s.grScoped, groupID, ScopedRole{RoleID: roleID, Scope: scope})
+	s.changes++
+	return nil
+}
+
+func (s *MemoryStore) RemoveScopedRoleFromGroup(ctx context.Context, groupID, roleID, scope string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	removeScoped(s.grScoped, groupID, ScopedRole{RoleID: roleID, Scope: scope})
+	s.changes++
+	return nil
+}
+
+func (s *MemoryStore) ListScopedRolesForGroup(ctx context.Context, groupID string) ([]ScopedRole, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return scopedList(s.grScoped, groupID), nil
+}
+
+//
+// ---------- EdgeSourceRepo ----------
+//
+
+func (s *MemoryStore) EdgeSource(ctx context.Context, kind, from, to string) (string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var exists bool
+	switch kind {
+	case KindUserRole:
+		exists = hasEdge(s.userRoles, from, to)
+	case KindRolePermission:
+		exists = hasEdge(s.rolePerms, from, to)
+	case KindGroupRole:
+		exists = hasEdge(s.groupRoles, from, to)
+	case KindUserGroup:
+		_, exists = s.userGroups[from][to]
+	default:
+		return "", fmt.Errorf("memory_store: unknown edge kind %q", kind)
+	}
+	return lookupSource(s.sources, edgeKey{kind, from, to}, exists), nil
+}
+
+//
+// ---------- ExportPager ----------
+//
+
+func (s *MemoryStore) ExportPage(ctx context.Context, kind, after string, limit int) ([]ExportItem, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var items []ExportItem
+	switch kind {
+	case KindPermission:
+		for id, p := range s.perms {
+			if id > after {
+				cp := *p
+				items = append(items, ExportItem{Key: id, Value: &cp})
+			}
+		}
+	case KindRole:
+		for id, r := range s.roles {
+			if id > after {
+				cp := *r
+				items = append(items, ExportItem{Key: id, Value: &cp})
+			}
+		}
+	case KindUser:
+		for id, u := range s.users {
+			if id > after {
+				cp := *u
+				items = append(items, ExportItem{Key: id, Value: &cp})
+			}
+		}
+	case KindRolePermission:
+		items = edgeItems(s.rolePerms, after)
+	case KindUserRole:
+		items = edgeItems(s.userRoles, after)
+	case KindGroupRole:
+		items = edgeItems(s.groupRoles, after)
+	case KindUserGroup:
+		for userID, groups := range s.userGroups {
+			for name, ug := range groups {
+				if key := userID + ExportKeySep + name; key > after {
+					cp := *ug
+					items = append(items, ExportItem{Key: key, Value: &cp})
+				}
+			}
+		}
+	default:
+		return nil, fmt.Errorf("memory_store: unknown export kind %q", kind)
+	}
+	return pageItems(items, after, limit), nil
+}
+
+//
+// ---------- TenantRepo ----------
+//
+
+func (s *MemoryStore) CreateTenant(ctx context.Context, t *Tenant) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if t.ID == "" {
+		t.ID = generateID(s.ids, KindTenant)
+	}
+	if _, ok := s.tenants[t.ID]; ok {
+		return fmt.Errorf("memory_store: tenant %q already exists", t.ID)
+	}
+	cp := *t
+	s.tenants[t.ID] = &cp
+	s.changes++
+	return nil
+}
+
+func (s *MemoryStore) DeleteTenant(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.tenants, id)
+	s.changes++
+	return nil
+}
+
+func (s *MemoryStore) GetTenantByID(ctx context.Context, id string) (*Tenant, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if t, ok := s.tenants[id]; ok {
+		cp := *t
+		return &cp, nil
+	}
+	return nil, nil
+}
+
+func (s *MemoryStore) ListTenants(ctx context.Context) ([]*Tenant, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	out := make([]*Tenant, 0, len(s.tenants))
+	for _, t := range s.tenants {
+		cp := *t
+		out = append(out, &cp)
+	}
+	return out, nil
+}
+
+func (s *MemoryStore) ListPermissionsByTenant(ctx context.Context, tenantID string) ([]*Permission, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var out []*Permission
+	for _, p := range s.perms {
+		if p.TenantID == tenantID {
+			cp := *p
+			out = append(out, &cp)
+		}
+	}
+	return out, nil
+}
+
+func (s *MemoryStore) ListRolesByTenant(ctx context.Context, tenantID string) ([]*Role, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var out []*Role
+	for _, r := range s.roles {
+		if r.TenantID == tenantID {
+			cp := *r
+			out = append(out, &cp)
+		}
+	}
+	return out, nil
+}
+
+func (s *MemoryStore) ListUsersByTenant(ctx context.Context, tenantID string) ([]*User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var out []*User
+	for _, u := range s.users {
+		if u.TenantID == tenantID {
+			cp := *u
+			out = append(out, &cp)
+		}
+	}
+	return out, nil
+}
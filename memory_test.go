@@ -0,0 +1,106 @@
+package rbac
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestMemoryStore(t *testing.T) {
+	s, err := NewMemoryStore(context.Background(), "")
+	if err != nil {
+		t.Fatalf("NewMemoryStore: %v", err)
+	}
+	runSuite(t, s)
+}
+
+func TestMemoryStoreSnapshots(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rbac.json")
+	ctx, cancel := context.WithCancel(context.Background())
+
+	mgr, err := NewMemoryStoreManager(ctx, path, time.Hour)
+	if err != nil {
+		t.Fatalf("NewMemoryStoreManager: %v", err)
+	}
+	perm := &Permission{Resource: "survey.*", Action: ActionRead}
+	if err := mgr.CreatePermission(ctx, perm); err != nil {
+		t.Fatalf("CreatePermission: %v", err)
+	}
+	role := &Role{Name: "reader"}
+	if err := mgr.CreateRole(ctx, role); err != nil {
+		t.Fatalf("CreateRole: %v", err)
+	}
+	if err := mgr.AssignPermissionToRole(ctx, role.ID, perm.ID); err != nil {
+		t.Fatalf("AssignPermissionToRole: %v", err)
+	}
+	if err := mgr.AddUserToGroup(ctx, &UserGroup{UserID: "alice", GroupName: "staff"}); err != nil {
+		t.Fatalf("AddUserToGroup: %v", err)
+	}
+	if err := mgr.AssignRoleToGroup(ctx, "staff", role.ID); err != nil {
+		t.Fatalf("AssignRoleToGroup: %v", err)
+	}
+
+	// Cancelling the context writes a final snapshot even though the
+	// interval never elapsed.
+	store := mgr.Perms.(*MemoryStore)
+	cancel()
+	deadline := time.Now().Add(5 * time.Second)
+	for store.dirty() {
+		if time.Now().After(deadline) {
+			t.Fatal("expected a snapshot after cancellation")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	reloaded, err := NewMemoryStoreManager(context.Background(), path, 0)
+	if err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+	ok, err := reloaded.Can(context.Background(), "alice", "survey.1", ActionRead)
+	if err != nil || !ok {
+		t.Fatalf("expected the reloaded policy to grant access, got %v (%v)", ok, err)
+	}
+	roles, _ := reloaded.Roles.ListAllRoles(context.Background())
+	if len(roles) != 2 {
+		t.Errorf("expected the default role not to be seeded twice, got %d roles", len(roles))
+	}
+}
+
+func TestMemoryStoreConcurrentAccess(t *testing.T) {
+	ctx := context.Background()
+	mgr, err := NewMemoryStoreManager(ctx, "", 0)
+	if err != nil {
+		t.Fatalf("NewMemoryStoreManager: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			role := &Role{Name: fmt.Sprintf("role-%d", i)}
+			if err := mgr.CreateRole(ctx, role); err != nil {
+				t.Errorf("CreateRole: %v", err)
+				return
+			}
+			perm := &Permission{Resource: fmt.Sprintf("doc.%d", i), Action: ActionRead}
+			if err := mgr.CreatePermission(ctx, perm); err != nil {
+				t.Errorf("CreatePermission: %v", err)
+				return
+			}
+			user := fmt.Sprintf("user-%d", i)
+			_ = mgr.AssignPermissionToRole(ctx, role.ID, perm.ID)
+			_ = mgr.AssignRoleToUser(ctx, user, role.ID)
+			for j := 0; j < 50; j++ {
+				if ok, err := mgr.Can(ctx, user, perm.Resource, ActionRead); err != nil || !ok {
+					t.Errorf("Can(%s): %v %v", user, ok, err)
+					return
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+}
@@ -2,8 +2,9 @@ package rbac
 
 import (
 	"context"
-
-	"go.mongodb.org/mongo-driver/mongo"
+	"slices"
+	"sort"
+	"time"
 )
 
 // MockRepo is an in-memory implementation of all RBAC repository interfaces.
@@ -14,36 +15,77 @@ type MockRepo struct {
 	perms      map[string]*Permission
 	roles      map[string]*Role
 	users      map[string]*User
-	rolePerms  map[string]map[string]struct{}   // roleID -> set of permIDs
-	userRoles  map[string]map[string]struct{}   // userID -> set of roleIDs
-	userGroups map[string]map[string]*UserGroup // userID -> groupID -> *UserGroup
-	groupUsers map[string]map[string]*UserGroup // groupID -> userID -> *UserGroup
-	groupRoles map[string]map[string]struct{}   // groupID -> set of roleIDs
+	rolePerms  map[string]map[string]struct{}        // roleID -> set of permIDs
+	userRoles  map[string]map[string]struct{}        // userID -> set of roleIDs
+	urWindows  map[string]map[string]*RoleAssignment // userID -> roleID -> window of scheduled assignments
+	userGroups map[string]map[string]*UserGroup      // userID -> groupID -> *UserGroup
+	groupUsers map[string]map[string]*UserGroup      // groupID -> userID -> *UserGroup
+	groupRoles map[string]map[string]struct{}        // groupID -> set of roleIDs
+	urScoped   map[string]map[ScopedRole]struct{}    // userID -> set of scoped roles
+	grScoped   map[string]map[ScopedRole]struct{}    // groupID -> set of scoped roles
+	urLimits   map[string]map[string]RoleConstraints // userID -> roleID -> constraints of constrained roles
+	parents    map[string]map[string]struct{}        // roleID -> set of parent roleIDs
+	sources    map[edgeKey]string                    // edge -> source, for edges not managed manually
+	tenants    map[string]*Tenant
+	groups     map[string]*Group
+	attests    map[string][]*Attestation // userID -> attestations, oldest first
+	archives   map[string]*Archive
+	snapshots  map[string]*PolicySnapshot
+	apiKeys    map[string]*APIKey
+	sessions   map[string]*Session
+	sods       map[string]*SoDConstraint
+	requests   map[string]*AssignmentRequest
+	delegs     map[string]*Delegation
+	permSets   map[string]*PermissionSet
+	roleSets   map[string][]string
+	bans       []*GroupBan
+	audit      []*AuditEntry // oldest first
+	ids        IDGenerator
+}
+
+// SetIDGenerator changes how the mock generates IDs for entities created
+// without one.
+func (f *MockRepo) SetIDGenerator(g IDGenerator) {
+	f.ids = g
 }
 
 func (f *MockRepo) ListAllRoles(ctx context.Context) ([]*Role, error) {
-	//TODO implement me
-	panic("implement me")
+	var out []*Role
+	for _, r := range f.roles {
+		out = append(out, r)
+	}
+	return out, nil
 }
 
 func (f *MockRepo) GetPermissionByResource(ctx context.Context, resource string, action Action) (*Permission, error) {
-	//TODO implement me
-	panic("implement me")
+	for _, p := range f.perms {
+		if p.Resource == resource && p.Action == action {
+			return p, nil
+		}
+	}
+	return nil, nil
 }
 
 func (f *MockRepo) GetUserByMeta(ctx context.Context, meta map[string]interface{}) (*User, error) {
-	//TODO implement me
-	panic("implement me")
+	terms, err := parseUserFilter(meta)
+	if err != nil {
+		return nil, err
+	}
+	for _, u := range f.users {
+		if matchUser(u, terms) {
+			return u, nil
+		}
+	}
+	return nil, nil
 }
 
 func (f *MockRepo) GetRoleByName(ctx context.Context, name string) (*Role, error) {
-	//TODO implement me
 	for _, role := range f.roles {
 		if role.Name == name {
 			return role, nil
 		}
 	}
-	return nil, mongo.ErrNoDocuments
+	return nil, nil
 }
 
 // NewMockRepo initializes a new MockRepo with empty data structures.
@@ -54,9 +96,27 @@ func NewMockRepo() *MockRepo {
 		users:      make(map[string]*User),
 		rolePerms:  make(map[string]map[string]struct{}),
 		userRoles:  make(map[string]map[string]struct{}),
+		urWindows:  make(map[string]map[string]*RoleAssignment),
 		userGroups: make(map[string]map[string]*UserGroup),
 		groupUsers: make(map[string]map[string]*UserGroup),
 		groupRoles: make(map[string]map[string]struct{}),
+		urScoped:   make(map[string]map[ScopedRole]struct{}),
+		grScoped:   make(map[string]map[ScopedRole]struct{}),
+		urLimits:   make(map[string]map[string]RoleConstraints),
+		parents:    make(map[string]map[string]struct{}),
+		sources:    make(map[edgeKey]string),
+		tenants:    make(map[string]*Tenant),
+		groups:     make(map[string]*Group),
+		attests:    make(map[string][]*Attestation),
+		archives:   make(map[string]*Archive),
+		snapshots:  make(map[string]*PolicySnapshot),
+		apiKeys:    make(map[string]*APIKey),
+		sessions:   make(map[string]*Session),
+		sods:       make(map[string]*SoDConstraint),
+		requests:   make(map[string]*AssignmentRequest),
+		delegs:     make(map[string]*Delegation),
+		permSets:   make(map[string]*PermissionSet),
+		roleSets:   make(map[string][]string),
 	}
 }
 
@@ -69,12 +129,26 @@ func NewMockRepoManager(m *MockRepo) *Manager {
 		UR:              m,
 		UG:              m,
 		GR:              m,
+		Tenants:         m,
+		Groups:          m,
+		Attestations:    m,
+		Archives:        m,
+		PolicyVersions:  m,
+		APIKeys:         m,
+		Sessions:        m,
+		SoD:             m,
+		Approvals:       m,
+		Delegations:     m,
+		PermSets:        m,
 		DefaultRoleName: "default",
 	}
 }
 
 // PermissionRepo implementation
 func (f *MockRepo) CreatePermission(ctx context.Context, p *Permission) error {
+	if p.ID == "" {
+		p.ID = generateID(f.ids, KindPermission)
+	}
 	f.perms[p.ID] = p
 	return nil
 }
@@ -82,6 +156,14 @@ func (f *MockRepo) DeletePermission(ctx context.Context, id string) error {
 	delete(f.perms, id)
 	return nil
 }
+func (f *MockRepo) GetPermissionByName(ctx context.Context, name string) (*Permission, error) {
+	for _, p := range f.perms {
+		if p.Name == name {
+			return p, nil
+		}
+	}
+	return nil, nil
+}
 func (f *MockRepo) GetPermissionByID(ctx context.Context, id string) (*Permission, error) {
 	if p, ok := f.perms[id]; ok {
 		return p, nil
@@ -89,8 +171,19 @@ func (f *MockRepo) GetPermissionByID(ctx context.Context, id string) (*Permissio
 	return nil, nil
 }
 
+func (f *MockRepo) ListAllPermissions(ctx context.Context) ([]*Permission, error) {
+	var out []*Permission
+	for _, p := range f.perms {
+		out = append(out, p)
+	}
+	return out, nil
+}
+
 // RoleRepo implementation
 func (f *MockRepo) CreateRole(ctx context.Context, r *Role) error {
+	if r.ID == "" {
+		r.ID = generateID(f.ids, KindRole)
+	}
 	f.roles[r.ID] = r
 	return nil
 }
@@ -105,8 +198,31 @@ func (f *MockRepo) GetRoleByID(ctx context.Context, id string) (*Role, error) {
 	return nil, nil
 }
 
+// RoleHierarchyRepo implementation
+func (f *MockRepo) AddRoleParent(ctx context.Context, roleID, parentID string) error {
+	if f.parents[roleID] == nil {
+		f.parents[roleID] = make(map[string]struct{})
+	}
+	f.parents[roleID][parentID] = struct{}{}
+	return nil
+}
+func (f *MockRepo) RemoveRoleParent(ctx context.Context, roleID, parentID string) error {
+	delete(f.parents[roleID], parentID)
+	return nil
+}
+func (f *MockRepo) ListRoleParents(ctx context.Context, roleID string) ([]string, error) {
+	var out []string
+	for pid := range f.parents[roleID] {
+		out = append(out, pid)
+	}
+	return out, nil
+}
+
 // UserRepo implementation
 func (f *MockRepo) CreateUser(ctx context.Context, u *User) error {
+	if u.ID == "" {
+		u.ID = generateID(f.ids, KindUser)
+	}
 	f.users[u.ID] = u
 	return nil
 }
@@ -121,8 +237,50 @@ func (f *MockRepo) GetUserByID(ctx context.Context, id string) (*User, error) {
 	return nil, nil
 }
 
+func (f *MockRepo) GetUserByUsername(ctx context.Context, username string) (*User, error) {
+	for _, u := range f.users {
+		if u.Username == username {
+			return u, nil
+		}
+	}
+	return nil, nil
+}
+func (f *MockRepo) GetUserByEmail(ctx context.Context, email string) (*User, error) {
+	for _, u := range f.users {
+		if u.Email == email {
+			return u, nil
+		}
+	}
+	return nil, nil
+}
+
+func (f *MockRepo) SetUserStatus(ctx context.Context, id string, status UserStatus) error {
+	if u, ok := f.users[id]; ok {
+		u.Status = status
+		stampUpdated(ctx, time.Now(), &u.UpdatedBy, &u.UpdatedAt)
+	}
+	return nil
+}
+
+func (f *MockRepo) SetEmailVerified(ctx context.Context, id string, verified bool) error {
+	if u, ok := f.users[id]; ok {
+		u.EmailVerified = verified
+		stampUpdated(ctx, time.Now(), &u.UpdatedBy, &u.UpdatedAt)
+	}
+	return nil
+}
+
+func (f *MockRepo) ListAllUsers(ctx context.Context) ([]*User, error) {
+	var out []*User
+	for _, u := range f.users {
+		out = append(out, u)
+	}
+	return out, nil
+}
+
 // RolePermissionRepo implementation
 func (f *MockRepo) AddRP(ctx context.Context, roleID, permID string) error {
+	recordSource(ctx, f.sources, edgeKey{KindRolePermission, roleID, permID}, hasEdge(f.rolePerms, roleID, permID))
 	if f.rolePerms[roleID] == nil {
 		f.rolePerms[roleID] = make(map[string]struct{})
 	}
@@ -133,6 +291,7 @@ func (f *MockRepo) Remove(ctx context.Context, roleID, permID string) error {
 	if m, ok := f.rolePerms[roleID]; ok {
 		delete(m, permID)
 	}
+	delete(f.sources, edgeKey{KindRolePermission, roleID, permID})
 	return nil
 }
 func (f *MockRepo) ListPermissions(ctx context.Context, roleID string) ([]string, error) {
@@ -147,30 +306,121 @@ func (f *MockRepo) ListPermissions(ctx context.Context, roleID string) ([]string
 
 // UserRoleRepo implementation
 func (f *MockRepo) AddUR(ctx context.Context, userID, roleID string) error {
+	recordSource(ctx, f.sources, edgeKey{KindUserRole, userID, roleID}, hasEdge(f.userRoles, userID, roleID))
 	if f.userRoles[userID] == nil {
 		f.userRoles[userID] = make(map[string]struct{})
 	}
 	f.userRoles[userID][roleID] = struct{}{}
+	delete(f.urWindows[userID], roleID)
 	return nil
 }
 func (f *MockRepo) RemoveUR(ctx context.Context, userID, roleID string) error {
 	if m, ok := f.userRoles[userID]; ok {
 		delete(m, roleID)
 	}
+	delete(f.urWindows[userID], roleID)
+	delete(f.sources, edgeKey{KindUserRole, userID, roleID})
 	return nil
 }
 func (f *MockRepo) ListRoles(ctx context.Context, userID string) ([]string, error) {
 	var out []string
+	now := time.Now()
 	if m, ok := f.userRoles[userID]; ok {
 		for rid := range m {
+			if w, ok := f.urWindows[userID][rid]; ok && !w.ActiveAt(now) {
+				continue
+			}
 			out = append(out, rid)
 		}
 	}
 	return out, nil
 }
+func (f *MockRepo) ListUsersForRole(ctx context.Context, roleID string) ([]string, error) {
+	return edgeSources(f.userRoles, roleID), nil
+}
+
+// ScheduledUserRoleRepo implementation
+func (f *MockRepo) AddScheduledUR(ctx context.Context, a *RoleAssignment) error {
+	_ = f.AddUR(ctx, a.UserID, a.RoleID)
+	if f.urWindows[a.UserID] == nil {
+		f.urWindows[a.UserID] = make(map[string]*RoleAssignment)
+	}
+	cp := *a
+	f.urWindows[a.UserID][a.RoleID] = &cp
+	return nil
+}
+func (f *MockRepo) ListRoleAssignments(ctx context.Context, userID string) ([]*RoleAssignment, error) {
+	var out []*RoleAssignment
+	for rid := range f.userRoles[userID] {
+		a := &RoleAssignment{UserID: userID, RoleID: rid}
+		if w, ok := f.urWindows[userID][rid]; ok {
+			*a = *w
+		}
+		out = append(out, a)
+	}
+	return out, nil
+}
+
+func (f *MockRepo) ListExpiringRoleAssignments(ctx context.Context, after, before int64) ([]*RoleAssignment, error) {
+	var out []*RoleAssignment
+	for _, windows := range f.urWindows {
+		for _, w := range windows {
+			if w.ExpiresAt > after && w.ExpiresAt <= before {
+				cp := *w
+				out = append(out, &cp)
+			}
+		}
+	}
+	return out, nil
+}
+func (f *MockRepo) ListExpiringMemberships(ctx context.Context, after, before int64) ([]*UserGroup, error) {
+	var out []*UserGroup
+	for _, groups := range f.userGroups {
+		for _, ug := range groups {
+			if ug.ExpiresAt > after && ug.ExpiresAt <= before {
+				out = append(out, ug)
+			}
+		}
+	}
+	return out, nil
+}
+
+// ScopedUserRoleRepo implementation
+func (f *MockRepo) AddScopedUR(ctx context.Context, userID, roleID, scope string) error {
+	addScoped(f.urScoped, userID, ScopedRole{RoleID: roleID, Scope: scope})
+	return nil
+}
+func (f *MockRepo) RemoveScopedUR(ctx context.Context, userID, roleID, scope string) error {
+	removeScoped(f.urScoped, userID, ScopedRole{RoleID: roleID, Scope: scope})
+	return nil
+}
+func (f *MockRepo) ListScopedRoles(ctx context.Context, userID string) ([]ScopedRole, error) {
+	return scopedList(f.urScoped, userID), nil
+}
+
+// ConstrainedUserRoleRepo implementation
+func (f *MockRepo) AddConstrainedUR(ctx context.Context, userID, roleID string, c RoleConstraints) error {
+	if f.urLimits[userID] == nil {
+		f.urLimits[userID] = make(map[string]RoleConstraints)
+	}
+	f.urLimits[userID][roleID] = c
+	return nil
+}
+func (f *MockRepo) RemoveConstrainedUR(ctx context.Context, userID, roleID string) error {
+	delete(f.urLimits[userID], roleID)
+	return nil
+}
+func (f *MockRepo) ListConstrainedRoles(ctx context.Context, userID string) ([]ConstrainedRole, error) {
+	return constrainedList(f.urLimits[userID]), nil
+}
 
 // UserGroupRepo implementation
 func (f *MockRepo) AddUserToGroup(ctx context.Context, ug *UserGroup) error {
+	if ug.ID == "" {
+		ug.ID = generateID(f.ids, KindUserGroup)
+	}
+	_, existed := f.userGroups[ug.UserID][ug.GroupName]
+	recordSource(ctx, f.sources, edgeKey{KindUserGroup, ug.UserID, ug.GroupName}, existed)
 	// by user
 	if f.userGroups[ug.UserID] == nil {
 		f.userGroups[ug.UserID] = make(map[string]*UserGroup)
@@ -190,6 +440,7 @@ func (f *MockRepo) RemoveUserFromGroup(ctx context.Context, groupID string, ug *
 	if m, ok := f.groupUsers[groupID]; ok {
 		delete(m, ug.UserID)
 	}
+	delete(f.sources, edgeKey{KindUserGroup, ug.UserID, groupID})
 	return nil
 }
 func (f *MockRepo) GetUsersByGroupID(ctx context.Context, groupID string) ([]*UserGroup, error) {
@@ -211,8 +462,39 @@ func (f *MockRepo) GetGroupsByUserID(ctx context.Context, userID string) ([]*Use
 	return out, nil
 }
 
+// GroupBanRepo implementation
+func (f *MockRepo) AddGroupBan(ctx context.Context, b *GroupBan) error {
+	f.RemoveGroupBan(ctx, b.GroupName, b.UserID)
+	cp := *b
+	f.bans = append(f.bans, &cp)
+	return nil
+}
+func (f *MockRepo) RemoveGroupBan(ctx context.Context, groupName, userID string) error {
+	f.bans = slices.DeleteFunc(f.bans, func(b *GroupBan) bool { return b.GroupName == groupName && b.UserID == userID })
+	return nil
+}
+func (f *MockRepo) ListGroupBans(ctx context.Context, groupName string) ([]*GroupBan, error) {
+	var out []*GroupBan
+	for _, b := range f.bans {
+		if b.GroupName == groupName {
+			out = append(out, b)
+		}
+	}
+	return out, nil
+}
+func (f *MockRepo) ListUserBans(ctx context.Context, userID string) ([]*GroupBan, error) {
+	var out []*GroupBan
+	for _, b := range f.bans {
+		if b.UserID == userID {
+			out = append(out, b)
+		}
+	}
+	return out, nil
+}
+
 // GroupRoleRepo implementation
 func (f *MockRepo) AddRoleToGroup(ctx context.Context, groupID, roleID string) error {
+	recordSource(ctx, f.sources, edgeKey{KindGroupRole, groupID, roleID}, hasEdge(f.groupRoles, groupID, roleID))
 	if f.groupRoles[groupID] == nil {
 		f.groupRoles[groupID] = make(map[string]struct{})
 	}
@@ -223,6 +505,7 @@ func (f *MockRepo) RemoveRoleFromGroup(ctx context.Context, groupID, roleID stri
 	if m, ok := f.groupRoles[groupID]; ok {
 		delete(m, roleID)
 	}
+	delete(f.sources, edgeKey{KindGroupRole, groupID, roleID})
 	return nil
 }
 func (f *MockRepo) ListRolesForGroup(ctx context.Context, groupID string) ([]string, error) {
@@ -234,3 +517,391 @@ func (f *MockRepo) ListRolesForGroup(ctx context.Context, groupID string) ([]str
 	}
 	return out, nil
 }
+func (f *MockRepo) ListGroupsForRole(ctx context.Context, roleID string) ([]string, error) {
+	return edgeSources(f.groupRoles, roleID), nil
+}
+
+// ScopedGroupRoleRepo implementation
+func (f *MockRepo) AddScopedRoleToGroup(ctx context.Context, groupID, roleID, scope string) error {
+	addScoped(f.grScoped, groupID, ScopedRole{RoleID: roleID, Scope: scope})
+	return nil
+}
+func (f *MockRepo) RemoveScopedRoleFromGroup(ctx context.Context, groupID, roleID, scope string) error {
+	removeScoped(f.grScoped, groupID, ScopedRole{RoleID: roleID, Scope: scope})
+	return nil
+}
+func (f *MockRepo) ListScopedRolesForGroup(ctx context.Context, groupID string) ([]ScopedRole, error) {
+	return scopedList(f.grScoped, groupID), nil
+}
+
+// EdgeSourceRepo implementation
+func (f *MockRepo) EdgeSource(ctx context.Context, kind, from, to string) (string, error) {
+	var exists bool
+	switch kind {
+	case KindUserRole:
+		exists = hasEdge(f.userRoles, from, to)
+	case KindRolePermission:
+		exists = hasEdge(f.rolePerms, from, to)
+	case KindGroupRole:
+		exists = hasEdge(f.groupRoles, from, to)
+	case KindUserGroup:
+		_, exists = f.userGroups[from][to]
+	}
+	return lookupSource(f.sources, edgeKey{kind, from, to}, exists), nil
+}
+
+// GroupRepo implementation
+func (f *MockRepo) CreateGroup(ctx context.Context, g *Group) error {
+	if g.ID == "" {
+		g.ID = generateID(f.ids, KindGroup)
+	}
+	f.groups[g.ID] = g
+	return nil
+}
+func (f *MockRepo) UpdateGroup(ctx context.Context, g *Group) error {
+	f.groups[g.ID] = g
+	return nil
+}
+func (f *MockRepo) DeleteGroup(ctx context.Context, id string) error {
+	delete(f.groups, id)
+	return nil
+}
+func (f *MockRepo) GetGroupByID(ctx context.Context, id string) (*Group, error) {
+	if g, ok := f.groups[id]; ok {
+		return g, nil
+	}
+	return nil, nil
+}
+func (f *MockRepo) GetGroupByName(ctx context.Context, name string) (*Group, error) {
+	for _, g := range f.groups {
+		if g.Name == name {
+			return g, nil
+		}
+	}
+	return nil, nil
+}
+func (f *MockRepo) ListGroups(ctx context.Context) ([]*Group, error) {
+	var out []*Group
+	for _, g := range f.groups {
+		out = append(out, g)
+	}
+	return out, nil
+}
+
+// AttestationRepo implementation
+func (f *MockRepo) AddAttestation(ctx context.Context, a *Attestation) error {
+	if a.ID == "" {
+		a.ID = generateID(f.ids, KindAttestation)
+	}
+	f.attests[a.UserID] = append(f.attests[a.UserID], a)
+	return nil
+}
+func (f *MockRepo) LatestAttestation(ctx context.Context, userID, roleID string) (*Attestation, error) {
+	list := f.attests[userID]
+	for i := len(list) - 1; i >= 0; i-- {
+		if list[i].RoleID == roleID {
+			return list[i], nil
+		}
+	}
+	return nil, nil
+}
+func (f *MockRepo) ListAttestations(ctx context.Context, userID string) ([]*Attestation, error) {
+	return f.attests[userID], nil
+}
+
+// ArchiveRepo implementation
+func (f *MockRepo) SaveArchive(ctx context.Context, a *Archive) error {
+	if a.ID == "" {
+		a.ID = generateID(f.ids, KindArchive)
+	}
+	f.archives[a.ID] = a
+	return nil
+}
+func (f *MockRepo) GetArchive(ctx context.Context, id string) (*Archive, error) {
+	if a, ok := f.archives[id]; ok {
+		return a, nil
+	}
+	return nil, nil
+}
+func (f *MockRepo) ListArchives(ctx context.Context) ([]*Archive, error) {
+	var out []*Archive
+	for _, a := range f.archives {
+		out = append(out, a)
+	}
+	sortArchives(out)
+	return out, nil
+}
+
+// PolicyVersionRepo implementation
+func (f *MockRepo) SavePolicySnapshot(ctx context.Context, s *PolicySnapshot) error {
+	if s.ID == "" {
+		s.ID = generateID(f.ids, KindPolicySnapshot)
+	}
+	f.snapshots[s.ID] = s
+	return nil
+}
+func (f *MockRepo) GetPolicySnapshot(ctx context.Context, id string) (*PolicySnapshot, error) {
+	if s, ok := f.snapshots[id]; ok {
+		return s, nil
+	}
+	return nil, nil
+}
+func (f *MockRepo) ListPolicySnapshots(ctx context.Context) ([]*PolicySnapshot, error) {
+	var out []*PolicySnapshot
+	for _, s := range f.snapshots {
+		out = append(out, s)
+	}
+	sortPolicySnapshots(out)
+	return out, nil
+}
+
+// APIKeyRepo implementation
+func (f *MockRepo) SaveAPIKey(ctx context.Context, k *APIKey) error {
+	if k.ID == "" {
+		k.ID = generateID(f.ids, KindAPIKey)
+	}
+	f.apiKeys[k.ID] = k
+	return nil
+}
+func (f *MockRepo) GetAPIKey(ctx context.Context, id string) (*APIKey, error) {
+	if k, ok := f.apiKeys[id]; ok {
+		return k, nil
+	}
+	return nil, nil
+}
+func (f *MockRepo) ListAPIKeys(ctx context.Context, principalID string) ([]*APIKey, error) {
+	var out []*APIKey
+	for _, k := range f.apiKeys {
+		if k.PrincipalID == principalID {
+			out = append(out, k)
+		}
+	}
+	sortAPIKeys(out)
+	return out, nil
+}
+
+// SessionRepo implementation
+func (f *MockRepo) SaveSession(ctx context.Context, s *Session) error {
+	if s.ID == "" {
+		s.ID = generateID(f.ids, KindSession)
+	}
+	f.sessions[s.ID] = s
+	return nil
+}
+func (f *MockRepo) GetSession(ctx context.Context, id string) (*Session, error) {
+	if s, ok := f.sessions[id]; ok {
+		return s, nil
+	}
+	return nil, nil
+}
+func (f *MockRepo) DeleteSession(ctx context.Context, id string) error {
+	delete(f.sessions, id)
+	return nil
+}
+
+// SoDRepo implementation
+func (f *MockRepo) CreateSoDConstraint(ctx context.Context, c *SoDConstraint) error {
+	if c.ID == "" {
+		c.ID = generateID(f.ids, KindSoDConstraint)
+	}
+	f.sods[c.ID] = c
+	return nil
+}
+func (f *MockRepo) GetSoDConstraint(ctx context.Context, id string) (*SoDConstraint, error) {
+	return f.sods[id], nil
+}
+func (f *MockRepo) ListSoDConstraints(ctx context.Context) ([]*SoDConstraint, error) {
+	out := make([]*SoDConstraint, 0, len(f.sods))
+	for _, c := range f.sods {
+		out = append(out, c)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out, nil
+}
+func (f *MockRepo) DeleteSoDConstraint(ctx context.Context, id string) error {
+	delete(f.sods, id)
+	return nil
+}
+
+// ApprovalRepo implementation
+func (f *MockRepo) SaveAssignmentRequest(ctx context.Context, r *AssignmentRequest) error {
+	if r.ID == "" {
+		r.ID = generateID(f.ids, KindAssignmentRequest)
+	}
+	cp := *r
+	f.requests[r.ID] = &cp
+	return nil
+}
+func (f *MockRepo) GetAssignmentRequest(ctx context.Context, id string) (*AssignmentRequest, error) {
+	if r, ok := f.requests[id]; ok {
+		cp := *r
+		return &cp, nil
+	}
+	return nil, nil
+}
+func (f *MockRepo) ListAssignmentRequests(ctx context.Context, status AssignmentStatus) ([]*AssignmentRequest, error) {
+	var out []*AssignmentRequest
+	for _, r := range f.requests {
+		if status == "" || r.Status == status {
+			cp := *r
+			out = append(out, &cp)
+		}
+	}
+	sortAssignmentRequests(out)
+	return out, nil
+}
+
+// DelegationRepo implementation
+func (f *MockRepo) SaveDelegation(ctx context.Context, d *Delegation) error {
+	if d.ID == "" {
+		d.ID = generateID(f.ids, KindDelegation)
+	}
+	cp := *d
+	f.delegs[d.ID] = &cp
+	return nil
+}
+func (f *MockRepo) GetDelegation(ctx context.Context, id string) (*Delegation, error) {
+	if d, ok := f.delegs[id]; ok {
+		cp := *d
+		return &cp, nil
+	}
+	return nil, nil
+}
+func (f *MockRepo) DeleteDelegation(ctx context.Context, id string) error {
+	delete(f.delegs, id)
+	return nil
+}
+func (f *MockRepo) ListDelegations(ctx context.Context, userID string) ([]*Delegation, error) {
+	var out []*Delegation
+	for _, d := range f.delegs {
+		if d.FromUserID == userID || d.ToUserID == userID {
+			cp := *d
+			out = append(out, &cp)
+		}
+	}
+	sortDelegations(out)
+	return out, nil
+}
+
+// AuditRepo implementation
+func (f *MockRepo) AppendAudit(ctx context.Context, e *AuditEntry) error {
+	if e.ID == "" {
+		e.ID = generateID(f.ids, KindAuditEntry)
+	}
+	cp := *e
+	f.audit = append(f.audit, &cp)
+	return nil
+}
+func (f *MockRepo) ListAudit(ctx context.Context, q AuditQuery) ([]*AuditEntry, error) {
+	var out []*AuditEntry
+	for _, e := range f.audit {
+		if q.Limit > 0 && len(out) == q.Limit {
+			break
+		}
+		if matchesAudit(e, q) {
+			cp := *e
+			out = append(out, &cp)
+		}
+	}
+	return out, nil
+}
+
+// PermissionSetRepo implementation
+func (f *MockRepo) SavePermissionSet(ctx context.Context, s *PermissionSet) error {
+	if s.ID == "" {
+		s.ID = generateID(f.ids, KindPermissionSet)
+	}
+	cp := *s
+	cp.Permissions = slices.Clone(s.Permissions)
+	f.permSets[s.ID] = &cp
+	return nil
+}
+func (f *MockRepo) GetPermissionSet(ctx context.Context, id string) (*PermissionSet, error) {
+	if s, ok := f.permSets[id]; ok {
+		cp := *s
+		cp.Permissions = slices.Clone(s.Permissions)
+		return &cp, nil
+	}
+	return nil, nil
+}
+func (f *MockRepo) ListPermissionSets(ctx context.Context) ([]*PermissionSet, error) {
+	out := make([]*PermissionSet, 0, len(f.permSets))
+	for _, s := range f.permSets {
+		out = append(out, s)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out, nil
+}
+func (f *MockRepo) DeletePermissionSet(ctx context.Context, id string) error {
+	delete(f.permSets, id)
+	for roleID := range f.roleSets {
+		f.RemoveSetFromRole(ctx, roleID, id)
+	}
+	return nil
+}
+func (f *MockRepo) AddSetToRole(ctx context.Context, roleID, setID string) error {
+	if !slices.Contains(f.roleSets[roleID], setID) {
+		f.roleSets[roleID] = append(f.roleSets[roleID], setID)
+	}
+	return nil
+}
+func (f *MockRepo) RemoveSetFromRole(ctx context.Context, roleID, setID string) error {
+	f.roleSets[roleID] = slices.DeleteFunc(f.roleSets[roleID], func(id string) bool { return id == setID })
+	return nil
+}
+func (f *MockRepo) ListSetsForRole(ctx context.Context, roleID string) ([]string, error) {
+	return slices.Clone(f.roleSets[roleID]), nil
+}
+
+// TenantRepo implementation
+func (f *MockRepo) CreateTenant(ctx context.Context, t *Tenant) error {
+	if t.ID == "" {
+		t.ID = generateID(f.ids, KindTenant)
+	}
+	f.tenants[t.ID] = t
+	return nil
+}
+func (f *MockRepo) DeleteTenant(ctx context.Context, id string) error {
+	delete(f.tenants, id)
+	return nil
+}
+func (f *MockRepo) GetTenantByID(ctx context.Context, id string) (*Tenant, error) {
+	if t, ok := f.tenants[id]; ok {
+		return t, nil
+	}
+	return nil, nil
+}
+func (f *MockRepo) ListTenants(ctx context.Context) ([]*Tenant, error) {
+	var out []*Tenant
+	for _, t := range f.tenants {
+		out = append(out, t)
+	}
+	return out, nil
+}
+func (f *MockRepo) ListPermissionsByTenant(ctx context.Context, tenantID string) ([]*Permission, error) {
+	var out []*Permission
+	for _, p := range f.perms {
+		if p.TenantID == tenantID {
+			out = append(out, p)
+		}
+	}
+	return out, nil
+}
+func (f *MockRepo) ListRolesByTenant(ctx context.Context, tenantID string) ([]*Role, error) {
+	var out []*Role
+	for _, r := range f.roles {
+		if r.TenantID == tenantID {
+			out = append(out, r)
+		}
+	}
+	return out, nil
+}
+func (f *MockRepo) ListUsersByTenant(ctx context.Context, tenantID string) ([]*User, error) {
+	var out []*User
+	for _, u := range f.users {
+		if u.TenantID == tenantID {
+			out = append(out, u)
+		}
+	}
+	return out, nil
+}
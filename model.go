@@ -32,33 +32,122 @@ func HTTPMethodToAction(method string) Action {
 	}
 }
 
+// Effect says whether a matching permission grants or forbids access.
+type Effect string
+
+const (
+	EffectAllow Effect = "allow"
+	// EffectDeny wins over every allow that matches the same request.
+	EffectDeny Effect = "deny"
+)
+
 type Permission struct {
-	ID        string `bson:"id" json:"id,omitempty"`
-	Resource  string `bson:"resource" json:"resource,omitempty"`
-	Action    Action `bson:"action" json:"action,omitempty"`
-	CreatedAt int64  `bson:"created_at" json:"created_at,omitempty"`
+	ID string `bson:"id" json:"id,omitempty" yaml:"id,omitempty"`
+	// Name is an optional human-readable identifier, e.g. "invoices.approve",
+	// unique among named permissions; see Manager.GetPermissionByName.
+	Name        string `bson:"name,omitempty" json:"name,omitempty" yaml:"name,omitempty"`
+	Description string `bson:"description,omitempty" json:"description,omitempty" yaml:"description,omitempty"`
+	// Labels are free-form tags for audit reviews, e.g. {"owner": "billing",
+	// "risk": "high"}.
+	Labels   map[string]string `bson:"labels,omitempty" json:"labels,omitempty" yaml:"labels,omitempty"`
+	Resource string            `bson:"resource" json:"resource,omitempty" yaml:"resource,omitempty"`
+	Action   Action            `bson:"action" json:"action,omitempty" yaml:"action,omitempty"`
+	// Effect is EffectAllow when empty.
+	Effect Effect `bson:"effect,omitempty" json:"effect,omitempty" yaml:"effect,omitempty"`
+	// Condition, when set, limits the permission to requests where the
+	// expression holds, e.g. `attrs.region == user.meta.region`; see
+	// rbaceval.Condition for the syntax and Manager.CanWithAttributes.
+	Condition string `bson:"condition,omitempty" json:"condition,omitempty" yaml:"condition,omitempty"`
+	TenantID  string `bson:"tenant_id,omitempty" json:"tenant_id,omitempty" yaml:"tenant_id,omitempty"`
+	CreatedAt int64  `bson:"created_at" json:"created_at,omitempty" yaml:"created_at,omitempty"`
+	// UpdatedAt, CreatedBy and UpdatedBy are filled by the Manager from the
+	// context's actor; see WithActor.
+	UpdatedAt int64  `bson:"updated_at,omitempty" json:"updated_at,omitempty" yaml:"updated_at,omitempty"`
+	CreatedBy string `bson:"created_by,omitempty" json:"created_by,omitempty" yaml:"created_by,omitempty"`
+	UpdatedBy string `bson:"updated_by,omitempty" json:"updated_by,omitempty" yaml:"updated_by,omitempty"`
+	// DeletedAt is the unix time the permission was soft-deleted; Can
+	// ignores it until Manager.RestorePermission.
+	DeletedAt int64 `bson:"deleted_at,omitempty" json:"deleted_at,omitempty" yaml:"deleted_at,omitempty"`
 }
 
 type Role struct {
-	ID          string `bson:"id" json:"id,omitempty"`
-	Name        string `bson:"name" json:"name,omitempty"`
-	Description string `bson:"description" json:"description,omitempty"`
-	CreatedAt   int64  `bson:"created_at" json:"created_at,omitempty"`
+	ID          string `bson:"id" json:"id,omitempty" yaml:"id,omitempty"`
+	Name        string `bson:"name" json:"name,omitempty" yaml:"name,omitempty"`
+	Description string `bson:"description" json:"description,omitempty" yaml:"description,omitempty"`
+	// Meta is free-form data about the role, e.g. the owning team or its
+	// review cadence. Can does not look at it.
+	Meta map[string]interface{} `bson:"meta,omitempty" json:"meta,omitempty" yaml:"meta,omitempty"`
+	// Priority settles conflicts between roles: when rules of several roles
+	// match a request, the highest-priority role's rule decides. Roles
+	// default to 0, where a deny wins as usual; see Manager.Decide.
+	Priority int `bson:"priority,omitempty" json:"priority,omitempty" yaml:"priority,omitempty"`
+	// Template marks a blueprint role: it cannot be assigned to users or
+	// groups, only copied with Manager.CloneRole.
+	Template bool `bson:"template,omitempty" json:"template,omitempty" yaml:"template,omitempty"`
+	// Generators are permissions computed per user at check time: each
+	// Resource is a template such as "teams/{team}/**", filled in from the
+	// user's meta (or any {user.*} / {attrs.*} path), so one role can serve
+	// every team. See rbaceval.ExpandTemplate.
+	Generators []Permission `bson:"generators,omitempty" json:"generators,omitempty" yaml:"generators,omitempty"`
+	TenantID   string       `bson:"tenant_id,omitempty" json:"tenant_id,omitempty" yaml:"tenant_id,omitempty"`
+	CreatedAt  int64        `bson:"created_at" json:"created_at,omitempty" yaml:"created_at,omitempty"`
+	UpdatedAt  int64        `bson:"updated_at,omitempty" json:"updated_at,omitempty" yaml:"updated_at,omitempty"`
+	CreatedBy  string       `bson:"created_by,omitempty" json:"created_by,omitempty" yaml:"created_by,omitempty"`
+	UpdatedBy  string       `bson:"updated_by,omitempty" json:"updated_by,omitempty" yaml:"updated_by,omitempty"`
+	// DeletedAt is the unix time the role was soft-deleted; Can ignores it,
+	// and what it grants, until Manager.RestoreRole.
+	DeletedAt int64 `bson:"deleted_at,omitempty" json:"deleted_at,omitempty" yaml:"deleted_at,omitempty"`
 }
 
 type User struct {
-	ID        string                 `bson:"id" json:"id,omitempty"`
-	Username  string                 `bson:"username" json:"username,omitempty"`
-	Email     string                 `bson:"email" json:"email,omitempty"`
-	Meta      map[string]interface{} `bson:"meta" json:"meta,omitempty"`
-	CreatedAt int64                  `bson:"created_at" json:"created_at,omitempty"`
+	ID       string `bson:"id" json:"id,omitempty" yaml:"id,omitempty"`
+	Username string `bson:"username" json:"username,omitempty" yaml:"username,omitempty"`
+	Email    string `bson:"email" json:"email,omitempty" yaml:"email,omitempty"`
+	// EmailVerified is set once the user proved they own Email; see
+	// Manager.RequireVerifiedEmail.
+	EmailVerified bool                   `bson:"email_verified,omitempty" json:"email_verified,omitempty" yaml:"email_verified,omitempty"`
+	Meta          map[string]interface{} `bson:"meta" json:"meta,omitempty" yaml:"meta,omitempty"`
+	TenantID      string                 `bson:"tenant_id,omitempty" json:"tenant_id,omitempty" yaml:"tenant_id,omitempty"`
+	CreatedAt     int64                  `bson:"created_at" json:"created_at,omitempty" yaml:"created_at,omitempty"`
+	UpdatedAt     int64                  `bson:"updated_at,omitempty" json:"updated_at,omitempty" yaml:"updated_at,omitempty"`
+	CreatedBy     string                 `bson:"created_by,omitempty" json:"created_by,omitempty" yaml:"created_by,omitempty"`
+	UpdatedBy     string                 `bson:"updated_by,omitempty" json:"updated_by,omitempty" yaml:"updated_by,omitempty"`
+	// Status is empty or UserActive unless the user was suspended or
+	// locked; see Manager.SuspendUser.
+	Status UserStatus `bson:"status,omitempty" json:"status,omitempty" yaml:"status,omitempty"`
 }
 
 type UserGroup struct {
-	ID        string `bson:"id" json:"id,omitempty"`
-	GroupName string `bson:"group_name" json:"group_name,omitempty"`
-	UserID    string `bson:"user_id" json:"user_id,omitempty"`
-	CreatedAt int64  `bson:"created_at" json:"created_at,omitempty"`
+	ID        string `bson:"id" json:"id,omitempty" yaml:"id,omitempty"`
+	GroupName string `bson:"group_name" json:"group_name,omitempty" yaml:"group_name,omitempty"`
+	UserID    string `bson:"user_id" json:"user_id,omitempty" yaml:"user_id,omitempty"`
+	TenantID  string `bson:"tenant_id,omitempty" json:"tenant_id,omitempty" yaml:"tenant_id,omitempty"`
+	CreatedAt int64  `bson:"created_at" json:"created_at,omitempty" yaml:"created_at,omitempty"`
+	UpdatedAt int64  `bson:"updated_at,omitempty" json:"updated_at,omitempty" yaml:"updated_at,omitempty"`
+	CreatedBy string `bson:"created_by,omitempty" json:"created_by,omitempty" yaml:"created_by,omitempty"`
+	UpdatedBy string `bson:"updated_by,omitempty" json:"updated_by,omitempty" yaml:"updated_by,omitempty"`
+	// ExpiresAt, when set, is the unix time the membership lapses; Can
+	// ignores it from then on.
+	ExpiresAt int64 `bson:"expires_at,omitempty" json:"expires_at,omitempty" yaml:"expires_at,omitempty"`
+	// Level is the member's standing in the group, MembershipMember when
+	// empty; see Manager.IsGroupAdmin.
+	Level MembershipLevel `bson:"level,omitempty" json:"level,omitempty" yaml:"level,omitempty"`
+}
+
+// Group is a named set of users that roles can be assigned to. Memberships
+// (UserGroup.GroupName) and group role bindings refer to a group by Name.
+type Group struct {
+	ID          string                 `bson:"id" json:"id,omitempty" yaml:"id,omitempty"`
+	Name        string                 `bson:"name" json:"name,omitempty" yaml:"name,omitempty"`
+	Description string                 `bson:"description" json:"description,omitempty" yaml:"description,omitempty"`
+	Meta        map[string]interface{} `bson:"meta" json:"meta,omitempty" yaml:"meta,omitempty"`
+	// Owner is the ID of the user responsible for the group.
+	Owner string `bson:"owner" json:"owner,omitempty" yaml:"owner,omitempty"`
+	// DefaultRoles are role IDs granted directly to each member while they
+	// belong to the group; see Manager.AddUserToGroup.
+	DefaultRoles []string `bson:"default_roles,omitempty" json:"default_roles,omitempty" yaml:"default_roles,omitempty"`
+	TenantID     string   `bson:"tenant_id,omitempty" json:"tenant_id,omitempty" yaml:"tenant_id,omitempty"`
+	CreatedAt    int64    `bson:"created_at" json:"created_at,omitempty" yaml:"created_at,omitempty"`
 }
 
 // Repository interfaces, storage-agnostic
@@ -67,6 +156,7 @@ type PermissionRepo interface {
 	DeletePermission(ctx context.Context, id string) error
 	GetPermissionByID(ctx context.Context, id string) (*Permission, error)
 	GetPermissionByResource(ctx context.Context, resource string, action Action) (*Permission, error)
+	ListAllPermissions(ctx context.Context) ([]*Permission, error)
 }
 
 type RoleRepo interface {
@@ -82,6 +172,7 @@ type UserRepo interface {
 	DeleteUser(ctx context.Context, id string) error
 	GetUserByID(ctx context.Context, id string) (*User, error)
 	GetUserByMeta(ctx context.Context, meta map[string]interface{}) (*User, error)
+	ListAllUsers(ctx context.Context) ([]*User, error)
 }
 
 type UserGroupRepo interface {
@@ -98,14 +189,39 @@ type RolePermissionRepo interface {
 	ListPermissions(ctx context.Context, roleID string) ([]string, error)
 }
 
+// RolePermissionDetailer is optionally implemented by a RolePermissionRepo
+// that can return a role's permissions in one read, so Can does not need a
+// GetPermissionByID lookup per binding.
+type RolePermissionDetailer interface {
+	ListPermissionDetails(ctx context.Context, roleID string) ([]*Permission, error)
+}
+
 type UserRoleRepo interface {
 	AddUR(ctx context.Context, userID, roleID string) error
 	RemoveUR(ctx context.Context, userID, roleID string) error
 	ListRoles(ctx context.Context, userID string) ([]string, error)
+	// ListUsersForRole returns the users assigned roleID directly, including
+	// scheduled assignments not in effect now.
+	ListUsersForRole(ctx context.Context, roleID string) ([]string, error)
 }
 
 type GroupRoleRepo interface {
 	AddRoleToGroup(ctx context.Context, groupID, roleID string) error
 	RemoveRoleFromGroup(ctx context.Context, groupID, roleID string) error
 	ListRolesForGroup(ctx context.Context, groupID string) ([]string, error)
+	// ListGroupsForRole returns the groups roleID is assigned to.
+	ListGroupsForRole(ctx context.Context, roleID string) ([]string, error)
+}
+
+// Store is implemented by backends that provide every repository, such as
+// MongoStore or PostgresStore. Wrappers that sit in front of a backend take
+// and return a Store.
+type Store interface {
+	PermissionRepo
+	RoleRepo
+	UserRepo
+	UserGroupRepo
+	RolePermissionRepo
+	UserRoleRepo
+	GroupRoleRepo
 }
@@ -5,12 +5,15 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"regexp"
+	"slices"
 	"time"
 
-	"github.com/google/uuid"
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
 )
 
 //
@@ -24,10 +27,17 @@ import (
 // Permissions
 
 // Role → Permission mapping
+type mongoRolePermissionSet struct {
+	RoleID    string `bson:"role_id"`
+	SetID     string `bson:"set_id"`
+	CreatedAt int64  `bson:"created_at"`
+}
+
 type mongoRolePermission struct {
 	RoleID       string `bson:"role_id"`
 	PermissionID string `bson:"permission_id"`
 	CreatedAt    int64  `bson:"created_at"`
+	ManagedBy    string `bson:"managed_by,omitempty"`
 }
 
 // User → Role mapping
@@ -35,6 +45,9 @@ type mongoUserRole struct {
 	UserID     string `bson:"user_id"`
 	RoleID     string `bson:"role_id"`
 	AssignedAt int64  `bson:"assigned_at"`
+	NotBefore  int64  `bson:"not_before,omitempty"`
+	ExpiresAt  int64  `bson:"expires_at,omitempty"`
+	ManagedBy  string `bson:"managed_by,omitempty"`
 }
 
 // Group → Role mapping
@@ -42,17 +55,85 @@ type mongoGroupRole struct {
 	GroupName string `bson:"group_name"`
 	RoleID    string `bson:"role_id"`
 	CreatedAt int64  `bson:"created_at"` // Added for consistency, though not strictly required
+	ManagedBy string `bson:"managed_by,omitempty"`
+}
+
+// User or group → role, limited to resources matching Scope. Subject is a
+// user ID in scoped_user_roles and a group name in scoped_group_roles.
+type mongoScopedRole struct {
+	Subject   string `bson:"subject"`
+	RoleID    string `bson:"role_id"`
+	Scope     string `bson:"scope"`
+	CreatedAt int64  `bson:"created_at"`
+}
+
+// User → role, applying to requests that meet Constraints.
+type mongoConstrainedRole struct {
+	Subject     string          `bson:"subject"`
+	RoleID      string          `bson:"role_id"`
+	Constraints RoleConstraints `bson:"constraints"`
+	CreatedAt   int64           `bson:"created_at"`
+}
+
+// User → Group membership
+type mongoUserGroup struct {
+	UserGroup `bson:",inline"`
+	ManagedBy string `bson:"managed_by,omitempty"`
+}
+
+// Role → parent role mapping
+type mongoRoleParent struct {
+	RoleID    string `bson:"role_id"`
+	ParentID  string `bson:"parent_id"`
+	CreatedAt int64  `bson:"created_at"`
 }
 
 // Ensure MongoStore implements all interfaces:
 var (
-	_ PermissionRepo     = (*MongoStore)(nil)
-	_ RoleRepo           = (*MongoStore)(nil)
-	_ UserRepo           = (*MongoStore)(nil)
-	_ RolePermissionRepo = (*MongoStore)(nil)
-	_ UserRoleRepo       = (*MongoStore)(nil)
-	_ UserGroupRepo      = (*MongoStore)(nil)
-	_ GroupRoleRepo      = (*MongoStore)(nil)
+	_ PermissionRepo        = (*MongoStore)(nil)
+	_ RoleRepo              = (*MongoStore)(nil)
+	_ UserRepo              = (*MongoStore)(nil)
+	_ RolePermissionRepo    = (*MongoStore)(nil)
+	_ UserRoleRepo          = (*MongoStore)(nil)
+	_ UserGroupRepo         = (*MongoStore)(nil)
+	_ GroupRoleRepo         = (*MongoStore)(nil)
+	_ TenantRepo            = (*MongoStore)(nil)
+	_ GroupRepo             = (*MongoStore)(nil)
+	_ AttestationRepo       = (*MongoStore)(nil)
+	_ ArchiveRepo           = (*MongoStore)(nil)
+	_ PolicyVersionRepo     = (*MongoStore)(nil)
+	_ APIKeyRepo            = (*MongoStore)(nil)
+	_ SessionRepo           = (*MongoStore)(nil)
+	_ SoDRepo               = (*MongoStore)(nil)
+	_ ApprovalRepo          = (*MongoStore)(nil)
+	_ DelegationRepo        = (*MongoStore)(nil)
+	_ AuditRepo             = (*MongoStore)(nil)
+	_ PermissionSetRepo     = (*MongoStore)(nil)
+	_ GroupBanRepo          = (*MongoStore)(nil)
+	_ SoftDeleteRepo        = (*MongoStore)(nil)
+	_ UserStatusRepo        = (*MongoStore)(nil)
+	_ EmailVerificationRepo = (*MongoStore)(nil)
+	_ UserMetaIndexer       = (*MongoStore)(nil)
+
+	_ ScheduledUserRoleRepo    = (*MongoStore)(nil)
+	_ ExpiringRoleLister       = (*MongoStore)(nil)
+	_ ExpiringMembershipLister = (*MongoStore)(nil)
+	_ ScopedUserRoleRepo       = (*MongoStore)(nil)
+	_ ScopedGroupRoleRepo      = (*MongoStore)(nil)
+	_ ConstrainedUserRoleRepo  = (*MongoStore)(nil)
+	_ RoleHierarchyRepo        = (*MongoStore)(nil)
+	_ EdgeSourceRepo           = (*MongoStore)(nil)
+	_ BulkUserRoleRepo         = (*MongoStore)(nil)
+	_ BulkRolePermissionRepo   = (*MongoStore)(nil)
+	_ BulkUserGroupRepo        = (*MongoStore)(nil)
+	_ ExportPager              = (*MongoStore)(nil)
+	_ ListPager                = (*MongoStore)(nil)
+	_ Searcher                 = (*MongoStore)(nil)
+	_ PermissionNameGetter     = (*MongoStore)(nil)
+	_ UserLookup               = (*MongoStore)(nil)
+	_ Transactor               = (*MongoStore)(nil)
+	_ Watcher                  = (*MongoStore)(nil)
+	_ HealthChecker            = (*MongoStore)(nil)
 )
 
 //
@@ -67,6 +148,25 @@ type MongoStore struct {
 	userRoleCol  *mongo.Collection
 	userGroupCol *mongo.Collection
 	groupRoleCol *mongo.Collection // unused if Option 1 (groups purely name-based)
+	tenantsCol   *mongo.Collection
+	groupsCol    *mongo.Collection
+	attestCol    *mongo.Collection
+	archivesCol  *mongo.Collection
+	snapshotsCol *mongo.Collection
+	apiKeysCol   *mongo.Collection
+	sessionsCol  *mongo.Collection
+	sodCol       *mongo.Collection
+	requestsCol  *mongo.Collection
+	delegCol     *mongo.Collection
+	permSetCol   *mongo.Collection
+	roleSetCol   *mongo.Collection
+	bansCol      *mongo.Collection
+	parentsCol   *mongo.Collection
+	urScopedCol  *mongo.Collection
+	grScopedCol  *mongo.Collection
+	urLimitsCol  *mongo.Collection
+	auditCol     *mongo.Collection
+	ids          IDGenerator
 }
 
 func NewMongoStore(ctx context.Context, db *mongo.Database) (*MongoStore, error) {
@@ -78,6 +178,24 @@ func NewMongoStore(ctx context.Context, db *mongo.Database) (*MongoStore, error)
 		userRoleCol:  db.Collection("user_roles"),
 		userGroupCol: db.Collection("user_groups"),
 		groupRoleCol: db.Collection("group_roles"), // Initialize groupRoleCol
+		tenantsCol:   db.Collection("tenants"),
+		groupsCol:    db.Collection("groups"),
+		attestCol:    db.Collection("attestations"),
+		archivesCol:  db.Collection("archives"),
+		snapshotsCol: db.Collection("policy_snapshots"),
+		apiKeysCol:   db.Collection("api_keys"),
+		sessionsCol:  db.Collection("sessions"),
+		sodCol:       db.Collection("sod_constraints"),
+		requestsCol:  db.Collection("assignment_requests"),
+		delegCol:     db.Collection("delegations"),
+		permSetCol:   db.Collection("permission_sets"),
+		roleSetCol:   db.Collection("role_permission_sets"),
+		bansCol:      db.Collection("group_bans"),
+		parentsCol:   db.Collection("role_parents"),
+		urScopedCol:  db.Collection("scoped_user_roles"),
+		grScopedCol:  db.Collection("scoped_group_roles"),
+		urLimitsCol:  db.Collection("constrained_user_roles"),
+		auditCol:     db.Collection("audit_log"),
 	}
 
 	if err := m.EnsureIndexes(ctx); err != nil {
@@ -87,6 +205,60 @@ func NewMongoStore(ctx context.Context, db *mongo.Database) (*MongoStore, error)
 	return m, nil
 }
 
+// SetIDGenerator changes how the store generates IDs for new entities.
+func (m *MongoStore) SetIDGenerator(g IDGenerator) {
+	m.ids = g
+}
+
+// Health implements HealthChecker: it pings the primary and checks that the
+// unique indexes EnsureIndexes creates for permissions, roles, users and
+// their assignments exist, since without them concurrent writes can leave
+// duplicates behind.
+func (m *MongoStore) Health(ctx context.Context) error {
+	if err := m.rolesCol.Database().Client().Ping(ctx, readpref.Primary()); err != nil {
+		return fmt.Errorf("mongo_store: ping: %w", err)
+	}
+	for col, names := range map[*mongo.Collection][]string{
+		m.permsCol:     {"resource_1_action_1", "id_1"},
+		m.rolesCol:     {"name_1", "id_1"},
+		m.usersCol:     {"id_1"},
+		m.rolePermCol:  {"role_id_1_permission_id_1"},
+		m.userRoleCol:  {"user_id_1_role_id_1"},
+		m.groupRoleCol: {"group_name_1_role_id_1"},
+	} {
+		specs, err := col.Indexes().ListSpecifications(ctx)
+		if err != nil {
+			return fmt.Errorf("mongo_store: list indexes of %s: %w", col.Name(), err)
+		}
+		for _, name := range names {
+			if !slices.ContainsFunc(specs, func(s *mongo.IndexSpecification) bool { return s.Name == name }) {
+				return fmt.Errorf("mongo_store: index %s of %s is missing, run EnsureIndexes", name, col.Name())
+			}
+		}
+	}
+	return nil
+}
+
+// WithTransaction runs fn in a multi-document transaction. Store calls made
+// with the ctx fn receives are part of it, and the driver retries fn on
+// transient errors, so fn must be safe to run more than once. Calls nested in
+// an existing transaction join it. Transactions need a replica set or
+// sharded cluster.
+func (m *MongoStore) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if mongo.SessionFromContext(ctx) != nil {
+		return fn(ctx)
+	}
+	sess, err := m.rolesCol.Database().Client().StartSession()
+	if err != nil {
+		return err
+	}
+	defer sess.EndSession(ctx)
+	_, err = sess.WithTransaction(ctx, func(sc mongo.SessionContext) (interface{}, error) {
+		return nil, fn(sc)
+	})
+	return err
+}
+
 func NewMongoStoreManager(ctx context.Context, db *mongo.Database) (*Manager, error) {
 	m, err := NewMongoStore(ctx, db)
 	if err != nil {
@@ -110,15 +282,42 @@ func NewMongoStoreManager(ctx context.Context, db *mongo.Database) (*Manager, er
 		RP:              m,
 		UR:              m,
 		UG:              m,
+		GR:              m,
+		Tenants:         m,
+		Groups:          m,
+		Attestations:    m,
+		Archives:        m,
+		PolicyVersions:  m,
+		APIKeys:         m,
+		Sessions:        m,
+		SoD:             m,
+		Approvals:       m,
+		Delegations:     m,
+		PermSets:        m,
 		DefaultRoleName: "default",
 	}, nil
 }
 
 // --- UserRepo ---
 
+// GetUserByMeta matches the filter described by parseUserFilter; the key
+// of a term is its document path, so "meta." keys can use IndexUserMeta.
 func (m *MongoStore) GetUserByMeta(ctx context.Context, meta map[string]interface{}) (*User, error) {
+	terms, err := parseUserFilter(meta)
+	if err != nil {
+		return nil, err
+	}
+	filter := bson.M{}
+	for _, t := range terms {
+		if t.in {
+			filter[t.key] = bson.M{"$in": t.values}
+		} else {
+			filter[t.key] = t.values[0]
+		}
+	}
+
 	var doc User
-	err := m.usersCol.FindOne(ctx, meta).Decode(&doc)
+	err = m.usersCol.FindOne(ctx, filter).Decode(&doc)
 	if err == mongo.ErrNoDocuments {
 		return nil, nil
 	}
@@ -141,6 +340,19 @@ func (m *MongoStore) GetPermissionByResource(ctx context.Context, resource strin
 	return &doc, nil
 }
 
+// GetPermissionByName implements PermissionNameGetter.
+func (m *MongoStore) GetPermissionByName(ctx context.Context, name string) (*Permission, error) {
+	var doc Permission
+	err := m.permsCol.FindOne(ctx, bson.M{"name": name}).Decode(&doc)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &doc, nil
+}
+
 func (m *MongoStore) GetGroupsByUserID(ctx context.Context, userID string) ([]*UserGroup, error) {
 
 	filter := bson.M{"user_id": userID}
@@ -168,16 +380,25 @@ func (m *MongoStore) GetGroupsByUserID(ctx context.Context, userID string) ([]*U
 func (m *MongoStore) EnsureIndexes(ctx context.Context) error {
 	// Permissions: unique(resource, action)
 	_, err := m.permsCol.Indexes().CreateOne(ctx, mongo.IndexModel{
-		Keys:    bson.D{{"resource", 1}, {"action", 1}}, //nolint:govet
+		Keys:    bson.D{{Key: "resource", Value: 1}, {Key: "action", Value: 1}},
 		Options: options.Index().SetUnique(true),
 	})
 	if err != nil {
 		return err
 	}
 
+	// Permissions: unique(name) among named permissions
+	_, err = m.permsCol.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "name", Value: 1}},
+		Options: options.Index().SetUnique(true).SetSparse(true),
+	})
+	if err != nil {
+		return err
+	}
+
 	// Roles: unique(name)
 	_, err = m.rolesCol.Indexes().CreateOne(ctx, mongo.IndexModel{
-		Keys:    bson.D{{"name", 1}}, //nolint:govet
+		Keys:    bson.D{{Key: "name", Value: 1}},
 		Options: options.Index().SetUnique(true),
 	})
 	if err != nil {
@@ -186,17 +407,29 @@ func (m *MongoStore) EnsureIndexes(ctx context.Context) error {
 
 	// Users: unique(username), unique(email)
 	for _, idx := range []mongo.IndexModel{
-		{Keys: bson.D{{"username", 1}}, Options: options.Index().SetUnique(true)}, //nolint:govet
-		{Keys: bson.D{{"email", 1}}, Options: options.Index().SetUnique(true)},    //nolint:govet
+		{Keys: bson.D{{Key: "username", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "email", Value: 1}}, Options: options.Index().SetUnique(true)},
 	} {
 		if _, err = m.usersCol.Indexes().CreateOne(ctx, idx); err != nil {
 			return err
 		}
 	}
 
+	// Permissions, roles, users: unique(id). IDs may come from the caller, so
+	// reusing one must fail here rather than leave two documents behind it.
+	for _, col := range []*mongo.Collection{m.permsCol, m.rolesCol, m.usersCol} {
+		_, err = col.Indexes().CreateOne(ctx, mongo.IndexModel{
+			Keys:    bson.D{{Key: "id", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
 	// Role permissions: unique(role_id, permission_id)
 	_, err = m.rolePermCol.Indexes().CreateOne(ctx, mongo.IndexModel{
-		Keys:    bson.D{{"role_id", 1}, {"permission_id", 1}}, //nolint:govet
+		Keys:    bson.D{{Key: "role_id", Value: 1}, {Key: "permission_id", Value: 1}},
 		Options: options.Index().SetUnique(true),
 	})
 	if err != nil {
@@ -205,7 +438,7 @@ func (m *MongoStore) EnsureIndexes(ctx context.Context) error {
 
 	// User roles: unique(user_id, role_id)
 	_, err = m.userRoleCol.Indexes().CreateOne(ctx, mongo.IndexModel{
-		Keys:    bson.D{{"user_id", 1}, {"role_id", 1}}, //nolint:govet
+		Keys:    bson.D{{Key: "user_id", Value: 1}, {Key: "role_id", Value: 1}},
 		Options: options.Index().SetUnique(true),
 	})
 	if err != nil {
@@ -213,13 +446,219 @@ func (m *MongoStore) EnsureIndexes(ctx context.Context) error {
 	}
 
 	_, err = m.groupRoleCol.Indexes().CreateOne(ctx, mongo.IndexModel{
-		Keys:    bson.D{{"group_name", 1}, {"role_id", 1}}, //nolint:govet
+		Keys:    bson.D{{Key: "group_name", Value: 1}, {Key: "role_id", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		return err
+	}
+
+	// Role parents: unique(role_id, parent_id)
+	_, err = m.parentsCol.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "role_id", Value: 1}, {Key: "parent_id", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		return err
+	}
+
+	// Scoped roles: unique(subject, role_id, scope)
+	for _, col := range []*mongo.Collection{m.urScopedCol, m.grScopedCol} {
+		_, err = col.Indexes().CreateOne(ctx, mongo.IndexModel{
+			Keys:    bson.D{{Key: "subject", Value: 1}, {Key: "role_id", Value: 1}, {Key: "scope", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	// Constrained roles: unique(subject, role_id)
+	_, err = m.urLimitsCol.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "subject", Value: 1}, {Key: "role_id", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		return err
+	}
+
+	// Tenant filtering: tenant_id on every entity that carries one
+	for _, col := range []*mongo.Collection{m.permsCol, m.rolesCol, m.usersCol, m.userGroupCol} {
+		_, err = col.Indexes().CreateOne(ctx, mongo.IndexModel{
+			Keys: bson.D{{Key: "tenant_id", Value: 1}},
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	// Paged lists: the members of a group and the permissions of a role in
+	// _id order
+	for _, idx := range []struct {
+		col   *mongo.Collection
+		field string
+	}{{m.userGroupCol, "group_name"}, {m.rolePermCol, "role_id"}} {
+		_, err = idx.col.Indexes().CreateOne(ctx, mongo.IndexModel{
+			Keys: bson.D{{Key: idx.field, Value: 1}, {Key: "_id", Value: 1}},
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	// Reverse lookups: the users and groups holding a role
+	for _, col := range []*mongo.Collection{m.userRoleCol, m.groupRoleCol} {
+		_, err = col.Indexes().CreateOne(ctx, mongo.IndexModel{
+			Keys: bson.D{{Key: "role_id", Value: 1}},
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	// Expiring access: sparse expires_at on time-limited assignments
+	for _, col := range []*mongo.Collection{m.userRoleCol, m.userGroupCol} {
+		_, err = col.Indexes().CreateOne(ctx, mongo.IndexModel{
+			Keys:    bson.D{{Key: "expires_at", Value: 1}},
+			Options: options.Index().SetSparse(true),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	// Attestations: latest per (user_id, role_id)
+	_, err = m.attestCol.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "role_id", Value: 1}, {Key: "certified_at", Value: -1}},
+	})
+	if err != nil {
+		return err
+	}
+
+	// Archives: unique(id)
+	_, err = m.archivesCol.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "id", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		return err
+	}
+
+	// Policy snapshots: unique(id)
+	_, err = m.snapshotsCol.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "id", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		return err
+	}
+
+	// API keys: unique(id), listed per principal
+	for _, idx := range []mongo.IndexModel{
+		{Keys: bson.D{{Key: "id", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "principal_id", Value: 1}, {Key: "created_at", Value: 1}}},
+	} {
+		if _, err = m.apiKeysCol.Indexes().CreateOne(ctx, idx); err != nil {
+			return err
+		}
+	}
+
+	// Sessions: unique(id)
+	_, err = m.sessionsCol.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "id", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		return err
+	}
+
+	// SoD constraints: unique(id)
+	_, err = m.sodCol.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "id", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		return err
+	}
+
+	// Assignment requests: unique(id), (status, created_at)
+	for _, idx := range []mongo.IndexModel{
+		{Keys: bson.D{{Key: "id", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "created_at", Value: 1}}},
+	} {
+		if _, err = m.requestsCol.Indexes().CreateOne(ctx, idx); err != nil {
+			return err
+		}
+	}
+
+	// Delegations: unique(id), from_user_id, to_user_id
+	for _, idx := range []mongo.IndexModel{
+		{Keys: bson.D{{Key: "id", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "from_user_id", Value: 1}}},
+		{Keys: bson.D{{Key: "to_user_id", Value: 1}}},
+	} {
+		if _, err = m.delegCol.Indexes().CreateOne(ctx, idx); err != nil {
+			return err
+		}
+	}
+
+	// Audit log: unique(id), (actor, at), (target, at), at
+	for _, idx := range []mongo.IndexModel{
+		{Keys: bson.D{{Key: "id", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "actor", Value: 1}, {Key: "at", Value: 1}}},
+		{Keys: bson.D{{Key: "target", Value: 1}, {Key: "at", Value: 1}}},
+		{Keys: bson.D{{Key: "at", Value: 1}}},
+	} {
+		if _, err = m.auditCol.Indexes().CreateOne(ctx, idx); err != nil {
+			return err
+		}
+	}
+
+	// Permission sets: unique(id); role bindings: unique(role_id, set_id), set_id
+	_, err = m.permSetCol.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "id", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		return err
+	}
+	for _, idx := range []mongo.IndexModel{
+		{Keys: bson.D{{Key: "role_id", Value: 1}, {Key: "set_id", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "set_id", Value: 1}}},
+	} {
+		if _, err = m.roleSetCol.Indexes().CreateOne(ctx, idx); err != nil {
+			return err
+		}
+	}
+
+	// Group bans: unique(group_name, user_id), user_id
+	for _, idx := range []mongo.IndexModel{
+		{Keys: bson.D{{Key: "group_name", Value: 1}, {Key: "user_id", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "user_id", Value: 1}}},
+	} {
+		if _, err = m.bansCol.Indexes().CreateOne(ctx, idx); err != nil {
+			return err
+		}
+	}
+
+	// Tenants: unique(id)
+	_, err = m.tenantsCol.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "id", Value: 1}},
 		Options: options.Index().SetUnique(true),
 	})
 	if err != nil {
 		return err
 	}
 
+	// Groups: unique(id), unique(name)
+	_, err = m.groupsCol.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "id", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "name", Value: 1}}, Options: options.Index().SetUnique(true)},
+	})
+	if err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -229,8 +668,14 @@ func (m *MongoStore) AddRoleToGroup(ctx context.Context, groupID, roleID string)
 		GroupName: groupID,
 		RoleID:    roleID,
 		CreatedAt: time.Now().Unix(),
+		ManagedBy: mongoManagedBy(ctx),
 	}
 	_, err := m.groupRoleCol.InsertOne(ctx, doc)
+	if mongo.IsDuplicateKeyError(err) {
+		if claimErr := m.claimEdge(ctx, m.groupRoleCol, bson.M{"group_name": groupID, "role_id": roleID}); claimErr != nil {
+			return claimErr
+		}
+	}
 	return err
 }
 
@@ -264,6 +709,112 @@ func (m *MongoStore) ListRolesForGroup(ctx context.Context, groupID string) ([]s
 	return out, cur.Err()
 }
 
+// ListGroupsForRole returns the groups roleID is assigned to
+func (m *MongoStore) ListGroupsForRole(ctx context.Context, roleID string) ([]string, error) {
+	cur, err := m.groupRoleCol.Find(ctx, bson.M{"role_id": roleID},
+		options.Find().SetSort(bson.D{{Key: "group_name", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = cur.Close(ctx)
+	}()
+
+	var out []string
+	for cur.Next(ctx) {
+		var doc mongoGroupRole
+		if err := cur.Decode(&doc); err != nil {
+			return nil, err
+		}
+		out = append(out, doc.GroupName)
+	}
+	return out, cur.Err()
+}
+
+// --- EdgeSourceRepo ---
+
+// mongoManagedBy returns the managed_by value for edges created with ctx,
+// which is empty for manual writes.
+func mongoManagedBy(ctx context.Context) string {
+	if src := AssignmentSource(ctx); src != SourceManual && src != SourceAny {
+		return src
+	}
+	return ""
+}
+
+// claimEdge makes an existing edge manual when ctx's writes are manual.
+func (m *MongoStore) claimEdge(ctx context.Context, col *mongo.Collection, filter bson.M) error {
+	if mongoManagedBy(ctx) != "" {
+		return nil
+	}
+	_, err := col.UpdateMany(ctx, filter, bson.M{"$unset": bson.M{"managed_by": ""}})
+	return err
+}
+
+func (m *MongoStore) EdgeSource(ctx context.Context, kind, from, to string) (string, error) {
+	var (
+		col    *mongo.Collection
+		filter bson.M
+	)
+	switch kind {
+	case KindUserRole:
+		col, filter = m.userRoleCol, bson.M{"user_id": from, "role_id": to}
+	case KindRolePermission:
+		col, filter = m.rolePermCol, bson.M{"role_id": from, "permission_id": to}
+	case KindUserGroup:
+		col, filter = m.userGroupCol, bson.M{"user_id": from, "group_name": to}
+	case KindGroupRole:
+		col, filter = m.groupRoleCol, bson.M{"group_name": from, "role_id": to}
+	default:
+		return "", fmt.Errorf("mongo_store: unknown edge kind %q", kind)
+	}
+	var doc struct {
+		ManagedBy string `bson:"managed_by"`
+	}
+	err := col.FindOne(ctx, filter).Decode(&doc)
+	if err == mongo.ErrNoDocuments {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	if doc.ManagedBy == "" {
+		return SourceManual, nil
+	}
+	return doc.ManagedBy, nil
+}
+
+// --- RoleHierarchyRepo ---
+
+func (m *MongoStore) AddRoleParent(ctx context.Context, roleID, parentID string) error {
+	_, err := m.parentsCol.InsertOne(ctx, mongoRoleParent{
+		RoleID:    roleID,
+		ParentID:  parentID,
+		CreatedAt: time.Now().Unix(),
+	})
+	if mongo.IsDuplicateKeyError(err) {
+		return nil
+	}
+	return err
+}
+
+func (m *MongoStore) RemoveRoleParent(ctx context.Context, roleID, parentID string) error {
+	_, err := m.parentsCol.DeleteOne(ctx, bson.M{"role_id": roleID, "parent_id": parentID})
+	return err
+}
+
+func (m *MongoStore) ListRoleParents(ctx context.Context, roleID string) ([]string, error) {
+	var docs []mongoRoleParent
+	if err := findAll(ctx, m.parentsCol, bson.M{"role_id": roleID}, &docs); err != nil {
+		return nil, err
+	}
+	out := make([]string, 0, len(docs))
+	for _, d := range docs {
+		out = append(out, d.ParentID)
+	}
+	return out, nil
+}
+
 // --- PermissionRepo ---
 func (m *MongoStore) GetPermissionByID(ctx context.Context, id string) (*Permission, error) {
 	var doc Permission
@@ -278,6 +829,14 @@ func (m *MongoStore) GetPermissionByID(ctx context.Context, id string) (*Permiss
 	return &doc, nil
 }
 
+func (m *MongoStore) ListAllPermissions(ctx context.Context) ([]*Permission, error) {
+	var out []*Permission
+	if err := findAll(ctx, m.permsCol, bson.M{}, &out); err != nil {
+		return nil, fmt.Errorf("failed to decode permission: %w", err)
+	}
+	return out, nil
+}
+
 func (m *MongoStore) DeleteRole(ctx context.Context, id string) error {
 	_, err := m.rolesCol.DeleteOne(ctx, bson.M{"id": id})
 	return err
@@ -307,6 +866,37 @@ func (m *MongoStore) ListAllRoles(ctx context.Context) (r []*Role, err error) {
 	return r, cur.Err()
 }
 
+//
+// ---------- Soft deletes ----------
+//
+
+func (m *MongoStore) SetRoleDeletedAt(ctx context.Context, id string, at int64) error {
+	_, err := m.rolesCol.UpdateOne(ctx, bson.M{"id": id}, mongoDeletedAt(ctx, at))
+	return err
+}
+
+func (m *MongoStore) SetPermissionDeletedAt(ctx context.Context, id string, at int64) error {
+	_, err := m.permsCol.UpdateOne(ctx, bson.M{"id": id}, mongoDeletedAt(ctx, at))
+	return err
+}
+
+// mongoDeletedAt sets deleted_at, or removes it to restore, matching the
+// omitempty tag on the models.
+func mongoDeletedAt(ctx context.Context, at int64) bson.M {
+	if at == 0 {
+		return bson.M{"$unset": bson.M{"deleted_at": ""}, "$set": mongoUpdated(ctx, bson.M{})}
+	}
+	return bson.M{"$set": mongoUpdated(ctx, bson.M{"deleted_at": at})}
+}
+
+// mongoUpdated adds the audit fields of an in-place update by ctx's actor
+// to set.
+func mongoUpdated(ctx context.Context, set bson.M) bson.M {
+	set["updated_at"] = time.Now().Unix()
+	set["updated_by"] = Actor(ctx)
+	return set
+}
+
 //
 // ---------- Permissions ----------
 //
@@ -318,10 +908,17 @@ func (m *MongoStore) CreatePermission(ctx context.Context, p *Permission) error
 		return nil
 	}
 
-	p.ID = uuid.New().String()
+	if p.ID == "" {
+		p.ID = generateID(m.ids, KindPermission)
+	}
 	p.CreatedAt = time.Now().Unix()
 
 	_, err := m.permsCol.InsertOne(ctx, p)
+	if p.Name != "" && mongo.IsDuplicateKeyError(err) {
+		if named, _ := m.GetPermissionByName(ctx, p.Name); named != nil {
+			return fmt.Errorf("%w: %q", ErrPermissionNameTaken, p.Name)
+		}
+	}
 	return err
 }
 
@@ -335,7 +932,9 @@ func (m *MongoStore) DeletePermission(ctx context.Context, id string) error {
 //
 
 func (m *MongoStore) CreateRole(ctx context.Context, r *Role) error {
-	r.ID = uuid.New().String()
+	if r.ID == "" {
+		r.ID = generateID(m.ids, KindRole)
+	}
 	r.CreatedAt = time.Now().Unix()
 
 	_, err := m.rolesCol.InsertOne(ctx, r)
@@ -374,7 +973,7 @@ func (m *MongoStore) GetRoleByID(ctx context.Context, id string) (*Role, error)
 
 func (m *MongoStore) CreateUser(ctx context.Context, u *User) error {
 	if u.ID == "" {
-		u.ID = uuid.New().String()
+		u.ID = generateID(m.ids, KindUser)
 	}
 	u.CreatedAt = time.Now().Unix()
 
@@ -395,9 +994,58 @@ func (m *MongoStore) GetUserByID(ctx context.Context, id string) (*User, error)
 	return &doc, nil
 }
 
-//
-// ---------- RolePermissions ----------
-//
+// IndexUserMeta creates an index on each of the Meta keys.
+func (m *MongoStore) IndexUserMeta(ctx context.Context, keys ...string) error {
+	for _, k := range keys {
+		idx := mongo.IndexModel{Keys: bson.D{{Key: MetaPrefix + k, Value: 1}}}
+		if _, err := m.usersCol.Indexes().CreateOne(ctx, idx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *MongoStore) SetUserStatus(ctx context.Context, id string, status UserStatus) error {
+	_, err := m.usersCol.UpdateOne(ctx, bson.M{"id": id}, bson.M{"$set": mongoUpdated(ctx, bson.M{"status": status})})
+	return err
+}
+
+func (m *MongoStore) SetEmailVerified(ctx context.Context, id string, verified bool) error {
+	_, err := m.usersCol.UpdateOne(ctx, bson.M{"id": id}, bson.M{"$set": mongoUpdated(ctx, bson.M{"email_verified": verified})})
+	return err
+}
+
+func (m *MongoStore) GetUserByUsername(ctx context.Context, username string) (*User, error) {
+	return m.findUser(ctx, bson.M{"username": username})
+}
+
+func (m *MongoStore) GetUserByEmail(ctx context.Context, email string) (*User, error) {
+	return m.findUser(ctx, bson.M{"email": email})
+}
+
+func (m *MongoStore) findUser(ctx context.Context, filter bson.M) (*User, error) {
+	var doc User
+	err := m.usersCol.FindOne(ctx, filter).Decode(&doc)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &doc, nil
+}
+
+func (m *MongoStore) ListAllUsers(ctx context.Context) ([]*User, error) {
+	var out []*User
+	if err := findAll(ctx, m.usersCol, bson.M{}, &out); err != nil {
+		return nil, fmt.Errorf("failed to decode user: %w", err)
+	}
+	return out, nil
+}
+
+//
+// ---------- RolePermissions ----------
+//
 
 func (m *MongoStore) AddRP(ctx context.Context, roleID, permID string) error {
 
@@ -405,12 +1053,60 @@ func (m *MongoStore) AddRP(ctx context.Context, roleID, permID string) error {
 		RoleID:       roleID,
 		PermissionID: permID,
 		CreatedAt:    time.Now().Unix(),
+		ManagedBy:    mongoManagedBy(ctx),
 	}
 
 	_, err := m.rolePermCol.InsertOne(ctx, doc)
+	if mongo.IsDuplicateKeyError(err) {
+		if claimErr := m.claimEdge(ctx, m.rolePermCol, bson.M{"role_id": roleID, "permission_id": permID}); claimErr != nil {
+			return claimErr
+		}
+	}
 	return err
 }
 
+// AddRPs binds permIDs with one InsertMany; bindings that already exist get
+// AddRP's handling.
+func (m *MongoStore) AddRPs(ctx context.Context, roleID string, permIDs []string) error {
+	docs := make([]interface{}, len(permIDs))
+	for i, permID := range permIDs {
+		docs[i] = mongoRolePermission{
+			RoleID:       roleID,
+			PermissionID: permID,
+			CreatedAt:    time.Now().Unix(),
+			ManagedBy:    mongoManagedBy(ctx),
+		}
+	}
+	return insertEdges(ctx, m.rolePermCol, docs, func(i int) error {
+		return m.AddRP(ctx, roleID, permIDs[i])
+	})
+}
+
+// insertEdges inserts docs unordered in one round trip and calls existing
+// with the index of each one a unique index rejected, so duplicates get the
+// single-edge handling.
+func insertEdges(ctx context.Context, col *mongo.Collection, docs []interface{}, existing func(i int) error) error {
+	if len(docs) == 0 {
+		return nil
+	}
+	_, err := col.InsertMany(ctx, docs, options.InsertMany().SetOrdered(false))
+	var bwe mongo.BulkWriteException
+	if !errors.As(err, &bwe) || bwe.WriteConcernError != nil {
+		return err
+	}
+	for _, we := range bwe.WriteErrors {
+		if !mongo.IsDuplicateKeyError(we.WriteError) {
+			return err
+		}
+	}
+	for _, we := range bwe.WriteErrors {
+		if err := existing(we.Index); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (m *MongoStore) Remove(ctx context.Context, roleID, permID string) error {
 	_, err := m.rolePermCol.DeleteOne(ctx, bson.M{
 		"role_id":       roleID,
@@ -447,10 +1143,118 @@ func (m *MongoStore) AddUR(ctx context.Context, userID, roleID string) error {
 		UserID:     userID,
 		RoleID:     roleID,
 		AssignedAt: time.Now().Unix(),
+		ManagedBy:  mongoManagedBy(ctx),
+	})
+	if !mongo.IsDuplicateKeyError(err) {
+		return err
+	}
+	filter := bson.M{"user_id": userID, "role_id": roleID}
+	if claimErr := m.claimEdge(ctx, m.userRoleCol, filter); claimErr != nil {
+		return claimErr
+	}
+	// Already assigned. A scheduled assignment gets its window opened; a
+	// plain duplicate still fails.
+	filter["$or"] = bson.A{bson.M{"not_before": bson.M{"$exists": true}}, bson.M{"expires_at": bson.M{"$exists": true}}}
+	res, updErr := m.userRoleCol.UpdateOne(ctx, filter, bson.M{"$unset": bson.M{"not_before": "", "expires_at": ""}})
+	if updErr != nil {
+		return updErr
+	}
+	if res.MatchedCount > 0 {
+		return nil
+	}
+	return err
+}
+
+// AddURs assigns roleIDs with one InsertMany; assignments that already
+// exist get AddUR's handling.
+func (m *MongoStore) AddURs(ctx context.Context, userID string, roleIDs []string) error {
+	docs := make([]interface{}, len(roleIDs))
+	for i, roleID := range roleIDs {
+		docs[i] = mongoUserRole{
+			UserID:     userID,
+			RoleID:     roleID,
+			AssignedAt: time.Now().Unix(),
+			ManagedBy:  mongoManagedBy(ctx),
+		}
+	}
+	return insertEdges(ctx, m.userRoleCol, docs, func(i int) error {
+		return m.AddUR(ctx, userID, roleIDs[i])
 	})
+}
+
+func (m *MongoStore) AddScheduledUR(ctx context.Context, a *RoleAssignment) error {
+	set := bson.M{"assigned_at": time.Now().Unix()}
+	unset := bson.M{}
+	for field, v := range map[string]int64{"not_before": a.NotBefore, "expires_at": a.ExpiresAt} {
+		if v == 0 {
+			unset[field] = ""
+		} else {
+			set[field] = v
+		}
+	}
+	update := bson.M{"$set": set}
+	if src := mongoManagedBy(ctx); src != "" {
+		update["$setOnInsert"] = bson.M{"managed_by": src}
+	} else {
+		unset["managed_by"] = ""
+	}
+	if len(unset) > 0 {
+		update["$unset"] = unset
+	}
+	_, err := m.userRoleCol.UpdateOne(ctx,
+		bson.M{"user_id": a.UserID, "role_id": a.RoleID},
+		update,
+		options.Update().SetUpsert(true),
+	)
 	return err
 }
 
+func (m *MongoStore) ListRoleAssignments(ctx context.Context, userID string) ([]*RoleAssignment, error) {
+	cur, err := m.userRoleCol.Find(ctx, bson.M{"user_id": userID})
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	var out []*RoleAssignment
+	for cur.Next(ctx) {
+		var rec mongoUserRole
+		if err := cur.Decode(&rec); err != nil {
+			return nil, err
+		}
+		out = append(out, &RoleAssignment{
+			UserID:    rec.UserID,
+			RoleID:    rec.RoleID,
+			NotBefore: rec.NotBefore,
+			ExpiresAt: rec.ExpiresAt,
+		})
+	}
+	return out, cur.Err()
+}
+
+func (m *MongoStore) ListExpiringRoleAssignments(ctx context.Context, after, before int64) ([]*RoleAssignment, error) {
+	cur, err := m.userRoleCol.Find(ctx, bson.M{"expires_at": bson.M{"$gt": after, "$lte": before}})
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	var out []*RoleAssignment
+	for cur.Next(ctx) {
+		var rec mongoUserRole
+		if err := cur.Decode(&rec); err != nil {
+			return nil, err
+		}
+		out = append(out, &RoleAssignment{
+			UserID:    rec.UserID,
+			RoleID:    rec.RoleID,
+			NotBefore: rec.NotBefore,
+			ExpiresAt: rec.ExpiresAt,
+		})
+	}
+	return out, cur.Err()
+}
+
 func (m *MongoStore) RemoveUR(ctx context.Context, userID, roleID string) error {
 	_, err := m.userRoleCol.DeleteOne(ctx, bson.M{
 		"user_id": userID,
@@ -461,13 +1265,16 @@ func (m *MongoStore) RemoveUR(ctx context.Context, userID, roleID string) error
 
 func (m *MongoStore) ListRoles(ctx context.Context, userID string) ([]string, error) {
 	var out []string
-	cur, err := m.userRoleCol.Find(ctx, bson.M{"user_id": userID})
+	now := time.Now().Unix()
+	cur, err := m.userRoleCol.Find(ctx, bson.M{
+		"user_id": userID,
+		"$and": bson.A{
+			bson.M{"$or": bson.A{bson.M{"not_before": bson.M{"$exists": false}}, bson.M{"not_before": bson.M{"$lte": now}}}},
+			bson.M{"$or": bson.A{bson.M{"expires_at": bson.M{"$exists": false}}, bson.M{"expires_at": bson.M{"$gt": now}}}},
+		},
+	})
 	if err != nil {
-		// always add default role
-		if r, _ := m.GetRoleByName(ctx, "default"); r != nil {
-			out = append(out, r.ID)
-		}
-		return out, err
+		return nil, err
 	}
 	defer cur.Close(ctx)
 
@@ -478,12 +1285,120 @@ func (m *MongoStore) ListRoles(ctx context.Context, userID string) ([]string, er
 		}
 		out = append(out, rec.RoleID)
 	}
+	return out, nil
+}
+
+// ListUsersForRole returns every user assigned roleID, whatever its window.
+func (m *MongoStore) ListUsersForRole(ctx context.Context, roleID string) ([]string, error) {
+	cur, err := m.userRoleCol.Find(ctx, bson.M{"role_id": roleID},
+		options.Find().SetSort(bson.D{{Key: "user_id", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	var out []string
+	for cur.Next(ctx) {
+		var rec mongoUserRole
+		if err := cur.Decode(&rec); err != nil {
+			return nil, err
+		}
+		out = append(out, rec.UserID)
+	}
+	return out, cur.Err()
+}
+
+//
+// ---------- Scoped roles ----------
+//
+
+func (m *MongoStore) AddScopedUR(ctx context.Context, userID, roleID, scope string) error {
+	return addMongoScoped(ctx, m.urScopedCol, userID, roleID, scope)
+}
+
+func (m *MongoStore) RemoveScopedUR(ctx context.Context, userID, roleID, scope string) error {
+	_, err := m.urScopedCol.DeleteOne(ctx, bson.M{"subject": userID, "role_id": roleID, "scope": scope})
+	return err
+}
+
+func (m *MongoStore) ListScopedRoles(ctx context.Context, userID string) ([]ScopedRole, error) {
+	return listMongoScoped(ctx, m.urScopedCol, userID)
+}
+
+func (m *MongoStore) AddScopedRoleToGroup(ctx context.Context, groupID, roleID, scope string) error {
+	return addMongoScoped(ctx, m.grScopedCol, groupID, roleID, scope)
+}
+
+func (m *MongoStore) RemoveScopedRoleFromGroup(ctx context.Context, groupID, roleID, scope string) error {
+	_, err := m.grScopedCol.DeleteOne(ctx, bson.M{"subject": groupID, "role_id": roleID, "scope": scope})
+	return err
+}
+
+func (m *MongoStore) ListScopedRolesForGroup(ctx context.Context, groupID string) ([]ScopedRole, error) {
+	return listMongoScoped(ctx, m.grScopedCol, groupID)
+}
+
+// addMongoScoped upserts the assignment, so adding it twice is a no-op.
+func addMongoScoped(ctx context.Context, col *mongo.Collection, subject, roleID, scope string) error {
+	_, err := col.UpdateOne(ctx,
+		bson.M{"subject": subject, "role_id": roleID, "scope": scope},
+		bson.M{"$setOnInsert": bson.M{"created_at": time.Now().Unix()}},
+		options.Update().SetUpsert(true),
+	)
+	return err
+}
 
-	// always add default role
-	if r, _ := m.GetRoleByName(ctx, "default"); r != nil {
-		out = append(out, r.ID)
+func listMongoScoped(ctx context.Context, col *mongo.Collection, subject string) ([]ScopedRole, error) {
+	cur, err := col.Find(ctx, bson.M{"subject": subject})
+	if err != nil {
+		return nil, err
 	}
+	var docs []mongoScopedRole
+	if err := cur.All(ctx, &docs); err != nil {
+		return nil, err
+	}
+	out := make([]ScopedRole, len(docs))
+	for i, d := range docs {
+		out[i] = ScopedRole{RoleID: d.RoleID, Scope: d.Scope}
+	}
+	return out, nil
+}
+
+//
+// ---------- Constrained roles ----------
+//
 
+// AddConstrainedUR upserts the assignment, replacing its constraints.
+func (m *MongoStore) AddConstrainedUR(ctx context.Context, userID, roleID string, c RoleConstraints) error {
+	_, err := m.urLimitsCol.UpdateOne(ctx,
+		bson.M{"subject": userID, "role_id": roleID},
+		bson.M{
+			"$set":         bson.M{"constraints": c},
+			"$setOnInsert": bson.M{"created_at": time.Now().Unix()},
+		},
+		options.Update().SetUpsert(true),
+	)
+	return err
+}
+
+func (m *MongoStore) RemoveConstrainedUR(ctx context.Context, userID, roleID string) error {
+	_, err := m.urLimitsCol.DeleteOne(ctx, bson.M{"subject": userID, "role_id": roleID})
+	return err
+}
+
+func (m *MongoStore) ListConstrainedRoles(ctx context.Context, userID string) ([]ConstrainedRole, error) {
+	cur, err := m.urLimitsCol.Find(ctx, bson.M{"subject": userID})
+	if err != nil {
+		return nil, err
+	}
+	var docs []mongoConstrainedRole
+	if err := cur.All(ctx, &docs); err != nil {
+		return nil, err
+	}
+	out := make([]ConstrainedRole, len(docs))
+	for i, d := range docs {
+		out[i] = ConstrainedRole{RoleID: d.RoleID, Constraints: d.Constraints}
+	}
 	return out, nil
 }
 
@@ -496,13 +1411,100 @@ func (m *MongoStore) AddUserToGroup(ctx context.Context, ug *UserGroup) error {
 		return errors.New("user id is empty")
 	}
 
-	ug.ID = uuid.New().String()
+	if ug.ID == "" {
+		ug.ID = generateID(m.ids, KindUserGroup)
+	}
 	ug.CreatedAt = time.Now().Unix()
 
-	_, err := m.userGroupCol.InsertOne(ctx, ug)
+	filter := bson.M{"user_id": ug.UserID, "group_name": ug.GroupName}
+	n, err := m.userGroupCol.CountDocuments(ctx, filter, options.Count().SetLimit(1))
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		// Re-adding a member renews, or clears, the membership's expiry
+		// and level.
+		set, unset := bson.M{}, bson.M{}
+		if ug.ExpiresAt != 0 {
+			set["expires_at"] = ug.ExpiresAt
+		} else {
+			unset["expires_at"] = ""
+		}
+		if ug.Level != "" {
+			set["level"] = ug.Level
+		} else {
+			unset["level"] = ""
+		}
+		update := bson.M{}
+		if len(set) > 0 {
+			update["$set"] = set
+		}
+		if len(unset) > 0 {
+			update["$unset"] = unset
+		}
+		if _, err := m.userGroupCol.UpdateMany(ctx, filter, update); err != nil {
+			return err
+		}
+		return m.claimEdge(ctx, m.userGroupCol, filter)
+	}
+	_, err = m.userGroupCol.InsertOne(ctx, mongoUserGroup{UserGroup: *ug, ManagedBy: mongoManagedBy(ctx)})
 	return err
 }
 
+// AddUsersToGroups inserts the new memberships with one InsertMany and
+// renews those that already exist, or repeat in ugs, as AddUserToGroup does.
+func (m *MongoStore) AddUsersToGroups(ctx context.Context, ugs []*UserGroup) error {
+	if len(ugs) == 0 {
+		return nil
+	}
+	pairs := make(bson.A, 0, len(ugs))
+	for _, ug := range ugs {
+		if ug.UserID == "" {
+			return errors.New("user id is empty")
+		}
+		pairs = append(pairs, bson.M{"user_id": ug.UserID, "group_name": ug.GroupName})
+	}
+	cur, err := m.userGroupCol.Find(ctx, bson.M{"$or": pairs}, options.Find().SetProjection(bson.M{"user_id": 1, "group_name": 1}))
+	if err != nil {
+		return err
+	}
+	var found []UserGroup
+	if err := cur.All(ctx, &found); err != nil {
+		return err
+	}
+	held := map[[2]string]bool{}
+	for _, ug := range found {
+		held[[2]string{ug.UserID, ug.GroupName}] = true
+	}
+
+	var docs []interface{}
+	var renew []*UserGroup
+	for _, ug := range ugs {
+		key := [2]string{ug.UserID, ug.GroupName}
+		if held[key] {
+			renew = append(renew, ug)
+			continue
+		}
+		held[key] = true
+		if ug.ID == "" {
+			ug.ID = generateID(m.ids, KindUserGroup)
+		}
+		ug.CreatedAt = time.Now().Unix()
+		docs = append(docs, mongoUserGroup{UserGroup: *ug, ManagedBy: mongoManagedBy(ctx)})
+	}
+	if len(docs) > 0 {
+		if _, err := m.userGroupCol.InsertMany(ctx, docs); err != nil {
+			return err
+		}
+	}
+	for _, ug := range renew {
+		if err := m.AddUserToGroup(ctx, ug); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (m *MongoStore) RemoveUserFromGroup(ctx context.Context, groupName string, ug *UserGroup) error {
 	if ug.UserID == "" {
 		return errors.New("user id is empty")
@@ -534,3 +1536,883 @@ func (m *MongoStore) GetUsersByGroupID(ctx context.Context, groupName string) ([
 
 	return out, cur.Err()
 }
+
+func (m *MongoStore) ListExpiringMemberships(ctx context.Context, after, before int64) ([]*UserGroup, error) {
+	var out []*UserGroup
+	err := findAll(ctx, m.userGroupCol, bson.M{"expires_at": bson.M{"$gt": after, "$lte": before}}, &out)
+	return out, err
+}
+
+//
+// ---------- Group bans ----------
+//
+
+func (m *MongoStore) AddGroupBan(ctx context.Context, b *GroupBan) error {
+	_, err := m.bansCol.ReplaceOne(ctx, bson.M{"group_name": b.GroupName, "user_id": b.UserID}, b, options.Replace().SetUpsert(true))
+	return err
+}
+
+func (m *MongoStore) RemoveGroupBan(ctx context.Context, groupName, userID string) error {
+	_, err := m.bansCol.DeleteOne(ctx, bson.M{"group_name": groupName, "user_id": userID})
+	return err
+}
+
+func (m *MongoStore) ListGroupBans(ctx context.Context, groupName string) ([]*GroupBan, error) {
+	var out []*GroupBan
+	if err := findAll(ctx, m.bansCol, bson.M{"group_name": groupName}, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (m *MongoStore) ListUserBans(ctx context.Context, userID string) ([]*GroupBan, error) {
+	var out []*GroupBan
+	if err := findAll(ctx, m.bansCol, bson.M{"user_id": userID}, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+//
+// ---------- Groups ----------
+//
+
+func (m *MongoStore) CreateGroup(ctx context.Context, g *Group) error {
+	if g.ID == "" {
+		g.ID = generateID(m.ids, KindGroup)
+	}
+	g.CreatedAt = time.Now().Unix()
+
+	_, err := m.groupsCol.InsertOne(ctx, g)
+	return err
+}
+
+func (m *MongoStore) UpdateGroup(ctx context.Context, g *Group) error {
+	res, err := m.groupsCol.ReplaceOne(ctx, bson.M{"id": g.ID}, g)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return fmt.Errorf("group %q not found", g.ID)
+	}
+	return nil
+}
+
+func (m *MongoStore) DeleteGroup(ctx context.Context, id string) error {
+	_, err := m.groupsCol.DeleteOne(ctx, bson.M{"id": id})
+	return err
+}
+
+func (m *MongoStore) GetGroupByID(ctx context.Context, id string) (*Group, error) {
+	return m.findGroup(ctx, bson.M{"id": id})
+}
+
+func (m *MongoStore) GetGroupByName(ctx context.Context, name string) (*Group, error) {
+	return m.findGroup(ctx, bson.M{"name": name})
+}
+
+func (m *MongoStore) findGroup(ctx context.Context, filter bson.M) (*Group, error) {
+	var doc Group
+	err := m.groupsCol.FindOne(ctx, filter).Decode(&doc)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &doc, nil
+}
+
+func (m *MongoStore) ListGroups(ctx context.Context) ([]*Group, error) {
+	var out []*Group
+	err := findAll(ctx, m.groupsCol, bson.M{}, &out)
+	return out, err
+}
+
+//
+// ---------- Attestations ----------
+//
+
+func (m *MongoStore) AddAttestation(ctx context.Context, a *Attestation) error {
+	if a.ID == "" {
+		a.ID = generateID(m.ids, KindAttestation)
+	}
+	_, err := m.attestCol.InsertOne(ctx, a)
+	return err
+}
+
+func (m *MongoStore) LatestAttestation(ctx context.Context, userID, roleID string) (*Attestation, error) {
+	var doc Attestation
+	err := m.attestCol.FindOne(ctx,
+		bson.M{"user_id": userID, "role_id": roleID},
+		options.FindOne().SetSort(bson.D{{Key: "certified_at", Value: -1}}),
+	).Decode(&doc)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &doc, nil
+}
+
+func (m *MongoStore) ListAttestations(ctx context.Context, userID string) ([]*Attestation, error) {
+	cur, err := m.attestCol.Find(ctx, bson.M{"user_id": userID}, options.Find().SetSort(bson.D{{Key: "certified_at", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	var out []*Attestation
+	if err := cur.All(ctx, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+//
+// ---------- Archives ----------
+//
+
+func (m *MongoStore) SaveArchive(ctx context.Context, a *Archive) error {
+	if a.ID == "" {
+		a.ID = generateID(m.ids, KindArchive)
+	}
+	_, err := m.archivesCol.ReplaceOne(ctx, bson.M{"id": a.ID}, a, options.Replace().SetUpsert(true))
+	return err
+}
+
+func (m *MongoStore) GetArchive(ctx context.Context, id string) (*Archive, error) {
+	var doc Archive
+	err := m.archivesCol.FindOne(ctx, bson.M{"id": id}).Decode(&doc)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &doc, nil
+}
+
+func (m *MongoStore) ListArchives(ctx context.Context) ([]*Archive, error) {
+	cur, err := m.archivesCol.Find(ctx, bson.M{}, options.Find().SetSort(bson.D{{Key: "archived_at", Value: 1}, {Key: "id", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	var out []*Archive
+	if err := cur.All(ctx, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+//
+// ---------- Policy snapshots ----------
+//
+
+func (m *MongoStore) SavePolicySnapshot(ctx context.Context, s *PolicySnapshot) error {
+	if s.ID == "" {
+		s.ID = generateID(m.ids, KindPolicySnapshot)
+	}
+	_, err := m.snapshotsCol.ReplaceOne(ctx, bson.M{"id": s.ID}, s, options.Replace().SetUpsert(true))
+	return err
+}
+
+func (m *MongoStore) GetPolicySnapshot(ctx context.Context, id string) (*PolicySnapshot, error) {
+	var doc PolicySnapshot
+	err := m.snapshotsCol.FindOne(ctx, bson.M{"id": id}).Decode(&doc)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &doc, nil
+}
+
+func (m *MongoStore) ListPolicySnapshots(ctx context.Context) ([]*PolicySnapshot, error) {
+	cur, err := m.snapshotsCol.Find(ctx, bson.M{}, options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}, {Key: "id", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	var out []*PolicySnapshot
+	if err := cur.All(ctx, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+//
+// ---------- API keys ----------
+//
+
+func (m *MongoStore) SaveAPIKey(ctx context.Context, k *APIKey) error {
+	if k.ID == "" {
+		k.ID = generateID(m.ids, KindAPIKey)
+	}
+	_, err := m.apiKeysCol.ReplaceOne(ctx, bson.M{"id": k.ID}, k, options.Replace().SetUpsert(true))
+	return err
+}
+
+func (m *MongoStore) GetAPIKey(ctx context.Context, id string) (*APIKey, error) {
+	var doc APIKey
+	err := m.apiKeysCol.FindOne(ctx, bson.M{"id": id}).Decode(&doc)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &doc, nil
+}
+
+func (m *MongoStore) ListAPIKeys(ctx context.Context, principalID string) ([]*APIKey, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}, {Key: "id", Value: 1}})
+	cur, err := m.apiKeysCol.Find(ctx, bson.M{"principal_id": principalID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	var out []*APIKey
+	if err := cur.All(ctx, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+//
+// ---------- Sessions ----------
+//
+
+func (m *MongoStore) SaveSession(ctx context.Context, s *Session) error {
+	if s.ID == "" {
+		s.ID = generateID(m.ids, KindSession)
+	}
+	_, err := m.sessionsCol.ReplaceOne(ctx, bson.M{"id": s.ID}, s, options.Replace().SetUpsert(true))
+	return err
+}
+
+func (m *MongoStore) GetSession(ctx context.Context, id string) (*Session, error) {
+	var doc Session
+	err := m.sessionsCol.FindOne(ctx, bson.M{"id": id}).Decode(&doc)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &doc, nil
+}
+
+func (m *MongoStore) DeleteSession(ctx context.Context, id string) error {
+	_, err := m.sessionsCol.DeleteOne(ctx, bson.M{"id": id})
+	return err
+}
+
+//
+// ---------- SoD constraints ----------
+//
+
+func (m *MongoStore) CreateSoDConstraint(ctx context.Context, c *SoDConstraint) error {
+	if c.ID == "" {
+		c.ID = generateID(m.ids, KindSoDConstraint)
+	}
+	_, err := m.sodCol.InsertOne(ctx, c)
+	return err
+}
+
+func (m *MongoStore) GetSoDConstraint(ctx context.Context, id string) (*SoDConstraint, error) {
+	var doc SoDConstraint
+	err := m.sodCol.FindOne(ctx, bson.M{"id": id}).Decode(&doc)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &doc, nil
+}
+
+func (m *MongoStore) ListSoDConstraints(ctx context.Context) ([]*SoDConstraint, error) {
+	cur, err := m.sodCol.Find(ctx, bson.M{}, options.Find().SetSort(bson.D{{Key: "id", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	var out []*SoDConstraint
+	if err := cur.All(ctx, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (m *MongoStore) DeleteSoDConstraint(ctx context.Context, id string) error {
+	_, err := m.sodCol.DeleteOne(ctx, bson.M{"id": id})
+	return err
+}
+
+//
+// ---------- Assignment requests ----------
+//
+
+func (m *MongoStore) SaveAssignmentRequest(ctx context.Context, r *AssignmentRequest) error {
+	if r.ID == "" {
+		r.ID = generateID(m.ids, KindAssignmentRequest)
+	}
+	_, err := m.requestsCol.ReplaceOne(ctx, bson.M{"id": r.ID}, r, options.Replace().SetUpsert(true))
+	return err
+}
+
+func (m *MongoStore) GetAssignmentRequest(ctx context.Context, id string) (*AssignmentRequest, error) {
+	var doc AssignmentRequest
+	err := m.requestsCol.FindOne(ctx, bson.M{"id": id}).Decode(&doc)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &doc, nil
+}
+
+func (m *MongoStore) ListAssignmentRequests(ctx context.Context, status AssignmentStatus) ([]*AssignmentRequest, error) {
+	filter := bson.M{}
+	if status != "" {
+		filter["status"] = status
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}, {Key: "id", Value: 1}})
+	cur, err := m.requestsCol.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	var out []*AssignmentRequest
+	if err := cur.All(ctx, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+//
+// ---------- Delegations ----------
+//
+
+func (m *MongoStore) SaveDelegation(ctx context.Context, d *Delegation) error {
+	if d.ID == "" {
+		d.ID = generateID(m.ids, KindDelegation)
+	}
+	_, err := m.delegCol.ReplaceOne(ctx, bson.M{"id": d.ID}, d, options.Replace().SetUpsert(true))
+	return err
+}
+
+func (m *MongoStore) GetDelegation(ctx context.Context, id string) (*Delegation, error) {
+	var doc Delegation
+	err := m.delegCol.FindOne(ctx, bson.M{"id": id}).Decode(&doc)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &doc, nil
+}
+
+func (m *MongoStore) DeleteDelegation(ctx context.Context, id string) error {
+	_, err := m.delegCol.DeleteOne(ctx, bson.M{"id": id})
+	return err
+}
+
+func (m *MongoStore) ListDelegations(ctx context.Context, userID string) ([]*Delegation, error) {
+	filter := bson.M{"$or": bson.A{bson.M{"from_user_id": userID}, bson.M{"to_user_id": userID}}}
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}, {Key: "id", Value: 1}})
+	cur, err := m.delegCol.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	var out []*Delegation
+	if err := cur.All(ctx, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+//
+// ---------- Audit log ----------
+//
+
+func (m *MongoStore) AppendAudit(ctx context.Context, e *AuditEntry) error {
+	if e.ID == "" {
+		e.ID = generateID(m.ids, KindAuditEntry)
+	}
+	_, err := m.auditCol.InsertOne(ctx, e)
+	return err
+}
+
+func (m *MongoStore) ListAudit(ctx context.Context, q AuditQuery) ([]*AuditEntry, error) {
+	filter := bson.M{}
+	if q.Actor != "" {
+		filter["actor"] = q.Actor
+	}
+	if q.Target != "" {
+		filter["target"] = q.Target
+	}
+	at := bson.M{}
+	if !q.Since.IsZero() {
+		at["$gte"] = q.Since
+	}
+	if !q.Until.IsZero() {
+		at["$lt"] = q.Until
+	}
+	if len(at) > 0 {
+		filter["at"] = at
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "at", Value: 1}, {Key: "id", Value: 1}})
+	if q.Limit > 0 {
+		opts.SetLimit(int64(q.Limit))
+	}
+	cur, err := m.auditCol.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	var out []*AuditEntry
+	if err := cur.All(ctx, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+//
+// ---------- Permission sets ----------
+//
+
+func (m *MongoStore) SavePermissionSet(ctx context.Context, s *PermissionSet) error {
+	if s.ID == "" {
+		s.ID = generateID(m.ids, KindPermissionSet)
+	}
+	_, err := m.permSetCol.ReplaceOne(ctx, bson.M{"id": s.ID}, s, options.Replace().SetUpsert(true))
+	return err
+}
+
+func (m *MongoStore) GetPermissionSet(ctx context.Context, id string) (*PermissionSet, error) {
+	var doc PermissionSet
+	err := m.permSetCol.FindOne(ctx, bson.M{"id": id}).Decode(&doc)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &doc, nil
+}
+
+func (m *MongoStore) ListPermissionSets(ctx context.Context) ([]*PermissionSet, error) {
+	cur, err := m.permSetCol.Find(ctx, bson.M{}, options.Find().SetSort(bson.D{{Key: "id", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	var out []*PermissionSet
+	if err := cur.All(ctx, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (m *MongoStore) DeletePermissionSet(ctx context.Context, id string) error {
+	if _, err := m.roleSetCol.DeleteMany(ctx, bson.M{"set_id": id}); err != nil {
+		return err
+	}
+	_, err := m.permSetCol.DeleteOne(ctx, bson.M{"id": id})
+	return err
+}
+
+func (m *MongoStore) AddSetToRole(ctx context.Context, roleID, setID string) error {
+	doc := mongoRolePermissionSet{RoleID: roleID, SetID: setID, CreatedAt: time.Now().Unix()}
+	_, err := m.roleSetCol.InsertOne(ctx, doc)
+	if mongo.IsDuplicateKeyError(err) {
+		return nil
+	}
+	return err
+}
+
+func (m *MongoStore) RemoveSetFromRole(ctx context.Context, roleID, setID string) error {
+	_, err := m.roleSetCol.DeleteOne(ctx, bson.M{"role_id": roleID, "set_id": setID})
+	return err
+}
+
+func (m *MongoStore) ListSetsForRole(ctx context.Context, roleID string) ([]string, error) {
+	var recs []mongoRolePermissionSet
+	if err := findAll(ctx, m.roleSetCol, bson.M{"role_id": roleID}, &recs); err != nil {
+		return nil, err
+	}
+	out := make([]string, 0, len(recs))
+	for _, rec := range recs {
+		out = append(out, rec.SetID)
+	}
+	return out, nil
+}
+
+//
+// ---------- Tenants ----------
+//
+
+func (m *MongoStore) CreateTenant(ctx context.Context, t *Tenant) error {
+	if t.ID == "" {
+		t.ID = generateID(m.ids, KindTenant)
+	}
+	if t.CreatedAt == 0 {
+		t.CreatedAt = time.Now().Unix()
+	}
+
+	_, err := m.tenantsCol.InsertOne(ctx, t)
+	return err
+}
+
+func (m *MongoStore) DeleteTenant(ctx context.Context, id string) error {
+	_, err := m.tenantsCol.DeleteOne(ctx, bson.M{"id": id})
+	return err
+}
+
+func (m *MongoStore) GetTenantByID(ctx context.Context, id string) (*Tenant, error) {
+	var doc Tenant
+	err := m.tenantsCol.FindOne(ctx, bson.M{"id": id}).Decode(&doc)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &doc, nil
+}
+
+func (m *MongoStore) ListTenants(ctx context.Context) ([]*Tenant, error) {
+	var out []*Tenant
+	err := findAll(ctx, m.tenantsCol, bson.M{}, &out)
+	return out, err
+}
+
+func (m *MongoStore) ListPermissionsByTenant(ctx context.Context, tenantID string) ([]*Permission, error) {
+	var out []*Permission
+	err := findAll(ctx, m.permsCol, bson.M{"tenant_id": tenantID}, &out)
+	return out, err
+}
+
+func (m *MongoStore) ListRolesByTenant(ctx context.Context, tenantID string) ([]*Role, error) {
+	var out []*Role
+	err := findAll(ctx, m.rolesCol, bson.M{"tenant_id": tenantID}, &out)
+	return out, err
+}
+
+func (m *MongoStore) ListUsersByTenant(ctx context.Context, tenantID string) ([]*User, error) {
+	var out []*User
+	err := findAll(ctx, m.usersCol, bson.M{"tenant_id": tenantID}, &out)
+	return out, err
+}
+
+// findAll decodes every document matching filter into out, which must be a
+// pointer to a slice.
+func findAll(ctx context.Context, col *mongo.Collection, filter interface{}, out interface{}) error {
+	cur, err := col.Find(ctx, filter)
+	if err != nil {
+		return err
+	}
+	return cur.All(ctx, out)
+}
+
+//
+// ---------- ListPager ----------
+//
+
+// The pages are read in _id order, so each page is one indexed range scan
+// however far into the list it is.
+
+func (m *MongoStore) ListAllRolesPage(ctx context.Context, page PageRequest) (PageResult[*Role], error) {
+	return mongoIDPage[Role](ctx, m.rolesCol, bson.M{}, page)
+}
+
+func (m *MongoStore) ListAllPermissionsPage(ctx context.Context, page PageRequest) (PageResult[*Permission], error) {
+	return mongoIDPage[Permission](ctx, m.permsCol, bson.M{}, page)
+}
+
+func (m *MongoStore) ListAllUsersPage(ctx context.Context, page PageRequest) (PageResult[*User], error) {
+	return mongoIDPage[User](ctx, m.usersCol, bson.M{}, page)
+}
+
+func (m *MongoStore) ListPermissionsPage(ctx context.Context, roleID string, page PageRequest) (PageResult[string], error) {
+	docs, err := mongoIDPage[mongoRolePermission](ctx, m.rolePermCol, bson.M{"role_id": roleID}, page)
+	res := PageResult[string]{Items: make([]string, 0, len(docs.Items)), NextCursor: docs.NextCursor}
+	for _, d := range docs.Items {
+		res.Items = append(res.Items, d.PermissionID)
+	}
+	return res, err
+}
+
+func (m *MongoStore) GetUsersByGroupIDPage(ctx context.Context, groupName string, page PageRequest) (PageResult[*UserGroup], error) {
+	return mongoIDPage[UserGroup](ctx, m.userGroupCol, bson.M{"group_name": groupName}, page)
+}
+
+// mongoPaged decodes a document together with its _id.
+type mongoPaged[T any] struct {
+	OID primitive.ObjectID `bson:"_id"`
+	Doc T                  `bson:",inline"`
+}
+
+// mongoIDPage reads the page of documents matching filter whose _id follows
+// the cursor. One extra document is read to tell whether another page
+// exists.
+func mongoIDPage[T any](ctx context.Context, col *mongo.Collection, filter bson.M, page PageRequest) (PageResult[*T], error) {
+	if page.Cursor != "" {
+		oid, err := primitive.ObjectIDFromHex(page.Cursor)
+		if err != nil {
+			return PageResult[*T]{}, fmt.Errorf("%w: %q", ErrInvalidPageCursor, page.Cursor)
+		}
+		filter["_id"] = bson.M{"$gt": oid}
+	}
+	limit := page.limit()
+	opts := options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}).SetLimit(int64(limit + 1))
+	cur, err := col.Find(ctx, filter, opts)
+	if err != nil {
+		return PageResult[*T]{}, err
+	}
+	var docs []*mongoPaged[T]
+	if err := cur.All(ctx, &docs); err != nil {
+		return PageResult[*T]{}, err
+	}
+
+	res := PageResult[*T]{Items: make([]*T, 0, len(docs))}
+	if len(docs) > limit {
+		docs = docs[:limit]
+		res.NextCursor = docs[limit-1].OID.Hex()
+	}
+	for _, d := range docs {
+		res.Items = append(res.Items, &d.Doc)
+	}
+	return res, nil
+}
+
+//
+// ---------- Search ----------
+//
+
+// SearchPage matches names with a case-insensitive regex over the pages of
+// ListPager. A prefix search is anchored, but the case folding still keeps
+// it from using the name indexes.
+func (m *MongoStore) SearchPage(ctx context.Context, kind string, q SearchQuery, page PageRequest) (PageResult[*SearchHit], error) {
+	pattern := regexp.QuoteMeta(q.Text)
+	if q.Prefix {
+		pattern = "^" + pattern
+	}
+	match := primitive.Regex{Pattern: pattern, Options: "i"}
+	live := bson.M{"$exists": false}
+	switch kind {
+	case KindRole:
+		return mongoSearchPage[Role](ctx, m.rolesCol, kind, bson.M{"name": match, "deleted_at": live}, page)
+	case KindPermission:
+		return mongoSearchPage[Permission](ctx, m.permsCol, kind, bson.M{"resource": match, "deleted_at": live}, page)
+	case KindUser:
+		return mongoSearchPage[User](ctx, m.usersCol, kind, bson.M{"username": match}, page)
+	case KindGroup:
+		return mongoSearchPage[Group](ctx, m.groupsCol, kind, bson.M{"name": match}, page)
+	}
+	return PageResult[*SearchHit]{}, fmt.Errorf("%w: kind %q", ErrInvalidSearch, kind)
+}
+
+func mongoSearchPage[T any](ctx context.Context, col *mongo.Collection, kind string, filter bson.M, page PageRequest) (PageResult[*SearchHit], error) {
+	docs, err := mongoIDPage[T](ctx, col, filter, page)
+	res := PageResult[*SearchHit]{Items: make([]*SearchHit, 0, len(docs.Items)), NextCursor: docs.NextCursor}
+	for _, d := range docs.Items {
+		res.Items = append(res.Items, newSearchHit(kind, d))
+	}
+	return res, err
+}
+
+//
+// ---------- Export ----------
+//
+
+func (m *MongoStore) ExportPage(ctx context.Context, kind, after string, limit int) ([]ExportItem, error) {
+	switch kind {
+	case KindPermission:
+		docs, err := mongoPage[Permission](ctx, m.permsCol, after, limit, "id")
+		return exportEntities(docs, func(p *Permission) string { return p.ID }), err
+	case KindRole:
+		docs, err := mongoPage[Role](ctx, m.rolesCol, after, limit, "id")
+		return exportEntities(docs, func(r *Role) string { return r.ID }), err
+	case KindUser:
+		docs, err := mongoPage[User](ctx, m.usersCol, after, limit, "id")
+		return exportEntities(docs, func(u *User) string { return u.ID }), err
+	case KindUserGroup:
+		docs, err := mongoPage[UserGroup](ctx, m.userGroupCol, after, limit, "user_id", "group_name")
+		return exportEntities(docs, func(ug *UserGroup) string { return ug.UserID + ExportKeySep + ug.GroupName }), err
+	case KindRolePermission:
+		docs, err := mongoPage[mongoRolePermission](ctx, m.rolePermCol, after, limit, "role_id", "permission_id")
+		return exportEdges(docs, func(d *mongoRolePermission) (string, string) { return d.RoleID, d.PermissionID }), err
+	case KindUserRole:
+		docs, err := mongoPage[mongoUserRole](ctx, m.userRoleCol, after, limit, "user_id", "role_id")
+		return exportEdges(docs, func(d *mongoUserRole) (string, string) { return d.UserID, d.RoleID }), err
+	case KindGroupRole:
+		docs, err := mongoPage[mongoGroupRole](ctx, m.groupRoleCol, after, limit, "group_name", "role_id")
+		return exportEdges(docs, func(d *mongoGroupRole) (string, string) { return d.GroupName, d.RoleID }), err
+	}
+	return nil, fmt.Errorf("mongo_store: unknown export kind %q", kind)
+}
+
+// mongoPage reads up to limit documents ordered by the key fields, starting
+// after the key after (the fields' values joined with ExportKeySep).
+func mongoPage[T any](ctx context.Context, col *mongo.Collection, after string, limit int, fields ...string) ([]*T, error) {
+	filter := bson.M{}
+	if after != "" {
+		if len(fields) == 1 {
+			filter[fields[0]] = bson.M{"$gt": after}
+		} else {
+			a, b := splitExportKey(after)
+			filter["$or"] = bson.A{
+				bson.M{fields[0]: bson.M{"$gt": a}},
+				bson.M{fields[0]: a, fields[1]: bson.M{"$gt": b}},
+			}
+		}
+	}
+	sort := bson.D{}
+	for _, f := range fields {
+		sort = append(sort, bson.E{Key: f, Value: 1})
+	}
+	cur, err := col.Find(ctx, filter, options.Find().SetSort(sort).SetLimit(int64(limit)))
+	if err != nil {
+		return nil, err
+	}
+	var docs []*T
+	if err := cur.All(ctx, &docs); err != nil {
+		return nil, err
+	}
+	return docs, nil
+}
+
+//
+// ---------- Change streams ----------
+//
+
+// mongoChange is the part of a change stream event Watch needs.
+type mongoChange struct {
+	OperationType string              `bson:"operationType"`
+	ClusterTime   primitive.Timestamp `bson:"clusterTime"`
+	NS            struct {
+		Coll string `bson:"coll"`
+	} `bson:"ns"`
+	FullDocument             *mongoChangeKeys `bson:"fullDocument"`
+	FullDocumentBeforeChange *mongoChangeKeys `bson:"fullDocumentBeforeChange"`
+}
+
+// mongoChangeKeys picks the identifying fields out of any stored document.
+type mongoChangeKeys struct {
+	ID           string `bson:"id"`
+	UserID       string `bson:"user_id"`
+	RoleID       string `bson:"role_id"`
+	PermissionID string `bson:"permission_id"`
+	GroupName    string `bson:"group_name"`
+}
+
+// Watch streams changes to the store's collections from a change stream,
+// which needs a replica set or sharded cluster. Watch asks the server to
+// record pre-images so deletes carry the removed record's IDs; where that is
+// not possible (MongoDB before 6.0, or without collMod rights) delete events
+// only have Kind and Op set, and consumers should invalidate everything of
+// that kind.
+func (m *MongoStore) Watch(ctx context.Context) (<-chan ChangeEvent, error) {
+	db := m.rolesCol.Database()
+	kinds := map[string]string{
+		m.permsCol.Name():     KindPermission,
+		m.rolesCol.Name():     KindRole,
+		m.usersCol.Name():     KindUser,
+		m.tenantsCol.Name():   KindTenant,
+		m.groupsCol.Name():    KindGroup,
+		m.rolePermCol.Name():  KindRolePermission,
+		m.userRoleCol.Name():  KindUserRole,
+		m.userGroupCol.Name(): KindUserGroup,
+		m.groupRoleCol.Name(): KindGroupRole,
+	}
+	names := make(bson.A, 0, len(kinds))
+	for name := range kinds {
+		names = append(names, name)
+		// Best effort: without pre-images deletes lose their IDs.
+		_ = db.RunCommand(ctx, bson.D{
+			{Key: "collMod", Value: name},
+			{Key: "changeStreamPreAndPostImages", Value: bson.D{{Key: "enabled", Value: true}}},
+		}).Err()
+	}
+
+	pipeline := mongo.Pipeline{{{Key: "$match", Value: bson.D{{Key: "ns.coll", Value: bson.D{{Key: "$in", Value: names}}}}}}}
+	opts := options.ChangeStream().
+		SetFullDocument(options.UpdateLookup).
+		SetFullDocumentBeforeChange(options.WhenAvailable)
+	cs, err := db.Watch(ctx, pipeline, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(chan ChangeEvent)
+	go func() {
+		defer close(out)
+		defer cs.Close(context.Background())
+		for cs.Next(ctx) {
+			var raw mongoChange
+			if err := cs.Decode(&raw); err != nil {
+				return
+			}
+			ce, ok := mongoChangeEvent(kinds[raw.NS.Coll], &raw)
+			if !ok {
+				continue
+			}
+			select {
+			case out <- ce:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out, nil
+}
+
+// mongoChangeEvent maps a change stream event onto a ChangeEvent, reporting
+// false for operations that do not change a record.
+func mongoChangeEvent(kind string, raw *mongoChange) (ChangeEvent, bool) {
+	ce := ChangeEvent{Kind: kind}
+	if raw.ClusterTime.T != 0 {
+		ce.At = time.Unix(int64(raw.ClusterTime.T), 0)
+	}
+	switch raw.OperationType {
+	case "insert":
+		ce.Op = ChangeCreate
+	case "update", "replace":
+		ce.Op = ChangeUpdate
+	case "delete":
+		ce.Op = ChangeDelete
+	default:
+		return ChangeEvent{}, false
+	}
+	if kind == "" {
+		return ChangeEvent{}, false
+	}
+
+	doc := raw.FullDocument
+	if doc == nil {
+		doc = raw.FullDocumentBeforeChange
+	}
+	if doc == nil {
+		return ce, true
+	}
+	switch kind {
+	case KindRolePermission:
+		ce.RoleID, ce.PermissionID = doc.RoleID, doc.PermissionID
+	case KindUserRole:
+		ce.UserID, ce.RoleID = doc.UserID, doc.RoleID
+	case KindUserGroup:
+		ce.UserID, ce.GroupName = doc.UserID, doc.GroupName
+	case KindGroupRole:
+		ce.GroupName, ce.RoleID = doc.GroupName, doc.RoleID
+	default:
+		ce.ID = doc.ID
+	}
+	return ce, true
+}
@@ -0,0 +1,64 @@
+package rbac
+
+import (
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestMongoChangeEvent(t *testing.T) {
+	cases := []struct {
+		name string
+		kind string
+		raw  mongoChange
+		want ChangeEvent
+		ok   bool
+	}{
+		{
+			name: "entity insert",
+			kind: KindRole,
+			raw:  mongoChange{OperationType: "insert", FullDocument: &mongoChangeKeys{ID: "r1"}},
+			want: ChangeEvent{Kind: KindRole, Op: ChangeCreate, ID: "r1"},
+			ok:   true,
+		},
+		{
+			name: "join delete with pre-image",
+			kind: KindRolePermission,
+			raw:  mongoChange{OperationType: "delete", FullDocumentBeforeChange: &mongoChangeKeys{RoleID: "r1", PermissionID: "p1"}},
+			want: ChangeEvent{Kind: KindRolePermission, Op: ChangeDelete, RoleID: "r1", PermissionID: "p1"},
+			ok:   true,
+		},
+		{
+			name: "delete without pre-image",
+			kind: KindUserGroup,
+			raw:  mongoChange{OperationType: "delete"},
+			want: ChangeEvent{Kind: KindUserGroup, Op: ChangeDelete},
+			ok:   true,
+		},
+		{
+			name: "update",
+			kind: KindGroupRole,
+			raw:  mongoChange{OperationType: "replace", FullDocument: &mongoChangeKeys{GroupName: "staff", RoleID: "r1"}},
+			want: ChangeEvent{Kind: KindGroupRole, Op: ChangeUpdate, GroupName: "staff", RoleID: "r1"},
+			ok:   true,
+		},
+		{
+			name: "cluster time",
+			kind: KindUser,
+			raw:  mongoChange{OperationType: "insert", ClusterTime: primitive.Timestamp{T: 1700000000, I: 3}, FullDocument: &mongoChangeKeys{ID: "u1"}},
+			want: ChangeEvent{Kind: KindUser, Op: ChangeCreate, ID: "u1", At: time.Unix(1700000000, 0)},
+			ok:   true,
+		},
+		{name: "drop", kind: KindRole, raw: mongoChange{OperationType: "drop"}},
+		{name: "unknown collection", raw: mongoChange{OperationType: "insert"}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, ok := mongoChangeEvent(tc.kind, &tc.raw)
+			if ok != tc.ok || got != tc.want {
+				t.Errorf("got %+v, %v; want %+v, %v", got, ok, tc.want, tc.ok)
+			}
+		})
+	}
+}
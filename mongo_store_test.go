@@ -2,7 +2,10 @@ package rbac_test
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"testing"
+	"time"
 
 	"github.com/Seann-Moser/rbac"
 	"github.com/stretchr/testify/require"
@@ -15,17 +18,29 @@ import (
 
 // Start MongoDB in a container for each test
 func startMongo(t *testing.T) (*mongo.Database, func()) {
+	return startMongoWith(t)
+}
+
+// startMongoReplicaSet starts a single-node replica set, which transactions
+// and change streams require.
+func startMongoReplicaSet(t *testing.T) (*mongo.Database, func()) {
+	return startMongoWith(t, mongodb.WithReplicaSet("rs0"))
+}
+
+func startMongoWith(t *testing.T, opts ...testcontainers.ContainerCustomizer) (*mongo.Database, func()) {
 	ctx := context.Background()
 
 	mongoC, err := mongodb.RunContainer(ctx, //nolint:staticcheck
-		testcontainers.WithImage("mongo:7"),
+		append([]testcontainers.ContainerCustomizer{testcontainers.WithImage("mongo:7")}, opts...)...,
 	)
 	require.NoError(t, err)
 
 	uri, err := mongoC.ConnectionString(ctx)
 	require.NoError(t, err)
 
-	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
+	// The replica set advertises the container's internal hostname, so
+	// connect directly rather than through member discovery.
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri).SetDirect(true))
 	require.NoError(t, err)
 
 	db := client.Database("testdb")
@@ -196,6 +211,103 @@ func TestUserGroups(t *testing.T) {
 	require.Equal(t, ug.GroupName, groups[0].GroupName)
 }
 
+func TestMongoGroupMembersPaged(t *testing.T) {
+	db, cleanup := startMongo(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	manager, err := rbac.NewMongoStoreManager(ctx, db)
+	require.NoError(t, err)
+
+	for i := range 5 {
+		require.NoError(t, manager.UG.AddUserToGroup(ctx, &rbac.UserGroup{UserID: fmt.Sprintf("user-%d", i), GroupName: "team-alpha"}))
+	}
+	require.NoError(t, manager.UG.AddUserToGroup(ctx, &rbac.UserGroup{UserID: "user-x", GroupName: "team-beta"}))
+
+	var members []string
+	page := rbac.PageRequest{Limit: 2}
+	for {
+		res, err := manager.GetUsersByGroupIDPage(ctx, "team-alpha", page)
+		require.NoError(t, err)
+		for _, ug := range res.Items {
+			members = append(members, ug.UserID)
+		}
+		if res.NextCursor == "" {
+			break
+		}
+		page.Cursor = res.NextCursor
+	}
+	require.Equal(t, []string{"user-0", "user-1", "user-2", "user-3", "user-4"}, members)
+
+	_, err = manager.GetUsersByGroupIDPage(ctx, "team-alpha", rbac.PageRequest{Cursor: "not-an-id"})
+	require.ErrorIs(t, err, rbac.ErrInvalidPageCursor)
+}
+
+func TestMongoBulkAssignments(t *testing.T) {
+	db, cleanup := startMongo(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	manager, err := rbac.NewMongoStoreManager(ctx, db)
+	require.NoError(t, err)
+
+	require.NoError(t, manager.UR.AddUR(ctx, "alice", "role-b"))
+	require.NoError(t, manager.AssignRolesToUser(ctx, "alice", []string{"role-a", "role-c"}))
+	roles, err := manager.UR.ListRoles(ctx, "alice")
+	require.NoError(t, err)
+	require.Subset(t, roles, []string{"role-a", "role-b", "role-c"})
+
+	require.NoError(t, manager.RP.AddRP(ctx, "role-a", "perm-1"))
+	require.NoError(t, manager.AssignPermissionsToRole(ctx, "role-a", []string{"perm-1", "perm-2"}))
+	perms, err := manager.RP.ListPermissions(ctx, "role-a")
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{"perm-1", "perm-2"}, perms)
+
+	require.NoError(t, manager.UG.AddUserToGroup(ctx, &rbac.UserGroup{UserID: "user-0", GroupName: "team-alpha", Level: rbac.MembershipAdmin}))
+	var ugs []*rbac.UserGroup
+	for i := range 3 {
+		ugs = append(ugs, &rbac.UserGroup{UserID: fmt.Sprintf("user-%d", i), GroupName: "team-alpha"})
+	}
+	require.NoError(t, manager.AddUsersToGroup(ctx, ugs))
+	members, err := manager.UG.GetUsersByGroupID(ctx, "team-alpha")
+	require.NoError(t, err)
+	require.Len(t, members, 3)
+	for _, ug := range members {
+		require.Empty(t, ug.Level, "re-adding a member replaces their level")
+	}
+}
+
+func TestMongoGetUserByMeta(t *testing.T) {
+	db, cleanup := startMongo(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	manager, err := rbac.NewMongoStoreManager(ctx, db)
+	require.NoError(t, err)
+	require.NoError(t, manager.IndexUserMeta(ctx, "team", "address.city"))
+
+	alice := &rbac.User{Username: "alice", Meta: map[string]interface{}{"team": "core", "address": map[string]interface{}{"city": "Oslo"}}}
+	bob := &rbac.User{Username: "bob", Meta: map[string]interface{}{"team": "infra"}}
+	require.NoError(t, manager.CreateUser(ctx, alice))
+	require.NoError(t, manager.CreateUser(ctx, bob))
+
+	got, err := manager.Users.GetUserByMeta(ctx, map[string]interface{}{"meta.address.city": "Oslo"})
+	require.NoError(t, err)
+	require.NotNil(t, got)
+	require.Equal(t, alice.ID, got.ID)
+
+	got, err = manager.Users.GetUserByMeta(ctx, map[string]interface{}{
+		"username":  "bob",
+		"meta.team": map[string]interface{}{"$in": []interface{}{"core", "infra"}},
+	})
+	require.NoError(t, err)
+	require.NotNil(t, got)
+	require.Equal(t, bob.ID, got.ID)
+
+	_, err = manager.Users.GetUserByMeta(ctx, map[string]interface{}{"meta.team": map[string]interface{}{"$ne": "core"}})
+	require.ErrorIs(t, err, rbac.ErrInvalidUserFilter)
+}
+
 //
 // ────────────────────────────────────────────────
 //   UNIQUE INDEX ENFORCEMENT
@@ -226,6 +338,40 @@ func TestUniqueIndexes_UserRoles(t *testing.T) {
 	require.Error(t, err)
 }
 
+func TestMongoCallerIDs(t *testing.T) {
+	db, cleanup := startMongo(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	mgr, err := rbac.NewMongoStoreManager(ctx, db)
+	require.NoError(t, err)
+
+	u := &rbac.User{ID: "3f1c9a52-7d0e-4b8e-9a57-2b6f0c1d4e90", Username: "kali", Email: "k@example.com"}
+	require.NoError(t, mgr.Users.CreateUser(ctx, u))
+	got, err := mgr.Users.GetUserByID(ctx, u.ID)
+	require.NoError(t, err)
+	require.NotNil(t, got)
+	require.Equal(t, "kali", got.Username)
+
+	r := &rbac.Role{ID: "role-ops", Name: "ops"}
+	require.NoError(t, mgr.Roles.CreateRole(ctx, r))
+	require.Equal(t, "role-ops", r.ID)
+	require.NoError(t, mgr.UR.AddUR(ctx, u.ID, r.ID))
+	roles, err := mgr.UR.ListRoles(ctx, u.ID)
+	require.NoError(t, err)
+	require.Contains(t, roles, "role-ops")
+
+	p := &rbac.Permission{ID: "perm-docs-read", Resource: "docs", Action: rbac.ActionRead}
+	require.NoError(t, mgr.Perms.CreatePermission(ctx, p))
+	gotPerm, err := mgr.Perms.GetPermissionByID(ctx, "perm-docs-read")
+	require.NoError(t, err)
+	require.NotNil(t, gotPerm)
+
+	// A caller ID that is already taken is rejected.
+	require.Error(t, mgr.Roles.CreateRole(ctx, &rbac.Role{ID: "role-ops", Name: "ops-2"}))
+	require.Error(t, mgr.Users.CreateUser(ctx, &rbac.User{ID: u.ID, Username: "kali2", Email: "k2@example.com"}))
+}
+
 //
 // ────────────────────────────────────────────────
 //   DEFAULT ROLE CREATION
@@ -245,3 +391,90 @@ func TestDefaultRoleInitialization(t *testing.T) {
 	require.NoError(t, err)
 	require.NotNil(t, def)
 }
+
+//
+// ────────────────────────────────────────────────
+//   TRANSACTIONS
+// ────────────────────────────────────────────────
+//
+
+func TestMongoTransaction(t *testing.T) {
+	db, cleanup := startMongoReplicaSet(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	manager, err := rbac.NewMongoStoreManager(ctx, db)
+	require.NoError(t, err)
+
+	perm := &rbac.Permission{Resource: "reports", Action: rbac.ActionRead}
+	require.NoError(t, manager.CreatePermission(ctx, perm))
+
+	// Everything commits together.
+	role := &rbac.Role{Name: "analyst"}
+	require.NoError(t, manager.WithTransaction(ctx, func(ctx context.Context) error {
+		if err := manager.CreateRole(ctx, role); err != nil {
+			return err
+		}
+		if err := manager.AssignPermissionToRole(ctx, role.ID, perm.ID); err != nil {
+			return err
+		}
+		return manager.AssignRoleToGroup(ctx, "finance", role.ID)
+	}))
+	perms, err := manager.ListPermissionsForRole(ctx, role.ID)
+	require.NoError(t, err)
+	require.Equal(t, []string{perm.ID}, perms)
+
+	// A failing step rolls back the earlier ones.
+	errAbort := errors.New("abort")
+	rolledBack := &rbac.Role{Name: "auditor"}
+	err = manager.WithTransaction(ctx, func(ctx context.Context) error {
+		if err := manager.CreateRole(ctx, rolledBack); err != nil {
+			return err
+		}
+		return errAbort
+	})
+	require.ErrorIs(t, err, errAbort)
+	got, err := manager.Roles.GetRoleByName(ctx, "auditor")
+	require.NoError(t, err)
+	require.Nil(t, got)
+}
+
+//
+// ────────────────────────────────────────────────
+//   CHANGE STREAMS
+// ────────────────────────────────────────────────
+//
+
+func TestMongoWatch(t *testing.T) {
+	db, cleanup := startMongoReplicaSet(t)
+	defer cleanup()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	store, err := rbac.NewMongoStore(ctx, db)
+	require.NoError(t, err)
+
+	events, err := store.Watch(ctx)
+	require.NoError(t, err)
+
+	role := &rbac.Role{Name: "watcher"}
+	require.NoError(t, store.CreateRole(ctx, role))
+	require.NoError(t, store.AddUR(ctx, "alice", role.ID))
+	require.NoError(t, store.RemoveUR(ctx, "alice", role.ID))
+
+	want := []rbac.ChangeEvent{
+		{Kind: rbac.KindRole, Op: rbac.ChangeCreate, ID: role.ID},
+		{Kind: rbac.KindUserRole, Op: rbac.ChangeCreate, UserID: "alice", RoleID: role.ID},
+		{Kind: rbac.KindUserRole, Op: rbac.ChangeDelete, UserID: "alice", RoleID: role.ID},
+	}
+	for _, w := range want {
+		select {
+		case got := <-events:
+			require.False(t, got.At.IsZero())
+			got.At = time.Time{}
+			require.Equal(t, w, got)
+		case <-time.After(10 * time.Second):
+			t.Fatalf("timed out waiting for %+v", w)
+		}
+	}
+}
@@ -9,19 +9,26 @@ import (
 	"strings"
 	"time"
 
-	_ "github.com/go-sql-driver/mysql"
-	"github.com/google/uuid"
+	"github.com/go-sql-driver/mysql"
 )
 
 // Ensure MySQLStore implements all interfaces:
 var (
-	_ PermissionRepo     = (*MySQLStore)(nil)
-	_ RoleRepo           = (*MySQLStore)(nil)
-	_ UserRepo           = (*MySQLStore)(nil)
-	_ RolePermissionRepo = (*MySQLStore)(nil)
-	_ UserRoleRepo       = (*MySQLStore)(nil)
-	_ UserGroupRepo      = (*MySQLStore)(nil)
-	_ GroupRoleRepo      = (*MySQLStore)(nil)
+	_ PermissionRepo           = (*MySQLStore)(nil)
+	_ RoleRepo                 = (*MySQLStore)(nil)
+	_ UserRepo                 = (*MySQLStore)(nil)
+	_ UserStatusRepo           = (*MySQLStore)(nil)
+	_ EmailVerificationRepo    = (*MySQLStore)(nil)
+	_ RolePermissionRepo       = (*MySQLStore)(nil)
+	_ UserRoleRepo             = (*MySQLStore)(nil)
+	_ UserGroupRepo            = (*MySQLStore)(nil)
+	_ GroupRoleRepo            = (*MySQLStore)(nil)
+	_ ExpiringMembershipLister = (*MySQLStore)(nil)
+	_ ExportPager              = (*MySQLStore)(nil)
+	_ AuditRepo                = (*MySQLStore)(nil)
+	_ BulkUserRoleRepo         = (*MySQLStore)(nil)
+	_ BulkRolePermissionRepo   = (*MySQLStore)(nil)
+	_ HealthChecker            = (*MySQLStore)(nil)
 )
 
 //
@@ -29,7 +36,8 @@ var (
 //
 
 type MySQLStore struct {
-	db *sql.DB
+	db  *sql.DB
+	ids IDGenerator
 }
 
 // NewMySQLStore creates the store and ensures the schema is in place.
@@ -41,6 +49,19 @@ func NewMySQLStore(ctx context.Context, db *sql.DB) (*MySQLStore, error) {
 	return s, nil
 }
 
+// SetIDGenerator changes how the store generates IDs for new entities.
+func (s *MySQLStore) SetIDGenerator(g IDGenerator) {
+	s.ids = g
+}
+
+// Health implements HealthChecker by pinging the database.
+func (s *MySQLStore) Health(ctx context.Context) error {
+	if err := s.db.PingContext(ctx); err != nil {
+		return fmt.Errorf("mysql_store: %w", err)
+	}
+	return nil
+}
+
 // NewMySQLStoreManager wraps the store in a Manager and seeds the default role.
 func NewMySQLStoreManager(ctx context.Context, db *sql.DB) (*Manager, error) {
 	s, err := NewMySQLStore(ctx, db)
@@ -76,10 +97,19 @@ func (s *MySQLStore) EnsureSchema(ctx context.Context) error {
 	stmts := []string{
 		`CREATE SCHEMA IF NOT EXISTS rbacv2;`,
 		`CREATE TABLE IF NOT EXISTS rbacv2.permissions (
-			id          VARCHAR(36)  NOT NULL PRIMARY KEY,
-			resource    VARCHAR(255) NOT NULL,
-			action      VARCHAR(64)  NOT NULL,
-			created_at  BIGINT       NOT NULL DEFAULT 0,
+			id             VARCHAR(36)  NOT NULL PRIMARY KEY,
+			name           VARCHAR(255) NOT NULL DEFAULT '',
+			description    TEXT         NOT NULL,
+			labels         TEXT         NOT NULL,
+			resource       VARCHAR(255) NOT NULL,
+			action         VARCHAR(64)  NOT NULL,
+			effect         VARCHAR(16)  NOT NULL DEFAULT '',
+			condition_expr TEXT         NOT NULL,
+			created_at     BIGINT       NOT NULL DEFAULT 0,
+			updated_at     BIGINT       NOT NULL DEFAULT 0,
+			created_by     VARCHAR(255) NOT NULL DEFAULT '',
+			updated_by     VARCHAR(255) NOT NULL DEFAULT '',
+			tenant_id      VARCHAR(255) NOT NULL DEFAULT '',
 			CONSTRAINT uq_permissions_resource_action UNIQUE (resource, action)
 		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4`,
 
@@ -87,7 +117,15 @@ func (s *MySQLStore) EnsureSchema(ctx context.Context) error {
 			id          VARCHAR(36)  NOT NULL PRIMARY KEY,
 			name        VARCHAR(255) NOT NULL,
 			description TEXT         NOT NULL,
+			meta        TEXT         NOT NULL,
+			template    BOOLEAN      NOT NULL DEFAULT FALSE,
+			priority    INT          NOT NULL DEFAULT 0,
+			generators  TEXT         NOT NULL,
 			created_at  BIGINT       NOT NULL DEFAULT 0,
+			updated_at  BIGINT       NOT NULL DEFAULT 0,
+			created_by  VARCHAR(255) NOT NULL DEFAULT '',
+			updated_by  VARCHAR(255) NOT NULL DEFAULT '',
+			tenant_id   VARCHAR(255) NOT NULL DEFAULT '',
 			CONSTRAINT uq_roles_name UNIQUE (name)
 		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4`,
 
@@ -95,7 +133,13 @@ func (s *MySQLStore) EnsureSchema(ctx context.Context) error {
 			id          VARCHAR(36)  NOT NULL PRIMARY KEY,
 			username    VARCHAR(255) NOT NULL,
 			email       VARCHAR(255) NOT NULL,
+			email_verified BOOLEAN NOT NULL DEFAULT FALSE,
+			status      VARCHAR(16)  NOT NULL DEFAULT '',
 			created_at  BIGINT       NOT NULL DEFAULT 0,
+			updated_at  BIGINT       NOT NULL DEFAULT 0,
+			created_by  VARCHAR(255) NOT NULL DEFAULT '',
+			updated_by  VARCHAR(255) NOT NULL DEFAULT '',
+			tenant_id   VARCHAR(255) NOT NULL DEFAULT '',
 			CONSTRAINT uq_users_username UNIQUE (username),
 			CONSTRAINT uq_users_email    UNIQUE (email)
 		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4`,
@@ -119,6 +163,12 @@ func (s *MySQLStore) EnsureSchema(ctx context.Context) error {
 			user_id     VARCHAR(36)  NOT NULL,
 			group_name  VARCHAR(255) NOT NULL,
 			created_at  BIGINT       NOT NULL DEFAULT 0,
+			updated_at  BIGINT       NOT NULL DEFAULT 0,
+			created_by  VARCHAR(255) NOT NULL DEFAULT '',
+			updated_by  VARCHAR(255) NOT NULL DEFAULT '',
+			level       VARCHAR(16)  NOT NULL DEFAULT '',
+			expires_at  BIGINT       NOT NULL DEFAULT 0,
+			tenant_id   VARCHAR(255) NOT NULL DEFAULT '',
 			CONSTRAINT uq_user_groups UNIQUE (user_id, group_name)
 		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4`,
 
@@ -128,6 +178,22 @@ func (s *MySQLStore) EnsureSchema(ctx context.Context) error {
 			created_at  BIGINT       NOT NULL DEFAULT 0,
 			PRIMARY KEY (group_name, role_id)
 		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4`,
+
+		`CREATE TABLE IF NOT EXISTS rbacv2.audit_log (
+			id        VARCHAR(36)  NOT NULL PRIMARY KEY,
+			at        BIGINT       NOT NULL,
+			actor     VARCHAR(255) NOT NULL DEFAULT '',
+			kind      VARCHAR(16)  NOT NULL,
+			method    VARCHAR(64)  NOT NULL,
+			target    VARCHAR(255) NOT NULL DEFAULT '',
+			resource  VARCHAR(255) NOT NULL DEFAULT '',
+			action    VARCHAR(64)  NOT NULL DEFAULT '',
+			outcome   VARCHAR(16)  NOT NULL,
+			error_msg TEXT         NOT NULL,
+			INDEX audit_log_by_actor (actor, at),
+			INDEX audit_log_by_target (target, at),
+			INDEX audit_log_by_at (at)
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4`,
 	}
 
 	for _, stmt := range stmts {
@@ -135,19 +201,70 @@ func (s *MySQLStore) EnsureSchema(ctx context.Context) error {
 			return err
 		}
 	}
+
+	// Columns and indexes added after the first release; MySQL has no ADD
+	// COLUMN IF NOT EXISTS, so a duplicate column or index means the table is
+	// already current.
+	migrations := []string{
+		`ALTER TABLE rbacv2.permissions ADD COLUMN effect VARCHAR(16) NOT NULL DEFAULT '' AFTER action`,
+		`ALTER TABLE rbacv2.permissions ADD COLUMN condition_expr TEXT NOT NULL AFTER effect`,
+		`ALTER TABLE rbacv2.roles ADD COLUMN meta TEXT NOT NULL AFTER description`,
+		`ALTER TABLE rbacv2.permissions ADD COLUMN updated_at BIGINT NOT NULL DEFAULT 0 AFTER created_at`,
+		`ALTER TABLE rbacv2.permissions ADD COLUMN created_by VARCHAR(255) NOT NULL DEFAULT '' AFTER updated_at`,
+		`ALTER TABLE rbacv2.permissions ADD COLUMN updated_by VARCHAR(255) NOT NULL DEFAULT '' AFTER created_by`,
+		`ALTER TABLE rbacv2.roles ADD COLUMN updated_at BIGINT NOT NULL DEFAULT 0 AFTER created_at`,
+		`ALTER TABLE rbacv2.roles ADD COLUMN created_by VARCHAR(255) NOT NULL DEFAULT '' AFTER updated_at`,
+		`ALTER TABLE rbacv2.roles ADD COLUMN updated_by VARCHAR(255) NOT NULL DEFAULT '' AFTER created_by`,
+		`ALTER TABLE rbacv2.roles ADD COLUMN template BOOLEAN NOT NULL DEFAULT FALSE AFTER meta`,
+		`ALTER TABLE rbacv2.roles ADD COLUMN priority INT NOT NULL DEFAULT 0 AFTER template`,
+		`ALTER TABLE rbacv2.roles ADD COLUMN generators TEXT NOT NULL AFTER priority`,
+		`ALTER TABLE rbacv2.users ADD COLUMN updated_at BIGINT NOT NULL DEFAULT 0 AFTER created_at`,
+		`ALTER TABLE rbacv2.users ADD COLUMN created_by VARCHAR(255) NOT NULL DEFAULT '' AFTER updated_at`,
+		`ALTER TABLE rbacv2.users ADD COLUMN updated_by VARCHAR(255) NOT NULL DEFAULT '' AFTER created_by`,
+		`ALTER TABLE rbacv2.users ADD COLUMN email_verified BOOLEAN NOT NULL DEFAULT FALSE AFTER email`,
+		`ALTER TABLE rbacv2.users ADD COLUMN status VARCHAR(16) NOT NULL DEFAULT '' AFTER email_verified`,
+		`ALTER TABLE rbacv2.user_groups ADD COLUMN updated_at BIGINT NOT NULL DEFAULT 0 AFTER created_at`,
+		`ALTER TABLE rbacv2.user_groups ADD COLUMN created_by VARCHAR(255) NOT NULL DEFAULT '' AFTER updated_at`,
+		`ALTER TABLE rbacv2.user_groups ADD COLUMN updated_by VARCHAR(255) NOT NULL DEFAULT '' AFTER created_by`,
+		`ALTER TABLE rbacv2.user_groups ADD COLUMN level VARCHAR(16) NOT NULL DEFAULT '' AFTER updated_by`,
+		`ALTER TABLE rbacv2.user_groups ADD COLUMN expires_at BIGINT NOT NULL DEFAULT 0 AFTER level`,
+		`ALTER TABLE rbacv2.permissions ADD COLUMN name VARCHAR(255) NOT NULL DEFAULT '' AFTER id`,
+		`ALTER TABLE rbacv2.permissions ADD COLUMN description TEXT NOT NULL AFTER name`,
+		`ALTER TABLE rbacv2.permissions ADD COLUMN labels TEXT NOT NULL AFTER description`,
+		`ALTER TABLE rbacv2.permissions ADD COLUMN tenant_id VARCHAR(255) NOT NULL DEFAULT '' AFTER updated_by`,
+		`ALTER TABLE rbacv2.roles ADD COLUMN tenant_id VARCHAR(255) NOT NULL DEFAULT '' AFTER updated_by`,
+		`ALTER TABLE rbacv2.users ADD COLUMN tenant_id VARCHAR(255) NOT NULL DEFAULT '' AFTER updated_by`,
+		`ALTER TABLE rbacv2.user_groups ADD COLUMN tenant_id VARCHAR(255) NOT NULL DEFAULT '' AFTER expires_at`,
+		`ALTER TABLE rbacv2.user_roles ADD INDEX user_roles_by_role (role_id)`,
+		`ALTER TABLE rbacv2.group_roles ADD INDEX group_roles_by_role (role_id)`,
+		`ALTER TABLE rbacv2.user_groups ADD INDEX user_groups_by_expiry (expires_at)`,
+	}
+	for _, stmt := range migrations {
+		_, err := s.db.ExecContext(ctx, stmt)
+		var myErr *mysql.MySQLError
+		if err != nil && !(errors.As(err, &myErr) && (myErr.Number == mysqlErrDupFieldName || myErr.Number == mysqlErrDupKeyName)) {
+			return err
+		}
+	}
 	return nil
 }
 
+// mysqlErrDupFieldName is ER_DUP_FIELDNAME and mysqlErrDupKeyName
+// ER_DUP_KEYNAME, returned for a column or index that already exists.
+const (
+	mysqlErrDupFieldName = 1060
+	mysqlErrDupKeyName   = 1061
+)
+
 //
 // ---------- UserRepo ----------
 //
 
 func (s *MySQLStore) GetUserByID(ctx context.Context, id string) (*User, error) {
 	row := s.db.QueryRowContext(ctx,
-		`SELECT id, username, email, created_at FROM rbacv2.users WHERE id = ?`, id)
+		`SELECT id, username, email, email_verified, status, created_at, updated_at, created_by, updated_by, tenant_id FROM rbacv2.users WHERE id = ?`, id)
 
-	u := &User{}
-	err := row.Scan(&u.ID, &u.Username, &u.Email, &u.CreatedAt)
+	u, err := scanUser(row.Scan)
 	if errors.Is(err, sql.ErrNoRows) {
 		return nil, nil
 	}
@@ -174,13 +291,12 @@ func (s *MySQLStore) GetUserByMeta(ctx context.Context, meta map[string]interfac
 	}
 
 	query := fmt.Sprintf(
-		`SELECT id, username, email, created_at FROM rbacv2.users WHERE %s`,
+		`SELECT id, username, email, email_verified, status, created_at, updated_at, created_by, updated_by, tenant_id FROM rbacv2.users WHERE %s`,
 		strings.Join(clauses, " AND "),
 	)
 
 	row := s.db.QueryRowContext(ctx, query, args...)
-	u := &User{}
-	err := row.Scan(&u.ID, &u.Username, &u.Email, &u.CreatedAt)
+	u, err := scanUser(row.Scan)
 	if errors.Is(err, sql.ErrNoRows) {
 		return nil, nil
 	}
@@ -190,15 +306,54 @@ func (s *MySQLStore) GetUserByMeta(ctx context.Context, meta map[string]interfac
 	return u, nil
 }
 
+func (s *MySQLStore) ListAllUsers(ctx context.Context) ([]*User, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT id, username, email, email_verified, status, created_at, updated_at, created_by, updated_by, tenant_id FROM rbacv2.users`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []*User
+	for rows.Next() {
+		u, err := scanUser(rows.Scan)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode user: %w", err)
+		}
+		out = append(out, u)
+	}
+	return out, rows.Err()
+}
+
 func (s *MySQLStore) CreateUser(ctx context.Context, u *User) error {
 	if u.ID == "" {
-		u.ID = uuid.New().String()
+		u.ID = generateID(s.ids, KindUser)
 	}
 	u.CreatedAt = time.Now().Unix()
 
 	_, err := s.db.ExecContext(ctx,
-		`INSERT INTO rbacv2.users (id, username, email, created_at) VALUES (?, ?, ?, ?)`,
-		u.ID, u.Username, u.Email, u.CreatedAt)
+		`INSERT INTO rbacv2.users (id, username, email, email_verified, status, created_at, updated_at, created_by, updated_by, tenant_id) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		u.ID, u.Username, u.Email, u.EmailVerified, string(u.Status), u.CreatedAt, u.UpdatedAt, u.CreatedBy, u.UpdatedBy, u.TenantID)
+	return err
+}
+
+func (s *MySQLStore) SetUserStatus(ctx context.Context, id string, status UserStatus) error {
+	var updatedBy string
+	var updatedAt int64
+	stampUpdated(ctx, time.Now(), &updatedBy, &updatedAt)
+	_, err := s.db.ExecContext(ctx,
+		`UPDATE rbacv2.users SET status = ?, updated_at = ?, updated_by = ? WHERE id = ?`,
+		string(status), updatedAt, updatedBy, id)
+	return err
+}
+
+func (s *MySQLStore) SetEmailVerified(ctx context.Context, id string, verified bool) error {
+	var updatedBy string
+	var updatedAt int64
+	stampUpdated(ctx, time.Now(), &updatedBy, &updatedAt)
+	_, err := s.db.ExecContext(ctx,
+		`UPDATE rbacv2.users SET email_verified = ?, updated_at = ?, updated_by = ? WHERE id = ?`,
+		verified, updatedAt, updatedBy, id)
 	return err
 }
 
@@ -209,7 +364,7 @@ func (s *MySQLStore) DeleteUser(ctx context.Context, id string) error {
 
 func (s *MySQLStore) GetGroupsByUserID(ctx context.Context, userID string) ([]*UserGroup, error) {
 	rows, err := s.db.QueryContext(ctx,
-		`SELECT id, user_id, group_name, created_at FROM rbacv2.user_groups WHERE user_id = ?`, userID)
+		`SELECT id, user_id, group_name, created_at, updated_at, created_by, updated_by, level, expires_at, tenant_id FROM rbacv2.user_groups WHERE user_id = ?`, userID)
 	if err != nil {
 		return nil, err
 	}
@@ -217,8 +372,8 @@ func (s *MySQLStore) GetGroupsByUserID(ctx context.Context, userID string) ([]*U
 
 	var out []*UserGroup
 	for rows.Next() {
-		ug := &UserGroup{}
-		if err := rows.Scan(&ug.ID, &ug.UserID, &ug.GroupName, &ug.CreatedAt); err != nil {
+		ug, err := scanUserGroup(rows.Scan)
+		if err != nil {
 			return nil, err
 		}
 		out = append(out, ug)
@@ -232,36 +387,49 @@ func (s *MySQLStore) GetGroupsByUserID(ctx context.Context, userID string) ([]*U
 
 func (s *MySQLStore) GetPermissionByID(ctx context.Context, id string) (*Permission, error) {
 	row := s.db.QueryRowContext(ctx,
-		`SELECT id, resource, action, created_at FROM rbacv2.permissions WHERE id = ?`, id)
+		`SELECT id, name, description, labels, resource, action, effect, condition_expr, created_at, updated_at, created_by, updated_by, tenant_id FROM rbacv2.permissions WHERE id = ?`, id)
 
-	p := &Permission{}
-	var action string
-	err := row.Scan(&p.ID, &p.Resource, &action, &p.CreatedAt)
+	p, err := scanPermission(row.Scan)
 	if errors.Is(err, sql.ErrNoRows) {
 		return nil, nil
 	}
 	if err != nil {
 		return nil, err
 	}
-	p.Action = Action(action)
 	return p, nil
 }
 
+func (s *MySQLStore) ListAllPermissions(ctx context.Context) ([]*Permission, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT id, name, description, labels, resource, action, effect, condition_expr, created_at, updated_at, created_by, updated_by, tenant_id FROM rbacv2.permissions`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []*Permission
+	for rows.Next() {
+		p, err := scanPermission(rows.Scan)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode permission: %w", err)
+		}
+		out = append(out, p)
+	}
+	return out, rows.Err()
+}
+
 func (s *MySQLStore) GetPermissionByResource(ctx context.Context, resource string, action Action) (*Permission, error) {
 	row := s.db.QueryRowContext(ctx,
-		`SELECT id, resource, action, created_at FROM rbacv2.permissions WHERE resource = ? AND action = ?`,
+		`SELECT id, name, description, labels, resource, action, effect, condition_expr, created_at, updated_at, created_by, updated_by, tenant_id FROM rbacv2.permissions WHERE resource = ? AND action = ?`,
 		resource, string(action))
 
-	p := &Permission{}
-	var act string
-	err := row.Scan(&p.ID, &p.Resource, &act, &p.CreatedAt)
+	p, err := scanPermission(row.Scan)
 	if errors.Is(err, sql.ErrNoRows) {
 		return nil, nil
 	}
 	if err != nil {
 		return nil, err
 	}
-	p.Action = Action(act)
 	return p, nil
 }
 
@@ -272,12 +440,18 @@ func (s *MySQLStore) CreatePermission(ctx context.Context, p *Permission) error
 		return nil
 	}
 
-	p.ID = uuid.New().String()
+	if p.ID == "" {
+		p.ID = generateID(s.ids, KindPermission)
+	}
 	p.CreatedAt = time.Now().Unix()
+	labels, err := encodeLabels(p.Labels)
+	if err != nil {
+		return err
+	}
 
-	_, err := s.db.ExecContext(ctx,
-		`INSERT INTO rbacv2.permissions (id, resource, action, created_at) VALUES (?, ?, ?, ?)`,
-		p.ID, p.Resource, string(p.Action), p.CreatedAt)
+	_, err = s.db.ExecContext(ctx,
+		`INSERT INTO rbacv2.permissions (id, name, description, labels, resource, action, effect, condition_expr, created_at, updated_at, created_by, updated_by, tenant_id) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		p.ID, p.Name, p.Description, labels, p.Resource, string(p.Action), string(p.Effect), p.Condition, p.CreatedAt, p.UpdatedAt, p.CreatedBy, p.UpdatedBy, p.TenantID)
 	return err
 }
 
@@ -291,42 +465,66 @@ func (s *MySQLStore) DeletePermission(ctx context.Context, id string) error {
 //
 
 func (s *MySQLStore) CreateRole(ctx context.Context, r *Role) error {
-	r.ID = uuid.New().String()
+	if r.ID == "" {
+		r.ID = generateID(s.ids, KindRole)
+	}
 	r.CreatedAt = time.Now().Unix()
+	meta, err := encodeMeta(r.Meta)
+	if err != nil {
+		return err
+	}
+	generators, err := encodeGenerators(r.Generators)
+	if err != nil {
+		return err
+	}
 
-	_, err := s.db.ExecContext(ctx,
-		`INSERT INTO rbacv2.roles (id, name, description, created_at) VALUES (?, ?, ?, ?)`,
-		r.ID, r.Name, r.Description, r.CreatedAt)
+	_, err = s.db.ExecContext(ctx,
+		`INSERT INTO rbacv2.roles (id, name, description, meta, template, priority, generators, created_at, updated_at, created_by, updated_by, tenant_id) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		r.ID, r.Name, r.Description, meta, r.Template, r.Priority, generators, r.CreatedAt, r.UpdatedAt, r.CreatedBy, r.UpdatedBy, r.TenantID)
 	return err
 }
 
 func (s *MySQLStore) GetRoleByName(ctx context.Context, name string) (*Role, error) {
 	row := s.db.QueryRowContext(ctx,
-		`SELECT id, name, description, created_at FROM rbacv2.roles WHERE name = ?`, name)
+		`SELECT id, name, description, meta, template, priority, generators, created_at, updated_at, created_by, updated_by, tenant_id FROM rbacv2.roles WHERE name = ?`, name)
 
 	r := &Role{}
-	err := row.Scan(&r.ID, &r.Name, &r.Description, &r.CreatedAt)
+	var meta, generators string
+	err := row.Scan(&r.ID, &r.Name, &r.Description, &meta, &r.Template, &r.Priority, &generators, &r.CreatedAt, &r.UpdatedAt, &r.CreatedBy, &r.UpdatedBy, &r.TenantID)
 	if errors.Is(err, sql.ErrNoRows) {
 		return nil, nil
 	}
 	if err != nil {
 		return nil, err
 	}
+	if err := decodeMeta(meta, &r.Meta); err != nil {
+		return nil, fmt.Errorf("failed to decode role meta: %w", err)
+	}
+	if err := decodeGenerators(generators, &r.Generators); err != nil {
+		return nil, fmt.Errorf("failed to decode role generators: %w", err)
+	}
 	return r, nil
 }
 
 func (s *MySQLStore) GetRoleByID(ctx context.Context, id string) (*Role, error) {
 	row := s.db.QueryRowContext(ctx,
-		`SELECT id, name, description, created_at FROM rbacv2.roles WHERE id = ?`, id)
+		`SELECT id, name, description, meta, template, priority, generators, created_at, updated_at, created_by, updated_by, tenant_id FROM rbacv2.roles WHERE id = ?`, id)
 
 	r := &Role{}
-	err := row.Scan(&r.ID, &r.Name, &r.Description, &r.CreatedAt)
+	var meta, generators string
+	err := row.Scan(&r.ID, &r.Name, &r.Description, &meta, &r.Template, &r.Priority, &generators, &r.CreatedAt, &r.UpdatedAt, &r.CreatedBy, &r.UpdatedBy, &r.TenantID)
 	if errors.Is(err, sql.ErrNoRows) {
 		return nil, nil
 	}
 	if err != nil {
 		return nil, err
 	}
+	if err := decodeMeta(meta, &r.Meta); err != nil {
+		return nil, fmt.Errorf("failed to decode role meta: %w", err)
+	}
+	if err := decodeGenerators(generators, &r.Generators); err != nil {
+		return nil, fmt.Errorf("failed to decode role generators: %w", err)
+	}
 	return r, nil
 }
 
@@ -337,7 +535,7 @@ func (s *MySQLStore) DeleteRole(ctx context.Context, id string) error {
 
 func (s *MySQLStore) ListAllRoles(ctx context.Context) ([]*Role, error) {
 	rows, err := s.db.QueryContext(ctx,
-		`SELECT id, name, description, created_at FROM rbacv2.roles`)
+		`SELECT id, name, description, meta, template, priority, generators, created_at, updated_at, created_by, updated_by, tenant_id FROM rbacv2.roles`)
 	if err != nil {
 		return nil, err
 	}
@@ -346,9 +544,16 @@ func (s *MySQLStore) ListAllRoles(ctx context.Context) ([]*Role, error) {
 	var out []*Role
 	for rows.Next() {
 		r := &Role{}
-		if err := rows.Scan(&r.ID, &r.Name, &r.Description, &r.CreatedAt); err != nil {
+		var meta, generators string
+		if err := rows.Scan(&r.ID, &r.Name, &r.Description, &meta, &r.Template, &r.Priority, &generators, &r.CreatedAt, &r.UpdatedAt, &r.CreatedBy, &r.UpdatedBy, &r.TenantID); err != nil {
 			return nil, fmt.Errorf("failed to decode role: %w", err)
 		}
+		if err := decodeMeta(meta, &r.Meta); err != nil {
+			return nil, fmt.Errorf("failed to decode role meta: %w", err)
+		}
+		if err := decodeGenerators(generators, &r.Generators); err != nil {
+			return nil, fmt.Errorf("failed to decode role generators: %w", err)
+		}
 		out = append(out, r)
 	}
 	return out, rows.Err()
@@ -365,6 +570,27 @@ func (s *MySQLStore) AddRP(ctx context.Context, roleID, permID string) error {
 	return err
 }
 
+func (s *MySQLStore) AddRPs(ctx context.Context, roleID string, permIDs []string) error {
+	return s.insertEdges(ctx, `INSERT IGNORE INTO rbacv2.role_permissions (role_id, permission_id, created_at) VALUES `, roleID, permIDs)
+}
+
+// insertEdges inserts a row (from, to, now) for each of tos with one
+// multi-row INSERT starting with stmt.
+func (s *MySQLStore) insertEdges(ctx context.Context, stmt, from string, tos []string) error {
+	if len(tos) == 0 {
+		return nil
+	}
+	now := time.Now().Unix()
+	rows := make([]string, len(tos))
+	args := make([]any, 0, 3*len(tos))
+	for i, to := range tos {
+		rows[i] = "(?, ?, ?)"
+		args = append(args, from, to, now)
+	}
+	_, err := s.db.ExecContext(ctx, stmt+strings.Join(rows, ", "), args...)
+	return err
+}
+
 func (s *MySQLStore) Remove(ctx context.Context, roleID, permID string) error {
 	_, err := s.db.ExecContext(ctx,
 		`DELETE FROM rbacv2.role_permissions WHERE role_id = ? AND permission_id = ?`,
@@ -402,6 +628,10 @@ func (s *MySQLStore) AddUR(ctx context.Context, userID, roleID string) error {
 	return err
 }
 
+func (s *MySQLStore) AddURs(ctx context.Context, userID string, roleIDs []string) error {
+	return s.insertEdges(ctx, `INSERT IGNORE INTO rbacv2.user_roles (user_id, role_id, assigned_at) VALUES `, userID, roleIDs)
+}
+
 func (s *MySQLStore) RemoveUR(ctx context.Context, userID, roleID string) error {
 	_, err := s.db.ExecContext(ctx,
 		`DELETE FROM rbacv2.user_roles WHERE user_id = ? AND role_id = ?`,
@@ -412,18 +642,12 @@ func (s *MySQLStore) RemoveUR(ctx context.Context, userID, roleID string) error
 func (s *MySQLStore) ListRoles(ctx context.Context, userID string) ([]string, error) {
 	rows, err := s.db.QueryContext(ctx,
 		`SELECT role_id FROM rbacv2.user_roles WHERE user_id = ?`, userID)
-
-	var out []string
-
 	if err != nil {
-		// Still append the default role even on error, mirroring the Mongo behaviour.
-		if r, _ := s.GetRoleByName(ctx, "default"); r != nil {
-			out = append(out, r.ID)
-		}
-		return out, err
+		return nil, err
 	}
 	defer rows.Close()
 
+	var out []string
 	for rows.Next() {
 		var id string
 		if err := rows.Scan(&id); err != nil {
@@ -434,12 +658,26 @@ func (s *MySQLStore) ListRoles(ctx context.Context, userID string) ([]string, er
 	if err := rows.Err(); err != nil {
 		return nil, err
 	}
+	return out, nil
+}
 
-	// Always include the default role.
-	if r, _ := s.GetRoleByName(ctx, "default"); r != nil {
-		out = append(out, r.ID)
+func (s *MySQLStore) ListUsersForRole(ctx context.Context, roleID string) ([]string, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT user_id FROM rbacv2.user_roles WHERE role_id = ? ORDER BY user_id`, roleID)
+	if err != nil {
+		return nil, err
 	}
-	return out, nil
+	defer rows.Close()
+
+	var out []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		out = append(out, id)
+	}
+	return out, rows.Err()
 }
 
 //
@@ -451,12 +689,17 @@ func (s *MySQLStore) AddUserToGroup(ctx context.Context, ug *UserGroup) error {
 		return errors.New("user id is empty")
 	}
 
-	ug.ID = uuid.New().String()
+	if ug.ID == "" {
+		ug.ID = generateID(s.ids, KindUserGroup)
+	}
 	ug.CreatedAt = time.Now().Unix()
 
+	// Re-adding a member replaces their level and expiry, as in the other
+	// stores.
 	_, err := s.db.ExecContext(ctx,
-		`INSERT INTO rbacv2.user_groups (id, user_id, group_name, created_at) VALUES (?, ?, ?, ?)`,
-		ug.ID, ug.UserID, ug.GroupName, ug.CreatedAt)
+		`INSERT INTO rbacv2.user_groups (id, user_id, group_name, created_at, updated_at, created_by, updated_by, level, expires_at, tenant_id) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		 ON DUPLICATE KEY UPDATE updated_at = VALUES(updated_at), updated_by = VALUES(updated_by), level = VALUES(level), expires_at = VALUES(expires_at), tenant_id = VALUES(tenant_id)`,
+		ug.ID, ug.UserID, ug.GroupName, ug.CreatedAt, ug.UpdatedAt, ug.CreatedBy, ug.UpdatedBy, string(ug.Level), ug.ExpiresAt, ug.TenantID)
 	return err
 }
 
@@ -473,7 +716,7 @@ func (s *MySQLStore) RemoveUserFromGroup(ctx context.Context, groupName string,
 
 func (s *MySQLStore) GetUsersByGroupID(ctx context.Context, groupName string) ([]*UserGroup, error) {
 	rows, err := s.db.QueryContext(ctx,
-		`SELECT id, user_id, group_name, created_at FROM rbacv2.user_groups WHERE group_name = ?`, groupName)
+		`SELECT id, user_id, group_name, created_at, updated_at, created_by, updated_by, level, expires_at, tenant_id FROM rbacv2.user_groups WHERE group_name = ?`, groupName)
 	if err != nil {
 		return nil, err
 	}
@@ -481,8 +724,28 @@ func (s *MySQLStore) GetUsersByGroupID(ctx context.Context, groupName string) ([
 
 	var out []*UserGroup
 	for rows.Next() {
-		ug := &UserGroup{}
-		if err := rows.Scan(&ug.ID, &ug.UserID, &ug.GroupName, &ug.CreatedAt); err != nil {
+		ug, err := scanUserGroup(rows.Scan)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, ug)
+	}
+	return out, rows.Err()
+}
+
+func (s *MySQLStore) ListExpiringMemberships(ctx context.Context, after, before int64) ([]*UserGroup, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT id, user_id, group_name, created_at, updated_at, created_by, updated_by, level, expires_at, tenant_id FROM rbacv2.user_groups
+		 WHERE expires_at > ? AND expires_at <= ?`, after, before)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []*UserGroup
+	for rows.Next() {
+		ug, err := scanUserGroup(rows.Scan)
+		if err != nil {
 			return nil, err
 		}
 		out = append(out, ug)
@@ -526,3 +789,139 @@ func (s *MySQLStore) ListRolesForGroup(ctx context.Context, groupID string) ([]s
 	}
 	return out, rows.Err()
 }
+
+func (s *MySQLStore) ListGroupsForRole(ctx context.Context, roleID string) ([]string, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT group_name FROM rbacv2.group_roles WHERE role_id = ? ORDER BY group_name`, roleID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		out = append(out, name)
+	}
+	return out, rows.Err()
+}
+
+//
+// ---------- AuditRepo ----------
+//
+
+func (s *MySQLStore) AppendAudit(ctx context.Context, e *AuditEntry) error {
+	if e.ID == "" {
+		e.ID = generateID(s.ids, KindAuditEntry)
+	}
+	_, err := s.db.ExecContext(ctx,
+		`INSERT INTO rbacv2.audit_log (`+auditColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		e.ID, e.At.UnixNano(), e.Actor, e.Kind, e.Method, e.Target, e.Resource, e.Action, e.Outcome, e.Error)
+	return err
+}
+
+func (s *MySQLStore) ListAudit(ctx context.Context, q AuditQuery) ([]*AuditEntry, error) {
+	clause, args := auditFilter(q, func(int) string { return "?" })
+	rows, err := s.db.QueryContext(ctx, `SELECT `+auditColumns+` FROM rbacv2.audit_log`+clause, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out, err := scanAudit(rows.Next, rows.Scan)
+	if err != nil {
+		return nil, err
+	}
+	return out, rows.Err()
+}
+
+//
+// ---------- ExportPager ----------
+//
+
+func (s *MySQLStore) ExportPage(ctx context.Context, kind, after string, limit int) ([]ExportItem, error) {
+	var (
+		query string
+		args  []any
+		scan  func(*sql.Rows) (ExportItem, error)
+	)
+	edge := func(table, fromCol, toCol string) {
+		from, to := splitExportKey(after)
+		query = fmt.Sprintf(`SELECT %[2]s, %[3]s FROM rbacv2.%[1]s WHERE (%[2]s, %[3]s) > (?, ?) ORDER BY %[2]s, %[3]s LIMIT ?`,
+			table, fromCol, toCol)
+		args = []any{from, to, limit}
+		scan = func(rows *sql.Rows) (ExportItem, error) {
+			e := &ExportEdge{}
+			err := rows.Scan(&e.From, &e.To)
+			return ExportItem{Key: e.From + ExportKeySep + e.To, Value: e}, err
+		}
+	}
+
+	switch kind {
+	case KindPermission:
+		query = `SELECT id, name, description, labels, resource, action, effect, condition_expr, created_at, updated_at, created_by, updated_by, tenant_id FROM rbacv2.permissions WHERE id > ? ORDER BY id LIMIT ?`
+		args = []any{after, limit}
+		scan = func(rows *sql.Rows) (ExportItem, error) {
+			p, err := scanPermission(rows.Scan)
+			return ExportItem{Key: p.ID, Value: p}, err
+		}
+	case KindRole:
+		query = `SELECT id, name, description, meta, template, priority, generators, created_at, updated_at, created_by, updated_by, tenant_id FROM rbacv2.roles WHERE id > ? ORDER BY id LIMIT ?`
+		args = []any{after, limit}
+		scan = func(rows *sql.Rows) (ExportItem, error) {
+			r := &Role{}
+			var meta, generators string
+			err := rows.Scan(&r.ID, &r.Name, &r.Description, &meta, &r.Template, &r.Priority, &generators, &r.CreatedAt, &r.UpdatedAt, &r.CreatedBy, &r.UpdatedBy, &r.TenantID)
+			if err == nil {
+				err = decodeMeta(meta, &r.Meta)
+			}
+			if err == nil {
+				err = decodeGenerators(generators, &r.Generators)
+			}
+			return ExportItem{Key: r.ID, Value: r}, err
+		}
+	case KindUser:
+		query = `SELECT id, username, email, email_verified, status, created_at, updated_at, created_by, updated_by, tenant_id FROM rbacv2.users WHERE id > ? ORDER BY id LIMIT ?`
+		args = []any{after, limit}
+		scan = func(rows *sql.Rows) (ExportItem, error) {
+			u, err := scanUser(rows.Scan)
+			return ExportItem{Key: u.ID, Value: u}, err
+		}
+	case KindUserGroup:
+		userID, group := splitExportKey(after)
+		query = `SELECT id, user_id, group_name, created_at, updated_at, created_by, updated_by, level, expires_at, tenant_id FROM rbacv2.user_groups
+			WHERE (user_id, group_name) > (?, ?) ORDER BY user_id, group_name LIMIT ?`
+		args = []any{userID, group, limit}
+		scan = func(rows *sql.Rows) (ExportItem, error) {
+			ug, err := scanUserGroup(rows.Scan)
+			return ExportItem{Key: ug.UserID + ExportKeySep + ug.GroupName, Value: ug}, err
+		}
+	case KindRolePermission:
+		edge("role_permissions", "role_id", "permission_id")
+	case KindUserRole:
+		edge("user_roles", "user_id", "role_id")
+	case KindGroupRole:
+		edge("group_roles", "group_name", "role_id")
+	default:
+		return nil, fmt.Errorf("mysql_store: unknown export kind %q", kind)
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []ExportItem
+	for rows.Next() {
+		it, err := scan(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, it)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,212 @@
+package rbac
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// RegoQuery evaluates a Rego decision, such as data.authz.allow, for an
+// input document and returns its result, or nil when the decision is
+// undefined. To embed OPA, wrap a prepared query of OPA's rego package:
+//
+//	pq, err := rego.New(rego.Query("data.authz.allow"), rego.Module("authz.rego", src)).PrepareForEval(ctx)
+//	query := rbac.RegoQueryFunc(func(ctx context.Context, input map[string]any) (any, error) {
+//		rs, err := pq.Eval(ctx, rego.EvalInput(input))
+//		if err != nil || len(rs) == 0 {
+//			return nil, err
+//		}
+//		return rs[0].Expressions[0].Value, nil
+//	})
+//
+// NewRemoteRegoQuery asks an OPA server instead.
+type RegoQuery interface {
+	Eval(ctx context.Context, input map[string]any) (any, error)
+}
+
+// RegoQueryFunc is a RegoQuery written as a function.
+type RegoQueryFunc func(ctx context.Context, input map[string]any) (any, error)
+
+func (f RegoQueryFunc) Eval(ctx context.Context, input map[string]any) (any, error) {
+	return f(ctx, input)
+}
+
+// remoteRegoQuery asks OPA's Data API for a decision.
+type remoteRegoQuery struct {
+	target string
+	client *http.Client
+	header http.Header
+}
+
+// NewRemoteRegoQuery returns a RegoQuery that asks the OPA server at
+// baseURL (for example "http://localhost:8181") for the decision at
+// decision, a path below data such as "authz/allow". header is sent with
+// every request, e.g. the Authorization header of an OPA run with
+// --authentication=token. A nil client uses http.DefaultClient.
+func NewRemoteRegoQuery(baseURL, decision string, client *http.Client, header http.Header) (RegoQuery, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("opa: invalid base URL %q", baseURL)
+	}
+	decision = strings.Trim(strings.TrimPrefix(strings.ReplaceAll(decision, ".", "/"), "data/"), "/")
+	if decision == "" {
+		return nil, fmt.Errorf("opa: empty decision path")
+	}
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &remoteRegoQuery{
+		target: strings.TrimSuffix(baseURL, "/") + "/v1/data/" + decision,
+		client: client,
+		header: header,
+	}, nil
+}
+
+func (q *remoteRegoQuery) Eval(ctx context.Context, input map[string]any) (any, error) {
+	data, err := json.Marshal(map[string]any{"input": input})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, q.target, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range q.header {
+		req.Header[k] = v
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := q.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("opa: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		var e struct {
+			Message string `json:"message"`
+		}
+		_ = json.NewDecoder(resp.Body).Decode(&e)
+		if e.Message == "" {
+			e.Message = http.StatusText(resp.StatusCode)
+		}
+		return nil, fmt.Errorf("opa: %d %s", resp.StatusCode, e.Message)
+	}
+	// An undefined decision comes back without a result.
+	var out struct {
+		Result any `json:"result"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return nil, fmt.Errorf("opa: decode result: %w", err)
+	}
+	return out.Result, nil
+}
+
+// OPAAuthorizer is a link of the authorizer chain that hands the request to
+// a Rego policy, for rules beyond what glob-matched permissions express.
+// The policy sees the store's data as its input:
+//
+//	{
+//	  "resource": "docs/1", "action": "read",
+//	  "user": {"id": ..., "username": ..., "email": ..., "tenant_id": ..., "meta": {...}},
+//	  "attrs": {...},   // the attrs of CanWithAttributes and Decide
+//	  "roles": [{"id": ..., "name": ..., "priority": 0}],
+//	  "permissions": [{"role_id": ..., "id": ..., "resource": ..., "action": ..., "effect": ...}]
+//	}
+//
+// A true or false result settles the request, as does an object with a
+// boolean "allow"; an undefined result, or an object without "allow",
+// leaves it to the next link. Put it before RoleAuthorizer to let Rego
+// overrule the permissions, or in its place to decide with Rego alone:
+//
+//	m.Authorizers = []rbac.Authorizer{rbac.ActiveUserAuthorizer, rbac.SuperAdminAuthorizer, &rbac.OPAAuthorizer{Query: query}}
+type OPAAuthorizer struct {
+	Query RegoQuery
+	// Name is set as Decision.Authorizer. Defaults to "opa".
+	Name string
+	// Permissions adds the permissions of the user's roles to the input.
+	// They are read through the permission cache, but still cost a lookup
+	// per role on a cold cache, so policies that do not read them should
+	// leave this unset.
+	Permissions bool
+}
+
+var _ Authorizer = (*OPAAuthorizer)(nil)
+
+func (a *OPAAuthorizer) Authorize(ctx context.Context, m *Manager, req *AuthorizeRequest) (*Decision, error) {
+	input := m.conditionVars(ctx, req.UserID, req.Resource, req.Action, req.Attrs)
+	roles := []map[string]any{}
+	var perms []map[string]any
+	if a.Permissions {
+		perms = []map[string]any{}
+	}
+	ranked, err := req.rankedRoles(ctx, m)
+	if err != nil {
+		return nil, err
+	}
+	for _, rr := range ranked {
+		role := map[string]any{"id": rr.id, "priority": rr.priority}
+		if rr.role != nil {
+			role["name"] = rr.role.Name
+		}
+		roles = append(roles, role)
+		if !a.Permissions {
+			continue
+		}
+		callStart := time.Now()
+		rolePerms, err := m.evalRolePermissions(ctx, req.start, rr.id)
+		req.tr.storeCall("RolePermissions", callStart, err, attribute.String("rbac.role_id", rr.id))
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range rolePerms {
+			if p.DeletedAt != 0 {
+				continue
+			}
+			effect := EffectAllow
+			if p.Effect == EffectDeny {
+				effect = EffectDeny
+			}
+			perm := map[string]any{"role_id": rr.id, "id": p.ID, "resource": p.Resource, "action": string(p.Action), "effect": string(effect)}
+			if p.Condition != "" {
+				perm["condition"] = p.Condition
+			}
+			perms = append(perms, perm)
+		}
+	}
+	input["roles"] = roles
+	if perms != nil {
+		input["permissions"] = perms
+	}
+
+	result, err := a.Query.Eval(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+	if obj, ok := result.(map[string]any); ok {
+		result = obj["allow"]
+	}
+	var allowed bool
+	switch v := result.(type) {
+	case nil:
+		return nil, nil
+	case bool:
+		allowed = v
+	default:
+		return nil, fmt.Errorf("opa: decision is %T, not a boolean", result)
+	}
+	d := &Decision{Allowed: allowed, Effect: EffectDeny, Authorizer: a.Name}
+	if allowed {
+		d.Effect = EffectAllow
+	}
+	if d.Authorizer == "" {
+		d.Authorizer = "opa"
+	}
+	return d, nil
+}
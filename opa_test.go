@@ -0,0 +1,127 @@
+package rbac
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestOPAAuthorizer(t *testing.T) {
+	ctx := context.Background()
+	mgr, err := NewMemoryStoreManager(ctx, "", 0)
+	if err != nil {
+		t.Fatalf("NewMemoryStoreManager: %v", err)
+	}
+	must := func(err error) {
+		t.Helper()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	must(mgr.CreateUser(ctx, &User{ID: "alice", Username: "alice", Email: "alice@example.com", Meta: map[string]any{"region": "eu"}}))
+	must(mgr.CreateUser(ctx, &User{ID: "bob", Username: "bob", Email: "bob@example.com"}))
+	must(mgr.CreatePermission(ctx, &Permission{ID: "docs-read", Resource: "docs/*", Action: ActionRead}))
+	must(mgr.CreateRole(ctx, &Role{ID: "reader", Name: "reader"}))
+	must(mgr.AssignPermissionToRole(ctx, "reader", "docs-read"))
+	must(mgr.AssignRoleToUser(ctx, "alice", "reader"))
+	must(mgr.AssignRoleToUser(ctx, "bob", "reader"))
+
+	// Stands in for a Rego policy: readers may read documents of their own
+	// region, and other requests are left to the permissions.
+	var inputs []map[string]any
+	query := RegoQueryFunc(func(ctx context.Context, input map[string]any) (any, error) {
+		inputs = append(inputs, input)
+		if !strings.HasPrefix(input["resource"].(string), "docs/") {
+			return nil, nil
+		}
+		user := input["user"].(map[string]any)
+		meta, _ := user["meta"].(map[string]any)
+		attrs, _ := input["attrs"].(map[string]any)
+		return map[string]any{"allow": meta["region"] != nil && attrs["region"] == meta["region"]}, nil
+	})
+	opa := &OPAAuthorizer{Query: query, Permissions: true}
+	mgr.Authorizers = []Authorizer{ActiveUserAuthorizer, SuperAdminAuthorizer, opa, RoleAuthorizer}
+
+	for _, tc := range []struct {
+		user, resource, region string
+		want                   bool
+	}{
+		{"alice", "docs/1", "eu", true},
+		{"alice", "docs/1", "us", false},
+		{"bob", "docs/1", "eu", false},
+		// undefined: the role's permissions decide
+		{"alice", "reports/1", "eu", false},
+	} {
+		d, err := mgr.Decide(ctx, tc.user, tc.resource, ActionRead, map[string]any{"region": tc.region})
+		if err != nil || d.Allowed != tc.want {
+			t.Errorf("Decide(%s, %s, %s): got %+v, %v; want %v", tc.user, tc.resource, tc.region, d, err, tc.want)
+			continue
+		}
+		if strings.HasPrefix(tc.resource, "docs/") && d.Authorizer != "opa" {
+			t.Errorf("Decide(%s, %s): decided by %q, want opa", tc.user, tc.resource, d.Authorizer)
+		}
+	}
+
+	input := inputs[0]
+	roles, _ := input["roles"].([]map[string]any)
+	perms, _ := input["permissions"].([]map[string]any)
+	// the memory store also gives every user its default role
+	if !slices.ContainsFunc(roles, func(r map[string]any) bool { return r["name"] == "reader" }) || len(perms) != 1 || perms[0]["resource"] != "docs/*" || perms[0]["effect"] != "allow" {
+		t.Errorf("input: roles %v, permissions %v", roles, perms)
+	}
+
+	opa.Query = RegoQueryFunc(func(ctx context.Context, input map[string]any) (any, error) { return "yes", nil })
+	if _, err := mgr.Can(ctx, "alice", "docs/1", ActionRead); err == nil {
+		t.Error("Can with a non-boolean decision: expected an error")
+	}
+}
+
+func TestRemoteRegoQuery(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.Header.Get("Authorization") != "Bearer token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"code": "unauthorized", "message": "authentication required"}`))
+			return
+		}
+		var body struct {
+			Input map[string]any `json:"input"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		switch r.URL.Path {
+		case "/v1/data/authz/allow":
+			_, _ = w.Write([]byte(`{"result": ` + map[bool]string{true: "true", false: "false"}[body.Input["action"] == "read"] + `}`))
+		default:
+			_, _ = w.Write([]byte(`{}`))
+		}
+	}))
+	defer srv.Close()
+	ctx := context.Background()
+	header := http.Header{"Authorization": {"Bearer token"}}
+
+	q, err := NewRemoteRegoQuery(srv.URL+"/", "data.authz.allow", nil, header)
+	if err != nil {
+		t.Fatalf("NewRemoteRegoQuery: %v", err)
+	}
+	if res, err := q.Eval(ctx, map[string]any{"action": "read"}); err != nil || res != true {
+		t.Errorf("Eval(read) = %v, %v; want true", res, err)
+	}
+	if res, err := q.Eval(ctx, map[string]any{"action": "write"}); err != nil || res != false {
+		t.Errorf("Eval(write) = %v, %v; want false", res, err)
+	}
+
+	undefined, _ := NewRemoteRegoQuery(srv.URL, "authz/missing", nil, header)
+	if res, err := undefined.Eval(ctx, nil); err != nil || res != nil {
+		t.Errorf("Eval of an undefined decision = %v, %v; want nil", res, err)
+	}
+	unauthorized, _ := NewRemoteRegoQuery(srv.URL, "authz/allow", nil, nil)
+	if _, err := unauthorized.Eval(ctx, nil); err == nil || !strings.Contains(err.Error(), "authentication required") {
+		t.Errorf("Eval without a token: got %v, want the server's message", err)
+	}
+	if _, err := NewRemoteRegoQuery("localhost:8181", "authz/allow", nil, nil); err == nil {
+		t.Error("NewRemoteRegoQuery without a scheme: expected an error")
+	}
+}
@@ -0,0 +1,217 @@
+package rbac
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+// OpenFGAModel is the OpenFGA authorization model, in the DSL, that the
+// tuples written by ExportOpenFGA are meant for. Users are assignees of a
+// role directly, through a group, or by holding a role that inherits from
+// it; a permission is granted or denied to a role's assignees. Resource and
+// action patterns are not relations, so a check asks about the permission
+// objects that match the request, e.g.
+// `check user:bob granted permission:<id>`, and applies denies itself.
+const OpenFGAModel = `model
+  schema 1.1
+
+type user
+
+type group
+  relations
+    define member: [user]
+
+type role
+  relations
+    define assignee: [user, group#member, role#assignee]
+
+type permission
+  relations
+    define granted: [role#assignee]
+    define denied: [role#assignee]
+`
+
+// OpenFGATuple is a relationship tuple in the shape OpenFGA's Write API and
+// `fga tuple write --file` take.
+type OpenFGATuple struct {
+	User     string `json:"user"`
+	Relation string `json:"relation"`
+	Object   string `json:"object"`
+}
+
+// ExportOpenFGA writes the users, groups, roles and permissions as a JSON
+// array of OpenFGATuple for OpenFGAModel, a page at a time, so the data can
+// be mirrored into OpenFGA while piloting relationship-based authorization.
+// Memberships that have expired are left out. Tuples have no expiry, so
+// scheduled grants and expiring memberships are exported as if permanent;
+// scoped roles and role generators have no tuple form and are skipped.
+// Conditional allows are skipped and conditional denies exported, so the
+// mirror never grants more than Can would. Every repo must implement
+// ExportPager.
+func (m *Manager) ExportOpenFGA(ctx context.Context, w io.Writer) error {
+	start := time.Now()
+	ctx, span := m.startSpan(ctx, "ExportOpenFGA")
+	defer span.End()
+	err := m.exportOpenFGA(ctx, start, w)
+	m.record(ctx, start, "ExportOpenFGA", err)
+	return err
+}
+
+func (m *Manager) exportOpenFGA(ctx context.Context, now time.Time, w io.Writer) error {
+	tw := &tupleWriter{w: w}
+	if _, err := io.WriteString(w, "["); err != nil {
+		return err
+	}
+
+	// the relation each permission is exported with; "" skips it
+	relations := map[string]string{}
+	err := m.exportPages(ctx, KindPermission, func(v any) error {
+		if p, ok := v.(*Permission); ok {
+			switch {
+			case p.Effect == EffectDeny:
+				relations[p.ID] = "denied"
+			case p.Condition == "":
+				relations[p.ID] = "granted"
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if h, ok := m.Roles.(RoleHierarchyRepo); ok {
+		var roles []string
+		err := m.exportPages(ctx, KindRole, func(v any) error {
+			if r, ok := v.(*Role); ok {
+				roles = append(roles, r.ID)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		for _, id := range roles {
+			parents, err := h.ListRoleParents(ctx, id)
+			if err != nil {
+				return err
+			}
+			for _, parent := range parents {
+				if err := tw.add("role:"+id+"#assignee", "assignee", "role:"+parent); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	steps := []struct {
+		kind  string
+		tuple func(v any) (user, relation, object string)
+	}{
+		{KindUserRole, func(v any) (string, string, string) {
+			e, ok := v.(*ExportEdge)
+			if !ok {
+				return "", "", ""
+			}
+			return "user:" + e.From, "assignee", "role:" + e.To
+		}},
+		{KindUserGroup, func(v any) (string, string, string) {
+			ug, ok := v.(*UserGroup)
+			if !ok || ug.ExpiresAt != 0 && ug.ExpiresAt <= now.Unix() {
+				return "", "", ""
+			}
+			return "user:" + ug.UserID, "member", "group:" + ug.GroupName
+		}},
+		{KindGroupRole, func(v any) (string, string, string) {
+			e, ok := v.(*ExportEdge)
+			if !ok {
+				return "", "", ""
+			}
+			return "group:" + e.From + "#member", "assignee", "role:" + e.To
+		}},
+		{KindRolePermission, func(v any) (string, string, string) {
+			e, ok := v.(*ExportEdge)
+			if !ok {
+				return "", "", ""
+			}
+			return "role:" + e.From + "#assignee", relations[e.To], "permission:" + e.To
+		}},
+	}
+	for _, step := range steps {
+		err := m.exportPages(ctx, step.kind, func(v any) error {
+			if user, relation, object := step.tuple(v); relation != "" {
+				return tw.add(user, relation, object)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	if err := tw.flush(); err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, "]\n")
+	return err
+}
+
+// exportPages calls fn with every record of kind, read through the repo's
+// ExportPager, until fn fails.
+func (m *Manager) exportPages(ctx context.Context, kind string, fn func(v any) error) error {
+	pager, ok := m.exportRepo(kind).(ExportPager)
+	if !ok {
+		return fmt.Errorf("%w: %s", errExportUnsupported, kind)
+	}
+	after := ""
+	for {
+		items, err := pager.ExportPage(ctx, kind, after, 500)
+		if err != nil {
+			return fmt.Errorf("rbac: export %s: %w", kind, err)
+		}
+		for _, it := range items {
+			if err := fn(it.Value); err != nil {
+				return err
+			}
+		}
+		if len(items) < 500 {
+			return nil
+		}
+		after = items[len(items)-1].Key
+	}
+}
+
+// tupleWriter writes the elements of a JSON array of tuples, about 64 KiB
+// at a time.
+type tupleWriter struct {
+	w     io.Writer
+	buf   []byte
+	count int
+}
+
+func (t *tupleWriter) add(user, relation, object string) error {
+	data, err := json.Marshal(OpenFGATuple{User: user, Relation: relation, Object: object})
+	if err != nil {
+		return err
+	}
+	if t.count > 0 {
+		t.buf = append(t.buf, ",\n"...)
+	}
+	t.buf = append(t.buf, data...)
+	t.count++
+	if len(t.buf) >= 64<<10 {
+		return t.flush()
+	}
+	return nil
+}
+
+func (t *tupleWriter) flush() error {
+	if len(t.buf) == 0 {
+		return nil
+	}
+	_, err := t.w.Write(t.buf)
+	t.buf = t.buf[:0]
+	return err
+}
@@ -0,0 +1,89 @@
+package rbac
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestExportOpenFGA(t *testing.T) {
+	ctx := context.Background()
+	mgr, err := NewMemoryStoreManager(ctx, "", 0)
+	if err != nil {
+		t.Fatalf("NewMemoryStoreManager: %v", err)
+	}
+	editor, viewer := &Role{ID: "editor", Name: "editor"}, &Role{ID: "viewer", Name: "viewer"}
+	for _, r := range []*Role{editor, viewer} {
+		if err := mgr.CreateRole(ctx, r); err != nil {
+			t.Fatalf("CreateRole: %v", err)
+		}
+	}
+	if err := mgr.AddRoleParent(ctx, "editor", "viewer"); err != nil {
+		t.Fatalf("AddRoleParent: %v", err)
+	}
+	perms := []*Permission{
+		{ID: "read", Resource: "docs/*", Action: ActionRead},
+		{ID: "purge", Resource: "docs/*", Action: ActionDelete, Effect: EffectDeny},
+		{ID: "eu-only", Resource: "reports/*", Action: ActionRead, Condition: `user.meta.region == "eu"`},
+	}
+	for _, p := range perms {
+		if err := mgr.CreatePermission(ctx, p); err != nil {
+			t.Fatalf("CreatePermission: %v", err)
+		}
+	}
+	for _, b := range [][2]string{{"viewer", "read"}, {"viewer", "eu-only"}, {"editor", "purge"}} {
+		if err := mgr.AssignPermissionToRole(ctx, b[0], b[1]); err != nil {
+			t.Fatalf("AssignPermissionToRole: %v", err)
+		}
+	}
+	if err := mgr.AssignRoleToUser(ctx, "alice", "editor"); err != nil {
+		t.Fatalf("AssignRoleToUser: %v", err)
+	}
+	if err := mgr.AssignRoleToGroup(ctx, "staff", "viewer"); err != nil {
+		t.Fatalf("AssignRoleToGroup: %v", err)
+	}
+	if err := mgr.AddUserToGroup(ctx, &UserGroup{UserID: "bob", GroupName: "staff"}); err != nil {
+		t.Fatalf("AddUserToGroup: %v", err)
+	}
+	past := time.Now().Add(-time.Hour).Unix()
+	if err := mgr.AddUserToGroup(ctx, &UserGroup{UserID: "carol", GroupName: "staff", ExpiresAt: past}); err != nil {
+		t.Fatalf("AddUserToGroup: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := mgr.ExportOpenFGA(ctx, &buf); err != nil {
+		t.Fatalf("ExportOpenFGA: %v", err)
+	}
+	var tuples []OpenFGATuple
+	if err := json.Unmarshal(buf.Bytes(), &tuples); err != nil {
+		t.Fatalf("decode %s: %v", buf.String(), err)
+	}
+	got := map[OpenFGATuple]bool{}
+	for _, tu := range tuples {
+		got[tu] = true
+	}
+
+	want := []OpenFGATuple{
+		{"role:editor#assignee", "assignee", "role:viewer"},
+		{"user:alice", "assignee", "role:editor"},
+		{"user:bob", "member", "group:staff"},
+		{"group:staff#member", "assignee", "role:viewer"},
+		{"role:viewer#assignee", "granted", "permission:read"},
+		{"role:editor#assignee", "denied", "permission:purge"},
+	}
+	for _, w := range want {
+		if !got[w] {
+			t.Errorf("missing tuple %+v in %v", w, tuples)
+		}
+	}
+	for _, tu := range tuples {
+		if tu.Object == "permission:eu-only" {
+			t.Errorf("expected the conditional allow to be skipped, got %+v", tu)
+		}
+		if tu.User == "user:carol" {
+			t.Errorf("expected the expired membership to be skipped, got %+v", tu)
+		}
+	}
+}
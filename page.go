@@ -0,0 +1,185 @@
+package rbac
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+)
+
+// Page sizes for PageRequest.
+const (
+	DefaultPageLimit = 100
+	MaxPageLimit     = 1000
+)
+
+// ErrInvalidPageCursor is returned for a cursor the repo did not issue.
+var ErrInvalidPageCursor = errors.New("rbac: invalid page cursor")
+
+// PageRequest asks for one page of a list. Cursor is the NextCursor of the
+// previous page, or "" for the first. Limit defaults to DefaultPageLimit and
+// is capped at MaxPageLimit.
+type PageRequest struct {
+	Cursor string `json:"cursor,omitempty"`
+	Limit  int    `json:"limit,omitempty"`
+}
+
+func (p PageRequest) limit() int {
+	switch {
+	case p.Limit <= 0:
+		return DefaultPageLimit
+	case p.Limit > MaxPageLimit:
+		return MaxPageLimit
+	}
+	return p.Limit
+}
+
+// PageResult is one page of a list. NextCursor is empty on the last page.
+type PageResult[T any] struct {
+	Items      []T    `json:"items"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// ListPager is optionally implemented by repos that can read their lists a
+// page at a time, so large groups and catalogs are never loaded whole. Its
+// cursors are opaque and only valid for the repo that issued them. The
+// Manager pages the full lists of repos without it.
+type ListPager interface {
+	ListAllRolesPage(ctx context.Context, page PageRequest) (PageResult[*Role], error)
+	ListAllPermissionsPage(ctx context.Context, page PageRequest) (PageResult[*Permission], error)
+	ListAllUsersPage(ctx context.Context, page PageRequest) (PageResult[*User], error)
+	// ListPermissionsPage pages the permission IDs of roleID.
+	ListPermissionsPage(ctx context.Context, roleID string, page PageRequest) (PageResult[string], error)
+	// GetUsersByGroupIDPage pages the memberships of the group.
+	GetUsersByGroupIDPage(ctx context.Context, groupName string, page PageRequest) (PageResult[*UserGroup], error)
+}
+
+// ListRolesPage returns a page of every role.
+func (m *Manager) ListRolesPage(ctx context.Context, page PageRequest) (PageResult[*Role], error) {
+	start := time.Now()
+	ctx, span := m.startSpan(ctx, "ListRolesPage")
+	defer span.End()
+	var (
+		res PageResult[*Role]
+		err error
+	)
+	if p, ok := m.Roles.(ListPager); ok {
+		res, err = p.ListAllRolesPage(ctx, page)
+	} else {
+		var all []*Role
+		if all, err = m.Roles.ListAllRoles(ctx); err == nil {
+			res = pageSlice(all, func(r *Role) string { return r.ID }, page)
+		}
+	}
+	m.record(ctx, start, "ListRolesPage", err)
+	return res, err
+}
+
+// ListPermissionsPage returns a page of every permission.
+func (m *Manager) ListPermissionsPage(ctx context.Context, page PageRequest) (PageResult[*Permission], error) {
+	start := time.Now()
+	ctx, span := m.startSpan(ctx, "ListPermissionsPage")
+	defer span.End()
+	var (
+		res PageResult[*Permission]
+		err error
+	)
+	if p, ok := m.Perms.(ListPager); ok {
+		res, err = p.ListAllPermissionsPage(ctx, page)
+	} else {
+		var all []*Permission
+		if all, err = m.Perms.ListAllPermissions(ctx); err == nil {
+			res = pageSlice(all, func(p *Permission) string { return p.ID }, page)
+		}
+	}
+	m.record(ctx, start, "ListPermissionsPage", err)
+	return res, err
+}
+
+// ListUsersPage returns a page of every user.
+func (m *Manager) ListUsersPage(ctx context.Context, page PageRequest) (PageResult[*User], error) {
+	start := time.Now()
+	ctx, span := m.startSpan(ctx, "ListUsersPage")
+	defer span.End()
+	res, err := m.listUsersPage(ctx, page)
+	m.record(ctx, start, "ListUsersPage", err)
+	return res, err
+}
+
+func (m *Manager) listUsersPage(ctx context.Context, page PageRequest) (PageResult[*User], error) {
+	if p, ok := m.Users.(ListPager); ok {
+		return p.ListAllUsersPage(ctx, page)
+	}
+	all, err := m.Users.ListAllUsers(ctx)
+	if err != nil {
+		return PageResult[*User]{}, err
+	}
+	return pageSlice(all, func(u *User) string { return u.ID }, page), nil
+}
+
+// ListPermissionsForRolePage returns a page of the permission IDs of roleID.
+func (m *Manager) ListPermissionsForRolePage(ctx context.Context, roleID string, page PageRequest) (PageResult[string], error) {
+	start := time.Now()
+	ctx, span := m.startSpan(ctx, "ListPermissionsForRolePage")
+	defer span.End()
+	var (
+		res PageResult[string]
+		err error
+	)
+	if p, ok := m.RP.(ListPager); ok {
+		res, err = p.ListPermissionsPage(ctx, roleID, page)
+	} else {
+		var all []string
+		if all, err = m.RP.ListPermissions(ctx, roleID); err == nil {
+			res = pageSlice(all, func(id string) string { return id }, page)
+		}
+	}
+	m.record(ctx, start, "ListPermissionsForRolePage", err)
+	return res, err
+}
+
+// GetUsersByGroupIDPage returns a page of the memberships of the group.
+// Banned members are left out, so a page from a ListPager may come up short.
+func (m *Manager) GetUsersByGroupIDPage(ctx context.Context, groupID string, page PageRequest) (PageResult[*UserGroup], error) {
+	start := time.Now()
+	ctx, span := m.startSpan(ctx, "GetUsersByGroupIDPage")
+	defer span.End()
+	var (
+		res PageResult[*UserGroup]
+		err error
+	)
+	if p, ok := m.UG.(ListPager); ok {
+		res, err = p.GetUsersByGroupIDPage(ctx, groupID, page)
+		if err == nil {
+			res.Items, err = m.withoutBannedMembers(ctx, groupID, res.Items)
+		}
+	} else {
+		var all []*UserGroup
+		if all, err = m.UG.GetUsersByGroupID(ctx, groupID); err == nil {
+			all, err = m.withoutBannedMembers(ctx, groupID, all)
+		}
+		if err == nil {
+			res = pageSlice(all, func(ug *UserGroup) string { return ug.UserID }, page)
+		}
+	}
+	m.record(ctx, start, "GetUsersByGroupIDPage", err)
+	return res, err
+}
+
+// pageSlice pages items in key order; the cursor is the last key returned.
+func pageSlice[T any](items []T, key func(T) string, page PageRequest) PageResult[T] {
+	sort.Slice(items, func(i, j int) bool { return key(items[i]) < key(items[j]) })
+	from := sort.Search(len(items), func(i int) bool { return key(items[i]) > page.Cursor })
+	if page.Cursor == "" {
+		from = 0
+	}
+	res := PageResult[T]{Items: items[from:]}
+	if limit := page.limit(); len(res.Items) > limit {
+		res.Items = res.Items[:limit]
+		res.NextCursor = key(res.Items[limit-1])
+	}
+	if res.Items == nil {
+		res.Items = []T{}
+	}
+	return res
+}
@@ -0,0 +1,83 @@
+package rbac
+
+import (
+	"context"
+	"fmt"
+	"testing"
+)
+
+func TestManagerPages(t *testing.T) {
+	ctx := context.Background()
+	mgr, err := NewMemoryStoreManager(ctx, "", 0)
+	if err != nil {
+		t.Fatalf("NewMemoryStoreManager: %v", err)
+	}
+	role := &Role{Name: "reader"}
+	if err := mgr.CreateRole(ctx, role); err != nil {
+		t.Fatalf("CreateRole: %v", err)
+	}
+	for i := range 7 {
+		p := &Permission{Resource: fmt.Sprintf("docs/%d", i), Action: ActionRead}
+		if err := mgr.CreatePermission(ctx, p); err != nil {
+			t.Fatalf("CreatePermission: %v", err)
+		}
+		if err := mgr.AssignPermissionToRole(ctx, role.ID, p.ID); err != nil {
+			t.Fatalf("AssignPermissionToRole: %v", err)
+		}
+		if err := mgr.AddUserToGroup(ctx, &UserGroup{UserID: fmt.Sprintf("user-%d", i), GroupName: "staff"}); err != nil {
+			t.Fatalf("AddUserToGroup: %v", err)
+		}
+	}
+
+	// walk the members three at a time
+	var (
+		seen  = map[string]bool{}
+		page  = PageRequest{Limit: 3}
+		pages int
+	)
+	for {
+		res, err := mgr.GetUsersByGroupIDPage(ctx, "staff", page)
+		if err != nil {
+			t.Fatalf("GetUsersByGroupIDPage: %v", err)
+		}
+		pages++
+		if len(res.Items) > 3 {
+			t.Fatalf("page %d has %d items, want at most 3", pages, len(res.Items))
+		}
+		for _, ug := range res.Items {
+			if seen[ug.UserID] {
+				t.Errorf("%s returned twice", ug.UserID)
+			}
+			seen[ug.UserID] = true
+		}
+		if res.NextCursor == "" {
+			break
+		}
+		page.Cursor = res.NextCursor
+	}
+	if len(seen) != 7 || pages != 3 {
+		t.Errorf("expected 7 members over 3 pages, got %d over %d", len(seen), pages)
+	}
+
+	perms, err := mgr.ListPermissionsForRolePage(ctx, role.ID, PageRequest{Limit: 5})
+	if err != nil || len(perms.Items) != 5 || perms.NextCursor == "" {
+		t.Fatalf("first page of role permissions = %+v, %v", perms, err)
+	}
+	rest, err := mgr.ListPermissionsForRolePage(ctx, role.ID, PageRequest{Cursor: perms.NextCursor, Limit: 5})
+	if err != nil || len(rest.Items) != 2 || rest.NextCursor != "" {
+		t.Errorf("last page of role permissions = %+v, %v", rest, err)
+	}
+
+	all, err := mgr.ListPermissionsPage(ctx, PageRequest{})
+	if err != nil || len(all.Items) != 7 || all.NextCursor != "" {
+		t.Errorf("default page of permissions = %d items, cursor %q, %v", len(all.Items), all.NextCursor, err)
+	}
+	roles, err := mgr.ListRolesPage(ctx, PageRequest{Limit: 1})
+	if err != nil || len(roles.Items) != 1 || roles.NextCursor == "" {
+		t.Errorf("first page of roles = %+v, %v", roles, err)
+	}
+	users, err := mgr.ListUsersPage(ctx, PageRequest{})
+	if err != nil || users.Items == nil || users.NextCursor != "" {
+		t.Errorf("page of no users = %+v, %v", users, err)
+	}
+}
@@ -0,0 +1,90 @@
+package rbac
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// PermissionNameGetter is optionally implemented by a PermissionRepo that
+// can look permissions up by Name. Names are unique among named permissions.
+type PermissionNameGetter interface {
+	// GetPermissionByName returns the named permission, or nil, nil.
+	GetPermissionByName(ctx context.Context, name string) (*Permission, error)
+}
+
+// ErrPermissionNameTaken is returned when creating a permission whose Name
+// another permission already has.
+var ErrPermissionNameTaken = errors.New("rbac: permission name is already taken")
+
+var errPermissionNameUnsupported = errors.New("rbac: permission repo cannot look up permissions by name")
+
+// checkPermissionName rejects a Name that belongs to another permission.
+// Creating the same resource and action again is idempotent, so a name held
+// by that permission is fine.
+func (m *Manager) checkPermissionName(ctx context.Context, p *Permission) error {
+	g, ok := m.Perms.(PermissionNameGetter)
+	if p.Name == "" || !ok {
+		return nil
+	}
+	existing, err := g.GetPermissionByName(ctx, p.Name)
+	if err != nil {
+		return err
+	}
+	if existing != nil && (existing.Resource != p.Resource || existing.Action != p.Action) {
+		return fmt.Errorf("%w: %q", ErrPermissionNameTaken, p.Name)
+	}
+	return nil
+}
+
+// GetPermissionByName returns the permission with the given Name, or nil if
+// there is none.
+func (m *Manager) GetPermissionByName(ctx context.Context, name string) (*Permission, error) {
+	start := time.Now()
+	ctx, span := m.startSpan(ctx, "GetPermissionByName")
+	defer span.End()
+	var (
+		perm *Permission
+		err  = errPermissionNameUnsupported
+	)
+	if g, ok := m.Perms.(PermissionNameGetter); ok {
+		perm, err = g.GetPermissionByName(ctx, name)
+	}
+	m.record(ctx, start, "GetPermissionByName", err)
+	return perm, err
+}
+
+// GetPermissionByResource returns the permission for resource and action,
+// or nil if there is none.
+func (m *Manager) GetPermissionByResource(ctx context.Context, resource string, action Action) (*Permission, error) {
+	start := time.Now()
+	ctx, span := m.startSpan(ctx, "GetPermissionByResource")
+	defer span.End()
+	perm, err := m.Perms.GetPermissionByResource(ctx, resource, action)
+	m.record(ctx, start, "GetPermissionByResource", err)
+	return perm, err
+}
+
+// AssignPermissionToRoleByResource binds the permission for resource and
+// action to roleID and returns it, creating the permission first when there
+// is none, so seed scripts and call sites need not track permission IDs.
+// The permission is found and created as CreatePermission does, and the two
+// steps run in a transaction when the store supports them.
+func (m *Manager) AssignPermissionToRoleByResource(ctx context.Context, roleID, resource string, action Action) (*Permission, error) {
+	start := time.Now()
+	ctx, span := m.startSpan(ctx, "AssignPermissionToRoleByResource")
+	defer span.End()
+	p := &Permission{Resource: resource, Action: action}
+	err := m.inTransaction(ctx, func(ctx context.Context) error {
+		if err := m.CreatePermission(ctx, p); err != nil {
+			return err
+		}
+		return m.AssignPermissionToRole(ctx, roleID, p.ID)
+	})
+	m.record(ctx, start, "AssignPermissionToRoleByResource", err)
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
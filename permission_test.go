@@ -0,0 +1,101 @@
+package rbac
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestPermissionNames(t *testing.T) {
+	ctx := context.Background()
+	mgr, err := NewMemoryStoreManager(ctx, "", 0)
+	if err != nil {
+		t.Fatalf("NewMemoryStoreManager: %v", err)
+	}
+
+	approve := &Permission{
+		Name:        "invoices.approve",
+		Description: "Approve invoices for payment",
+		Labels:      map[string]string{"owner": "billing", "risk": "high"},
+		Resource:    "invoices/*",
+		Action:      ActionUpdate,
+	}
+	if err := mgr.CreatePermission(ctx, approve); err != nil {
+		t.Fatalf("CreatePermission: %v", err)
+	}
+	if err := mgr.CreatePermission(ctx, &Permission{Name: "invoices.approve", Resource: "invoices/*", Action: ActionUpdate}); err != nil {
+		t.Errorf("expected re-creating the same permission to stay idempotent, got %v", err)
+	}
+	if err := mgr.CreatePermission(ctx, &Permission{Name: "invoices.approve", Resource: "invoices/*", Action: ActionDelete}); !errors.Is(err, ErrPermissionNameTaken) {
+		t.Errorf("expected a taken name to fail with ErrPermissionNameTaken, got %v", err)
+	}
+
+	got, err := mgr.GetPermissionByName(ctx, "invoices.approve")
+	if err != nil || got == nil || got.ID != approve.ID || got.Labels["risk"] != "high" || got.Description == "" {
+		t.Fatalf("GetPermissionByName = %+v, %v", got, err)
+	}
+	if got, _ := mgr.GetPermissionByName(ctx, "missing"); got != nil {
+		t.Errorf("expected nil for an unknown name, got %+v", got)
+	}
+
+	acme, globex := mgr.ForTenant("acme"), mgr.ForTenant("globex")
+	if err := acme.CreatePermission(ctx, &Permission{Name: "invoices.approve", Resource: "invoices/*", Action: ActionUpdate}); err != nil {
+		t.Fatalf("acme CreatePermission: %v", err)
+	}
+	if got, _ := acme.GetPermissionByName(ctx, "invoices.approve"); got == nil || got.TenantID != "acme" || got.Name != "invoices.approve" {
+		t.Errorf("acme GetPermissionByName = %+v", got)
+	}
+	if got, _ := globex.GetPermissionByName(ctx, "invoices.approve"); got != nil {
+		t.Errorf("expected another tenant's permission to be invisible, got %+v", got)
+	}
+}
+
+func TestAssignPermissionToRoleByResource(t *testing.T) {
+	ctx := context.Background()
+	memory, err := NewMemoryStoreManager(ctx, "", 0)
+	if err != nil {
+		t.Fatalf("NewMemoryStoreManager: %v", err)
+	}
+	for name, mgr := range map[string]*Manager{
+		"memory": memory,
+		"mock":   NewMockRepoManager(NewMockRepo()),
+	} {
+		t.Run(name, func(t *testing.T) {
+			if err := mgr.CreateRole(ctx, &Role{ID: "viewer", Name: "viewer"}); err != nil {
+				t.Fatalf("CreateRole: %v", err)
+			}
+			if err := mgr.CreateRole(ctx, &Role{ID: "auditor", Name: "auditor"}); err != nil {
+				t.Fatalf("CreateRole: %v", err)
+			}
+
+			created, err := mgr.AssignPermissionToRoleByResource(ctx, "viewer", "reports/*", ActionRead)
+			if err != nil || created == nil || created.ID == "" {
+				t.Fatalf("AssignPermissionToRoleByResource = %+v, %v", created, err)
+			}
+			got, err := mgr.GetPermissionByResource(ctx, "reports/*", ActionRead)
+			if err != nil || got == nil || got.ID != created.ID {
+				t.Fatalf("GetPermissionByResource = %+v, %v; want %s", got, err, created.ID)
+			}
+
+			// a second role reuses the permission
+			again, err := mgr.AssignPermissionToRoleByResource(ctx, "auditor", "reports/*", ActionRead)
+			if err != nil || again.ID != created.ID {
+				t.Errorf("second assignment = %+v, %v; want permission %s", again, err, created.ID)
+			}
+			for _, roleID := range []string{"viewer", "auditor"} {
+				if perms, err := mgr.ListPermissionsForRole(ctx, roleID); err != nil || len(perms) != 1 || perms[0] != created.ID {
+					t.Errorf("%s has %v, %v; want %s", roleID, perms, err, created.ID)
+				}
+			}
+			if ok, err := mgr.Can(ctx, "alice", "reports/q3", ActionRead); err != nil || ok {
+				t.Errorf("Can(alice) = %v, %v before any assignment", ok, err)
+			}
+			if err := mgr.AssignRoleToUser(ctx, "alice", "auditor"); err != nil {
+				t.Fatalf("AssignRoleToUser: %v", err)
+			}
+			if ok, err := mgr.Can(ctx, "alice", "reports/q3", ActionRead); err != nil || !ok {
+				t.Errorf("Can(alice) = %v, %v; want true", ok, err)
+			}
+		})
+	}
+}
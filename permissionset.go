@@ -0,0 +1,270 @@
+package rbac
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// KindPermissionSet is passed to IDGenerator.NewID for permission sets.
+const KindPermissionSet = "permission_set"
+
+// PermissionSet bundles permissions so they can be bound to roles as one
+// unit: a role bound to the set grants every permission in it, and changing
+// the set changes every such role.
+type PermissionSet struct {
+	ID          string   `bson:"id" json:"id"`
+	Name        string   `bson:"name" json:"name"`
+	Description string   `bson:"description,omitempty" json:"description,omitempty"`
+	Permissions []string `bson:"permissions" json:"permissions"` // permission IDs
+	CreatedAt   int64    `bson:"created_at" json:"created_at"`
+}
+
+// PermissionSetRepo stores permission sets and their bindings to roles.
+type PermissionSetRepo interface {
+	// SavePermissionSet creates or replaces the set.
+	SavePermissionSet(ctx context.Context, s *PermissionSet) error
+	// GetPermissionSet returns the set, or nil, nil.
+	GetPermissionSet(ctx context.Context, id string) (*PermissionSet, error)
+	ListPermissionSets(ctx context.Context) ([]*PermissionSet, error)
+	// DeletePermissionSet removes the set and its role bindings.
+	DeletePermissionSet(ctx context.Context, id string) error
+
+	AddSetToRole(ctx context.Context, roleID, setID string) error
+	RemoveSetFromRole(ctx context.Context, roleID, setID string) error
+	ListSetsForRole(ctx context.Context, roleID string) ([]string, error)
+}
+
+var (
+	// ErrPermissionSetNotFound is returned for an unknown permission set ID.
+	ErrPermissionSetNotFound = errors.New("rbac: permission set not found")
+	// ErrInvalidPermissionSet is returned by CreatePermissionSet for a set
+	// without a name or with a name another set has.
+	ErrInvalidPermissionSet = errors.New("rbac: invalid permission set")
+)
+
+var errNoPermissionSetRepo = errors.New("rbac: no PermissionSetRepo configured")
+
+// CreatePermissionSet stores a new set. Its permissions must exist.
+func (m *Manager) CreatePermissionSet(ctx context.Context, s *PermissionSet) error {
+	start := time.Now()
+	ctx, span := m.startSpan(ctx, "CreatePermissionSet")
+	defer span.End()
+	err := m.createPermissionSet(ctx, start, s)
+	m.record(ctx, start, "CreatePermissionSet", err)
+	m.changed(ctx, "CreatePermissionSet", s.ID, err)
+	return err
+}
+
+func (m *Manager) createPermissionSet(ctx context.Context, now time.Time, s *PermissionSet) error {
+	if m.PermSets == nil {
+		return errNoPermissionSetRepo
+	}
+	if s.Name == "" {
+		return fmt.Errorf("%w: missing name", ErrInvalidPermissionSet)
+	}
+	sets, err := m.PermSets.ListPermissionSets(ctx)
+	if err != nil {
+		return err
+	}
+	for _, other := range sets {
+		if other.Name == s.Name {
+			return fmt.Errorf("%w: name %q is taken", ErrInvalidPermissionSet, s.Name)
+		}
+	}
+	perms := slices.Compact(slices.Sorted(slices.Values(s.Permissions)))
+	for _, id := range perms {
+		if err := m.checkPermissionExists(ctx, id); err != nil {
+			return err
+		}
+	}
+	s.Permissions = perms
+	s.CreatedAt = now.Unix()
+	m.assignID(&s.ID, KindPermissionSet)
+	return m.PermSets.SavePermissionSet(ctx, s)
+}
+
+// GetPermissionSet returns a set by ID.
+func (m *Manager) GetPermissionSet(ctx context.Context, id string) (*PermissionSet, error) {
+	start := time.Now()
+	ctx, span := m.startSpan(ctx, "GetPermissionSet")
+	defer span.End()
+	s, err := m.getPermissionSet(ctx, id)
+	m.record(ctx, start, "GetPermissionSet", err)
+	return s, err
+}
+
+func (m *Manager) getPermissionSet(ctx context.Context, id string) (*PermissionSet, error) {
+	if m.PermSets == nil {
+		return nil, errNoPermissionSetRepo
+	}
+	s, err := m.PermSets.GetPermissionSet(ctx, id)
+	if err == nil && s == nil {
+		err = fmt.Errorf("%w: %q", ErrPermissionSetNotFound, id)
+	}
+	return s, err
+}
+
+// ListPermissionSets returns every set.
+func (m *Manager) ListPermissionSets(ctx context.Context) ([]*PermissionSet, error) {
+	start := time.Now()
+	ctx, span := m.startSpan(ctx, "ListPermissionSets")
+	defer span.End()
+	var (
+		out []*PermissionSet
+		err = errNoPermissionSetRepo
+	)
+	if m.PermSets != nil {
+		out, err = m.PermSets.ListPermissionSets(ctx)
+	}
+	m.record(ctx, start, "ListPermissionSets", err)
+	return out, err
+}
+
+// AddPermissionToSet adds a permission to the set, granting it to every
+// role bound to the set.
+func (m *Manager) AddPermissionToSet(ctx context.Context, setID, permID string) error {
+	start := time.Now()
+	ctx, span := m.startSpan(ctx, "AddPermissionToSet")
+	defer span.End()
+	err := m.updatePermissionSet(ctx, setID, func(s *PermissionSet) error {
+		if err := m.checkPermissionExists(ctx, permID); err != nil {
+			return err
+		}
+		if !slices.Contains(s.Permissions, permID) {
+			s.Permissions = append(s.Permissions, permID)
+			slices.Sort(s.Permissions)
+		}
+		return nil
+	})
+	m.record(ctx, start, "AddPermissionToSet", err)
+	m.changed(ctx, "AddPermissionToSet", setID, err)
+	return err
+}
+
+// RemovePermissionFromSet removes a permission from the set; roles keep it
+// only if they hold it some other way.
+func (m *Manager) RemovePermissionFromSet(ctx context.Context, setID, permID string) error {
+	start := time.Now()
+	ctx, span := m.startSpan(ctx, "RemovePermissionFromSet")
+	defer span.End()
+	err := m.updatePermissionSet(ctx, setID, func(s *PermissionSet) error {
+		s.Permissions = slices.DeleteFunc(s.Permissions, func(id string) bool { return id == permID })
+		return nil
+	})
+	m.record(ctx, start, "RemovePermissionFromSet", err)
+	m.changed(ctx, "RemovePermissionFromSet", setID, err)
+	return err
+}
+
+func (m *Manager) updatePermissionSet(ctx context.Context, setID string, update func(*PermissionSet) error) error {
+	s, err := m.getPermissionSet(ctx, setID)
+	if err != nil {
+		return err
+	}
+	if err := update(s); err != nil {
+		return err
+	}
+	return m.PermSets.SavePermissionSet(ctx, s)
+}
+
+// DeletePermissionSet removes a set, unbinding it from every role.
+func (m *Manager) DeletePermissionSet(ctx context.Context, id string) error {
+	start := time.Now()
+	ctx, span := m.startSpan(ctx, "DeletePermissionSet")
+	defer span.End()
+	_, err := m.getPermissionSet(ctx, id)
+	if err == nil {
+		err = m.PermSets.DeletePermissionSet(ctx, id)
+	}
+	m.record(ctx, start, "DeletePermissionSet", err)
+	m.changed(ctx, "DeletePermissionSet", id, err)
+	return err
+}
+
+// AssignPermissionSetToRole binds a set to a role, which then grants the
+// set's permissions as they are at the time of each check.
+func (m *Manager) AssignPermissionSetToRole(ctx context.Context, roleID, setID string) error {
+	start := time.Now()
+	ctx, span := m.startSpan(ctx, "AssignPermissionSetToRole")
+	defer span.End()
+	_, err := m.getPermissionSet(ctx, setID)
+	if err == nil {
+		var r *Role
+		if r, err = m.Roles.GetRoleByID(ctx, roleID); err == nil && r == nil {
+			err = fmt.Errorf("%w: %q", ErrRoleNotFound, roleID)
+		}
+	}
+	if err == nil {
+		err = m.PermSets.AddSetToRole(ctx, roleID, setID)
+	}
+	m.record(ctx, start, "AssignPermissionSetToRole", err)
+	m.changedRole(ctx, "AssignPermissionSetToRole", roleID, err)
+	return err
+}
+
+// RemovePermissionSetFromRole unbinds a set from a role.
+func (m *Manager) RemovePermissionSetFromRole(ctx context.Context, roleID, setID string) error {
+	start := time.Now()
+	ctx, span := m.startSpan(ctx, "RemovePermissionSetFromRole")
+	defer span.End()
+	err := errNoPermissionSetRepo
+	if m.PermSets != nil {
+		err = m.PermSets.RemoveSetFromRole(ctx, roleID, setID)
+	}
+	m.record(ctx, start, "RemovePermissionSetFromRole", err)
+	m.changedRole(ctx, "RemovePermissionSetFromRole", roleID, err)
+	return err
+}
+
+// ListPermissionSetsForRole returns the IDs of the sets bound to a role.
+func (m *Manager) ListPermissionSetsForRole(ctx context.Context, roleID string) ([]string, error) {
+	start := time.Now()
+	ctx, span := m.startSpan(ctx, "ListPermissionSetsForRole")
+	defer span.End()
+	var (
+		out []string
+		err = errNoPermissionSetRepo
+	)
+	if m.PermSets != nil {
+		out, err = m.PermSets.ListSetsForRole(ctx, roleID)
+	}
+	m.record(ctx, start, "ListPermissionSetsForRole", err)
+	return out, err
+}
+
+// setPermissions returns the IDs of the permissions a role is granted
+// through its permission sets.
+func (m *Manager) setPermissions(ctx context.Context, roleID string) ([]string, error) {
+	if m.PermSets == nil {
+		return nil, nil
+	}
+	setIDs, err := m.PermSets.ListSetsForRole(ctx, roleID)
+	if err != nil {
+		return nil, err
+	}
+	var out []string
+	for _, id := range setIDs {
+		s, err := m.PermSets.GetPermissionSet(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if s != nil {
+			out = append(out, s.Permissions...)
+		}
+	}
+	return out, nil
+}
+
+func (m *Manager) checkPermissionExists(ctx context.Context, permID string) error {
+	p, err := m.Perms.GetPermissionByID(ctx, permID)
+	if err != nil {
+		return err
+	}
+	if p == nil {
+		return fmt.Errorf("%w: %q", ErrPermissionNotFound, permID)
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package rbac
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestPermissionSets(t *testing.T) {
+	ctx := context.Background()
+	memory, err := NewMemoryStoreManager(ctx, "", 0)
+	if err != nil {
+		t.Fatalf("NewMemoryStoreManager: %v", err)
+	}
+	for name, mgr := range map[string]*Manager{"memory": memory, "mock": NewMockRepoManager(NewMockRepo())} {
+		t.Run(name, func(t *testing.T) {
+			read := &Permission{Resource: "reports/*", Action: ActionRead}
+			export := &Permission{Resource: "reports/*/export", Action: ActionCreate}
+			for _, p := range []*Permission{read, export} {
+				if err := mgr.CreatePermission(ctx, p); err != nil {
+					t.Fatalf("CreatePermission: %v", err)
+				}
+			}
+			analyst, auditor := &Role{Name: "analyst"}, &Role{Name: "auditor"}
+			for _, r := range []*Role{analyst, auditor} {
+				if err := mgr.CreateRole(ctx, r); err != nil {
+					t.Fatalf("CreateRole: %v", err)
+				}
+			}
+			for user, role := range map[string]*Role{"alice": analyst, "bob": auditor} {
+				if err := mgr.AssignRoleToUser(ctx, user, role.ID); err != nil {
+					t.Fatalf("AssignRoleToUser: %v", err)
+				}
+			}
+
+			if err := mgr.CreatePermissionSet(ctx, &PermissionSet{Name: "reporting", Permissions: []string{"missing"}}); !errors.Is(err, ErrPermissionNotFound) {
+				t.Errorf("expected ErrPermissionNotFound, got %v", err)
+			}
+			set := &PermissionSet{Name: "reporting", Permissions: []string{read.ID, read.ID}}
+			if err := mgr.CreatePermissionSet(ctx, set); err != nil {
+				t.Fatalf("CreatePermissionSet: %v", err)
+			}
+			if set.ID == "" || len(set.Permissions) != 1 {
+				t.Errorf("unexpected set: %+v", set)
+			}
+			if err := mgr.CreatePermissionSet(ctx, &PermissionSet{Name: "reporting"}); !errors.Is(err, ErrInvalidPermissionSet) {
+				t.Errorf("duplicate name: expected ErrInvalidPermissionSet, got %v", err)
+			}
+			for _, r := range []*Role{analyst, auditor} {
+				if err := mgr.AssignPermissionSetToRole(ctx, r.ID, set.ID); err != nil {
+					t.Fatalf("AssignPermissionSetToRole: %v", err)
+				}
+			}
+
+			can := func(user, resource string, action Action) bool {
+				t.Helper()
+				ok, err := mgr.Can(ctx, user, resource, action)
+				if err != nil {
+					t.Fatalf("Can: %v", err)
+				}
+				return ok
+			}
+			if !can("alice", "reports/q3", ActionRead) || !can("bob", "reports/q3", ActionRead) {
+				t.Error("expected both roles to grant the set's permission")
+			}
+			if ok, err := mgr.HasPermission(ctx, "alice", read.ID); err != nil || !ok {
+				t.Errorf("HasPermission: got %v, %v", ok, err)
+			}
+			if can("alice", "reports/q3/export", ActionCreate) {
+				t.Error("export allowed before it was added to the set")
+			}
+
+			if err := mgr.AddPermissionToSet(ctx, set.ID, export.ID); err != nil {
+				t.Fatalf("AddPermissionToSet: %v", err)
+			}
+			if !can("alice", "reports/q3/export", ActionCreate) || !can("bob", "reports/q3/export", ActionCreate) {
+				t.Error("expected updating the set to update every bound role")
+			}
+			if err := mgr.RemovePermissionFromSet(ctx, set.ID, read.ID); err != nil {
+				t.Fatalf("RemovePermissionFromSet: %v", err)
+			}
+			if can("bob", "reports/q3", ActionRead) {
+				t.Error("expected the removed permission to no longer be granted")
+			}
+
+			if err := mgr.RemovePermissionSetFromRole(ctx, auditor.ID, set.ID); err != nil {
+				t.Fatalf("RemovePermissionSetFromRole: %v", err)
+			}
+			if can("bob", "reports/q3/export", ActionCreate) {
+				t.Error("expected the unbound role to lose the set's permissions")
+			}
+			if ids, err := mgr.ListPermissionSetsForRole(ctx, analyst.ID); err != nil || len(ids) != 1 || ids[0] != set.ID {
+				t.Errorf("ListPermissionSetsForRole: got %v, %v", ids, err)
+			}
+
+			if err := mgr.DeletePermissionSet(ctx, set.ID); err != nil {
+				t.Fatalf("DeletePermissionSet: %v", err)
+			}
+			if can("alice", "reports/q3/export", ActionCreate) {
+				t.Error("expected the deleted set to no longer grant permissions")
+			}
+			if ids, _ := mgr.ListPermissionSetsForRole(ctx, analyst.ID); len(ids) != 0 {
+				t.Errorf("expected the deleted set's bindings to be dropped, got %v", ids)
+			}
+			if err := mgr.AssignPermissionSetToRole(ctx, analyst.ID, set.ID); !errors.Is(err, ErrPermissionSetNotFound) {
+				t.Errorf("expected ErrPermissionSetNotFound, got %v", err)
+			}
+		})
+	}
+}
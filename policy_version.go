@@ -0,0 +1,72 @@
+package rbac
+
+import (
+	"context"
+	"strconv"
+	"time"
+)
+
+// PolicyVersioner is optionally implemented by stores that can report a
+// version of their contents which changes whenever the policy changes, even
+// when the change was made by another process.
+type PolicyVersioner interface {
+	PolicyVersion(ctx context.Context) (string, error)
+}
+
+// processEpoch distinguishes in-process policy versions across restarts.
+var processEpoch = strconv.FormatInt(time.Now().UnixNano(), 36)
+
+// PolicyVersion returns an opaque string that changes whenever the policy
+// changes. It comes from the permission store when that implements
+// PolicyVersioner; otherwise it counts the changes made through this Manager,
+// which is only accurate when this Manager is the sole writer.
+func (m *Manager) PolicyVersion(ctx context.Context) (string, error) {
+	if m.base != nil {
+		return m.base.PolicyVersion(ctx)
+	}
+	if v, ok := m.Perms.(PolicyVersioner); ok {
+		return v.PolicyVersion(ctx)
+	}
+	return processEpoch + "-" + strconv.FormatUint(m.version.Load(), 36), nil
+}
+
+// changed records a policy mutation made through the Manager by method on
+// target, and audits it whether or not it succeeded.
+func (m *Manager) changed(ctx context.Context, method, target string, err error) {
+	if m.base != nil {
+		m.base.changed(ctx, method, target, err)
+		return
+	}
+	m.auditChange(ctx, method, target, err)
+	if err == nil {
+		m.version.Add(1)
+		m.Decisions.InvalidateAll()
+	}
+}
+
+// changedUser records a mutation that only affects userID's access.
+func (m *Manager) changedUser(ctx context.Context, method, userID string, err error) {
+	if m.base != nil {
+		m.base.changedUser(ctx, method, userID, err)
+		return
+	}
+	m.auditChange(ctx, method, userID, err)
+	if err == nil {
+		m.version.Add(1)
+		m.Decisions.InvalidateUser(userID)
+	}
+}
+
+// changedRole records a mutation that only affects the access roleID
+// grants.
+func (m *Manager) changedRole(ctx context.Context, method, roleID string, err error) {
+	if m.base != nil {
+		m.base.changedRole(ctx, method, roleID, err)
+		return
+	}
+	m.auditChange(ctx, method, roleID, err)
+	if err == nil {
+		m.version.Add(1)
+		m.Decisions.InvalidateRole(roleID)
+	}
+}
@@ -0,0 +1,34 @@
+package rbac
+
+import (
+	"context"
+	"testing"
+)
+
+func TestPolicyVersionChangesOnMutation(t *testing.T) {
+	ctx := context.Background()
+	mgr := NewMockRepoManager(NewMockRepo())
+
+	v1, err := mgr.PolicyVersion(ctx)
+	if err != nil {
+		t.Fatalf("PolicyVersion: %v", err)
+	}
+	if v2, _ := mgr.PolicyVersion(ctx); v2 != v1 {
+		t.Fatalf("version changed without a mutation: %s -> %s", v1, v2)
+	}
+
+	if err := mgr.CreateRole(ctx, &Role{ID: "r1", Name: "editor"}); err != nil {
+		t.Fatalf("CreateRole: %v", err)
+	}
+	v2, _ := mgr.PolicyVersion(ctx)
+	if v2 == v1 {
+		t.Fatal("expected version to change after CreateRole")
+	}
+
+	if _, err := mgr.Can(ctx, "nobody", "survey", ActionRead); err != nil {
+		t.Fatalf("Can: %v", err)
+	}
+	if v3, _ := mgr.PolicyVersion(ctx); v3 != v2 {
+		t.Errorf("expected reads to leave the version alone: %s -> %s", v2, v3)
+	}
+}
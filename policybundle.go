@@ -0,0 +1,623 @@
+package rbac
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"slices"
+	"sort"
+	"time"
+)
+
+// PolicyBundleVersion is the format version of the bundles ExportPolicy
+// returns and ImportPolicy accepts.
+const PolicyBundleVersion = 1
+
+// PolicyBundle is the policy of a store — its permissions, roles and groups,
+// and the bindings between them — in a form meant to be kept in git,
+// reviewed, and applied to another environment with ImportPolicy. Users and
+// their roles and memberships differ between environments and are left out.
+//
+// Written as YAML by WritePolicyBundle, a bundle looks like:
+//
+//	version: 1
+//	permissions:
+//	  - id: docs-read
+//	    resource: docs/*
+//	    action: read
+//	roles:
+//	  - id: viewer
+//	    name: viewer
+//	    permissions: [docs-read]
+//	  - id: editor
+//	    name: editor
+//	    parents: [viewer]
+//	groups:
+//	  - name: writers
+//	    roles: [editor]
+//
+// Bindings refer to permissions and roles by ID, and every one they name
+// must be in the bundle. Creation and update stamps are not exported, so two
+// exports of the same policy compare equal.
+type PolicyBundle struct {
+	Version     int            `bson:"version" json:"version" yaml:"version"`
+	Permissions []*Permission  `bson:"permissions,omitempty" json:"permissions,omitempty" yaml:"permissions,omitempty"`
+	Roles       []*BundleRole  `bson:"roles,omitempty" json:"roles,omitempty" yaml:"roles,omitempty"`
+	Groups      []*BundleGroup `bson:"groups,omitempty" json:"groups,omitempty" yaml:"groups,omitempty"`
+}
+
+// BundleRole is a role in a PolicyBundle with the IDs of the permissions it
+// grants and of the roles it inherits from.
+type BundleRole struct {
+	Role        `bson:",inline" yaml:",inline"`
+	Permissions []string `bson:"permissions,omitempty" json:"permissions,omitempty" yaml:"permissions,omitempty"`
+	Parents     []string `bson:"parents,omitempty" json:"parents,omitempty" yaml:"parents,omitempty"`
+}
+
+// BundleGroup is a group in a PolicyBundle with the IDs of the roles bound to
+// it. Only Name and Roles are needed when the Manager has no GroupRepo.
+type BundleGroup struct {
+	Group `bson:",inline" yaml:",inline"`
+	Roles []string `bson:"roles,omitempty" json:"roles,omitempty" yaml:"roles,omitempty"`
+}
+
+// ImportMode selects how ImportPolicy treats policy missing from a bundle.
+type ImportMode string
+
+const (
+	// ImportMerge adds what the bundle has and the store lacks, and keeps
+	// everything else.
+	ImportMerge ImportMode = "merge"
+	// ImportReplace also removes the permissions, roles, groups and bindings
+	// the bundle does not have, so the store's policy ends up equal to it.
+	ImportReplace ImportMode = "replace"
+)
+
+// ImportOptions configures Manager.ImportPolicy.
+type ImportOptions struct {
+	// Mode defaults to ImportMerge.
+	Mode ImportMode
+}
+
+// ErrInvalidBundle is returned by ImportPolicy and ReadPolicyBundle for a
+// bundle of an unknown version, or whose bindings refer to permissions or
+// roles it does not define.
+var ErrInvalidBundle = errors.New("rbac: invalid policy bundle")
+
+// ReadPolicyBundle decodes a bundle written in format, rejecting unknown
+// fields so a typo in a reviewed bundle fails instead of being dropped.
+func ReadPolicyBundle(r io.Reader, format FileFormat) (*PolicyBundle, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	var b PolicyBundle
+	if err := decodeFormat(format, data, &b); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidBundle, err)
+	}
+	if err := b.validate(); err != nil {
+		return nil, err
+	}
+	return &b, nil
+}
+
+// WritePolicyBundle encodes b to w in format.
+func WritePolicyBundle(w io.Writer, b *PolicyBundle, format FileFormat) error {
+	data, err := encodeFormat(format, b)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+// ExportPolicy returns the store's permissions, roles and groups with their
+// bindings, sorted so that exports of the same policy are identical.
+// Soft-deleted roles and permissions are left out. Groups are those of the
+// GroupRepo and, when the GroupRoleRepo implements ExportPager, every other
+// group a role is bound to.
+func (m *Manager) ExportPolicy(ctx context.Context) (*PolicyBundle, error) {
+	start := time.Now()
+	ctx, span := m.startSpan(ctx, "ExportPolicy")
+	defer span.End()
+	b, err := m.exportPolicy(ctx)
+	m.record(ctx, start, "ExportPolicy", err)
+	return b, err
+}
+
+func (m *Manager) exportPolicy(ctx context.Context) (*PolicyBundle, error) {
+	b := &PolicyBundle{Version: PolicyBundleVersion}
+
+	perms, err := m.Perms.ListAllPermissions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	live := map[string]bool{}
+	for _, p := range perms {
+		if p.DeletedAt != 0 {
+			continue
+		}
+		cp := *p
+		cp.CreatedAt, cp.UpdatedAt, cp.CreatedBy, cp.UpdatedBy = 0, 0, "", ""
+		b.Permissions = append(b.Permissions, &cp)
+		live[p.ID] = true
+	}
+	sort.Slice(b.Permissions, func(i, j int) bool { return b.Permissions[i].ID < b.Permissions[j].ID })
+
+	roles, err := m.Roles.ListAllRoles(ctx)
+	if err != nil {
+		return nil, err
+	}
+	hierarchy, _ := m.Roles.(RoleHierarchyRepo)
+	for _, r := range roles {
+		if r.DeletedAt != 0 {
+			continue
+		}
+		br := &BundleRole{Role: *r}
+		br.CreatedAt, br.UpdatedAt, br.CreatedBy, br.UpdatedBy = 0, 0, "", ""
+		permIDs, err := m.RP.ListPermissions(ctx, r.ID)
+		if err != nil {
+			return nil, err
+		}
+		for _, id := range permIDs {
+			if live[id] {
+				br.Permissions = append(br.Permissions, id)
+			}
+		}
+		if hierarchy != nil {
+			parents, err := hierarchy.ListRoleParents(ctx, r.ID)
+			if err != nil && !errors.Is(err, errHierarchyUnsupported) {
+				return nil, err
+			}
+			br.Parents = parents
+		}
+		b.Roles = append(b.Roles, br)
+	}
+	sort.Slice(b.Roles, func(i, j int) bool { return b.Roles[i].ID < b.Roles[j].ID })
+	exported := map[string]bool{}
+	for _, r := range b.Roles {
+		exported[r.ID] = true
+	}
+	for _, r := range b.Roles {
+		r.Permissions = sortedIDs(r.Permissions)
+		r.Parents = sortedIDs(slices.DeleteFunc(r.Parents, func(id string) bool { return !exported[id] }))
+	}
+
+	groups, err := m.groupRoles(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, g := range groups {
+		g.Roles = sortedIDs(slices.DeleteFunc(g.Roles, func(id string) bool { return !exported[id] }))
+		b.Groups = append(b.Groups, g)
+	}
+	sort.Slice(b.Groups, func(i, j int) bool { return b.Groups[i].Name < b.Groups[j].Name })
+	return b, nil
+}
+
+// sortedIDs returns ids sorted and without repeats, or nil when there are
+// none, so that bundles decoded from a file compare equal to exported ones.
+func sortedIDs(ids []string) []string {
+	if len(ids) == 0 {
+		return nil
+	}
+	ids = slices.Clone(ids)
+	slices.Sort(ids)
+	return slices.Compact(ids)
+}
+
+// groupRoles returns every known group with the roles bound to it, keyed by
+// name.
+func (m *Manager) groupRoles(ctx context.Context) (map[string]*BundleGroup, error) {
+	out := map[string]*BundleGroup{}
+	if m.Groups != nil {
+		groups, err := m.Groups.ListGroups(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, g := range groups {
+			bg := &BundleGroup{Group: *g}
+			bg.CreatedAt = 0
+			out[g.Name] = bg
+		}
+	}
+	if m.GR == nil {
+		return out, nil
+	}
+	if _, ok := m.GR.(ExportPager); ok {
+		err := m.exportPages(ctx, KindGroupRole, func(v any) error {
+			if e, ok := v.(*ExportEdge); ok {
+				if out[e.From] == nil {
+					out[e.From] = &BundleGroup{Group: Group{Name: e.From}}
+				}
+				out[e.From].Roles = append(out[e.From].Roles, e.To)
+			}
+			return nil
+		})
+		return out, err
+	}
+	for name, g := range out {
+		roles, err := m.GR.ListRolesForGroup(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		g.Roles = roles
+	}
+	return out, nil
+}
+
+// validate checks that b is of a known version and complete: every
+// permission and role has an ID, every group a name, and every binding
+// refers to an entity in b.
+func (b *PolicyBundle) validate() error {
+	if b.Version != PolicyBundleVersion {
+		return fmt.Errorf("%w: unsupported version %d", ErrInvalidBundle, b.Version)
+	}
+	perms := map[string]bool{}
+	for _, p := range b.Permissions {
+		if p.ID == "" {
+			return fmt.Errorf("%w: permission %s,%s has no id", ErrInvalidBundle, p.Resource, p.Action)
+		}
+		perms[p.ID] = true
+	}
+	roles := map[string]bool{}
+	for _, r := range b.Roles {
+		if r.ID == "" {
+			return fmt.Errorf("%w: role %q has no id", ErrInvalidBundle, r.Name)
+		}
+		roles[r.ID] = true
+	}
+	for _, r := range b.Roles {
+		for _, id := range r.Permissions {
+			if !perms[id] {
+				return fmt.Errorf("%w: role %q grants unknown permission %q", ErrInvalidBundle, r.ID, id)
+			}
+		}
+		for _, id := range r.Parents {
+			if !roles[id] {
+				return fmt.Errorf("%w: role %q inherits from unknown role %q", ErrInvalidBundle, r.ID, id)
+			}
+		}
+	}
+	for _, g := range b.Groups {
+		if g.Name == "" {
+			return fmt.Errorf("%w: group has no name", ErrInvalidBundle)
+		}
+		for _, id := range slices.Concat(g.Roles, g.DefaultRoles) {
+			if !roles[id] {
+				return fmt.Errorf("%w: group %q has unknown role %q", ErrInvalidBundle, g.Name, id)
+			}
+		}
+	}
+	return nil
+}
+
+// ImportPolicy applies b to the store, e.g. to promote policy reviewed in
+// staging to production. Permissions and roles are matched by ID and groups
+// by name; what the store lacks is created and bound, and in ImportReplace
+// mode what b lacks is removed. Roles and permissions that already exist
+// keep their stored fields, since neither can be updated in place, while
+// existing groups take b's description, meta, owner and default roles.
+//
+// A permission whose resource and action already have a permission under
+// another ID is not duplicated: b's bindings are applied to the stored one.
+// Removing a role or permission purges it along with its assignments, user
+// ones included, and removing a group deletes its memberships.
+//
+// Each change is made through the Manager method for it, so it is validated,
+// audited and reported to Listeners as usual. When the Manager has a
+// PolicyVersionRepo, the policy is first saved as a PolicySnapshot, so a bad
+// import is undone with RollbackPolicy. The import runs in a transaction
+// when the store supports them.
+func (m *Manager) ImportPolicy(ctx context.Context, b *PolicyBundle, opts ImportOptions) error {
+	start := time.Now()
+	ctx, span := m.startSpan(ctx, "ImportPolicy")
+	defer span.End()
+	err := b.validate()
+	if err == nil && opts.Mode != "" && opts.Mode != ImportMerge && opts.Mode != ImportReplace {
+		err = fmt.Errorf("rbac: unknown import mode %q", opts.Mode)
+	}
+	if err == nil {
+		err = m.inTransaction(ctx, func(ctx context.Context) error {
+			if err := m.snapshotBefore(ctx, "before ImportPolicy"); err != nil {
+				return err
+			}
+			_, err := m.importPolicy(ctx, b, opts.Mode == ImportReplace, func(ChangeEvent) {})
+			return err
+		})
+	}
+	m.record(ctx, start, "ImportPolicy", err)
+	return err
+}
+
+// importPolicy applies b, calling report for each change it makes, and
+// returns the roles and permissions whose stored fields differ from b's as
+// ChangeUpdate events it could not make.
+func (m *Manager) importPolicy(ctx context.Context, b *PolicyBundle, replace bool, report func(ChangeEvent)) ([]ChangeEvent, error) {
+	cur, err := m.exportPolicy(ctx)
+	if err != nil {
+		return nil, err
+	}
+	want, err := m.resolveBundle(ctx, b)
+	if err != nil {
+		return nil, err
+	}
+	if replace {
+		if err := m.removeMissing(ctx, want, cur, report); err != nil {
+			return nil, err
+		}
+	}
+
+	curPerms := map[string]*Permission{}
+	for _, p := range cur.Permissions {
+		curPerms[p.ID] = p
+	}
+	curRoles := map[string]*BundleRole{}
+	for _, r := range cur.Roles {
+		curRoles[r.ID] = r
+	}
+	curGroups := map[string]*BundleGroup{}
+	for _, g := range cur.Groups {
+		curGroups[g.Name] = g
+	}
+
+	var drift []ChangeEvent
+	for _, p := range want.Permissions {
+		if c := curPerms[p.ID]; c != nil {
+			if !samePolicy(c, p) {
+				drift = append(drift, ChangeEvent{Kind: KindPermission, Op: ChangeUpdate, ID: p.ID})
+			}
+			continue
+		}
+		if err := m.CreatePermission(ctx, p); err != nil {
+			return nil, fmt.Errorf("rbac: import permission %q: %w", p.ID, err)
+		}
+		report(ChangeEvent{Kind: KindPermission, Op: ChangeCreate, ID: p.ID})
+	}
+	for _, r := range want.Roles {
+		if c := curRoles[r.ID]; c != nil {
+			if !samePolicy(&c.Role, &r.Role) {
+				drift = append(drift, ChangeEvent{Kind: KindRole, Op: ChangeUpdate, ID: r.ID})
+			}
+			continue
+		}
+		if err := m.importRole(ctx, &r.Role); err != nil {
+			return nil, fmt.Errorf("rbac: import role %q: %w", r.ID, err)
+		}
+		report(ChangeEvent{Kind: KindRole, Op: ChangeCreate, ID: r.ID})
+	}
+	for _, r := range want.Roles {
+		var have, parents []string
+		if c := curRoles[r.ID]; c != nil {
+			have, parents = c.Permissions, c.Parents
+		}
+		var add []string
+		for _, id := range r.Permissions {
+			if !slices.Contains(have, id) {
+				add = append(add, id)
+			}
+		}
+		if len(add) > 0 {
+			if err := m.AssignPermissionsToRole(ctx, r.ID, add); err != nil {
+				return nil, fmt.Errorf("rbac: import role %q: %w", r.ID, err)
+			}
+			for _, id := range add {
+				report(ChangeEvent{Kind: KindRolePermission, Op: ChangeCreate, RoleID: r.ID, PermissionID: id})
+			}
+		}
+		for _, id := range r.Parents {
+			if slices.Contains(parents, id) {
+				continue
+			}
+			if err := m.AddRoleParent(ctx, r.ID, id); err != nil {
+				return nil, fmt.Errorf("rbac: import role %q: %w", r.ID, err)
+			}
+			report(ChangeEvent{Kind: KindRoleParent, Op: ChangeCreate, RoleID: r.ID, ID: id})
+		}
+	}
+	for _, g := range want.Groups {
+		if err := m.importGroup(ctx, g, curGroups[g.Name], report); err != nil {
+			return nil, fmt.Errorf("rbac: import group %q: %w", g.Name, err)
+		}
+	}
+	return drift, nil
+}
+
+// samePolicy reports whether two roles or two permissions, as exported, have
+// the same fields, treating empty and missing values alike.
+func samePolicy(a, b any) bool {
+	x, errX := json.Marshal(a)
+	y, errY := json.Marshal(b)
+	return errX == nil && errY == nil && bytes.Equal(x, y)
+}
+
+// resolveBundle returns a copy of b whose permission and role IDs are those
+// of the matching stored ones, where they differ: a permission matches the
+// stored one for its resource and action, and a role with an unknown ID the
+// stored role of its name. Soft-deleted entities match too, and are restored
+// by the import.
+func (m *Manager) resolveBundle(ctx context.Context, b *PolicyBundle) (*PolicyBundle, error) {
+	out := &PolicyBundle{Version: b.Version}
+	perms := map[string]string{}
+	for _, p := range b.Permissions {
+		cp := *p
+		stored, err := m.Perms.GetPermissionByResource(ctx, p.Resource, p.Action)
+		if err != nil {
+			return nil, err
+		}
+		if stored != nil {
+			cp.ID = stored.ID
+		}
+		perms[p.ID] = cp.ID
+		out.Permissions = append(out.Permissions, &cp)
+	}
+
+	roles := map[string]string{}
+	for _, r := range b.Roles {
+		cp := &BundleRole{Role: r.Role}
+		stored, err := m.Roles.GetRoleByID(ctx, r.ID)
+		if err == nil && stored == nil && r.Name != "" {
+			stored, err = m.Roles.GetRoleByName(ctx, r.Name)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if stored != nil {
+			cp.ID = stored.ID
+		}
+		roles[r.ID] = cp.ID
+		out.Roles = append(out.Roles, cp)
+	}
+	mapIDs := func(ids []string, to map[string]string) []string {
+		var mapped []string
+		for _, id := range ids {
+			mapped = append(mapped, to[id])
+		}
+		return uniqueIDs(mapped)
+	}
+	for i, r := range b.Roles {
+		out.Roles[i].Permissions = mapIDs(r.Permissions, perms)
+		out.Roles[i].Parents = mapIDs(r.Parents, roles)
+	}
+	for _, g := range b.Groups {
+		cp := &BundleGroup{Group: g.Group, Roles: mapIDs(g.Roles, roles)}
+		if len(g.DefaultRoles) > 0 {
+			cp.DefaultRoles = mapIDs(g.DefaultRoles, roles)
+		}
+		out.Groups = append(out.Groups, cp)
+	}
+	return out, nil
+}
+
+// importRole creates r, or restores it when it was soft-deleted.
+func (m *Manager) importRole(ctx context.Context, r *Role) error {
+	stored, err := m.Roles.GetRoleByID(ctx, r.ID)
+	if err != nil {
+		return err
+	}
+	if stored != nil && stored.DeletedAt != 0 {
+		return m.RestoreRole(ctx, r.ID)
+	}
+	cp := *r
+	return m.CreateRole(ctx, &cp)
+}
+
+// importGroup creates or updates g's Group, when the Manager has a
+// GroupRepo, and binds the roles cur lacks.
+func (m *Manager) importGroup(ctx context.Context, g *BundleGroup, cur *BundleGroup, report func(ChangeEvent)) error {
+	if m.Groups != nil {
+		stored, err := m.Groups.GetGroupByName(ctx, g.Name)
+		if err != nil {
+			return err
+		}
+		want := g.Group
+		switch {
+		case stored == nil:
+			if err := m.CreateGroup(ctx, &want); err != nil {
+				return err
+			}
+			report(ChangeEvent{Kind: KindGroup, Op: ChangeCreate, ID: want.ID, GroupName: g.Name})
+		case stored.Description != want.Description || stored.Owner != want.Owner ||
+			(len(stored.Meta) > 0 || len(want.Meta) > 0) && !reflect.DeepEqual(stored.Meta, want.Meta) ||
+			!slices.Equal(stored.DefaultRoles, want.DefaultRoles):
+			want.ID = stored.ID
+			if err := m.UpdateGroup(ctx, &want); err != nil {
+				return err
+			}
+			report(ChangeEvent{Kind: KindGroup, Op: ChangeUpdate, ID: want.ID, GroupName: g.Name})
+		}
+	}
+	var have []string
+	if cur != nil {
+		have = cur.Roles
+	}
+	for _, roleID := range g.Roles {
+		if slices.Contains(have, roleID) {
+			continue
+		}
+		if err := m.AssignRoleToGroup(ctx, g.Name, roleID); err != nil {
+			return err
+		}
+		report(ChangeEvent{Kind: KindGroupRole, Op: ChangeCreate, GroupName: g.Name, RoleID: roleID})
+	}
+	return nil
+}
+
+// removeMissing removes the groups, bindings, roles and permissions of cur,
+// the store's policy, that b does not have. Bindings to a role or permission
+// being purged go with it.
+func (m *Manager) removeMissing(ctx context.Context, b, cur *PolicyBundle, report func(ChangeEvent)) error {
+	perms := map[string]bool{}
+	for _, p := range b.Permissions {
+		perms[p.ID] = true
+	}
+	roles := map[string]*BundleRole{}
+	for _, r := range b.Roles {
+		roles[r.ID] = r
+	}
+	groups := map[string]*BundleGroup{}
+	for _, g := range b.Groups {
+		groups[g.Name] = g
+	}
+
+	for _, g := range cur.Groups {
+		want := groups[g.Name]
+		if want == nil && m.Groups != nil && g.ID != "" {
+			if err := m.DeleteGroup(ctx, g.ID); err != nil {
+				return err
+			}
+			report(ChangeEvent{Kind: KindGroup, Op: ChangeDelete, ID: g.ID, GroupName: g.Name})
+			continue
+		}
+		for _, roleID := range g.Roles {
+			if roles[roleID] == nil || want != nil && slices.Contains(want.Roles, roleID) {
+				continue
+			}
+			if err := m.UnassignRoleFromGroup(ctx, g.Name, roleID); err != nil {
+				return err
+			}
+			report(ChangeEvent{Kind: KindGroupRole, Op: ChangeDelete, GroupName: g.Name, RoleID: roleID})
+		}
+	}
+
+	for _, r := range cur.Roles {
+		want := roles[r.ID]
+		if want == nil {
+			if err := m.PurgeRole(ctx, r.ID); err != nil {
+				return err
+			}
+			report(ChangeEvent{Kind: KindRole, Op: ChangeDelete, ID: r.ID})
+			continue
+		}
+		for _, permID := range r.Permissions {
+			if perms[permID] && !slices.Contains(want.Permissions, permID) {
+				if err := m.RemovePermissionFromRole(ctx, r.ID, permID); err != nil {
+					return err
+				}
+				report(ChangeEvent{Kind: KindRolePermission, Op: ChangeDelete, RoleID: r.ID, PermissionID: permID})
+			}
+		}
+		for _, parentID := range r.Parents {
+			if roles[parentID] != nil && !slices.Contains(want.Parents, parentID) {
+				if err := m.RemoveRoleParent(ctx, r.ID, parentID); err != nil {
+					return err
+				}
+				report(ChangeEvent{Kind: KindRoleParent, Op: ChangeDelete, RoleID: r.ID, ID: parentID})
+			}
+		}
+	}
+
+	for _, p := range cur.Permissions {
+		if !perms[p.ID] {
+			if err := m.PurgePermission(ctx, p.ID); err != nil {
+				return err
+			}
+			report(ChangeEvent{Kind: KindPermission, Op: ChangeDelete, ID: p.ID})
+		}
+	}
+	return nil
+}
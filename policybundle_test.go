@@ -0,0 +1,172 @@
+package rbac
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestPolicyBundle(t *testing.T) {
+	ctx := context.Background()
+	managers := map[string]func() *Manager{
+		"memory": func() *Manager {
+			m, err := NewMemoryStoreManager(ctx, "", 0)
+			if err != nil {
+				t.Fatalf("NewMemoryStoreManager: %v", err)
+			}
+			return m
+		},
+		"mock": func() *Manager { return NewMockRepoManager(NewMockRepo()) },
+	}
+	for name, newManager := range managers {
+		t.Run(name, func(t *testing.T) {
+			staging, prod := newManager(), newManager()
+			for _, p := range []*Permission{
+				{ID: "docs-read", Resource: "docs/*", Action: ActionRead},
+				{ID: "docs-write", Resource: "docs/*", Action: ActionUpdate},
+			} {
+				if err := staging.CreatePermission(ctx, p); err != nil {
+					t.Fatalf("CreatePermission: %v", err)
+				}
+			}
+			for _, r := range []*Role{{ID: "viewer", Name: "viewer"}, {ID: "editor", Name: "editor"}} {
+				if err := staging.CreateRole(ctx, r); err != nil {
+					t.Fatalf("CreateRole: %v", err)
+				}
+			}
+			if err := staging.AssignPermissionToRole(ctx, "viewer", "docs-read"); err != nil {
+				t.Fatalf("AssignPermissionToRole: %v", err)
+			}
+			if err := staging.AssignPermissionToRole(ctx, "editor", "docs-write"); err != nil {
+				t.Fatalf("AssignPermissionToRole: %v", err)
+			}
+			if err := staging.AddRoleParent(ctx, "editor", "viewer"); err != nil {
+				t.Fatalf("AddRoleParent: %v", err)
+			}
+			if err := staging.CreateGroup(ctx, &Group{ID: "g1", Name: "writers", Description: "Docs writers"}); err != nil {
+				t.Fatalf("CreateGroup: %v", err)
+			}
+			if err := staging.AssignRoleToGroup(ctx, "writers", "editor"); err != nil {
+				t.Fatalf("AssignRoleToGroup: %v", err)
+			}
+			if err := staging.AssignRoleToUser(ctx, "alice", "editor"); err != nil {
+				t.Fatalf("AssignRoleToUser: %v", err)
+			}
+
+			bundle, err := staging.ExportPolicy(ctx)
+			if err != nil {
+				t.Fatalf("ExportPolicy: %v", err)
+			}
+			for _, format := range []FileFormat{FileFormatYAML, FileFormatJSON} {
+				var buf bytes.Buffer
+				if err := WritePolicyBundle(&buf, bundle, format); err != nil {
+					t.Fatalf("WritePolicyBundle(%s): %v", format, err)
+				}
+				read, err := ReadPolicyBundle(&buf, format)
+				if err != nil {
+					t.Fatalf("ReadPolicyBundle(%s): %v", format, err)
+				}
+				if !reflect.DeepEqual(read, bundle) {
+					got, _ := json.Marshal(read)
+					want, _ := json.Marshal(bundle)
+					t.Errorf("%s round trip changed the bundle:\n%s\nwant\n%s", format, got, want)
+				}
+			}
+
+			// merge keeps what the bundle lacks
+			if err := prod.CreateRole(ctx, &Role{ID: "legacy", Name: "legacy"}); err != nil {
+				t.Fatalf("CreateRole: %v", err)
+			}
+			if err := prod.ImportPolicy(ctx, bundle, ImportOptions{}); err != nil {
+				t.Fatalf("ImportPolicy(merge): %v", err)
+			}
+			if err := prod.AssignRoleToUser(ctx, "bob", "editor"); err != nil {
+				t.Fatalf("AssignRoleToUser: %v", err)
+			}
+			for _, action := range []Action{ActionRead, ActionUpdate} {
+				if ok, err := prod.Can(ctx, "bob", "docs/1", action); err != nil || !ok {
+					t.Errorf("Can(bob, %s) = %v, %v after import; want true", action, ok, err)
+				}
+			}
+			if g, err := prod.GetGroupByName(ctx, "writers"); err != nil || g == nil || g.Description != "Docs writers" {
+				t.Errorf("writers = %+v, %v after import", g, err)
+			}
+			if roles, err := prod.ListRolesForGroup(ctx, "writers"); err != nil || !slices.Equal(roles, []string{"editor"}) {
+				t.Errorf("writers holds %v, %v; want editor", roles, err)
+			}
+			if r, err := prod.GetRole(ctx, "legacy"); err != nil || r == nil {
+				t.Errorf("merge removed legacy: %+v, %v", r, err)
+			}
+
+			// importing the same bundle again changes nothing
+			before, _ := prod.PolicyVersion(ctx)
+			if err := prod.ImportPolicy(ctx, bundle, ImportOptions{Mode: ImportMerge}); err != nil {
+				t.Fatalf("ImportPolicy(merge again): %v", err)
+			}
+			if after, _ := prod.PolicyVersion(ctx); after != before {
+				t.Errorf("repeated import changed the policy version from %s to %s", before, after)
+			}
+
+			// replace removes what the bundle lacks
+			for _, r := range bundle.Roles {
+				if r.ID == "editor" {
+					r.Permissions = nil
+				}
+			}
+			bundle.Permissions = slices.DeleteFunc(bundle.Permissions, func(p *Permission) bool { return p.ID == "docs-write" })
+			if err := prod.ImportPolicy(ctx, bundle, ImportOptions{Mode: ImportReplace}); err != nil {
+				t.Fatalf("ImportPolicy(replace): %v", err)
+			}
+			if r, err := prod.GetRole(ctx, "legacy"); err != nil || r != nil {
+				t.Errorf("replace kept legacy: %+v, %v", r, err)
+			}
+			if ok, err := prod.Can(ctx, "bob", "docs/1", ActionUpdate); err != nil || ok {
+				t.Errorf("Can(bob, update) = %v, %v after replace; want false", ok, err)
+			}
+			if ok, err := prod.Can(ctx, "bob", "docs/1", ActionRead); err != nil || !ok {
+				t.Errorf("Can(bob, read) = %v, %v after replace; want true", ok, err)
+			}
+			got, err := prod.ExportPolicy(ctx)
+			if err != nil {
+				t.Fatalf("ExportPolicy: %v", err)
+			}
+			if len(got.Permissions) != 1 || len(got.Roles) != len(bundle.Roles) || len(got.Groups) != 1 {
+				t.Errorf("replaced policy = %+v; want the bundle's", got)
+			}
+		})
+	}
+}
+
+func TestPolicyBundleInvalid(t *testing.T) {
+	ctx := context.Background()
+	mgr := NewMockRepoManager(NewMockRepo())
+	for name, b := range map[string]*PolicyBundle{
+		"version": {Version: 2},
+		"unknown permission": {Version: PolicyBundleVersion, Roles: []*BundleRole{
+			{Role: Role{ID: "editor", Name: "editor"}, Permissions: []string{"missing"}},
+		}},
+		"unknown parent": {Version: PolicyBundleVersion, Roles: []*BundleRole{
+			{Role: Role{ID: "editor", Name: "editor"}, Parents: []string{"missing"}},
+		}},
+		"unknown group role": {Version: PolicyBundleVersion, Groups: []*BundleGroup{
+			{Group: Group{Name: "writers"}, Roles: []string{"missing"}},
+		}},
+	} {
+		if err := mgr.ImportPolicy(ctx, b, ImportOptions{}); !errors.Is(err, ErrInvalidBundle) {
+			t.Errorf("%s: ImportPolicy = %v; want ErrInvalidBundle", name, err)
+		}
+	}
+	if err := mgr.ImportPolicy(ctx, &PolicyBundle{Version: PolicyBundleVersion}, ImportOptions{Mode: "upsert"}); err == nil {
+		t.Errorf("ImportPolicy with an unknown mode succeeded")
+	}
+
+	_, err := ReadPolicyBundle(strings.NewReader("version: 1\nroles:\n  - id: editor\n    permisions: [docs-read]\n"), FileFormatYAML)
+	if !errors.Is(err, ErrInvalidBundle) {
+		t.Errorf("ReadPolicyBundle with a misspelt field = %v; want ErrInvalidBundle", err)
+	}
+}
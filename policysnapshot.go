@@ -0,0 +1,297 @@
+package rbac
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"time"
+)
+
+// KindPolicySnapshot is passed to IDGenerator.NewID for policy snapshots.
+const KindPolicySnapshot = "policy_snapshot"
+
+// PolicySnapshot is a version of the store's policy: the PolicyBundle
+// ExportPolicy returned when it was taken, with who took it and why.
+type PolicySnapshot struct {
+	ID        string    `bson:"id" json:"id"`
+	CreatedAt time.Time `bson:"created_at" json:"created_at"`
+	// CreatedBy is the caller's WithActor, empty when there was none.
+	CreatedBy string       `bson:"created_by,omitempty" json:"created_by,omitempty"`
+	Comment   string       `bson:"comment,omitempty" json:"comment,omitempty"`
+	Policy    PolicyBundle `bson:"policy" json:"policy"`
+}
+
+// PolicyVersionRepo stores policy snapshots.
+type PolicyVersionRepo interface {
+	// SavePolicySnapshot creates the snapshot or replaces the one with its ID.
+	SavePolicySnapshot(ctx context.Context, s *PolicySnapshot) error
+	// GetPolicySnapshot returns the snapshot, or nil, nil.
+	GetPolicySnapshot(ctx context.Context, id string) (*PolicySnapshot, error)
+	// ListPolicySnapshots returns every snapshot, oldest first.
+	ListPolicySnapshots(ctx context.Context) ([]*PolicySnapshot, error)
+}
+
+var (
+	// ErrPolicySnapshotNotFound is returned for an unknown snapshot ID.
+	ErrPolicySnapshotNotFound = errors.New("rbac: policy snapshot not found")
+
+	errNoPolicyVersionRepo = errors.New("rbac: no PolicyVersionRepo configured")
+)
+
+// SnapshotPolicy saves the store's current policy as a new version. When the
+// Manager has a PolicyVersionRepo, ImportPolicy, Apply and RollbackPolicy
+// take one too before changing anything, so each can be undone with
+// RollbackPolicy.
+func (m *Manager) SnapshotPolicy(ctx context.Context, comment string) (*PolicySnapshot, error) {
+	start := time.Now()
+	ctx, span := m.startSpan(ctx, "SnapshotPolicy")
+	defer span.End()
+	var (
+		s   *PolicySnapshot
+		err = errNoPolicyVersionRepo
+	)
+	if m.PolicyVersions != nil {
+		s, err = m.snapshotPolicy(ctx, comment)
+	}
+	m.record(ctx, start, "SnapshotPolicy", err)
+	return s, err
+}
+
+func (m *Manager) snapshotPolicy(ctx context.Context, comment string) (*PolicySnapshot, error) {
+	b, err := m.exportPolicy(ctx)
+	if err != nil {
+		return nil, err
+	}
+	s := &PolicySnapshot{
+		CreatedAt: time.Now(),
+		CreatedBy: Actor(ctx),
+		Comment:   comment,
+		Policy:    *b,
+	}
+	m.assignID(&s.ID, KindPolicySnapshot)
+	if err := m.PolicyVersions.SavePolicySnapshot(ctx, s); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// snapshotBefore takes the snapshot ImportPolicy, Apply and RollbackPolicy
+// take before changing the policy, if the Manager has a PolicyVersionRepo.
+func (m *Manager) snapshotBefore(ctx context.Context, comment string) error {
+	if m.PolicyVersions == nil {
+		return nil
+	}
+	if _, err := m.snapshotPolicy(ctx, comment); err != nil {
+		return fmt.Errorf("rbac: snapshot policy: %w", err)
+	}
+	return nil
+}
+
+// GetPolicySnapshot returns a snapshot, or nil if there is none with the ID.
+func (m *Manager) GetPolicySnapshot(ctx context.Context, id string) (*PolicySnapshot, error) {
+	start := time.Now()
+	ctx, span := m.startSpan(ctx, "GetPolicySnapshot")
+	defer span.End()
+	var (
+		s   *PolicySnapshot
+		err = errNoPolicyVersionRepo
+	)
+	if m.PolicyVersions != nil {
+		s, err = m.PolicyVersions.GetPolicySnapshot(ctx, id)
+	}
+	m.record(ctx, start, "GetPolicySnapshot", err)
+	return s, err
+}
+
+// ListPolicySnapshots returns every snapshot, oldest first.
+func (m *Manager) ListPolicySnapshots(ctx context.Context) ([]*PolicySnapshot, error) {
+	start := time.Now()
+	ctx, span := m.startSpan(ctx, "ListPolicySnapshots")
+	defer span.End()
+	var (
+		out []*PolicySnapshot
+		err = errNoPolicyVersionRepo
+	)
+	if m.PolicyVersions != nil {
+		out, err = m.PolicyVersions.ListPolicySnapshots(ctx)
+	}
+	m.record(ctx, start, "ListPolicySnapshots", err)
+	return out, err
+}
+
+// DiffPolicySnapshots returns the changes that turn the policy of snapshot
+// fromID into that of toID, as DiffPolicy does. An unknown ID fails with
+// ErrPolicySnapshotNotFound.
+func (m *Manager) DiffPolicySnapshots(ctx context.Context, fromID, toID string) ([]ChangeEvent, error) {
+	start := time.Now()
+	ctx, span := m.startSpan(ctx, "DiffPolicySnapshots")
+	defer span.End()
+	var out []ChangeEvent
+	from, err := m.policySnapshot(ctx, fromID)
+	if err == nil {
+		var to *PolicySnapshot
+		if to, err = m.policySnapshot(ctx, toID); err == nil {
+			out = DiffPolicy(&from.Policy, &to.Policy)
+		}
+	}
+	m.record(ctx, start, "DiffPolicySnapshots", err)
+	return out, err
+}
+
+// RollbackPolicy returns the store's policy to that of snapshot id by
+// applying it with Apply, after taking a snapshot of the current policy so
+// the rollback can itself be undone. Users keep their direct roles, except
+// roles the rollback removes.
+func (m *Manager) RollbackPolicy(ctx context.Context, id string) (*ApplyResult, error) {
+	start := time.Now()
+	ctx, span := m.startSpan(ctx, "RollbackPolicy")
+	defer span.End()
+	res := &ApplyResult{}
+	s, err := m.policySnapshot(ctx, id)
+	if err == nil {
+		res, err = m.apply(ctx, &s.Policy, "before RollbackPolicy to "+id)
+	}
+	m.record(ctx, start, "RollbackPolicy", err)
+	return res, err
+}
+
+// policySnapshot returns snapshot id or ErrPolicySnapshotNotFound.
+func (m *Manager) policySnapshot(ctx context.Context, id string) (*PolicySnapshot, error) {
+	if m.PolicyVersions == nil {
+		return nil, errNoPolicyVersionRepo
+	}
+	s, err := m.PolicyVersions.GetPolicySnapshot(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if s == nil {
+		return nil, fmt.Errorf("%w: %s", ErrPolicySnapshotNotFound, id)
+	}
+	return s, nil
+}
+
+// DiffPolicy returns the changes that turn policy from into policy to, in
+// the order Apply would make them: removals first, then creations and
+// updates. Permissions and roles are matched by ID and groups by name;
+// bindings to a removed role or permission go with it and are not listed.
+// Role and permission updates are ones Apply reports as drift.
+func DiffPolicy(from, to *PolicyBundle) []ChangeEvent {
+	fromPerms, toPerms := map[string]*Permission{}, map[string]*Permission{}
+	for _, p := range from.Permissions {
+		fromPerms[p.ID] = p
+	}
+	for _, p := range to.Permissions {
+		toPerms[p.ID] = p
+	}
+	fromRoles, toRoles := map[string]*BundleRole{}, map[string]*BundleRole{}
+	for _, r := range from.Roles {
+		fromRoles[r.ID] = r
+	}
+	for _, r := range to.Roles {
+		toRoles[r.ID] = r
+	}
+	fromGroups, toGroups := map[string]*BundleGroup{}, map[string]*BundleGroup{}
+	for _, g := range from.Groups {
+		fromGroups[g.Name] = g
+	}
+	for _, g := range to.Groups {
+		toGroups[g.Name] = g
+	}
+
+	var out []ChangeEvent
+	for _, g := range from.Groups {
+		want := toGroups[g.Name]
+		if want == nil {
+			out = append(out, ChangeEvent{Kind: KindGroup, Op: ChangeDelete, ID: g.ID, GroupName: g.Name})
+			continue
+		}
+		for _, roleID := range g.Roles {
+			if toRoles[roleID] != nil && !slices.Contains(want.Roles, roleID) {
+				out = append(out, ChangeEvent{Kind: KindGroupRole, Op: ChangeDelete, GroupName: g.Name, RoleID: roleID})
+			}
+		}
+	}
+	for _, r := range from.Roles {
+		want := toRoles[r.ID]
+		if want == nil {
+			out = append(out, ChangeEvent{Kind: KindRole, Op: ChangeDelete, ID: r.ID})
+			continue
+		}
+		for _, permID := range r.Permissions {
+			if toPerms[permID] != nil && !slices.Contains(want.Permissions, permID) {
+				out = append(out, ChangeEvent{Kind: KindRolePermission, Op: ChangeDelete, RoleID: r.ID, PermissionID: permID})
+			}
+		}
+		for _, parentID := range r.Parents {
+			if toRoles[parentID] != nil && !slices.Contains(want.Parents, parentID) {
+				out = append(out, ChangeEvent{Kind: KindRoleParent, Op: ChangeDelete, RoleID: r.ID, ID: parentID})
+			}
+		}
+	}
+	for _, p := range from.Permissions {
+		if toPerms[p.ID] == nil {
+			out = append(out, ChangeEvent{Kind: KindPermission, Op: ChangeDelete, ID: p.ID})
+		}
+	}
+
+	for _, p := range to.Permissions {
+		switch c := fromPerms[p.ID]; {
+		case c == nil:
+			out = append(out, ChangeEvent{Kind: KindPermission, Op: ChangeCreate, ID: p.ID})
+		case !samePolicy(c, p):
+			out = append(out, ChangeEvent{Kind: KindPermission, Op: ChangeUpdate, ID: p.ID})
+		}
+	}
+	for _, r := range to.Roles {
+		switch c := fromRoles[r.ID]; {
+		case c == nil:
+			out = append(out, ChangeEvent{Kind: KindRole, Op: ChangeCreate, ID: r.ID})
+		case !samePolicy(&c.Role, &r.Role):
+			out = append(out, ChangeEvent{Kind: KindRole, Op: ChangeUpdate, ID: r.ID})
+		}
+	}
+	for _, r := range to.Roles {
+		var have, parents []string
+		if c := fromRoles[r.ID]; c != nil {
+			have, parents = c.Permissions, c.Parents
+		}
+		for _, permID := range r.Permissions {
+			if !slices.Contains(have, permID) {
+				out = append(out, ChangeEvent{Kind: KindRolePermission, Op: ChangeCreate, RoleID: r.ID, PermissionID: permID})
+			}
+		}
+		for _, parentID := range r.Parents {
+			if !slices.Contains(parents, parentID) {
+				out = append(out, ChangeEvent{Kind: KindRoleParent, Op: ChangeCreate, RoleID: r.ID, ID: parentID})
+			}
+		}
+	}
+	for _, g := range to.Groups {
+		var have []string
+		switch c := fromGroups[g.Name]; {
+		case c == nil:
+			out = append(out, ChangeEvent{Kind: KindGroup, Op: ChangeCreate, ID: g.ID, GroupName: g.Name})
+		default:
+			have = c.Roles
+			if !samePolicy(&c.Group, &g.Group) {
+				out = append(out, ChangeEvent{Kind: KindGroup, Op: ChangeUpdate, ID: g.ID, GroupName: g.Name})
+			}
+		}
+		for _, roleID := range g.Roles {
+			if !slices.Contains(have, roleID) {
+				out = append(out, ChangeEvent{Kind: KindGroupRole, Op: ChangeCreate, GroupName: g.Name, RoleID: roleID})
+			}
+		}
+	}
+	return out
+}
+
+// sortPolicySnapshots orders snapshots oldest first.
+func sortPolicySnapshots(list []*PolicySnapshot) {
+	sort.Slice(list, func(i, j int) bool {
+		a, b := list[i], list[j]
+		return a.CreatedAt.Before(b.CreatedAt) || (a.CreatedAt.Equal(b.CreatedAt) && a.ID < b.ID)
+	})
+}
@@ -0,0 +1,113 @@
+package rbac
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestPolicySnapshotRollback(t *testing.T) {
+	ctx := context.Background()
+	memory, err := NewMemoryStoreManager(ctx, "", 0)
+	if err != nil {
+		t.Fatalf("NewMemoryStoreManager: %v", err)
+	}
+	for name, mgr := range map[string]*Manager{
+		"memory": memory,
+		"mock":   NewMockRepoManager(NewMockRepo()),
+	} {
+		t.Run(name, func(t *testing.T) {
+			if err := mgr.CreatePermission(ctx, &Permission{ID: "docs-read", Resource: "docs/*", Action: ActionRead}); err != nil {
+				t.Fatalf("CreatePermission: %v", err)
+			}
+			if err := mgr.CreateRole(ctx, &Role{ID: "viewer", Name: "viewer"}); err != nil {
+				t.Fatalf("CreateRole: %v", err)
+			}
+			if err := mgr.AssignPermissionToRole(ctx, "viewer", "docs-read"); err != nil {
+				t.Fatalf("AssignPermissionToRole: %v", err)
+			}
+			if err := mgr.AssignRoleToUser(ctx, "alice", "viewer"); err != nil {
+				t.Fatalf("AssignRoleToUser: %v", err)
+			}
+			good, err := mgr.SnapshotPolicy(ctx, "known good")
+			if err != nil {
+				t.Fatalf("SnapshotPolicy: %v", err)
+			}
+
+			// a bad import wipes viewer's permission and adds a stray role
+			bad, err := mgr.ExportPolicy(ctx)
+			if err != nil {
+				t.Fatalf("ExportPolicy: %v", err)
+			}
+			bad.Permissions = nil
+			for _, r := range bad.Roles {
+				r.Permissions = nil
+			}
+			bad.Roles = append(bad.Roles, &BundleRole{Role: Role{ID: "stray", Name: "stray"}})
+			if err := mgr.ImportPolicy(ctx, bad, ImportOptions{Mode: ImportReplace}); err != nil {
+				t.Fatalf("ImportPolicy: %v", err)
+			}
+			if ok, _ := mgr.Can(ctx, "alice", "docs/1", ActionRead); ok {
+				t.Fatalf("Can(alice) = true after the bad import")
+			}
+
+			snaps, err := mgr.ListPolicySnapshots(ctx)
+			if err != nil || len(snaps) != 2 || snaps[0].ID != good.ID || snaps[1].Comment != "before ImportPolicy" {
+				t.Fatalf("ListPolicySnapshots = %+v, %v; want the manual and the import snapshot", snaps, err)
+			}
+			now, err := mgr.SnapshotPolicy(ctx, "")
+			if err != nil {
+				t.Fatalf("SnapshotPolicy: %v", err)
+			}
+			diff, err := mgr.DiffPolicySnapshots(ctx, good.ID, now.ID)
+			if err != nil {
+				t.Fatalf("DiffPolicySnapshots: %v", err)
+			}
+			want := []ChangeEvent{
+				{Kind: KindPermission, Op: ChangeDelete, ID: "docs-read"},
+				{Kind: KindRole, Op: ChangeCreate, ID: "stray"},
+			}
+			if len(diff) != len(want) {
+				t.Fatalf("diff = %+v; want %+v", diff, want)
+			}
+			for i := range want {
+				if diff[i] != want[i] {
+					t.Errorf("diff[%d] = %+v; want %+v", i, diff[i], want[i])
+				}
+			}
+
+			res, err := mgr.RollbackPolicy(ctx, good.ID)
+			if err != nil {
+				t.Fatalf("RollbackPolicy: %v", err)
+			}
+			if len(res.Changes) != 3 {
+				t.Errorf("rollback made %+v; want stray removed and docs-read restored and bound", res.Changes)
+			}
+			if ok, err := mgr.Can(ctx, "alice", "docs/1", ActionRead); err != nil || !ok {
+				t.Errorf("Can(alice) = %v, %v after rollback; want true", ok, err)
+			}
+			if r, err := mgr.GetRole(ctx, "stray"); err != nil || r != nil {
+				t.Errorf("stray = %+v, %v after rollback; want it removed", r, err)
+			}
+			if diff, err := mgr.DiffPolicySnapshots(ctx, good.ID, good.ID); err != nil || len(diff) != 0 {
+				t.Errorf("diff of a snapshot with itself = %+v, %v", diff, err)
+			}
+
+			if _, err := mgr.RollbackPolicy(ctx, "missing"); !errors.Is(err, ErrPolicySnapshotNotFound) {
+				t.Errorf("RollbackPolicy(missing) = %v; want ErrPolicySnapshotNotFound", err)
+			}
+		})
+	}
+}
+
+func TestPolicySnapshotNoRepo(t *testing.T) {
+	ctx := context.Background()
+	mgr := NewMockRepoManager(NewMockRepo())
+	mgr.PolicyVersions = nil
+	if err := mgr.ImportPolicy(ctx, &PolicyBundle{Version: PolicyBundleVersion}, ImportOptions{}); err != nil {
+		t.Errorf("ImportPolicy without a PolicyVersionRepo: %v", err)
+	}
+	if _, err := mgr.SnapshotPolicy(ctx, ""); err == nil {
+		t.Errorf("SnapshotPolicy without a PolicyVersionRepo succeeded")
+	}
+}
@@ -0,0 +1,120 @@
+package rbac
+
+import (
+	"context"
+	"runtime"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// WorkerPoolConfig configures a WorkerPool. Zero values select the defaults.
+type WorkerPoolConfig struct {
+	// Parallelism is the number of tasks run at once. Defaults to GOMAXPROCS.
+	Parallelism int
+	// Rate caps how many tasks start per second; zero means no cap. Share one
+	// pool among the Managers of a backing store so the cap protects it
+	// across every batch.
+	Rate float64
+	// Burst is how many tasks may start at once under Rate. Defaults to
+	// Parallelism.
+	Burst int
+}
+
+// WorkerPool runs the tasks of batch operations such as CanBatch with bounded
+// parallelism and an optional rate limit, so large batches finish quickly
+// without overwhelming the database. It is safe for concurrent use.
+type WorkerPool struct {
+	parallelism int
+	limiter     *rate.Limiter
+}
+
+// NewWorkerPool returns a pool configured by cfg.
+func NewWorkerPool(cfg WorkerPoolConfig) *WorkerPool {
+	if cfg.Parallelism <= 0 {
+		cfg.Parallelism = runtime.GOMAXPROCS(0)
+	}
+	p := &WorkerPool{parallelism: cfg.Parallelism}
+	if cfg.Rate > 0 {
+		if cfg.Burst <= 0 {
+			cfg.Burst = cfg.Parallelism
+		}
+		p.limiter = rate.NewLimiter(rate.Limit(cfg.Rate), cfg.Burst)
+	}
+	return p
+}
+
+// Run calls fn for every index in [0, n) across the pool's workers and waits
+// for them. The first error cancels the context given to the remaining tasks
+// and is returned.
+func (p *WorkerPool) Run(ctx context.Context, n int, fn func(ctx context.Context, i int) error) error {
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	workers := min(p.parallelism, n)
+	next := make(chan int)
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				if err := fn(ctx, i); err != nil {
+					cancel(err)
+				}
+			}
+		}()
+	}
+
+feed:
+	for i := 0; i < n; i++ {
+		if p.limiter != nil {
+			if err := p.limiter.Wait(ctx); err != nil {
+				break
+			}
+		}
+		select {
+		case next <- i:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(next)
+	wg.Wait()
+	return context.Cause(ctx)
+}
+
+// pool returns the Manager's WorkerPool, or a default one.
+func (m *Manager) pool() *WorkerPool {
+	if m.Pool != nil {
+		return m.Pool
+	}
+	return NewWorkerPool(WorkerPoolConfig{})
+}
+
+// AccessCheck is one question for CanBatch.
+type AccessCheck struct {
+	UserID   string `json:"user_id"`
+	Resource string `json:"resource"`
+	Action   Action `json:"action"`
+}
+
+// CanBatch answers many checks, possibly for different users, on the
+// Manager's WorkerPool. Results line up with checks.
+func (m *Manager) CanBatch(ctx context.Context, checks []AccessCheck) ([]bool, error) {
+	start := time.Now()
+	ctx, span := m.startSpan(ctx, "CanBatch")
+	defer span.End()
+	out := make([]bool, len(checks))
+	err := m.pool().Run(ctx, len(checks), func(ctx context.Context, i int) error {
+		ok, err := m.Can(ctx, checks[i].UserID, checks[i].Resource, checks[i].Action)
+		out[i] = ok
+		return err
+	})
+	m.record(ctx, start, "CanBatch", err)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
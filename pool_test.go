@@ -0,0 +1,108 @@
+package rbac
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWorkerPoolRun(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Parallelism", func(t *testing.T) {
+		p := NewWorkerPool(WorkerPoolConfig{Parallelism: 3})
+		var running, peak atomic.Int32
+		var done [20]atomic.Bool
+		err := p.Run(ctx, len(done), func(ctx context.Context, i int) error {
+			n := running.Add(1)
+			for {
+				old := peak.Load()
+				if n <= old || peak.CompareAndSwap(old, n) {
+					break
+				}
+			}
+			time.Sleep(5 * time.Millisecond)
+			running.Add(-1)
+			done[i].Store(true)
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("Run: %v", err)
+		}
+		if got := peak.Load(); got > 3 {
+			t.Errorf("expected at most 3 tasks at once, saw %d", got)
+		}
+		for i := range done {
+			if !done[i].Load() {
+				t.Errorf("task %d did not run", i)
+			}
+		}
+	})
+
+	t.Run("Rate", func(t *testing.T) {
+		p := NewWorkerPool(WorkerPoolConfig{Parallelism: 4, Rate: 100, Burst: 1})
+		start := time.Now()
+		if err := p.Run(ctx, 6, func(context.Context, int) error { return nil }); err != nil {
+			t.Fatalf("Run: %v", err)
+		}
+		if elapsed := time.Since(start); elapsed < 40*time.Millisecond {
+			t.Errorf("expected 6 tasks at 100/s to take ~50ms, took %v", elapsed)
+		}
+	})
+
+	t.Run("FirstErrorStops", func(t *testing.T) {
+		p := NewWorkerPool(WorkerPoolConfig{Parallelism: 1})
+		boom := errors.New("boom")
+		var ran atomic.Int32
+		err := p.Run(ctx, 100, func(ctx context.Context, i int) error {
+			ran.Add(1)
+			if i == 2 {
+				return boom
+			}
+			return nil
+		})
+		if !errors.Is(err, boom) {
+			t.Fatalf("expected boom, got %v", err)
+		}
+		if n := ran.Load(); n > 4 {
+			t.Errorf("expected the pool to stop feeding tasks after the error, %d ran", n)
+		}
+	})
+}
+
+func TestCanBatch(t *testing.T) {
+	ctx := context.Background()
+	mgr, err := NewMemoryStoreManager(ctx, "", 0)
+	if err != nil {
+		t.Fatalf("NewMemoryStoreManager: %v", err)
+	}
+	mgr.Pool = NewWorkerPool(WorkerPoolConfig{Parallelism: 2})
+
+	perm := &Permission{ID: "p1", Resource: "survey", Action: ActionRead}
+	if err := mgr.CreatePermission(ctx, perm); err != nil {
+		t.Fatalf("CreatePermission: %v", err)
+	}
+	if err := mgr.CreateRole(ctx, &Role{ID: "r1", Name: "reader"}); err != nil {
+		t.Fatalf("CreateRole: %v", err)
+	}
+	_ = mgr.AssignPermissionToRole(ctx, "r1", "p1")
+	_ = mgr.AssignRoleToUser(ctx, "alice", "r1")
+
+	checks := []AccessCheck{
+		{UserID: "alice", Resource: "survey", Action: ActionRead},
+		{UserID: "bob", Resource: "survey", Action: ActionRead},
+		{UserID: "alice", Resource: "survey", Action: ActionDelete},
+	}
+	got, err := mgr.CanBatch(ctx, checks)
+	if err != nil {
+		t.Fatalf("CanBatch: %v", err)
+	}
+	want := []bool{true, false, false}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("check %d: got %v, want %v", i, got[i], want[i])
+		}
+	}
+}
@@ -3,24 +3,32 @@ package rbac
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"time"
 
-	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 // Ensure PostgresStore implements all interfaces:
 var (
-	_ PermissionRepo     = (*PostgresStore)(nil)
-	_ RoleRepo           = (*PostgresStore)(nil)
-	_ UserRepo           = (*PostgresStore)(nil)
-	_ RolePermissionRepo = (*PostgresStore)(nil)
-	_ UserRoleRepo       = (*PostgresStore)(nil)
-	_ UserGroupRepo      = (*PostgresStore)(nil)
-	_ GroupRoleRepo      = (*PostgresStore)(nil)
+	_ PermissionRepo           = (*PostgresStore)(nil)
+	_ RoleRepo                 = (*PostgresStore)(nil)
+	_ UserRepo                 = (*PostgresStore)(nil)
+	_ UserStatusRepo           = (*PostgresStore)(nil)
+	_ EmailVerificationRepo    = (*PostgresStore)(nil)
+	_ RolePermissionRepo       = (*PostgresStore)(nil)
+	_ UserRoleRepo             = (*PostgresStore)(nil)
+	_ UserGroupRepo            = (*PostgresStore)(nil)
+	_ GroupRoleRepo            = (*PostgresStore)(nil)
+	_ ExpiringMembershipLister = (*PostgresStore)(nil)
+	_ ExportPager              = (*PostgresStore)(nil)
+	_ AuditRepo                = (*PostgresStore)(nil)
+	_ BulkUserRoleRepo         = (*PostgresStore)(nil)
+	_ BulkRolePermissionRepo   = (*PostgresStore)(nil)
+	_ HealthChecker            = (*PostgresStore)(nil)
 )
 
 //
@@ -28,7 +36,8 @@ var (
 //
 
 type PostgresStore struct {
-	db *pgxpool.Pool
+	db  *pgxpool.Pool
+	ids IDGenerator
 }
 
 // NewPostgresStore creates the store and ensures the schema is in place.
@@ -40,6 +49,19 @@ func NewPostgresStore(ctx context.Context, db *pgxpool.Pool) (*PostgresStore, er
 	return s, nil
 }
 
+// SetIDGenerator changes how the store generates IDs for new entities.
+func (s *PostgresStore) SetIDGenerator(g IDGenerator) {
+	s.ids = g
+}
+
+// Health implements HealthChecker by pinging the database.
+func (s *PostgresStore) Health(ctx context.Context) error {
+	if err := s.db.Ping(ctx); err != nil {
+		return fmt.Errorf("postgres_store: %w", err)
+	}
+	return nil
+}
+
 // NewPostgresStoreManager wraps the store in a Manager and seeds the default role.
 func NewPostgresStoreManager(ctx context.Context, db *pgxpool.Pool) (*Manager, error) {
 	s, err := NewPostgresStore(ctx, db)
@@ -75,28 +97,74 @@ func (s *PostgresStore) EnsureSchema(ctx context.Context) error {
 	ddl := `
 	CREATE TABLE IF NOT EXISTS permissions (
 		id          TEXT PRIMARY KEY,
+		name        TEXT        NOT NULL DEFAULT '',
+		description TEXT        NOT NULL DEFAULT '',
+		labels      TEXT        NOT NULL DEFAULT '',
 		resource    TEXT        NOT NULL,
 		action      TEXT        NOT NULL,
+		effect      TEXT        NOT NULL DEFAULT '',
+		condition   TEXT        NOT NULL DEFAULT '',
 		created_at  BIGINT      NOT NULL DEFAULT 0,
+		updated_at  BIGINT      NOT NULL DEFAULT 0,
+		created_by  TEXT        NOT NULL DEFAULT '',
+		updated_by  TEXT        NOT NULL DEFAULT '',
+		tenant_id   TEXT        NOT NULL DEFAULT '',
 		CONSTRAINT uq_permissions_resource_action UNIQUE (resource, action)
 	);
+	ALTER TABLE permissions ADD COLUMN IF NOT EXISTS effect TEXT NOT NULL DEFAULT '';
+	ALTER TABLE permissions ADD COLUMN IF NOT EXISTS condition TEXT NOT NULL DEFAULT '';
+	ALTER TABLE permissions ADD COLUMN IF NOT EXISTS updated_at BIGINT NOT NULL DEFAULT 0;
+	ALTER TABLE permissions ADD COLUMN IF NOT EXISTS created_by TEXT NOT NULL DEFAULT '';
+	ALTER TABLE permissions ADD COLUMN IF NOT EXISTS updated_by TEXT NOT NULL DEFAULT '';
+	ALTER TABLE permissions ADD COLUMN IF NOT EXISTS name TEXT NOT NULL DEFAULT '';
+	ALTER TABLE permissions ADD COLUMN IF NOT EXISTS description TEXT NOT NULL DEFAULT '';
+	ALTER TABLE permissions ADD COLUMN IF NOT EXISTS labels TEXT NOT NULL DEFAULT '';
+	ALTER TABLE permissions ADD COLUMN IF NOT EXISTS tenant_id TEXT NOT NULL DEFAULT '';
 
 	CREATE TABLE IF NOT EXISTS roles (
 		id          TEXT PRIMARY KEY,
 		name        TEXT        NOT NULL,
 		description TEXT        NOT NULL DEFAULT '',
+		meta        TEXT        NOT NULL DEFAULT '',
+		template    BOOLEAN     NOT NULL DEFAULT FALSE,
+		priority    INTEGER     NOT NULL DEFAULT 0,
+		generators  TEXT        NOT NULL DEFAULT '',
 		created_at  BIGINT      NOT NULL DEFAULT 0,
+		updated_at  BIGINT      NOT NULL DEFAULT 0,
+		created_by  TEXT        NOT NULL DEFAULT '',
+		updated_by  TEXT        NOT NULL DEFAULT '',
+		tenant_id   TEXT        NOT NULL DEFAULT '',
 		CONSTRAINT uq_roles_name UNIQUE (name)
 	);
+	ALTER TABLE roles ADD COLUMN IF NOT EXISTS meta TEXT NOT NULL DEFAULT '';
+	ALTER TABLE roles ADD COLUMN IF NOT EXISTS updated_at BIGINT NOT NULL DEFAULT 0;
+	ALTER TABLE roles ADD COLUMN IF NOT EXISTS created_by TEXT NOT NULL DEFAULT '';
+	ALTER TABLE roles ADD COLUMN IF NOT EXISTS updated_by TEXT NOT NULL DEFAULT '';
+	ALTER TABLE roles ADD COLUMN IF NOT EXISTS template BOOLEAN NOT NULL DEFAULT FALSE;
+	ALTER TABLE roles ADD COLUMN IF NOT EXISTS priority INTEGER NOT NULL DEFAULT 0;
+	ALTER TABLE roles ADD COLUMN IF NOT EXISTS generators TEXT NOT NULL DEFAULT '';
+	ALTER TABLE roles ADD COLUMN IF NOT EXISTS tenant_id TEXT NOT NULL DEFAULT '';
 
 	CREATE TABLE IF NOT EXISTS users (
 		id          TEXT PRIMARY KEY,
 		username    TEXT        NOT NULL,
 		email       TEXT        NOT NULL,
+		email_verified BOOLEAN NOT NULL DEFAULT FALSE,
+		status      TEXT        NOT NULL DEFAULT '',
 		created_at  BIGINT      NOT NULL DEFAULT 0,
+		updated_at  BIGINT      NOT NULL DEFAULT 0,
+		created_by  TEXT        NOT NULL DEFAULT '',
+		updated_by  TEXT        NOT NULL DEFAULT '',
+		tenant_id   TEXT        NOT NULL DEFAULT '',
 		CONSTRAINT uq_users_username UNIQUE (username),
 		CONSTRAINT uq_users_email    UNIQUE (email)
 	);
+	ALTER TABLE users ADD COLUMN IF NOT EXISTS updated_at BIGINT NOT NULL DEFAULT 0;
+	ALTER TABLE users ADD COLUMN IF NOT EXISTS created_by TEXT NOT NULL DEFAULT '';
+	ALTER TABLE users ADD COLUMN IF NOT EXISTS updated_by TEXT NOT NULL DEFAULT '';
+	ALTER TABLE users ADD COLUMN IF NOT EXISTS email_verified BOOLEAN NOT NULL DEFAULT FALSE;
+	ALTER TABLE users ADD COLUMN IF NOT EXISTS status TEXT NOT NULL DEFAULT '';
+	ALTER TABLE users ADD COLUMN IF NOT EXISTS tenant_id TEXT NOT NULL DEFAULT '';
 
 	CREATE TABLE IF NOT EXISTS role_permissions (
 		role_id       TEXT   NOT NULL,
@@ -117,8 +185,20 @@ func (s *PostgresStore) EnsureSchema(ctx context.Context) error {
 		user_id     TEXT   NOT NULL,
 		group_name  TEXT   NOT NULL,
 		created_at  BIGINT NOT NULL DEFAULT 0,
+		updated_at  BIGINT NOT NULL DEFAULT 0,
+		created_by  TEXT   NOT NULL DEFAULT '',
+		updated_by  TEXT   NOT NULL DEFAULT '',
+		level       TEXT   NOT NULL DEFAULT '',
+		expires_at  BIGINT NOT NULL DEFAULT 0,
+		tenant_id   TEXT   NOT NULL DEFAULT '',
 		CONSTRAINT uq_user_groups UNIQUE (user_id, group_name)
 	);
+	ALTER TABLE user_groups ADD COLUMN IF NOT EXISTS updated_at BIGINT NOT NULL DEFAULT 0;
+	ALTER TABLE user_groups ADD COLUMN IF NOT EXISTS created_by TEXT NOT NULL DEFAULT '';
+	ALTER TABLE user_groups ADD COLUMN IF NOT EXISTS updated_by TEXT NOT NULL DEFAULT '';
+	ALTER TABLE user_groups ADD COLUMN IF NOT EXISTS level TEXT NOT NULL DEFAULT '';
+	ALTER TABLE user_groups ADD COLUMN IF NOT EXISTS expires_at BIGINT NOT NULL DEFAULT 0;
+	ALTER TABLE user_groups ADD COLUMN IF NOT EXISTS tenant_id TEXT NOT NULL DEFAULT '';
 
 	CREATE TABLE IF NOT EXISTS group_roles (
 		group_name  TEXT   NOT NULL,
@@ -126,6 +206,26 @@ func (s *PostgresStore) EnsureSchema(ctx context.Context) error {
 		created_at  BIGINT NOT NULL DEFAULT 0,
 		PRIMARY KEY (group_name, role_id)
 	);
+
+	CREATE INDEX IF NOT EXISTS user_roles_by_role ON user_roles (role_id);
+	CREATE INDEX IF NOT EXISTS group_roles_by_role ON group_roles (role_id);
+	CREATE INDEX IF NOT EXISTS user_groups_by_expiry ON user_groups (expires_at);
+
+	CREATE TABLE IF NOT EXISTS audit_log (
+		id        TEXT PRIMARY KEY,
+		at        BIGINT NOT NULL,
+		actor     TEXT   NOT NULL DEFAULT '',
+		kind      TEXT   NOT NULL,
+		method    TEXT   NOT NULL,
+		target    TEXT   NOT NULL DEFAULT '',
+		resource  TEXT   NOT NULL DEFAULT '',
+		action    TEXT   NOT NULL DEFAULT '',
+		outcome   TEXT   NOT NULL,
+		error_msg TEXT   NOT NULL DEFAULT ''
+	);
+	CREATE INDEX IF NOT EXISTS audit_log_by_actor ON audit_log (actor, at);
+	CREATE INDEX IF NOT EXISTS audit_log_by_target ON audit_log (target, at);
+	CREATE INDEX IF NOT EXISTS audit_log_by_at ON audit_log (at);
 	`
 
 	_, err := s.db.Exec(ctx, ddl)
@@ -138,10 +238,9 @@ func (s *PostgresStore) EnsureSchema(ctx context.Context) error {
 
 func (s *PostgresStore) GetUserByID(ctx context.Context, id string) (*User, error) {
 	row := s.db.QueryRow(ctx,
-		`SELECT id, username, email, created_at FROM users WHERE id = $1`, id)
+		`SELECT id, username, email, email_verified, status, created_at, updated_at, created_by, updated_by, tenant_id FROM users WHERE id = $1`, id)
 
-	u := &User{}
-	err := row.Scan(&u.ID, &u.Username, &u.Email, &u.CreatedAt)
+	u, err := scanUser(row.Scan)
 	if errors.Is(err, pgx.ErrNoRows) {
 		return nil, nil
 	}
@@ -175,11 +274,10 @@ func (s *PostgresStore) GetUserByMeta(ctx context.Context, meta map[string]inter
 	}
 
 	row := s.db.QueryRow(ctx,
-		fmt.Sprintf(`SELECT id, username, email, created_at FROM users WHERE %s`, where),
+		fmt.Sprintf(`SELECT id, username, email, email_verified, status, created_at, updated_at, created_by, updated_by, tenant_id FROM users WHERE %s`, where),
 		args...)
 
-	u := &User{}
-	err := row.Scan(&u.ID, &u.Username, &u.Email, &u.CreatedAt)
+	u, err := scanUser(row.Scan)
 	if errors.Is(err, pgx.ErrNoRows) {
 		return nil, nil
 	}
@@ -189,18 +287,68 @@ func (s *PostgresStore) GetUserByMeta(ctx context.Context, meta map[string]inter
 	return u, nil
 }
 
+func (s *PostgresStore) ListAllUsers(ctx context.Context) ([]*User, error) {
+	rows, err := s.db.Query(ctx,
+		`SELECT id, username, email, email_verified, status, created_at, updated_at, created_by, updated_by, tenant_id FROM users`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []*User
+	for rows.Next() {
+		u, err := scanUser(rows.Scan)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode user: %w", err)
+		}
+		out = append(out, u)
+	}
+	return out, rows.Err()
+}
+
 func (s *PostgresStore) CreateUser(ctx context.Context, u *User) error {
 	if u.ID == "" {
-		u.ID = uuid.New().String()
+		u.ID = generateID(s.ids, KindUser)
 	}
 	u.CreatedAt = time.Now().Unix()
 
 	_, err := s.db.Exec(ctx,
-		`INSERT INTO users (id, username, email, created_at) VALUES ($1, $2, $3, $4)`,
-		u.ID, u.Username, u.Email, u.CreatedAt)
+		`INSERT INTO users (id, username, email, email_verified, status, created_at, updated_at, created_by, updated_by, tenant_id) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`,
+		u.ID, u.Username, u.Email, u.EmailVerified, string(u.Status), u.CreatedAt, u.UpdatedAt, u.CreatedBy, u.UpdatedBy, u.TenantID)
+	return err
+}
+
+func (s *PostgresStore) SetUserStatus(ctx context.Context, id string, status UserStatus) error {
+	var updatedBy string
+	var updatedAt int64
+	stampUpdated(ctx, time.Now(), &updatedBy, &updatedAt)
+	_, err := s.db.Exec(ctx,
+		`UPDATE users SET status = $1, updated_at = $2, updated_by = $3 WHERE id = $4`,
+		string(status), updatedAt, updatedBy, id)
 	return err
 }
 
+func (s *PostgresStore) SetEmailVerified(ctx context.Context, id string, verified bool) error {
+	var updatedBy string
+	var updatedAt int64
+	stampUpdated(ctx, time.Now(), &updatedBy, &updatedAt)
+	_, err := s.db.Exec(ctx,
+		`UPDATE users SET email_verified = $1, updated_at = $2, updated_by = $3 WHERE id = $4`,
+		verified, updatedAt, updatedBy, id)
+	return err
+}
+
+// scanUser reads a users row of the SQL stores, selected as id, username,
+// email, email_verified, status, created_at, updated_at, created_by,
+// updated_by, tenant_id. Like scanUserGroup, it returns the user even on an error.
+func scanUser(scan func(dest ...any) error) (*User, error) {
+	u := &User{}
+	var status string
+	err := scan(&u.ID, &u.Username, &u.Email, &u.EmailVerified, &status, &u.CreatedAt, &u.UpdatedAt, &u.CreatedBy, &u.UpdatedBy, &u.TenantID)
+	u.Status = UserStatus(status)
+	return u, err
+}
+
 func (s *PostgresStore) DeleteUser(ctx context.Context, id string) error {
 	_, err := s.db.Exec(ctx, `DELETE FROM users WHERE id = $1`, id)
 	return err
@@ -208,7 +356,7 @@ func (s *PostgresStore) DeleteUser(ctx context.Context, id string) error {
 
 func (s *PostgresStore) GetGroupsByUserID(ctx context.Context, userID string) ([]*UserGroup, error) {
 	rows, err := s.db.Query(ctx,
-		`SELECT id, user_id, group_name, created_at FROM user_groups WHERE user_id = $1`, userID)
+		`SELECT id, user_id, group_name, created_at, updated_at, created_by, updated_by, level, expires_at, tenant_id FROM user_groups WHERE user_id = $1`, userID)
 	if err != nil {
 		return nil, err
 	}
@@ -216,8 +364,8 @@ func (s *PostgresStore) GetGroupsByUserID(ctx context.Context, userID string) ([
 
 	var out []*UserGroup
 	for rows.Next() {
-		ug := &UserGroup{}
-		if err := rows.Scan(&ug.ID, &ug.UserID, &ug.GroupName, &ug.CreatedAt); err != nil {
+		ug, err := scanUserGroup(rows.Scan)
+		if err != nil {
 			return nil, err
 		}
 		out = append(out, ug)
@@ -231,36 +379,49 @@ func (s *PostgresStore) GetGroupsByUserID(ctx context.Context, userID string) ([
 
 func (s *PostgresStore) GetPermissionByID(ctx context.Context, id string) (*Permission, error) {
 	row := s.db.QueryRow(ctx,
-		`SELECT id, resource, action, created_at FROM permissions WHERE id = $1`, id)
+		`SELECT id, name, description, labels, resource, action, effect, condition, created_at, updated_at, created_by, updated_by, tenant_id FROM permissions WHERE id = $1`, id)
 
-	p := &Permission{}
-	var action string
-	err := row.Scan(&p.ID, &p.Resource, &action, &p.CreatedAt)
+	p, err := scanPermission(row.Scan)
 	if errors.Is(err, pgx.ErrNoRows) {
 		return nil, nil
 	}
 	if err != nil {
 		return nil, err
 	}
-	p.Action = Action(action)
 	return p, nil
 }
 
+func (s *PostgresStore) ListAllPermissions(ctx context.Context) ([]*Permission, error) {
+	rows, err := s.db.Query(ctx,
+		`SELECT id, name, description, labels, resource, action, effect, condition, created_at, updated_at, created_by, updated_by, tenant_id FROM permissions`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []*Permission
+	for rows.Next() {
+		p, err := scanPermission(rows.Scan)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode permission: %w", err)
+		}
+		out = append(out, p)
+	}
+	return out, rows.Err()
+}
+
 func (s *PostgresStore) GetPermissionByResource(ctx context.Context, resource string, action Action) (*Permission, error) {
 	row := s.db.QueryRow(ctx,
-		`SELECT id, resource, action, created_at FROM permissions WHERE resource = $1 AND action = $2`,
+		`SELECT id, name, description, labels, resource, action, effect, condition, created_at, updated_at, created_by, updated_by, tenant_id FROM permissions WHERE resource = $1 AND action = $2`,
 		resource, string(action))
 
-	p := &Permission{}
-	var act string
-	err := row.Scan(&p.ID, &p.Resource, &act, &p.CreatedAt)
+	p, err := scanPermission(row.Scan)
 	if errors.Is(err, pgx.ErrNoRows) {
 		return nil, nil
 	}
 	if err != nil {
 		return nil, err
 	}
-	p.Action = Action(act)
 	return p, nil
 }
 
@@ -271,12 +432,18 @@ func (s *PostgresStore) CreatePermission(ctx context.Context, p *Permission) err
 		return nil
 	}
 
-	p.ID = uuid.New().String()
+	if p.ID == "" {
+		p.ID = generateID(s.ids, KindPermission)
+	}
 	p.CreatedAt = time.Now().Unix()
+	labels, err := encodeLabels(p.Labels)
+	if err != nil {
+		return err
+	}
 
-	_, err := s.db.Exec(ctx,
-		`INSERT INTO permissions (id, resource, action, created_at) VALUES ($1, $2, $3, $4)`,
-		p.ID, p.Resource, string(p.Action), p.CreatedAt)
+	_, err = s.db.Exec(ctx,
+		`INSERT INTO permissions (id, name, description, labels, resource, action, effect, condition, created_at, updated_at, created_by, updated_by, tenant_id) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)`,
+		p.ID, p.Name, p.Description, labels, p.Resource, string(p.Action), string(p.Effect), p.Condition, p.CreatedAt, p.UpdatedAt, p.CreatedBy, p.UpdatedBy, p.TenantID)
 	return err
 }
 
@@ -290,42 +457,66 @@ func (s *PostgresStore) DeletePermission(ctx context.Context, id string) error {
 //
 
 func (s *PostgresStore) CreateRole(ctx context.Context, r *Role) error {
-	r.ID = uuid.New().String()
+	if r.ID == "" {
+		r.ID = generateID(s.ids, KindRole)
+	}
 	r.CreatedAt = time.Now().Unix()
+	meta, err := encodeMeta(r.Meta)
+	if err != nil {
+		return err
+	}
+	generators, err := encodeGenerators(r.Generators)
+	if err != nil {
+		return err
+	}
 
-	_, err := s.db.Exec(ctx,
-		`INSERT INTO roles (id, name, description, created_at) VALUES ($1, $2, $3, $4)`,
-		r.ID, r.Name, r.Description, r.CreatedAt)
+	_, err = s.db.Exec(ctx,
+		`INSERT INTO roles (id, name, description, meta, template, priority, generators, created_at, updated_at, created_by, updated_by, tenant_id) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)`,
+		r.ID, r.Name, r.Description, meta, r.Template, r.Priority, generators, r.CreatedAt, r.UpdatedAt, r.CreatedBy, r.UpdatedBy, r.TenantID)
 	return err
 }
 
 func (s *PostgresStore) GetRoleByName(ctx context.Context, name string) (*Role, error) {
 	row := s.db.QueryRow(ctx,
-		`SELECT id, name, description, created_at FROM roles WHERE name = $1`, name)
+		`SELECT id, name, description, meta, template, priority, generators, created_at, updated_at, created_by, updated_by, tenant_id FROM roles WHERE name = $1`, name)
 
 	r := &Role{}
-	err := row.Scan(&r.ID, &r.Name, &r.Description, &r.CreatedAt)
+	var meta, generators string
+	err := row.Scan(&r.ID, &r.Name, &r.Description, &meta, &r.Template, &r.Priority, &generators, &r.CreatedAt, &r.UpdatedAt, &r.CreatedBy, &r.UpdatedBy, &r.TenantID)
 	if errors.Is(err, pgx.ErrNoRows) {
 		return nil, nil
 	}
 	if err != nil {
 		return nil, err
 	}
+	if err := decodeMeta(meta, &r.Meta); err != nil {
+		return nil, fmt.Errorf("failed to decode role meta: %w", err)
+	}
+	if err := decodeGenerators(generators, &r.Generators); err != nil {
+		return nil, fmt.Errorf("failed to decode role generators: %w", err)
+	}
 	return r, nil
 }
 
 func (s *PostgresStore) GetRoleByID(ctx context.Context, id string) (*Role, error) {
 	row := s.db.QueryRow(ctx,
-		`SELECT id, name, description, created_at FROM roles WHERE id = $1`, id)
+		`SELECT id, name, description, meta, template, priority, generators, created_at, updated_at, created_by, updated_by, tenant_id FROM roles WHERE id = $1`, id)
 
 	r := &Role{}
-	err := row.Scan(&r.ID, &r.Name, &r.Description, &r.CreatedAt)
+	var meta, generators string
+	err := row.Scan(&r.ID, &r.Name, &r.Description, &meta, &r.Template, &r.Priority, &generators, &r.CreatedAt, &r.UpdatedAt, &r.CreatedBy, &r.UpdatedBy, &r.TenantID)
 	if errors.Is(err, pgx.ErrNoRows) {
 		return nil, nil
 	}
 	if err != nil {
 		return nil, err
 	}
+	if err := decodeMeta(meta, &r.Meta); err != nil {
+		return nil, fmt.Errorf("failed to decode role meta: %w", err)
+	}
+	if err := decodeGenerators(generators, &r.Generators); err != nil {
+		return nil, fmt.Errorf("failed to decode role generators: %w", err)
+	}
 	return r, nil
 }
 
@@ -336,7 +527,7 @@ func (s *PostgresStore) DeleteRole(ctx context.Context, id string) error {
 
 func (s *PostgresStore) ListAllRoles(ctx context.Context) ([]*Role, error) {
 	rows, err := s.db.Query(ctx,
-		`SELECT id, name, description, created_at FROM roles`)
+		`SELECT id, name, description, meta, template, priority, generators, created_at, updated_at, created_by, updated_by, tenant_id FROM roles`)
 	if err != nil {
 		return nil, err
 	}
@@ -345,14 +536,90 @@ func (s *PostgresStore) ListAllRoles(ctx context.Context) ([]*Role, error) {
 	var out []*Role
 	for rows.Next() {
 		r := &Role{}
-		if err := rows.Scan(&r.ID, &r.Name, &r.Description, &r.CreatedAt); err != nil {
+		var meta, generators string
+		if err := rows.Scan(&r.ID, &r.Name, &r.Description, &meta, &r.Template, &r.Priority, &generators, &r.CreatedAt, &r.UpdatedAt, &r.CreatedBy, &r.UpdatedBy, &r.TenantID); err != nil {
 			return nil, fmt.Errorf("failed to decode role: %w", err)
 		}
+		if err := decodeMeta(meta, &r.Meta); err != nil {
+			return nil, fmt.Errorf("failed to decode role meta: %w", err)
+		}
+		if err := decodeGenerators(generators, &r.Generators); err != nil {
+			return nil, fmt.Errorf("failed to decode role generators: %w", err)
+		}
 		out = append(out, r)
 	}
 	return out, rows.Err()
 }
 
+// encodeMeta returns the JSON the SQL and Cassandra stores keep a Meta map
+// as, or "" for an empty map.
+func encodeMeta(meta map[string]interface{}) (string, error) {
+	if len(meta) == 0 {
+		return "", nil
+	}
+	b, err := json.Marshal(meta)
+	return string(b), err
+}
+
+// decodeMeta is the inverse of encodeMeta.
+func decodeMeta(s string, meta *map[string]interface{}) error {
+	if s == "" {
+		return nil
+	}
+	return json.Unmarshal([]byte(s), meta)
+}
+
+// encodeGenerators returns the JSON the SQL and Cassandra stores keep a
+// role's generators as, or "" for none.
+func encodeGenerators(gens []Permission) (string, error) {
+	if len(gens) == 0 {
+		return "", nil
+	}
+	b, err := json.Marshal(gens)
+	return string(b), err
+}
+
+// decodeGenerators is the inverse of encodeGenerators.
+func decodeGenerators(s string, gens *[]Permission) error {
+	if s == "" {
+		return nil
+	}
+	return json.Unmarshal([]byte(s), gens)
+}
+
+// encodeLabels returns the JSON the SQL, Cassandra and Spanner stores keep
+// a permission's labels as, or "" for none.
+func encodeLabels(labels map[string]string) (string, error) {
+	if len(labels) == 0 {
+		return "", nil
+	}
+	b, err := json.Marshal(labels)
+	return string(b), err
+}
+
+// decodeLabels is the inverse of encodeLabels.
+func decodeLabels(s string, labels *map[string]string) error {
+	if s == "" {
+		return nil
+	}
+	return json.Unmarshal([]byte(s), labels)
+}
+
+// scanPermission reads a permissions row of the SQL stores, selected as id,
+// name, description, labels, resource, action, effect, condition,
+// created_at, updated_at, created_by, updated_by, tenant_id. Like scanUser,
+// it returns the permission even on an error.
+func scanPermission(scan func(dest ...any) error) (*Permission, error) {
+	p := &Permission{}
+	var labels, action, effect string
+	err := scan(&p.ID, &p.Name, &p.Description, &labels, &p.Resource, &action, &effect, &p.Condition, &p.CreatedAt, &p.UpdatedAt, &p.CreatedBy, &p.UpdatedBy, &p.TenantID)
+	p.Action, p.Effect = Action(action), Effect(effect)
+	if err == nil {
+		err = decodeLabels(labels, &p.Labels)
+	}
+	return p, err
+}
+
 //
 // ---------- RolePermissionRepo ----------
 //
@@ -366,6 +633,15 @@ func (s *PostgresStore) AddRP(ctx context.Context, roleID, permID string) error
 	return err
 }
 
+func (s *PostgresStore) AddRPs(ctx context.Context, roleID string, permIDs []string) error {
+	_, err := s.db.Exec(ctx,
+		`INSERT INTO role_permissions (role_id, permission_id, created_at)
+		 SELECT $1, unnest($2::text[]), $3
+		 ON CONFLICT DO NOTHING`,
+		roleID, permIDs, time.Now().Unix())
+	return err
+}
+
 func (s *PostgresStore) Remove(ctx context.Context, roleID, permID string) error {
 	_, err := s.db.Exec(ctx,
 		`DELETE FROM role_permissions WHERE role_id = $1 AND permission_id = $2`,
@@ -405,6 +681,15 @@ func (s *PostgresStore) AddUR(ctx context.Context, userID, roleID string) error
 	return err
 }
 
+func (s *PostgresStore) AddURs(ctx context.Context, userID string, roleIDs []string) error {
+	_, err := s.db.Exec(ctx,
+		`INSERT INTO user_roles (user_id, role_id, assigned_at)
+		 SELECT $1, unnest($2::text[]), $3
+		 ON CONFLICT DO NOTHING`,
+		userID, roleIDs, time.Now().Unix())
+	return err
+}
+
 func (s *PostgresStore) RemoveUR(ctx context.Context, userID, roleID string) error {
 	_, err := s.db.Exec(ctx,
 		`DELETE FROM user_roles WHERE user_id = $1 AND role_id = $2`,
@@ -415,18 +700,12 @@ func (s *PostgresStore) RemoveUR(ctx context.Context, userID, roleID string) err
 func (s *PostgresStore) ListRoles(ctx context.Context, userID string) ([]string, error) {
 	rows, err := s.db.Query(ctx,
 		`SELECT role_id FROM user_roles WHERE user_id = $1`, userID)
-
-	var out []string
-
 	if err != nil {
-		// Still append the default role even on error, mirroring the Mongo behaviour.
-		if r, _ := s.GetRoleByName(ctx, "default"); r != nil {
-			out = append(out, r.ID)
-		}
-		return out, err
+		return nil, err
 	}
 	defer rows.Close()
 
+	var out []string
 	for rows.Next() {
 		var id string
 		if err := rows.Scan(&id); err != nil {
@@ -437,12 +716,26 @@ func (s *PostgresStore) ListRoles(ctx context.Context, userID string) ([]string,
 	if err := rows.Err(); err != nil {
 		return nil, err
 	}
+	return out, nil
+}
 
-	// Always include the default role.
-	if r, _ := s.GetRoleByName(ctx, "default"); r != nil {
-		out = append(out, r.ID)
+func (s *PostgresStore) ListUsersForRole(ctx context.Context, roleID string) ([]string, error) {
+	rows, err := s.db.Query(ctx,
+		`SELECT user_id FROM user_roles WHERE role_id = $1 ORDER BY user_id`, roleID)
+	if err != nil {
+		return nil, err
 	}
-	return out, nil
+	defer rows.Close()
+
+	var out []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		out = append(out, id)
+	}
+	return out, rows.Err()
 }
 
 //
@@ -454,13 +747,19 @@ func (s *PostgresStore) AddUserToGroup(ctx context.Context, ug *UserGroup) error
 		return errors.New("user id is empty")
 	}
 
-	ug.ID = uuid.New().String()
+	if ug.ID == "" {
+		ug.ID = generateID(s.ids, KindUserGroup)
+	}
 	ug.CreatedAt = time.Now().Unix()
 
+	// Re-adding a member replaces their level and expiry, as in the other
+	// stores.
 	_, err := s.db.Exec(ctx,
-		`INSERT INTO user_groups (id, user_id, group_name, created_at)
-		 VALUES ($1, $2, $3, $4)`,
-		ug.ID, ug.UserID, ug.GroupName, ug.CreatedAt)
+		`INSERT INTO user_groups (id, user_id, group_name, created_at, updated_at, created_by, updated_by, level, expires_at, tenant_id)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		 ON CONFLICT (user_id, group_name) DO UPDATE
+		 SET updated_at = EXCLUDED.updated_at, updated_by = EXCLUDED.updated_by, level = EXCLUDED.level, expires_at = EXCLUDED.expires_at, tenant_id = EXCLUDED.tenant_id`,
+		ug.ID, ug.UserID, ug.GroupName, ug.CreatedAt, ug.UpdatedAt, ug.CreatedBy, ug.UpdatedBy, string(ug.Level), ug.ExpiresAt, ug.TenantID)
 	return err
 }
 
@@ -477,7 +776,7 @@ func (s *PostgresStore) RemoveUserFromGroup(ctx context.Context, groupName strin
 
 func (s *PostgresStore) GetUsersByGroupID(ctx context.Context, groupName string) ([]*UserGroup, error) {
 	rows, err := s.db.Query(ctx,
-		`SELECT id, user_id, group_name, created_at FROM user_groups WHERE group_name = $1`, groupName)
+		`SELECT id, user_id, group_name, created_at, updated_at, created_by, updated_by, level, expires_at, tenant_id FROM user_groups WHERE group_name = $1`, groupName)
 	if err != nil {
 		return nil, err
 	}
@@ -485,8 +784,8 @@ func (s *PostgresStore) GetUsersByGroupID(ctx context.Context, groupName string)
 
 	var out []*UserGroup
 	for rows.Next() {
-		ug := &UserGroup{}
-		if err := rows.Scan(&ug.ID, &ug.UserID, &ug.GroupName, &ug.CreatedAt); err != nil {
+		ug, err := scanUserGroup(rows.Scan)
+		if err != nil {
 			return nil, err
 		}
 		out = append(out, ug)
@@ -494,6 +793,38 @@ func (s *PostgresStore) GetUsersByGroupID(ctx context.Context, groupName string)
 	return out, rows.Err()
 }
 
+func (s *PostgresStore) ListExpiringMemberships(ctx context.Context, after, before int64) ([]*UserGroup, error) {
+	rows, err := s.db.Query(ctx,
+		`SELECT id, user_id, group_name, created_at, updated_at, created_by, updated_by, level, expires_at, tenant_id FROM user_groups
+		 WHERE expires_at > $1 AND expires_at <= $2`, after, before)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []*UserGroup
+	for rows.Next() {
+		ug, err := scanUserGroup(rows.Scan)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, ug)
+	}
+	return out, rows.Err()
+}
+
+// scanUserGroup reads a user_groups row of the SQL stores, selected as
+// id, user_id, group_name, created_at, updated_at, created_by, updated_by,
+// level, expires_at, tenant_id. Like rows.Scan, it returns the membership even on an
+// error, so ExportPage's scanners can key their item by it.
+func scanUserGroup(scan func(dest ...any) error) (*UserGroup, error) {
+	ug := &UserGroup{}
+	var level string
+	err := scan(&ug.ID, &ug.UserID, &ug.GroupName, &ug.CreatedAt, &ug.UpdatedAt, &ug.CreatedBy, &ug.UpdatedBy, &level, &ug.ExpiresAt, &ug.TenantID)
+	ug.Level = MembershipLevel(level)
+	return ug, err
+}
+
 //
 // ---------- GroupRoleRepo ----------
 //
@@ -532,3 +863,139 @@ func (s *PostgresStore) ListRolesForGroup(ctx context.Context, groupID string) (
 	}
 	return out, rows.Err()
 }
+
+func (s *PostgresStore) ListGroupsForRole(ctx context.Context, roleID string) ([]string, error) {
+	rows, err := s.db.Query(ctx,
+		`SELECT group_name FROM group_roles WHERE role_id = $1 ORDER BY group_name`, roleID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		out = append(out, name)
+	}
+	return out, rows.Err()
+}
+
+//
+// ---------- AuditRepo ----------
+//
+
+func (s *PostgresStore) AppendAudit(ctx context.Context, e *AuditEntry) error {
+	if e.ID == "" {
+		e.ID = generateID(s.ids, KindAuditEntry)
+	}
+	_, err := s.db.Exec(ctx,
+		`INSERT INTO audit_log (`+auditColumns+`) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`,
+		e.ID, e.At.UnixNano(), e.Actor, e.Kind, e.Method, e.Target, e.Resource, e.Action, e.Outcome, e.Error)
+	return err
+}
+
+func (s *PostgresStore) ListAudit(ctx context.Context, q AuditQuery) ([]*AuditEntry, error) {
+	clause, args := auditFilter(q, func(n int) string { return fmt.Sprintf("$%d", n) })
+	rows, err := s.db.Query(ctx, `SELECT `+auditColumns+` FROM audit_log`+clause, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out, err := scanAudit(rows.Next, rows.Scan)
+	if err != nil {
+		return nil, err
+	}
+	return out, rows.Err()
+}
+
+//
+// ---------- ExportPager ----------
+//
+
+func (s *PostgresStore) ExportPage(ctx context.Context, kind, after string, limit int) ([]ExportItem, error) {
+	var (
+		query string
+		args  []any
+		scan  func(pgx.Rows) (ExportItem, error)
+	)
+	edge := func(table, fromCol, toCol string) {
+		from, to := splitExportKey(after)
+		query = fmt.Sprintf(`SELECT %[2]s, %[3]s FROM %[1]s WHERE (%[2]s, %[3]s) > ($1, $2) ORDER BY %[2]s, %[3]s LIMIT $3`,
+			table, fromCol, toCol)
+		args = []any{from, to, limit}
+		scan = func(rows pgx.Rows) (ExportItem, error) {
+			e := &ExportEdge{}
+			err := rows.Scan(&e.From, &e.To)
+			return ExportItem{Key: e.From + ExportKeySep + e.To, Value: e}, err
+		}
+	}
+
+	switch kind {
+	case KindPermission:
+		query = `SELECT id, name, description, labels, resource, action, effect, condition, created_at, updated_at, created_by, updated_by, tenant_id FROM permissions WHERE id > $1 ORDER BY id LIMIT $2`
+		args = []any{after, limit}
+		scan = func(rows pgx.Rows) (ExportItem, error) {
+			p, err := scanPermission(rows.Scan)
+			return ExportItem{Key: p.ID, Value: p}, err
+		}
+	case KindRole:
+		query = `SELECT id, name, description, meta, template, priority, generators, created_at, updated_at, created_by, updated_by, tenant_id FROM roles WHERE id > $1 ORDER BY id LIMIT $2`
+		args = []any{after, limit}
+		scan = func(rows pgx.Rows) (ExportItem, error) {
+			r := &Role{}
+			var meta, generators string
+			err := rows.Scan(&r.ID, &r.Name, &r.Description, &meta, &r.Template, &r.Priority, &generators, &r.CreatedAt, &r.UpdatedAt, &r.CreatedBy, &r.UpdatedBy, &r.TenantID)
+			if err == nil {
+				err = decodeMeta(meta, &r.Meta)
+			}
+			if err == nil {
+				err = decodeGenerators(generators, &r.Generators)
+			}
+			return ExportItem{Key: r.ID, Value: r}, err
+		}
+	case KindUser:
+		query = `SELECT id, username, email, email_verified, status, created_at, updated_at, created_by, updated_by, tenant_id FROM users WHERE id > $1 ORDER BY id LIMIT $2`
+		args = []any{after, limit}
+		scan = func(rows pgx.Rows) (ExportItem, error) {
+			u, err := scanUser(rows.Scan)
+			return ExportItem{Key: u.ID, Value: u}, err
+		}
+	case KindUserGroup:
+		userID, group := splitExportKey(after)
+		query = `SELECT id, user_id, group_name, created_at, updated_at, created_by, updated_by, level, expires_at, tenant_id FROM user_groups
+			WHERE (user_id, group_name) > ($1, $2) ORDER BY user_id, group_name LIMIT $3`
+		args = []any{userID, group, limit}
+		scan = func(rows pgx.Rows) (ExportItem, error) {
+			ug, err := scanUserGroup(rows.Scan)
+			return ExportItem{Key: ug.UserID + ExportKeySep + ug.GroupName, Value: ug}, err
+		}
+	case KindRolePermission:
+		edge("role_permissions", "role_id", "permission_id")
+	case KindUserRole:
+		edge("user_roles", "user_id", "role_id")
+	case KindGroupRole:
+		edge("group_roles", "group_name", "role_id")
+	default:
+		return nil, fmt.Errorf("postgres_store: unknown export kind %q", kind)
+	}
+
+	rows, err := s.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []ExportItem
+	for rows.Next() {
+		it, err := scan(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, it)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,30 @@
+package rbacServer
+
+import (
+	"net/http"
+
+	"github.com/Seann-Moser/rbac"
+)
+
+// actionList is the response of ListActionsHandler.
+type actionList struct {
+	Actions []rbac.ActionDef   `json:"actions"`
+	Groups  []rbac.ActionGroup `json:"groups"`
+}
+
+// ListActionsHandler lists the actions and action groups permissions may be
+// created with, for the management UI's action inputs. Without an action
+// registry it lists the CRUD actions.
+// GET /actions/list
+func (s *Server) ListActionsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, r, http.StatusMethodNotAllowed, "Method not allowed", nil)
+		return
+	}
+
+	reg := s.manager(r).Actions
+	if reg == nil {
+		reg, _ = rbac.NewActionRegistry()
+	}
+	writeJSONResponse(w, http.StatusOK, actionList{Actions: reg.Actions(), Groups: reg.Groups()})
+}
@@ -0,0 +1,117 @@
+package rbacServer
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/Seann-Moser/rbac"
+)
+
+// APIKeyHeader carries the key for APIKeyCanHandler.
+const APIKeyHeader = "X-API-Key"
+
+// CreateAPIKeyHandler mints an API key for a principal. The key is only
+// ever returned in this response.
+// POST /apikeys/create
+// Request Body: {"principal_id": "svc-billing", "name": "billing", "scopes": ["invoices/**"], "expires_at": 1767225600}
+func (s *Server) CreateAPIKeyHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, r, http.StatusMethodNotAllowed, "Method not allowed", nil)
+		return
+	}
+
+	var k rbac.APIKey
+	if err := json.NewDecoder(r.Body).Decode(&k); err != nil || k.PrincipalID == "" {
+		s.writeError(w, r, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+
+	key, err := s.manager(r).MintAPIKey(r.Context(), &k)
+	if err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, "Failed to create API key", err)
+		return
+	}
+
+	writeJSONResponse(w, http.StatusCreated, map[string]string{
+		"message": s.Message(r, "API key created successfully"),
+		"key_id":  k.ID,
+		"key":     key,
+	})
+}
+
+// ListAPIKeysHandler lists a principal's API keys, without their secret
+// hashes.
+// GET /apikeys/list?principal_id=svc-billing
+func (s *Server) ListAPIKeysHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, r, http.StatusMethodNotAllowed, "Method not allowed", nil)
+		return
+	}
+
+	principalID := r.URL.Query().Get("principal_id")
+	if principalID == "" {
+		s.writeError(w, r, http.StatusBadRequest, "Missing principal_id query parameter", nil)
+		return
+	}
+
+	keys, err := s.manager(r).ListAPIKeys(r.Context(), principalID)
+	if err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, "Failed to list API keys", err)
+		return
+	}
+	for _, k := range keys {
+		k.SecretHash = ""
+	}
+
+	writeJSONResponse(w, http.StatusOK, keys)
+}
+
+// RevokeAPIKeyHandler revokes an API key.
+// POST /apikeys/revoke
+// Request Body: {"id": "keyID"}
+func (s *Server) RevokeAPIKeyHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, r, http.StatusMethodNotAllowed, "Method not allowed", nil)
+		return
+	}
+
+	var req struct {
+		ID string `json:"id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.ID == "" {
+		s.writeError(w, r, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+
+	if err := s.manager(r).RevokeAPIKey(r.Context(), req.ID); err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, "Failed to revoke API key", err)
+		return
+	}
+
+	writeJSONResponse(w, http.StatusOK, map[string]string{"message": s.Message(r, "API key revoked successfully")})
+}
+
+// APIKeyCanHandler checks whether the principal of the API key in the
+// X-API-Key header can perform an action on a resource. An invalid key is
+// answered with 401.
+// GET /apikeys/can?resource=/api/data&action=read
+func (s *Server) APIKeyCanHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, r, http.StatusMethodNotAllowed, "Method not allowed", nil)
+		return
+	}
+
+	key := r.Header.Get(APIKeyHeader)
+	if key == "" {
+		s.writeError(w, r, http.StatusUnauthorized, "Missing API key", nil)
+		return
+	}
+	q := r.URL.Query()
+	ok, err := s.manager(r).CanByAPIKey(r.Context(), key, q.Get("resource"), rbac.Action(q.Get("action")))
+	if err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, "Failed to perform authorization check", err)
+		return
+	}
+
+	writeJSONResponse(w, http.StatusOK, map[string]bool{"can_perform_action": ok})
+}
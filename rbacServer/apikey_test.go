@@ -0,0 +1,71 @@
+package rbacServer
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Seann-Moser/rbac"
+)
+
+func TestAPIKeyHandlers(t *testing.T) {
+	ctx := context.Background()
+	mgr, err := rbac.NewMemoryStoreManager(ctx, "", 0)
+	if err != nil {
+		t.Fatalf("NewMemoryStoreManager: %v", err)
+	}
+	role := &rbac.Role{Name: "reader"}
+	if err := mgr.CreateRole(ctx, role); err != nil {
+		t.Fatalf("CreateRole: %v", err)
+	}
+	perm := &rbac.Permission{Resource: "/api/data", Action: rbac.ActionRead}
+	if err := mgr.CreatePermission(ctx, perm); err != nil {
+		t.Fatalf("CreatePermission: %v", err)
+	}
+	if err := mgr.AssignPermissionToRole(ctx, role.ID, perm.ID); err != nil {
+		t.Fatalf("AssignPermissionToRole: %v", err)
+	}
+	if err := mgr.AssignRoleToUser(ctx, "svc", role.ID); err != nil {
+		t.Fatalf("AssignRoleToUser: %v", err)
+	}
+	srv := NewServer(mgr)
+
+	rec := httptest.NewRecorder()
+	srv.CreateAPIKeyHandler(rec, httptest.NewRequest(http.MethodPost, "/apikeys/create", strings.NewReader(`{"principal_id": "svc"}`)))
+	var created map[string]string
+	if rec.Code != http.StatusCreated || json.NewDecoder(rec.Body).Decode(&created) != nil || created["key"] == "" {
+		t.Fatalf("create: unexpected response %d %v", rec.Code, created)
+	}
+
+	can := func(key string) (int, bool) {
+		req := httptest.NewRequest(http.MethodGet, "/apikeys/can?resource=/api/data&action=read", nil)
+		req.Header.Set(APIKeyHeader, key)
+		rec := httptest.NewRecorder()
+		srv.APIKeyCanHandler(rec, req)
+		var resp map[string]bool
+		_ = json.NewDecoder(rec.Body).Decode(&resp)
+		return rec.Code, resp["can_perform_action"]
+	}
+	if code, ok := can(created["key"]); code != http.StatusOK || !ok {
+		t.Errorf("can: expected 200 and true, got %d %v", code, ok)
+	}
+
+	rec = httptest.NewRecorder()
+	srv.ListAPIKeysHandler(rec, httptest.NewRequest(http.MethodGet, "/apikeys/list?principal_id=svc", nil))
+	var keys []*rbac.APIKey
+	if rec.Code != http.StatusOK || json.NewDecoder(rec.Body).Decode(&keys) != nil || len(keys) != 1 || keys[0].SecretHash != "" {
+		t.Errorf("list: unexpected response %d %+v", rec.Code, keys)
+	}
+
+	rec = httptest.NewRecorder()
+	srv.RevokeAPIKeyHandler(rec, httptest.NewRequest(http.MethodPost, "/apikeys/revoke", strings.NewReader(`{"id": "`+created["key_id"]+`"}`)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("revoke: expected 200, got %d", rec.Code)
+	}
+	if code, _ := can(created["key"]); code != http.StatusUnauthorized {
+		t.Errorf("can with a revoked key: expected 401, got %d", code)
+	}
+}
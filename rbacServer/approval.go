@@ -0,0 +1,127 @@
+package rbacServer
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/Seann-Moser/rbac"
+)
+
+// RequestRoleAssignmentHandler asks for a role to be assigned to a user once
+// a second user approves. When the request is authenticated the principal is
+// the requester and "requested_by" is ignored.
+// POST /approvals/request
+// Request Body: {"user_id": "...", "role_id": "...", "requested_by": "...", "reason": "..."}
+func (s *Server) RequestRoleAssignmentHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, r, http.StatusMethodNotAllowed, "Method not allowed", nil)
+		return
+	}
+
+	var req struct {
+		UserID      string `json:"user_id"`
+		RoleID      string `json:"role_id"`
+		RequestedBy string `json:"requested_by"`
+		Reason      string `json:"reason"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.UserID == "" || req.RoleID == "" {
+		s.writeError(w, r, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+	if p := PrincipalFromContext(r.Context()); p != nil {
+		req.RequestedBy = p.ID
+	}
+	if req.RequestedBy == "" {
+		s.writeError(w, r, http.StatusBadRequest, "Invalid request body", nil)
+		return
+	}
+
+	ar, err := s.manager(r).RequestRoleAssignment(r.Context(), req.RequestedBy, req.UserID, req.RoleID, req.Reason)
+	if err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, "Failed to request role assignment", err)
+		return
+	}
+
+	writeJSONResponse(w, http.StatusCreated, ar)
+}
+
+// ListAssignmentRequestsHandler lists role assignment requests, optionally
+// only those with the given status.
+// GET /approvals/list?status=pending
+func (s *Server) ListAssignmentRequestsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, r, http.StatusMethodNotAllowed, "Method not allowed", nil)
+		return
+	}
+
+	list, err := s.manager(r).ListAssignmentRequests(r.Context(), rbac.AssignmentStatus(r.URL.Query().Get("status")))
+	if err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, "Failed to list assignment requests", err)
+		return
+	}
+
+	writeJSONResponse(w, http.StatusOK, list)
+}
+
+// ApproveRoleAssignmentHandler approves a pending request, assigning the
+// role. The approver must not be the requester or the user.
+// POST /approvals/approve
+// Request Body: {"id": "...", "approver_id": "...", "comment": "..."}
+func (s *Server) ApproveRoleAssignmentHandler(w http.ResponseWriter, r *http.Request) {
+	d, ok := s.decodeDecision(w, r)
+	if !ok {
+		return
+	}
+
+	ar, err := s.manager(r).ApproveRoleAssignment(r.Context(), d.ID, d.ApproverID, d.Comment)
+	if err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, "Failed to approve role assignment", err)
+		return
+	}
+
+	writeJSONResponse(w, http.StatusOK, ar)
+}
+
+// RejectRoleAssignmentHandler rejects, or lets the requester withdraw, a
+// pending request.
+// POST /approvals/reject
+// Request Body: {"id": "...", "approver_id": "...", "comment": "..."}
+func (s *Server) RejectRoleAssignmentHandler(w http.ResponseWriter, r *http.Request) {
+	d, ok := s.decodeDecision(w, r)
+	if !ok {
+		return
+	}
+
+	ar, err := s.manager(r).RejectRoleAssignment(r.Context(), d.ID, d.ApproverID, d.Comment)
+	if err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, "Failed to reject role assignment", err)
+		return
+	}
+
+	writeJSONResponse(w, http.StatusOK, ar)
+}
+
+type decision struct {
+	ID         string `json:"id"`
+	ApproverID string `json:"approver_id"`
+	Comment    string `json:"comment"`
+}
+
+// decodeDecision reads the body of the approve and reject endpoints,
+// writing the error response when it is invalid. When the request is
+// authenticated the principal is the approver and "approver_id" is ignored.
+func (s *Server) decodeDecision(w http.ResponseWriter, r *http.Request) (decision, bool) {
+	var d decision
+	if r.Method != http.MethodPost {
+		s.writeError(w, r, http.StatusMethodNotAllowed, "Method not allowed", nil)
+		return d, false
+	}
+	if err := json.NewDecoder(r.Body).Decode(&d); err != nil || d.ID == "" {
+		s.writeError(w, r, http.StatusBadRequest, "Invalid request body", err)
+		return d, false
+	}
+	if p := PrincipalFromContext(r.Context()); p != nil {
+		d.ApproverID = p.ID
+	}
+	return d, true
+}
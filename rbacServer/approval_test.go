@@ -0,0 +1,76 @@
+package rbacServer
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/Seann-Moser/rbac"
+)
+
+func TestApprovalHandlers(t *testing.T) {
+	ctx := context.Background()
+	mgr, err := rbac.NewMemoryStoreManager(ctx, "", 0)
+	if err != nil {
+		t.Fatalf("NewMemoryStoreManager: %v", err)
+	}
+	admin, approvers := &rbac.Role{Name: "admin"}, &rbac.Role{Name: "approvers"}
+	for _, r := range []*rbac.Role{admin, approvers} {
+		if err := mgr.CreateRole(ctx, r); err != nil {
+			t.Fatalf("CreateRole: %v", err)
+		}
+	}
+	perm := &rbac.Permission{Resource: rbac.ApprovalResource + "/*", Action: rbac.ActionUpdate}
+	if err := mgr.CreatePermission(ctx, perm); err != nil {
+		t.Fatalf("CreatePermission: %v", err)
+	}
+	if err := mgr.AssignPermissionToRole(ctx, approvers.ID, perm.ID); err != nil {
+		t.Fatalf("AssignPermissionToRole: %v", err)
+	}
+	if err := mgr.AssignRoleToUser(ctx, "carol", approvers.ID); err != nil {
+		t.Fatalf("AssignRoleToUser: %v", err)
+	}
+	srv := NewServer(mgr)
+	post := func(h http.HandlerFunc, body string, principal *rbac.User) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+		if principal != nil {
+			req = req.WithContext(WithPrincipal(req.Context(), principal))
+		}
+		rec := httptest.NewRecorder()
+		h(rec, req)
+		return rec
+	}
+
+	rec := post(srv.RequestRoleAssignmentHandler, `{"user_id": "bob", "role_id": "`+admin.ID+`", "requested_by": "carol"}`, &rbac.User{ID: "bob"})
+	var ar rbac.AssignmentRequest
+	if rec.Code != http.StatusCreated || json.NewDecoder(rec.Body).Decode(&ar) != nil || ar.RequestedBy != "bob" {
+		t.Fatalf("request: unexpected response %d %+v", rec.Code, ar)
+	}
+
+	rec = httptest.NewRecorder()
+	srv.ListAssignmentRequestsHandler(rec, httptest.NewRequest(http.MethodGet, "/approvals/list?status=pending", nil))
+	var list []rbac.AssignmentRequest
+	if rec.Code != http.StatusOK || json.NewDecoder(rec.Body).Decode(&list) != nil || len(list) != 1 {
+		t.Fatalf("list: unexpected response %d %+v", rec.Code, list)
+	}
+
+	if rec := post(srv.ApproveRoleAssignmentHandler, `{"id": "`+ar.ID+`", "approver_id": "carol"}`, &rbac.User{ID: "bob"}); rec.Code != http.StatusForbidden {
+		t.Errorf("self-approval: expected 403, got %d", rec.Code)
+	}
+	if rec := post(srv.ApproveRoleAssignmentHandler, `{"id": "missing", "approver_id": "carol"}`, nil); rec.Code != http.StatusNotFound {
+		t.Errorf("unknown request: expected 404, got %d", rec.Code)
+	}
+	if rec := post(srv.ApproveRoleAssignmentHandler, `{"id": "`+ar.ID+`"}`, &rbac.User{ID: "carol"}); rec.Code != http.StatusOK {
+		t.Fatalf("approve: expected 200, got %d", rec.Code)
+	}
+	if rec := post(srv.RejectRoleAssignmentHandler, `{"id": "`+ar.ID+`"}`, &rbac.User{ID: "carol"}); rec.Code != http.StatusConflict {
+		t.Errorf("reject after approval: expected 409, got %d", rec.Code)
+	}
+	if roles, err := mgr.ListRolesForUser(ctx, "bob"); err != nil || !slices.Contains(roles, admin.ID) {
+		t.Errorf("expected bob to hold the approved role, got %v, %v", roles, err)
+	}
+}
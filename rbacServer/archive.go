@@ -0,0 +1,120 @@
+package rbacServer
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/Seann-Moser/rbac"
+)
+
+// ArchiveHandler archives a role or group: it is removed from evaluation and
+// kept, with its assignments, as a restorable archive.
+// POST /archives/create
+// Request Body: {"kind": "role", "id": "roleA"}
+func (s *Server) ArchiveHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, r, http.StatusMethodNotAllowed, "Method not allowed", nil)
+		return
+	}
+
+	var req struct {
+		Kind string `json:"kind"`
+		ID   string `json:"id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.ID == "" {
+		s.writeError(w, r, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+
+	var (
+		a   *rbac.Archive
+		err error
+		msg string
+	)
+	switch req.Kind {
+	case rbac.KindRole:
+		a, err = s.manager(r).ArchiveRole(r.Context(), req.ID)
+		msg = s.Message(r, "Role archived successfully")
+	case rbac.KindGroup:
+		a, err = s.manager(r).ArchiveGroup(r.Context(), req.ID)
+		msg = s.Message(r, "Group archived successfully")
+	default:
+		s.writeError(w, r, http.StatusBadRequest, "Invalid request body", nil)
+		return
+	}
+	if err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, "Failed to archive", err)
+		return
+	}
+
+	writeJSONResponse(w, http.StatusCreated, map[string]string{"message": msg, "archive_id": a.ID})
+}
+
+// ListArchivesHandler lists every archive, oldest first.
+// GET /archives/list
+func (s *Server) ListArchivesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, r, http.StatusMethodNotAllowed, "Method not allowed", nil)
+		return
+	}
+
+	archives, err := s.manager(r).ListArchives(r.Context())
+	if err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, "Failed to list archives", err)
+		return
+	}
+
+	writeJSONResponse(w, http.StatusOK, archives)
+}
+
+// GetArchiveHandler returns an archive with its bundle.
+// GET /archives/get?id=archiveID
+func (s *Server) GetArchiveHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, r, http.StatusMethodNotAllowed, "Method not allowed", nil)
+		return
+	}
+
+	id := r.URL.Query().Get("id")
+	if id == "" {
+		s.writeError(w, r, http.StatusBadRequest, "Missing archive ID query parameter", nil)
+		return
+	}
+
+	a, err := s.manager(r).GetArchive(r.Context(), id)
+	if err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, "Failed to get archive", err)
+		return
+	}
+	if a == nil {
+		s.writeError(w, r, http.StatusNotFound, "Archive not found", nil)
+		return
+	}
+
+	writeJSONResponse(w, http.StatusOK, a)
+}
+
+// RestoreArchiveHandler restores an archived role or group.
+// POST /archives/restore
+// Request Body: {"id": "archiveID"}
+func (s *Server) RestoreArchiveHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, r, http.StatusMethodNotAllowed, "Method not allowed", nil)
+		return
+	}
+
+	var req struct {
+		ID string `json:"id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.ID == "" {
+		s.writeError(w, r, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+
+	if err := s.manager(r).RestoreArchive(r.Context(), req.ID); err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, "Failed to restore archive", err)
+		return
+	}
+
+	writeJSONResponse(w, http.StatusOK, map[string]string{"message": s.Message(r, "Archive restored successfully")})
+}
@@ -0,0 +1,65 @@
+package rbacServer
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Seann-Moser/rbac"
+)
+
+func TestArchiveHandlers(t *testing.T) {
+	ctx := context.Background()
+	mgr, err := rbac.NewMemoryStoreManager(ctx, "", 0)
+	if err != nil {
+		t.Fatalf("NewMemoryStoreManager: %v", err)
+	}
+	role := &rbac.Role{Name: "auditor"}
+	if err := mgr.CreateRole(ctx, role); err != nil {
+		t.Fatalf("CreateRole: %v", err)
+	}
+	srv := NewServer(mgr)
+
+	rec := httptest.NewRecorder()
+	srv.ArchiveHandler(rec, httptest.NewRequest(http.MethodPost, "/archives/create", strings.NewReader(`{"kind": "role", "id": "`+role.ID+`"}`)))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("archive: expected 201, got %d: %s", rec.Code, rec.Body)
+	}
+	var created map[string]string
+	if err := json.NewDecoder(rec.Body).Decode(&created); err != nil || created["archive_id"] == "" {
+		t.Fatalf("archive: unexpected body %v, %v", created, err)
+	}
+
+	rec = httptest.NewRecorder()
+	srv.GetArchiveHandler(rec, httptest.NewRequest(http.MethodGet, "/archives/get?id="+created["archive_id"], nil))
+	var a rbac.Archive
+	if rec.Code != http.StatusOK || json.NewDecoder(rec.Body).Decode(&a) != nil || a.Bundle.Role == nil || a.Bundle.Role.Name != "auditor" {
+		t.Fatalf("get: unexpected response %d %+v", rec.Code, a)
+	}
+	rec = httptest.NewRecorder()
+	srv.GetArchiveHandler(rec, httptest.NewRequest(http.MethodGet, "/archives/get?id=missing", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("get missing: expected 404, got %d", rec.Code)
+	}
+
+	restore := func() int {
+		rec := httptest.NewRecorder()
+		srv.RestoreArchiveHandler(rec, httptest.NewRequest(http.MethodPost, "/archives/restore", strings.NewReader(`{"id": "`+created["archive_id"]+`"}`)))
+		return rec.Code
+	}
+	if code := restore(); code != http.StatusOK {
+		t.Fatalf("restore: expected 200, got %d", code)
+	}
+	if code := restore(); code != http.StatusConflict {
+		t.Errorf("second restore: expected 409, got %d", code)
+	}
+
+	rec = httptest.NewRecorder()
+	srv.ArchiveHandler(rec, httptest.NewRequest(http.MethodPost, "/archives/create", strings.NewReader(`{"kind": "user", "id": "alice"}`)))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("unknown kind: expected 400, got %d", rec.Code)
+	}
+}
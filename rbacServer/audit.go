@@ -0,0 +1,59 @@
+package rbacServer
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Seann-Moser/rbac"
+)
+
+// ListAuditHandler lists audit entries, oldest first, optionally only those
+// of one actor or target and within a time range.
+// GET /audit/list?actor=alice&target=userID&since=2024-01-01T00:00:00Z&until=...&limit=100
+func (s *Server) ListAuditHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, r, http.StatusMethodNotAllowed, "Method not allowed", nil)
+		return
+	}
+
+	query := r.URL.Query()
+	q := rbac.AuditQuery{Actor: query.Get("actor"), Target: query.Get("target")}
+	var err error
+	if q.Since, err = parseTimeParam(query.Get("since")); err != nil {
+		s.writeError(w, r, http.StatusBadRequest, "Invalid since query parameter", err)
+		return
+	}
+	if q.Until, err = parseTimeParam(query.Get("until")); err != nil {
+		s.writeError(w, r, http.StatusBadRequest, "Invalid until query parameter", err)
+		return
+	}
+	if v := query.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			s.writeError(w, r, http.StatusBadRequest, "Invalid limit query parameter", err)
+			return
+		}
+		q.Limit = n
+	}
+
+	entries, err := s.manager(r).ListAuditEntries(r.Context(), q)
+	if err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, "Failed to list audit entries", err)
+		return
+	}
+	if entries == nil {
+		entries = []*rbac.AuditEntry{}
+	}
+
+	writeJSONResponse(w, http.StatusOK, entries)
+}
+
+// parseTimeParam parses an RFC 3339 query parameter, returning the zero time
+// when it is empty.
+func parseTimeParam(v string) (time.Time, error) {
+	if v == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339, v)
+}
@@ -0,0 +1,51 @@
+package rbacServer
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Seann-Moser/rbac"
+)
+
+func TestListAuditHandler(t *testing.T) {
+	ctx := rbac.WithActor(context.Background(), "admin")
+	repo := rbac.NewMockRepo()
+	mgr := rbac.NewMockRepoManager(repo)
+	mgr.Audit = repo
+	if err := mgr.AssignRoleToUser(ctx, "alice", "default"); err != nil {
+		t.Fatalf("AssignRoleToUser: %v", err)
+	}
+	if err := mgr.AssignRoleToUser(ctx, "bob", "default"); err != nil {
+		t.Fatalf("AssignRoleToUser: %v", err)
+	}
+	srv := NewServer(mgr)
+	get := func(url string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		srv.ListAuditHandler(rec, httptest.NewRequest(http.MethodGet, url, nil))
+		return rec
+	}
+
+	rec := get("/audit/list?actor=admin&target=bob&since=2000-01-01T00:00:00Z")
+	var entries []rbac.AuditEntry
+	if rec.Code != http.StatusOK || json.NewDecoder(rec.Body).Decode(&entries) != nil {
+		t.Fatalf("list: unexpected response %d", rec.Code)
+	}
+	if len(entries) != 1 || entries[0].Target != "bob" || entries[0].Method != "AssignRoleToUser" {
+		t.Errorf("list = %+v; want bob's assignment", entries)
+	}
+
+	for _, url := range []string{"/audit/list?since=yesterday", "/audit/list?until=1", "/audit/list?limit=-1"} {
+		if rec := get(url); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", url, rec.Code)
+		}
+	}
+
+	rec = httptest.NewRecorder()
+	NewServer(rbac.NewMockRepoManager(rbac.NewMockRepo())).ListAuditHandler(rec, httptest.NewRequest(http.MethodGet, "/audit/list", nil))
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("list without an audit repo: expected 500, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,90 @@
+package rbacServer
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/Seann-Moser/rbac"
+)
+
+type casbinImportRequest struct {
+	Model  string `json:"model"`
+	Policy string `json:"policy"`
+}
+
+// ExportCasbinHandler returns the policy as a Casbin policy CSV, or with
+// file=model the rbac.CasbinModel it is written for. Principals of a tenant
+// may not export.
+// GET /policy/casbin/export?file=model
+func (s *Server) ExportCasbinHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, r, http.StatusMethodNotAllowed, "Method not allowed", nil)
+		return
+	}
+	if p := PrincipalFromContext(r.Context()); p != nil && p.TenantID != "" {
+		s.writeError(w, r, http.StatusForbidden, "Export is not available to tenant principals", nil)
+		return
+	}
+	switch r.URL.Query().Get("file") {
+	case "", "policy":
+	case "model":
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(rbac.CasbinModel))
+		return
+	default:
+		s.writeError(w, r, http.StatusBadRequest, "Invalid file query parameter", nil)
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := s.RBACManager.ExportCasbin(r.Context(), &buf); err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, "Failed to export Casbin policy", err)
+		return
+	}
+	w.Header().Set("Content-Type", "text/csv")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(buf.Bytes())
+}
+
+// ImportCasbinHandler converts a Casbin model.conf and policy CSV with
+// rbac.ParseCasbin and imports them, merging into the store or, with
+// mode=replace, replacing its roles, permissions and groups. Principals of
+// a tenant may not import.
+// POST /policy/casbin/import?mode=replace {"model": "...", "policy": "..."}
+func (s *Server) ImportCasbinHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, r, http.StatusMethodNotAllowed, "Method not allowed", nil)
+		return
+	}
+	if p := PrincipalFromContext(r.Context()); p != nil && p.TenantID != "" {
+		s.writeError(w, r, http.StatusForbidden, "Import is not available to tenant principals", nil)
+		return
+	}
+	opts := rbac.ImportOptions{Mode: rbac.ImportMode(r.URL.Query().Get("mode"))}
+	switch opts.Mode {
+	case "", rbac.ImportMerge, rbac.ImportReplace:
+	default:
+		s.writeError(w, r, http.StatusBadRequest, "Invalid mode query parameter", nil)
+		return
+	}
+	var req casbinImportRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.writeError(w, r, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+
+	err := s.RBACManager.ImportCasbin(r.Context(), strings.NewReader(req.Model), strings.NewReader(req.Policy), opts)
+	if err != nil {
+		if errors.Is(err, rbac.ErrUnsupportedCasbin) || errors.Is(err, rbac.ErrInvalidBundle) {
+			s.writeError(w, r, http.StatusBadRequest, "Invalid Casbin policy", err)
+			return
+		}
+		s.writeError(w, r, http.StatusInternalServerError, "Failed to import Casbin policy", err)
+		return
+	}
+	writeJSONResponse(w, http.StatusOK, map[string]string{"message": s.Message(r, "Policy imported successfully")})
+}
@@ -0,0 +1,58 @@
+package rbacServer
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Seann-Moser/rbac"
+)
+
+func TestCasbinHandlers(t *testing.T) {
+	ctx := context.Background()
+	mgr, err := rbac.NewMemoryStoreManager(ctx, "", 0)
+	if err != nil {
+		t.Fatalf("NewMemoryStoreManager: %v", err)
+	}
+	srv := NewServer(mgr)
+	importCasbin := func(query, model, policy string) int {
+		body, _ := json.Marshal(map[string]string{"model": model, "policy": policy})
+		rec := httptest.NewRecorder()
+		srv.ImportCasbinHandler(rec, httptest.NewRequest(http.MethodPost, "/policy/casbin/import"+query, strings.NewReader(string(body))))
+		return rec.Code
+	}
+
+	policy := "p, reader, /docs/*, read, allow\np, editor, /docs/*, write, allow\ng, editor, reader\ng, alice, editor\n"
+	if code := importCasbin("?mode=replace", rbac.CasbinModel, policy); code != http.StatusOK {
+		t.Fatalf("import: expected 200, got %d", code)
+	}
+	if parents, err := mgr.ListRoleParents(ctx, "editor"); err != nil || len(parents) != 1 || parents[0] != "reader" {
+		t.Errorf("editor inherits from %v, %v after import; want reader", parents, err)
+	}
+	if code := importCasbin("?mode=upsert", rbac.CasbinModel, policy); code != http.StatusBadRequest {
+		t.Errorf("import with bad mode: expected 400, got %d", code)
+	}
+	if code := importCasbin("", "[request_definition]\nr = sub, obj\n", policy); code != http.StatusBadRequest {
+		t.Errorf("import of unsupported model: expected 400, got %d", code)
+	}
+
+	rec := httptest.NewRecorder()
+	srv.ExportCasbinHandler(rec, httptest.NewRequest(http.MethodGet, "/policy/casbin/export", nil))
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "text/csv" {
+		t.Fatalf("export: unexpected response %d %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	for _, line := range []string{"p,reader,", "p,editor,", "g,editor,reader", "g,alice,editor"} {
+		if !strings.Contains(rec.Body.String(), line) {
+			t.Errorf("export lacks %q:\n%s", line, rec.Body.String())
+		}
+	}
+
+	rec = httptest.NewRecorder()
+	srv.ExportCasbinHandler(rec, httptest.NewRequest(http.MethodGet, "/policy/casbin/export?file=model", nil))
+	if rec.Code != http.StatusOK || rec.Body.String() != rbac.CasbinModel {
+		t.Errorf("export of model: unexpected response %d %q", rec.Code, rec.Body.String())
+	}
+}
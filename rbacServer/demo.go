@@ -0,0 +1,118 @@
+package rbacServer
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Seann-Moser/rbac"
+)
+
+// NewDemoServer returns a server over an in-memory store preloaded by
+// LoadDemoPolicy, with permission usage tracking on. Nothing is persisted,
+// so every start begins from the same sample policy. It is meant for
+// exploring the API and the management UI, not for production.
+func NewDemoServer() (*Server, error) {
+	ctx := context.Background()
+	mgr, err := rbac.NewMemoryStoreManager(ctx, "", 0)
+	if err != nil {
+		return nil, err
+	}
+	mgr.Usage = rbac.NewUsageTracker(0, 0)
+	if err := LoadDemoPolicy(ctx, mgr); err != nil {
+		return nil, err
+	}
+	return NewServer(mgr), nil
+}
+
+// demoRole is a role of the sample policy with its permissions.
+type demoRole struct {
+	name, description string
+	parent            string
+	perms             []rbac.Permission
+}
+
+// LoadDemoPolicy fills mgr with a small publishing company: an editor role
+// that inherits from viewer, a deny rule carving billing out of the docs
+// editors may change, a region-scoped condition, groups, and users that
+// exercise each of them.
+func LoadDemoPolicy(ctx context.Context, mgr *rbac.Manager) error {
+	roles := []demoRole{
+		{name: "viewer", description: "Reads published docs and reports", perms: []rbac.Permission{
+			{Resource: "docs/**", Action: rbac.ActionRead},
+			{Resource: "reports/*", Action: rbac.ActionRead},
+		}},
+		{name: "editor", description: "Writes docs, except billing", parent: "viewer", perms: []rbac.Permission{
+			{Resource: "docs/**", Action: rbac.ActionAll},
+			{Resource: "docs/billing/*", Action: rbac.ActionUpdate, Effect: rbac.EffectDeny},
+		}},
+		{name: "admin", description: "Manages everything", perms: []rbac.Permission{
+			{Resource: "**", Action: rbac.ActionAll},
+		}},
+		{name: "billing", description: "Manages invoices in the user's own region", perms: []rbac.Permission{
+			{Resource: "invoices/*", Action: rbac.ActionAll, Condition: `attrs.region == user.meta.region`},
+		}},
+	}
+	users := []struct {
+		user   rbac.User
+		roles  []string
+		groups []string
+	}{
+		{rbac.User{Username: "alice", Email: "alice@example.com"}, []string{"admin"}, nil},
+		{rbac.User{Username: "bob", Email: "bob@example.com"}, nil, []string{"writers"}},
+		{rbac.User{Username: "carol", Email: "carol@example.com"}, nil, []string{"analysts"}},
+		{rbac.User{Username: "dave", Email: "dave@example.com", Meta: map[string]interface{}{"region": "eu"}}, nil, []string{"finance"}},
+		{rbac.User{Username: "erin", Email: "erin@example.com"}, nil, nil},
+	}
+	groups := map[string][]string{
+		"writers":  {"editor"},
+		"analysts": {"viewer"},
+		"finance":  {"viewer", "billing"},
+	}
+
+	ids := map[string]string{}
+	for _, dr := range roles {
+		role := &rbac.Role{Name: dr.name, Description: dr.description}
+		if err := mgr.CreateRole(ctx, role); err != nil {
+			return fmt.Errorf("demo: role %s: %w", dr.name, err)
+		}
+		ids[dr.name] = role.ID
+		if dr.parent != "" {
+			if err := mgr.AddRoleParent(ctx, role.ID, ids[dr.parent]); err != nil {
+				return fmt.Errorf("demo: role %s: %w", dr.name, err)
+			}
+		}
+		for _, p := range dr.perms {
+			perm := p
+			if err := mgr.CreatePermission(ctx, &perm); err != nil {
+				return fmt.Errorf("demo: permission %s: %w", p.Resource, err)
+			}
+			if err := mgr.AssignPermissionToRole(ctx, role.ID, perm.ID); err != nil {
+				return err
+			}
+		}
+	}
+	for name, roleNames := range groups {
+		for _, r := range roleNames {
+			if err := mgr.AssignRoleToGroup(ctx, name, ids[r]); err != nil {
+				return fmt.Errorf("demo: group %s: %w", name, err)
+			}
+		}
+	}
+	for _, du := range users {
+		u := du.user
+		if err := mgr.CreateUser(ctx, &u); err != nil {
+			return fmt.Errorf("demo: user %s: %w", u.Username, err)
+		}
+		for _, r := range du.roles {
+			if err := mgr.AssignRoleToUser(ctx, u.ID, ids[r]); err != nil {
+				return err
+			}
+		}
+		for _, g := range du.groups {
+			if err := mgr.AddUserToGroup(ctx, &rbac.UserGroup{UserID: u.ID, GroupName: g}); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
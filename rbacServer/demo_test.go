@@ -0,0 +1,46 @@
+package rbacServer
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Seann-Moser/rbac"
+)
+
+func TestNewDemoServer(t *testing.T) {
+	ctx := context.Background()
+	srv, err := NewDemoServer()
+	if err != nil {
+		t.Fatalf("NewDemoServer: %v", err)
+	}
+	mgr := srv.RBACManager
+
+	cases := []struct {
+		user, resource string
+		action         rbac.Action
+		attrs          map[string]any
+		want           bool
+	}{
+		{"alice", "anything/at/all", rbac.ActionDelete, nil, true},
+		{"bob", "docs/guide", rbac.ActionUpdate, nil, true},
+		{"bob", "docs/billing/rates", rbac.ActionUpdate, nil, false},
+		{"carol", "reports/q1", rbac.ActionRead, nil, true},
+		{"carol", "docs/guide", rbac.ActionUpdate, nil, false},
+		{"dave", "invoices/42", rbac.ActionUpdate, map[string]any{"region": "eu"}, true},
+		{"dave", "invoices/42", rbac.ActionUpdate, map[string]any{"region": "us"}, false},
+		{"erin", "docs/guide", rbac.ActionRead, nil, false},
+	}
+	for _, c := range cases {
+		u, err := mgr.Users.GetUserByMeta(ctx, map[string]interface{}{"username": c.user})
+		if err != nil || u == nil {
+			t.Fatalf("GetUserByMeta(%s): %v, %v", c.user, u, err)
+		}
+		got, err := mgr.CanWithAttributes(ctx, u.ID, c.resource, c.action, c.attrs)
+		if err != nil {
+			t.Fatalf("CanWithAttributes(%s, %s): %v", c.user, c.resource, err)
+		}
+		if got != c.want {
+			t.Errorf("CanWithAttributes(%s, %s, %s) = %v, want %v", c.user, c.resource, c.action, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,38 @@
+package rbacServer
+
+import (
+	"net/http"
+	"time"
+)
+
+// defaultExpiringWithin is how far ahead ExpiringAssignmentsHandler looks
+// when no within parameter is given.
+const defaultExpiringWithin = 7 * 24 * time.Hour
+
+// ExpiringAssignmentsHandler lists the role assignments and group
+// memberships that lapse within the given duration, soonest first.
+// GET /assignments/expiring?within=72h
+func (s *Server) ExpiringAssignmentsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, r, http.StatusMethodNotAllowed, "Method not allowed", nil)
+		return
+	}
+
+	within := defaultExpiringWithin
+	if v := r.URL.Query().Get("within"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			s.writeError(w, r, http.StatusBadRequest, "Invalid within query parameter", err)
+			return
+		}
+		within = d
+	}
+
+	expiring, err := s.manager(r).ListExpiringAssignments(r.Context(), within)
+	if err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, "Failed to list expiring assignments", err)
+		return
+	}
+
+	writeJSONResponse(w, http.StatusOK, expiring)
+}
@@ -0,0 +1,44 @@
+package rbacServer
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Seann-Moser/rbac"
+)
+
+func TestExpiringAssignmentsHandler(t *testing.T) {
+	ctx := context.Background()
+	mgr := rbac.NewMockRepoManager(rbac.NewMockRepo())
+	srv := NewServer(mgr)
+	if err := mgr.ScheduleRoleForUser(ctx, "alice", "oncall", time.Time{}, time.Now().Add(48*time.Hour)); err != nil {
+		t.Fatalf("ScheduleRoleForUser: %v", err)
+	}
+
+	list := func(query string) (*httptest.ResponseRecorder, []rbac.ExpiringAssignment) {
+		req := httptest.NewRequest(http.MethodGet, "/assignments/expiring"+query, nil)
+		rec := httptest.NewRecorder()
+		srv.ExpiringAssignmentsHandler(rec, req)
+		var out []rbac.ExpiringAssignment
+		if rec.Code == http.StatusOK {
+			if err := json.NewDecoder(rec.Body).Decode(&out); err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+		}
+		return rec, out
+	}
+
+	if rec, got := list(""); rec.Code != http.StatusOK || len(got) != 1 || got[0].UserID != "alice" {
+		t.Fatalf("default window: %d %+v", rec.Code, got)
+	}
+	if rec, got := list("?within=24h"); rec.Code != http.StatusOK || len(got) != 0 {
+		t.Fatalf("24h window: %d %+v", rec.Code, got)
+	}
+	if rec, _ := list("?within=soon"); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for a bad window, got %d", rec.Code)
+	}
+}
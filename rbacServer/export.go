@@ -0,0 +1,69 @@
+package rbacServer
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/Seann-Moser/rbac"
+)
+
+// ExportHandler streams every permission, role, user and assignment as
+// NDJSON (see rbac.ExportLine), a page at a time, at no more than
+// Server.ExportRate records per second. A client whose connection breaks
+// resumes by passing the cursor of the last line it received; a complete
+// export ends with a line of kind "end". Principals of a tenant may not
+// export.
+// GET /export?cursor=...&page_size=500
+func (s *Server) ExportHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, r, http.StatusMethodNotAllowed, "Method not allowed", nil)
+		return
+	}
+	if p := PrincipalFromContext(r.Context()); p != nil && p.TenantID != "" {
+		s.writeError(w, r, http.StatusForbidden, "Export is not available to tenant principals", nil)
+		return
+	}
+	opts := rbac.ExportOptions{Cursor: r.URL.Query().Get("cursor"), Rate: s.ExportRate}
+	if v := r.URL.Query().Get("page_size"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 || n > 10000 {
+			s.writeError(w, r, http.StatusBadRequest, "Invalid page_size query parameter", err)
+			return
+		}
+		opts.PageSize = n
+	}
+
+	fw := &flushWriter{w: w}
+	err := s.RBACManager.Export(r.Context(), fw, opts)
+	switch {
+	case err == nil:
+	case fw.wrote:
+		// The status is already sent; the missing end line tells the client
+		// to resume from its last cursor.
+		log.Printf("Export stream aborted: %v", err)
+	case errors.Is(err, rbac.ErrInvalidCursor):
+		s.writeError(w, r, http.StatusBadRequest, "Invalid cursor", err)
+	default:
+		s.writeError(w, r, http.StatusInternalServerError, "Failed to export", err)
+	}
+}
+
+// flushWriter sends each page of an export to the client as it is written.
+type flushWriter struct {
+	w     http.ResponseWriter
+	wrote bool
+}
+
+func (f *flushWriter) Write(p []byte) (int, error) {
+	if !f.wrote {
+		f.w.Header().Set("Content-Type", "application/x-ndjson")
+		f.wrote = true
+	}
+	n, err := f.w.Write(p)
+	if fl, ok := f.w.(http.Flusher); ok {
+		fl.Flush()
+	}
+	return n, err
+}
@@ -0,0 +1,60 @@
+package rbacServer
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Seann-Moser/rbac"
+)
+
+func TestExportHandler(t *testing.T) {
+	ctx := context.Background()
+	mgr, err := rbac.NewMemoryStoreManager(ctx, "", 0)
+	if err != nil {
+		t.Fatalf("NewMemoryStoreManager: %v", err)
+	}
+	for _, name := range []string{"alice", "bob", "carol"} {
+		if err := mgr.CreateUser(ctx, &rbac.User{Username: name}); err != nil {
+			t.Fatalf("CreateUser: %v", err)
+		}
+	}
+	srv := NewServer(mgr)
+
+	export := func(query string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		srv.ExportHandler(rec, httptest.NewRequest(http.MethodGet, "/export"+query, nil))
+		return rec
+	}
+
+	rec := export("?page_size=1")
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "application/x-ndjson" {
+		t.Fatalf("unexpected response %d %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	var lines []rbac.ExportLine
+	sc := bufio.NewScanner(rec.Body)
+	for sc.Scan() {
+		var l rbac.ExportLine
+		if err := json.Unmarshal(sc.Bytes(), &l); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		lines = append(lines, l)
+	}
+	// the default role, three users and the end line
+	if len(lines) != 5 || lines[4].Kind != rbac.KindExportEnd {
+		t.Fatalf("unexpected export: %+v", lines)
+	}
+
+	if rec := export("?cursor=" + lines[2].Cursor); rec.Code != http.StatusOK {
+		t.Fatalf("resume: expected 200, got %d", rec.Code)
+	}
+	if rec := export("?cursor=bogus"); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for a bad cursor, got %d", rec.Code)
+	}
+	if rec := export("?page_size=0"); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for a bad page_size, got %d", rec.Code)
+	}
+}
@@ -11,7 +11,7 @@ import (
 // Request Body: {"group_id": "group1", "role_id": "roleA"}
 func (s *Server) AssignRoleToGroupHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		writeErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed", nil)
+		s.writeError(w, r, http.StatusMethodNotAllowed, "Method not allowed", nil)
 		return
 	}
 
@@ -20,16 +20,16 @@ func (s *Server) AssignRoleToGroupHandler(w http.ResponseWriter, r *http.Request
 		RoleID  string `json:"role_id"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeErrorResponse(w, http.StatusBadRequest, "Invalid request body", err)
+		s.writeError(w, r, http.StatusBadRequest, "Invalid request body", err)
 		return
 	}
 
-	if err := s.RBACManager.AssignRoleToGroup(r.Context(), req.GroupID, req.RoleID); err != nil {
-		writeErrorResponse(w, http.StatusInternalServerError, "Failed to assign role to group", err)
+	if err := s.manager(r).AssignRoleToGroup(r.Context(), req.GroupID, req.RoleID); err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, "Failed to assign role to group", err)
 		return
 	}
 
-	writeJSONResponse(w, http.StatusOK, map[string]string{"message": "Role assigned to group successfully"})
+	writeJSONResponse(w, http.StatusOK, map[string]string{"message": s.Message(r, "Role assigned to group successfully")})
 }
 
 // UnassignRoleFromGroupHandler handles unassigning a role from a group.
@@ -37,7 +37,7 @@ func (s *Server) AssignRoleToGroupHandler(w http.ResponseWriter, r *http.Request
 // Request Body: {"group_id": "group1", "role_id": "roleA"}
 func (s *Server) UnassignRoleFromGroupHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		writeErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed", nil)
+		s.writeError(w, r, http.StatusMethodNotAllowed, "Method not allowed", nil)
 		return
 	}
 
@@ -46,126 +46,278 @@ func (s *Server) UnassignRoleFromGroupHandler(w http.ResponseWriter, r *http.Req
 		RoleID  string `json:"role_id"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeErrorResponse(w, http.StatusBadRequest, "Invalid request body", err)
+		s.writeError(w, r, http.StatusBadRequest, "Invalid request body", err)
 		return
 	}
 
-	if err := s.RBACManager.UnassignRoleFromGroup(r.Context(), req.GroupID, req.RoleID); err != nil {
-		writeErrorResponse(w, http.StatusInternalServerError, "Failed to unassign role from group", err)
+	if err := s.manager(r).UnassignRoleFromGroup(r.Context(), req.GroupID, req.RoleID); err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, "Failed to unassign role from group", err)
 		return
 	}
 
-	writeJSONResponse(w, http.StatusOK, map[string]string{"message": "Role unassigned from group successfully"})
+	writeJSONResponse(w, http.StatusOK, map[string]string{"message": s.Message(r, "Role unassigned from group successfully")})
 }
 
 // ListRolesForGroupHandler handles listing roles for a group.
 // GET /roles/list-for-group?group_id=group1
 func (s *Server) ListRolesForGroupHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		writeErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed", nil)
+		s.writeError(w, r, http.StatusMethodNotAllowed, "Method not allowed", nil)
 		return
 	}
 
 	groupID := r.URL.Query().Get("group_id")
 	if groupID == "" {
-		writeErrorResponse(w, http.StatusBadRequest, "Missing group_id query parameter", nil)
+		s.writeError(w, r, http.StatusBadRequest, "Missing group_id query parameter", nil)
 		return
 	}
 
-	roles, err := s.RBACManager.ListRolesForGroup(r.Context(), groupID)
+	roles, err := s.manager(r).ListRolesForGroup(r.Context(), groupID)
 	if err != nil {
-		writeErrorResponse(w, http.StatusInternalServerError, "Failed to list roles for group", err)
+		s.writeError(w, r, http.StatusInternalServerError, "Failed to list roles for group", err)
 		return
 	}
 
 	writeJSONResponse(w, http.StatusOK, roles)
 }
 
+// ListUsersForRoleHandler lists the users assigned a role directly.
+// GET /roles/list-users?role_id=role1
+func (s *Server) ListUsersForRoleHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, r, http.StatusMethodNotAllowed, "Method not allowed", nil)
+		return
+	}
+
+	roleID := r.URL.Query().Get("role_id")
+	if roleID == "" {
+		s.writeError(w, r, http.StatusBadRequest, "Missing role_id query parameter", nil)
+		return
+	}
+
+	users, err := s.manager(r).ListUsersForRole(r.Context(), roleID)
+	if err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, "Failed to list users for role", err)
+		return
+	}
+
+	writeJSONResponse(w, http.StatusOK, users)
+}
+
+// ListGroupsForRoleHandler lists the groups a role is assigned to.
+// GET /roles/list-groups?role_id=role1
+func (s *Server) ListGroupsForRoleHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, r, http.StatusMethodNotAllowed, "Method not allowed", nil)
+		return
+	}
+
+	roleID := r.URL.Query().Get("role_id")
+	if roleID == "" {
+		s.writeError(w, r, http.StatusBadRequest, "Missing role_id query parameter", nil)
+		return
+	}
+
+	groups, err := s.manager(r).ListGroupsForRole(r.Context(), roleID)
+	if err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, "Failed to list groups for role", err)
+		return
+	}
+
+	writeJSONResponse(w, http.StatusOK, groups)
+}
+
 // CreateRoleHandler handles creating a new role.
 // POST /roles/create
-// Request Body: {"id": "new_role_id", "name": "New Role Name"}
+// Request Body: {"id": "new_role_id", "name": "New Role Name", "meta": {"team": "billing"}}
 func (s *Server) CreateRoleHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		writeErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed", nil)
+		s.writeError(w, r, http.StatusMethodNotAllowed, "Method not allowed", nil)
 		return
 	}
 
 	var newRole rbac.Role
 	if err := json.NewDecoder(r.Body).Decode(&newRole); err != nil {
-		writeErrorResponse(w, http.StatusBadRequest, "Invalid request body", err)
+		s.writeError(w, r, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+
+	if err := s.manager(r).CreateRole(r.Context(), &newRole); err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, "Failed to create role", err)
+		return
+	}
+
+	writeJSONResponse(w, http.StatusCreated, map[string]string{"message": s.Message(r, "Role created successfully"), "role_id": newRole.ID})
+}
+
+// CloneRoleHandler copies a role and its permission bindings under a new
+// name, typically to stamp out a customer's role from a template role.
+// POST /roles/clone
+// Request Body: {"role_id": "blueprint", "name": "acme-support"}
+func (s *Server) CloneRoleHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, r, http.StatusMethodNotAllowed, "Method not allowed", nil)
 		return
 	}
 
-	if err := s.RBACManager.CreateRole(r.Context(), &newRole); err != nil {
-		writeErrorResponse(w, http.StatusInternalServerError, "Failed to create role", err)
+	var req struct {
+		RoleID string `json:"role_id"`
+		Name   string `json:"name"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.RoleID == "" || req.Name == "" {
+		s.writeError(w, r, http.StatusBadRequest, "Invalid request body", err)
 		return
 	}
 
-	writeJSONResponse(w, http.StatusCreated, map[string]string{"message": "Role created successfully", "role_id": newRole.ID})
+	clone, err := s.manager(r).CloneRole(r.Context(), req.RoleID, req.Name)
+	if err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, "Failed to clone role", err)
+		return
+	}
+
+	writeJSONResponse(w, http.StatusCreated, map[string]string{"message": s.Message(r, "Role cloned successfully"), "role_id": clone.ID})
 }
 
-// DeleteRoleHandler handles deleting a role.
+// DeleteRoleHandler handles deleting a role. The role is soft-deleted when
+// the store supports it; see RestoreRoleHandler and PurgeRoleHandler.
 // DELETE /roles/delete?id=roleID
 func (s *Server) DeleteRoleHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodDelete {
-		writeErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed", nil)
+		s.writeError(w, r, http.StatusMethodNotAllowed, "Method not allowed", nil)
+		return
+	}
+
+	roleID := r.URL.Query().Get("id")
+	if roleID == "" {
+		s.writeError(w, r, http.StatusBadRequest, "Missing role ID query parameter", nil)
+		return
+	}
+
+	if err := s.manager(r).DeleteRole(r.Context(), roleID); err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, "Failed to delete role", err)
+		return
+	}
+
+	writeJSONResponse(w, http.StatusOK, map[string]string{"message": s.Message(r, "Role deleted successfully")})
+}
+
+// RestoreRoleHandler handles restoring a soft-deleted role.
+// POST /roles/restore?id=roleID
+func (s *Server) RestoreRoleHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, r, http.StatusMethodNotAllowed, "Method not allowed", nil)
+		return
+	}
+
+	roleID := r.URL.Query().Get("id")
+	if roleID == "" {
+		s.writeError(w, r, http.StatusBadRequest, "Missing role ID query parameter", nil)
+		return
+	}
+
+	if err := s.manager(r).RestoreRole(r.Context(), roleID); err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, "Failed to restore role", err)
+		return
+	}
+
+	writeJSONResponse(w, http.StatusOK, map[string]string{"message": s.Message(r, "Role restored successfully")})
+}
+
+// PurgeRoleHandler handles deleting a role for good, soft-deleted or not.
+// DELETE /roles/purge?id=roleID
+func (s *Server) PurgeRoleHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		s.writeError(w, r, http.StatusMethodNotAllowed, "Method not allowed", nil)
 		return
 	}
 
 	roleID := r.URL.Query().Get("id")
 	if roleID == "" {
-		writeErrorResponse(w, http.StatusBadRequest, "Missing role ID query parameter", nil)
+		s.writeError(w, r, http.StatusBadRequest, "Missing role ID query parameter", nil)
 		return
 	}
 
-	if err := s.RBACManager.DeleteRole(r.Context(), roleID); err != nil {
-		writeErrorResponse(w, http.StatusInternalServerError, "Failed to delete role", err)
+	if err := s.manager(r).PurgeRole(r.Context(), roleID); err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, "Failed to purge role", err)
 		return
 	}
 
-	writeJSONResponse(w, http.StatusOK, map[string]string{"message": "Role deleted successfully"})
+	writeJSONResponse(w, http.StatusOK, map[string]string{"message": s.Message(r, "Role purged successfully")})
 }
 
 // GetRoleHandler handles retrieving a role by ID.
 // GET /roles/get?id=roleID
 func (s *Server) GetRoleHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		writeErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed", nil)
+		s.writeError(w, r, http.StatusMethodNotAllowed, "Method not allowed", nil)
 		return
 	}
 
 	roleID := r.URL.Query().Get("id")
 	if roleID == "" {
-		writeErrorResponse(w, http.StatusBadRequest, "Missing role ID query parameter", nil)
+		s.writeError(w, r, http.StatusBadRequest, "Missing role ID query parameter", nil)
+		return
+	}
+
+	role, err := s.manager(r).GetRole(r.Context(), roleID)
+	if err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, "Failed to get role", err)
+		return
+	}
+	if role == nil {
+		s.writeError(w, r, http.StatusNotFound, "Role not found", nil)
+		return
+	}
+
+	writeJSONResponse(w, http.StatusOK, role)
+}
+
+// GetRoleByNameHandler handles retrieving a role by name.
+// GET /roles/get-by-name?name=roleName
+func (s *Server) GetRoleByNameHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, r, http.StatusMethodNotAllowed, "Method not allowed", nil)
 		return
 	}
 
-	role, err := s.RBACManager.GetRole(r.Context(), roleID)
+	name := r.URL.Query().Get("name")
+	if name == "" {
+		s.writeError(w, r, http.StatusBadRequest, "Missing role name query parameter", nil)
+		return
+	}
+
+	role, err := s.manager(r).Roles.GetRoleByName(r.Context(), name)
 	if err != nil {
-		writeErrorResponse(w, http.StatusInternalServerError, "Failed to get role", err)
+		s.writeError(w, r, http.StatusInternalServerError, "Failed to get role", err)
 		return
 	}
 	if role == nil {
-		writeErrorResponse(w, http.StatusNotFound, "Role not found", nil)
+		s.writeError(w, r, http.StatusNotFound, "Role not found", nil)
 		return
 	}
 
 	writeJSONResponse(w, http.StatusOK, role)
 }
 
+// ListRoles handles listing every role, or a page of them when a cursor or
+// limit is given.
+// GET /roles/get-all?limit=100&cursor=...
 func (s *Server) ListRoles(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		writeErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed", nil)
+		s.writeError(w, r, http.StatusMethodNotAllowed, "Method not allowed", nil)
+		return
+	}
+	if writePage(s, w, r, s.manager(r).ListRolesPage) {
 		return
 	}
 
-	role, err := s.RBACManager.Roles.ListAllRoles(r.Context())
+	role, err := s.manager(r).Roles.ListAllRoles(r.Context())
 	if err != nil {
-		writeErrorResponse(w, http.StatusInternalServerError, "Failed to get role", err)
+		s.writeError(w, r, http.StatusInternalServerError, "Failed to get role", err)
 		return
 	}
 	if role == nil {
-		writeErrorResponse(w, http.StatusNotFound, "Role not found", nil)
+		s.writeError(w, r, http.StatusNotFound, "Role not found", nil)
 		return
 	}
 
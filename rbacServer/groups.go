@@ -0,0 +1,174 @@
+package rbacServer
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/Seann-Moser/rbac"
+)
+
+// CreateGroupHandler handles creating a new group.
+// POST /groups/create
+// Request Body: {"name": "writers", "description": "...", "owner": "user1"}
+func (s *Server) CreateGroupHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, r, http.StatusMethodNotAllowed, "Method not allowed", nil)
+		return
+	}
+
+	var group rbac.Group
+	if err := json.NewDecoder(r.Body).Decode(&group); err != nil {
+		s.writeError(w, r, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+
+	if err := s.manager(r).CreateGroup(r.Context(), &group); err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, "Failed to create group", err)
+		return
+	}
+
+	writeJSONResponse(w, http.StatusCreated, map[string]string{"message": s.Message(r, "Group created successfully"), "group_id": group.ID})
+}
+
+// GetGroupHandler handles retrieving a group by ID.
+// GET /groups/get?id=groupID
+func (s *Server) GetGroupHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, r, http.StatusMethodNotAllowed, "Method not allowed", nil)
+		return
+	}
+
+	groupID := r.URL.Query().Get("id")
+	if groupID == "" {
+		s.writeError(w, r, http.StatusBadRequest, "Missing group ID query parameter", nil)
+		return
+	}
+
+	group, err := s.manager(r).GetGroup(r.Context(), groupID)
+	if err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, "Failed to get group", err)
+		return
+	}
+	if group == nil {
+		s.writeError(w, r, http.StatusNotFound, "Group not found", nil)
+		return
+	}
+
+	writeJSONResponse(w, http.StatusOK, group)
+}
+
+// GetGroupByNameHandler handles retrieving a group by name.
+// GET /groups/get-by-name?name=writers
+func (s *Server) GetGroupByNameHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, r, http.StatusMethodNotAllowed, "Method not allowed", nil)
+		return
+	}
+
+	name := r.URL.Query().Get("name")
+	if name == "" {
+		s.writeError(w, r, http.StatusBadRequest, "Missing group name query parameter", nil)
+		return
+	}
+
+	group, err := s.manager(r).GetGroupByName(r.Context(), name)
+	if err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, "Failed to get group", err)
+		return
+	}
+	if group == nil {
+		s.writeError(w, r, http.StatusNotFound, "Group not found", nil)
+		return
+	}
+
+	writeJSONResponse(w, http.StatusOK, group)
+}
+
+// ListGroupsHandler handles listing all groups.
+// GET /groups/list
+func (s *Server) ListGroupsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, r, http.StatusMethodNotAllowed, "Method not allowed", nil)
+		return
+	}
+
+	groups, err := s.manager(r).ListGroups(r.Context())
+	if err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, "Failed to list groups", err)
+		return
+	}
+
+	writeJSONResponse(w, http.StatusOK, groups)
+}
+
+// UpdateGroupHandler handles updating a group's description, metadata and
+// owner. The name cannot be changed here; use /groups/rename.
+// POST /groups/update
+// Request Body: {"id": "grp_1", "name": "writers", "description": "..."}
+func (s *Server) UpdateGroupHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, r, http.StatusMethodNotAllowed, "Method not allowed", nil)
+		return
+	}
+
+	var group rbac.Group
+	if err := json.NewDecoder(r.Body).Decode(&group); err != nil {
+		s.writeError(w, r, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+
+	if err := s.manager(r).UpdateGroup(r.Context(), &group); err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, "Failed to update group", err)
+		return
+	}
+
+	writeJSONResponse(w, http.StatusOK, map[string]string{"message": s.Message(r, "Group updated successfully")})
+}
+
+// RenameGroupHandler handles renaming a group, moving its members and roles.
+// POST /groups/rename
+// Request Body: {"id": "grp_1", "name": "authors"}
+func (s *Server) RenameGroupHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, r, http.StatusMethodNotAllowed, "Method not allowed", nil)
+		return
+	}
+
+	var req struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.writeError(w, r, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+
+	if err := s.manager(r).RenameGroup(r.Context(), req.ID, req.Name); err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, "Failed to rename group", err)
+		return
+	}
+
+	writeJSONResponse(w, http.StatusOK, map[string]string{"message": s.Message(r, "Group renamed successfully")})
+}
+
+// DeleteGroupHandler handles deleting a group with its memberships and roles.
+// DELETE /groups/delete?id=groupID
+func (s *Server) DeleteGroupHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		s.writeError(w, r, http.StatusMethodNotAllowed, "Method not allowed", nil)
+		return
+	}
+
+	groupID := r.URL.Query().Get("id")
+	if groupID == "" {
+		s.writeError(w, r, http.StatusBadRequest, "Missing group ID query parameter", nil)
+		return
+	}
+
+	if err := s.manager(r).DeleteGroup(r.Context(), groupID); err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, "Failed to delete group", err)
+		return
+	}
+
+	writeJSONResponse(w, http.StatusOK, map[string]string{"message": s.Message(r, "Group deleted successfully")})
+}
@@ -0,0 +1,35 @@
+package rbacServer
+
+import (
+	"net/http"
+
+	"github.com/Seann-Moser/rbac"
+)
+
+// healthResponse is the report of rbac.Manager.Health, with an error
+// message when a store is unhealthy.
+type healthResponse struct {
+	*rbac.HealthReport
+	Error string `json:"error,omitempty"`
+}
+
+// HealthHandler answers readiness probes: 200 with the Manager's health
+// report while every store passes its check, 503 with the report once one
+// fails. It checks the server's own Manager whoever asks, so mount it
+// outside any authentication middleware.
+func (s *Server) HealthHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		s.writeError(w, r, http.StatusMethodNotAllowed, "Method not allowed", nil)
+		return
+	}
+
+	report, err := s.RBACManager.Health(r.Context())
+	if err != nil {
+		writeJSONResponse(w, http.StatusServiceUnavailable, healthResponse{
+			HealthReport: report,
+			Error:        s.Message(r, "Policy store is unhealthy"),
+		})
+		return
+	}
+	writeJSONResponse(w, http.StatusOK, healthResponse{HealthReport: report})
+}
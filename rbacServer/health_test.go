@@ -0,0 +1,49 @@
+package rbacServer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Seann-Moser/rbac"
+)
+
+type downStore struct{ *rbac.MockRepo }
+
+func (downStore) Health(context.Context) error { return errors.New("connection refused") }
+
+func TestHealthHandler(t *testing.T) {
+	mgr := rbac.NewMockRepoManager(rbac.NewMockRepo())
+	srv := NewServer(mgr)
+	mux := http.NewServeMux()
+	srv.Routes(mux)
+	get := func() (int, healthResponse) {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+		var out healthResponse
+		if err := json.NewDecoder(rec.Body).Decode(&out); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		return rec.Code, out
+	}
+
+	if code, out := get(); code != http.StatusOK || !out.Healthy || out.Error != "" {
+		t.Errorf("healthy store: got %d %+v", code, out)
+	}
+
+	mgr.Users = downStore{rbac.NewMockRepo()}
+	code, out := get()
+	if code != http.StatusServiceUnavailable || out.Healthy || out.Error != "Policy store is unhealthy" || len(out.Stores) != 2 {
+		t.Errorf("unhealthy store: got %d %+v", code, out)
+	}
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/healthz", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("POST: expected 405, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,42 @@
+package rbacServer
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/Seann-Moser/rbac"
+)
+
+type integrityRequest struct {
+	Repair     bool `json:"repair"`
+	CheckUsers bool `json:"check_users"`
+}
+
+// IntegrityCheckHandler runs rbac.Manager.CheckIntegrity and returns its
+// report. It checks the whole store, so principals of a tenant may not run
+// it; an empty body checks without repairing.
+// POST /integrity/check {"repair": false, "check_users": false}
+func (s *Server) IntegrityCheckHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, r, http.StatusMethodNotAllowed, "Method not allowed", nil)
+		return
+	}
+	if p := PrincipalFromContext(r.Context()); p != nil && p.TenantID != "" {
+		s.writeError(w, r, http.StatusForbidden, "Integrity checks are not available to tenant principals", nil)
+		return
+	}
+	var req integrityRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		s.writeError(w, r, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+
+	report, err := s.RBACManager.CheckIntegrity(r.Context(), rbac.IntegrityOptions{Repair: req.Repair, CheckUsers: req.CheckUsers})
+	if err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, "Failed to check integrity", err)
+		return
+	}
+	writeJSONResponse(w, http.StatusOK, report)
+}
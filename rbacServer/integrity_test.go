@@ -0,0 +1,49 @@
+package rbacServer
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Seann-Moser/rbac"
+)
+
+func TestIntegrityCheckHandler(t *testing.T) {
+	ctx := context.Background()
+	mgr, err := rbac.NewMemoryStoreManager(ctx, "", 0)
+	if err != nil {
+		t.Fatalf("NewMemoryStoreManager: %v", err)
+	}
+	if err := mgr.UR.AddUR(ctx, "alice", "gone-role"); err != nil {
+		t.Fatalf("AddUR: %v", err)
+	}
+	srv := NewServer(mgr)
+	check := func(body string) (int, rbac.IntegrityReport) {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		srv.IntegrityCheckHandler(rec, httptest.NewRequest(http.MethodPost, "/integrity/check", strings.NewReader(body)))
+		var out rbac.IntegrityReport
+		_ = json.NewDecoder(rec.Body).Decode(&out)
+		return rec.Code, out
+	}
+
+	if code, out := check(""); code != http.StatusOK || len(out.Issues) != 1 || out.Issues[0].Repaired {
+		t.Errorf("check: got %d %+v; want one unrepaired issue", code, out)
+	}
+	if code, out := check(`{"repair": true}`); code != http.StatusOK || len(out.Issues) != 1 || !out.Issues[0].Repaired {
+		t.Errorf("repair: got %d %+v; want the issue repaired", code, out)
+	}
+	if code, out := check("{}"); code != http.StatusOK || len(out.Issues) != 0 {
+		t.Errorf("after repair: got %d %+v; want no issues", code, out)
+	}
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/integrity/check", nil)
+	srv.IntegrityCheckHandler(rec, req.WithContext(WithPrincipal(ctx, &rbac.User{ID: "t1-admin", TenantID: "t1"})))
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("tenant principal: expected 403, got %d", rec.Code)
+	}
+}
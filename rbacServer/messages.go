@@ -0,0 +1,366 @@
+package rbacServer
+
+import (
+	"encoding/json"
+	"html"
+	"net/http"
+	"sort"
+	"strings"
+
+	"golang.org/x/text/language"
+)
+
+// MessageKeys lists every user-facing message of the server's responses and
+// of the management UI, in English. Translations are keyed by these strings.
+var MessageKeys = append(append([]string(nil), serverMessages...), uiMessages...)
+
+var serverMessages = []string{
+	"API key created successfully",
+	"API key revoked successfully",
+	"Apply is not available to tenant principals",
+	"Archive not found",
+	"Archive restored successfully",
+	"Authentication not configured",
+	"Export is not available to tenant principals",
+	"Failed to acknowledge notification",
+	"Failed to add user to group",
+	"Failed to add users to group",
+	"Failed to apply policy",
+	"Failed to approve role assignment",
+	"Failed to archive",
+	"Failed to assign permission to role",
+	"Failed to assign permissions to role",
+	"Failed to assign role to group",
+	"Failed to assign role to user",
+	"Failed to assign roles to user",
+	"Failed to check integrity",
+	"Failed to check permission",
+	"Failed to clone role",
+	"Failed to create API key",
+	"Failed to create group",
+	"Failed to create permission",
+	"Failed to create role",
+	"Failed to create separation of duties constraint",
+	"Failed to create user",
+	"Failed to delete group",
+	"Failed to delete permission",
+	"Failed to delete role",
+	"Failed to delete separation of duties constraint",
+	"Failed to delete user",
+	"Failed to diff policy snapshots",
+	"Failed to explain decision",
+	"Failed to export",
+	"Failed to export Casbin policy",
+	"Failed to export policy",
+	"Failed to find user",
+	"Failed to find who can access resource",
+	"Failed to get archive",
+	"Failed to get group",
+	"Failed to get groups by user ID",
+	"Failed to get permission",
+	"Failed to get permission usage",
+	"Failed to get policy snapshot",
+	"Failed to get role",
+	"Failed to get stats",
+	"Failed to get user",
+	"Failed to get users by group ID",
+	"Failed to import Casbin policy",
+	"Failed to import policy",
+	"Failed to list API keys",
+	"Failed to list archives",
+	"Failed to list assignment requests",
+	"Failed to list audit entries",
+	"Failed to list effective permissions",
+	"Failed to list expiring assignments",
+	"Failed to list groups",
+	"Failed to list groups for role",
+	"Failed to list permissions",
+	"Failed to list permissions for role",
+	"Failed to list policy snapshots",
+	"Failed to list roles for group",
+	"Failed to list roles for user",
+	"Failed to list separation of duties constraints",
+	"Failed to list users",
+	"Failed to list users for role",
+	"Failed to perform authorization check",
+	"Failed to purge permission",
+	"Failed to purge role",
+	"Failed to reactivate user",
+	"Failed to read page",
+	"Failed to read policy version",
+	"Failed to reject role assignment",
+	"Failed to remove permission from role",
+	"Failed to remove user from group",
+	"Failed to rename group",
+	"Failed to request role assignment",
+	"Failed to restore archive",
+	"Failed to restore permission",
+	"Failed to restore role",
+	"Failed to revoke API key",
+	"Failed to roll back policy",
+	"Failed to search",
+	"Failed to snapshot policy",
+	"Failed to suspend user",
+	"Failed to unassign role from group",
+	"Failed to unassign role from user",
+	"Failed to update group",
+	"Group archived successfully",
+	"Group created successfully",
+	"Group deleted successfully",
+	"Group not found",
+	"Group renamed successfully",
+	"Group updated successfully",
+	"Import is not available to tenant principals",
+	"Integrity checks are not available to tenant principals",
+	"Invalid Casbin policy",
+	"Invalid client IP",
+	"Invalid cursor",
+	"Invalid file query parameter",
+	"Invalid format query parameter",
+	"Invalid limit query parameter",
+	"Invalid mode query parameter",
+	"Invalid page_size query parameter",
+	"Invalid policy bundle",
+	"Invalid request body",
+	"Invalid since query parameter",
+	"Invalid until query parameter",
+	"Invalid window query parameter",
+	"Invalid within query parameter",
+	"Method not allowed",
+	"Missing API key",
+	"Missing archive ID query parameter",
+	"Missing email query parameter",
+	"Missing from or to query parameter",
+	"Missing group ID query parameter",
+	"Missing group name query parameter",
+	"Missing group_id query parameter",
+	"Missing permission ID query parameter",
+	"Missing permission name query parameter",
+	"Missing principal_id query parameter",
+	"Missing q query parameter",
+	"Missing resource or action query parameter",
+	"Missing role ID query parameter",
+	"Missing role name query parameter",
+	"Missing role_id query parameter",
+	"Missing snapshot ID query parameter",
+	"Missing user ID query parameter",
+	"Missing user_id or perm_id query parameters",
+	"Missing user_id query parameter",
+	"Notification not found",
+	"Notifications are not available to tenant principals",
+	"Permission assigned to role successfully",
+	"Permission created successfully",
+	"Permission deleted successfully",
+	"Permission not found",
+	"Permission purged successfully",
+	"Permission removed from role successfully",
+	"Permission restored successfully",
+	"Permission usage tracking is not enabled",
+	"Permissions assigned to role successfully",
+	"Policy imported successfully",
+	"Policy snapshot created successfully",
+	"Policy snapshot not found",
+	"Policy snapshots are not available to tenant principals",
+	"Policy store is unhealthy",
+	"Resource catalog is not configured",
+	"Role archived successfully",
+	"Role assigned to group successfully",
+	"Role assigned to user successfully",
+	"Role cloned successfully",
+	"Role created successfully",
+	"Role deleted successfully",
+	"Role not found",
+	"Role purged successfully",
+	"Role restored successfully",
+	"Role unassigned from group successfully",
+	"Role unassigned from user successfully",
+	"Roles assigned to user successfully",
+	"Separation of duties constraint deleted successfully",
+	"Unauthorized",
+	"User added to group successfully",
+	"User created successfully",
+	"User deleted successfully",
+	"User not found",
+	"User reactivated successfully",
+	"User removed from group successfully",
+	"User suspended successfully",
+	"Users added to group successfully",
+}
+
+var uiMessages = []string{
+	"Action",
+	"Action (e.g., read, write)",
+	"Action (e.g., view, edit)",
+	"Action:",
+	"Add User",
+	"Add User to Group",
+	"All Permissions",
+	"All Roles",
+	"All Users",
+	"Assign Permission",
+	"Assign Permission to Role",
+	"Assign Role",
+	"Assign Role to Group",
+	"Assign Role to User",
+	"Authorization Checks",
+	"Can Perform Action on Resource?",
+	"Can Perform Action:",
+	"Check Can",
+	"Check Permission",
+	"Create Permission",
+	"Create Role",
+	"Create User",
+	"Delete Permission",
+	"Delete Role",
+	"Delete User",
+	"Error:",
+	"Get Permission",
+	"Get Role",
+	"Get User",
+	"Group ID",
+	"Group Name (e.g., admin_team)",
+	"Group-Role Management",
+	"Group:",
+	"Groups for User:",
+	"HTTP error! Status:",
+	"Has Permission:",
+	"Has Permission?",
+	"ID",
+	"ID:",
+	"List Groups",
+	"List Groups for User",
+	"List Permissions",
+	"List Permissions for Role",
+	"List Roles",
+	"List Roles for Group",
+	"List Roles for User",
+	"List Users",
+	"List Users by Group",
+	"Name",
+	"Name:",
+	"No",
+	"No permissions found.",
+	"No roles found.",
+	"No users found.",
+	"None",
+	"Operation successful!",
+	"Permission ID",
+	"Permission Name",
+	"Permissions",
+	"Permissions:",
+	"RBAC Manager",
+	"RBAC Manager Web Interface",
+	"Refresh Permissions",
+	"Refresh Roles",
+	"Refresh Users",
+	"Remove Permission",
+	"Remove Permission from Role",
+	"Remove User",
+	"Remove User from Group",
+	"Resource",
+	"Resource (e.g., /docs/**)",
+	"Resource (e.g., /documents/123)",
+	"Resource:",
+	"Role ID",
+	"Role Name",
+	"Role-Permission Management",
+	"Roles",
+	"Roles in Group:",
+	"Roles:",
+	"Unassign Role",
+	"Unassign Role from Group",
+	"Unassign Role from User",
+	"User ID",
+	"User Name",
+	"User-Group Management",
+	"User-Role Management",
+	"User:",
+	"Users",
+	"Users in Group:",
+	"Yes",
+}
+
+// MessageCatalog maps a locale (a BCP 47 tag such as "de" or "pt-BR") to
+// translations of MessageKeys. Messages missing from a locale are sent in
+// English. A locale with no translations, e.g. {"de": nil}, is still offered
+// to clients, for deployments that translate through Server.Translate.
+type MessageCatalog map[string]map[string]string
+
+// Locale returns the locale a request is answered in: the best match among
+// DefaultLocale and the locales of Messages for the lang query parameter,
+// or else for the Accept-Language header.
+func (s *Server) Locale(r *http.Request) string {
+	supported := []string{s.defaultLocale()}
+	for locale := range s.Messages {
+		if locale != supported[0] {
+			supported = append(supported, locale)
+		}
+	}
+	if len(supported) == 1 {
+		return supported[0]
+	}
+	sort.Strings(supported[1:])
+	tags := make([]language.Tag, len(supported))
+	for i, l := range supported {
+		tags[i] = language.Make(l)
+	}
+	matcher := language.NewMatcher(tags)
+
+	var prefs []language.Tag
+	if lang := r.URL.Query().Get("lang"); lang != "" {
+		if tag, err := language.Parse(lang); err == nil {
+			prefs = []language.Tag{tag}
+		}
+	}
+	if prefs == nil {
+		prefs, _, _ = language.ParseAcceptLanguage(r.Header.Get("Accept-Language"))
+	}
+	if _, i, conf := matcher.Match(prefs...); conf != language.No {
+		return supported[i]
+	}
+	return supported[0]
+}
+
+func (s *Server) defaultLocale() string {
+	if s.DefaultLocale != "" {
+		return s.DefaultLocale
+	}
+	return "en"
+}
+
+// Message returns msg, one of MessageKeys, in the request's locale: from
+// Translate when it returns a non-empty string, else from Messages, else
+// msg itself.
+func (s *Server) Message(r *http.Request, msg string) string {
+	locale := s.Locale(r)
+	if s.Translate != nil {
+		if out := s.Translate(r, locale, msg); out != "" {
+			return out
+		}
+	}
+	if out := s.Messages[locale][msg]; out != "" {
+		return out
+	}
+	return msg
+}
+
+// writeError sends an error response with message translated for r.
+func (s *Server) writeError(w http.ResponseWriter, r *http.Request, statusCode int, message string, err error) {
+	writeErrorResponse(w, statusCode, s.Message(r, message), err)
+}
+
+// managementPage returns the management UI with its lang attribute and
+// strings set for r's locale.
+func (s *Server) managementPage(r *http.Request) string {
+	locale := s.Locale(r)
+	msgs := map[string]string{}
+	for _, key := range uiMessages {
+		if out := s.Message(r, key); out != key {
+			msgs[key] = out
+		}
+	}
+	// json.Marshal escapes <, > and &, so the result is safe inside <script>.
+	data, _ := json.Marshal(msgs)
+	page := strings.Replace(rbacManagementHTML, `<html lang="en">`, `<html lang="`+html.EscapeString(locale)+`">`, 1)
+	return strings.Replace(page, "{/*messages*/}", string(data), 1)
+}
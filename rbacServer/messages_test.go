@@ -0,0 +1,133 @@
+package rbacServer
+
+import (
+	"encoding/json"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/Seann-Moser/rbac"
+)
+
+func TestLocalizedMessages(t *testing.T) {
+	srv := NewServer(rbac.NewMockRepoManager(rbac.NewMockRepo()))
+	srv.Messages = MessageCatalog{
+		"de":    {"Method not allowed": "Methode nicht erlaubt", "Users": "Benutzer"},
+		"pt-BR": {"Method not allowed": "Método não permitido"},
+	}
+
+	errorFor := func(setup func(*http.Request)) string {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, "/users/create", nil)
+		setup(req)
+		rec := httptest.NewRecorder()
+		srv.CreateUserHandler(rec, req)
+		var body map[string]string
+		if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		return body["error"]
+	}
+
+	tests := []struct {
+		name  string
+		setup func(*http.Request)
+		want  string
+	}{
+		{"default", func(*http.Request) {}, "Method not allowed"},
+		{"accept-language", func(r *http.Request) { r.Header.Set("Accept-Language", "fr;q=0.9, de-DE;q=0.8") }, "Methode nicht erlaubt"},
+		{"region", func(r *http.Request) { r.Header.Set("Accept-Language", "pt-BR") }, "Método não permitido"},
+		{"query", func(r *http.Request) {
+			r.Header.Set("Accept-Language", "de")
+			r.URL.RawQuery = "lang=pt-BR"
+		}, "Método não permitido"},
+		{"unsupported", func(r *http.Request) { r.Header.Set("Accept-Language", "ja") }, "Method not allowed"},
+	}
+	for _, tt := range tests {
+		if got := errorFor(tt.setup); got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+
+	srv.Translate = func(r *http.Request, locale, msg string) string {
+		if msg == "Method not allowed" {
+			return "[" + locale + "] Nope"
+		}
+		return ""
+	}
+	if got := errorFor(func(r *http.Request) { r.Header.Set("Accept-Language", "de") }); got != "[de] Nope" {
+		t.Errorf("expected the Translate hook to win, got %q", got)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/manage", nil)
+	req.Header.Set("Accept-Language", "de")
+	rec := httptest.NewRecorder()
+	srv.MangementInterface(rec, req)
+	page := rec.Body.String()
+	if !strings.Contains(page, `<html lang="de">`) || !strings.Contains(page, `"Users":"Benutzer"`) {
+		t.Error("expected the management UI to carry the German locale and translations")
+	}
+}
+
+// TestMessageKeysComplete keeps MessageKeys in step with the handlers and
+// the management UI, so translators see every message.
+func TestMessageKeysComplete(t *testing.T) {
+	files, err := filepath.Glob("*.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fset := token.NewFileSet()
+	for _, name := range files {
+		if strings.HasSuffix(name, "_test.go") || name == "messages.go" {
+			continue
+		}
+		f, err := parser.ParseFile(fset, name, nil, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ast.Inspect(f, func(n ast.Node) bool {
+			call, ok := n.(*ast.CallExpr)
+			if !ok {
+				return true
+			}
+			sel, ok := call.Fun.(*ast.SelectorExpr)
+			if !ok {
+				return true
+			}
+			arg := -1
+			switch sel.Sel.Name {
+			case "writeError":
+				arg = 3
+			case "Message":
+				arg = 1
+			}
+			if arg < 0 || len(call.Args) <= arg {
+				return true
+			}
+			lit, ok := call.Args[arg].(*ast.BasicLit)
+			if !ok {
+				t.Errorf("%s: message is not a string literal", fset.Position(call.Pos()))
+				return true
+			}
+			msg, _ := strconv.Unquote(lit.Value)
+			if !slices.Contains(MessageKeys, msg) {
+				t.Errorf("%s: %q missing from MessageKeys", fset.Position(call.Pos()), msg)
+			}
+			return true
+		})
+	}
+
+	for _, m := range regexp.MustCompile(`\bt\('([^']*)'\)`).FindAllStringSubmatch(rbacManagementHTML, -1) {
+		if !slices.Contains(MessageKeys, m[1]) {
+			t.Errorf("management UI: %q missing from MessageKeys", m[1])
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package rbacServer
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/Seann-Moser/rbac"
+)
+
+// PendingNotificationsHandler lists owner notifications awaiting
+// acknowledgment, optionally for one team. The catalog is shared by every
+// tenant, so principals of a tenant may not read it.
+// GET /notifications/pending?team=finance
+func (s *Server) PendingNotificationsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, r, http.StatusMethodNotAllowed, "Method not allowed", nil)
+		return
+	}
+	if p := PrincipalFromContext(r.Context()); p != nil && p.TenantID != "" {
+		s.writeError(w, r, http.StatusForbidden, "Notifications are not available to tenant principals", nil)
+		return
+	}
+	if s.RBACManager.Catalog == nil {
+		s.writeError(w, r, http.StatusNotImplemented, "Resource catalog is not configured", nil)
+		return
+	}
+	pending := s.RBACManager.Catalog.Pending(r.URL.Query().Get("team"))
+	if pending == nil {
+		pending = []*rbac.OwnerNotification{}
+	}
+	writeJSONResponse(w, http.StatusOK, pending)
+}
+
+// AcknowledgeNotificationHandler acknowledges an owner notification. "by"
+// defaults to the authenticated principal's username. Like
+// PendingNotificationsHandler, it refuses tenant principals.
+// POST /notifications/acknowledge
+// Request Body: {"id": "...", "by": "finance-lead"}
+func (s *Server) AcknowledgeNotificationHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, r, http.StatusMethodNotAllowed, "Method not allowed", nil)
+		return
+	}
+	if p := PrincipalFromContext(r.Context()); p != nil && p.TenantID != "" {
+		s.writeError(w, r, http.StatusForbidden, "Notifications are not available to tenant principals", nil)
+		return
+	}
+	if s.RBACManager.Catalog == nil {
+		s.writeError(w, r, http.StatusNotImplemented, "Resource catalog is not configured", nil)
+		return
+	}
+	var req struct {
+		ID string `json:"id"`
+		By string `json:"by"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.ID == "" {
+		s.writeError(w, r, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+	if p := PrincipalFromContext(r.Context()); p != nil && req.By == "" {
+		req.By = p.Username
+	}
+
+	n, err := s.RBACManager.Catalog.Acknowledge(req.ID, req.By)
+	if errors.Is(err, rbac.ErrNotificationNotFound) {
+		s.writeError(w, r, http.StatusNotFound, "Notification not found", err)
+		return
+	}
+	if err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, "Failed to acknowledge notification", err)
+		return
+	}
+	writeJSONResponse(w, http.StatusOK, n)
+}
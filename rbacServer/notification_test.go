@@ -0,0 +1,43 @@
+package rbacServer
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Seann-Moser/rbac"
+)
+
+func TestNotificationHandlersRefuseTenantPrincipals(t *testing.T) {
+	ctx := context.Background()
+	mgr, err := rbac.NewMemoryStoreManager(ctx, "", 0)
+	if err != nil {
+		t.Fatalf("NewMemoryStoreManager: %v", err)
+	}
+	if mgr.Catalog, err = rbac.NewResourceCatalog(); err != nil {
+		t.Fatalf("NewResourceCatalog: %v", err)
+	}
+	srv := NewServer(mgr)
+	tenantCtx := WithPrincipal(ctx, &rbac.User{ID: "t1-admin", TenantID: "t1"})
+
+	rec := httptest.NewRecorder()
+	srv.PendingNotificationsHandler(rec, httptest.NewRequest(http.MethodGet, "/notifications/pending", nil).WithContext(tenantCtx))
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("pending: expected 403, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/notifications/acknowledge", strings.NewReader(`{"id": "n1"}`))
+	srv.AcknowledgeNotificationHandler(rec, req.WithContext(tenantCtx))
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("acknowledge: expected 403, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	srv.PendingNotificationsHandler(rec, httptest.NewRequest(http.MethodGet, "/notifications/pending", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("pending without a tenant: expected 200, got %d", rec.Code)
+	}
+}
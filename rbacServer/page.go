@@ -0,0 +1,40 @@
+package rbacServer
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/Seann-Moser/rbac"
+)
+
+// writePage answers a list request carrying a cursor or limit query
+// parameter with one page from list (see rbac.PageResult), and reports
+// whether it did. Requests with neither get the whole list as before.
+func writePage[T any](s *Server, w http.ResponseWriter, r *http.Request, list func(context.Context, rbac.PageRequest) (rbac.PageResult[T], error)) bool {
+	q := r.URL.Query()
+	if !q.Has("cursor") && !q.Has("limit") {
+		return false
+	}
+	page := rbac.PageRequest{Cursor: q.Get("cursor")}
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 || n > rbac.MaxPageLimit {
+			s.writeError(w, r, http.StatusBadRequest, "Invalid limit query parameter", err)
+			return true
+		}
+		page.Limit = n
+	}
+
+	res, err := list(r.Context(), page)
+	switch {
+	case errors.Is(err, rbac.ErrInvalidPageCursor):
+		s.writeError(w, r, http.StatusBadRequest, "Invalid cursor", err)
+	case err != nil:
+		s.writeError(w, r, http.StatusInternalServerError, "Failed to read page", err)
+	default:
+		writeJSONResponse(w, http.StatusOK, res)
+	}
+	return true
+}
@@ -0,0 +1,57 @@
+package rbacServer
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Seann-Moser/rbac"
+)
+
+func TestListPages(t *testing.T) {
+	ctx := context.Background()
+	mgr, err := rbac.NewMemoryStoreManager(ctx, "", 0)
+	if err != nil {
+		t.Fatalf("NewMemoryStoreManager: %v", err)
+	}
+	for _, name := range []string{"alice", "bob", "carol"} {
+		if err := mgr.CreateUser(ctx, &rbac.User{Username: name}); err != nil {
+			t.Fatalf("CreateUser: %v", err)
+		}
+	}
+	srv := NewServer(mgr)
+
+	get := func(url string) (int, rbac.PageResult[*rbac.User]) {
+		rec := httptest.NewRecorder()
+		srv.ListUsersHandler(rec, httptest.NewRequest(http.MethodGet, url, nil))
+		var page rbac.PageResult[*rbac.User]
+		if rec.Code == http.StatusOK {
+			if err := json.NewDecoder(rec.Body).Decode(&page); err != nil {
+				t.Fatalf("decode %s: %v", url, err)
+			}
+		}
+		return rec.Code, page
+	}
+
+	code, first := get("/users/get-all?limit=2")
+	if code != http.StatusOK || len(first.Items) != 2 || first.NextCursor == "" {
+		t.Fatalf("first page: %d %+v", code, first)
+	}
+	code, last := get("/users/get-all?limit=2&cursor=" + first.NextCursor)
+	if code != http.StatusOK || len(last.Items) != 1 || last.NextCursor != "" {
+		t.Errorf("last page: %d %+v", code, last)
+	}
+	if code, _ := get("/users/get-all?limit=0"); code != http.StatusBadRequest {
+		t.Errorf("limit=0: expected 400, got %d", code)
+	}
+
+	// without paging parameters the whole list is returned as before
+	rec := httptest.NewRecorder()
+	srv.ListUsersHandler(rec, httptest.NewRequest(http.MethodGet, "/users/get-all", nil))
+	var users []*rbac.User
+	if err := json.NewDecoder(rec.Body).Decode(&users); err != nil || len(users) != 3 {
+		t.Errorf("unpaged list: %v, %v", users, err)
+	}
+}
@@ -1,77 +1,201 @@
 package rbacServer
 
 import (
+	"context"
 	"encoding/json"
 	"github.com/Seann-Moser/rbac"
 	"net/http"
+	"time"
 )
 
 // CreatePermissionHandler handles creating a new permission.
 // POST /permissions/create
-// Request Body: {"id": "new_perm_id", "name": "New Permission Name", "resource": "/api/data", "action": "read"}
+// Request Body: {"name": "data.read", "description": "Read the data API", "labels": {"owner": "data"}, "resource": "/api/data", "action": "read"}
 func (s *Server) CreatePermissionHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		writeErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed", nil)
+		s.writeError(w, r, http.StatusMethodNotAllowed, "Method not allowed", nil)
 		return
 	}
 
 	var newPerm rbac.Permission
 	if err := json.NewDecoder(r.Body).Decode(&newPerm); err != nil {
-		writeErrorResponse(w, http.StatusBadRequest, "Invalid request body", err)
+		s.writeError(w, r, http.StatusBadRequest, "Invalid request body", err)
 		return
 	}
 
-	if err := s.RBACManager.CreatePermission(r.Context(), &newPerm); err != nil {
-		writeErrorResponse(w, http.StatusInternalServerError, "Failed to create permission", err)
+	if err := s.manager(r).CreatePermission(r.Context(), &newPerm); err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, "Failed to create permission", err)
 		return
 	}
 
-	writeJSONResponse(w, http.StatusCreated, map[string]string{"message": "Permission created successfully", "permission_id": newPerm.ID})
+	writeJSONResponse(w, http.StatusCreated, map[string]string{"message": s.Message(r, "Permission created successfully"), "permission_id": newPerm.ID})
 }
 
-// DeletePermissionHandler handles deleting a permission.
+// DeletePermissionHandler handles deleting a permission. The permission is
+// soft-deleted when the store supports it; see RestorePermissionHandler and
+// PurgePermissionHandler.
 // DELETE /permissions/delete?id=permID
 func (s *Server) DeletePermissionHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodDelete {
-		writeErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed", nil)
+		s.writeError(w, r, http.StatusMethodNotAllowed, "Method not allowed", nil)
 		return
 	}
 
 	permID := r.URL.Query().Get("id")
 	if permID == "" {
-		writeErrorResponse(w, http.StatusBadRequest, "Missing permission ID query parameter", nil)
+		s.writeError(w, r, http.StatusBadRequest, "Missing permission ID query parameter", nil)
 		return
 	}
 
-	if err := s.RBACManager.DeletePermission(r.Context(), permID); err != nil {
-		writeErrorResponse(w, http.StatusInternalServerError, "Failed to delete permission", err)
+	if err := s.manager(r).DeletePermission(r.Context(), permID); err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, "Failed to delete permission", err)
 		return
 	}
 
-	writeJSONResponse(w, http.StatusOK, map[string]string{"message": "Permission deleted successfully"})
+	writeJSONResponse(w, http.StatusOK, map[string]string{"message": s.Message(r, "Permission deleted successfully")})
+}
+
+// RestorePermissionHandler handles restoring a soft-deleted permission.
+// POST /permissions/restore?id=permID
+func (s *Server) RestorePermissionHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, r, http.StatusMethodNotAllowed, "Method not allowed", nil)
+		return
+	}
+
+	permID := r.URL.Query().Get("id")
+	if permID == "" {
+		s.writeError(w, r, http.StatusBadRequest, "Missing permission ID query parameter", nil)
+		return
+	}
+
+	if err := s.manager(r).RestorePermission(r.Context(), permID); err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, "Failed to restore permission", err)
+		return
+	}
+
+	writeJSONResponse(w, http.StatusOK, map[string]string{"message": s.Message(r, "Permission restored successfully")})
+}
+
+// PurgePermissionHandler handles deleting a permission for good, soft-deleted or not.
+// DELETE /permissions/purge?id=permID
+func (s *Server) PurgePermissionHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		s.writeError(w, r, http.StatusMethodNotAllowed, "Method not allowed", nil)
+		return
+	}
+
+	permID := r.URL.Query().Get("id")
+	if permID == "" {
+		s.writeError(w, r, http.StatusBadRequest, "Missing permission ID query parameter", nil)
+		return
+	}
+
+	if err := s.manager(r).PurgePermission(r.Context(), permID); err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, "Failed to purge permission", err)
+		return
+	}
+
+	writeJSONResponse(w, http.StatusOK, map[string]string{"message": s.Message(r, "Permission purged successfully")})
 }
 
 // GetPermissionHandler handles retrieving a permission by ID.
 // GET /permissions/get?id=permID
 func (s *Server) GetPermissionHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		writeErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed", nil)
+		s.writeError(w, r, http.StatusMethodNotAllowed, "Method not allowed", nil)
 		return
 	}
 
 	permID := r.URL.Query().Get("id")
 	if permID == "" {
-		writeErrorResponse(w, http.StatusBadRequest, "Missing permission ID query parameter", nil)
+		s.writeError(w, r, http.StatusBadRequest, "Missing permission ID query parameter", nil)
 		return
 	}
 
-	perm, err := s.RBACManager.GetPermission(r.Context(), permID)
+	perm, err := s.manager(r).GetPermission(r.Context(), permID)
 	if err != nil {
-		writeErrorResponse(w, http.StatusInternalServerError, "Failed to get permission", err)
+		s.writeError(w, r, http.StatusInternalServerError, "Failed to get permission", err)
 		return
 	}
 	if perm == nil {
-		writeErrorResponse(w, http.StatusNotFound, "Permission not found", nil)
+		s.writeError(w, r, http.StatusNotFound, "Permission not found", nil)
+		return
+	}
+
+	writeJSONResponse(w, http.StatusOK, perm)
+}
+
+// ListPermissionsHandler handles listing every permission, or a page of
+// them when a cursor or limit is given.
+// GET /permissions/get-all?limit=100&cursor=...
+func (s *Server) ListPermissionsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, r, http.StatusMethodNotAllowed, "Method not allowed", nil)
+		return
+	}
+	if writePage(s, w, r, s.manager(r).ListPermissionsPage) {
+		return
+	}
+
+	perms, err := s.manager(r).Perms.ListAllPermissions(r.Context())
+	if err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, "Failed to list permissions", err)
+		return
+	}
+
+	writeJSONResponse(w, http.StatusOK, perms)
+}
+
+// GetPermissionByResourceHandler handles retrieving a permission by resource and action.
+// GET /permissions/get-by-resource?resource=/api/data&action=read
+func (s *Server) GetPermissionByResourceHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, r, http.StatusMethodNotAllowed, "Method not allowed", nil)
+		return
+	}
+
+	resource := r.URL.Query().Get("resource")
+	action := r.URL.Query().Get("action")
+	if resource == "" || action == "" {
+		s.writeError(w, r, http.StatusBadRequest, "Missing resource or action query parameter", nil)
+		return
+	}
+
+	perm, err := s.manager(r).Perms.GetPermissionByResource(r.Context(), resource, rbac.Action(action))
+	if err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, "Failed to get permission", err)
+		return
+	}
+	if perm == nil {
+		s.writeError(w, r, http.StatusNotFound, "Permission not found", nil)
+		return
+	}
+
+	writeJSONResponse(w, http.StatusOK, perm)
+}
+
+// GetPermissionByNameHandler handles retrieving a permission by name.
+// GET /permissions/get-by-name?name=data.read
+func (s *Server) GetPermissionByNameHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, r, http.StatusMethodNotAllowed, "Method not allowed", nil)
+		return
+	}
+
+	name := r.URL.Query().Get("name")
+	if name == "" {
+		s.writeError(w, r, http.StatusBadRequest, "Missing permission name query parameter", nil)
+		return
+	}
+
+	perm, err := s.manager(r).GetPermissionByName(r.Context(), name)
+	if err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, "Failed to get permission", err)
+		return
+	}
+	if perm == nil {
+		s.writeError(w, r, http.StatusNotFound, "Permission not found", nil)
 		return
 	}
 
@@ -83,7 +207,7 @@ func (s *Server) GetPermissionHandler(w http.ResponseWriter, r *http.Request) {
 // Request Body: {"role_id": "roleA", "perm_id": "permission1"}
 func (s *Server) AssignPermissionToRoleHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		writeErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed", nil)
+		s.writeError(w, r, http.StatusMethodNotAllowed, "Method not allowed", nil)
 		return
 	}
 
@@ -92,16 +216,42 @@ func (s *Server) AssignPermissionToRoleHandler(w http.ResponseWriter, r *http.Re
 		PermID string `json:"perm_id"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeErrorResponse(w, http.StatusBadRequest, "Invalid request body", err)
+		s.writeError(w, r, http.StatusBadRequest, "Invalid request body", err)
 		return
 	}
 
-	if err := s.RBACManager.AssignPermissionToRole(r.Context(), req.RoleID, req.PermID); err != nil {
-		writeErrorResponse(w, http.StatusInternalServerError, "Failed to assign permission to role", err)
+	if err := s.manager(r).AssignPermissionToRole(r.Context(), req.RoleID, req.PermID); err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, "Failed to assign permission to role", err)
 		return
 	}
 
-	writeJSONResponse(w, http.StatusOK, map[string]string{"message": "Permission assigned to role successfully"})
+	writeJSONResponse(w, http.StatusOK, map[string]string{"message": s.Message(r, "Permission assigned to role successfully")})
+}
+
+// AssignPermissionsToRoleHandler binds many permissions to a role at once.
+// POST /permissions/assign-many-to-role
+// Request Body: {"role_id": "roleA", "perm_ids": ["permission1", "permission2"]}
+func (s *Server) AssignPermissionsToRoleHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, r, http.StatusMethodNotAllowed, "Method not allowed", nil)
+		return
+	}
+
+	var req struct {
+		RoleID  string   `json:"role_id"`
+		PermIDs []string `json:"perm_ids"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.writeError(w, r, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+
+	if err := s.manager(r).AssignPermissionsToRole(r.Context(), req.RoleID, req.PermIDs); err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, "Failed to assign permissions to role", err)
+		return
+	}
+
+	writeJSONResponse(w, http.StatusOK, map[string]string{"message": s.Message(r, "Permissions assigned to role successfully")})
 }
 
 // RemovePermissionFromRoleHandler handles removing a permission from a role.
@@ -109,7 +259,7 @@ func (s *Server) AssignPermissionToRoleHandler(w http.ResponseWriter, r *http.Re
 // Request Body: {"role_id": "roleA", "perm_id": "permission1"}
 func (s *Server) RemovePermissionFromRoleHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		writeErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed", nil)
+		s.writeError(w, r, http.StatusMethodNotAllowed, "Method not allowed", nil)
 		return
 	}
 
@@ -118,37 +268,78 @@ func (s *Server) RemovePermissionFromRoleHandler(w http.ResponseWriter, r *http.
 		PermID string `json:"perm_id"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeErrorResponse(w, http.StatusBadRequest, "Invalid request body", err)
+		s.writeError(w, r, http.StatusBadRequest, "Invalid request body", err)
 		return
 	}
 
-	if err := s.RBACManager.RemovePermissionFromRole(r.Context(), req.RoleID, req.PermID); err != nil {
-		writeErrorResponse(w, http.StatusInternalServerError, "Failed to remove permission from role", err)
+	if err := s.manager(r).RemovePermissionFromRole(r.Context(), req.RoleID, req.PermID); err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, "Failed to remove permission from role", err)
 		return
 	}
 
-	writeJSONResponse(w, http.StatusOK, map[string]string{"message": "Permission removed from role successfully"})
+	writeJSONResponse(w, http.StatusOK, map[string]string{"message": s.Message(r, "Permission removed from role successfully")})
 }
 
-// ListPermissionsForRoleHandler handles listing permissions for a role.
-// GET /permissions/list-for-role?role_id=roleA
+// ListPermissionsForRoleHandler handles listing permissions for a role, a
+// page at a time when a cursor or limit is given.
+// GET /permissions/list-for-role?role_id=roleA&limit=100&cursor=...
 func (s *Server) ListPermissionsForRoleHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		writeErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed", nil)
+		s.writeError(w, r, http.StatusMethodNotAllowed, "Method not allowed", nil)
 		return
 	}
 
 	roleID := r.URL.Query().Get("role_id")
 	if roleID == "" {
-		writeErrorResponse(w, http.StatusBadRequest, "Missing role_id query parameter", nil)
+		s.writeError(w, r, http.StatusBadRequest, "Missing role_id query parameter", nil)
+		return
+	}
+
+	page := func(ctx context.Context, p rbac.PageRequest) (rbac.PageResult[string], error) {
+		return s.manager(r).ListPermissionsForRolePage(ctx, roleID, p)
+	}
+	if writePage(s, w, r, page) {
 		return
 	}
 
-	permissions, err := s.RBACManager.ListPermissionsForRole(r.Context(), roleID)
+	permissions, err := s.manager(r).ListPermissionsForRole(r.Context(), roleID)
 	if err != nil {
-		writeErrorResponse(w, http.StatusInternalServerError, "Failed to list permissions for role", err)
+		s.writeError(w, r, http.StatusInternalServerError, "Failed to list permissions for role", err)
 		return
 	}
 
 	writeJSONResponse(w, http.StatusOK, permissions)
 }
+
+// PermissionUsageHandler returns per-permission allow hits bucketed over time,
+// for rendering a usage heatmap. Rows with a zero total are bound to a role
+// but granted nothing in the window.
+// GET /permissions/usage?window=24h
+func (s *Server) PermissionUsageHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, r, http.StatusMethodNotAllowed, "Method not allowed", nil)
+		return
+	}
+	if s.manager(r).Usage == nil {
+		s.writeError(w, r, http.StatusNotImplemented, "Permission usage tracking is not enabled", nil)
+		return
+	}
+
+	var since time.Time
+	if v := r.URL.Query().Get("window"); v != "" {
+		window, err := time.ParseDuration(v)
+		if err != nil || window <= 0 {
+			s.writeError(w, r, http.StatusBadRequest, "Invalid window query parameter", err)
+			return
+		}
+		since = time.Now().Add(-window)
+	}
+
+	heatmap, err := s.manager(r).PermissionUsage(r.Context(), since)
+	if err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, "Failed to get permission usage", err)
+		return
+	}
+
+	writeJSONResponse(w, http.StatusOK, heatmap)
+}
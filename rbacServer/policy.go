@@ -0,0 +1,290 @@
+package rbacServer
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/Seann-Moser/rbac"
+)
+
+// ExportPolicyHandler returns the policy bundle (see rbac.PolicyBundle) as
+// JSON or YAML. Principals of a tenant may not export.
+// GET /policy/export?format=yaml
+func (s *Server) ExportPolicyHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, r, http.StatusMethodNotAllowed, "Method not allowed", nil)
+		return
+	}
+	if p := PrincipalFromContext(r.Context()); p != nil && p.TenantID != "" {
+		s.writeError(w, r, http.StatusForbidden, "Export is not available to tenant principals", nil)
+		return
+	}
+	format, ok := bundleFormat(r)
+	if !ok {
+		s.writeError(w, r, http.StatusBadRequest, "Invalid format query parameter", nil)
+		return
+	}
+
+	b, err := s.RBACManager.ExportPolicy(r.Context())
+	if err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, "Failed to export policy", err)
+		return
+	}
+	var buf bytes.Buffer
+	if err := rbac.WritePolicyBundle(&buf, b, format); err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, "Failed to export policy", err)
+		return
+	}
+	if format == rbac.FileFormatYAML {
+		w.Header().Set("Content-Type", "application/yaml")
+	} else {
+		w.Header().Set("Content-Type", "application/json")
+	}
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(buf.Bytes())
+}
+
+// ImportPolicyHandler applies the policy bundle in the request body, merging
+// it into the store or, with mode=replace, replacing the store's policy.
+// Principals of a tenant may not import.
+// POST /policy/import?format=yaml&mode=replace
+func (s *Server) ImportPolicyHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, r, http.StatusMethodNotAllowed, "Method not allowed", nil)
+		return
+	}
+	if p := PrincipalFromContext(r.Context()); p != nil && p.TenantID != "" {
+		s.writeError(w, r, http.StatusForbidden, "Import is not available to tenant principals", nil)
+		return
+	}
+	format, ok := bundleFormat(r)
+	if !ok {
+		s.writeError(w, r, http.StatusBadRequest, "Invalid format query parameter", nil)
+		return
+	}
+	opts := rbac.ImportOptions{Mode: rbac.ImportMode(r.URL.Query().Get("mode"))}
+	switch opts.Mode {
+	case "", rbac.ImportMerge, rbac.ImportReplace:
+	default:
+		s.writeError(w, r, http.StatusBadRequest, "Invalid mode query parameter", nil)
+		return
+	}
+
+	b, err := rbac.ReadPolicyBundle(r.Body, format)
+	if err != nil {
+		s.writeError(w, r, http.StatusBadRequest, "Invalid policy bundle", err)
+		return
+	}
+	if err := s.RBACManager.ImportPolicy(r.Context(), b, opts); err != nil {
+		if errors.Is(err, rbac.ErrInvalidBundle) {
+			s.writeError(w, r, http.StatusBadRequest, "Invalid policy bundle", err)
+			return
+		}
+		s.writeError(w, r, http.StatusInternalServerError, "Failed to import policy", err)
+		return
+	}
+
+	writeJSONResponse(w, http.StatusOK, map[string]string{"message": s.Message(r, "Policy imported successfully")})
+}
+
+// bundleFormat returns the format query parameter, JSON by default.
+func bundleFormat(r *http.Request) (rbac.FileFormat, bool) {
+	switch f := rbac.FileFormat(r.URL.Query().Get("format")); f {
+	case "":
+		return rbac.FileFormatJSON, true
+	case rbac.FileFormatJSON, rbac.FileFormatYAML:
+		return f, true
+	}
+	return "", false
+}
+
+// ApplyPolicyHandler reconciles the store with the policy bundle in the
+// request body and returns what changed (see rbac.ApplyResult). Principals
+// of a tenant may not apply.
+// POST /policy/apply?format=yaml
+func (s *Server) ApplyPolicyHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, r, http.StatusMethodNotAllowed, "Method not allowed", nil)
+		return
+	}
+	if p := PrincipalFromContext(r.Context()); p != nil && p.TenantID != "" {
+		s.writeError(w, r, http.StatusForbidden, "Apply is not available to tenant principals", nil)
+		return
+	}
+	format, ok := bundleFormat(r)
+	if !ok {
+		s.writeError(w, r, http.StatusBadRequest, "Invalid format query parameter", nil)
+		return
+	}
+
+	b, err := rbac.ReadPolicyBundle(r.Body, format)
+	if err != nil {
+		s.writeError(w, r, http.StatusBadRequest, "Invalid policy bundle", err)
+		return
+	}
+	res, err := s.RBACManager.Apply(r.Context(), b)
+	if err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, "Failed to apply policy", err)
+		return
+	}
+	if res.Changes == nil {
+		res.Changes = []rbac.ChangeEvent{}
+	}
+
+	writeJSONResponse(w, http.StatusOK, res)
+}
+
+// CreatePolicySnapshotHandler saves the current policy as a snapshot (see
+// rbac.PolicySnapshot). Principals of a tenant may not use snapshots.
+// POST /policy/snapshots/create
+// Request Body: {"comment": "before the quarterly cleanup"}
+func (s *Server) CreatePolicySnapshotHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, r, http.StatusMethodNotAllowed, "Method not allowed", nil)
+		return
+	}
+	if !s.allowSnapshots(w, r) {
+		return
+	}
+
+	var req struct {
+		Comment string `json:"comment"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		s.writeError(w, r, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+	snap, err := s.RBACManager.SnapshotPolicy(r.Context(), req.Comment)
+	if err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, "Failed to snapshot policy", err)
+		return
+	}
+
+	writeJSONResponse(w, http.StatusCreated, map[string]string{"message": s.Message(r, "Policy snapshot created successfully"), "snapshot_id": snap.ID})
+}
+
+// ListPolicySnapshotsHandler lists every policy snapshot, oldest first.
+// GET /policy/snapshots/list
+func (s *Server) ListPolicySnapshotsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, r, http.StatusMethodNotAllowed, "Method not allowed", nil)
+		return
+	}
+	if !s.allowSnapshots(w, r) {
+		return
+	}
+
+	snaps, err := s.RBACManager.ListPolicySnapshots(r.Context())
+	if err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, "Failed to list policy snapshots", err)
+		return
+	}
+	if snaps == nil {
+		snaps = []*rbac.PolicySnapshot{}
+	}
+
+	writeJSONResponse(w, http.StatusOK, snaps)
+}
+
+// GetPolicySnapshotHandler returns a policy snapshot with its policy.
+// GET /policy/snapshots/get?id=snapshotID
+func (s *Server) GetPolicySnapshotHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, r, http.StatusMethodNotAllowed, "Method not allowed", nil)
+		return
+	}
+	if !s.allowSnapshots(w, r) {
+		return
+	}
+
+	id := r.URL.Query().Get("id")
+	if id == "" {
+		s.writeError(w, r, http.StatusBadRequest, "Missing snapshot ID query parameter", nil)
+		return
+	}
+	snap, err := s.RBACManager.GetPolicySnapshot(r.Context(), id)
+	if err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, "Failed to get policy snapshot", err)
+		return
+	}
+	if snap == nil {
+		s.writeError(w, r, http.StatusNotFound, "Policy snapshot not found", nil)
+		return
+	}
+
+	writeJSONResponse(w, http.StatusOK, snap)
+}
+
+// DiffPolicySnapshotsHandler returns the changes that turn the policy of one
+// snapshot into that of another (see rbac.DiffPolicy).
+// GET /policy/snapshots/diff?from=snapshotID&to=snapshotID
+func (s *Server) DiffPolicySnapshotsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, r, http.StatusMethodNotAllowed, "Method not allowed", nil)
+		return
+	}
+	if !s.allowSnapshots(w, r) {
+		return
+	}
+
+	from, to := r.URL.Query().Get("from"), r.URL.Query().Get("to")
+	if from == "" || to == "" {
+		s.writeError(w, r, http.StatusBadRequest, "Missing from or to query parameter", nil)
+		return
+	}
+	changes, err := s.RBACManager.DiffPolicySnapshots(r.Context(), from, to)
+	if err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, "Failed to diff policy snapshots", err)
+		return
+	}
+	if changes == nil {
+		changes = []rbac.ChangeEvent{}
+	}
+
+	writeJSONResponse(w, http.StatusOK, changes)
+}
+
+// RollbackPolicyHandler returns the policy to that of a snapshot and
+// returns what changed (see rbac.ApplyResult).
+// POST /policy/snapshots/rollback
+// Request Body: {"id": "snapshotID"}
+func (s *Server) RollbackPolicyHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, r, http.StatusMethodNotAllowed, "Method not allowed", nil)
+		return
+	}
+	if !s.allowSnapshots(w, r) {
+		return
+	}
+
+	var req struct {
+		ID string `json:"id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.ID == "" {
+		s.writeError(w, r, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+	res, err := s.RBACManager.RollbackPolicy(r.Context(), req.ID)
+	if err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, "Failed to roll back policy", err)
+		return
+	}
+	if res.Changes == nil {
+		res.Changes = []rbac.ChangeEvent{}
+	}
+
+	writeJSONResponse(w, http.StatusOK, res)
+}
+
+// allowSnapshots rejects principals of a tenant, whose Managers are scoped
+// to part of the policy, and reports whether the request may go on.
+func (s *Server) allowSnapshots(w http.ResponseWriter, r *http.Request) bool {
+	if p := PrincipalFromContext(r.Context()); p != nil && p.TenantID != "" {
+		s.writeError(w, r, http.StatusForbidden, "Policy snapshots are not available to tenant principals", nil)
+		return false
+	}
+	return true
+}
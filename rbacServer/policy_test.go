@@ -0,0 +1,155 @@
+package rbacServer
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Seann-Moser/rbac"
+)
+
+func TestPolicyHandlers(t *testing.T) {
+	ctx := context.Background()
+	staging := rbac.NewMockRepoManager(rbac.NewMockRepo())
+	if err := staging.CreatePermission(ctx, &rbac.Permission{ID: "docs-read", Resource: "docs/*", Action: rbac.ActionRead}); err != nil {
+		t.Fatalf("CreatePermission: %v", err)
+	}
+	if err := staging.CreateRole(ctx, &rbac.Role{ID: "viewer", Name: "viewer"}); err != nil {
+		t.Fatalf("CreateRole: %v", err)
+	}
+	if err := staging.AssignPermissionToRole(ctx, "viewer", "docs-read"); err != nil {
+		t.Fatalf("AssignPermissionToRole: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	NewServer(staging).ExportPolicyHandler(rec, httptest.NewRequest(http.MethodGet, "/policy/export?format=yaml", nil))
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "application/yaml" {
+		t.Fatalf("export: unexpected response %d %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	bundle := rec.Body.String()
+
+	prod := rbac.NewMockRepoManager(rbac.NewMockRepo())
+	srv := NewServer(prod)
+	importPolicy := func(query, body string) int {
+		rec := httptest.NewRecorder()
+		srv.ImportPolicyHandler(rec, httptest.NewRequest(http.MethodPost, "/policy/import"+query, strings.NewReader(body)))
+		return rec.Code
+	}
+	if code := importPolicy("?format=yaml&mode=replace", bundle); code != http.StatusOK {
+		t.Fatalf("import: expected 200, got %d", code)
+	}
+	if perms, err := prod.ListPermissionsForRole(ctx, "viewer"); err != nil || len(perms) != 1 || perms[0] != "docs-read" {
+		t.Errorf("viewer has %v, %v after import; want docs-read", perms, err)
+	}
+
+	for query, body := range map[string]string{
+		"?format=yaml&mode=upsert": bundle,
+		"?format=toml":             bundle,
+		"?format=yaml":             "version: 2\n",
+		"":                         `{"version": 1, "roles": [{"id": "viewer", "permissions": ["missing"]}]}`,
+	} {
+		if code := importPolicy(query, body); code != http.StatusBadRequest {
+			t.Errorf("import%s: expected 400, got %d", query, code)
+		}
+	}
+}
+
+func TestApplyPolicyHandler(t *testing.T) {
+	ctx := context.Background()
+	mgr := rbac.NewMockRepoManager(rbac.NewMockRepo())
+	srv := NewServer(mgr)
+	apply := func(body string) (*httptest.ResponseRecorder, rbac.ApplyResult) {
+		rec := httptest.NewRecorder()
+		srv.ApplyPolicyHandler(rec, httptest.NewRequest(http.MethodPost, "/policy/apply?format=yaml", strings.NewReader(body)))
+		var res rbac.ApplyResult
+		_ = json.NewDecoder(rec.Body).Decode(&res)
+		return rec, res
+	}
+
+	doc := "version: 1\nroles:\n  - id: viewer\n    name: viewer\n"
+	rec, res := apply(doc)
+	if rec.Code != http.StatusOK || len(res.Changes) != 1 || res.Changes[0].Kind != rbac.KindRole {
+		t.Fatalf("apply: unexpected response %d %+v", rec.Code, res)
+	}
+	if r, err := mgr.GetRole(ctx, "viewer"); err != nil || r == nil {
+		t.Errorf("viewer = %+v, %v after apply", r, err)
+	}
+	if rec, res := apply(doc); rec.Code != http.StatusOK || len(res.Changes) != 0 {
+		t.Errorf("second apply: unexpected response %d %+v", rec.Code, res)
+	}
+	if rec, _ := apply("version: 1\nroles: [{id: viewer, parents: [missing]}]\n"); rec.Code != http.StatusBadRequest {
+		t.Errorf("invalid bundle: expected 400, got %d", rec.Code)
+	}
+}
+
+func TestPolicySnapshotHandlers(t *testing.T) {
+	ctx := context.Background()
+	mgr := rbac.NewMockRepoManager(rbac.NewMockRepo())
+	if err := mgr.CreateRole(ctx, &rbac.Role{ID: "viewer", Name: "viewer"}); err != nil {
+		t.Fatalf("CreateRole: %v", err)
+	}
+	srv := NewServer(mgr)
+	call := func(h http.HandlerFunc, method, target, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h(rec, httptest.NewRequest(method, target, strings.NewReader(body)))
+		return rec
+	}
+
+	rec := call(srv.CreatePolicySnapshotHandler, http.MethodPost, "/policy/snapshots/create", `{"comment": "known good"}`)
+	var created struct {
+		SnapshotID string `json:"snapshot_id"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&created); rec.Code != http.StatusCreated || err != nil || created.SnapshotID == "" {
+		t.Fatalf("create: unexpected response %d, %v", rec.Code, err)
+	}
+	if err := mgr.DeleteRole(ctx, "viewer"); err != nil {
+		t.Fatalf("DeleteRole: %v", err)
+	}
+	rec = call(srv.CreatePolicySnapshotHandler, http.MethodPost, "/policy/snapshots/create", "")
+	var after struct {
+		SnapshotID string `json:"snapshot_id"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&after); rec.Code != http.StatusCreated || err != nil {
+		t.Fatalf("create without a body: unexpected response %d, %v", rec.Code, err)
+	}
+
+	var snaps []*rbac.PolicySnapshot
+	rec = call(srv.ListPolicySnapshotsHandler, http.MethodGet, "/policy/snapshots/list", "")
+	if err := json.NewDecoder(rec.Body).Decode(&snaps); err != nil || len(snaps) != 2 || snaps[0].Comment != "known good" {
+		t.Fatalf("list = %+v, %v", snaps, err)
+	}
+	var diff []rbac.ChangeEvent
+	rec = call(srv.DiffPolicySnapshotsHandler, http.MethodGet, "/policy/snapshots/diff?from="+created.SnapshotID+"&to="+after.SnapshotID, "")
+	if err := json.NewDecoder(rec.Body).Decode(&diff); err != nil || len(diff) != 1 || diff[0].Op != rbac.ChangeDelete || diff[0].ID != "viewer" {
+		t.Errorf("diff = %+v, %v; want viewer deleted", diff, err)
+	}
+
+	rec = call(srv.RollbackPolicyHandler, http.MethodPost, "/policy/snapshots/rollback", `{"id": "`+created.SnapshotID+`"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("rollback: expected 200, got %d", rec.Code)
+	}
+	if r, err := mgr.GetRole(ctx, "viewer"); err != nil || r == nil {
+		t.Errorf("viewer = %+v, %v after rollback", r, err)
+	}
+
+	for target, code := range map[string]int{
+		"/policy/snapshots/get?id=missing":                           http.StatusNotFound,
+		"/policy/snapshots/get":                                      http.StatusBadRequest,
+		"/policy/snapshots/diff?from=" + after.SnapshotID:            http.StatusBadRequest,
+		"/policy/snapshots/diff?from=missing&to=" + after.SnapshotID: http.StatusNotFound,
+	} {
+		h := srv.GetPolicySnapshotHandler
+		if strings.Contains(target, "diff") {
+			h = srv.DiffPolicySnapshotsHandler
+		}
+		if rec := call(h, http.MethodGet, target, ""); rec.Code != code {
+			t.Errorf("GET %s: expected %d, got %d", target, code, rec.Code)
+		}
+	}
+	if rec := call(srv.RollbackPolicyHandler, http.MethodPost, "/policy/snapshots/rollback", `{"id": "missing"}`); rec.Code != http.StatusNotFound {
+		t.Errorf("rollback to a missing snapshot: expected 404, got %d", rec.Code)
+	}
+}
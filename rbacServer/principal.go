@@ -0,0 +1,204 @@
+package rbacServer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Seann-Moser/rbac"
+)
+
+// Credential schemes extracted by the authentication middlewares.
+const (
+	SchemeJWT        = "jwt"
+	SchemeAPIKey     = "api_key"
+	SchemeClientCert = "client_cert"
+)
+
+// Credential is what an authentication middleware found on a request. Value
+// is the bearer token, the API key, or the verified client certificate's
+// subject, depending on Scheme.
+type Credential struct {
+	Scheme string
+	Value  string
+}
+
+// PrincipalVerifier turns a credential into the acting user. Deployments plug
+// in their own scheme (signature checks, key lookup, ...) here; handlers only
+// ever see the resolved user. Returning a nil user rejects the credential.
+type PrincipalVerifier interface {
+	VerifyPrincipal(ctx context.Context, cred Credential) (*rbac.User, error)
+}
+
+// PrincipalVerifierFunc adapts a function to PrincipalVerifier.
+type PrincipalVerifierFunc func(ctx context.Context, cred Credential) (*rbac.User, error)
+
+func (f PrincipalVerifierFunc) VerifyPrincipal(ctx context.Context, cred Credential) (*rbac.User, error) {
+	return f(ctx, cred)
+}
+
+// LookupUser resolves the credential value as the given user field ("id",
+// "username" or "email"). It does no verification of its own, so use it only
+// for credentials that are already authenticated, such as the subject of a
+// client certificate the TLS stack has verified.
+func LookupUser(m *rbac.Manager, field string) PrincipalVerifier {
+	return PrincipalVerifierFunc(func(ctx context.Context, cred Credential) (*rbac.User, error) {
+		if field == "id" {
+			return m.GetUser(ctx, cred.Value)
+		}
+		return m.Users.GetUserByMeta(ctx, map[string]interface{}{field: cred.Value})
+	})
+}
+
+// CachedVerifier remembers the users a PrincipalVerifier resolved for ttl, so
+// repeated requests with the same credential skip the verification. Rejected
+// credentials are not cached.
+type CachedVerifier struct {
+	verifier PrincipalVerifier
+	ttl      time.Duration
+	now      func() time.Time
+
+	mu      sync.Mutex
+	entries map[Credential]cachedPrincipal
+}
+
+type cachedPrincipal struct {
+	user    *rbac.User
+	expires time.Time
+}
+
+// maxCachedPrincipals bounds the cache; expired entries are swept when it is
+// reached, and the cache is cleared if that is not enough.
+const maxCachedPrincipals = 10000
+
+// NewCachedVerifier wraps v with a cache whose entries live for ttl.
+func NewCachedVerifier(v PrincipalVerifier, ttl time.Duration) *CachedVerifier {
+	return &CachedVerifier{
+		verifier: v,
+		ttl:      ttl,
+		now:      time.Now,
+		entries:  map[Credential]cachedPrincipal{},
+	}
+}
+
+func (c *CachedVerifier) VerifyPrincipal(ctx context.Context, cred Credential) (*rbac.User, error) {
+	now := c.now()
+	c.mu.Lock()
+	e, ok := c.entries[cred]
+	c.mu.Unlock()
+	if ok && now.Before(e.expires) {
+		return e.user, nil
+	}
+
+	user, err := c.verifier.VerifyPrincipal(ctx, cred)
+	if err != nil || user == nil {
+		return user, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.entries) >= maxCachedPrincipals {
+		for k, e := range c.entries {
+			if !now.Before(e.expires) {
+				delete(c.entries, k)
+			}
+		}
+		if len(c.entries) >= maxCachedPrincipals {
+			c.entries = map[Credential]cachedPrincipal{}
+		}
+	}
+	c.entries[cred] = cachedPrincipal{user: user, expires: now.Add(c.ttl)}
+	return user, nil
+}
+
+// Forget drops every cached principal, e.g. after a user was disabled.
+func (c *CachedVerifier) Forget() {
+	c.mu.Lock()
+	c.entries = map[Credential]cachedPrincipal{}
+	c.mu.Unlock()
+}
+
+type principalKey struct{}
+
+// WithPrincipal returns a context carrying the acting user. The user is also
+// the context's rbac.Actor, so the writes it makes are attributed to them.
+func WithPrincipal(ctx context.Context, u *rbac.User) context.Context {
+	if u != nil {
+		ctx = rbac.WithActor(ctx, u.ID)
+	}
+	return context.WithValue(ctx, principalKey{}, u)
+}
+
+// PrincipalFromContext returns the user set by an authentication middleware,
+// or nil.
+func PrincipalFromContext(ctx context.Context) *rbac.User {
+	u, _ := ctx.Value(principalKey{}).(*rbac.User)
+	return u
+}
+
+// JWTMiddleware authenticates requests carrying "Authorization: Bearer <jwt>".
+func (s *Server) JWTMiddleware(next http.Handler) http.Handler {
+	return s.authenticate(next, func(r *http.Request) (Credential, error) {
+		scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+		if !ok || !strings.EqualFold(scheme, "Bearer") || strings.TrimSpace(token) == "" {
+			return Credential{}, errors.New("missing bearer token")
+		}
+		return Credential{Scheme: SchemeJWT, Value: strings.TrimSpace(token)}, nil
+	})
+}
+
+// APIKeyMiddleware authenticates requests carrying an X-API-Key header.
+func (s *Server) APIKeyMiddleware(next http.Handler) http.Handler {
+	return s.authenticate(next, func(r *http.Request) (Credential, error) {
+		key := r.Header.Get("X-API-Key")
+		if key == "" {
+			return Credential{}, errors.New("missing API key")
+		}
+		return Credential{Scheme: SchemeAPIKey, Value: key}, nil
+	})
+}
+
+// ClientCertMiddleware authenticates requests by the subject common name of
+// the client certificate. The server's tls.Config must require and verify
+// client certificates; unverified chains are rejected here.
+func (s *Server) ClientCertMiddleware(next http.Handler) http.Handler {
+	return s.authenticate(next, func(r *http.Request) (Credential, error) {
+		if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+			return Credential{}, errors.New("missing verified client certificate")
+		}
+		cn := r.TLS.VerifiedChains[0][0].Subject.CommonName
+		if cn == "" {
+			return Credential{}, errors.New("client certificate has no common name")
+		}
+		return Credential{Scheme: SchemeClientCert, Value: cn}, nil
+	})
+}
+
+// authenticate runs the Server's PrincipalVerifier on the extracted
+// credential and passes the resolved user to next through the context.
+func (s *Server) authenticate(next http.Handler, extract func(*http.Request) (Credential, error)) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.Verifier == nil {
+			s.writeError(w, r, http.StatusInternalServerError, "Authentication not configured", errors.New("no PrincipalVerifier set"))
+			return
+		}
+		cred, err := extract(r)
+		if err != nil {
+			s.writeError(w, r, http.StatusUnauthorized, "Unauthorized", err)
+			return
+		}
+		user, err := s.Verifier.VerifyPrincipal(r.Context(), cred)
+		if err == nil && user == nil {
+			err = fmt.Errorf("no user for %s credential", cred.Scheme)
+		}
+		if err != nil {
+			s.writeError(w, r, http.StatusUnauthorized, "Unauthorized", err)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(WithPrincipal(r.Context(), user)))
+	})
+}
@@ -0,0 +1,100 @@
+package rbacServer
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Seann-Moser/rbac"
+)
+
+func TestAuthenticationMiddlewares(t *testing.T) {
+	ctx := context.Background()
+	mgr := rbac.NewMockRepoManager(rbac.NewMockRepo())
+	if err := mgr.CreateUser(ctx, &rbac.User{ID: "alice", Username: "alice"}); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+
+	calls := 0
+	keys := PrincipalVerifierFunc(func(ctx context.Context, cred Credential) (*rbac.User, error) {
+		calls++
+		if cred.Value != "secret" {
+			return nil, nil
+		}
+		return mgr.GetUser(ctx, "alice")
+	})
+	srv := NewServer(mgr)
+	srv.Verifier = NewCachedVerifier(keys, time.Minute)
+
+	whoami := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		u := PrincipalFromContext(r.Context())
+		if u == nil {
+			t.Error("expected a principal in the handler context")
+			return
+		}
+		_, _ = w.Write([]byte(u.ID))
+	})
+
+	serve := func(h http.Handler, r *http.Request) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, r)
+		return rec
+	}
+
+	t.Run("APIKey", func(t *testing.T) {
+		h := srv.APIKeyMiddleware(whoami)
+		for i := 0; i < 2; i++ {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("X-API-Key", "secret")
+			if rec := serve(h, req); rec.Code != http.StatusOK || rec.Body.String() != "alice" {
+				t.Fatalf("expected alice, got %d %q", rec.Code, rec.Body.String())
+			}
+		}
+		if calls != 1 {
+			t.Errorf("expected the verified principal to be cached, verifier ran %d times", calls)
+		}
+
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("X-API-Key", "wrong")
+		if rec := serve(h, req); rec.Code != http.StatusUnauthorized {
+			t.Errorf("expected 401 for an unknown key, got %d", rec.Code)
+		}
+		if rec := serve(h, httptest.NewRequest(http.MethodGet, "/", nil)); rec.Code != http.StatusUnauthorized {
+			t.Errorf("expected 401 without a key, got %d", rec.Code)
+		}
+	})
+
+	t.Run("JWT", func(t *testing.T) {
+		h := srv.JWTMiddleware(whoami)
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Authorization", "Bearer secret")
+		if rec := serve(h, req); rec.Code != http.StatusOK {
+			t.Errorf("expected 200, got %d", rec.Code)
+		}
+		req.Header.Set("Authorization", "Basic secret")
+		if rec := serve(h, req); rec.Code != http.StatusUnauthorized {
+			t.Errorf("expected 401 for a non-bearer scheme, got %d", rec.Code)
+		}
+	})
+
+	t.Run("ClientCert", func(t *testing.T) {
+		certSrv := NewServer(mgr)
+		certSrv.Verifier = LookupUser(mgr, "id")
+		h := certSrv.ClientCertMiddleware(whoami)
+
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if rec := serve(h, req); rec.Code != http.StatusUnauthorized {
+			t.Errorf("expected 401 without TLS, got %d", rec.Code)
+		}
+		cert := &x509.Certificate{Subject: pkix.Name{CommonName: "alice"}}
+		req.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{cert}}}
+		if rec := serve(h, req); rec.Code != http.StatusOK || rec.Body.String() != "alice" {
+			t.Errorf("expected alice, got %d %q", rec.Code, rec.Body.String())
+		}
+	})
+}
@@ -0,0 +1,100 @@
+package rbacServer
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Seann-Moser/rbac"
+)
+
+func TestRemoteStore(t *testing.T) {
+	ctx := context.Background()
+	central, err := rbac.NewMemoryStoreManager(ctx, "", 0)
+	if err != nil {
+		t.Fatalf("NewMemoryStoreManager: %v", err)
+	}
+	mux := http.NewServeMux()
+	NewServer(central).Routes(mux)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-API-Key") != "secret" {
+			writeErrorResponse(w, http.StatusUnauthorized, "Unauthorized", nil)
+			return
+		}
+		mux.ServeHTTP(w, r)
+	}))
+	defer ts.Close()
+
+	if _, err := rbac.NewRemoteStore(ctx, ts.URL, ts.Client(), nil); err == nil {
+		t.Fatal("expected the probe without an API key to fail")
+	}
+	local, err := rbac.NewRemoteStoreManager(ctx, ts.URL, ts.Client(), http.Header{"X-Api-Key": {"secret"}})
+	if err != nil {
+		t.Fatalf("NewRemoteStoreManager: %v", err)
+	}
+	remote := local.Perms.(*rbac.RemoteStore)
+	if err := remote.Health(ctx); err != nil {
+		t.Errorf("Health: %v", err)
+	}
+
+	perm := &rbac.Permission{Resource: "survey.*", Action: rbac.ActionRead}
+	if err := local.CreatePermission(ctx, perm); err != nil {
+		t.Fatalf("CreatePermission: %v", err)
+	}
+	dup := &rbac.Permission{Resource: "survey.*", Action: rbac.ActionRead}
+	if err := local.CreatePermission(ctx, dup); err != nil || dup.ID != perm.ID {
+		t.Fatalf("expected the existing permission back, got %+v (%v)", dup, err)
+	}
+	role := &rbac.Role{Name: "reader"}
+	if err := local.CreateRole(ctx, role); err != nil {
+		t.Fatalf("CreateRole: %v", err)
+	}
+	user := &rbac.User{Username: "alice", Email: "alice@example.com"}
+	if err := local.CreateUser(ctx, user); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	if err := local.AssignPermissionToRole(ctx, role.ID, perm.ID); err != nil {
+		t.Fatalf("AssignPermissionToRole: %v", err)
+	}
+	ug := &rbac.UserGroup{UserID: user.ID, GroupName: "staff"}
+	if err := local.AddUserToGroup(ctx, ug); err != nil || ug.ID == "" {
+		t.Fatalf("AddUserToGroup: %+v %v", ug, err)
+	}
+	if err := local.AssignRoleToGroup(ctx, "staff", role.ID); err != nil {
+		t.Fatalf("AssignRoleToGroup: %v", err)
+	}
+
+	ok, err := local.Can(ctx, user.ID, "survey.1", rbac.ActionRead)
+	if err != nil || !ok {
+		t.Fatalf("expected access through the remote policy, got %v (%v)", ok, err)
+	}
+	if ok, _ := central.Can(ctx, user.ID, "survey.1", rbac.ActionRead); !ok {
+		t.Error("expected the writes to land in the central store")
+	}
+
+	if groups, err := local.ListGroupsForRole(ctx, role.ID); err != nil || len(groups) != 1 || groups[0] != "staff" {
+		t.Errorf("ListGroupsForRole = %v (%v); want [staff]", groups, err)
+	}
+	if users, err := local.ListUsersForRole(ctx, role.ID); err != nil || len(users) != 0 {
+		t.Errorf("ListUsersForRole = %v (%v); want none", users, err)
+	}
+
+	got, err := remote.GetUserByMeta(ctx, map[string]interface{}{"email": "alice@example.com"})
+	if err != nil || got == nil || got.ID != user.ID {
+		t.Errorf("GetUserByMeta: %+v (%v)", got, err)
+	}
+	if r, err := remote.GetRoleByName(ctx, "missing"); err != nil || r != nil {
+		t.Errorf("expected nil for a missing role, got %+v (%v)", r, err)
+	}
+	if err := remote.CreateRole(ctx, &rbac.Role{Name: "reader"}); err == nil {
+		t.Error("expected a duplicate role to be rejected by the server")
+	}
+
+	if err := local.RemoveUserFromGroup(ctx, "staff", ug); err != nil {
+		t.Fatalf("RemoveUserFromGroup: %v", err)
+	}
+	if ok, _ := local.Can(ctx, user.ID, "survey.1", rbac.ActionRead); ok {
+		t.Error("expected access to end with the membership")
+	}
+}
@@ -0,0 +1,46 @@
+package rbacServer
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/Seann-Moser/rbac"
+)
+
+// SearchHandler finds the roles, permissions, users and groups whose name
+// contains q, or starts with it when prefix is true. kinds is a
+// comma-separated list of rbac.SearchKinds to search; all by default.
+// GET /search?q=adm&prefix=true&kinds=role,user[&cursor=...&limit=...]
+func (s *Server) SearchHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, r, http.StatusMethodNotAllowed, "Method not allowed", nil)
+		return
+	}
+	q := r.URL.Query()
+	query := rbac.SearchQuery{Text: q.Get("q")}
+	if query.Text == "" {
+		s.writeError(w, r, http.StatusBadRequest, "Missing q query parameter", nil)
+		return
+	}
+	query.Prefix, _ = strconv.ParseBool(q.Get("prefix"))
+	var kinds []string
+	if v := q.Get("kinds"); v != "" {
+		kinds = strings.Split(v, ",")
+	}
+
+	page := func(ctx context.Context, p rbac.PageRequest) (rbac.PageResult[*rbac.SearchHit], error) {
+		return s.manager(r).Search(ctx, query, kinds, p)
+	}
+	if writePage(s, w, r, page) {
+		return
+	}
+
+	res, err := page(r.Context(), rbac.PageRequest{})
+	if err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, "Failed to search", err)
+		return
+	}
+	writeJSONResponse(w, http.StatusOK, res)
+}
@@ -0,0 +1,49 @@
+package rbacServer
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Seann-Moser/rbac"
+)
+
+func TestSearchHandler(t *testing.T) {
+	ctx := context.Background()
+	mgr, err := rbac.NewMemoryStoreManager(ctx, "", 0)
+	if err != nil {
+		t.Fatalf("NewMemoryStoreManager: %v", err)
+	}
+	if err := mgr.CreateRole(ctx, &rbac.Role{ID: "r-admin", Name: "admin"}); err != nil {
+		t.Fatalf("CreateRole: %v", err)
+	}
+	if err := mgr.CreateGroup(ctx, &rbac.Group{ID: "g-admins", Name: "Admins"}); err != nil {
+		t.Fatalf("CreateGroup: %v", err)
+	}
+	mux := http.NewServeMux()
+	NewServer(mgr).Routes(mux)
+	get := func(target string) (int, rbac.PageResult[*rbac.SearchHit]) {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		var out rbac.PageResult[*rbac.SearchHit]
+		_ = json.NewDecoder(rec.Body).Decode(&out)
+		return rec.Code, out
+	}
+
+	code, out := get("/search?q=admin")
+	if code != http.StatusOK || len(out.Items) != 2 || out.Items[0].ID != "r-admin" || out.Items[1].Kind != rbac.KindGroup {
+		t.Errorf("search: got %d %+v; want the role, then the group", code, out)
+	}
+	code, out = get("/search?q=admin&kinds=group&limit=1")
+	if code != http.StatusOK || len(out.Items) != 1 || out.Items[0].ID != "g-admins" {
+		t.Errorf("groups: got %d %+v; want the group", code, out)
+	}
+	for _, target := range []string{"/search", "/search?q=admin&kinds=tenant", "/search?q=admin&cursor=nope"} {
+		if code, _ := get(target); code != http.StatusBadRequest {
+			t.Errorf("GET %s: expected 400, got %d", target, code)
+		}
+	}
+}
@@ -3,6 +3,7 @@ package rbacServer
 import (
 	_ "embed"
 	"encoding/json"
+	"errors"
 	"github.com/Seann-Moser/rbac"
 	"log"
 	"net/http"
@@ -13,6 +14,21 @@ var rbacManagementHTML string
 
 type Server struct {
 	RBACManager *rbac.Manager
+	// Verifier resolves the acting user for the authentication middlewares.
+	Verifier PrincipalVerifier
+	// ExportRate caps how many records each /export stream writes per
+	// second; zero means no cap.
+	ExportRate float64
+
+	// Messages translates the server's responses and the management UI;
+	// each request is answered in the locale picked by Locale, and
+	// DefaultLocale ("en" when empty) is used when none matches.
+	Messages      MessageCatalog
+	DefaultLocale string
+	// Translate, when set, overrides Messages: it returns msg, one of
+	// MessageKeys, in locale, or "" to fall back to Messages. Use it for
+	// per-deployment wording, e.g. a white-label admin console.
+	Translate func(r *http.Request, locale, msg string) string
 }
 
 // NewServer creates a new instance of your server with the RBAC manager
@@ -22,6 +38,128 @@ func NewServer(manager *rbac.Manager) *Server {
 	}
 }
 
+// manager returns the Manager a request works on: for a principal that
+// belongs to a tenant it is scoped to that tenant (see rbac.Manager.ForTenant),
+// so tenant users only ever see and change their own tenant's policy.
+func (s *Server) manager(r *http.Request) *rbac.Manager {
+	if p := PrincipalFromContext(r.Context()); p != nil && p.TenantID != "" {
+		return s.RBACManager.ForTenant(p.TenantID)
+	}
+	return s.RBACManager
+}
+
+// Routes registers every handler on mux under its documented path. The
+// paths are the contract rbac.RemoteStore relies on.
+func (s *Server) Routes(mux *http.ServeMux) {
+	mux.HandleFunc("/roles/assign-to-group", s.AssignRoleToGroupHandler)
+	mux.HandleFunc("/roles/unassign-from-group", s.UnassignRoleFromGroupHandler)
+	mux.HandleFunc("/roles/list-for-group", s.ListRolesForGroupHandler)
+	mux.HandleFunc("/roles/list-users", s.ListUsersForRoleHandler)
+	mux.HandleFunc("/roles/list-groups", s.ListGroupsForRoleHandler)
+	mux.HandleFunc("/roles/create", s.CreateRoleHandler)
+	mux.HandleFunc("/roles/clone", s.CloneRoleHandler)
+	mux.HandleFunc("/roles/delete", s.DeleteRoleHandler)
+	mux.HandleFunc("/roles/restore", s.RestoreRoleHandler)
+	mux.HandleFunc("/roles/purge", s.PurgeRoleHandler)
+	mux.HandleFunc("/roles/get", s.GetRoleHandler)
+	mux.HandleFunc("/roles/get-by-name", s.GetRoleByNameHandler)
+	mux.HandleFunc("/roles/get-all", s.ListRoles)
+
+	mux.HandleFunc("/groups/create", s.CreateGroupHandler)
+	mux.HandleFunc("/groups/get", s.GetGroupHandler)
+	mux.HandleFunc("/groups/get-by-name", s.GetGroupByNameHandler)
+	mux.HandleFunc("/groups/list", s.ListGroupsHandler)
+	mux.HandleFunc("/groups/update", s.UpdateGroupHandler)
+	mux.HandleFunc("/groups/rename", s.RenameGroupHandler)
+	mux.HandleFunc("/groups/delete", s.DeleteGroupHandler)
+
+	mux.HandleFunc("/users/create", s.CreateUserHandler)
+	mux.HandleFunc("/users/delete", s.DeleteUserHandler)
+	mux.HandleFunc("/users/suspend", s.SuspendUserHandler)
+	mux.HandleFunc("/users/reactivate", s.ReactivateUserHandler)
+	mux.HandleFunc("/users/get", s.GetUserHandler)
+	mux.HandleFunc("/users/get-all", s.ListUsersHandler)
+	mux.HandleFunc("/users/find", s.FindUserHandler)
+	mux.HandleFunc("/users/get-by-meta", s.GetUserByMetaHandler)
+	mux.HandleFunc("/users/get-by-email", s.GetUserByEmailHandler)
+	mux.HandleFunc("/users/assign-role", s.AssignRoleToUserHandler)
+	mux.HandleFunc("/users/assign-roles", s.AssignRolesToUserHandler)
+	mux.HandleFunc("/users/unassign-role", s.UnassignRoleFromUserHandler)
+	mux.HandleFunc("/users/list-roles", s.ListRolesForUserHandler)
+	mux.HandleFunc("/users/add-to-group", s.AddUserToGroupHandler)
+	mux.HandleFunc("/users/add-many-to-group", s.AddUsersToGroupHandler)
+	mux.HandleFunc("/users/remove-from-group", s.RemoveUserFromGroupHandler)
+	mux.HandleFunc("/users/list-by-group", s.GetUsersByGroupIDHandler)
+	mux.HandleFunc("/users/list-groups", s.GetGroupsByUserIDHandler)
+	mux.HandleFunc("/users/has-permission", s.HasPermissionHandler)
+	mux.HandleFunc("/users/can", s.CanHandler)
+	mux.HandleFunc("/users/can-batch", s.CanBatchHandler)
+	mux.HandleFunc("/users/explain", s.ExplainHandler)
+	mux.HandleFunc("/users/effective-permissions", s.EffectivePermissionsHandler)
+	mux.HandleFunc("/users/who-can", s.WhoCanHandler)
+
+	mux.HandleFunc("/permissions/create", s.CreatePermissionHandler)
+	mux.HandleFunc("/permissions/delete", s.DeletePermissionHandler)
+	mux.HandleFunc("/permissions/restore", s.RestorePermissionHandler)
+	mux.HandleFunc("/permissions/purge", s.PurgePermissionHandler)
+	mux.HandleFunc("/permissions/get", s.GetPermissionHandler)
+	mux.HandleFunc("/permissions/get-by-resource", s.GetPermissionByResourceHandler)
+	mux.HandleFunc("/permissions/get-by-name", s.GetPermissionByNameHandler)
+	mux.HandleFunc("/permissions/get-all", s.ListPermissionsHandler)
+	mux.HandleFunc("/permissions/assign-to-role", s.AssignPermissionToRoleHandler)
+	mux.HandleFunc("/permissions/assign-many-to-role", s.AssignPermissionsToRoleHandler)
+	mux.HandleFunc("/permissions/remove-from-role", s.RemovePermissionFromRoleHandler)
+	mux.HandleFunc("/permissions/list-for-role", s.ListPermissionsForRoleHandler)
+	mux.HandleFunc("/permissions/usage", s.PermissionUsageHandler)
+
+	mux.HandleFunc("/actions/list", s.ListActionsHandler)
+
+	mux.HandleFunc("/assignments/expiring", s.ExpiringAssignmentsHandler)
+
+	mux.HandleFunc("/audit/list", s.ListAuditHandler)
+
+	mux.HandleFunc("/archives/create", s.ArchiveHandler)
+	mux.HandleFunc("/archives/list", s.ListArchivesHandler)
+	mux.HandleFunc("/archives/get", s.GetArchiveHandler)
+	mux.HandleFunc("/archives/restore", s.RestoreArchiveHandler)
+
+	mux.HandleFunc("/apikeys/create", s.CreateAPIKeyHandler)
+	mux.HandleFunc("/apikeys/list", s.ListAPIKeysHandler)
+	mux.HandleFunc("/apikeys/revoke", s.RevokeAPIKeyHandler)
+	mux.HandleFunc("/apikeys/can", s.APIKeyCanHandler)
+
+	mux.HandleFunc("/sod/create", s.CreateSoDConstraintHandler)
+	mux.HandleFunc("/sod/list", s.ListSoDConstraintsHandler)
+	mux.HandleFunc("/sod/delete", s.DeleteSoDConstraintHandler)
+
+	mux.HandleFunc("/approvals/request", s.RequestRoleAssignmentHandler)
+	mux.HandleFunc("/approvals/list", s.ListAssignmentRequestsHandler)
+	mux.HandleFunc("/approvals/approve", s.ApproveRoleAssignmentHandler)
+	mux.HandleFunc("/approvals/reject", s.RejectRoleAssignmentHandler)
+
+	mux.HandleFunc("/notifications/pending", s.PendingNotificationsHandler)
+	mux.HandleFunc("/notifications/acknowledge", s.AcknowledgeNotificationHandler)
+
+	mux.HandleFunc("/export", s.ExportHandler)
+	mux.HandleFunc("/policy/export", s.ExportPolicyHandler)
+	mux.HandleFunc("/policy/import", s.ImportPolicyHandler)
+	mux.HandleFunc("/policy/apply", s.ApplyPolicyHandler)
+	mux.HandleFunc("/policy/casbin/export", s.ExportCasbinHandler)
+	mux.HandleFunc("/policy/casbin/import", s.ImportCasbinHandler)
+	mux.HandleFunc("/policy/snapshots/create", s.CreatePolicySnapshotHandler)
+	mux.HandleFunc("/policy/snapshots/list", s.ListPolicySnapshotsHandler)
+	mux.HandleFunc("/policy/snapshots/get", s.GetPolicySnapshotHandler)
+	mux.HandleFunc("/policy/snapshots/diff", s.DiffPolicySnapshotsHandler)
+	mux.HandleFunc("/policy/snapshots/rollback", s.RollbackPolicyHandler)
+
+	mux.HandleFunc("/integrity/check", s.IntegrityCheckHandler)
+	mux.HandleFunc("/stats", s.StatsHandler)
+	mux.HandleFunc("/search", s.SearchHandler)
+
+	mux.HandleFunc("/healthz", s.HealthHandler)
+	mux.HandleFunc("/manage", s.MangementInterface)
+}
+
 // writeJSONResponse is a helper to send JSON responses
 func writeJSONResponse(w http.ResponseWriter, statusCode int, data interface{}) {
 	w.Header().Set("Content-Type", "application/json")
@@ -34,12 +172,37 @@ func writeJSONResponse(w http.ResponseWriter, statusCode int, data interface{})
 	}
 }
 
-// writeErrorResponse is a helper to send error responses
+// writeErrorResponse is a helper to send error responses. A tenant-scoped
+// request that touched another tenant's entity is reported as forbidden.
 func writeErrorResponse(w http.ResponseWriter, statusCode int, message string, err error) {
+	switch {
+	case errors.Is(err, rbac.ErrTenantMismatch), errors.Is(err, rbac.ErrApprovalDenied):
+		statusCode = http.StatusForbidden
+	case errors.Is(err, rbac.ErrInvalidAPIKey):
+		statusCode = http.StatusUnauthorized
+	case errors.Is(err, rbac.ErrGroupNotFound), errors.Is(err, rbac.ErrRoleNotFound),
+		errors.Is(err, rbac.ErrArchiveNotFound), errors.Is(err, rbac.ErrPermissionNotFound),
+		errors.Is(err, rbac.ErrAPIKeyNotFound), errors.Is(err, rbac.ErrUserNotFound),
+		errors.Is(err, rbac.ErrSoDConstraintNotFound), errors.Is(err, rbac.ErrAssignmentRequestNotFound),
+		errors.Is(err, rbac.ErrPolicySnapshotNotFound):
+		statusCode = http.StatusNotFound
+	case errors.Is(err, rbac.ErrGroupExists), errors.Is(err, rbac.ErrPermissionNameTaken),
+		errors.Is(err, rbac.ErrArchiveRestored), errors.Is(err, rbac.ErrRoleNameTaken),
+		errors.Is(err, rbac.ErrUsernameTaken), errors.Is(err, rbac.ErrEmailTaken),
+		errors.Is(err, rbac.ErrSoDConflict), errors.Is(err, rbac.ErrLimitExceeded),
+		errors.Is(err, rbac.ErrAssignmentRequestDecided), errors.Is(err, rbac.ErrBannedFromGroup):
+		statusCode = http.StatusConflict
+	case errors.Is(err, rbac.ErrTemplateRole), errors.Is(err, rbac.ErrInvalidUserFilter),
+		errors.Is(err, rbac.ErrUnknownResourceType), errors.Is(err, rbac.ErrActionNotAllowed),
+		errors.Is(err, rbac.ErrUnknownAction), errors.Is(err, rbac.ErrInvalidSoDConstraint),
+		errors.Is(err, rbac.ErrInvalidMembershipLevel), errors.Is(err, rbac.ErrInvalidSearch):
+		statusCode = http.StatusBadRequest
+	}
 	log.Printf("Handler error (status %d): %s - %v", statusCode, message, err)
 	writeJSONResponse(w, statusCode, map[string]string{"error": message})
 }
 
 func (s *Server) MangementInterface(w http.ResponseWriter, r *http.Request) {
-	_, _ = w.Write([]byte(rbacManagementHTML))
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	_, _ = w.Write([]byte(s.managementPage(r)))
 }
@@ -0,0 +1,74 @@
+package rbacServer
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/Seann-Moser/rbac"
+)
+
+// CreateSoDConstraintHandler creates a set of mutually exclusive roles.
+// Assigning a user a second role of the set is then answered with 409.
+// POST /sod/create
+// Request Body: {"name": "payments", "roles": ["payments-approver-id", "payments-requester-id"]}
+func (s *Server) CreateSoDConstraintHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, r, http.StatusMethodNotAllowed, "Method not allowed", nil)
+		return
+	}
+
+	var c rbac.SoDConstraint
+	if err := json.NewDecoder(r.Body).Decode(&c); err != nil {
+		s.writeError(w, r, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+
+	if err := s.manager(r).CreateSoDConstraint(r.Context(), &c); err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, "Failed to create separation of duties constraint", err)
+		return
+	}
+
+	writeJSONResponse(w, http.StatusCreated, c)
+}
+
+// ListSoDConstraintsHandler lists the separation-of-duties constraints.
+// GET /sod/list
+func (s *Server) ListSoDConstraintsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, r, http.StatusMethodNotAllowed, "Method not allowed", nil)
+		return
+	}
+
+	list, err := s.manager(r).ListSoDConstraints(r.Context())
+	if err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, "Failed to list separation of duties constraints", err)
+		return
+	}
+
+	writeJSONResponse(w, http.StatusOK, list)
+}
+
+// DeleteSoDConstraintHandler deletes a separation-of-duties constraint.
+// POST /sod/delete
+// Request Body: {"id": "constraintID"}
+func (s *Server) DeleteSoDConstraintHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, r, http.StatusMethodNotAllowed, "Method not allowed", nil)
+		return
+	}
+
+	var req struct {
+		ID string `json:"id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.ID == "" {
+		s.writeError(w, r, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+
+	if err := s.manager(r).DeleteSoDConstraint(r.Context(), req.ID); err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, "Failed to delete separation of duties constraint", err)
+		return
+	}
+
+	writeJSONResponse(w, http.StatusOK, map[string]string{"message": s.Message(r, "Separation of duties constraint deleted successfully")})
+}
@@ -0,0 +1,62 @@
+package rbacServer
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Seann-Moser/rbac"
+)
+
+func TestSoDConstraintHandlers(t *testing.T) {
+	ctx := context.Background()
+	mgr, err := rbac.NewMemoryStoreManager(ctx, "", 0)
+	if err != nil {
+		t.Fatalf("NewMemoryStoreManager: %v", err)
+	}
+	approver, requester := &rbac.Role{Name: "payments-approver"}, &rbac.Role{Name: "payments-requester"}
+	for _, r := range []*rbac.Role{approver, requester} {
+		if err := mgr.CreateRole(ctx, r); err != nil {
+			t.Fatalf("CreateRole: %v", err)
+		}
+	}
+	srv := NewServer(mgr)
+	post := func(h http.HandlerFunc, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h(rec, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body)))
+		return rec
+	}
+
+	if rec := post(srv.CreateSoDConstraintHandler, `{"name": "payments", "roles": ["`+approver.ID+`"]}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("create with one role: expected 400, got %d", rec.Code)
+	}
+	rec := post(srv.CreateSoDConstraintHandler, `{"name": "payments", "roles": ["`+approver.ID+`", "`+requester.ID+`"]}`)
+	var created rbac.SoDConstraint
+	if rec.Code != http.StatusCreated || json.NewDecoder(rec.Body).Decode(&created) != nil || created.ID == "" {
+		t.Fatalf("create: unexpected response %d %+v", rec.Code, created)
+	}
+
+	rec = httptest.NewRecorder()
+	srv.ListSoDConstraintsHandler(rec, httptest.NewRequest(http.MethodGet, "/sod/list", nil))
+	var list []rbac.SoDConstraint
+	if rec.Code != http.StatusOK || json.NewDecoder(rec.Body).Decode(&list) != nil || len(list) != 1 {
+		t.Fatalf("list: unexpected response %d %+v", rec.Code, list)
+	}
+
+	if rec := post(srv.AssignRoleToUserHandler, `{"user_id": "alice", "role_id": "`+approver.ID+`"}`); rec.Code != http.StatusOK {
+		t.Fatalf("assign approver: expected 200, got %d", rec.Code)
+	}
+	if rec := post(srv.AssignRoleToUserHandler, `{"user_id": "alice", "role_id": "`+requester.ID+`"}`); rec.Code != http.StatusConflict {
+		t.Errorf("assign requester: expected 409, got %d", rec.Code)
+	}
+
+	if rec := post(srv.DeleteSoDConstraintHandler, `{"id": "`+created.ID+`"}`); rec.Code != http.StatusOK {
+		t.Errorf("delete: expected 200, got %d", rec.Code)
+	}
+	if rec := post(srv.DeleteSoDConstraintHandler, `{"id": "`+created.ID+`"}`); rec.Code != http.StatusNotFound {
+		t.Errorf("delete again: expected 404, got %d", rec.Code)
+	}
+}
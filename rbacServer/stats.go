@@ -0,0 +1,19 @@
+package rbacServer
+
+import "net/http"
+
+// StatsHandler returns the rbac.PolicyStats of the store; for a principal of
+// a tenant, of that tenant's policy.
+// GET /stats
+func (s *Server) StatsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, r, http.StatusMethodNotAllowed, "Method not allowed", nil)
+		return
+	}
+	stats, err := s.manager(r).Stats(r.Context())
+	if err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, "Failed to get stats", err)
+		return
+	}
+	writeJSONResponse(w, http.StatusOK, stats)
+}
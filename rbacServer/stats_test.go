@@ -0,0 +1,44 @@
+package rbacServer
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Seann-Moser/rbac"
+)
+
+func TestStatsHandler(t *testing.T) {
+	ctx := context.Background()
+	mgr, err := rbac.NewMemoryStoreManager(ctx, "", 0)
+	if err != nil {
+		t.Fatalf("NewMemoryStoreManager: %v", err)
+	}
+	role := &rbac.Role{Name: "editor"}
+	if err := mgr.CreateRole(ctx, role); err != nil {
+		t.Fatalf("CreateRole: %v", err)
+	}
+	if err := mgr.AssignRoleToUser(ctx, "alice", role.ID); err != nil {
+		t.Fatalf("AssignRoleToUser: %v", err)
+	}
+	mux := http.NewServeMux()
+	NewServer(mgr).Routes(mux)
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/stats", nil))
+	var out rbac.PolicyStats
+	if err := json.NewDecoder(rec.Body).Decode(&out); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("GET /stats: got %d, %v", rec.Code, err)
+	}
+	if out.UserRoles != 1 || len(out.LargestRoles) == 0 || out.LargestRoles[0].ID != role.ID {
+		t.Errorf("GET /stats: got %+v; want editor as the largest role", out)
+	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/stats", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("POST /stats: expected 405, got %d", rec.Code)
+	}
+}
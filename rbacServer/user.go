@@ -1,8 +1,14 @@
 package rbacServer
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"net/http"
+	"net/netip"
+	"strings"
+	"time"
 
 	"github.com/Seann-Moser/rbac"
 )
@@ -12,67 +18,220 @@ import (
 // Request Body: {"id": "new_user_id", "name": "New User Name"}
 func (s *Server) CreateUserHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		writeErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed", nil)
+		s.writeError(w, r, http.StatusMethodNotAllowed, "Method not allowed", nil)
 		return
 	}
 
 	var newUser rbac.User
 	if err := json.NewDecoder(r.Body).Decode(&newUser); err != nil {
-		writeErrorResponse(w, http.StatusBadRequest, "Invalid request body", err)
+		s.writeError(w, r, http.StatusBadRequest, "Invalid request body", err)
 		return
 	}
 
-	if err := s.RBACManager.CreateUser(r.Context(), &newUser); err != nil {
-		writeErrorResponse(w, http.StatusInternalServerError, "Failed to create user", err)
+	if err := s.manager(r).CreateUser(r.Context(), &newUser); err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, "Failed to create user", err)
 		return
 	}
 
-	writeJSONResponse(w, http.StatusCreated, map[string]string{"message": "User created successfully", "user_id": newUser.ID})
+	writeJSONResponse(w, http.StatusCreated, map[string]string{"message": s.Message(r, "User created successfully"), "user_id": newUser.ID})
 }
 
 // DeleteUserHandler handles deleting a user.
 // DELETE /users/delete?id=userID
 func (s *Server) DeleteUserHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodDelete {
-		writeErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed", nil)
+		s.writeError(w, r, http.StatusMethodNotAllowed, "Method not allowed", nil)
 		return
 	}
 
 	userID := r.URL.Query().Get("id")
 	if userID == "" {
-		writeErrorResponse(w, http.StatusBadRequest, "Missing user ID query parameter", nil)
+		s.writeError(w, r, http.StatusBadRequest, "Missing user ID query parameter", nil)
 		return
 	}
 
-	if err := s.RBACManager.DeleteUser(r.Context(), userID); err != nil {
-		writeErrorResponse(w, http.StatusInternalServerError, "Failed to delete user", err)
+	if err := s.manager(r).DeleteUser(r.Context(), userID); err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, "Failed to delete user", err)
 		return
 	}
 
-	writeJSONResponse(w, http.StatusOK, map[string]string{"message": "User deleted successfully"})
+	writeJSONResponse(w, http.StatusOK, map[string]string{"message": s.Message(r, "User deleted successfully")})
+}
+
+// SuspendUserHandler handles suspending a user, which denies them every
+// access check while keeping their roles.
+// POST /users/suspend?id=userID
+func (s *Server) SuspendUserHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, r, http.StatusMethodNotAllowed, "Method not allowed", nil)
+		return
+	}
+
+	userID := r.URL.Query().Get("id")
+	if userID == "" {
+		s.writeError(w, r, http.StatusBadRequest, "Missing user ID query parameter", nil)
+		return
+	}
+
+	if err := s.manager(r).SuspendUser(r.Context(), userID); err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, "Failed to suspend user", err)
+		return
+	}
+
+	writeJSONResponse(w, http.StatusOK, map[string]string{"message": s.Message(r, "User suspended successfully")})
+}
+
+// ReactivateUserHandler handles making a suspended or locked user active.
+// POST /users/reactivate?id=userID
+func (s *Server) ReactivateUserHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, r, http.StatusMethodNotAllowed, "Method not allowed", nil)
+		return
+	}
+
+	userID := r.URL.Query().Get("id")
+	if userID == "" {
+		s.writeError(w, r, http.StatusBadRequest, "Missing user ID query parameter", nil)
+		return
+	}
+
+	if err := s.manager(r).ReactivateUser(r.Context(), userID); err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, "Failed to reactivate user", err)
+		return
+	}
+
+	writeJSONResponse(w, http.StatusOK, map[string]string{"message": s.Message(r, "User reactivated successfully")})
 }
 
 // GetUserHandler handles retrieving a user by ID.
 // GET /users/get?id=userID
 func (s *Server) GetUserHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		writeErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed", nil)
+		s.writeError(w, r, http.StatusMethodNotAllowed, "Method not allowed", nil)
 		return
 	}
 
 	userID := r.URL.Query().Get("id")
 	if userID == "" {
-		writeErrorResponse(w, http.StatusBadRequest, "Missing user ID query parameter", nil)
+		s.writeError(w, r, http.StatusBadRequest, "Missing user ID query parameter", nil)
+		return
+	}
+
+	user, err := s.manager(r).GetUser(r.Context(), userID)
+	if err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, "Failed to get user", err)
+		return
+	}
+	if user == nil {
+		s.writeError(w, r, http.StatusNotFound, "User not found", nil)
+		return
+	}
+
+	writeJSONResponse(w, http.StatusOK, user)
+}
+
+// GetUserByEmailHandler looks a user up by email, e.g. for support tooling.
+// GET /users/get-by-email?email=alice@example.com
+func (s *Server) GetUserByEmailHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, r, http.StatusMethodNotAllowed, "Method not allowed", nil)
+		return
+	}
+
+	email := r.URL.Query().Get("email")
+	if email == "" {
+		s.writeError(w, r, http.StatusBadRequest, "Missing email query parameter", nil)
+		return
+	}
+
+	user, err := s.manager(r).GetUserByEmail(r.Context(), email)
+	if err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, "Failed to get user", err)
+		return
+	}
+	if user == nil {
+		s.writeError(w, r, http.StatusNotFound, "User not found", nil)
 		return
 	}
 
-	user, err := s.RBACManager.GetUser(r.Context(), userID)
+	writeJSONResponse(w, http.StatusOK, user)
+}
+
+// ListUsersHandler handles listing every user, or a page of them when a
+// cursor or limit is given.
+// GET /users/get-all?limit=100&cursor=...
+func (s *Server) ListUsersHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, r, http.StatusMethodNotAllowed, "Method not allowed", nil)
+		return
+	}
+	if writePage(s, w, r, s.manager(r).ListUsersPage) {
+		return
+	}
+
+	users, err := s.manager(r).Users.ListAllUsers(r.Context())
 	if err != nil {
-		writeErrorResponse(w, http.StatusInternalServerError, "Failed to get user", err)
+		s.writeError(w, r, http.StatusInternalServerError, "Failed to list users", err)
+		return
+	}
+
+	writeJSONResponse(w, http.StatusOK, users)
+}
+
+// FindUserHandler handles retrieving a user by id, username or email.
+// POST /users/find
+// Request Body: {"username": "alice"}
+func (s *Server) FindUserHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, r, http.StatusMethodNotAllowed, "Method not allowed", nil)
+		return
+	}
+
+	var meta map[string]interface{}
+	if err := json.NewDecoder(r.Body).Decode(&meta); err != nil {
+		s.writeError(w, r, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+
+	user, err := s.manager(r).Users.GetUserByMeta(r.Context(), meta)
+	if err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, "Failed to find user", err)
+		return
+	}
+	if user == nil {
+		s.writeError(w, r, http.StatusNotFound, "User not found", nil)
+		return
+	}
+
+	writeJSONResponse(w, http.StatusOK, user)
+}
+
+// GetUserByMetaHandler handles retrieving a user by fields and meta keys
+// given as query parameters. A parameter given more than once matches any
+// of its values.
+// GET /users/get-by-meta?username=alice&meta.team=core&meta.team=infra
+func (s *Server) GetUserByMetaHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, r, http.StatusMethodNotAllowed, "Method not allowed", nil)
+		return
+	}
+
+	filter := map[string]interface{}{}
+	for k, vs := range r.URL.Query() {
+		if len(vs) == 1 {
+			filter[k] = vs[0]
+		} else {
+			filter[k] = map[string]interface{}{"$in": vs}
+		}
+	}
+
+	user, err := s.manager(r).Users.GetUserByMeta(r.Context(), filter)
+	if err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, "Failed to find user", err)
 		return
 	}
 	if user == nil {
-		writeErrorResponse(w, http.StatusNotFound, "User not found", nil)
+		s.writeError(w, r, http.StatusNotFound, "User not found", nil)
 		return
 	}
 
@@ -84,7 +243,7 @@ func (s *Server) GetUserHandler(w http.ResponseWriter, r *http.Request) {
 // Request Body: {"user_id": "user1", "role_id": "roleA"}
 func (s *Server) AssignRoleToUserHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		writeErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed", nil)
+		s.writeError(w, r, http.StatusMethodNotAllowed, "Method not allowed", nil)
 		return
 	}
 
@@ -93,16 +252,43 @@ func (s *Server) AssignRoleToUserHandler(w http.ResponseWriter, r *http.Request)
 		RoleID string `json:"role_id"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeErrorResponse(w, http.StatusBadRequest, "Invalid request body", err)
+		s.writeError(w, r, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+
+	if err := s.manager(r).AssignRoleToUser(r.Context(), req.UserID, req.RoleID); err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, "Failed to assign role to user", err)
+		return
+	}
+
+	writeJSONResponse(w, http.StatusOK, map[string]string{"message": s.Message(r, "Role assigned to user successfully")})
+}
+
+// AssignRolesToUserHandler assigns many roles to a user at once. Either
+// all are assigned or none is.
+// POST /users/assign-roles
+// Request Body: {"user_id": "user1", "role_ids": ["roleA", "roleB"]}
+func (s *Server) AssignRolesToUserHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, r, http.StatusMethodNotAllowed, "Method not allowed", nil)
+		return
+	}
+
+	var req struct {
+		UserID  string   `json:"user_id"`
+		RoleIDs []string `json:"role_ids"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.writeError(w, r, http.StatusBadRequest, "Invalid request body", err)
 		return
 	}
 
-	if err := s.RBACManager.AssignRoleToUser(r.Context(), req.UserID, req.RoleID); err != nil {
-		writeErrorResponse(w, http.StatusInternalServerError, "Failed to assign role to user", err)
+	if err := s.manager(r).AssignRolesToUser(r.Context(), req.UserID, req.RoleIDs); err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, "Failed to assign roles to user", err)
 		return
 	}
 
-	writeJSONResponse(w, http.StatusOK, map[string]string{"message": "Role assigned to user successfully"})
+	writeJSONResponse(w, http.StatusOK, map[string]string{"message": s.Message(r, "Roles assigned to user successfully")})
 }
 
 // UnassignRoleFromUserHandler handles unassigning a role from a user.
@@ -110,7 +296,7 @@ func (s *Server) AssignRoleToUserHandler(w http.ResponseWriter, r *http.Request)
 // Request Body: {"user_id": "user1", "role_id": "roleA"}
 func (s *Server) UnassignRoleFromUserHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		writeErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed", nil)
+		s.writeError(w, r, http.StatusMethodNotAllowed, "Method not allowed", nil)
 		return
 	}
 
@@ -119,71 +305,120 @@ func (s *Server) UnassignRoleFromUserHandler(w http.ResponseWriter, r *http.Requ
 		RoleID string `json:"role_id"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeErrorResponse(w, http.StatusBadRequest, "Invalid request body", err)
+		s.writeError(w, r, http.StatusBadRequest, "Invalid request body", err)
 		return
 	}
 
-	if err := s.RBACManager.UnassignRoleFromUser(r.Context(), req.UserID, req.RoleID); err != nil {
-		writeErrorResponse(w, http.StatusInternalServerError, "Failed to unassign role from user", err)
+	if err := s.manager(r).UnassignRoleFromUser(r.Context(), req.UserID, req.RoleID); err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, "Failed to unassign role from user", err)
 		return
 	}
 
-	writeJSONResponse(w, http.StatusOK, map[string]string{"message": "Role unassigned from user successfully"})
+	writeJSONResponse(w, http.StatusOK, map[string]string{"message": s.Message(r, "Role unassigned from user successfully")})
 }
 
 // ListRolesForUserHandler handles listing roles for a user.
 // GET /users/list-roles?user_id=user1
 func (s *Server) ListRolesForUserHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		writeErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed", nil)
+		s.writeError(w, r, http.StatusMethodNotAllowed, "Method not allowed", nil)
 		return
 	}
 
 	userID := r.URL.Query().Get("user_id")
 	if userID == "" {
-		writeErrorResponse(w, http.StatusBadRequest, "Missing user_id query parameter", nil)
+		s.writeError(w, r, http.StatusBadRequest, "Missing user_id query parameter", nil)
 		return
 	}
 
-	roles, err := s.RBACManager.ListRolesForUser(r.Context(), userID)
+	roles, err := s.manager(r).ListRolesForUser(r.Context(), userID)
 	if err != nil {
-		writeErrorResponse(w, http.StatusInternalServerError, "Failed to list roles for user", err)
+		s.writeError(w, r, http.StatusInternalServerError, "Failed to list roles for user", err)
 		return
 	}
 
 	writeJSONResponse(w, http.StatusOK, roles)
 }
 
-// AddUserToGroupHandler handles adding a user to a group.
+// AddUserToGroupHandler handles adding a user to a group, optionally as an
+// "admin" or "owner" of it.
 // POST /users/add-to-group
-// Request Body: {"group_id": "group1", "user_id": "user1", "group_name": "GroupName"}
+// Request Body: {"group_id": "group1", "user_id": "user1", "group_name": "GroupName", "level": "admin"}
 func (s *Server) AddUserToGroupHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		writeErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed", nil)
+		s.writeError(w, r, http.StatusMethodNotAllowed, "Method not allowed", nil)
 		return
 	}
 
 	var req struct {
+		ID        string `json:"id"`
 		GroupID   string `json:"group_id"`
 		UserID    string `json:"user_id"`
 		GroupName string `json:"group_name"`
+		TenantID  string `json:"tenant_id"`
+		Level     string `json:"level"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeErrorResponse(w, http.StatusBadRequest, "Invalid request body", err)
+		s.writeError(w, r, http.StatusBadRequest, "Invalid request body", err)
 		return
 	}
 
 	ug := &rbac.UserGroup{
+		ID:        req.ID,
 		UserID:    req.UserID,
 		GroupName: req.GroupName,
+		TenantID:  req.TenantID,
+		Level:     rbac.MembershipLevel(req.Level),
 	}
 
-	if err := s.RBACManager.AddUserToGroup(r.Context(), ug); err != nil {
-		writeErrorResponse(w, http.StatusInternalServerError, "Failed to add user to group", err)
+	if err := s.manager(r).AddUserToGroup(r.Context(), ug); err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, "Failed to add user to group", err)
 		return
 	}
 
-	writeJSONResponse(w, http.StatusOK, map[string]string{"message": "User added to group successfully"})
+	writeJSONResponse(w, http.StatusOK, map[string]string{"message": s.Message(r, "User added to group successfully"), "user_group_id": ug.ID})
+}
+
+// AddUsersToGroupHandler adds many users to a group at once, e.g. when
+// onboarding a team. Either all are added or none is.
+// POST /users/add-many-to-group
+// Request Body: {"group_name": "GroupName", "user_ids": ["user1", "user2"], "level": "member"}
+func (s *Server) AddUsersToGroupHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, r, http.StatusMethodNotAllowed, "Method not allowed", nil)
+		return
+	}
+
+	var req struct {
+		GroupName string   `json:"group_name"`
+		UserIDs   []string `json:"user_ids"`
+		TenantID  string   `json:"tenant_id"`
+		Level     string   `json:"level"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.writeError(w, r, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+
+	ugs := make([]*rbac.UserGroup, len(req.UserIDs))
+	for i, userID := range req.UserIDs {
+		ugs[i] = &rbac.UserGroup{
+			UserID:    userID,
+			GroupName: req.GroupName,
+			TenantID:  req.TenantID,
+			Level:     rbac.MembershipLevel(req.Level),
+		}
+	}
+	if err := s.manager(r).AddUsersToGroup(r.Context(), ugs); err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, "Failed to add users to group", err)
+		return
+	}
+
+	ids := make([]string, len(ugs))
+	for i, ug := range ugs {
+		ids[i] = ug.ID
+	}
+	writeJSONResponse(w, http.StatusOK, map[string]any{"message": s.Message(r, "Users added to group successfully"), "user_group_ids": ids})
 }
 
 // RemoveUserFromGroupHandler handles removing a user from a group.
@@ -191,7 +426,7 @@ func (s *Server) AddUserToGroupHandler(w http.ResponseWriter, r *http.Request) {
 // Request Body: {"group_id": "group1", "user_id": "user1", "group_name": "GroupName"}
 func (s *Server) RemoveUserFromGroupHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		writeErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed", nil)
+		s.writeError(w, r, http.StatusMethodNotAllowed, "Method not allowed", nil)
 		return
 	}
 
@@ -201,7 +436,7 @@ func (s *Server) RemoveUserFromGroupHandler(w http.ResponseWriter, r *http.Reque
 		GroupName string `json:"group_name"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeErrorResponse(w, http.StatusBadRequest, "Invalid request body", err)
+		s.writeError(w, r, http.StatusBadRequest, "Invalid request body", err)
 		return
 	}
 
@@ -210,31 +445,39 @@ func (s *Server) RemoveUserFromGroupHandler(w http.ResponseWriter, r *http.Reque
 		GroupName: req.GroupName,
 	}
 
-	if err := s.RBACManager.RemoveUserFromGroup(r.Context(), req.GroupID, ug); err != nil {
-		writeErrorResponse(w, http.StatusInternalServerError, "Failed to remove user from group", err)
+	if err := s.manager(r).RemoveUserFromGroup(r.Context(), req.GroupID, ug); err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, "Failed to remove user from group", err)
 		return
 	}
 
-	writeJSONResponse(w, http.StatusOK, map[string]string{"message": "User removed from group successfully"})
+	writeJSONResponse(w, http.StatusOK, map[string]string{"message": s.Message(r, "User removed from group successfully")})
 }
 
-// GetUsersByGroupIDHandler handles getting users by group ID.
-// GET /users/list-by-group?group_id=group1
+// GetUsersByGroupIDHandler handles getting users by group ID, a page at a
+// time when a cursor or limit is given.
+// GET /users/list-by-group?group_id=group1&limit=100&cursor=...
 func (s *Server) GetUsersByGroupIDHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		writeErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed", nil)
+		s.writeError(w, r, http.StatusMethodNotAllowed, "Method not allowed", nil)
 		return
 	}
 
 	groupID := r.URL.Query().Get("group_id")
 	if groupID == "" {
-		writeErrorResponse(w, http.StatusBadRequest, "Missing group_id query parameter", nil)
+		s.writeError(w, r, http.StatusBadRequest, "Missing group_id query parameter", nil)
+		return
+	}
+
+	page := func(ctx context.Context, p rbac.PageRequest) (rbac.PageResult[*rbac.UserGroup], error) {
+		return s.manager(r).GetUsersByGroupIDPage(ctx, groupID, p)
+	}
+	if writePage(s, w, r, page) {
 		return
 	}
 
-	users, err := s.RBACManager.GetUsersByGroupID(r.Context(), groupID)
+	users, err := s.manager(r).GetUsersByGroupID(r.Context(), groupID)
 	if err != nil {
-		writeErrorResponse(w, http.StatusInternalServerError, "Failed to get users by group ID", err)
+		s.writeError(w, r, http.StatusInternalServerError, "Failed to get users by group ID", err)
 		return
 	}
 
@@ -245,19 +488,19 @@ func (s *Server) GetUsersByGroupIDHandler(w http.ResponseWriter, r *http.Request
 // GET /users/list-groups?user_id=user1
 func (s *Server) GetGroupsByUserIDHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		writeErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed", nil)
+		s.writeError(w, r, http.StatusMethodNotAllowed, "Method not allowed", nil)
 		return
 	}
 
 	userID := r.URL.Query().Get("user_id")
 	if userID == "" {
-		writeErrorResponse(w, http.StatusBadRequest, "Missing user_id query parameter", nil)
+		s.writeError(w, r, http.StatusBadRequest, "Missing user_id query parameter", nil)
 		return
 	}
 
-	groups, err := s.RBACManager.GetGroupsByUserID(r.Context(), userID)
+	groups, err := s.manager(r).GetGroupsByUserID(r.Context(), userID)
 	if err != nil {
-		writeErrorResponse(w, http.StatusInternalServerError, "Failed to get groups by user ID", err)
+		s.writeError(w, r, http.StatusInternalServerError, "Failed to get groups by user ID", err)
 		return
 	}
 
@@ -268,7 +511,7 @@ func (s *Server) GetGroupsByUserIDHandler(w http.ResponseWriter, r *http.Request
 // GET /users/has-permission?user_id=user1&perm_id=permission1
 func (s *Server) HasPermissionHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		writeErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed", nil)
+		s.writeError(w, r, http.StatusMethodNotAllowed, "Method not allowed", nil)
 		return
 	}
 
@@ -276,13 +519,13 @@ func (s *Server) HasPermissionHandler(w http.ResponseWriter, r *http.Request) {
 	permID := r.URL.Query().Get("perm_id")
 
 	if userID == "" || permID == "" {
-		writeErrorResponse(w, http.StatusBadRequest, "Missing user_id or perm_id query parameters", nil)
+		s.writeError(w, r, http.StatusBadRequest, "Missing user_id or perm_id query parameters", nil)
 		return
 	}
 
-	hasPermission, err := s.RBACManager.HasPermission(r.Context(), userID, permID)
+	hasPermission, err := s.manager(r).HasPermission(r.Context(), userID, permID)
 	if err != nil {
-		writeErrorResponse(w, http.StatusInternalServerError, "Failed to check permission", err)
+		s.writeError(w, r, http.StatusInternalServerError, "Failed to check permission", err)
 		return
 	}
 
@@ -292,27 +535,245 @@ func (s *Server) HasPermissionHandler(w http.ResponseWriter, r *http.Request) {
 // CanHandler checks if a user can perform an action on a resource.
 // POST /users/can
 // Request Body: {"user_id": "user1", "resource": "/api/data", "action": "read"}
+// GET /users/can?user_id=user1&resource=/api/data&action=read
+//
+// A POST body may add "attributes", an object of request attributes for
+// permission conditions, which are then checked with CanWithAttributes.
+// It may also add "client_ip" and "time" (RFC 3339, default now) describing
+// the end user's request, so roles assigned with constraints such as
+// allowed CIDRs count when the request meets them (see
+// rbac.Manager.CanWithContext).
+// When a permission decided the check, the response names its role as
+// "role_id" (see rbac.Manager.Decide).
+//
+// Responses carry the policy version and an ETag derived from it and the
+// request. Clients that cached a decision can revalidate it by sending the
+// ETag in If-None-Match; while the policy is unchanged the answer is
+// 304 Not Modified without evaluating the check.
 func (s *Server) CanHandler(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		UserID     string         `json:"user_id"`
+		Resource   string         `json:"resource"`
+		Action     string         `json:"action"`
+		Attributes map[string]any `json:"attributes,omitempty"`
+		ClientIP   string         `json:"client_ip,omitempty"`
+		Time       *time.Time     `json:"time,omitempty"`
+	}
+	switch r.Method {
+	case http.MethodPost:
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			s.writeError(w, r, http.StatusBadRequest, "Invalid request body", err)
+			return
+		}
+	case http.MethodGet:
+		q := r.URL.Query()
+		req.UserID, req.Resource, req.Action = q.Get("user_id"), q.Get("resource"), q.Get("action")
+	default:
+		s.writeError(w, r, http.StatusMethodNotAllowed, "Method not allowed", nil)
+		return
+	}
+
+	// a request context defaulting to now changes the ETag on every call
+	ctx, when := r.Context(), ""
+	if req.ClientIP != "" || req.Time != nil {
+		rc := rbac.RequestContext{Time: time.Now()}
+		if req.Time != nil {
+			rc.Time = *req.Time
+		}
+		if req.ClientIP != "" {
+			ip, err := netip.ParseAddr(req.ClientIP)
+			if err != nil {
+				s.writeError(w, r, http.StatusBadRequest, "Invalid client IP", err)
+				return
+			}
+			rc.ClientIP = ip
+		}
+		ctx, when = rbac.WithRequestContext(ctx, rc), rc.Time.UTC().Format(time.RFC3339Nano)
+	}
+
+	version, err := s.manager(r).PolicyVersion(r.Context())
+	if err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, "Failed to read policy version", err)
+		return
+	}
+	var attrs []byte
+	if req.Attributes != nil {
+		attrs, _ = json.Marshal(req.Attributes)
+	}
+	etag := decisionETag(version, req.UserID, req.Resource, req.Action, string(attrs), req.ClientIP, when)
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", "no-cache")
+	if etagMatches(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	decision, err := s.manager(r).Decide(ctx, req.UserID, req.Resource, rbac.Action(req.Action), req.Attributes)
+	if err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, "Failed to perform authorization check", err)
+		return
+	}
+
+	resp := map[string]interface{}{"can_perform_action": decision.Allowed, "policy_version": version}
+	if decision.RoleID != "" {
+		resp["role_id"] = decision.RoleID
+	}
+	if decision.SuperAdmin {
+		resp["super_admin"] = true
+	}
+	writeJSONResponse(w, http.StatusOK, resp)
+}
+
+// CanBatchHandler decides many checks for one user, e.g. which of a page's
+// buttons to render, in one round trip (see rbac.Manager.BatchCan).
+// POST /users/can-batch
+// Request Body: {"user_id": "user1", "checks": [{"resource": "/api/data", "action": "read"}, ...]}
+// Response Body: {"results": [{"resource": "/api/data", "action": "read", "allowed": true, "role_id": "...", ...}, ...], "policy_version": "..."}
+//
+// Results are in the order of the checks and carry the deciding rule as
+// rbac.Decision does.
+func (s *Server) CanBatchHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		writeErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed", nil)
+		s.writeError(w, r, http.StatusMethodNotAllowed, "Method not allowed", nil)
+		return
+	}
+	var req struct {
+		UserID string       `json:"user_id"`
+		Checks []rbac.Check `json:"checks"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.writeError(w, r, http.StatusBadRequest, "Invalid request body", err)
 		return
 	}
+	version, err := s.manager(r).PolicyVersion(r.Context())
+	if err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, "Failed to read policy version", err)
+		return
+	}
+	decisions, err := s.manager(r).BatchCan(r.Context(), req.UserID, req.Checks)
+	if err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, "Failed to perform authorization check", err)
+		return
+	}
+
+	type result struct {
+		rbac.Check
+		rbac.Decision
+	}
+	results := make([]result, len(decisions))
+	for i, d := range decisions {
+		results[i] = result{Check: req.Checks[i], Decision: d}
+	}
+	writeJSONResponse(w, http.StatusOK, map[string]interface{}{"results": results, "policy_version": version})
+}
 
+// ExplainHandler reports why a user may or may not perform an action on a
+// resource: the roles they hold and how, the permissions that matched, and
+// the one that decided (see rbac.Manager.Explain).
+// GET /users/explain?user_id=user1&resource=/api/data&action=read
+// POST /users/explain
+// Request Body: {"user_id": "user1", "resource": "/api/data", "action": "read"}
+func (s *Server) ExplainHandler(w http.ResponseWriter, r *http.Request) {
 	var req struct {
 		UserID   string `json:"user_id"`
 		Resource string `json:"resource"`
 		Action   string `json:"action"`
 	}
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeErrorResponse(w, http.StatusBadRequest, "Invalid request body", err)
+	switch r.Method {
+	case http.MethodPost:
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			s.writeError(w, r, http.StatusBadRequest, "Invalid request body", err)
+			return
+		}
+	case http.MethodGet:
+		q := r.URL.Query()
+		req.UserID, req.Resource, req.Action = q.Get("user_id"), q.Get("resource"), q.Get("action")
+	default:
+		s.writeError(w, r, http.StatusMethodNotAllowed, "Method not allowed", nil)
 		return
 	}
 
-	can, err := s.RBACManager.Can(r.Context(), req.UserID, req.Resource, rbac.Action(req.Action))
+	explanation, err := s.manager(r).Explain(r.Context(), req.UserID, req.Resource, rbac.Action(req.Action))
 	if err != nil {
-		writeErrorResponse(w, http.StatusInternalServerError, "Failed to perform authorization check", err)
+		s.writeError(w, r, http.StatusInternalServerError, "Failed to explain decision", err)
+		return
+	}
+	writeJSONResponse(w, http.StatusOK, explanation)
+}
+
+// EffectivePermissionsHandler lists the permissions a user holds through
+// its direct, group, delegated and inherited roles, each once.
+// GET /users/effective-permissions?user_id=user1
+func (s *Server) EffectivePermissionsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, r, http.StatusMethodNotAllowed, "Method not allowed", nil)
 		return
 	}
 
-	writeJSONResponse(w, http.StatusOK, map[string]bool{"can_perform_action": can})
+	userID := r.URL.Query().Get("user_id")
+	if userID == "" {
+		s.writeError(w, r, http.StatusBadRequest, "Missing user_id query parameter", nil)
+		return
+	}
+
+	perms, err := s.manager(r).ListEffectivePermissions(r.Context(), userID)
+	if err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, "Failed to list effective permissions", err)
+		return
+	}
+	writeJSONResponse(w, http.StatusOK, perms)
+}
+
+// WhoCanHandler lists a page of the users allowed to perform an action on a
+// resource (see rbac.Manager.WhoCan). Without cursor or limit it returns the
+// first page.
+// GET /users/who-can?resource=billing/*&action=delete&cursor=&limit=100
+func (s *Server) WhoCanHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, r, http.StatusMethodNotAllowed, "Method not allowed", nil)
+		return
+	}
+
+	resource := r.URL.Query().Get("resource")
+	action := r.URL.Query().Get("action")
+	if resource == "" || action == "" {
+		s.writeError(w, r, http.StatusBadRequest, "Missing resource or action query parameter", nil)
+		return
+	}
+
+	page := func(ctx context.Context, p rbac.PageRequest) (rbac.PageResult[*rbac.User], error) {
+		return s.manager(r).WhoCan(ctx, resource, rbac.Action(action), p)
+	}
+	if writePage(s, w, r, page) {
+		return
+	}
+
+	res, err := page(r.Context(), rbac.PageRequest{})
+	if err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, "Failed to find who can access resource", err)
+		return
+	}
+	writeJSONResponse(w, http.StatusOK, res)
+}
+
+// decisionETag identifies a decision for one request under one policy version.
+func decisionETag(version string, request ...string) string {
+	h := sha256.New()
+	for _, part := range append([]string{version}, request...) {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	return `"` + hex.EncodeToString(h.Sum(nil)[:16]) + `"`
+}
+
+// etagMatches reports whether an If-None-Match header value matches etag.
+// Weak validators compare equal to their strong form.
+func etagMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
 }
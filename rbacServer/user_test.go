@@ -0,0 +1,715 @@
+package rbacServer
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Seann-Moser/rbac"
+)
+
+func TestCanHandlerConditional(t *testing.T) {
+	ctx := context.Background()
+	mgr := rbac.NewMockRepoManager(rbac.NewMockRepo())
+	srv := NewServer(mgr)
+
+	if err := mgr.CreateUser(ctx, &rbac.User{ID: "alice", Username: "alice"}); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+
+	check := func(ifNoneMatch string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/users/can?user_id=alice&resource=survey&action=read", nil)
+		if ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		rec := httptest.NewRecorder()
+		srv.CanHandler(rec, req)
+		return rec
+	}
+
+	first := check("")
+	if first.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", first.Code)
+	}
+	etag := first.Header().Get("ETag")
+	if etag == "" {
+		t.Fatal("expected an ETag on the decision")
+	}
+	var body struct {
+		Can     bool   `json:"can_perform_action"`
+		Version string `json:"policy_version"`
+	}
+	if err := json.NewDecoder(first.Body).Decode(&body); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if body.Can || body.Version == "" {
+		t.Fatalf("unexpected decision: %+v", body)
+	}
+
+	if rec := check(etag); rec.Code != http.StatusNotModified {
+		t.Fatalf("expected 304 for an unchanged policy, got %d", rec.Code)
+	}
+	if rec := check(`"other", W/` + etag); rec.Code != http.StatusNotModified {
+		t.Fatalf("expected 304 for a matching weak ETag in a list, got %d", rec.Code)
+	}
+
+	if err := mgr.CreateRole(ctx, &rbac.Role{ID: "reader", Name: "reader"}); err != nil {
+		t.Fatalf("CreateRole: %v", err)
+	}
+	rec := check(etag)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 after a policy change, got %d", rec.Code)
+	}
+	if rec.Header().Get("ETag") == etag {
+		t.Error("expected a new ETag after a policy change")
+	}
+}
+
+func TestTenantPrincipalScopesHandlers(t *testing.T) {
+	ctx := context.Background()
+	mgr, err := rbac.NewMemoryStoreManager(ctx, "", 0)
+	if err != nil {
+		t.Fatalf("NewMemoryStoreManager: %v", err)
+	}
+	srv := NewServer(mgr)
+	for _, id := range []string{"acme", "globex"} {
+		if err := mgr.CreateTenant(ctx, &rbac.Tenant{ID: id}); err != nil {
+			t.Fatalf("CreateTenant(%s): %v", id, err)
+		}
+	}
+	globexAdmin, _ := mgr.ForTenant("globex").Roles.GetRoleByName(ctx, "admin")
+	alice := &rbac.User{Username: "alice"}
+	if err := mgr.ForTenant("acme").CreateUser(ctx, alice); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	as := func(req *http.Request) *http.Request {
+		return req.WithContext(WithPrincipal(req.Context(), alice))
+	}
+
+	rec := httptest.NewRecorder()
+	srv.ListRoles(rec, as(httptest.NewRequest(http.MethodGet, "/roles/get-all", nil)))
+	var roles []*rbac.Role
+	if err := json.NewDecoder(rec.Body).Decode(&roles); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(roles) != len(rbac.DefaultTenantTemplate.Roles) {
+		t.Fatalf("expected only acme's roles, got %+v", roles)
+	}
+	for _, r := range roles {
+		if r.TenantID != "acme" {
+			t.Errorf("expected acme's roles only, got %+v", r)
+		}
+	}
+
+	body := strings.NewReader(`{"user_id":"` + alice.ID + `","role_id":"` + globexAdmin.ID + `"}`)
+	rec = httptest.NewRecorder()
+	srv.AssignRoleToUserHandler(rec, as(httptest.NewRequest(http.MethodPost, "/users/assign-role", body)))
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("expected 403 for another tenant's role, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	srv.ExportHandler(rec, as(httptest.NewRequest(http.MethodGet, "/export", nil)))
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("expected tenant principals to be refused an export, got %d", rec.Code)
+	}
+}
+
+func TestGroupHandlers(t *testing.T) {
+	ctx := context.Background()
+	mgr := rbac.NewMockRepoManager(rbac.NewMockRepo())
+	srv := NewServer(mgr)
+
+	do := func(method, target, body string, h http.HandlerFunc) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		rec := httptest.NewRecorder()
+		h(rec, req)
+		return rec
+	}
+
+	rec := do(http.MethodPost, "/groups/create", `{"name": "writers", "owner": "alice"}`, srv.CreateGroupHandler)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create: expected 201, got %d: %s", rec.Code, rec.Body)
+	}
+	var created map[string]string
+	if err := json.NewDecoder(rec.Body).Decode(&created); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	id := created["group_id"]
+	if rec := do(http.MethodPost, "/groups/create", `{"name": "writers"}`, srv.CreateGroupHandler); rec.Code != http.StatusConflict {
+		t.Errorf("duplicate create: expected 409, got %d", rec.Code)
+	}
+
+	if err := mgr.AddUserToGroup(ctx, &rbac.UserGroup{UserID: "bob", GroupName: "writers"}); err != nil {
+		t.Fatalf("AddUserToGroup: %v", err)
+	}
+	if rec := do(http.MethodPost, "/groups/rename", `{"id": "`+id+`", "name": "authors"}`, srv.RenameGroupHandler); rec.Code != http.StatusOK {
+		t.Fatalf("rename: expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+	rec = do(http.MethodGet, "/groups/get-by-name?name=authors", "", srv.GetGroupByNameHandler)
+	var group rbac.Group
+	if err := json.NewDecoder(rec.Body).Decode(&group); err != nil || group.ID != id || group.Owner != "alice" {
+		t.Fatalf("get-by-name = %+v, %v", group, err)
+	}
+	if members, _ := mgr.GetUsersByGroupID(ctx, "authors"); len(members) != 1 {
+		t.Errorf("expected the member to follow the rename, got %+v", members)
+	}
+
+	if rec := do(http.MethodDelete, "/groups/delete?id="+id, "", srv.DeleteGroupHandler); rec.Code != http.StatusOK {
+		t.Fatalf("delete: expected 200, got %d", rec.Code)
+	}
+	if rec := do(http.MethodGet, "/groups/get?id="+id, "", srv.GetGroupHandler); rec.Code != http.StatusNotFound {
+		t.Errorf("get after delete: expected 404, got %d", rec.Code)
+	}
+	if rec := do(http.MethodPost, "/groups/rename", `{"id": "`+id+`", "name": "x"}`, srv.RenameGroupHandler); rec.Code != http.StatusNotFound {
+		t.Errorf("rename of a missing group: expected 404, got %d", rec.Code)
+	}
+}
+
+func TestPermissionNameHandlers(t *testing.T) {
+	srv := NewServer(rbac.NewMockRepoManager(rbac.NewMockRepo()))
+
+	do := func(method, target, body string, h http.HandlerFunc) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		rec := httptest.NewRecorder()
+		h(rec, req)
+		return rec
+	}
+
+	body := `{"name": "invoices.approve", "description": "Approve invoices", "labels": {"owner": "billing"}, "resource": "invoices/*", "action": "update"}`
+	if rec := do(http.MethodPost, "/permissions/create", body, srv.CreatePermissionHandler); rec.Code != http.StatusCreated {
+		t.Fatalf("create: expected 201, got %d: %s", rec.Code, rec.Body)
+	}
+	dup := `{"name": "invoices.approve", "resource": "invoices/*", "action": "delete"}`
+	if rec := do(http.MethodPost, "/permissions/create", dup, srv.CreatePermissionHandler); rec.Code != http.StatusConflict {
+		t.Errorf("duplicate name: expected 409, got %d", rec.Code)
+	}
+
+	rec := do(http.MethodGet, "/permissions/get-by-name?name=invoices.approve", "", srv.GetPermissionByNameHandler)
+	var perm rbac.Permission
+	if err := json.NewDecoder(rec.Body).Decode(&perm); err != nil || perm.Description != "Approve invoices" || perm.Labels["owner"] != "billing" {
+		t.Fatalf("get-by-name = %+v, %v", perm, err)
+	}
+	if rec := do(http.MethodGet, "/permissions/get-by-name?name=missing", "", srv.GetPermissionByNameHandler); rec.Code != http.StatusNotFound {
+		t.Errorf("missing: expected 404, got %d", rec.Code)
+	}
+}
+
+func TestRoleMetaHandlers(t *testing.T) {
+	srv := NewServer(rbac.NewMockRepoManager(rbac.NewMockRepo()))
+
+	body := `{"name": "billing-admin", "meta": {"team": "billing", "ticket": "FIN-7", "review": "quarterly"}}`
+	rec := httptest.NewRecorder()
+	srv.CreateRoleHandler(rec, httptest.NewRequest(http.MethodPost, "/roles/create", strings.NewReader(body)))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create: expected 201, got %d: %s", rec.Code, rec.Body)
+	}
+
+	rec = httptest.NewRecorder()
+	srv.GetRoleByNameHandler(rec, httptest.NewRequest(http.MethodGet, "/roles/get-by-name?name=billing-admin", nil))
+	var role rbac.Role
+	if err := json.NewDecoder(rec.Body).Decode(&role); err != nil || role.Meta["team"] != "billing" || role.Meta["review"] != "quarterly" {
+		t.Fatalf("get-by-name = %+v, %v", role, err)
+	}
+}
+
+func TestAddUserToGroupLevel(t *testing.T) {
+	ctx := context.Background()
+	mgr := rbac.NewMockRepoManager(rbac.NewMockRepo())
+	srv := NewServer(mgr)
+
+	add := func(body string) int {
+		rec := httptest.NewRecorder()
+		srv.AddUserToGroupHandler(rec, httptest.NewRequest(http.MethodPost, "/users/add-to-group", strings.NewReader(body)))
+		return rec.Code
+	}
+	if code := add(`{"user_id": "alice", "group_name": "docs", "level": "admin"}`); code != http.StatusOK {
+		t.Fatalf("add: expected 200, got %d", code)
+	}
+	if ok, err := mgr.IsGroupAdmin(ctx, "docs", "alice"); err != nil || !ok {
+		t.Errorf("IsGroupAdmin = %v, %v; want true", ok, err)
+	}
+	if code := add(`{"user_id": "bob", "group_name": "docs", "level": "boss"}`); code != http.StatusBadRequest {
+		t.Errorf("invalid level: expected 400, got %d", code)
+	}
+}
+
+func TestCloneRoleHandler(t *testing.T) {
+	ctx := context.Background()
+	mgr := rbac.NewMockRepoManager(rbac.NewMockRepo())
+	srv := NewServer(mgr)
+	blueprint := &rbac.Role{Name: "blueprint", Template: true}
+	if err := mgr.CreateRole(ctx, blueprint); err != nil {
+		t.Fatalf("CreateRole: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/roles/clone", strings.NewReader(`{"role_id": "`+blueprint.ID+`", "name": "acme"}`))
+	rec := httptest.NewRecorder()
+	srv.CloneRoleHandler(rec, req)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("clone: expected 201, got %d: %s", rec.Code, rec.Body)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/users/assign-role", strings.NewReader(`{"user_id": "bob", "role_id": "`+blueprint.ID+`"}`))
+	rec = httptest.NewRecorder()
+	srv.AssignRoleToUserHandler(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("assigning a template: expected 400, got %d", rec.Code)
+	}
+}
+
+func TestListAllHandlers(t *testing.T) {
+	ctx := context.Background()
+	mgr := rbac.NewMockRepoManager(rbac.NewMockRepo())
+	srv := NewServer(mgr)
+	for _, name := range []string{"alice", "bob"} {
+		if err := mgr.CreateUser(ctx, &rbac.User{Username: name}); err != nil {
+			t.Fatalf("CreateUser: %v", err)
+		}
+	}
+	if err := mgr.CreatePermission(ctx, &rbac.Permission{Resource: "survey", Action: rbac.ActionRead}); err != nil {
+		t.Fatalf("CreatePermission: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	srv.ListUsersHandler(rec, httptest.NewRequest(http.MethodGet, "/users/get-all", nil))
+	var users []*rbac.User
+	if rec.Code != http.StatusOK || json.NewDecoder(rec.Body).Decode(&users) != nil || len(users) != 2 {
+		t.Errorf("users: unexpected response %d %v", rec.Code, users)
+	}
+
+	rec = httptest.NewRecorder()
+	srv.ListPermissionsHandler(rec, httptest.NewRequest(http.MethodGet, "/permissions/get-all", nil))
+	var perms []*rbac.Permission
+	if rec.Code != http.StatusOK || json.NewDecoder(rec.Body).Decode(&perms) != nil || len(perms) != 1 || perms[0].Resource != "survey" {
+		t.Errorf("permissions: unexpected response %d %v", rec.Code, perms)
+	}
+}
+
+func TestRestoreAndPurgeRoleHandlers(t *testing.T) {
+	ctx := context.Background()
+	mgr, err := rbac.NewMemoryStoreManager(ctx, "", 0)
+	if err != nil {
+		t.Fatalf("NewMemoryStoreManager: %v", err)
+	}
+	role := &rbac.Role{Name: "editor"}
+	if err := mgr.CreateRole(ctx, role); err != nil {
+		t.Fatalf("CreateRole: %v", err)
+	}
+	srv := NewServer(mgr)
+	do := func(method, url string, h http.HandlerFunc) int {
+		rec := httptest.NewRecorder()
+		h(rec, httptest.NewRequest(method, url, nil))
+		return rec.Code
+	}
+
+	if code := do(http.MethodDelete, "/roles/delete?id="+role.ID, srv.DeleteRoleHandler); code != http.StatusOK {
+		t.Fatalf("delete: expected 200, got %d", code)
+	}
+	if r, _ := mgr.GetRole(ctx, role.ID); r == nil || r.DeletedAt == 0 {
+		t.Fatalf("expected a soft-deleted role, got %+v", r)
+	}
+	if code := do(http.MethodPost, "/roles/restore?id="+role.ID, srv.RestoreRoleHandler); code != http.StatusOK {
+		t.Errorf("restore: expected 200, got %d", code)
+	}
+	if code := do(http.MethodDelete, "/roles/purge?id="+role.ID, srv.PurgeRoleHandler); code != http.StatusOK {
+		t.Errorf("purge: expected 200, got %d", code)
+	}
+	if code := do(http.MethodPost, "/roles/restore?id="+role.ID, srv.RestoreRoleHandler); code != http.StatusNotFound {
+		t.Errorf("restore purged: expected 404, got %d", code)
+	}
+	if code := do(http.MethodPost, "/permissions/restore?id=missing", srv.RestorePermissionHandler); code != http.StatusNotFound {
+		t.Errorf("restore missing permission: expected 404, got %d", code)
+	}
+}
+
+func TestSuspendAndReactivateUserHandlers(t *testing.T) {
+	ctx := context.Background()
+	mgr, err := rbac.NewMemoryStoreManager(ctx, "", 0)
+	if err != nil {
+		t.Fatalf("NewMemoryStoreManager: %v", err)
+	}
+	user := &rbac.User{Username: "alice"}
+	if err := mgr.CreateUser(ctx, user); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	srv := NewServer(mgr)
+	do := func(url string, h http.HandlerFunc) int {
+		rec := httptest.NewRecorder()
+		h(rec, httptest.NewRequest(http.MethodPost, url, nil))
+		return rec.Code
+	}
+
+	if code := do("/users/suspend?id="+user.ID, srv.SuspendUserHandler); code != http.StatusOK {
+		t.Fatalf("suspend: expected 200, got %d", code)
+	}
+	if u, _ := mgr.GetUser(ctx, user.ID); u == nil || u.Status != rbac.UserSuspended {
+		t.Fatalf("expected a suspended user, got %+v", u)
+	}
+	if code := do("/users/reactivate?id="+user.ID, srv.ReactivateUserHandler); code != http.StatusOK {
+		t.Errorf("reactivate: expected 200, got %d", code)
+	}
+	if code := do("/users/suspend?id=missing", srv.SuspendUserHandler); code != http.StatusNotFound {
+		t.Errorf("suspend missing user: expected 404, got %d", code)
+	}
+}
+
+func TestGetUserByMetaHandler(t *testing.T) {
+	ctx := context.Background()
+	mgr, err := rbac.NewMemoryStoreManager(ctx, "", 0)
+	if err != nil {
+		t.Fatalf("NewMemoryStoreManager: %v", err)
+	}
+	user := &rbac.User{Username: "alice", Meta: map[string]interface{}{"team": "core"}}
+	if err := mgr.CreateUser(ctx, user); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	srv := NewServer(mgr)
+	get := func(url string) (int, *rbac.User) {
+		rec := httptest.NewRecorder()
+		srv.GetUserByMetaHandler(rec, httptest.NewRequest(http.MethodGet, url, nil))
+		var u rbac.User
+		_ = json.NewDecoder(rec.Body).Decode(&u)
+		return rec.Code, &u
+	}
+
+	if code, u := get("/users/get-by-meta?meta.team=infra&meta.team=core"); code != http.StatusOK || u.ID != user.ID {
+		t.Errorf("expected alice, got %d %+v", code, u)
+	}
+	if code, _ := get("/users/get-by-meta?username=alice&meta.team=infra"); code != http.StatusNotFound {
+		t.Errorf("expected 404, got %d", code)
+	}
+	if code, _ := get("/users/get-by-meta?password=x"); code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an unsupported field, got %d", code)
+	}
+}
+
+func TestGetUserByEmailHandler(t *testing.T) {
+	ctx := context.Background()
+	mgr := rbac.NewMockRepoManager(rbac.NewMockRepo())
+	user := &rbac.User{Username: "alice", Email: "alice@example.com", EmailVerified: true}
+	if err := mgr.CreateUser(ctx, user); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	srv := NewServer(mgr)
+	get := func(url string) (int, *rbac.User) {
+		rec := httptest.NewRecorder()
+		srv.GetUserByEmailHandler(rec, httptest.NewRequest(http.MethodGet, url, nil))
+		var u rbac.User
+		_ = json.NewDecoder(rec.Body).Decode(&u)
+		return rec.Code, &u
+	}
+
+	if code, u := get("/users/get-by-email?email=alice@example.com"); code != http.StatusOK || u.ID != user.ID || !u.EmailVerified {
+		t.Errorf("expected alice, got %d %+v", code, u)
+	}
+	if code, _ := get("/users/get-by-email?email=bob@example.com"); code != http.StatusNotFound {
+		t.Errorf("expected 404, got %d", code)
+	}
+	if code, _ := get("/users/get-by-email"); code != http.StatusBadRequest {
+		t.Errorf("expected 400 without an email, got %d", code)
+	}
+}
+
+func TestListActionsHandler(t *testing.T) {
+	ctx := context.Background()
+	mgr, err := rbac.NewMemoryStoreManager(ctx, "", 0)
+	if err != nil {
+		t.Fatalf("NewMemoryStoreManager: %v", err)
+	}
+	srv := NewServer(mgr)
+	list := func() actionList {
+		rec := httptest.NewRecorder()
+		srv.ListActionsHandler(rec, httptest.NewRequest(http.MethodGet, "/actions/list", nil))
+		var out actionList
+		if rec.Code != http.StatusOK || json.NewDecoder(rec.Body).Decode(&out) != nil {
+			t.Fatalf("unexpected response %d", rec.Code)
+		}
+		return out
+	}
+
+	if got := list(); len(got.Actions) != 4 || len(got.Groups) != 0 {
+		t.Errorf("expected the CRUD actions without a registry, got %+v", got)
+	}
+	if mgr.Actions, err = rbac.NewActionRegistry(rbac.ActionDef{Name: "approve"}); err != nil {
+		t.Fatalf("NewActionRegistry: %v", err)
+	}
+	if err := mgr.Actions.RegisterGroup(rbac.ActionGroup{Name: "review", Actions: []rbac.Action{"approve", rbac.ActionRead}}); err != nil {
+		t.Fatalf("RegisterGroup: %v", err)
+	}
+	if got := list(); len(got.Actions) != 5 || len(got.Groups) != 1 || got.Groups[0].Name != "review" {
+		t.Errorf("unexpected actions %+v", got)
+	}
+}
+
+func TestCanHandlerRequestContext(t *testing.T) {
+	ctx := context.Background()
+	mgr := rbac.NewMockRepoManager(rbac.NewMockRepo())
+	srv := NewServer(mgr)
+
+	role := &rbac.Role{Name: "admin"}
+	perm := &rbac.Permission{Resource: "settings", Action: rbac.ActionUpdate}
+	if err := mgr.CreateRole(ctx, role); err != nil {
+		t.Fatalf("CreateRole: %v", err)
+	}
+	if err := mgr.CreatePermission(ctx, perm); err != nil {
+		t.Fatalf("CreatePermission: %v", err)
+	}
+	if err := mgr.AssignPermissionToRole(ctx, role.ID, perm.ID); err != nil {
+		t.Fatalf("AssignPermissionToRole: %v", err)
+	}
+	if err := mgr.AssignConstrainedRoleToUser(ctx, "alice", role.ID, rbac.RoleConstraints{AllowedCIDRs: []string{"10.0.0.0/8"}}); err != nil {
+		t.Fatalf("AssignConstrainedRoleToUser: %v", err)
+	}
+
+	check := func(body string) (int, bool) {
+		rec := httptest.NewRecorder()
+		srv.CanHandler(rec, httptest.NewRequest(http.MethodPost, "/users/can", strings.NewReader(body)))
+		var resp struct {
+			Can bool `json:"can_perform_action"`
+		}
+		_ = json.NewDecoder(rec.Body).Decode(&resp)
+		return rec.Code, resp.Can
+	}
+	if code, can := check(`{"user_id":"alice","resource":"settings","action":"update","client_ip":"10.4.5.6"}`); code != http.StatusOK || !can {
+		t.Errorf("from the corporate network: %d, %v; want 200, true", code, can)
+	}
+	if code, can := check(`{"user_id":"alice","resource":"settings","action":"update","client_ip":"198.51.100.7"}`); code != http.StatusOK || can {
+		t.Errorf("from outside: %d, %v; want 200, false", code, can)
+	}
+	if code, can := check(`{"user_id":"alice","resource":"settings","action":"update"}`); code != http.StatusOK || can {
+		t.Errorf("without a client ip: %d, %v; want 200, false", code, can)
+	}
+	if code, _ := check(`{"user_id":"alice","resource":"settings","action":"update","client_ip":"nope"}`); code != http.StatusBadRequest {
+		t.Errorf("invalid client ip: %d, want 400", code)
+	}
+}
+
+func TestCanBatchHandler(t *testing.T) {
+	ctx := context.Background()
+	mgr := rbac.NewMockRepoManager(rbac.NewMockRepo())
+	srv := NewServer(mgr)
+
+	role := &rbac.Role{Name: "editor"}
+	perm := &rbac.Permission{Resource: "docs/*", Action: rbac.ActionUpdate}
+	if err := mgr.CreateRole(ctx, role); err != nil {
+		t.Fatalf("CreateRole: %v", err)
+	}
+	if err := mgr.CreatePermission(ctx, perm); err != nil {
+		t.Fatalf("CreatePermission: %v", err)
+	}
+	if err := mgr.AssignPermissionToRole(ctx, role.ID, perm.ID); err != nil {
+		t.Fatalf("AssignPermissionToRole: %v", err)
+	}
+	if err := mgr.AssignRoleToUser(ctx, "alice", role.ID); err != nil {
+		t.Fatalf("AssignRoleToUser: %v", err)
+	}
+
+	body := `{"user_id": "alice", "checks": [
+		{"resource": "docs/1", "action": "update"},
+		{"resource": "docs/1", "action": "delete"}
+	]}`
+	rec := httptest.NewRecorder()
+	srv.CanBatchHandler(rec, httptest.NewRequest(http.MethodPost, "/users/can-batch", strings.NewReader(body)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+	var resp struct {
+		Results []struct {
+			Resource string `json:"resource"`
+			Action   string `json:"action"`
+			Allowed  bool   `json:"allowed"`
+			RoleID   string `json:"role_id"`
+		} `json:"results"`
+		Version string `json:"policy_version"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(resp.Results) != 2 || resp.Version == "" {
+		t.Fatalf("unexpected response %+v", resp)
+	}
+	if r := resp.Results[0]; r.Action != "update" || !r.Allowed || r.RoleID != role.ID {
+		t.Errorf("update result = %+v; want allowed by %s", r, role.ID)
+	}
+	if r := resp.Results[1]; r.Action != "delete" || r.Allowed {
+		t.Errorf("delete result = %+v; want denied", r)
+	}
+
+	rec = httptest.NewRecorder()
+	srv.CanBatchHandler(rec, httptest.NewRequest(http.MethodGet, "/users/can-batch", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected 405 for GET, got %d", rec.Code)
+	}
+}
+
+func TestExplainHandler(t *testing.T) {
+	ctx := context.Background()
+	mgr := rbac.NewMockRepoManager(rbac.NewMockRepo())
+	srv := NewServer(mgr)
+
+	role := &rbac.Role{Name: "reader"}
+	perm := &rbac.Permission{Resource: "docs/*", Action: rbac.ActionRead}
+	if err := mgr.CreateRole(ctx, role); err != nil {
+		t.Fatalf("CreateRole: %v", err)
+	}
+	if err := mgr.CreatePermission(ctx, perm); err != nil {
+		t.Fatalf("CreatePermission: %v", err)
+	}
+	if err := mgr.AssignPermissionToRole(ctx, role.ID, perm.ID); err != nil {
+		t.Fatalf("AssignPermissionToRole: %v", err)
+	}
+	if err := mgr.AssignRoleToUser(ctx, "alice", role.ID); err != nil {
+		t.Fatalf("AssignRoleToUser: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	srv.ExplainHandler(rec, httptest.NewRequest(http.MethodGet, "/users/explain?user_id=alice&resource=docs/1&action=read", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+	var e rbac.Explanation
+	if err := json.NewDecoder(rec.Body).Decode(&e); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if !e.Allowed || e.PermissionID != perm.ID || e.Reason == "" {
+		t.Errorf("unexpected explanation %+v", e)
+	}
+	if len(e.Roles) != 1 || e.Roles[0] != (rbac.RoleGrant{RoleID: role.ID, Via: rbac.ViaDirect}) {
+		t.Errorf("expected alice's direct role, got %+v", e.Roles)
+	}
+	if len(e.Rules) != 1 || e.Rules[0].Resource != "docs/*" {
+		t.Errorf("expected the docs/* rule, got %+v", e.Rules)
+	}
+}
+
+func TestEffectivePermissionsHandler(t *testing.T) {
+	ctx := context.Background()
+	mgr := rbac.NewMockRepoManager(rbac.NewMockRepo())
+	srv := NewServer(mgr)
+
+	role := &rbac.Role{Name: "reader"}
+	perm := &rbac.Permission{Resource: "docs/*", Action: rbac.ActionRead}
+	if err := mgr.CreateRole(ctx, role); err != nil {
+		t.Fatalf("CreateRole: %v", err)
+	}
+	if err := mgr.CreatePermission(ctx, perm); err != nil {
+		t.Fatalf("CreatePermission: %v", err)
+	}
+	if err := mgr.AssignPermissionToRole(ctx, role.ID, perm.ID); err != nil {
+		t.Fatalf("AssignPermissionToRole: %v", err)
+	}
+	if err := mgr.AssignRoleToGroup(ctx, "eng", role.ID); err != nil {
+		t.Fatalf("AssignRoleToGroup: %v", err)
+	}
+	if err := mgr.AddUserToGroup(ctx, &rbac.UserGroup{UserID: "alice", GroupName: "eng"}); err != nil {
+		t.Fatalf("AddUserToGroup: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	srv.EffectivePermissionsHandler(rec, httptest.NewRequest(http.MethodGet, "/users/effective-permissions?user_id=alice", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+	var perms []rbac.Permission
+	if err := json.NewDecoder(rec.Body).Decode(&perms); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(perms) != 1 || perms[0].ID != perm.ID {
+		t.Errorf("expected alice's group permission, got %+v", perms)
+	}
+
+	rec = httptest.NewRecorder()
+	srv.EffectivePermissionsHandler(rec, httptest.NewRequest(http.MethodGet, "/users/effective-permissions", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 without user_id, got %d", rec.Code)
+	}
+}
+
+func TestWhoCanHandler(t *testing.T) {
+	ctx := context.Background()
+	mgr := rbac.NewMockRepoManager(rbac.NewMockRepo())
+	srv := NewServer(mgr)
+
+	role := &rbac.Role{Name: "billing-admin"}
+	perm := &rbac.Permission{Resource: "billing/*", Action: rbac.ActionDelete}
+	if err := mgr.CreateRole(ctx, role); err != nil {
+		t.Fatalf("CreateRole: %v", err)
+	}
+	if err := mgr.CreatePermission(ctx, perm); err != nil {
+		t.Fatalf("CreatePermission: %v", err)
+	}
+	if err := mgr.AssignPermissionToRole(ctx, role.ID, perm.ID); err != nil {
+		t.Fatalf("AssignPermissionToRole: %v", err)
+	}
+	for _, name := range []string{"alice", "bob"} {
+		if err := mgr.CreateUser(ctx, &rbac.User{ID: name, Username: name, Email: name + "@example.com"}); err != nil {
+			t.Fatalf("CreateUser: %v", err)
+		}
+	}
+	if err := mgr.AssignRoleToUser(ctx, "alice", role.ID); err != nil {
+		t.Fatalf("AssignRoleToUser: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	srv.WhoCanHandler(rec, httptest.NewRequest(http.MethodGet, "/users/who-can?resource=billing/1&action=delete", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+	var res rbac.PageResult[*rbac.User]
+	if err := json.NewDecoder(rec.Body).Decode(&res); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(res.Items) != 1 || res.Items[0].ID != "alice" || res.NextCursor != "" {
+		t.Errorf("expected only alice, got %+v", res)
+	}
+
+	rec = httptest.NewRecorder()
+	srv.WhoCanHandler(rec, httptest.NewRequest(http.MethodGet, "/users/who-can?resource=billing/1", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 without action, got %d", rec.Code)
+	}
+}
+
+func TestBulkAssignmentHandlers(t *testing.T) {
+	ctx := context.Background()
+	mgr := rbac.NewMockRepoManager(rbac.NewMockRepo())
+	srv := NewServer(mgr)
+	post := func(h http.HandlerFunc, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h(rec, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body)))
+		return rec
+	}
+
+	if rec := post(srv.AssignRolesToUserHandler, `{"user_id": "alice", "role_ids": ["dev", "ops"]}`); rec.Code != http.StatusOK {
+		t.Fatalf("assign roles: expected 200, got %d", rec.Code)
+	}
+	if roles, err := mgr.ListRolesForUser(ctx, "alice"); err != nil || len(roles) != 2 {
+		t.Errorf("alice holds %v, %v; want dev and ops", roles, err)
+	}
+
+	if rec := post(srv.AssignPermissionsToRoleHandler, `{"role_id": "dev", "perm_ids": ["p1", "p2"]}`); rec.Code != http.StatusOK {
+		t.Fatalf("assign permissions: expected 200, got %d", rec.Code)
+	}
+	if perms, err := mgr.ListPermissionsForRole(ctx, "dev"); err != nil || len(perms) != 2 {
+		t.Errorf("dev has %v, %v; want p1 and p2", perms, err)
+	}
+
+	rec := post(srv.AddUsersToGroupHandler, `{"group_name": "team", "user_ids": ["bob", "carol"], "level": "admin"}`)
+	var resp struct {
+		UserGroupIDs []string `json:"user_group_ids"`
+	}
+	if rec.Code != http.StatusOK || json.NewDecoder(rec.Body).Decode(&resp) != nil || len(resp.UserGroupIDs) != 2 {
+		t.Fatalf("add users: unexpected response %d %+v", rec.Code, resp)
+	}
+	if rec := post(srv.AddUsersToGroupHandler, `{"group_name": "team", "user_ids": ["dave"], "level": "boss"}`); rec.Code == http.StatusOK {
+		t.Errorf("add users with an invalid level: expected an error, got 200")
+	}
+
+	if rec := post(srv.AssignRolesToUserHandler, `not json`); rec.Code != http.StatusBadRequest {
+		t.Errorf("invalid body: expected 400, got %d", rec.Code)
+	}
+}
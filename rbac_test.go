@@ -248,3 +248,94 @@ func TestCanGlobalResourceWildcard(t *testing.T) {
 		t.Errorf("expected global resource wildcard match=true, got %v, err %v", ok, err)
 	}
 }
+
+func TestCanDenyOverridesAllow(t *testing.T) {
+	ctx := context.Background()
+	fake := NewMockRepo()
+	mgr := NewMockRepoManager(fake)
+
+	// editors can do everything under /docs, but a second role carves out
+	// billing; the deny must win whichever role is checked first
+	allow := &Permission{ID: "docsAll", Resource: "/docs/**", Action: ActionAll}
+	deny := &Permission{ID: "noBilling", Resource: "/docs/billing/*", Action: ActionAll, Effect: EffectDeny}
+	_ = mgr.CreatePermission(ctx, allow)
+	_ = mgr.CreatePermission(ctx, deny)
+	_ = fake.CreateRole(ctx, &Role{ID: "editor"})
+	_ = fake.CreateRole(ctx, &Role{ID: "restricted"})
+	_ = mgr.AssignPermissionToRole(ctx, "editor", "docsAll")
+	_ = mgr.AssignPermissionToRole(ctx, "restricted", "noBilling")
+	_ = mgr.AssignRoleToUser(ctx, "user1", "editor")
+	_ = mgr.AssignRoleToUser(ctx, "user1", "restricted")
+
+	cases := []struct {
+		resource string
+		want     bool
+	}{
+		{"/docs/guide", true},
+		{"/docs/billing", true},
+		{"/docs/billing/invoice", false},
+	}
+	for _, c := range cases {
+		ok, err := mgr.Can(ctx, "user1", c.resource, ActionUpdate)
+		if err != nil {
+			t.Fatalf("Can(%s): %v", c.resource, err)
+		}
+		if ok != c.want {
+			t.Errorf("Can(%s) = %v, want %v", c.resource, ok, c.want)
+		}
+	}
+
+	// a deny on its own grants nothing
+	_ = mgr.AssignRoleToUser(ctx, "user2", "restricted")
+	ok, err := mgr.Can(ctx, "user2", "/docs/guide", ActionRead)
+	if err != nil || ok {
+		t.Errorf("expected deny-only role to grant nothing, got %v, err %v", ok, err)
+	}
+}
+
+func TestDefaultRole(t *testing.T) {
+	ctx := context.Background()
+	memory, err := NewMemoryStoreManager(ctx, "", 0)
+	if err != nil {
+		t.Fatalf("NewMemoryStoreManager: %v", err)
+	}
+	mock := NewMockRepoManager(NewMockRepo())
+	if err := mock.CreateRole(ctx, &Role{Name: "default"}); err != nil {
+		t.Fatalf("CreateRole: %v", err)
+	}
+	for name, mgr := range map[string]*Manager{"memory": memory, "mock": mock} {
+		t.Run(name, func(t *testing.T) {
+			def, err := mgr.Roles.GetRoleByName(ctx, "default")
+			if err != nil || def == nil {
+				t.Fatalf("default role = %+v, %v", def, err)
+			}
+			if err := mgr.CreatePermission(ctx, &Permission{ID: "profile-read", Resource: "profile", Action: ActionRead}); err != nil {
+				t.Fatalf("CreatePermission: %v", err)
+			}
+			if err := mgr.AssignPermissionToRole(ctx, def.ID, "profile-read"); err != nil {
+				t.Fatalf("AssignPermissionToRole: %v", err)
+			}
+
+			if ok, err := mgr.Can(ctx, "carol", "profile", ActionRead); err != nil || !ok {
+				t.Errorf("Can(carol) = %v, %v; want the default role to grant it", ok, err)
+			}
+			if roles, err := mgr.UR.ListRoles(ctx, "carol"); err != nil || len(roles) != 0 {
+				t.Errorf("store lists %v, %v for carol; want no roles", roles, err)
+			}
+			if roles, err := mgr.ListRolesForUser(ctx, "carol"); err != nil || len(roles) != 1 || roles[0] != def.ID {
+				t.Errorf("ListRolesForUser(carol) = %v, %v; want the default role", roles, err)
+			}
+			if err := mgr.AssignRoleToUser(ctx, "carol", def.ID); err != nil {
+				t.Fatalf("AssignRoleToUser: %v", err)
+			}
+			if roles, err := mgr.ListRolesForUser(ctx, "carol"); err != nil || len(roles) != 1 {
+				t.Errorf("ListRolesForUser(carol) = %v, %v; want the default role once", roles, err)
+			}
+
+			mgr.DefaultRoleName = ""
+			if ok, err := mgr.Can(ctx, "dave", "profile", ActionRead); err != nil || ok {
+				t.Errorf("Can(dave) = %v, %v with the default role disabled; want false", ok, err)
+			}
+		})
+	}
+}
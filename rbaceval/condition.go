@@ -0,0 +1,427 @@
+package rbaceval
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// ErrMissingAttribute is returned by Condition.Eval when the expression reads
+// a variable that is not set.
+var ErrMissingAttribute = errors.New("rbaceval: missing attribute")
+
+// Condition is a parsed permission condition: a small boolean expression over
+// request and user attributes, such as
+//
+//	attrs.region == user.meta.region && attrs.amount < 1000
+//
+// Operands are dotted variable paths, 'single' or "double" quoted strings,
+// numbers, true, false and [lists]. Operators, loosest first, are ||, &&, !,
+// the comparisons == != < <= > >=, and in, which tests membership of a list.
+// Numbers compare by value whatever their Go type; values of different kinds
+// are never equal, and < and friends only apply to two numbers or two
+// strings.
+type Condition struct {
+	src  string
+	root condNode
+}
+
+// ParseCondition parses src. An empty src is not a valid condition.
+func ParseCondition(src string) (*Condition, error) {
+	p := &condParser{src: src}
+	if err := p.lex(); err != nil {
+		return nil, err
+	}
+	if len(p.toks) == 0 {
+		return nil, errors.New("rbaceval: empty condition")
+	}
+	n, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.toks) {
+		return nil, p.errorf("unexpected %q", p.toks[p.pos].text)
+	}
+	return &Condition{src: src, root: n}, nil
+}
+
+// String returns the source the condition was parsed from.
+func (c *Condition) String() string { return c.src }
+
+// Eval evaluates the condition against vars, where a path such as
+// user.meta.region is looked up as vars["user"]["meta"]["region"] through
+// nested maps. It fails with ErrMissingAttribute when a path is not set and
+// with a plain error when the expression does not yield a boolean.
+func (c *Condition) Eval(vars map[string]any) (bool, error) {
+	v, err := c.root.eval(vars)
+	if err != nil {
+		return false, err
+	}
+	b, ok := v.(bool)
+	if !ok {
+		return false, fmt.Errorf("rbaceval: condition %q is not a boolean", c.src)
+	}
+	return b, nil
+}
+
+//
+// ---------- Evaluation ----------
+//
+
+type condNode interface {
+	eval(vars map[string]any) (any, error)
+}
+
+type condLiteral struct{ v any }
+
+func (n condLiteral) eval(map[string]any) (any, error) { return n.v, nil }
+
+type condPath []string
+
+func (n condPath) eval(vars map[string]any) (any, error) {
+	var cur any = vars
+	for _, seg := range n {
+		next, ok := lookup(cur, seg)
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrMissingAttribute, strings.Join(n, "."))
+		}
+		cur = next
+	}
+	return cur, nil
+}
+
+func lookup(v any, key string) (any, bool) {
+	switch m := v.(type) {
+	case map[string]any:
+		x, ok := m[key]
+		return x, ok
+	case map[string]string:
+		x, ok := m[key]
+		return x, ok
+	}
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Map || rv.Type().Key().Kind() != reflect.String {
+		return nil, false
+	}
+	x := rv.MapIndex(reflect.ValueOf(key).Convert(rv.Type().Key()))
+	if !x.IsValid() {
+		return nil, false
+	}
+	return x.Interface(), true
+}
+
+type condList []condNode
+
+func (n condList) eval(vars map[string]any) (any, error) {
+	out := make([]any, len(n))
+	for i, e := range n {
+		v, err := e.eval(vars)
+		if err != nil {
+			return nil, err
+		}
+		out[i] = v
+	}
+	return out, nil
+}
+
+type condNot struct{ x condNode }
+
+func (n condNot) eval(vars map[string]any) (any, error) {
+	b, err := evalBool(n.x, vars)
+	return !b, err
+}
+
+type condLogic struct {
+	op   string
+	l, r condNode
+}
+
+func (n condLogic) eval(vars map[string]any) (any, error) {
+	l, err := evalBool(n.l, vars)
+	if err != nil {
+		return nil, err
+	}
+	if (n.op == "&&") != l {
+		return l, nil
+	}
+	return evalBool(n.r, vars)
+}
+
+func evalBool(n condNode, vars map[string]any) (bool, error) {
+	v, err := n.eval(vars)
+	if err != nil {
+		return false, err
+	}
+	b, ok := v.(bool)
+	if !ok {
+		return false, fmt.Errorf("rbaceval: %v is not a boolean", v)
+	}
+	return b, nil
+}
+
+type condCompare struct {
+	op   string
+	l, r condNode
+}
+
+func (n condCompare) eval(vars map[string]any) (any, error) {
+	l, err := n.l.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+	r, err := n.r.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+	switch n.op {
+	case "==":
+		return equal(l, r), nil
+	case "!=":
+		return !equal(l, r), nil
+	case "in":
+		rv := reflect.ValueOf(r)
+		if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+			return nil, fmt.Errorf("rbaceval: right side of in is not a list")
+		}
+		for i := 0; i < rv.Len(); i++ {
+			if equal(l, rv.Index(i).Interface()) {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+
+	if lf, ok := number(l); ok {
+		if rf, ok := number(r); ok {
+			return order(n.op, compareFloat(lf, rf)), nil
+		}
+	}
+	ls, lok := l.(string)
+	rs, rok := r.(string)
+	if lok && rok {
+		return order(n.op, strings.Compare(ls, rs)), nil
+	}
+	return nil, fmt.Errorf("rbaceval: cannot compare %v %s %v", l, n.op, r)
+}
+
+func equal(a, b any) bool {
+	if af, ok := number(a); ok {
+		bf, ok := number(b)
+		return ok && af == bf
+	}
+	switch a.(type) {
+	case string, bool, nil:
+		return a == b
+	}
+	return false
+}
+
+func number(v any) (float64, bool) {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(rv.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), true
+	}
+	return 0, false
+}
+
+func compareFloat(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func order(op string, c int) bool {
+	switch op {
+	case "<":
+		return c < 0
+	case "<=":
+		return c <= 0
+	case ">":
+		return c > 0
+	}
+	return c >= 0
+}
+
+//
+// ---------- Parsing ----------
+//
+
+type condToken struct {
+	kind byte // 'o' operator, 's' string, 'n' number, 'i' identifier path
+	text string
+	pos  int
+}
+
+type condParser struct {
+	src  string
+	toks []condToken
+	pos  int
+}
+
+func (p *condParser) errorf(format string, args ...any) error {
+	return fmt.Errorf("rbaceval: condition %q: %s", p.src, fmt.Sprintf(format, args...))
+}
+
+func (p *condParser) lex() error {
+	s := p.src
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '\'' || c == '"':
+			j := strings.IndexByte(s[i+1:], c)
+			if j < 0 {
+				return p.errorf("unterminated string at %d", i)
+			}
+			p.toks = append(p.toks, condToken{'s', s[i+1 : i+1+j], i})
+			i += j + 2
+		case c >= '0' && c <= '9' || c == '-' && i+1 < len(s) && s[i+1] >= '0' && s[i+1] <= '9':
+			j := i + 1
+			for j < len(s) && (s[j] >= '0' && s[j] <= '9' || s[j] == '.') {
+				j++
+			}
+			p.toks = append(p.toks, condToken{'n', s[i:j], i})
+			i = j
+		case c == '_' || unicode.IsLetter(rune(c)):
+			j := i + 1
+			for j < len(s) && (s[j] == '_' || s[j] == '.' || unicode.IsLetter(rune(s[j])) || unicode.IsDigit(rune(s[j]))) {
+				j++
+			}
+			p.toks = append(p.toks, condToken{'i', s[i:j], i})
+			i = j
+		default:
+			op := ""
+			for _, o := range []string{"&&", "||", "==", "!=", "<=", ">=", "<", ">", "!", "(", ")", "[", "]", ","} {
+				if strings.HasPrefix(s[i:], o) {
+					op = o
+					break
+				}
+			}
+			if op == "" {
+				return p.errorf("unexpected %q at %d", c, i)
+			}
+			p.toks = append(p.toks, condToken{'o', op, i})
+			i += len(op)
+		}
+	}
+	return nil
+}
+
+// accept consumes the next token when it is the operator or keyword text.
+func (p *condParser) accept(text string) bool {
+	if p.pos < len(p.toks) && p.toks[p.pos].kind != 's' && p.toks[p.pos].text == text {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *condParser) or() (condNode, error) {
+	l, err := p.and()
+	for err == nil && p.accept("||") {
+		var r condNode
+		r, err = p.and()
+		l = condLogic{"||", l, r}
+	}
+	return l, err
+}
+
+func (p *condParser) and() (condNode, error) {
+	l, err := p.unary()
+	for err == nil && p.accept("&&") {
+		var r condNode
+		r, err = p.unary()
+		l = condLogic{"&&", l, r}
+	}
+	return l, err
+}
+
+func (p *condParser) unary() (condNode, error) {
+	if p.accept("!") {
+		x, err := p.unary()
+		return condNot{x}, err
+	}
+	l, err := p.operand()
+	if err != nil {
+		return nil, err
+	}
+	for _, op := range []string{"==", "!=", "<=", ">=", "<", ">", "in"} {
+		if p.accept(op) {
+			r, err := p.operand()
+			return condCompare{op, l, r}, err
+		}
+	}
+	return l, nil
+}
+
+func (p *condParser) operand() (condNode, error) {
+	if p.pos >= len(p.toks) {
+		return nil, p.errorf("unexpected end")
+	}
+	t := p.toks[p.pos]
+	p.pos++
+	switch t.kind {
+	case 's':
+		return condLiteral{t.text}, nil
+	case 'n':
+		f, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			return nil, p.errorf("bad number %q", t.text)
+		}
+		return condLiteral{f}, nil
+	case 'i':
+		switch t.text {
+		case "true":
+			return condLiteral{true}, nil
+		case "false":
+			return condLiteral{false}, nil
+		case "in":
+			return nil, p.errorf("unexpected in at %d", t.pos)
+		}
+		path := strings.Split(t.text, ".")
+		for _, seg := range path {
+			if seg == "" {
+				return nil, p.errorf("bad path %q", t.text)
+			}
+		}
+		return condPath(path), nil
+	}
+	switch t.text {
+	case "(":
+		x, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if !p.accept(")") {
+			return nil, p.errorf("missing )")
+		}
+		return x, nil
+	case "[":
+		var list condList
+		for !p.accept("]") {
+			if len(list) > 0 && !p.accept(",") {
+				return nil, p.errorf("missing , or ]")
+			}
+			x, err := p.operand()
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, x)
+		}
+		return list, nil
+	}
+	return nil, p.errorf("unexpected %q at %d", t.text, t.pos)
+}
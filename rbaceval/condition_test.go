@@ -0,0 +1,70 @@
+package rbaceval_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/Seann-Moser/rbac/rbaceval"
+)
+
+func TestCondition(t *testing.T) {
+	vars := map[string]any{
+		"attrs": map[string]any{"region": "eu", "amount": 250, "tags": []string{"a", "b"}},
+		"user":  map[string]any{"id": "alice", "meta": map[string]any{"region": "eu", "level": 3.0}},
+	}
+	cases := []struct {
+		expr string
+		want bool
+	}{
+		{`attrs.region == user.meta.region`, true},
+		{`attrs.region != "eu"`, false},
+		{`attrs.amount < 1000 && user.meta.level >= 3`, true},
+		{`attrs.amount > 1000 || user.id == 'alice'`, true},
+		{`!(attrs.region == "us")`, true},
+		{`attrs.region in ["us", "eu"]`, true},
+		{`"c" in attrs.tags`, false},
+		{`attrs.amount == "250"`, false},
+		{`true && (false || attrs.amount == 250)`, true},
+	}
+	for _, c := range cases {
+		cond, err := rbaceval.ParseCondition(c.expr)
+		if err != nil {
+			t.Fatalf("ParseCondition(%s): %v", c.expr, err)
+		}
+		got, err := cond.Eval(vars)
+		if err != nil {
+			t.Fatalf("Eval(%s): %v", c.expr, err)
+		}
+		if got != c.want {
+			t.Errorf("Eval(%s) = %v, want %v", c.expr, got, c.want)
+		}
+	}
+
+	cond, _ := rbaceval.ParseCondition(`attrs.missing == "x"`)
+	if _, err := cond.Eval(vars); !errors.Is(err, rbaceval.ErrMissingAttribute) {
+		t.Errorf("expected ErrMissingAttribute, got %v", err)
+	}
+	cond, _ = rbaceval.ParseCondition(`attrs.region`)
+	if _, err := cond.Eval(vars); err == nil {
+		t.Errorf("expected error for non-boolean condition")
+	}
+
+	for _, bad := range []string{"", `attrs.region ==`, `(true`, `"open`, `a # b`, `[1 2]`} {
+		if _, err := rbaceval.ParseCondition(bad); err == nil {
+			t.Errorf("ParseCondition(%q): expected error", bad)
+		}
+	}
+}
+
+func TestCheckConditionMissingAttribute(t *testing.T) {
+	vars := rbaceval.ConditionVars("doc", "read", map[string]any{"id": "alice"}, nil)
+	if ok, err := rbaceval.CheckCondition(`attrs.region == "eu"`, false, vars); err != nil || ok {
+		t.Errorf("allow with missing attribute: got %v, %v, want false", ok, err)
+	}
+	if ok, err := rbaceval.CheckCondition(`attrs.region == "eu"`, true, vars); err != nil || !ok {
+		t.Errorf("deny with missing attribute: got %v, %v, want true", ok, err)
+	}
+	if ok, err := rbaceval.CheckCondition("", false, vars); err != nil || !ok {
+		t.Errorf("empty condition: got %v, %v, want true", ok, err)
+	}
+}
@@ -0,0 +1,152 @@
+package rbaceval
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// ErrInvalidPattern is returned for a resource pattern with a malformed
+// named parameter.
+var ErrInvalidPattern = errors.New("rbaceval: invalid resource pattern")
+
+// HasParams reports whether a glob resource pattern has named parameters,
+// such as {project_id} in projects/{project_id}/docs/*. It is false for
+// regular expressions, whose braces are quantifiers.
+func HasParams(pattern string) bool {
+	return !IsRegex(pattern) && strings.ContainsAny(pattern, "{}")
+}
+
+// MatchResourceParams is MatchResource for patterns with named parameters.
+// A parameter matches one non-empty segment, like "*", and params maps each
+// parameter's name to the segment it matched. params is nil for patterns
+// without parameters or when resource does not match. The named groups of
+// a RegexPrefix pattern are its parameters.
+func MatchResourceParams(pattern, resource string) (params map[string]string, ok bool, err error) {
+	var re *regexp.Regexp
+	switch {
+	case IsRegex(pattern):
+		re, err = regexPattern(pattern)
+	case HasParams(pattern):
+		re, err = paramPattern(pattern)
+	default:
+		ok, err = MatchResource(pattern, resource)
+		return nil, ok, err
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	m := re.FindStringSubmatch(resource)
+	if m == nil {
+		return nil, false, nil
+	}
+	for i, name := range re.SubexpNames() {
+		if name == "" {
+			continue
+		}
+		if params == nil {
+			params = make(map[string]string, len(m)-1)
+		}
+		params[name] = m[i]
+	}
+	return params, true, nil
+}
+
+// ParamsAgree reports whether every parameter that is also a request
+// attribute matched the attribute's value, so a check on projects/42 with
+// attrs {"project_id": "7"} is not granted by projects/{project_id}/**.
+func ParamsAgree(params map[string]string, attrs map[string]any) bool {
+	for name, v := range params {
+		if want, ok := attrs[name]; ok && fmt.Sprint(want) != v {
+			return false
+		}
+	}
+	return true
+}
+
+// WithParams returns vars with params visible to conditions as params, e.g.
+// params.project_id == user.meta.project. vars is not modified.
+func WithParams(vars map[string]any, params map[string]string) map[string]any {
+	if params == nil {
+		return vars
+	}
+	p := make(map[string]any, len(params))
+	for k, v := range params {
+		p[k] = v
+	}
+	out := make(map[string]any, len(vars)+1)
+	for k, v := range vars {
+		out[k] = v
+	}
+	out["params"] = p
+	return out
+}
+
+var paramPatterns sync.Map // pattern -> *regexp.Regexp
+
+var paramName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// paramPattern compiles a pattern with parameters to an anchored regexp
+// with one named group per parameter. "**" matches anything, "*" and "?"
+// stay within a segment, and a backslash escapes the next character.
+// Character classes cannot be combined with parameters.
+func paramPattern(pattern string) (*regexp.Regexp, error) {
+	if re, ok := paramPatterns.Load(pattern); ok {
+		return re.(*regexp.Regexp), nil
+	}
+	invalid := func(format string, args ...any) error {
+		return fmt.Errorf("%w: %q: %s", ErrInvalidPattern, pattern, fmt.Sprintf(format, args...))
+	}
+	var b strings.Builder
+	b.WriteString("^")
+	seen := map[string]bool{}
+	for i := 0; i < len(pattern); i++ {
+		switch pattern[i] {
+		case '*':
+			if strings.HasPrefix(pattern[i:], "**") {
+				b.WriteString(".*")
+				i++
+			} else {
+				b.WriteString("[^/]*")
+			}
+		case '?':
+			b.WriteString("[^/]")
+		case '\\':
+			if i+1 == len(pattern) {
+				return nil, invalid("trailing backslash")
+			}
+			i++
+			b.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		case '[':
+			return nil, invalid("character classes cannot be combined with named parameters")
+		case '}':
+			return nil, invalid("unexpected '}'")
+		case '{':
+			end := strings.IndexByte(pattern[i:], '}')
+			if end < 0 {
+				return nil, invalid("unclosed '{'")
+			}
+			name := pattern[i+1 : i+end]
+			if !paramName.MatchString(name) {
+				return nil, invalid("invalid parameter name %q", name)
+			}
+			if seen[name] {
+				return nil, invalid("parameter %q appears twice", name)
+			}
+			seen[name] = true
+			b.WriteString("(?P<" + name + ">[^/]+)")
+			i += end
+		default:
+			b.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		}
+	}
+	b.WriteString("$")
+	re, err := regexp.Compile(b.String())
+	if err != nil {
+		return nil, invalid("%v", err)
+	}
+	paramPatterns.Store(pattern, re)
+	return re, nil
+}
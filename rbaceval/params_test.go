@@ -0,0 +1,64 @@
+package rbaceval_test
+
+import (
+	"errors"
+	"maps"
+	"testing"
+
+	"github.com/Seann-Moser/rbac/rbaceval"
+)
+
+func TestMatchResourceParams(t *testing.T) {
+	cases := []struct {
+		pattern, resource string
+		params            map[string]string
+		ok                bool
+	}{
+		{"projects/{project_id}/docs/*", "projects/42/docs/spec", map[string]string{"project_id": "42"}, true},
+		{"projects/{project_id}/docs/*", "projects/42/files/spec", nil, false},
+		{"projects/{project_id}/docs/*", "projects//docs/spec", nil, false},
+		{"orgs/{org}/projects/{project}/**", "orgs/acme/projects/apollo/a/b", map[string]string{"org": "acme", "project": "apollo"}, true},
+		{"orgs/{org}/**", "orgs/acme/x", map[string]string{"org": "acme"}, true},
+		{"survey.{id}", "survey.42", map[string]string{"id": "42"}, true},
+		{"users/{id}", "users/a/b", nil, false},
+		{"docs/*", "docs/1", nil, true},
+	}
+	for _, c := range cases {
+		params, ok, err := rbaceval.MatchResourceParams(c.pattern, c.resource)
+		if err != nil || ok != c.ok || !maps.Equal(params, c.params) {
+			t.Errorf("MatchResourceParams(%q, %q) = %v, %v, %v; want %v, %v", c.pattern, c.resource, params, ok, err, c.params, c.ok)
+		}
+		if ok2, _ := rbaceval.MatchResource(c.pattern, c.resource); ok2 != c.ok {
+			t.Errorf("MatchResource(%q, %q) = %v, want %v", c.pattern, c.resource, ok2, c.ok)
+		}
+	}
+
+	for _, bad := range []string{"projects/{id", "projects/id}", "projects/{}/x", "projects/{a-b}", "x/{id}/y/{id}", "x/{id}/[ab]"} {
+		if _, _, err := rbaceval.MatchResourceParams(bad, "x"); !errors.Is(err, rbaceval.ErrInvalidPattern) {
+			t.Errorf("MatchResourceParams(%q): expected ErrInvalidPattern, got %v", bad, err)
+		}
+	}
+}
+
+func TestPolicyParams(t *testing.T) {
+	policy := rbaceval.Policy{
+		Roles: []rbaceval.Role{{Name: "member", Permissions: []rbaceval.Permission{
+			{Resource: "projects/{project}/**", Action: "read", Condition: `params.project == user.meta.project`},
+		}}},
+		Users: []rbaceval.User{{ID: "alice", Roles: []string{"member"}, Meta: map[string]any{"project": "apollo"}}},
+	}
+	for _, c := range []struct {
+		resource string
+		attrs    map[string]any
+		want     bool
+	}{
+		{"projects/apollo/docs/1", nil, true},
+		{"projects/gemini/docs/1", nil, false},
+		{"projects/apollo/docs/1", map[string]any{"project": "apollo"}, true},
+		{"projects/apollo/docs/1", map[string]any{"project": "gemini"}, false},
+	} {
+		if ok, err := policy.CanWithAttributes("alice", c.resource, "read", c.attrs); err != nil || ok != c.want {
+			t.Errorf("CanWithAttributes(%s, %v) = %v, %v; want %v", c.resource, c.attrs, ok, err, c.want)
+		}
+	}
+}
@@ -0,0 +1,249 @@
+// Package rbaceval is a dependency-free, in-memory evaluator with the same
+// Can semantics as rbac.Manager. It is meant for unit tests in applications
+// that use rbac, where standing up a Manager and a store is more than the
+// test needs:
+//
+//	policy := rbaceval.Policy{
+//		Roles: []rbaceval.Role{
+//			{Name: "editor", Permissions: []rbaceval.Permission{{Resource: "survey.**", Action: "*"}}},
+//		},
+//		Users:  []rbaceval.User{{ID: "alice", Roles: []string{"editor"}}},
+//	}
+//	ok, err := policy.Can("alice", "survey.42", "update")
+package rbaceval
+
+import (
+	"errors"
+	"path"
+	"strings"
+)
+
+// Permission grants Action on Resource. Both may use the same wildcards as
+// rbac permissions. A Deny permission forbids instead, and wins over every
+// permission that grants the same request. Condition, when set, limits the
+// permission to requests where it holds; see CheckCondition.
+type Permission struct {
+	Resource  string
+	Action    string
+	Deny      bool
+	Condition string
+}
+
+// Role is a named set of permissions. Generators are permissions whose
+// Resource is a template filled in per user at check time; see
+// ExpandTemplate. When permissions of several roles match a request, the
+// role with the highest Priority decides, as with rbac.Role.Priority.
+type Role struct {
+	Name        string
+	Permissions []Permission
+	Generators  []Permission
+	Priority    int
+}
+
+// Group assigns roles to every member.
+type Group struct {
+	Name  string
+	Roles []string
+}
+
+// User lists the roles assigned directly to a user and the groups the user
+// belongs to.
+type User struct {
+	ID     string
+	Roles  []string
+	Groups []string
+	// Meta is visible to conditions as user.meta.
+	Meta map[string]any
+}
+
+// Policy is a complete, static authorization policy. Roles and groups are
+// referenced by name.
+type Policy struct {
+	Roles  []Role
+	Groups []Group
+	Users  []User
+
+	// DefaultRole, when set, is granted to every user, including users that
+	// are not listed in Users. This mirrors rbac.Manager.DefaultRoleName.
+	DefaultRole string
+}
+
+// Can reports whether userID may perform action on resource. Unknown users,
+// roles and groups simply grant nothing. An error is returned only for a
+// malformed pattern.
+func (p Policy) Can(userID, resource, action string) (bool, error) {
+	return p.CanWithAttributes(userID, resource, action, nil)
+}
+
+// CanWithAttributes is Can with request attributes for permission
+// conditions, visible to them as attrs.
+func (p Policy) CanWithAttributes(userID, resource, action string, attrs map[string]any) (bool, error) {
+	user := map[string]any{"id": userID}
+	for _, u := range p.Users {
+		if u.ID == userID && u.Meta != nil {
+			user["meta"] = u.Meta
+		}
+	}
+	vars := ConditionVars(resource, action, user, attrs)
+
+	var (
+		matched  bool
+		allow    bool
+		priority int
+	)
+	for _, roleName := range p.rolesFor(userID) {
+		for _, r := range p.Roles {
+			if r.Name != roleName {
+				continue
+			}
+			perms, err := withGenerated(r, vars)
+			if err != nil {
+				return false, err
+			}
+			for _, perm := range perms {
+				if matched && (r.Priority < priority || r.Priority == priority && (!allow || !perm.Deny)) {
+					continue
+				}
+				params, ok, err := MatchResourceParams(perm.Resource, resource)
+				if err != nil {
+					return false, err
+				}
+				if ok {
+					ok, err = path.Match(perm.Action, action)
+				}
+				if err != nil {
+					return false, err
+				}
+				if !ok || !ParamsAgree(params, attrs) {
+					continue
+				}
+				if ok, err = CheckCondition(perm.Condition, perm.Deny, WithParams(vars, params)); err != nil {
+					return false, err
+				}
+				if !ok {
+					continue
+				}
+				matched, allow, priority = true, !perm.Deny, r.Priority
+			}
+		}
+	}
+	return allow, nil
+}
+
+// withGenerated returns the role's permissions followed by its expanded
+// generators. Generators whose placeholders cannot be filled are dropped.
+func withGenerated(r Role, vars map[string]any) ([]Permission, error) {
+	if len(r.Generators) == 0 {
+		return r.Permissions, nil
+	}
+	perms := append([]Permission(nil), r.Permissions...)
+	for _, g := range r.Generators {
+		resource, ok, err := ExpandTemplate(g.Resource, vars)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			g.Resource = resource
+			perms = append(perms, g)
+		}
+	}
+	return perms, nil
+}
+
+// rolesFor collects the direct, group and default roles of userID.
+func (p Policy) rolesFor(userID string) []string {
+	var roles []string
+	for _, u := range p.Users {
+		if u.ID != userID {
+			continue
+		}
+		roles = append(roles, u.Roles...)
+		for _, groupName := range u.Groups {
+			for _, g := range p.Groups {
+				if g.Name == groupName {
+					roles = append(roles, g.Roles...)
+				}
+			}
+		}
+	}
+	if p.DefaultRole != "" {
+		roles = append(roles, p.DefaultRole)
+	}
+	return roles
+}
+
+// Match reports whether perm covers action on resource.
+func Match(perm Permission, resource, action string) (bool, error) {
+	ok, err := MatchResource(perm.Resource, resource)
+	if err != nil || !ok {
+		return false, err
+	}
+	return path.Match(perm.Action, action)
+}
+
+// MatchResource matches resource against a permission's resource pattern.
+// "**" matches zero or more segments; anything else is matched with
+// path.Match, so "*" matches a single segment. A named parameter such as
+// {project_id} matches a single segment too; see MatchResourceParams. A
+// pattern starting with RegexPrefix is a regular expression instead.
+func MatchResource(pattern, resource string) (bool, error) {
+	if IsRegex(pattern) {
+		re, err := regexPattern(pattern)
+		if err != nil {
+			return false, err
+		}
+		return re.MatchString(resource), nil
+	}
+	if HasParams(pattern) {
+		_, ok, err := MatchResourceParams(pattern, resource)
+		return ok, err
+	}
+	if strings.Contains(pattern, "**") {
+		parts := strings.SplitN(pattern, "**", 2)
+		prefix, suffix := parts[0], parts[1]
+		if !strings.HasPrefix(resource, prefix) {
+			return false, nil
+		}
+		if suffix != "" && !strings.HasSuffix(resource, suffix) {
+			return false, nil
+		}
+		if len(resource) < len(prefix)+len(suffix) {
+			return false, nil
+		}
+		return true, nil
+	}
+	return path.Match(pattern, resource)
+}
+
+// ConditionVars builds the variables conditions are evaluated against:
+// resource, action, user and attrs. A nil attrs map is left unset, so
+// conditions that read it fail with ErrMissingAttribute.
+func ConditionVars(resource, action string, user, attrs map[string]any) map[string]any {
+	vars := map[string]any{"resource": resource, "action": action}
+	if user != nil {
+		vars["user"] = user
+	}
+	if attrs != nil {
+		vars["attrs"] = attrs
+	}
+	return vars
+}
+
+// CheckCondition reports whether a permission whose resource and action
+// matched applies given its condition. An empty condition always applies.
+// A condition that reads a missing attribute fails closed: an allow does not
+// apply, but a deny does.
+func CheckCondition(condition string, deny bool, vars map[string]any) (bool, error) {
+	if condition == "" {
+		return true, nil
+	}
+	c, err := ParseCondition(condition)
+	if err != nil {
+		return false, err
+	}
+	ok, err := c.Eval(vars)
+	if errors.Is(err, ErrMissingAttribute) {
+		return deny, nil
+	}
+	return ok, err
+}
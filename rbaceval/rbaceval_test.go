@@ -0,0 +1,140 @@
+package rbaceval_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Seann-Moser/rbac"
+	"github.com/Seann-Moser/rbac/rbaceval"
+)
+
+var policy = rbaceval.Policy{
+	Roles: []rbaceval.Role{
+		{Name: "editor", Permissions: []rbaceval.Permission{
+			{Resource: "survey.**", Action: "*"},
+			{Resource: "survey.*.billing", Action: "*", Deny: true},
+		}},
+		{Name: "viewer", Permissions: []rbaceval.Permission{{Resource: "survey.*.report", Action: "read"}}},
+		{Name: "default", Permissions: []rbaceval.Permission{{Resource: "profile", Action: "read"}}},
+	},
+	Groups: []rbaceval.Group{
+		{Name: "analysts", Roles: []string{"viewer"}},
+	},
+	Users: []rbaceval.User{
+		{ID: "alice", Roles: []string{"editor"}},
+		{ID: "bob", Groups: []string{"analysts"}},
+	},
+	DefaultRole: "default",
+}
+
+var cases = []struct {
+	user, resource, action string
+	want                   bool
+}{
+	{"alice", "survey.1.report", "delete", true},
+	{"alice", "survey.1", "update", true},
+	{"alice", "survey.1.billing", "read", false},
+	{"alice", "billing", "read", false},
+	{"bob", "survey.1.report", "read", true},
+	{"bob", "survey.1.report", "update", false},
+	{"bob", "survey.1", "read", false},
+	{"carol", "profile", "read", true},
+	{"carol", "survey.1.report", "read", false},
+}
+
+func TestPolicyCan(t *testing.T) {
+	for _, c := range cases {
+		got, err := policy.Can(c.user, c.resource, c.action)
+		if err != nil {
+			t.Fatalf("Can(%s, %s, %s): %v", c.user, c.resource, c.action, err)
+		}
+		if got != c.want {
+			t.Errorf("Can(%s, %s, %s) = %v, want %v", c.user, c.resource, c.action, got, c.want)
+		}
+	}
+}
+
+// TestPolicyMatchesManager loads the same policy into a Manager and checks
+// both evaluators agree on every case.
+func TestPolicyMatchesManager(t *testing.T) {
+	ctx := context.Background()
+	repo := rbac.NewMockRepo()
+	mgr := &rbac.Manager{Perms: repo, Roles: repo, Users: repo, RP: repo, UR: repo, UG: repo, GR: repo}
+
+	roleIDs := map[string]string{}
+	for _, r := range policy.Roles {
+		role := &rbac.Role{Name: r.Name}
+		if err := mgr.CreateRole(ctx, role); err != nil {
+			t.Fatalf("CreateRole: %v", err)
+		}
+		roleIDs[r.Name] = role.ID
+		for _, p := range r.Permissions {
+			perm := &rbac.Permission{Resource: p.Resource, Action: rbac.Action(p.Action)}
+			if p.Deny {
+				perm.Effect = rbac.EffectDeny
+			}
+			if err := mgr.CreatePermission(ctx, perm); err != nil {
+				t.Fatalf("CreatePermission: %v", err)
+			}
+			if err := mgr.AssignPermissionToRole(ctx, role.ID, perm.ID); err != nil {
+				t.Fatalf("AssignPermissionToRole: %v", err)
+			}
+		}
+	}
+	for _, g := range policy.Groups {
+		for _, r := range g.Roles {
+			if err := mgr.AssignRoleToGroup(ctx, g.Name, roleIDs[r]); err != nil {
+				t.Fatalf("AssignRoleToGroup: %v", err)
+			}
+		}
+	}
+	// MockRepo does not add the default role itself, so every user that
+	// appears in the cases gets it explicitly.
+	for _, c := range cases {
+		if err := mgr.AssignRoleToUser(ctx, c.user, roleIDs[policy.DefaultRole]); err != nil {
+			t.Fatalf("AssignRoleToUser: %v", err)
+		}
+	}
+	for _, u := range policy.Users {
+		for _, r := range u.Roles {
+			if err := mgr.AssignRoleToUser(ctx, u.ID, roleIDs[r]); err != nil {
+				t.Fatalf("AssignRoleToUser: %v", err)
+			}
+		}
+		for _, g := range u.Groups {
+			if err := mgr.AddUserToGroup(ctx, &rbac.UserGroup{UserID: u.ID, GroupName: g}); err != nil {
+				t.Fatalf("AddUserToGroup: %v", err)
+			}
+		}
+	}
+
+	for _, c := range cases {
+		want, err := mgr.Can(ctx, c.user, c.resource, rbac.Action(c.action))
+		if err != nil {
+			t.Fatalf("Manager.Can: %v", err)
+		}
+		got, err := policy.Can(c.user, c.resource, c.action)
+		if err != nil {
+			t.Fatalf("Policy.Can: %v", err)
+		}
+		if got != want {
+			t.Errorf("Can(%s, %s, %s): policy %v, manager %v", c.user, c.resource, c.action, got, want)
+		}
+	}
+}
+
+func TestPolicyRolePriority(t *testing.T) {
+	p := rbaceval.Policy{
+		Roles: []rbaceval.Role{
+			{Name: "frozen", Permissions: []rbaceval.Permission{{Resource: "payments/*", Action: "*", Deny: true}}},
+			{Name: "break-glass", Priority: 10, Permissions: []rbaceval.Permission{{Resource: "payments/*", Action: "update"}}},
+		},
+		Users: []rbaceval.User{{ID: "alice", Roles: []string{"frozen", "break-glass"}}},
+	}
+	if ok, err := p.Can("alice", "payments/1", "update"); err != nil || !ok {
+		t.Errorf("update = %v, %v; want the higher-priority allow to win", ok, err)
+	}
+	if ok, _ := p.Can("alice", "payments/1", "read"); ok {
+		t.Error("expected the deny to apply where the higher-priority role does not match")
+	}
+}
@@ -0,0 +1,102 @@
+package rbaceval
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// RegexPrefix marks a resource pattern as a regular expression, for
+// resources glob syntax cannot express, e.g. re:survey\.[0-9]+\.results.
+// The expression must match the whole resource, and its named groups,
+// such as (?P<id>[0-9]+), are returned as parameters like {id}.
+const RegexPrefix = "re:"
+
+// IsRegex reports whether a resource pattern is a regular expression.
+func IsRegex(pattern string) bool {
+	return strings.HasPrefix(pattern, RegexPrefix)
+}
+
+var regexPatterns sync.Map // pattern -> *regexp.Regexp
+
+// regexPattern compiles a pattern starting with RegexPrefix, anchored at
+// both ends.
+func regexPattern(pattern string) (*regexp.Regexp, error) {
+	if re, ok := regexPatterns.Load(pattern); ok {
+		return re.(*regexp.Regexp), nil
+	}
+	expr := strings.TrimPrefix(pattern, RegexPrefix)
+	if expr == "" {
+		return nil, fmt.Errorf("%w: %q: empty regular expression", ErrInvalidPattern, pattern)
+	}
+	re, err := regexp.Compile(`^(?:` + expr + `)$`)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %q: %v", ErrInvalidPattern, pattern, err)
+	}
+	regexPatterns.Store(pattern, re)
+	return re, nil
+}
+
+// PatternRegexp returns a regular expression, anchored at both ends, that
+// matches the resources pattern matches, for systems that only take
+// regular expressions. Named parameters become named groups.
+func PatternRegexp(pattern string) (*regexp.Regexp, error) {
+	switch {
+	case IsRegex(pattern):
+		return regexPattern(pattern)
+	case HasParams(pattern):
+		return paramPattern(pattern)
+	case strings.Contains(pattern, "**"):
+		// MatchResource takes what surrounds the first ** literally
+		prefix, suffix, _ := strings.Cut(pattern, "**")
+		return regexp.Compile("^" + regexp.QuoteMeta(prefix) + ".*" + regexp.QuoteMeta(suffix) + "$")
+	}
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, fmt.Errorf("%w: %q: %v", ErrInvalidPattern, pattern, err)
+	}
+
+	// the pattern is well-formed, so escapes and classes are complete
+	quote := func(c byte) string {
+		if c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= 0x80 {
+			return string(c)
+		}
+		return `\` + string(c)
+	}
+	var b strings.Builder
+	b.WriteString("^")
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; c {
+		case '*':
+			b.WriteString("[^/]*")
+		case '?':
+			b.WriteString("[^/]")
+		case '\\':
+			i++
+			b.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		case '[':
+			b.WriteByte('[')
+			if i++; pattern[i] == '^' {
+				b.WriteByte('^')
+				i++
+			}
+			for ; pattern[i] != ']'; i++ {
+				switch pattern[i] {
+				case '\\':
+					i++
+					b.WriteString(quote(pattern[i]))
+				case '-':
+					b.WriteByte('-')
+				default:
+					b.WriteString(quote(pattern[i]))
+				}
+			}
+			b.WriteByte(']')
+		default:
+			b.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		}
+	}
+	b.WriteString("$")
+	return regexp.Compile(b.String())
+}
@@ -0,0 +1,68 @@
+package rbaceval_test
+
+import (
+	"errors"
+	"maps"
+	"testing"
+
+	"github.com/Seann-Moser/rbac/rbaceval"
+)
+
+func TestMatchResourceRegex(t *testing.T) {
+	cases := []struct {
+		pattern, resource string
+		params            map[string]string
+		ok                bool
+	}{
+		{`re:^survey\.[0-9]+\.results$`, "survey.42.results", nil, true},
+		{`re:^survey\.[0-9]+\.results$`, "survey.x.results", nil, false},
+		{`re:survey\.[0-9]+`, "survey.42.results", nil, false}, // the whole resource must match
+		{`re:survey\.[0-9]{2}`, "survey.42", nil, true},
+		{`re:survey\.[0-9]{2}`, "survey.421", nil, false},
+		{`re:reports/(2023|2024)/.*`, "reports/2024/q1/summary", nil, true},
+		{`re:projects/(?P<project>[a-z]+)/docs/[0-9]+`, "projects/apollo/docs/7", map[string]string{"project": "apollo"}, true},
+	}
+	for _, c := range cases {
+		params, ok, err := rbaceval.MatchResourceParams(c.pattern, c.resource)
+		if err != nil || ok != c.ok || !maps.Equal(params, c.params) {
+			t.Errorf("MatchResourceParams(%q, %q) = %v, %v, %v; want %v, %v", c.pattern, c.resource, params, ok, err, c.params, c.ok)
+		}
+		if ok2, _ := rbaceval.MatchResource(c.pattern, c.resource); ok2 != c.ok {
+			t.Errorf("MatchResource(%q, %q) = %v, want %v", c.pattern, c.resource, ok2, c.ok)
+		}
+	}
+	if rbaceval.HasParams(`re:x{2}`) {
+		t.Error("regex quantifiers are not named parameters")
+	}
+
+	for _, bad := range []string{"re:", "re:survey(", "re:[a-"} {
+		if _, err := rbaceval.MatchResource(bad, "x"); !errors.Is(err, rbaceval.ErrInvalidPattern) {
+			t.Errorf("MatchResource(%q): expected ErrInvalidPattern, got %v", bad, err)
+		}
+	}
+}
+
+func TestPatternRegexp(t *testing.T) {
+	resources := []string{"", "docs", "docs/1", "docs/1/2", "docs/a.b", "docs/-", "docs/x", "projects/7/docs/1", "projects/7/notes/1", "reports/2024/q1/summary"}
+	for _, pattern := range []string{
+		"docs", "docs/*", "docs/**", "docs/**/2", "docs/?", "docs/a.b", `docs/[0-9]`, `docs/[^0-9]`, `docs/[a\-]`, `docs/\*`,
+		"projects/{id}/docs/*", "projects/{id}/**", `re:reports/(2023|2024)/.*`, "*",
+	} {
+		re, err := rbaceval.PatternRegexp(pattern)
+		if err != nil {
+			t.Errorf("PatternRegexp(%q): %v", pattern, err)
+			continue
+		}
+		for _, resource := range resources {
+			want, _ := rbaceval.MatchResource(pattern, resource)
+			if got := re.MatchString(resource); got != want {
+				t.Errorf("PatternRegexp(%q) = %s matches %q: %v; MatchResource says %v", pattern, re, resource, got, want)
+			}
+		}
+	}
+	for _, bad := range []string{"docs/[", `docs/\`, "re:("} {
+		if _, err := rbaceval.PatternRegexp(bad); !errors.Is(err, rbaceval.ErrInvalidPattern) {
+			t.Errorf("PatternRegexp(%q): expected ErrInvalidPattern, got %v", bad, err)
+		}
+	}
+}
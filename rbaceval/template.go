@@ -0,0 +1,103 @@
+package rbaceval
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidTemplate is returned for a resource template with an unclosed
+// or empty placeholder.
+var ErrInvalidTemplate = errors.New("rbaceval: invalid resource template")
+
+// IsTemplate reports whether a resource contains placeholders.
+func IsTemplate(resource string) bool {
+	return strings.ContainsAny(resource, "{}")
+}
+
+// ParseTemplate checks a resource template without expanding it.
+func ParseTemplate(template string) error {
+	_, _, err := expandTemplate(template, nil, false)
+	return err
+}
+
+// ExpandTemplate fills the placeholders of a generated permission's resource
+// from the condition variables, e.g. "teams/{team}/**" for a user whose
+// meta.team is "blue" becomes "teams/blue/**". A placeholder is a dotted
+// path into vars, such as {user.id} or {attrs.project}; a bare name is
+// short for {user.meta.name}. ok is false when a placeholder's value is
+// missing, empty, not a string or number, or contains pattern characters,
+// so a generator never grants more than its template allows.
+func ExpandTemplate(template string, vars map[string]any) (resource string, ok bool, err error) {
+	return expandTemplate(template, vars, true)
+}
+
+func expandTemplate(template string, vars map[string]any, expand bool) (string, bool, error) {
+	var b strings.Builder
+	rest := template
+	for {
+		open := strings.IndexAny(rest, "{}")
+		if open < 0 {
+			b.WriteString(rest)
+			return b.String(), true, nil
+		}
+		if rest[open] == '}' {
+			return "", false, fmt.Errorf("%w: unexpected '}' in %q", ErrInvalidTemplate, template)
+		}
+		end := strings.IndexAny(rest[open+1:], "{}")
+		if end < 0 || rest[open+1+end] != '}' {
+			return "", false, fmt.Errorf("%w: unclosed '{' in %q", ErrInvalidTemplate, template)
+		}
+		name := strings.TrimSpace(rest[open+1 : open+1+end])
+		if name == "" {
+			return "", false, fmt.Errorf("%w: empty placeholder in %q", ErrInvalidTemplate, template)
+		}
+		b.WriteString(rest[:open])
+		rest = rest[open+1+end+1:]
+		if !expand {
+			continue
+		}
+
+		path := strings.Split(name, ".")
+		if len(path) == 1 {
+			path = []string{"user", "meta", name}
+		}
+		var v any = vars
+		for _, key := range path {
+			var found bool
+			if v, found = lookup(v, key); !found {
+				return "", false, nil
+			}
+		}
+		s, ok := templateValue(v)
+		if !ok {
+			return "", false, nil
+		}
+		b.WriteString(s)
+	}
+}
+
+// templateValue formats a placeholder value, rejecting values that would
+// widen the resulting pattern.
+func templateValue(v any) (string, bool) {
+	var s string
+	switch x := v.(type) {
+	case string:
+		s = x
+	case int:
+		s = strconv.Itoa(x)
+	case int32:
+		s = strconv.FormatInt(int64(x), 10)
+	case int64:
+		s = strconv.FormatInt(x, 10)
+	case float64:
+		s = strconv.FormatFloat(x, 'f', -1, 64)
+	default:
+		return "", false
+	}
+	if s == "" || strings.ContainsAny(s, `*?[]\{}`) {
+		return "", false
+	}
+	return s, true
+}
@@ -0,0 +1,68 @@
+package rbaceval_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/Seann-Moser/rbac/rbaceval"
+)
+
+func TestExpandTemplate(t *testing.T) {
+	vars := map[string]any{
+		"attrs": map[string]any{"project": "apollo"},
+		"user": map[string]any{"id": "alice", "meta": map[string]any{
+			"team": "blue", "level": 3.0, "wild": "*", "empty": "",
+		}},
+	}
+	cases := []struct {
+		template string
+		want     string
+		ok       bool
+	}{
+		{"teams/{team}/**", "teams/blue/**", true},
+		{"teams/{user.meta.team}/projects/{attrs.project}", "teams/blue/projects/apollo", true},
+		{"users/{user.id}/*", "users/alice/*", true},
+		{"levels/{level}", "levels/3", true},
+		{"static/*", "static/*", true},
+		{"teams/{region}/**", "", false},
+		{"teams/{wild}/**", "", false},
+		{"teams/{empty}/**", "", false},
+	}
+	for _, c := range cases {
+		got, ok, err := rbaceval.ExpandTemplate(c.template, vars)
+		if err != nil {
+			t.Fatalf("ExpandTemplate(%s): %v", c.template, err)
+		}
+		if ok != c.ok || got != c.want {
+			t.Errorf("ExpandTemplate(%s) = %q, %v; want %q, %v", c.template, got, ok, c.want, c.ok)
+		}
+	}
+
+	for _, bad := range []string{"teams/{team/**", "teams/}", "teams/{}/x"} {
+		if err := rbaceval.ParseTemplate(bad); !errors.Is(err, rbaceval.ErrInvalidTemplate) {
+			t.Errorf("ParseTemplate(%s) = %v, want ErrInvalidTemplate", bad, err)
+		}
+	}
+}
+
+func TestPolicyGenerators(t *testing.T) {
+	p := rbaceval.Policy{
+		Roles: []rbaceval.Role{{
+			Name:       "team-member",
+			Generators: []rbaceval.Permission{{Resource: "teams/{team}/**", Action: "read"}},
+		}},
+		Users: []rbaceval.User{
+			{ID: "alice", Roles: []string{"team-member"}, Meta: map[string]any{"team": "blue"}},
+			{ID: "bob", Roles: []string{"team-member"}},
+		},
+	}
+	if ok, err := p.Can("alice", "teams/blue/docs", "read"); err != nil || !ok {
+		t.Errorf("own team = %v, %v; want true", ok, err)
+	}
+	if ok, _ := p.Can("bob", "teams/blue/docs", "read"); ok {
+		t.Error("expected a user without meta.team to get nothing from the generator")
+	}
+	if ok, _ := p.Can("alice", "teams/red/docs", "read"); ok {
+		t.Error("expected another team's resources to be denied")
+	}
+}
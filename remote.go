@@ -0,0 +1,462 @@
+// file: rbac/remote_store.go
+package rbac
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+var _ Store = (*RemoteStore)(nil)
+var _ HealthChecker = (*RemoteStore)(nil)
+
+// RemoteError is returned when the RBAC service answers with an error status.
+type RemoteError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *RemoteError) Error() string {
+	return fmt.Sprintf("remote_store: %d %s", e.StatusCode, e.Message)
+}
+
+//
+// ---------- RemoteStore Core ----------
+//
+
+// RemoteStore implements every repository by calling a central rbacServer
+// over HTTP, so a service can run a local Manager against the shared policy
+// without a database of its own. Writes go through the server's Manager and
+// are recorded there.
+type RemoteStore struct {
+	baseURL string
+	client  *http.Client
+	header  http.Header
+	ids     IDGenerator
+}
+
+// NewRemoteStore creates a client for the rbacServer at baseURL (for example
+// "https://rbac.internal/") and checks that it answers. header is sent with
+// every request, e.g. the Authorization or X-API-Key header the server's
+// middleware expects; credentials that rotate belong in the client's
+// Transport instead. A nil client uses http.DefaultClient.
+func NewRemoteStore(ctx context.Context, baseURL string, client *http.Client, header http.Header) (*RemoteStore, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("remote_store: invalid base URL %q", baseURL)
+	}
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	s := &RemoteStore{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		client:  client,
+		header:  header.Clone(),
+	}
+	if _, err := s.ListAllRoles(ctx); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// SetIDGenerator makes the store assign IDs before sending new entities.
+// Without one the server assigns them.
+func (s *RemoteStore) SetIDGenerator(g IDGenerator) {
+	s.ids = g
+}
+
+// Health implements HealthChecker with the server's /healthz, so it fails
+// when the server's own stores do. Servers without /healthz are probed with
+// a role lookup.
+func (s *RemoteStore) Health(ctx context.Context) error {
+	found, err := s.call(ctx, http.MethodGet, "/healthz", nil, nil, nil)
+	if err == nil && !found {
+		_, err = s.GetRoleByID(ctx, "")
+	}
+	return err
+}
+
+// NewRemoteStoreManager wraps the store in a Manager and seeds the default
+// role on the server if it is missing.
+func NewRemoteStoreManager(ctx context.Context, baseURL string, client *http.Client, header http.Header) (*Manager, error) {
+	s, err := NewRemoteStore(ctx, baseURL, client, header)
+	if err != nil {
+		return nil, err
+	}
+
+	def, _ := s.GetRoleByName(ctx, "default")
+	if def == nil {
+		def = &Role{Name: "default", Description: "Default role"}
+		if createErr := s.CreateRole(ctx, def); createErr != nil {
+			return nil, fmt.Errorf("failed to create default role: %w", createErr)
+		}
+	}
+
+	return &Manager{
+		Perms:           s,
+		Roles:           s,
+		Users:           s,
+		RP:              s,
+		UR:              s,
+		UG:              s,
+		GR:              s,
+		DefaultRoleName: "default",
+	}, nil
+}
+
+// call sends a request and decodes the JSON response into out. A 404 is
+// reported as found == false rather than as an error, which is how the
+// server says an entity does not exist.
+func (s *RemoteStore) call(ctx context.Context, method, path string, query url.Values, body, out interface{}) (found bool, err error) {
+	target := s.baseURL + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+
+	var rd io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return false, err
+		}
+		rd = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target, rd)
+	if err != nil {
+		return false, err
+	}
+	for k, v := range s.header {
+		req.Header[k] = v
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return false, fmt.Errorf("remote_store: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return false, nil
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		var e struct {
+			Error string `json:"error"`
+		}
+		_ = json.NewDecoder(resp.Body).Decode(&e)
+		if e.Error == "" {
+			e.Error = http.StatusText(resp.StatusCode)
+		}
+		return false, &RemoteError{StatusCode: resp.StatusCode, Message: e.Error}
+	}
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			return false, fmt.Errorf("remote_store: decode %s: %w", path, err)
+		}
+	}
+	return true, nil
+}
+
+// write sends a mutation; a 404 from a write endpoint is an error.
+func (s *RemoteStore) write(ctx context.Context, method, path string, query url.Values, body, out interface{}) error {
+	found, err := s.call(ctx, method, path, query, body, out)
+	if err == nil && !found {
+		err = &RemoteError{StatusCode: http.StatusNotFound, Message: path + " not found"}
+	}
+	return err
+}
+
+func idQuery(key, id string) url.Values {
+	return url.Values{key: {id}}
+}
+
+//
+// ---------- UserRepo ----------
+//
+
+func (s *RemoteStore) GetUserByID(ctx context.Context, id string) (*User, error) {
+	u := &User{}
+	found, err := s.call(ctx, http.MethodGet, "/users/get", idQuery("id", id), nil, u)
+	if err != nil || !found {
+		return nil, err
+	}
+	return u, nil
+}
+
+func (s *RemoteStore) ListAllUsers(ctx context.Context) ([]*User, error) {
+	var out []*User
+	_, err := s.call(ctx, http.MethodGet, "/users/get-all", nil, nil, &out)
+	return out, err
+}
+
+func (s *RemoteStore) GetUserByMeta(ctx context.Context, meta map[string]interface{}) (*User, error) {
+	u := &User{}
+	found, err := s.call(ctx, http.MethodPost, "/users/find", nil, meta, u)
+	if err != nil || !found {
+		return nil, err
+	}
+	return u, nil
+}
+
+func (s *RemoteStore) CreateUser(ctx context.Context, u *User) error {
+	if u.ID == "" && s.ids != nil {
+		u.ID = generateID(s.ids, KindUser)
+	}
+	var resp struct {
+		UserID string `json:"user_id"`
+	}
+	if err := s.write(ctx, http.MethodPost, "/users/create", nil, u, &resp); err != nil {
+		return err
+	}
+	u.ID = resp.UserID
+	return nil
+}
+
+func (s *RemoteStore) DeleteUser(ctx context.Context, id string) error {
+	return s.write(ctx, http.MethodDelete, "/users/delete", idQuery("id", id), nil, nil)
+}
+
+func (s *RemoteStore) GetGroupsByUserID(ctx context.Context, userID string) ([]*UserGroup, error) {
+	var out []*UserGroup
+	_, err := s.call(ctx, http.MethodGet, "/users/list-groups", idQuery("user_id", userID), nil, &out)
+	return out, err
+}
+
+//
+// ---------- PermissionRepo ----------
+//
+
+func (s *RemoteStore) GetPermissionByID(ctx context.Context, id string) (*Permission, error) {
+	p := &Permission{}
+	found, err := s.call(ctx, http.MethodGet, "/permissions/get", idQuery("id", id), nil, p)
+	if err != nil || !found {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (s *RemoteStore) ListAllPermissions(ctx context.Context) ([]*Permission, error) {
+	var out []*Permission
+	_, err := s.call(ctx, http.MethodGet, "/permissions/get-all", nil, nil, &out)
+	return out, err
+}
+
+func (s *RemoteStore) GetPermissionByResource(ctx context.Context, resource string, action Action) (*Permission, error) {
+	p := &Permission{}
+	q := url.Values{"resource": {resource}, "action": {string(action)}}
+	found, err := s.call(ctx, http.MethodGet, "/permissions/get-by-resource", q, nil, p)
+	if err != nil || !found {
+		return nil, err
+	}
+	return p, nil
+}
+
+// CreatePermission fills p from the server afterwards, since the server
+// returns the existing permission for a duplicate resource and action.
+func (s *RemoteStore) CreatePermission(ctx context.Context, p *Permission) error {
+	if p.ID == "" && s.ids != nil {
+		p.ID = generateID(s.ids, KindPermission)
+	}
+	var resp struct {
+		PermissionID string `json:"permission_id"`
+	}
+	if err := s.write(ctx, http.MethodPost, "/permissions/create", nil, p, &resp); err != nil {
+		return err
+	}
+	stored, err := s.GetPermissionByID(ctx, resp.PermissionID)
+	if err != nil {
+		return err
+	}
+	if stored == nil {
+		p.ID = resp.PermissionID
+		return nil
+	}
+	*p = *stored
+	return nil
+}
+
+func (s *RemoteStore) DeletePermission(ctx context.Context, id string) error {
+	return s.write(ctx, http.MethodDelete, "/permissions/delete", idQuery("id", id), nil, nil)
+}
+
+//
+// ---------- RoleRepo ----------
+//
+
+func (s *RemoteStore) CreateRole(ctx context.Context, r *Role) error {
+	if r.ID == "" && s.ids != nil {
+		r.ID = generateID(s.ids, KindRole)
+	}
+	var resp struct {
+		RoleID string `json:"role_id"`
+	}
+	if err := s.write(ctx, http.MethodPost, "/roles/create", nil, r, &resp); err != nil {
+		return err
+	}
+	r.ID = resp.RoleID
+	return nil
+}
+
+func (s *RemoteStore) GetRoleByName(ctx context.Context, name string) (*Role, error) {
+	r := &Role{}
+	found, err := s.call(ctx, http.MethodGet, "/roles/get-by-name", idQuery("name", name), nil, r)
+	if err != nil || !found {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (s *RemoteStore) GetRoleByID(ctx context.Context, id string) (*Role, error) {
+	r := &Role{}
+	found, err := s.call(ctx, http.MethodGet, "/roles/get", idQuery("id", id), nil, r)
+	if err != nil || !found {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (s *RemoteStore) DeleteRole(ctx context.Context, id string) error {
+	return s.write(ctx, http.MethodDelete, "/roles/delete", idQuery("id", id), nil, nil)
+}
+
+func (s *RemoteStore) ListAllRoles(ctx context.Context) ([]*Role, error) {
+	var out []*Role
+	_, err := s.call(ctx, http.MethodGet, "/roles/get-all", nil, nil, &out)
+	return out, err
+}
+
+//
+// ---------- RolePermissionRepo ----------
+//
+
+type remoteRolePermission struct {
+	RoleID string `json:"role_id"`
+	PermID string `json:"perm_id"`
+}
+
+func (s *RemoteStore) AddRP(ctx context.Context, roleID, permID string) error {
+	return s.write(ctx, http.MethodPost, "/permissions/assign-to-role", nil, remoteRolePermission{roleID, permID}, nil)
+}
+
+func (s *RemoteStore) Remove(ctx context.Context, roleID, permID string) error {
+	return s.write(ctx, http.MethodPost, "/permissions/remove-from-role", nil, remoteRolePermission{roleID, permID}, nil)
+}
+
+func (s *RemoteStore) ListPermissions(ctx context.Context, roleID string) ([]string, error) {
+	var out []string
+	_, err := s.call(ctx, http.MethodGet, "/permissions/list-for-role", idQuery("role_id", roleID), nil, &out)
+	return out, err
+}
+
+//
+// ---------- UserRoleRepo ----------
+//
+
+type remoteUserRole struct {
+	UserID string `json:"user_id"`
+	RoleID string `json:"role_id"`
+}
+
+func (s *RemoteStore) AddUR(ctx context.Context, userID, roleID string) error {
+	return s.write(ctx, http.MethodPost, "/users/assign-role", nil, remoteUserRole{userID, roleID}, nil)
+}
+
+func (s *RemoteStore) RemoveUR(ctx context.Context, userID, roleID string) error {
+	return s.write(ctx, http.MethodPost, "/users/unassign-role", nil, remoteUserRole{userID, roleID}, nil)
+}
+
+// ListRoles returns the server's answer, which includes the server's
+// default role.
+func (s *RemoteStore) ListRoles(ctx context.Context, userID string) ([]string, error) {
+	var out []string
+	_, err := s.call(ctx, http.MethodGet, "/users/list-roles", idQuery("user_id", userID), nil, &out)
+	return out, err
+}
+
+func (s *RemoteStore) ListUsersForRole(ctx context.Context, roleID string) ([]string, error) {
+	var out []string
+	_, err := s.call(ctx, http.MethodGet, "/roles/list-users", idQuery("role_id", roleID), nil, &out)
+	return out, err
+}
+
+//
+// ---------- UserGroupRepo ----------
+//
+
+type remoteUserGroup struct {
+	ID        string `json:"id,omitempty"`
+	GroupID   string `json:"group_id"`
+	UserID    string `json:"user_id"`
+	GroupName string `json:"group_name"`
+	TenantID  string `json:"tenant_id,omitempty"`
+}
+
+func (s *RemoteStore) AddUserToGroup(ctx context.Context, ug *UserGroup) error {
+	if ug.UserID == "" {
+		return errors.New("user id is empty")
+	}
+	if ug.ID == "" && s.ids != nil {
+		ug.ID = generateID(s.ids, KindUserGroup)
+	}
+	var resp struct {
+		UserGroupID string `json:"user_group_id"`
+	}
+	body := remoteUserGroup{ID: ug.ID, GroupID: ug.GroupName, UserID: ug.UserID, GroupName: ug.GroupName, TenantID: ug.TenantID}
+	if err := s.write(ctx, http.MethodPost, "/users/add-to-group", nil, body, &resp); err != nil {
+		return err
+	}
+	ug.ID = resp.UserGroupID
+	return nil
+}
+
+func (s *RemoteStore) RemoveUserFromGroup(ctx context.Context, groupName string, ug *UserGroup) error {
+	if ug.UserID == "" {
+		return errors.New("user id is empty")
+	}
+	body := remoteUserGroup{GroupID: groupName, UserID: ug.UserID, GroupName: groupName}
+	return s.write(ctx, http.MethodPost, "/users/remove-from-group", nil, body, nil)
+}
+
+func (s *RemoteStore) GetUsersByGroupID(ctx context.Context, groupName string) ([]*UserGroup, error) {
+	var out []*UserGroup
+	_, err := s.call(ctx, http.MethodGet, "/users/list-by-group", idQuery("group_id", groupName), nil, &out)
+	return out, err
+}
+
+//
+// ---------- GroupRoleRepo ----------
+//
+
+type remoteGroupRole struct {
+	GroupID string `json:"group_id"`
+	RoleID  string `json:"role_id"`
+}
+
+func (s *RemoteStore) AddRoleToGroup(ctx context.Context, groupID, roleID string) error {
+	return s.write(ctx, http.MethodPost, "/roles/assign-to-group", nil, remoteGroupRole{groupID, roleID}, nil)
+}
+
+func (s *RemoteStore) RemoveRoleFromGroup(ctx context.Context, groupID, roleID string) error {
+	return s.write(ctx, http.MethodPost, "/roles/unassign-from-group", nil, remoteGroupRole{groupID, roleID}, nil)
+}
+
+func (s *RemoteStore) ListRolesForGroup(ctx context.Context, groupID string) ([]string, error) {
+	var out []string
+	_, err := s.call(ctx, http.MethodGet, "/roles/list-for-group", idQuery("group_id", groupID), nil, &out)
+	return out, err
+}
+
+func (s *RemoteStore) ListGroupsForRole(ctx context.Context, roleID string) ([]string, error) {
+	var out []string
+	_, err := s.call(ctx, http.MethodGet, "/roles/list-groups", idQuery("role_id", roleID), nil, &out)
+	return out, err
+}
@@ -0,0 +1,392 @@
+package rbac
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+var (
+	replicaEvents metric.Int64Counter
+	replicaLag    metric.Float64Histogram
+)
+
+func init() {
+	replicaEvents, _ = meter.Int64Counter(
+		"rbac_replica_events_total",
+		metric.WithDescription("Change events handled by a Replicator, by kind and result"),
+	)
+	replicaLag, _ = meter.Float64Histogram(
+		"rbac_replica_lag_seconds",
+		metric.WithDescription("Delay between a change committing on the source and being applied to the standby"),
+	)
+}
+
+// ErrPromoted is returned by Run once the Replicator has been promoted.
+var ErrPromoted = errors.New("rbac: replica has been promoted")
+
+// errReplicaSkipped marks events a Replicator cannot apply: those without
+// the IDs of the record they changed, and kinds it does not replicate.
+var errReplicaSkipped = errors.New("rbac: change event cannot be replicated")
+
+// ReplicatorConfig tunes a Replicator. Zero values select the defaults.
+type ReplicatorConfig struct {
+	// Attempts bounds how often an event is applied before it is reported
+	// to OnError and skipped. Defaults to 3.
+	Attempts int
+	// RetryInterval is the wait between attempts and before re-opening a
+	// change stream that ended. Defaults to 1s.
+	RetryInterval time.Duration
+	// OnError is called with every event that could not be applied.
+	// Defaults to logging it.
+	OnError func(ev ChangeEvent, err error)
+}
+
+// Replicator keeps a warm standby Store in step with a source by tailing
+// the source's change events and copying each changed record across. The
+// standby may be a different backend, e.g. a MemoryStore snapshot or a
+// PostgresStore in another region.
+//
+// Events carry IDs rather than records, so each one is applied by reading
+// the record's current state from the source. The standby must start from a
+// copy of the source; changes made while no stream is open are not seen.
+// Entities have no update path in the repositories, so update events for
+// an entity the standby already has leave it as it is.
+type Replicator struct {
+	source  Store
+	watcher Watcher
+	target  Store
+	cfg     ReplicatorConfig
+
+	mu       sync.Mutex
+	cancel   context.CancelFunc
+	done     chan struct{}
+	promoted bool
+
+	applied     atomic.Uint64
+	failed      atomic.Uint64
+	lastApplied atomic.Int64 // unix nanoseconds
+	lag         atomic.Int64 // nanoseconds
+}
+
+// NewReplicator returns a Replicator that applies changes streamed by
+// watcher, read from source, to target. watcher is typically source itself.
+func NewReplicator(source Store, watcher Watcher, target Store, cfg ReplicatorConfig) *Replicator {
+	if cfg.Attempts <= 0 {
+		cfg.Attempts = 3
+	}
+	if cfg.RetryInterval <= 0 {
+		cfg.RetryInterval = time.Second
+	}
+	if cfg.OnError == nil {
+		cfg.OnError = func(ev ChangeEvent, err error) {
+			log.Printf("replica: %s %s: %v", ev.Kind, ev.Op, err)
+		}
+	}
+	return &Replicator{source: source, watcher: watcher, target: target, cfg: cfg}
+}
+
+// ReplicationStatus reports a Replicator's progress.
+type ReplicationStatus struct {
+	Applied     uint64
+	Failed      uint64
+	LastApplied time.Time
+	// Lag is the delay of the most recently applied event: from the commit
+	// on the source when the store reports it, otherwise from its receipt.
+	Lag      time.Duration
+	Promoted bool
+}
+
+// Status returns the Replicator's progress so far.
+func (r *Replicator) Status() ReplicationStatus {
+	r.mu.Lock()
+	promoted := r.promoted
+	r.mu.Unlock()
+	st := ReplicationStatus{
+		Applied:  r.applied.Load(),
+		Failed:   r.failed.Load(),
+		Lag:      time.Duration(r.lag.Load()),
+		Promoted: promoted,
+	}
+	if ns := r.lastApplied.Load(); ns != 0 {
+		st.LastApplied = time.Unix(0, ns)
+	}
+	return st
+}
+
+// Run tails the source until ctx is cancelled or the Replicator is
+// promoted, re-opening the change stream whenever it ends. It returns
+// ErrPromoted after a promotion and ctx's error otherwise.
+func (r *Replicator) Run(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	r.mu.Lock()
+	if r.promoted {
+		r.mu.Unlock()
+		return ErrPromoted
+	}
+	if r.done != nil {
+		r.mu.Unlock()
+		return errors.New("rbac: replicator is already running")
+	}
+	done := make(chan struct{})
+	r.cancel, r.done = cancel, done
+	r.mu.Unlock()
+	defer close(done)
+
+	for {
+		events, err := r.watcher.Watch(ctx)
+		if err == nil {
+			for ev := range events {
+				r.handle(ctx, ev)
+			}
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		if err != nil {
+			log.Printf("replica: watch: %v", err)
+		} else {
+			log.Printf("replica: change stream ended, reopening; changes made meanwhile are not replicated")
+		}
+		select {
+		case <-ctx.Done():
+		case <-time.After(r.cfg.RetryInterval):
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cancel, r.done = nil, nil
+	if r.promoted {
+		return ErrPromoted
+	}
+	return ctx.Err()
+}
+
+// Promote stops replication, waiting for the event being applied to
+// finish, and returns a Manager over the standby so it can take over from
+// the source. The default role is created on the standby if it is missing.
+func (r *Replicator) Promote(ctx context.Context) (*Manager, error) {
+	r.mu.Lock()
+	r.promoted = true
+	cancel, done := r.cancel, r.done
+	r.mu.Unlock()
+	if cancel != nil {
+		cancel()
+		select {
+		case <-done:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	def, _ := r.target.GetRoleByName(ctx, "default")
+	if def == nil {
+		def = &Role{Name: "default", Description: "Default role"}
+		if err := r.target.CreateRole(ctx, def); err != nil {
+			return nil, fmt.Errorf("failed to create default role: %w", err)
+		}
+	}
+	return &Manager{
+		Perms:           r.target,
+		Roles:           r.target,
+		Users:           r.target,
+		RP:              r.target,
+		UR:              r.target,
+		UG:              r.target,
+		GR:              r.target,
+		DefaultRoleName: "default",
+	}, nil
+}
+
+// handle applies ev, retrying failures, and records the outcome.
+func (r *Replicator) handle(ctx context.Context, ev ChangeEvent) {
+	received := time.Now()
+	var err error
+	for attempt := 1; ; attempt++ {
+		err = r.Apply(ctx, ev)
+		if err == nil || errors.Is(err, errReplicaSkipped) || attempt >= r.cfg.Attempts || ctx.Err() != nil {
+			break
+		}
+		select {
+		case <-ctx.Done():
+		case <-time.After(r.cfg.RetryInterval):
+		}
+	}
+
+	result := "applied"
+	switch {
+	case errors.Is(err, errReplicaSkipped):
+		result = "skipped"
+	case err != nil:
+		result = "failed"
+	}
+	replicaEvents.Add(ctx, 1, metric.WithAttributes(attribute.String("kind", ev.Kind), attribute.String("result", result)))
+	if err != nil {
+		r.failed.Add(1)
+		if ctx.Err() == nil {
+			r.cfg.OnError(ev, err)
+		}
+		return
+	}
+
+	now := time.Now()
+	since := received
+	if !ev.At.IsZero() {
+		since = ev.At
+	}
+	lag := max(now.Sub(since), 0)
+	replicaLag.Record(ctx, lag.Seconds())
+	r.applied.Add(1)
+	r.lastApplied.Store(now.UnixNano())
+	r.lag.Store(int64(lag))
+}
+
+// Apply copies the record ev refers to from the source to the standby, or
+// removes it from the standby when it no longer exists on the source.
+// Applying an event twice has no further effect.
+func (r *Replicator) Apply(ctx context.Context, ev ChangeEvent) error {
+	switch ev.Kind {
+	case KindPermission:
+		if ev.ID == "" {
+			break
+		}
+		return replicateEntity(ctx, ev.ID, r.source.GetPermissionByID, r.target.GetPermissionByID,
+			r.target.CreatePermission, r.target.DeletePermission)
+	case KindRole:
+		if ev.ID == "" {
+			break
+		}
+		return replicateEntity(ctx, ev.ID, r.source.GetRoleByID, r.target.GetRoleByID,
+			r.target.CreateRole, r.target.DeleteRole)
+	case KindUser:
+		if ev.ID == "" {
+			break
+		}
+		return replicateEntity(ctx, ev.ID, r.source.GetUserByID, r.target.GetUserByID,
+			r.target.CreateUser, r.target.DeleteUser)
+	case KindRolePermission:
+		if ev.RoleID == "" || ev.PermissionID == "" {
+			break
+		}
+		if ev.Op == ChangeDelete {
+			return r.target.Remove(ctx, ev.RoleID, ev.PermissionID)
+		}
+		return r.target.AddRP(r.edgeContext(ctx, ev.Kind, ev.RoleID, ev.PermissionID), ev.RoleID, ev.PermissionID)
+	case KindUserRole:
+		if ev.UserID == "" || ev.RoleID == "" {
+			break
+		}
+		if ev.Op == ChangeDelete {
+			return r.target.RemoveUR(ctx, ev.UserID, ev.RoleID)
+		}
+		return r.replicateUserRole(r.edgeContext(ctx, ev.Kind, ev.UserID, ev.RoleID), ev.UserID, ev.RoleID)
+	case KindUserGroup:
+		if ev.UserID == "" || ev.GroupName == "" {
+			break
+		}
+		if ev.Op == ChangeDelete {
+			return r.target.RemoveUserFromGroup(ctx, ev.GroupName, &UserGroup{UserID: ev.UserID})
+		}
+		return r.replicateMembership(r.edgeContext(ctx, ev.Kind, ev.UserID, ev.GroupName), ev.UserID, ev.GroupName)
+	case KindGroupRole:
+		if ev.GroupName == "" || ev.RoleID == "" {
+			break
+		}
+		if ev.Op == ChangeDelete {
+			return r.target.RemoveRoleFromGroup(ctx, ev.GroupName, ev.RoleID)
+		}
+		return r.target.AddRoleToGroup(r.edgeContext(ctx, ev.Kind, ev.GroupName, ev.RoleID), ev.GroupName, ev.RoleID)
+	}
+	return errReplicaSkipped
+}
+
+// edgeContext attributes the standby's copy of an edge to the source that
+// manages it on the source store.
+func (r *Replicator) edgeContext(ctx context.Context, kind, from, to string) context.Context {
+	repo, ok := r.source.(EdgeSourceRepo)
+	if !ok {
+		return ctx
+	}
+	if src, err := repo.EdgeSource(ctx, kind, from, to); err == nil && src != "" {
+		return WithAssignmentSource(ctx, src)
+	}
+	return ctx
+}
+
+// replicateEntity creates the source's copy of id on the target when the
+// target lacks it, and deletes the target's copy when the source no longer
+// has one.
+func replicateEntity[T any](
+	ctx context.Context, id string,
+	get, getTarget func(context.Context, string) (*T, error),
+	create func(context.Context, *T) error,
+	del func(context.Context, string) error,
+) error {
+	rec, err := get(ctx, id)
+	if err != nil {
+		return err
+	}
+	if rec == nil {
+		return del(ctx, id)
+	}
+	have, err := getTarget(ctx, id)
+	if err != nil || have != nil {
+		return err
+	}
+	cp := *rec
+	return create(ctx, &cp)
+}
+
+// replicateUserRole copies a user role assignment, with its window when
+// both stores support scheduling.
+func (r *Replicator) replicateUserRole(ctx context.Context, userID, roleID string) error {
+	src, ok1 := r.source.(ScheduledUserRoleRepo)
+	dst, ok2 := r.target.(ScheduledUserRoleRepo)
+	if ok1 && ok2 {
+		assignments, err := src.ListRoleAssignments(ctx, userID)
+		if err != nil {
+			return err
+		}
+		for _, a := range assignments {
+			if a.RoleID == roleID && (a.NotBefore != 0 || a.ExpiresAt != 0) {
+				return dst.AddScheduledUR(ctx, a)
+			}
+		}
+	}
+	return r.target.AddUR(ctx, userID, roleID)
+}
+
+// replicateMembership copies the source's membership of userID in
+// groupName, if it still exists.
+func (r *Replicator) replicateMembership(ctx context.Context, userID, groupName string) error {
+	groups, err := r.source.GetGroupsByUserID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	for _, ug := range groups {
+		if ug.GroupName != groupName {
+			continue
+		}
+		have, err := r.target.GetGroupsByUserID(ctx, userID)
+		if err != nil {
+			return err
+		}
+		for _, h := range have {
+			if h.GroupName == groupName {
+				return nil
+			}
+		}
+		cp := *ug
+		return r.target.AddUserToGroup(ctx, &cp)
+	}
+	return nil
+}
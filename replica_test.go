@@ -0,0 +1,108 @@
+package rbac
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// ctxWatcher streams the events sent on its channel, closing the stream
+// when ctx is done as real stores do.
+type ctxWatcher chan ChangeEvent
+
+func (w ctxWatcher) Watch(ctx context.Context) (<-chan ChangeEvent, error) {
+	out := make(chan ChangeEvent)
+	go func() {
+		defer close(out)
+		for {
+			select {
+			case ev := <-w:
+				select {
+				case out <- ev:
+				case <-ctx.Done():
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out, nil
+}
+
+func TestReplicator(t *testing.T) {
+	ctx := context.Background()
+	source, _ := NewMemoryStore(ctx, "")
+	target, _ := NewMemoryStore(ctx, "")
+	events := make(ctxWatcher)
+	var failures []ChangeEvent
+	r := NewReplicator(source, events, target, ReplicatorConfig{
+		Attempts: 1,
+		OnError:  func(ev ChangeEvent, err error) { failures = append(failures, ev) },
+	})
+
+	runErr := make(chan error, 1)
+	go func() { runErr <- r.Run(ctx) }()
+
+	_ = source.CreatePermission(ctx, &Permission{ID: "p1", Resource: "doc", Action: ActionRead})
+	_ = source.CreateRole(ctx, &Role{ID: "r1", Name: "reader"})
+	_ = source.AddRP(ctx, "r1", "p1")
+	_ = source.AddUR(ctx, "alice", "r1")
+	_ = source.AddUserToGroup(ctx, &UserGroup{UserID: "bob", GroupName: "staff"})
+	_ = source.AddRoleToGroup(ctx, "staff", "r1")
+	committed := time.Now().Add(-2 * time.Second)
+	for _, ev := range []ChangeEvent{
+		{Kind: KindPermission, Op: ChangeCreate, ID: "p1"},
+		{Kind: KindRole, Op: ChangeCreate, ID: "r1"},
+		{Kind: KindRolePermission, Op: ChangeCreate, RoleID: "r1", PermissionID: "p1"},
+		{Kind: KindUserRole, Op: ChangeCreate, UserID: "alice", RoleID: "r1"},
+		{Kind: KindUserGroup, Op: ChangeCreate, UserID: "bob", GroupName: "staff"},
+		{Kind: KindGroupRole, Op: ChangeCreate, GroupName: "staff", RoleID: "r1", At: committed},
+		{Kind: KindUserGroup, Op: ChangeDelete},
+	} {
+		events <- ev
+	}
+	for deadline := time.Now().Add(5 * time.Second); ; {
+		if st := r.Status(); st.Applied+st.Failed == 7 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for events to be handled: %+v", r.Status())
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	mgr, err := r.Promote(ctx)
+	if err != nil {
+		t.Fatalf("Promote: %v", err)
+	}
+	if err := <-runErr; !errors.Is(err, ErrPromoted) {
+		t.Errorf("expected Run to stop with ErrPromoted, got %v", err)
+	}
+
+	for _, user := range []string{"alice", "bob"} {
+		if ok, err := mgr.Can(ctx, user, "doc", ActionRead); !ok || err != nil {
+			t.Errorf("expected %s to be able to read on the standby, got %v, %v", user, ok, err)
+		}
+	}
+	st := r.Status()
+	if st.Applied != 6 || st.Failed != 1 || !st.Promoted {
+		t.Errorf("unexpected status %+v", st)
+	}
+	if st.Lag < 2*time.Second {
+		t.Errorf("expected lag to be measured from the commit time, got %v", st.Lag)
+	}
+	if len(failures) != 1 || failures[0].Kind != KindUserGroup {
+		t.Errorf("expected the event without IDs to be reported, got %+v", failures)
+	}
+
+	// Deleting on the source removes the standby's copy.
+	_ = source.DeleteRole(ctx, "r1")
+	if err := r.Apply(ctx, ChangeEvent{Kind: KindRole, Op: ChangeDelete, ID: "r1"}); err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+	if got, _ := target.GetRoleByID(ctx, "r1"); got != nil {
+		t.Errorf("expected r1 to be deleted on the standby")
+	}
+}
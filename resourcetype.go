@@ -0,0 +1,139 @@
+package rbac
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"slices"
+	"sort"
+	"sync"
+)
+
+var (
+	// ErrUnknownResourceType is returned when creating a permission whose
+	// resource belongs to no registered ResourceType.
+	ErrUnknownResourceType = errors.New("rbac: resource matches no registered resource type")
+	// ErrActionNotAllowed is returned when creating a permission with an
+	// action its ResourceType does not list.
+	ErrActionNotAllowed = errors.New("rbac: action not allowed on resource type")
+)
+
+// ResourceType describes a kind of resource, such as surveys. Its resources
+// are Name itself, for the collection, and Name/<id> with the id matching
+// IDPattern, a resource pattern that defaults to "*". A type without a Name
+// covers the resources matching IDPattern alone.
+type ResourceType struct {
+	Name      string `json:"name"`
+	IDPattern string `json:"id_pattern,omitempty"`
+	// Actions lists the actions permissions on the type may grant; empty
+	// allows any.
+	Actions []Action `json:"actions,omitempty"`
+}
+
+// pattern is the resource pattern covering the type's items.
+func (t ResourceType) pattern() string {
+	id := t.IDPattern
+	if id == "" {
+		id = "*"
+	}
+	if t.Name == "" {
+		return id
+	}
+	return t.Name + "/" + id
+}
+
+// covers reports whether resource, which may itself be a pattern such as
+// surveys/*, names resources of the type.
+func (t ResourceType) covers(resource string) bool {
+	if resource == t.Name {
+		return true
+	}
+	ok, _ := matchResource(t.pattern(), resource)
+	return ok
+}
+
+// allows reports whether action, which may be a pattern such as "*", only
+// names actions of the type. A pattern is allowed when it matches at least
+// one of them.
+func (t ResourceType) allows(action Action) bool {
+	if len(t.Actions) == 0 || slices.Contains(t.Actions, action) {
+		return true
+	}
+	for _, a := range t.Actions {
+		if ok, _ := path.Match(string(action), string(a)); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// ResourceRegistry holds the resource types permissions may be created on.
+// Set one on Manager.Resources to have CreatePermission reject resources
+// and actions outside it. Broad permissions such as "**" need a type of
+// their own, e.g. {IDPattern: "**"}. It is safe for concurrent use.
+type ResourceRegistry struct {
+	mu    sync.RWMutex
+	types map[string]ResourceType
+}
+
+// NewResourceRegistry returns a registry holding types.
+func NewResourceRegistry(types ...ResourceType) (*ResourceRegistry, error) {
+	r := &ResourceRegistry{types: map[string]ResourceType{}}
+	for _, t := range types {
+		if err := r.Register(t); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// Register adds t, replacing any type with the same name.
+func (r *ResourceRegistry) Register(t ResourceType) error {
+	if _, err := matchResource(t.pattern(), ""); err != nil {
+		return fmt.Errorf("rbac: resource type %q: %w", t.Name, err)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.types[t.Name] = t
+	return nil
+}
+
+// Lookup returns the type registered under name.
+func (r *ResourceRegistry) Lookup(name string) (ResourceType, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	t, ok := r.types[name]
+	return t, ok
+}
+
+// Types returns the registered types, by name.
+func (r *ResourceRegistry) Types() []ResourceType {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	out := make([]ResourceType, 0, len(r.types))
+	for _, t := range r.types {
+		out = append(out, t)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// Validate checks that resource belongs to a registered type that allows
+// action. When several types cover the resource, one allowing the action
+// is enough.
+func (r *ResourceRegistry) Validate(resource string, action Action) error {
+	var matched bool
+	for _, t := range r.Types() {
+		if !t.covers(resource) {
+			continue
+		}
+		if t.allows(action) {
+			return nil
+		}
+		matched = true
+	}
+	if matched {
+		return fmt.Errorf("%w: %q on %q", ErrActionNotAllowed, action, resource)
+	}
+	return fmt.Errorf("%w: %q", ErrUnknownResourceType, resource)
+}
@@ -0,0 +1,69 @@
+package rbac
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestResourceRegistry(t *testing.T) {
+	reg, err := NewResourceRegistry(
+		ResourceType{Name: "surveys", Actions: []Action{ActionRead, ActionUpdate}},
+		ResourceType{Name: "projects", IDPattern: "**"},
+	)
+	if err != nil {
+		t.Fatalf("NewResourceRegistry: %v", err)
+	}
+	for _, c := range []struct {
+		resource string
+		action   Action
+		want     error
+	}{
+		{"surveys", ActionRead, nil},
+		{"surveys/42", ActionUpdate, nil},
+		{"surveys/*", ActionAll, nil},
+		{"surveys/42", ActionDelete, ErrActionNotAllowed},
+		{"surveys/42/answers", ActionRead, ErrUnknownResourceType},
+		{"survey/42", ActionRead, ErrUnknownResourceType},
+		{"projects/7/docs/1", ActionDelete, nil},
+		{"**", ActionRead, ErrUnknownResourceType},
+	} {
+		if err := reg.Validate(c.resource, c.action); !errors.Is(err, c.want) {
+			t.Errorf("Validate(%q, %q) = %v, want %v", c.resource, c.action, err, c.want)
+		}
+	}
+
+	if err := reg.Register(ResourceType{IDPattern: "**"}); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	if err := reg.Validate("**", ActionRead); err != nil {
+		t.Errorf("expected a catch-all type to allow **, got %v", err)
+	}
+	if names := reg.Types(); len(names) != 3 || names[1].Name != "projects" {
+		t.Errorf("unexpected types %+v", names)
+	}
+}
+
+func TestCreatePermissionValidatesResourceType(t *testing.T) {
+	ctx := context.Background()
+	mgr, err := NewMemoryStoreManager(ctx, "", 0)
+	if err != nil {
+		t.Fatalf("NewMemoryStoreManager: %v", err)
+	}
+	if mgr.Resources, err = NewResourceRegistry(ResourceType{Name: "surveys", Actions: []Action{ActionRead}}); err != nil {
+		t.Fatalf("NewResourceRegistry: %v", err)
+	}
+
+	if err := mgr.CreatePermission(ctx, &Permission{Resource: "surveys/*", Action: ActionRead}); err != nil {
+		t.Errorf("CreatePermission on a registered type: %v", err)
+	}
+	if err := mgr.CreatePermission(ctx, &Permission{Resource: "survey/*", Action: ActionRead}); !errors.Is(err, ErrUnknownResourceType) {
+		t.Errorf("expected ErrUnknownResourceType for a typo, got %v", err)
+	}
+	if err := mgr.CreatePermission(ctx, &Permission{Resource: "surveys/*", Action: ActionDelete}); !errors.Is(err, ErrActionNotAllowed) {
+		t.Errorf("expected ErrActionNotAllowed, got %v", err)
+	}
+	if p, _ := mgr.Perms.GetPermissionByResource(ctx, "survey/*", ActionRead); p != nil {
+		t.Errorf("expected the rejected permission not to be stored, got %+v", p)
+	}
+}
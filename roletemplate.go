@@ -0,0 +1,109 @@
+package rbac
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"time"
+)
+
+var (
+	// ErrTemplateRole is returned when assigning a role marked as a
+	// template; clone it with CloneRole and assign the copy instead.
+	ErrTemplateRole = errors.New("rbac: template roles cannot be assigned")
+	// ErrRoleNotFound is returned by CloneRole for an unknown source role.
+	ErrRoleNotFound = errors.New("rbac: role not found")
+)
+
+// checkAssignable fails for a template role. Unknown roles pass, as the
+// assignment methods have never required the role to exist, and so does
+// every role of a Manager without a RoleRepo.
+func (m *Manager) checkAssignable(ctx context.Context, roleID string) error {
+	if m.Roles == nil {
+		return nil
+	}
+	r, err := m.Roles.GetRoleByID(ctx, roleID)
+	if err != nil {
+		return err
+	}
+	if r != nil && r.Template {
+		return fmt.Errorf("%w: %q", ErrTemplateRole, r.Name)
+	}
+	return nil
+}
+
+// CloneRole creates a role named newName with the description, priority
+// and generators of srcRoleID, bound to the same permissions and, when the
+// role repo supports hierarchy, inheriting from the same parents. The copy
+// is never a template, so per-customer roles can be stamped out of a
+// blueprint marked with Role.Template. Bindings keep the source they had
+// on the original. It runs in a transaction when the store supports them.
+func (m *Manager) CloneRole(ctx context.Context, srcRoleID, newName string) (*Role, error) {
+	start := time.Now()
+	ctx, span := m.startSpan(ctx, "CloneRole")
+	defer span.End()
+	var clone *Role
+	err := m.inTransaction(ctx, func(ctx context.Context) error {
+		var err error
+		clone, err = m.cloneRole(ctx, srcRoleID, newName)
+		return err
+	})
+	m.record(ctx, start, "CloneRole", err)
+	m.changed(ctx, "CloneRole", srcRoleID, err)
+	if err != nil {
+		return nil, err
+	}
+	return clone, nil
+}
+
+func (m *Manager) cloneRole(ctx context.Context, srcRoleID, newName string) (*Role, error) {
+	if newName == "" {
+		return nil, errors.New("rbac: cloned role needs a name")
+	}
+	src, err := m.Roles.GetRoleByID(ctx, srcRoleID)
+	if err != nil {
+		return nil, err
+	}
+	if src == nil {
+		return nil, fmt.Errorf("%w: %q", ErrRoleNotFound, srcRoleID)
+	}
+	clone := &Role{
+		Name:        newName,
+		Description: src.Description,
+		Priority:    src.Priority,
+		Meta:        maps.Clone(src.Meta),
+		Generators:  append([]Permission(nil), src.Generators...),
+	}
+	if err := checkGenerators(clone); err != nil {
+		return nil, err
+	}
+	m.assignID(&clone.ID, KindRole)
+	stampCreated(ctx, time.Now(), &clone.CreatedBy, &clone.UpdatedBy, &clone.UpdatedAt)
+	if err := m.Roles.CreateRole(ctx, clone); err != nil {
+		return nil, err
+	}
+
+	perms, err := m.RP.ListPermissions(ctx, src.ID)
+	if err != nil {
+		return nil, err
+	}
+	for _, permID := range perms {
+		if err := m.RP.AddRP(m.sourceContext(ctx, KindRolePermission, src.ID, permID), clone.ID, permID); err != nil {
+			return nil, err
+		}
+	}
+
+	if h, ok := m.Roles.(RoleHierarchyRepo); ok {
+		parents, err := h.ListRoleParents(ctx, src.ID)
+		if err != nil {
+			return nil, err
+		}
+		for _, parent := range parents {
+			if err := h.AddRoleParent(ctx, clone.ID, parent); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return clone, nil
+}
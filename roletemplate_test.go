@@ -0,0 +1,73 @@
+package rbac
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestCloneRoleFromTemplate(t *testing.T) {
+	ctx := context.Background()
+	mgr, err := NewMemoryStoreManager(ctx, "", 0)
+	if err != nil {
+		t.Fatalf("NewMemoryStoreManager: %v", err)
+	}
+	base := &Role{Name: "base"}
+	blueprint := &Role{Name: "support-blueprint", Description: "Customer support", Priority: 5, Template: true, Meta: map[string]interface{}{"team": "support"}}
+	for _, r := range []*Role{base, blueprint} {
+		if err := mgr.CreateRole(ctx, r); err != nil {
+			t.Fatalf("CreateRole(%s): %v", r.Name, err)
+		}
+	}
+	if err := mgr.AddRoleParent(ctx, blueprint.ID, base.ID); err != nil {
+		t.Fatalf("AddRoleParent: %v", err)
+	}
+	var permIDs []string
+	for _, p := range []*Permission{{Resource: "tickets/*", Action: ActionRead}, {Resource: "tickets/*", Action: ActionUpdate}} {
+		if err := mgr.CreatePermission(ctx, p); err != nil {
+			t.Fatalf("CreatePermission: %v", err)
+		}
+		if err := mgr.AssignPermissionToRole(ctx, blueprint.ID, p.ID); err != nil {
+			t.Fatalf("AssignPermissionToRole: %v", err)
+		}
+		permIDs = append(permIDs, p.ID)
+	}
+
+	if err := mgr.AssignRoleToUser(ctx, "alice", blueprint.ID); !errors.Is(err, ErrTemplateRole) {
+		t.Errorf("expected assigning a template to a user to fail, got %v", err)
+	}
+	if err := mgr.AssignRoleToGroup(ctx, "support", blueprint.ID); !errors.Is(err, ErrTemplateRole) {
+		t.Errorf("expected assigning a template to a group to fail, got %v", err)
+	}
+	if err := mgr.CreateGroup(ctx, &Group{Name: "support", DefaultRoles: []string{blueprint.ID}}); !errors.Is(err, ErrTemplateRole) {
+		t.Errorf("expected a template default role to be rejected, got %v", err)
+	}
+
+	clone, err := mgr.CloneRole(ctx, blueprint.ID, "acme-support")
+	if err != nil {
+		t.Fatalf("CloneRole: %v", err)
+	}
+	if clone.ID == blueprint.ID || clone.Template || clone.Description != blueprint.Description || clone.Priority != 5 || clone.Meta["team"] != "support" {
+		t.Errorf("unexpected clone %+v", clone)
+	}
+	perms, _ := mgr.ListPermissionsForRole(ctx, clone.ID)
+	slices.Sort(perms)
+	slices.Sort(permIDs)
+	if !slices.Equal(perms, permIDs) {
+		t.Errorf("expected the clone to get the template's permissions %v, got %v", permIDs, perms)
+	}
+	if parents, _ := mgr.ListRoleParents(ctx, clone.ID); len(parents) != 1 || parents[0] != base.ID {
+		t.Errorf("expected the clone to inherit from %s, got %v", base.ID, parents)
+	}
+
+	if err := mgr.AssignRoleToUser(ctx, "alice", clone.ID); err != nil {
+		t.Fatalf("AssignRoleToUser(clone): %v", err)
+	}
+	if ok, err := mgr.Can(ctx, "alice", "tickets/42", ActionUpdate); err != nil || !ok {
+		t.Errorf("Can through the clone = %v, %v; want true", ok, err)
+	}
+	if _, err := mgr.CloneRole(ctx, "missing", "x"); !errors.Is(err, ErrRoleNotFound) {
+		t.Errorf("expected ErrRoleNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,128 @@
+package rbac
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestScheduledRoleAssignments(t *testing.T) {
+	ctx := context.Background()
+	stores := map[string]func(t *testing.T) *Manager{
+		"Mock": func(t *testing.T) *Manager { return NewMockRepoManager(NewMockRepo()) },
+		"Memory": func(t *testing.T) *Manager {
+			mgr, err := NewMemoryStoreManager(ctx, "", 0)
+			if err != nil {
+				t.Fatalf("NewMemoryStoreManager: %v", err)
+			}
+			return mgr
+		},
+	}
+
+	for name, newManager := range stores {
+		t.Run(name, func(t *testing.T) {
+			mgr := newManager(t)
+			perm := &Permission{Resource: "payroll", Action: ActionRead}
+			if err := mgr.CreatePermission(ctx, perm); err != nil {
+				t.Fatalf("CreatePermission: %v", err)
+			}
+			role := &Role{Name: "payroll-reader"}
+			if err := mgr.CreateRole(ctx, role); err != nil {
+				t.Fatalf("CreateRole: %v", err)
+			}
+			if err := mgr.AssignPermissionToRole(ctx, role.ID, perm.ID); err != nil {
+				t.Fatalf("AssignPermissionToRole: %v", err)
+			}
+
+			can := func() bool {
+				t.Helper()
+				ok, err := mgr.Can(ctx, "alice", "payroll", ActionRead)
+				if err != nil {
+					t.Fatalf("Can: %v", err)
+				}
+				return ok
+			}
+
+			now := time.Now()
+			if err := mgr.ScheduleRoleForUser(ctx, "alice", role.ID, now.Add(time.Hour), time.Time{}); err != nil {
+				t.Fatalf("ScheduleRoleForUser: %v", err)
+			}
+			if can() {
+				t.Error("expected a pending assignment to be ignored")
+			}
+			as, err := mgr.ListRoleAssignments(ctx, "alice")
+			if err != nil {
+				t.Fatalf("ListRoleAssignments: %v", err)
+			}
+			if len(as) != 1 || as[0].RoleID != role.ID || as[0].NotBefore != now.Add(time.Hour).Unix() {
+				t.Fatalf("expected the pending assignment to be listed, got %+v", as)
+			}
+
+			if err := mgr.ScheduleRoleForUser(ctx, "alice", role.ID, now.Add(-time.Hour), now.Add(time.Hour)); err != nil {
+				t.Fatalf("ScheduleRoleForUser: %v", err)
+			}
+			if !can() {
+				t.Error("expected an active assignment to grant access")
+			}
+
+			if err := mgr.ScheduleRoleForUser(ctx, "alice", role.ID, now.Add(-2*time.Hour), now.Add(-time.Hour)); err != nil {
+				t.Fatalf("ScheduleRoleForUser: %v", err)
+			}
+			if can() {
+				t.Error("expected a lapsed assignment to be ignored")
+			}
+
+			if err := mgr.AssignRoleToUser(ctx, "alice", role.ID); err != nil {
+				t.Fatalf("AssignRoleToUser: %v", err)
+			}
+			if !can() {
+				t.Error("expected AssignRoleToUser to open the window")
+			}
+			as, _ = mgr.ListRoleAssignments(ctx, "alice")
+			if len(as) != 1 || as[0].NotBefore != 0 || as[0].ExpiresAt != 0 {
+				t.Errorf("expected an open assignment, got %+v", as)
+			}
+
+			if err := mgr.ScheduleRoleForUser(ctx, "alice", role.ID, now, now.Add(-time.Hour)); err == nil {
+				t.Error("expected an error for a window that ends before it starts")
+			}
+		})
+	}
+}
+
+func TestScheduledRoleAssignmentsSnapshot(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "rbac.json")
+	s, err := NewMemoryStore(ctx, path)
+	if err != nil {
+		t.Fatalf("NewMemoryStore: %v", err)
+	}
+	want := &RoleAssignment{UserID: "alice", RoleID: "r1", NotBefore: 100, ExpiresAt: 200}
+	if err := s.AddScheduledUR(ctx, want); err != nil {
+		t.Fatalf("AddScheduledUR: %v", err)
+	}
+	if err := s.Snapshot(ctx); err != nil {
+		t.Fatalf("Snapshot: %v", err)
+	}
+
+	reloaded, err := NewMemoryStore(ctx, path)
+	if err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+	as, err := reloaded.ListRoleAssignments(ctx, "alice")
+	if err != nil {
+		t.Fatalf("ListRoleAssignments: %v", err)
+	}
+	if len(as) != 1 || *as[0] != *want {
+		t.Errorf("expected %+v after reload, got %+v", want, as)
+	}
+}
+
+func TestScheduleRoleForUserUnsupported(t *testing.T) {
+	mgr := NewMockRepoManager(NewMockRepo())
+	mgr.UR = struct{ UserRoleRepo }{mgr.UR}
+	if err := mgr.ScheduleRoleForUser(context.Background(), "alice", "r1", time.Now(), time.Time{}); err != errSchedulingUnsupported {
+		t.Errorf("expected errSchedulingUnsupported, got %v", err)
+	}
+}
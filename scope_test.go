@@ -0,0 +1,88 @@
+package rbac
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestScopedRoleAssignments(t *testing.T) {
+	ctx := context.Background()
+	stores := map[string]func(t *testing.T) *Manager{
+		"Mock": func(t *testing.T) *Manager { return NewMockRepoManager(NewMockRepo()) },
+		"Memory": func(t *testing.T) *Manager {
+			mgr, err := NewMemoryStoreManager(ctx, "", 0)
+			if err != nil {
+				t.Fatalf("NewMemoryStoreManager: %v", err)
+			}
+			return mgr
+		},
+		"Cached": func(t *testing.T) *Manager {
+			inner, err := NewMemoryStore(ctx, "")
+			if err != nil {
+				t.Fatalf("NewMemoryStore: %v", err)
+			}
+			return NewCachedStoreManager(inner, time.Minute)
+		},
+	}
+
+	for name, newManager := range stores {
+		t.Run(name, func(t *testing.T) {
+			mgr := newManager(t)
+			perm := &Permission{Resource: "projects/**", Action: ActionUpdate}
+			if err := mgr.CreatePermission(ctx, perm); err != nil {
+				t.Fatalf("CreatePermission: %v", err)
+			}
+			editor := &Role{Name: "project-editor"}
+			if err := mgr.CreateRole(ctx, editor); err != nil {
+				t.Fatalf("CreateRole: %v", err)
+			}
+			if err := mgr.AssignPermissionToRole(ctx, editor.ID, perm.ID); err != nil {
+				t.Fatalf("AssignPermissionToRole: %v", err)
+			}
+
+			can := func(user, resource string) bool {
+				t.Helper()
+				ok, err := mgr.Can(ctx, user, resource, ActionUpdate)
+				if err != nil {
+					t.Fatalf("Can: %v", err)
+				}
+				return ok
+			}
+
+			if err := mgr.AssignScopedRoleToUser(ctx, "alice", editor.ID, "projects/42/**"); err != nil {
+				t.Fatalf("AssignScopedRoleToUser: %v", err)
+			}
+			if !can("alice", "projects/42/docs/readme") {
+				t.Error("expected the scoped role to apply inside its scope")
+			}
+			if can("alice", "projects/43/docs/readme") {
+				t.Error("expected the scoped role to be ignored outside its scope")
+			}
+
+			if err := mgr.AddUserToGroup(ctx, &UserGroup{UserID: "bob", GroupName: "team-7"}); err != nil {
+				t.Fatalf("AddUserToGroup: %v", err)
+			}
+			if err := mgr.AssignScopedRoleToGroup(ctx, "team-7", editor.ID, "projects/7/**"); err != nil {
+				t.Fatalf("AssignScopedRoleToGroup: %v", err)
+			}
+			if !can("bob", "projects/7/board") || can("bob", "projects/42/board") {
+				t.Error("expected the group's scoped role to apply only inside its scope")
+			}
+			list, err := mgr.ListScopedRolesForGroup(ctx, "team-7")
+			if err != nil || len(list) != 1 || list[0].Scope != "projects/7/**" {
+				t.Fatalf("ListScopedRolesForGroup = %+v, %v", list, err)
+			}
+
+			if err := mgr.UnassignScopedRoleFromUser(ctx, "alice", editor.ID, "projects/42/**"); err != nil {
+				t.Fatalf("UnassignScopedRoleFromUser: %v", err)
+			}
+			if can("alice", "projects/42/docs/readme") {
+				t.Error("expected the unassigned scoped role to be gone")
+			}
+			if err := mgr.AssignScopedRoleToUser(ctx, "alice", editor.ID, ""); err == nil {
+				t.Error("expected an empty scope to be rejected")
+			}
+		})
+	}
+}
@@ -0,0 +1,202 @@
+package rbac
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// SearchKinds are the kinds Manager.Search looks through, in the order it
+// returns them.
+var SearchKinds = []string{KindRole, KindPermission, KindUser, KindGroup}
+
+// ErrInvalidSearch is returned by Search for an empty query or a kind it
+// cannot search.
+var ErrInvalidSearch = errors.New("rbac: invalid search")
+
+// SearchQuery is what Manager.Search looks for. Text is matched without
+// regard to case against role names, permission resources, usernames and
+// group names.
+type SearchQuery struct {
+	Text string `json:"text"`
+	// Prefix matches only names that start with Text; otherwise Text may
+	// appear anywhere in the name.
+	Prefix bool `json:"prefix,omitempty"`
+}
+
+func (q SearchQuery) matches(name string) bool {
+	name, text := strings.ToLower(name), strings.ToLower(q.Text)
+	if q.Prefix {
+		return strings.HasPrefix(name, text)
+	}
+	return strings.Contains(name, text)
+}
+
+// SearchHit is one entity found by Search. Name is the field that matched
+// and Value the *Role, *Permission, *User or *Group.
+type SearchHit struct {
+	Kind  string `json:"kind"`
+	ID    string `json:"id"`
+	Name  string `json:"name"`
+	Value any    `json:"value"`
+}
+
+// Searcher is optionally implemented by repos that can search their own
+// records, e.g. with an index, so Search does not list the whole store.
+// SearchPage pages the hits of one of SearchKinds; soft-deleted roles and
+// permissions are left out. Its cursors are opaque, as ListPager's are.
+type Searcher interface {
+	SearchPage(ctx context.Context, kind string, q SearchQuery, page PageRequest) (PageResult[*SearchHit], error)
+}
+
+// Search returns a page of the roles, permissions, users and groups whose
+// name matches q, for the management UI's search box. kinds limits the
+// search to some of SearchKinds; when empty all are searched, groups only
+// when the Manager has a GroupRepo. Hits come kind by kind in the order of
+// SearchKinds, and a page may span kinds. Soft-deleted roles and
+// permissions are not found.
+func (m *Manager) Search(ctx context.Context, q SearchQuery, kinds []string, page PageRequest) (PageResult[*SearchHit], error) {
+	start := time.Now()
+	ctx, span := m.startSpan(ctx, "Search")
+	defer span.End()
+	res, err := m.search(ctx, q, kinds, page)
+	m.record(ctx, start, "Search", err)
+	return res, err
+}
+
+func (m *Manager) search(ctx context.Context, q SearchQuery, kinds []string, page PageRequest) (PageResult[*SearchHit], error) {
+	if strings.TrimSpace(q.Text) == "" {
+		return PageResult[*SearchHit]{}, fmt.Errorf("%w: empty query", ErrInvalidSearch)
+	}
+	for _, kind := range kinds {
+		if !slices.Contains(SearchKinds, kind) {
+			return PageResult[*SearchHit]{}, fmt.Errorf("%w: kind %q", ErrInvalidSearch, kind)
+		}
+	}
+	var order []string
+	for _, kind := range SearchKinds {
+		if len(kinds) == 0 && kind == KindGroup && m.Groups == nil {
+			continue
+		}
+		if len(kinds) == 0 || slices.Contains(kinds, kind) {
+			order = append(order, kind)
+		}
+	}
+
+	// The cursor is the kind being searched and that kind's own cursor.
+	from, inner := 0, ""
+	if page.Cursor != "" {
+		kind, rest, ok := strings.Cut(page.Cursor, ":")
+		if from = slices.Index(order, kind); !ok || from < 0 {
+			return PageResult[*SearchHit]{}, fmt.Errorf("%w: %q", ErrInvalidPageCursor, page.Cursor)
+		}
+		inner = rest
+	}
+
+	limit := page.limit()
+	res := PageResult[*SearchHit]{Items: []*SearchHit{}}
+	for i := from; i < len(order); i++ {
+		if len(res.Items) == limit {
+			res.NextCursor = order[i] + ":"
+			break
+		}
+		hits, err := m.searchKind(ctx, order[i], q, PageRequest{Cursor: inner, Limit: limit - len(res.Items)})
+		if err != nil {
+			return PageResult[*SearchHit]{}, fmt.Errorf("rbac: search %s: %w", order[i], err)
+		}
+		res.Items = append(res.Items, hits.Items...)
+		if hits.NextCursor != "" {
+			res.NextCursor = order[i] + ":" + hits.NextCursor
+			break
+		}
+		inner = ""
+	}
+	return res, nil
+}
+
+// searchKind pages the hits of one kind, with the repo's Searcher or by
+// filtering its full list.
+func (m *Manager) searchKind(ctx context.Context, kind string, q SearchQuery, page PageRequest) (PageResult[*SearchHit], error) {
+	var repo any
+	switch kind {
+	case KindRole:
+		repo = m.Roles
+	case KindPermission:
+		repo = m.Perms
+	case KindUser:
+		repo = m.Users
+	case KindGroup:
+		if m.Groups == nil {
+			return PageResult[*SearchHit]{}, errNoGroupRepo
+		}
+		repo = m.Groups
+	}
+	if s, ok := repo.(Searcher); ok {
+		return s.SearchPage(ctx, kind, q, page)
+	}
+
+	var hits []*SearchHit
+	add := func(hit *SearchHit) {
+		if q.matches(hit.Name) {
+			hits = append(hits, hit)
+		}
+	}
+	switch kind {
+	case KindRole:
+		roles, err := m.Roles.ListAllRoles(ctx)
+		if err != nil {
+			return PageResult[*SearchHit]{}, err
+		}
+		for _, r := range roles {
+			if r.DeletedAt == 0 {
+				add(newSearchHit(kind, r))
+			}
+		}
+	case KindPermission:
+		perms, err := m.Perms.ListAllPermissions(ctx)
+		if err != nil {
+			return PageResult[*SearchHit]{}, err
+		}
+		for _, p := range perms {
+			if p.DeletedAt == 0 {
+				add(newSearchHit(kind, p))
+			}
+		}
+	case KindUser:
+		users, err := m.Users.ListAllUsers(ctx)
+		if err != nil {
+			return PageResult[*SearchHit]{}, err
+		}
+		for _, u := range users {
+			add(newSearchHit(kind, u))
+		}
+	case KindGroup:
+		groups, err := m.Groups.ListGroups(ctx)
+		if err != nil {
+			return PageResult[*SearchHit]{}, err
+		}
+		for _, g := range groups {
+			add(newSearchHit(kind, g))
+		}
+	}
+	return pageSlice(hits, func(h *SearchHit) string { return h.ID }, page), nil
+}
+
+// newSearchHit returns the hit for v, a *Role, *Permission, *User or *Group.
+func newSearchHit(kind string, v any) *SearchHit {
+	hit := &SearchHit{Kind: kind, Value: v}
+	switch v := v.(type) {
+	case *Role:
+		hit.ID, hit.Name = v.ID, v.Name
+	case *Permission:
+		hit.ID, hit.Name = v.ID, v.Resource
+	case *User:
+		hit.ID, hit.Name = v.ID, v.Username
+	case *Group:
+		hit.ID, hit.Name = v.ID, v.Name
+	}
+	return hit
+}
@@ -0,0 +1,80 @@
+package rbac
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestSearch(t *testing.T) {
+	ctx := context.Background()
+	memory, err := NewMemoryStoreManager(ctx, "", 0)
+	if err != nil {
+		t.Fatalf("NewMemoryStoreManager: %v", err)
+	}
+	for name, mgr := range map[string]*Manager{
+		"memory": memory,
+		"mock":   NewMockRepoManager(NewMockRepo()),
+	} {
+		t.Run(name, func(t *testing.T) {
+			must := func(err error) {
+				t.Helper()
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+			for _, r := range []*Role{{ID: "r-admin", Name: "admin"}, {ID: "r-billing", Name: "Billing Admin"}, {ID: "r-old", Name: "old-admin"}, {ID: "r-viewer", Name: "viewer"}} {
+				must(mgr.CreateRole(ctx, r))
+			}
+			must(mgr.DeleteRole(ctx, "r-old"))
+			must(mgr.CreatePermission(ctx, &Permission{ID: "p-settings", Resource: "admin/settings", Action: ActionUpdate}))
+			must(mgr.CreatePermission(ctx, &Permission{ID: "p-reports", Resource: "reports", Action: ActionRead}))
+			must(mgr.CreateUser(ctx, &User{ID: "u-bot", Username: "admin-bot", Email: "bot@example.com"}))
+			must(mgr.CreateUser(ctx, &User{ID: "u-bob", Username: "bob", Email: "bob@example.com"}))
+
+			ids := func(q SearchQuery, kinds []string, limit int) []string {
+				t.Helper()
+				var out []string
+				page := PageRequest{Limit: limit}
+				for {
+					res, err := mgr.Search(ctx, q, kinds, page)
+					must(err)
+					for _, h := range res.Items {
+						out = append(out, h.ID)
+					}
+					if res.NextCursor == "" {
+						return out
+					}
+					page.Cursor = res.NextCursor
+				}
+			}
+
+			want := []string{"r-admin", "r-billing", "p-settings", "u-bot"}
+			for _, limit := range []int{0, 1, 3} {
+				if got := ids(SearchQuery{Text: "ADM"}, nil, limit); !slices.Equal(got, want) {
+					t.Errorf("substring, limit %d: got %v; want %v", limit, got, want)
+				}
+			}
+			if got := ids(SearchQuery{Text: "adm", Prefix: true}, nil, 0); !slices.Equal(got, []string{"r-admin", "p-settings", "u-bot"}) {
+				t.Errorf("prefix: got %v", got)
+			}
+			if got := ids(SearchQuery{Text: "adm"}, []string{KindUser, KindRole}, 0); !slices.Equal(got, []string{"r-admin", "r-billing", "u-bot"}) {
+				t.Errorf("roles and users: got %v", got)
+			}
+
+			for _, kinds := range [][]string{nil, {"tenant"}} {
+				text := ""
+				if kinds != nil {
+					text = "adm"
+				}
+				if _, err := mgr.Search(ctx, SearchQuery{Text: text}, kinds, PageRequest{}); !errors.Is(err, ErrInvalidSearch) {
+					t.Errorf("Search(%q, %v): got %v; want ErrInvalidSearch", text, kinds, err)
+				}
+			}
+			if _, err := mgr.Search(ctx, SearchQuery{Text: "adm"}, nil, PageRequest{Cursor: "api_key:x"}); !errors.Is(err, ErrInvalidPageCursor) {
+				t.Errorf("bad cursor: got %v; want ErrInvalidPageCursor", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,202 @@
+package rbac
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// KindSession is passed to IDGenerator.NewID by stores saving a session
+// without an ID. Sessions created through the Manager get random IDs instead.
+const KindSession = "session"
+
+// Session is a snapshot of the roles a user held when it was created.
+// CanForSession decides against the snapshot, so a session's permissions do
+// not change with the user's assignments until it expires; the permissions
+// bound to its roles are still read live.
+type Session struct {
+	ID     string `bson:"id" json:"id"`
+	UserID string `bson:"user_id" json:"user_id"`
+	// Roles are the user's direct, group and delegated roles and the roles
+	// they inherit from.
+	Roles []string `bson:"roles" json:"roles"`
+	// Scoped are the user's scoped roles, direct and through groups.
+	Scoped    []ScopedRole `bson:"scoped,omitempty" json:"scoped,omitempty"`
+	CreatedAt int64        `bson:"created_at" json:"created_at"`
+	ExpiresAt int64        `bson:"expires_at" json:"expires_at"`
+}
+
+// SessionRepo stores sessions. Expired sessions may linger in it; the
+// Manager treats them as missing.
+type SessionRepo interface {
+	// SaveSession creates the session or replaces the one with its ID.
+	SaveSession(ctx context.Context, s *Session) error
+	// GetSession returns the session, or nil, nil.
+	GetSession(ctx context.Context, id string) (*Session, error)
+	DeleteSession(ctx context.Context, id string) error
+}
+
+// ErrSessionNotFound is returned for a session that is unknown, ended or
+// expired.
+var ErrSessionNotFound = errors.New("rbac: session not found")
+
+var errNoSessionRepo = errors.New("rbac: no SessionRepo configured")
+
+// CreateSession snapshots the roles userID holds now into a session that
+// lasts ttl. Its ID is random and serves as the session token.
+func (m *Manager) CreateSession(ctx context.Context, userID string, ttl time.Duration) (*Session, error) {
+	start := time.Now()
+	ctx, span := m.startSpan(ctx, "CreateSession")
+	defer span.End()
+	s, err := m.createSession(ctx, start, userID, ttl)
+	m.record(ctx, start, "CreateSession", err)
+	return s, err
+}
+
+func (m *Manager) createSession(ctx context.Context, now time.Time, userID string, ttl time.Duration) (*Session, error) {
+	if m.Sessions == nil {
+		return nil, errNoSessionRepo
+	}
+	if ttl <= 0 {
+		return nil, errors.New("rbac: session needs a positive TTL")
+	}
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return nil, err
+	}
+	s := &Session{
+		ID:        base64.RawURLEncoding.EncodeToString(raw),
+		UserID:    userID,
+		CreatedAt: now.Unix(),
+		ExpiresAt: now.Add(ttl).Unix(),
+	}
+
+	roles, groups, err := m.resolveRoles(withoutRequestContext(ctx), nil, now, "CreateSession", userID, "")
+	if err != nil {
+		return nil, err
+	}
+	s.Roles = roles
+	if s.Scoped, err = m.listScopedRoles(ctx, userID, groups); err != nil {
+		return nil, err
+	}
+	if err := m.Sessions.SaveSession(ctx, s); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// GetSession returns the session, or ErrSessionNotFound once it has ended or
+// expired.
+func (m *Manager) GetSession(ctx context.Context, id string) (*Session, error) {
+	start := time.Now()
+	ctx, span := m.startSpan(ctx, "GetSession")
+	defer span.End()
+	s, err := m.getSession(ctx, start, id)
+	m.record(ctx, start, "GetSession", err)
+	return s, err
+}
+
+func (m *Manager) getSession(ctx context.Context, now time.Time, id string) (*Session, error) {
+	if m.Sessions == nil {
+		return nil, errNoSessionRepo
+	}
+	s, err := m.Sessions.GetSession(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if s == nil || now.Unix() >= s.ExpiresAt {
+		return nil, ErrSessionNotFound
+	}
+	return s, nil
+}
+
+// EndSession deletes the session, e.g. on logout.
+func (m *Manager) EndSession(ctx context.Context, id string) error {
+	start := time.Now()
+	ctx, span := m.startSpan(ctx, "EndSession")
+	defer span.End()
+	err := errNoSessionRepo
+	if m.Sessions != nil {
+		err = m.Sessions.DeleteSession(ctx, id)
+	}
+	m.record(ctx, start, "EndSession", err)
+	return err
+}
+
+// CanForSession reports whether the session's user can perform action on
+// resource, deciding as Can does but with the roles the session captured
+// rather than those the user holds now.
+func (m *Manager) CanForSession(ctx context.Context, sessionID, resource string, action Action) (bool, error) {
+	d, err := m.decideForSession(ctx, sessionID, resource, action)
+	if err != nil {
+		return false, err
+	}
+	return d.Allowed, nil
+}
+
+func (m *Manager) decideForSession(ctx context.Context, sessionID, resource string, action Action) (d *Decision, err error) {
+	const method = "CanForSession"
+	start := time.Now()
+	ctx, span := m.startSpan(ctx, method)
+	defer span.End()
+	ctx, tr := m.startDecisionTrace(ctx)
+	defer func() {
+		tr.finish(resource, action, d, err)
+		span.SetAttributes(decisionAttributes(resource, action, d, err)...)
+	}()
+
+	s, err := m.getSession(ctx, start, sessionID)
+	if err != nil {
+		m.record(ctx, start, method, err)
+		return nil, err
+	}
+	var scoped []string
+	for _, sr := range s.Scoped {
+		ok, err := matchResource(sr.Scope, resource)
+		if err != nil {
+			m.record(ctx, start, method, err)
+			return nil, err
+		}
+		if ok {
+			scoped = append(scoped, sr.RoleID)
+		}
+	}
+	roles := s.Roles
+	if len(scoped) > 0 {
+		if scoped, err = m.expandRoles(ctx, scoped); err != nil {
+			m.record(ctx, start, method, err)
+		}
+		roles = append(slices.Clip(roles), scoped...)
+	}
+	return m.evaluate(ctx, start, tr, method, s.UserID, roles, resource, action, nil)
+}
+
+// listScopedRoles returns every scoped role the user holds, directly or
+// through groups. Repos without scoped assignments add none.
+func (m *Manager) listScopedRoles(ctx context.Context, userID string, groups []*UserGroup) ([]ScopedRole, error) {
+	var out []ScopedRole
+	add := func(list []ScopedRole, err error) error {
+		if err != nil && !errors.Is(err, errScopeUnsupported) {
+			return err
+		}
+		out = append(out, list...)
+		return nil
+	}
+	if repo, ok := m.UR.(ScopedUserRoleRepo); ok {
+		if err := add(repo.ListScopedRoles(ctx, userID)); err != nil {
+			return nil, fmt.Errorf("rbac: scoped roles of %q: %w", userID, err)
+		}
+	}
+	if repo, ok := m.GR.(ScopedGroupRoleRepo); ok {
+		for _, ug := range groups {
+			if err := add(repo.ListScopedRolesForGroup(ctx, ug.GroupName)); err != nil {
+				return nil, fmt.Errorf("rbac: scoped roles of group %q: %w", ug.GroupName, err)
+			}
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,100 @@
+package rbac
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSessions(t *testing.T) {
+	ctx := context.Background()
+	mgr, err := NewMemoryStoreManager(ctx, "", 0)
+	if err != nil {
+		t.Fatalf("NewMemoryStoreManager: %v", err)
+	}
+	role := func(name, resource string) *Role {
+		t.Helper()
+		r := &Role{Name: name}
+		if err := mgr.CreateRole(ctx, r); err != nil {
+			t.Fatalf("CreateRole: %v", err)
+		}
+		p := &Permission{Resource: resource, Action: ActionRead}
+		if err := mgr.CreatePermission(ctx, p); err != nil {
+			t.Fatalf("CreatePermission: %v", err)
+		}
+		if err := mgr.AssignPermissionToRole(ctx, r.ID, p.ID); err != nil {
+			t.Fatalf("AssignPermissionToRole: %v", err)
+		}
+		return r
+	}
+	reader := role("reader", "docs/*")
+	auditor := role("auditor", "ledgers/*")
+	projects := role("project-reader", "projects/*")
+	approver := role("approver", "invoices/*")
+	if err := mgr.AssignRoleToUser(ctx, "alice", reader.ID); err != nil {
+		t.Fatalf("AssignRoleToUser: %v", err)
+	}
+	if err := mgr.AddUserToGroup(ctx, &UserGroup{UserID: "alice", GroupName: "finance"}); err != nil {
+		t.Fatalf("AddUserToGroup: %v", err)
+	}
+	if err := mgr.AssignRoleToGroup(ctx, "finance", auditor.ID); err != nil {
+		t.Fatalf("AssignRoleToGroup: %v", err)
+	}
+	if err := mgr.AssignScopedRoleToUser(ctx, "alice", projects.ID, "projects/42"); err != nil {
+		t.Fatalf("AssignScopedRoleToUser: %v", err)
+	}
+	if err := mgr.AssignRoleToUser(ctx, "bob", approver.ID); err != nil {
+		t.Fatalf("AssignRoleToUser: %v", err)
+	}
+	if _, err := mgr.DelegateRole(ctx, "bob", "alice", approver.ID, time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("DelegateRole: %v", err)
+	}
+
+	sess, err := mgr.CreateSession(ctx, "alice", time.Hour)
+	if err != nil {
+		t.Fatalf("CreateSession: %v", err)
+	}
+	can := func(resource string) bool {
+		t.Helper()
+		ok, err := mgr.CanForSession(ctx, sess.ID, resource, ActionRead)
+		if err != nil {
+			t.Fatalf("CanForSession(%s): %v", resource, err)
+		}
+		return ok
+	}
+	for resource, want := range map[string]bool{"docs/1": true, "ledgers/1": true, "invoices/1": true, "projects/42": true, "projects/7": false} {
+		if got := can(resource); got != want {
+			t.Errorf("CanForSession(%s) = %v, want %v", resource, got, want)
+		}
+	}
+
+	// the session keeps the roles it captured
+	if err := mgr.UnassignRoleFromUser(ctx, "alice", reader.ID); err != nil {
+		t.Fatalf("UnassignRoleFromUser: %v", err)
+	}
+	if ok, _ := mgr.Can(ctx, "alice", "docs/1", ActionRead); ok {
+		t.Error("expected Can to see the revocation")
+	}
+	if !can("docs/1") {
+		t.Error("expected the session to keep its snapshot")
+	}
+
+	if err := mgr.EndSession(ctx, sess.ID); err != nil {
+		t.Fatalf("EndSession: %v", err)
+	}
+	if _, err := mgr.CanForSession(ctx, sess.ID, "docs/1", ActionRead); !errors.Is(err, ErrSessionNotFound) {
+		t.Errorf("expected ErrSessionNotFound after EndSession, got %v", err)
+	}
+
+	expired := &Session{UserID: "alice", Roles: []string{auditor.ID}, ExpiresAt: time.Now().Add(-time.Second).Unix()}
+	if err := mgr.Sessions.SaveSession(ctx, expired); err != nil {
+		t.Fatalf("SaveSession: %v", err)
+	}
+	if _, err := mgr.GetSession(ctx, expired.ID); !errors.Is(err, ErrSessionNotFound) {
+		t.Errorf("expected ErrSessionNotFound for an expired session, got %v", err)
+	}
+	if _, err := mgr.CreateSession(ctx, "alice", 0); err == nil {
+		t.Error("expected an error for a zero TTL")
+	}
+}
@@ -0,0 +1,329 @@
+package rbac
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// KindSoDConstraint is passed to IDGenerator.NewID for separation-of-duties
+// constraints.
+const KindSoDConstraint = "sod_constraint"
+
+// SoDConstraint is a set of mutually exclusive roles: no user may hold more
+// than one of them, directly, through a group or by inheritance. For
+// example, payments-approver and payments-requester.
+type SoDConstraint struct {
+	ID          string   `bson:"id" json:"id"`
+	Name        string   `bson:"name" json:"name"`
+	Description string   `bson:"description,omitempty" json:"description,omitempty"`
+	Roles       []string `bson:"roles" json:"roles"` // role IDs
+	CreatedAt   int64    `bson:"created_at" json:"created_at"`
+}
+
+// SoDRepo stores separation-of-duties constraints.
+type SoDRepo interface {
+	CreateSoDConstraint(ctx context.Context, c *SoDConstraint) error
+	// GetSoDConstraint returns the constraint, or nil, nil.
+	GetSoDConstraint(ctx context.Context, id string) (*SoDConstraint, error)
+	ListSoDConstraints(ctx context.Context) ([]*SoDConstraint, error)
+	DeleteSoDConstraint(ctx context.Context, id string) error
+}
+
+var (
+	// ErrSoDConflict is wrapped by SoDConflictError; match it with
+	// errors.Is.
+	ErrSoDConflict = errors.New("rbac: separation of duties conflict")
+	// ErrSoDConstraintNotFound is returned for an unknown constraint ID.
+	ErrSoDConstraintNotFound = errors.New("rbac: separation of duties constraint not found")
+	// ErrInvalidSoDConstraint is returned by CreateSoDConstraint for a
+	// constraint without a name or with fewer than two distinct roles.
+	ErrInvalidSoDConstraint = errors.New("rbac: invalid separation of duties constraint")
+)
+
+var errNoSoDRepo = errors.New("rbac: no SoDRepo configured")
+
+// SoDConflictError is returned when an assignment would give UserID both
+// RoleID and HeldRoleID, which Constraint makes mutually exclusive.
+type SoDConflictError struct {
+	Constraint string // the constraint's name
+	UserID     string
+	RoleID     string
+	HeldRoleID string
+}
+
+func (e *SoDConflictError) Error() string {
+	return fmt.Sprintf("%v: user %q cannot hold role %q together with %q (constraint %q)",
+		ErrSoDConflict, e.UserID, e.RoleID, e.HeldRoleID, e.Constraint)
+}
+
+func (e *SoDConflictError) Unwrap() error { return ErrSoDConflict }
+
+// CreateSoDConstraint stores a set of at least two mutually exclusive roles.
+// Assignments made before it are not revisited; it applies to later ones.
+func (m *Manager) CreateSoDConstraint(ctx context.Context, c *SoDConstraint) error {
+	start := time.Now()
+	ctx, span := m.startSpan(ctx, "CreateSoDConstraint")
+	defer span.End()
+	err := m.createSoDConstraint(ctx, start, c)
+	m.record(ctx, start, "CreateSoDConstraint", err)
+	m.changed(ctx, "CreateSoDConstraint", c.ID, err)
+	return err
+}
+
+func (m *Manager) createSoDConstraint(ctx context.Context, now time.Time, c *SoDConstraint) error {
+	if m.SoD == nil {
+		return errNoSoDRepo
+	}
+	if c.Name == "" {
+		return fmt.Errorf("%w: missing name", ErrInvalidSoDConstraint)
+	}
+	roles := slices.Compact(slices.Sorted(slices.Values(c.Roles)))
+	if len(roles) < 2 || roles[0] == "" {
+		return fmt.Errorf("%w: needs two or more distinct roles", ErrInvalidSoDConstraint)
+	}
+	for _, id := range roles {
+		r, err := m.Roles.GetRoleByID(ctx, id)
+		if err != nil {
+			return err
+		}
+		if r == nil {
+			return fmt.Errorf("%w: %q", ErrRoleNotFound, id)
+		}
+	}
+	c.Roles = roles
+	c.CreatedAt = now.Unix()
+	m.assignID(&c.ID, KindSoDConstraint)
+	return m.SoD.CreateSoDConstraint(ctx, c)
+}
+
+// GetSoDConstraint returns a constraint by ID.
+func (m *Manager) GetSoDConstraint(ctx context.Context, id string) (*SoDConstraint, error) {
+	start := time.Now()
+	ctx, span := m.startSpan(ctx, "GetSoDConstraint")
+	defer span.End()
+	var (
+		c   *SoDConstraint
+		err = errNoSoDRepo
+	)
+	if m.SoD != nil {
+		c, err = m.SoD.GetSoDConstraint(ctx, id)
+		if err == nil && c == nil {
+			err = fmt.Errorf("%w: %q", ErrSoDConstraintNotFound, id)
+		}
+	}
+	m.record(ctx, start, "GetSoDConstraint", err)
+	return c, err
+}
+
+// ListSoDConstraints returns every constraint.
+func (m *Manager) ListSoDConstraints(ctx context.Context) ([]*SoDConstraint, error) {
+	start := time.Now()
+	ctx, span := m.startSpan(ctx, "ListSoDConstraints")
+	defer span.End()
+	var (
+		out []*SoDConstraint
+		err = errNoSoDRepo
+	)
+	if m.SoD != nil {
+		out, err = m.SoD.ListSoDConstraints(ctx)
+	}
+	m.record(ctx, start, "ListSoDConstraints", err)
+	return out, err
+}
+
+// DeleteSoDConstraint removes a constraint, allowing its roles to be
+// combined again.
+func (m *Manager) DeleteSoDConstraint(ctx context.Context, id string) error {
+	start := time.Now()
+	ctx, span := m.startSpan(ctx, "DeleteSoDConstraint")
+	defer span.End()
+	err := errNoSoDRepo
+	if m.SoD != nil {
+		var c *SoDConstraint
+		if c, err = m.SoD.GetSoDConstraint(ctx, id); err == nil && c == nil {
+			err = fmt.Errorf("%w: %q", ErrSoDConstraintNotFound, id)
+		}
+		if err == nil {
+			err = m.SoD.DeleteSoDConstraint(ctx, id)
+		}
+	}
+	m.record(ctx, start, "DeleteSoDConstraint", err)
+	m.changed(ctx, "DeleteSoDConstraint", id, err)
+	return err
+}
+
+// checkDuties returns a SoDConflictError when giving userID the adding
+// roles would have them hold two roles of one constraint. Both sides are
+// expanded through the role hierarchy.
+func (m *Manager) checkDuties(ctx context.Context, userID string, adding ...string) error {
+	if m.SoD == nil || len(adding) == 0 {
+		return nil
+	}
+	constraints, err := m.SoD.ListSoDConstraints(ctx)
+	if err != nil || len(constraints) == 0 {
+		return err
+	}
+	held, err := m.heldRoles(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if held, err = m.expandRoles(ctx, held); err != nil {
+		return err
+	}
+	if adding, err = m.expandRoles(ctx, adding); err != nil {
+		return err
+	}
+	for _, c := range constraints {
+		var had, add []string
+		for _, id := range held {
+			if slices.Contains(c.Roles, id) && !slices.Contains(had, id) {
+				had = append(had, id)
+			}
+		}
+		for _, id := range adding {
+			if slices.Contains(c.Roles, id) && !slices.Contains(had, id) && !slices.Contains(add, id) {
+				add = append(add, id)
+			}
+		}
+		if len(add) == 0 || len(had)+len(add) < 2 {
+			continue
+		}
+		err := &SoDConflictError{Constraint: c.Name, UserID: userID, RoleID: add[0]}
+		if len(had) > 0 {
+			err.HeldRoleID = had[0]
+		} else {
+			err.HeldRoleID = add[1]
+		}
+		return err
+	}
+	return nil
+}
+
+// checkGroupDuties runs checkDuties for each member of a group.
+func (m *Manager) checkGroupDuties(ctx context.Context, groupName string, adding ...string) error {
+	if m.SoD == nil {
+		return nil
+	}
+	members, err := m.UG.GetUsersByGroupID(ctx, groupName)
+	if err != nil {
+		return err
+	}
+	for _, ug := range activeMemberships(members, time.Now()) {
+		if err := m.checkDuties(ctx, ug.UserID, adding...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// heldRoles returns every role assigned to the user, including pending
+// scheduled, scoped and constrained ones, those of their groups and those
+// delegated to them.
+func (m *Manager) heldRoles(ctx context.Context, userID string) ([]string, error) {
+	held, err := m.assignedRoles(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	groups, err := m.UG.GetGroupsByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	groups = activeMemberships(groups, time.Now())
+	for _, ug := range groups {
+		roles, err := m.GR.ListRolesForGroup(ctx, ug.GroupName)
+		if err != nil {
+			return nil, err
+		}
+		held = append(held, roles...)
+	}
+	if repo, ok := m.GR.(ScopedGroupRoleRepo); ok {
+		for _, ug := range groups {
+			list, err := repo.ListScopedRolesForGroup(ctx, ug.GroupName)
+			if err != nil && !errors.Is(err, errScopeUnsupported) {
+				return nil, err
+			}
+			for _, sr := range list {
+				held = append(held, sr.RoleID)
+			}
+		}
+	}
+	delegated, err := m.delegatedRoles(ctx, userID, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	return append(held, delegated...), nil
+}
+
+// assignedRoles returns the roles assigned to the user directly, including
+// pending scheduled, scoped and constrained ones. It may hold duplicates.
+func (m *Manager) assignedRoles(ctx context.Context, userID string) ([]string, error) {
+	var held []string
+	if repo, ok := m.UR.(ScheduledUserRoleRepo); ok {
+		list, err := repo.ListRoleAssignments(ctx, userID)
+		if err != nil && !errors.Is(err, errSchedulingUnsupported) {
+			return nil, err
+		}
+		for _, a := range list {
+			held = append(held, a.RoleID)
+		}
+	}
+	roles, err := m.UR.ListRoles(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	held = append(held, roles...)
+	if repo, ok := m.UR.(ScopedUserRoleRepo); ok {
+		list, err := repo.ListScopedRoles(ctx, userID)
+		if err != nil && !errors.Is(err, errScopeUnsupported) {
+			return nil, err
+		}
+		for _, sr := range list {
+			held = append(held, sr.RoleID)
+		}
+	}
+	if repo, ok := m.UR.(ConstrainedUserRoleRepo); ok {
+		list, err := repo.ListConstrainedRoles(ctx, userID)
+		if err != nil && !errors.Is(err, errConstraintsUnsupported) {
+			return nil, err
+		}
+		for _, cr := range list {
+			held = append(held, cr.RoleID)
+		}
+	}
+	return held, nil
+}
+
+// checkJoinDuties runs checkDuties for the roles ug's user would gain by
+// joining the group: the group's roles, scoped ones included, and its
+// DefaultRoles.
+func (m *Manager) checkJoinDuties(ctx context.Context, ug *UserGroup) error {
+	if m.SoD == nil {
+		return nil
+	}
+	adding, err := m.GR.ListRolesForGroup(ctx, ug.GroupName)
+	if err != nil {
+		return err
+	}
+	if repo, ok := m.GR.(ScopedGroupRoleRepo); ok {
+		list, err := repo.ListScopedRolesForGroup(ctx, ug.GroupName)
+		if err != nil && !errors.Is(err, errScopeUnsupported) {
+			return err
+		}
+		for _, sr := range list {
+			adding = append(adding, sr.RoleID)
+		}
+	}
+	if m.Groups != nil {
+		g, err := m.Groups.GetGroupByName(ctx, ug.GroupName)
+		if err != nil {
+			return err
+		}
+		if g != nil {
+			adding = append(adding, g.DefaultRoles...)
+		}
+	}
+	return m.checkDuties(ctx, ug.UserID, adding...)
+}
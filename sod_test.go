@@ -0,0 +1,142 @@
+package rbac
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestSeparationOfDuties(t *testing.T) {
+	ctx := context.Background()
+	memory, err := NewMemoryStoreManager(ctx, "", 0)
+	if err != nil {
+		t.Fatalf("NewMemoryStoreManager: %v", err)
+	}
+	for name, mgr := range map[string]*Manager{"memory": memory, "mock": NewMockRepoManager(NewMockRepo())} {
+		t.Run(name, func(t *testing.T) {
+			role := func(name string) *Role {
+				t.Helper()
+				r := &Role{Name: name}
+				if err := mgr.CreateRole(ctx, r); err != nil {
+					t.Fatalf("CreateRole(%s): %v", name, err)
+				}
+				return r
+			}
+			approver, requester, auditor := role("payments-approver"), role("payments-requester"), role("auditor")
+
+			if err := mgr.CreateSoDConstraint(ctx, &SoDConstraint{Name: "payments", Roles: []string{approver.ID, approver.ID}}); !errors.Is(err, ErrInvalidSoDConstraint) {
+				t.Fatalf("expected ErrInvalidSoDConstraint for a single role, got %v", err)
+			}
+			if err := mgr.CreateSoDConstraint(ctx, &SoDConstraint{Name: "payments", Roles: []string{approver.ID, "missing"}}); !errors.Is(err, ErrRoleNotFound) {
+				t.Fatalf("expected ErrRoleNotFound, got %v", err)
+			}
+			c := &SoDConstraint{Name: "payments", Roles: []string{approver.ID, requester.ID}}
+			if err := mgr.CreateSoDConstraint(ctx, c); err != nil {
+				t.Fatalf("CreateSoDConstraint: %v", err)
+			}
+
+			if err := mgr.AssignRoleToUser(ctx, "alice", approver.ID); err != nil {
+				t.Fatalf("AssignRoleToUser: %v", err)
+			}
+			if err := mgr.AssignRoleToUser(ctx, "alice", auditor.ID); err != nil {
+				t.Fatalf("AssignRoleToUser(auditor): %v", err)
+			}
+			if err := mgr.AssignRoleToUser(ctx, "alice", approver.ID); err != nil {
+				t.Fatalf("reassigning a held role: %v", err)
+			}
+			err := mgr.AssignRoleToUser(ctx, "alice", requester.ID)
+			var conflict *SoDConflictError
+			if !errors.As(err, &conflict) || !errors.Is(err, ErrSoDConflict) {
+				t.Fatalf("expected a SoDConflictError, got %v", err)
+			}
+			if conflict.Constraint != "payments" || conflict.RoleID != requester.ID || conflict.HeldRoleID != approver.ID {
+				t.Errorf("unexpected conflict: %+v", conflict)
+			}
+
+			// through a group, in either order
+			if err := mgr.AssignRoleToGroup(ctx, "requesters", requester.ID); err != nil {
+				t.Fatalf("AssignRoleToGroup: %v", err)
+			}
+			if err := mgr.AddUserToGroup(ctx, &UserGroup{UserID: "alice", GroupName: "requesters"}); !errors.Is(err, ErrSoDConflict) {
+				t.Errorf("joining a group with a conflicting role: expected ErrSoDConflict, got %v", err)
+			}
+			if err := mgr.AddUserToGroup(ctx, &UserGroup{UserID: "bob", GroupName: "approvers"}); err != nil {
+				t.Fatalf("AddUserToGroup: %v", err)
+			}
+			if err := mgr.AssignRoleToUser(ctx, "bob", requester.ID); err != nil {
+				t.Fatalf("AssignRoleToUser(bob): %v", err)
+			}
+			if err := mgr.AssignRoleToGroup(ctx, "approvers", approver.ID); !errors.Is(err, ErrSoDConflict) {
+				t.Errorf("giving a member's group a conflicting role: expected ErrSoDConflict, got %v", err)
+			}
+
+			list, err := mgr.ListSoDConstraints(ctx)
+			if err != nil || len(list) != 1 || list[0].ID != c.ID {
+				t.Fatalf("ListSoDConstraints = %+v, %v", list, err)
+			}
+			if err := mgr.DeleteSoDConstraint(ctx, c.ID); err != nil {
+				t.Fatalf("DeleteSoDConstraint: %v", err)
+			}
+			if err := mgr.DeleteSoDConstraint(ctx, c.ID); !errors.Is(err, ErrSoDConstraintNotFound) {
+				t.Errorf("expected ErrSoDConstraintNotFound, got %v", err)
+			}
+			if err := mgr.AssignRoleToUser(ctx, "alice", requester.ID); err != nil {
+				t.Errorf("AssignRoleToUser after deleting the constraint: %v", err)
+			}
+		})
+	}
+}
+
+func TestSeparationOfDutiesInheritance(t *testing.T) {
+	ctx := context.Background()
+	mgr, err := NewMemoryStoreManager(ctx, "", 0)
+	if err != nil {
+		t.Fatalf("NewMemoryStoreManager: %v", err)
+	}
+	approver, requester, lead := &Role{Name: "approver"}, &Role{Name: "requester"}, &Role{Name: "finance-lead"}
+	for _, r := range []*Role{approver, requester, lead} {
+		if err := mgr.CreateRole(ctx, r); err != nil {
+			t.Fatalf("CreateRole: %v", err)
+		}
+	}
+	if err := mgr.AddRoleParent(ctx, lead.ID, approver.ID); err != nil {
+		t.Fatalf("AddRoleParent: %v", err)
+	}
+	if err := mgr.CreateSoDConstraint(ctx, &SoDConstraint{Name: "payments", Roles: []string{approver.ID, requester.ID}}); err != nil {
+		t.Fatalf("CreateSoDConstraint: %v", err)
+	}
+	if err := mgr.AssignRoleToUser(ctx, "alice", requester.ID); err != nil {
+		t.Fatalf("AssignRoleToUser: %v", err)
+	}
+	if err := mgr.AssignRoleToUser(ctx, "alice", lead.ID); !errors.Is(err, ErrSoDConflict) {
+		t.Errorf("a role inheriting a conflicting role: expected ErrSoDConflict, got %v", err)
+	}
+}
+
+func TestSeparationOfDutiesForTenant(t *testing.T) {
+	ctx := context.Background()
+	mgr, err := NewMemoryStoreManager(ctx, "", 0)
+	if err != nil {
+		t.Fatalf("NewMemoryStoreManager: %v", err)
+	}
+	acme := mgr.ForTenant("acme")
+	approver, requester := &Role{Name: "approver"}, &Role{Name: "requester"}
+	for _, r := range []*Role{approver, requester} {
+		if err := acme.CreateRole(ctx, r); err != nil {
+			t.Fatalf("CreateRole: %v", err)
+		}
+	}
+	alice := &User{Username: "alice"}
+	if err := acme.CreateUser(ctx, alice); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	if err := mgr.CreateSoDConstraint(ctx, &SoDConstraint{Name: "payments", Roles: []string{approver.ID, requester.ID}}); err != nil {
+		t.Fatalf("CreateSoDConstraint: %v", err)
+	}
+	if err := acme.AssignRoleToUser(ctx, alice.ID, requester.ID); err != nil {
+		t.Fatalf("AssignRoleToUser: %v", err)
+	}
+	if err := acme.AssignRoleToUser(ctx, alice.ID, approver.ID); !errors.Is(err, ErrSoDConflict) {
+		t.Errorf("tenant manager: expected ErrSoDConflict, got %v", err)
+	}
+}
@@ -0,0 +1,130 @@
+package rbac
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrPermissionNotFound is returned for an unknown permission ID, such as
+// when restoring one or adding one to a permission set.
+var ErrPermissionNotFound = errors.New("rbac: permission not found")
+
+var errSoftDeleteUnsupported = errors.New("rbac: repo does not support soft deletes")
+
+// SoftDeleteRepo is optionally implemented by repos that can mark roles and
+// permissions deleted while keeping them, and their assignments, for a later
+// restore. Manager.DeleteRole and DeletePermission use it when present.
+type SoftDeleteRepo interface {
+	// SetRoleDeletedAt sets the role's DeletedAt; 0 restores it.
+	SetRoleDeletedAt(ctx context.Context, id string, at int64) error
+	// SetPermissionDeletedAt sets the permission's DeletedAt; 0 restores it.
+	SetPermissionDeletedAt(ctx context.Context, id string, at int64) error
+}
+
+// DeleteRole soft-deletes the role when the repo implements SoftDeleteRepo:
+// Can ignores it, but it keeps its name, permissions and holders until
+// RestoreRole or PurgeRole. Other repos delete it for good, as PurgeRole
+// does.
+func (m *Manager) DeleteRole(ctx context.Context, id string) error {
+	start := time.Now()
+	ctx, span := m.startSpan(ctx, "DeleteRole")
+	defer span.End()
+	var err error
+	if sd, ok := m.Roles.(SoftDeleteRepo); ok {
+		err = sd.SetRoleDeletedAt(ctx, id, time.Now().Unix())
+	} else {
+		err = m.purgeRole(ctx, id)
+	}
+	m.record(ctx, start, "DeleteRole", err)
+	m.changedRole(ctx, "DeleteRole", id, err)
+	m.emit(err, func(l Listener) { l.OnRoleDeleted(ctx, id) })
+	return err
+}
+
+// RestoreRole undoes DeleteRole.
+func (m *Manager) RestoreRole(ctx context.Context, id string) error {
+	start := time.Now()
+	ctx, span := m.startSpan(ctx, "RestoreRole")
+	defer span.End()
+	err := errSoftDeleteUnsupported
+	if sd, ok := m.Roles.(SoftDeleteRepo); ok {
+		var r *Role
+		if r, err = m.Roles.GetRoleByID(ctx, id); err == nil && r == nil {
+			err = fmt.Errorf("%w: %q", ErrRoleNotFound, id)
+		}
+		if err == nil && r.DeletedAt != 0 {
+			err = sd.SetRoleDeletedAt(ctx, id, 0)
+		}
+	}
+	m.record(ctx, start, "RestoreRole", err)
+	m.changedRole(ctx, "RestoreRole", id, err)
+	return err
+}
+
+// PurgeRole deletes the role for good, whether or not it was soft-deleted,
+// along with its assignments; see removeRoleEdges.
+func (m *Manager) PurgeRole(ctx context.Context, id string) error {
+	start := time.Now()
+	ctx, span := m.startSpan(ctx, "PurgeRole")
+	defer span.End()
+	err := m.purgeRole(ctx, id)
+	m.record(ctx, start, "PurgeRole", err)
+	m.changedRole(ctx, "PurgeRole", id, err)
+	m.emit(err, func(l Listener) { l.OnRoleDeleted(ctx, id) })
+	return err
+}
+
+// DeletePermission soft-deletes the permission when the repo implements
+// SoftDeleteRepo: Can ignores it, but it stays bound to its roles until
+// RestorePermission or PurgePermission. Other repos delete it for good, as
+// PurgePermission does.
+func (m *Manager) DeletePermission(ctx context.Context, id string) error {
+	start := time.Now()
+	ctx, span := m.startSpan(ctx, "DeletePermission")
+	defer span.End()
+	var err error
+	if sd, ok := m.Perms.(SoftDeleteRepo); ok {
+		err = sd.SetPermissionDeletedAt(ctx, id, time.Now().Unix())
+	} else {
+		err = m.purgePermission(ctx, id)
+	}
+	m.record(ctx, start, "DeletePermission", err)
+	m.changed(ctx, "DeletePermission", id, err)
+	m.emit(err, func(l Listener) { l.OnPermissionDeleted(ctx, id) })
+	return err
+}
+
+// RestorePermission undoes DeletePermission.
+func (m *Manager) RestorePermission(ctx context.Context, id string) error {
+	start := time.Now()
+	ctx, span := m.startSpan(ctx, "RestorePermission")
+	defer span.End()
+	err := errSoftDeleteUnsupported
+	if sd, ok := m.Perms.(SoftDeleteRepo); ok {
+		var p *Permission
+		if p, err = m.Perms.GetPermissionByID(ctx, id); err == nil && p == nil {
+			err = fmt.Errorf("%w: %q", ErrPermissionNotFound, id)
+		}
+		if err == nil && p.DeletedAt != 0 {
+			err = sd.SetPermissionDeletedAt(ctx, id, 0)
+		}
+	}
+	m.record(ctx, start, "RestorePermission", err)
+	m.changed(ctx, "RestorePermission", id, err)
+	return err
+}
+
+// PurgePermission deletes the permission for good, whether or not it was
+// soft-deleted, and unbinds it from every role.
+func (m *Manager) PurgePermission(ctx context.Context, id string) error {
+	start := time.Now()
+	ctx, span := m.startSpan(ctx, "PurgePermission")
+	defer span.End()
+	err := m.purgePermission(ctx, id)
+	m.record(ctx, start, "PurgePermission", err)
+	m.changed(ctx, "PurgePermission", id, err)
+	m.emit(err, func(l Listener) { l.OnPermissionDeleted(ctx, id) })
+	return err
+}
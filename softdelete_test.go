@@ -0,0 +1,100 @@
+package rbac
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestSoftDeleteAndRestore(t *testing.T) {
+	ctx := context.Background()
+	mgr, err := NewMemoryStoreManager(ctx, "", 0)
+	if err != nil {
+		t.Fatalf("NewMemoryStoreManager: %v", err)
+	}
+	role := &Role{Name: "editor"}
+	if err := mgr.CreateRole(ctx, role); err != nil {
+		t.Fatalf("CreateRole: %v", err)
+	}
+	edit := &Permission{Resource: "docs/*", Action: ActionUpdate}
+	read := &Permission{Resource: "docs/*", Action: ActionRead}
+	for _, p := range []*Permission{edit, read} {
+		if err := mgr.CreatePermission(ctx, p); err != nil {
+			t.Fatalf("CreatePermission: %v", err)
+		}
+		if err := mgr.AssignPermissionToRole(ctx, role.ID, p.ID); err != nil {
+			t.Fatalf("AssignPermissionToRole: %v", err)
+		}
+	}
+	if err := mgr.AssignRoleToUser(ctx, "alice", role.ID); err != nil {
+		t.Fatalf("AssignRoleToUser: %v", err)
+	}
+	can := func(action Action) bool {
+		t.Helper()
+		ok, err := mgr.Can(ctx, "alice", "docs/1", action)
+		if err != nil {
+			t.Fatalf("Can: %v", err)
+		}
+		return ok
+	}
+
+	if err := mgr.DeletePermission(ctx, edit.ID); err != nil {
+		t.Fatalf("DeletePermission: %v", err)
+	}
+	if can(ActionUpdate) || !can(ActionRead) {
+		t.Error("expected only the soft-deleted permission to stop granting")
+	}
+	if p, _ := mgr.GetPermission(ctx, edit.ID); p == nil || p.DeletedAt == 0 {
+		t.Errorf("expected the permission to be kept with DeletedAt, got %+v", p)
+	}
+	// creating the same permission again brings it back
+	again := &Permission{Resource: "docs/*", Action: ActionUpdate}
+	if err := mgr.CreatePermission(ctx, again); err != nil || again.ID != edit.ID || again.DeletedAt != 0 {
+		t.Fatalf("CreatePermission of a deleted permission = %+v, %v", again, err)
+	}
+	if !can(ActionUpdate) {
+		t.Error("expected the re-created permission to grant again")
+	}
+
+	if err := mgr.DeleteRole(ctx, role.ID); err != nil {
+		t.Fatalf("DeleteRole: %v", err)
+	}
+	if can(ActionRead) {
+		t.Error("expected a soft-deleted role to grant nothing")
+	}
+	if err := mgr.CreateRole(ctx, &Role{Name: "editor"}); !errors.Is(err, ErrRoleNameTaken) {
+		t.Errorf("expected a soft-deleted role to keep its name, got %v", err)
+	}
+	if err := mgr.RestoreRole(ctx, role.ID); err != nil {
+		t.Fatalf("RestoreRole: %v", err)
+	}
+	if !can(ActionRead) || !can(ActionUpdate) {
+		t.Error("expected the restored role to grant with its permissions and holders")
+	}
+
+	if err := mgr.DeletePermission(ctx, read.ID); err != nil {
+		t.Fatalf("DeletePermission: %v", err)
+	}
+	if err := mgr.RestorePermission(ctx, read.ID); err != nil {
+		t.Fatalf("RestorePermission: %v", err)
+	}
+	if !can(ActionRead) {
+		t.Error("expected the restored permission to grant again")
+	}
+
+	if err := mgr.PurgeRole(ctx, role.ID); err != nil {
+		t.Fatalf("PurgeRole: %v", err)
+	}
+	if r, _ := mgr.GetRole(ctx, role.ID); r != nil {
+		t.Errorf("expected the purged role to be gone, got %+v", r)
+	}
+	if err := mgr.RestoreRole(ctx, role.ID); !errors.Is(err, ErrRoleNotFound) {
+		t.Errorf("expected ErrRoleNotFound restoring a purged role, got %v", err)
+	}
+	if err := mgr.PurgePermission(ctx, read.ID); err != nil {
+		t.Fatalf("PurgePermission: %v", err)
+	}
+	if err := mgr.RestorePermission(ctx, read.ID); !errors.Is(err, ErrPermissionNotFound) {
+		t.Errorf("expected ErrPermissionNotFound restoring a purged permission, got %v", err)
+	}
+}
@@ -0,0 +1,142 @@
+package rbac
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// Assignment sources record who manages an edge (a user's role, a role's
+// permission, a group member or a group's role), so automation only removes
+// the edges it created.
+const (
+	SourceManual        = "manual"
+	SourceBundle        = "bundle"
+	SourceDirectorySync = "directory_sync"
+	SourceRule          = "rule"
+	// SourceGroupDefault marks user roles granted by a group's
+	// DefaultRoles.
+	SourceGroupDefault = "group_default"
+
+	// SourceAny lets a removal proceed whoever manages the edge.
+	SourceAny = "*"
+)
+
+// ErrManagedElsewhere is returned when removing an edge that another source
+// manages.
+var ErrManagedElsewhere = errors.New("rbac: assignment is managed by another source")
+
+var errSourceUnsupported = errors.New("rbac: repo does not track assignment sources")
+
+type sourceKey struct{}
+
+// WithAssignmentSource returns a context whose writes are attributed to
+// source. Stores that implement EdgeSourceRepo record it on new edges; an
+// edge keeps its first source, except that a manual assignment claims an
+// existing edge so automation stops managing it. Manager removals made with
+// the context only touch edges managed by source.
+func WithAssignmentSource(ctx context.Context, source string) context.Context {
+	return context.WithValue(ctx, sourceKey{}, source)
+}
+
+// AssignmentSource returns the source set by WithAssignmentSource, or
+// SourceManual.
+func AssignmentSource(ctx context.Context) string {
+	if s, _ := ctx.Value(sourceKey{}).(string); s != "" {
+		return s
+	}
+	return SourceManual
+}
+
+// EdgeSourceRepo is optionally implemented by join repos that record the
+// source of each edge. kind is KindUserRole, KindRolePermission,
+// KindUserGroup or KindGroupRole, and from and to are the edge's ends in the
+// order the repos take them (user → role, role → permission, user → group,
+// group → role).
+type EdgeSourceRepo interface {
+	// EdgeSource returns the edge's source, SourceManual for edges recorded
+	// without one, and "" when the edge does not exist.
+	EdgeSource(ctx context.Context, kind, from, to string) (string, error)
+}
+
+// EdgeSource returns who manages an edge; see EdgeSourceRepo. It fails when
+// the repo holding edges of kind does not track sources.
+func (m *Manager) EdgeSource(ctx context.Context, kind, from, to string) (string, error) {
+	repo, ok := m.edgeRepo(kind).(EdgeSourceRepo)
+	if !ok {
+		return "", errSourceUnsupported
+	}
+	return repo.EdgeSource(ctx, kind, from, to)
+}
+
+// checkSource fails with ErrManagedElsewhere when the edge exists and is
+// managed by a source other than ctx's. Repos that do not track sources
+// allow every removal.
+func (m *Manager) checkSource(ctx context.Context, kind, from, to string) error {
+	want := AssignmentSource(ctx)
+	if want == SourceAny {
+		return nil
+	}
+	owner, err := m.EdgeSource(ctx, kind, from, to)
+	if errors.Is(err, errSourceUnsupported) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if owner != "" && owner != want {
+		return fmt.Errorf("%w: %s %s → %s is managed by %s", ErrManagedElsewhere, kind, from, to, owner)
+	}
+	return nil
+}
+
+func (m *Manager) edgeRepo(kind string) any {
+	switch kind {
+	case KindUserRole:
+		return m.UR
+	case KindRolePermission:
+		return m.RP
+	case KindUserGroup:
+		return m.UG
+	case KindGroupRole:
+		return m.GR
+	}
+	return nil
+}
+
+// edgeKey identifies an edge in the sources maps of the in-memory stores.
+type edgeKey struct {
+	Kind, From, To string
+}
+
+// ManagedEdge is an edge with a source other than SourceManual, as stored
+// in a MemorySnapshot.
+type ManagedEdge struct {
+	Kind   string `json:"kind"`
+	From   string `json:"from"`
+	To     string `json:"to"`
+	Source string `json:"source"`
+}
+
+// recordSource applies the source rules of WithAssignmentSource to an edge
+// being written to sources; existed says whether the edge was already there.
+func recordSource(ctx context.Context, sources map[edgeKey]string, k edgeKey, existed bool) {
+	src := AssignmentSource(ctx)
+	switch {
+	case src == SourceManual || src == SourceAny:
+		delete(sources, k)
+	case !existed:
+		sources[k] = src
+	}
+}
+
+// lookupSource implements EdgeSource for the in-memory stores.
+func lookupSource(sources map[edgeKey]string, k edgeKey, exists bool) string {
+	if !exists {
+		return ""
+	}
+	if s, ok := sources[k]; ok {
+		return s
+	}
+	return SourceManual
+}
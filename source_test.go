@@ -0,0 +1,67 @@
+package rbac
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestAssignmentSource(t *testing.T) {
+	ctx := context.Background()
+	mgr, err := NewMemoryStoreManager(ctx, "", 0)
+	if err != nil {
+		t.Fatalf("NewMemoryStoreManager: %v", err)
+	}
+	sync := WithAssignmentSource(ctx, SourceDirectorySync)
+	rules := WithAssignmentSource(ctx, SourceRule)
+
+	source := func(from, to string, want string) {
+		t.Helper()
+		if got, err := mgr.EdgeSource(ctx, KindUserRole, from, to); got != want || err != nil {
+			t.Errorf("EdgeSource(%s, %s) = %q, %v; want %q", from, to, got, err, want)
+		}
+	}
+
+	_ = mgr.AssignRoleToUser(ctx, "alice", "manual-role")
+	_ = mgr.AssignRoleToUser(sync, "alice", "synced-role")
+	source("alice", "manual-role", SourceManual)
+	source("alice", "synced-role", SourceDirectorySync)
+	source("alice", "missing", "")
+
+	// Automation does not take over a manual grant, or another source's.
+	_ = mgr.AssignRoleToUser(sync, "alice", "manual-role")
+	_ = mgr.AssignRoleToUser(rules, "alice", "synced-role")
+	source("alice", "manual-role", SourceManual)
+	source("alice", "synced-role", SourceDirectorySync)
+
+	if err := mgr.UnassignRoleFromUser(sync, "alice", "manual-role"); !errors.Is(err, ErrManagedElsewhere) {
+		t.Errorf("expected the sync to be refused a manual grant, got %v", err)
+	}
+	if err := mgr.UnassignRoleFromUser(ctx, "alice", "synced-role"); !errors.Is(err, ErrManagedElsewhere) {
+		t.Errorf("expected a manual removal to be refused a synced grant, got %v", err)
+	}
+
+	// The memory store keeps sources across snapshots.
+	var buf bytes.Buffer
+	store := mgr.UR.(*MemoryStore)
+	if err := store.WriteSnapshot(&buf); err != nil {
+		t.Fatalf("WriteSnapshot: %v", err)
+	}
+	if err := store.LoadSnapshot(&buf); err != nil {
+		t.Fatalf("LoadSnapshot: %v", err)
+	}
+	source("alice", "synced-role", SourceDirectorySync)
+
+	// A manual assignment claims the edge.
+	_ = mgr.AssignRoleToUser(ctx, "alice", "synced-role")
+	source("alice", "synced-role", SourceManual)
+	if err := mgr.UnassignRoleFromUser(ctx, "alice", "synced-role"); err != nil {
+		t.Errorf("UnassignRoleFromUser: %v", err)
+	}
+
+	if err := mgr.UnassignRoleFromUser(WithAssignmentSource(sync, SourceAny), "alice", "manual-role"); err != nil {
+		t.Errorf("expected SourceAny to remove any edge, got %v", err)
+	}
+	source("alice", "manual-role", "")
+}
@@ -0,0 +1,831 @@
+// file: rbac/spanner_store.go
+package rbac
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/spanner"
+	database "cloud.google.com/go/spanner/admin/database/apiv1"
+	"cloud.google.com/go/spanner/admin/database/apiv1/databasepb"
+	"google.golang.org/api/iterator"
+	"google.golang.org/grpc/codes"
+)
+
+// Ensure SpannerStore implements all interfaces:
+var (
+	_ PermissionRepo           = (*SpannerStore)(nil)
+	_ RoleRepo                 = (*SpannerStore)(nil)
+	_ UserRepo                 = (*SpannerStore)(nil)
+	_ UserStatusRepo           = (*SpannerStore)(nil)
+	_ EmailVerificationRepo    = (*SpannerStore)(nil)
+	_ RolePermissionRepo       = (*SpannerStore)(nil)
+	_ UserRoleRepo             = (*SpannerStore)(nil)
+	_ UserGroupRepo            = (*SpannerStore)(nil)
+	_ GroupRoleRepo            = (*SpannerStore)(nil)
+	_ ExpiringMembershipLister = (*SpannerStore)(nil)
+	_ HealthChecker            = (*SpannerStore)(nil)
+)
+
+//
+// ---------- Schema ----------
+//
+
+// spannerSchema lists every table and index in creation order, followed by
+// columns added to existing tables, named table.column. role_permissions and
+// user_roles are interleaved in roles and users, so a role's permissions and
+// a user's roles are stored next to their parent row and removed with it.
+var spannerSchema = []struct {
+	name string
+	ddl  string
+}{
+	{"permissions", `CREATE TABLE permissions (
+		id         STRING(MAX) NOT NULL,
+		resource   STRING(MAX) NOT NULL,
+		action     STRING(MAX) NOT NULL,
+		created_at INT64 NOT NULL,
+	) PRIMARY KEY (id)`},
+	{"permissions_by_resource", `CREATE UNIQUE INDEX permissions_by_resource ON permissions (resource, action)`},
+	{"permissions.effect", `ALTER TABLE permissions ADD COLUMN effect STRING(MAX)`},
+	{"permissions.condition", `ALTER TABLE permissions ADD COLUMN condition STRING(MAX)`},
+	{"permissions.updated_at", `ALTER TABLE permissions ADD COLUMN updated_at INT64`},
+	{"permissions.created_by", `ALTER TABLE permissions ADD COLUMN created_by STRING(MAX)`},
+	{"permissions.updated_by", `ALTER TABLE permissions ADD COLUMN updated_by STRING(MAX)`},
+	{"permissions.name", `ALTER TABLE permissions ADD COLUMN name STRING(MAX)`},
+	{"permissions.description", `ALTER TABLE permissions ADD COLUMN description STRING(MAX)`},
+	{"permissions.labels", `ALTER TABLE permissions ADD COLUMN labels STRING(MAX)`},
+	{"permissions.tenant_id", `ALTER TABLE permissions ADD COLUMN tenant_id STRING(MAX)`},
+
+	{"roles", `CREATE TABLE roles (
+		id          STRING(MAX) NOT NULL,
+		name        STRING(MAX) NOT NULL,
+		description STRING(MAX),
+		created_at  INT64 NOT NULL,
+	) PRIMARY KEY (id)`},
+	{"roles_by_name", `CREATE UNIQUE INDEX roles_by_name ON roles (name)`},
+	{"roles.meta", `ALTER TABLE roles ADD COLUMN meta JSON`},
+	{"roles.updated_at", `ALTER TABLE roles ADD COLUMN updated_at INT64`},
+	{"roles.created_by", `ALTER TABLE roles ADD COLUMN created_by STRING(MAX)`},
+	{"roles.updated_by", `ALTER TABLE roles ADD COLUMN updated_by STRING(MAX)`},
+	{"roles.template", `ALTER TABLE roles ADD COLUMN template BOOL`},
+	{"roles.priority", `ALTER TABLE roles ADD COLUMN priority INT64`},
+	{"roles.generators", `ALTER TABLE roles ADD COLUMN generators STRING(MAX)`},
+	{"roles.tenant_id", `ALTER TABLE roles ADD COLUMN tenant_id STRING(MAX)`},
+
+	{"users", `CREATE TABLE users (
+		id         STRING(MAX) NOT NULL,
+		username   STRING(MAX) NOT NULL,
+		email      STRING(MAX),
+		meta       JSON,
+		created_at INT64 NOT NULL,
+	) PRIMARY KEY (id)`},
+	{"users_by_username", `CREATE UNIQUE INDEX users_by_username ON users (username)`},
+	{"users_by_email", `CREATE UNIQUE NULL_FILTERED INDEX users_by_email ON users (email)`},
+	{"users.updated_at", `ALTER TABLE users ADD COLUMN updated_at INT64`},
+	{"users.created_by", `ALTER TABLE users ADD COLUMN created_by STRING(MAX)`},
+	{"users.updated_by", `ALTER TABLE users ADD COLUMN updated_by STRING(MAX)`},
+	{"users.email_verified", `ALTER TABLE users ADD COLUMN email_verified BOOL`},
+	{"users.status", `ALTER TABLE users ADD COLUMN status STRING(MAX)`},
+	{"users.tenant_id", `ALTER TABLE users ADD COLUMN tenant_id STRING(MAX)`},
+
+	{"role_permissions", `CREATE TABLE role_permissions (
+		role_id       STRING(MAX) NOT NULL,
+		permission_id STRING(MAX) NOT NULL,
+		created_at    INT64 NOT NULL,
+	) PRIMARY KEY (role_id, permission_id),
+	  INTERLEAVE IN PARENT roles ON DELETE CASCADE`},
+	{"role_permissions_by_permission", `CREATE INDEX role_permissions_by_permission ON role_permissions (permission_id)`},
+
+	{"user_roles", `CREATE TABLE user_roles (
+		user_id     STRING(MAX) NOT NULL,
+		role_id     STRING(MAX) NOT NULL,
+		assigned_at INT64 NOT NULL,
+	) PRIMARY KEY (user_id, role_id),
+	  INTERLEAVE IN PARENT users ON DELETE CASCADE`},
+	{"user_roles_by_role", `CREATE INDEX user_roles_by_role ON user_roles (role_id)`},
+
+	{"user_groups", `CREATE TABLE user_groups (
+		user_id    STRING(MAX) NOT NULL,
+		group_name STRING(MAX) NOT NULL,
+		id         STRING(MAX) NOT NULL,
+		created_at INT64 NOT NULL,
+	) PRIMARY KEY (user_id, group_name)`},
+	{"user_groups_by_group", `CREATE INDEX user_groups_by_group ON user_groups (group_name)`},
+	{"user_groups.updated_at", `ALTER TABLE user_groups ADD COLUMN updated_at INT64`},
+	{"user_groups.created_by", `ALTER TABLE user_groups ADD COLUMN created_by STRING(MAX)`},
+	{"user_groups.updated_by", `ALTER TABLE user_groups ADD COLUMN updated_by STRING(MAX)`},
+	{"user_groups.level", `ALTER TABLE user_groups ADD COLUMN level STRING(MAX)`},
+	{"user_groups.expires_at", `ALTER TABLE user_groups ADD COLUMN expires_at INT64`},
+	{"user_groups.tenant_id", `ALTER TABLE user_groups ADD COLUMN tenant_id STRING(MAX)`},
+	{"user_groups_by_expiry", `CREATE INDEX user_groups_by_expiry ON user_groups (expires_at)`},
+
+	{"group_roles", `CREATE TABLE group_roles (
+		group_name STRING(MAX) NOT NULL,
+		role_id    STRING(MAX) NOT NULL,
+		created_at INT64 NOT NULL,
+	) PRIMARY KEY (group_name, role_id)`},
+	{"group_roles_by_role", `CREATE INDEX group_roles_by_role ON group_roles (role_id)`},
+}
+
+// EnsureSpannerSchema creates whichever tables and indexes are missing from
+// the client's database.
+func EnsureSpannerSchema(ctx context.Context, client *spanner.Client, admin *database.DatabaseAdminClient) error {
+	existing := map[string]bool{}
+	iter := client.Single().Query(ctx, spanner.Statement{SQL: `
+		SELECT table_name FROM information_schema.tables WHERE table_schema = ''
+		UNION ALL
+		SELECT index_name FROM information_schema.indexes WHERE table_schema = '' AND index_type = 'INDEX'
+		UNION ALL
+		SELECT CONCAT(table_name, '.', column_name) FROM information_schema.columns WHERE table_schema = ''`})
+	err := iter.Do(func(r *spanner.Row) error {
+		var name string
+		if err := r.Columns(&name); err != nil {
+			return err
+		}
+		existing[name] = true
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	var stmts []string
+	for _, obj := range spannerSchema {
+		if !existing[obj.name] {
+			stmts = append(stmts, obj.ddl)
+		}
+	}
+	if len(stmts) == 0 {
+		return nil
+	}
+
+	op, err := admin.UpdateDatabaseDdl(ctx, &databasepb.UpdateDatabaseDdlRequest{
+		Database:   client.DatabaseName(),
+		Statements: stmts,
+	})
+	if err != nil {
+		return err
+	}
+	return op.Wait(ctx)
+}
+
+//
+// ---------- SpannerStore Core ----------
+//
+
+// SpannerStore is a Cloud Spanner backed store. Reads use strong single-use
+// transactions, so every check sees the latest committed policy.
+type SpannerStore struct {
+	client *spanner.Client
+	ids    IDGenerator
+}
+
+// NewSpannerStore creates the store. When admin is non-nil the schema is
+// created or completed first; pass nil when the schema is managed elsewhere.
+func NewSpannerStore(ctx context.Context, client *spanner.Client, admin *database.DatabaseAdminClient) (*SpannerStore, error) {
+	if admin != nil {
+		if err := EnsureSpannerSchema(ctx, client, admin); err != nil {
+			return nil, fmt.Errorf("spanner_store: ensure schema: %w", err)
+		}
+	}
+	return &SpannerStore{client: client}, nil
+}
+
+// SetIDGenerator changes how the store generates IDs for new entities.
+func (s *SpannerStore) SetIDGenerator(g IDGenerator) {
+	s.ids = g
+}
+
+// Health implements HealthChecker with a single-use SELECT 1.
+func (s *SpannerStore) Health(ctx context.Context) error {
+	err := s.client.Single().Query(ctx, spanner.Statement{SQL: `SELECT 1`}).Do(func(*spanner.Row) error { return nil })
+	if err != nil {
+		return fmt.Errorf("spanner_store: %w", err)
+	}
+	return nil
+}
+
+// NewSpannerStoreManager wraps the store in a Manager and seeds the default role.
+func NewSpannerStoreManager(ctx context.Context, client *spanner.Client, admin *database.DatabaseAdminClient) (*Manager, error) {
+	s, err := NewSpannerStore(ctx, client, admin)
+	if err != nil {
+		return nil, err
+	}
+
+	def, _ := s.GetRoleByName(ctx, "default")
+	if def == nil {
+		def = &Role{Name: "default", Description: "Default role"}
+		if createErr := s.CreateRole(ctx, def); createErr != nil {
+			return nil, fmt.Errorf("failed to create default role: %w", createErr)
+		}
+	}
+
+	return &Manager{
+		Perms:           s,
+		Roles:           s,
+		Users:           s,
+		RP:              s,
+		UR:              s,
+		UG:              s,
+		GR:              s,
+		DefaultRoleName: "default",
+	}, nil
+}
+
+// readRow reads the row with the given key into ptrs, reporting false when
+// it does not exist.
+func (s *SpannerStore) readRow(ctx context.Context, table string, key spanner.Key, cols []string, ptrs ...interface{}) (bool, error) {
+	row, err := s.client.Single().ReadRow(ctx, table, key, cols)
+	if spanner.ErrCode(err) == codes.NotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, row.Columns(ptrs...)
+}
+
+// spannerQuerier is satisfied by both read-only and read-write transactions.
+type spannerQuerier interface {
+	Query(context.Context, spanner.Statement) *spanner.RowIterator
+}
+
+// queryFirst runs stmt and reads the first row into ptrs, reporting false when
+// there are no rows.
+func queryFirst(ctx context.Context, q spannerQuerier, stmt spanner.Statement, ptrs ...interface{}) (bool, error) {
+	iter := q.Query(ctx, stmt)
+	defer iter.Stop()
+
+	row, err := iter.Next()
+	if errors.Is(err, iterator.Done) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, row.Columns(ptrs...)
+}
+
+func (s *SpannerStore) queryStrings(ctx context.Context, stmt spanner.Statement) ([]string, error) {
+	var out []string
+	err := s.client.Single().Query(ctx, stmt).Do(func(r *spanner.Row) error {
+		var v string
+		if err := r.Columns(&v); err != nil {
+			return err
+		}
+		out = append(out, v)
+		return nil
+	})
+	return out, err
+}
+
+//
+// ---------- UserRepo ----------
+//
+
+var spannerUserCols = []string{"id", "username", "email", "meta", "created_at", "updated_at", "created_by", "updated_by", "email_verified", "status", "tenant_id"}
+
+func (s *SpannerStore) GetUserByID(ctx context.Context, id string) (*User, error) {
+	var u spannerUser
+	ok, err := s.readRow(ctx, "users", spanner.Key{id}, spannerUserCols, u.ptrs()...)
+	if err != nil || !ok {
+		return nil, err
+	}
+	return u.user()
+}
+
+func (s *SpannerStore) GetUserByMeta(ctx context.Context, meta map[string]interface{}) (*User, error) {
+	allowed := map[string]bool{"id": true, "username": true, "email": true}
+	var clauses []string
+	params := map[string]interface{}{}
+	for k, v := range meta {
+		if !allowed[k] {
+			return nil, fmt.Errorf("GetUserByMeta: unsupported field %q", k)
+		}
+		clauses = append(clauses, k+" = @"+k)
+		params[k] = fmt.Sprint(v)
+	}
+	if len(clauses) == 0 {
+		return nil, errors.New("GetUserByMeta: no filter provided")
+	}
+
+	var u spannerUser
+	ok, err := queryFirst(ctx, s.client.Single(), spanner.Statement{
+		SQL:    "SELECT " + strings.Join(spannerUserCols, ", ") + " FROM users WHERE " + strings.Join(clauses, " AND ") + " LIMIT 1",
+		Params: params,
+	}, u.ptrs()...)
+	if err != nil || !ok {
+		return nil, err
+	}
+	return u.user()
+}
+
+func (s *SpannerStore) ListAllUsers(ctx context.Context) ([]*User, error) {
+	var out []*User
+	err := s.client.Single().Query(ctx, spanner.Statement{
+		SQL: "SELECT " + strings.Join(spannerUserCols, ", ") + " FROM users",
+	}).Do(func(row *spanner.Row) error {
+		var u spannerUser
+		if err := row.Columns(u.ptrs()...); err != nil {
+			return fmt.Errorf("failed to decode user: %w", err)
+		}
+		user, err := u.user()
+		if err != nil {
+			return err
+		}
+		out = append(out, user)
+		return nil
+	})
+	return out, err
+}
+
+func (s *SpannerStore) CreateUser(ctx context.Context, u *User) error {
+	if u.ID == "" {
+		u.ID = generateID(s.ids, KindUser)
+	}
+	u.CreatedAt = time.Now().Unix()
+
+	email := spanner.NullString{StringVal: u.Email, Valid: u.Email != ""}
+	meta := spanner.NullJSON{Value: u.Meta, Valid: len(u.Meta) > 0}
+	_, err := s.client.Apply(ctx, []*spanner.Mutation{
+		spanner.Insert("users", spannerUserCols, []interface{}{u.ID, u.Username, email, meta, u.CreatedAt, u.UpdatedAt, u.CreatedBy, u.UpdatedBy, u.EmailVerified, string(u.Status), u.TenantID}),
+	})
+	if spanner.ErrCode(err) == codes.AlreadyExists {
+		return fmt.Errorf("spanner_store: user %q already exists: %w", u.Username, err)
+	}
+	return err
+}
+
+func (s *SpannerStore) SetUserStatus(ctx context.Context, id string, status UserStatus) error {
+	return s.updateUser(ctx, id, "status", string(status))
+}
+
+func (s *SpannerStore) SetEmailVerified(ctx context.Context, id string, verified bool) error {
+	return s.updateUser(ctx, id, "email_verified", verified)
+}
+
+// updateUser sets one column of a user along with updated_at and updated_by.
+func (s *SpannerStore) updateUser(ctx context.Context, id, col string, v interface{}) error {
+	var updatedBy string
+	var updatedAt int64
+	stampUpdated(ctx, time.Now(), &updatedBy, &updatedAt)
+	_, err := s.client.Apply(ctx, []*spanner.Mutation{
+		spanner.Update("users", []string{"id", col, "updated_at", "updated_by"}, []interface{}{id, v, updatedAt, updatedBy}),
+	})
+	return err
+}
+
+func (s *SpannerStore) DeleteUser(ctx context.Context, id string) error {
+	// user_roles rows are removed by the interleave cascade.
+	_, err := s.client.Apply(ctx, []*spanner.Mutation{spanner.Delete("users", spanner.Key{id})})
+	return err
+}
+
+func (s *SpannerStore) GetGroupsByUserID(ctx context.Context, userID string) ([]*UserGroup, error) {
+	return s.listUserGroups(ctx, spanner.Statement{
+		SQL:    `SELECT id, user_id, group_name, created_at, updated_at, created_by, updated_by, level, expires_at, tenant_id FROM user_groups WHERE user_id = @id`,
+		Params: map[string]interface{}{"id": userID},
+	})
+}
+
+type spannerUser struct {
+	id, username string
+	email        spanner.NullString
+	meta         spanner.NullJSON
+	createdAt    int64
+	audit        spannerAudit
+	verified     spanner.NullBool
+	status       spanner.NullString
+	tenantID     spanner.NullString
+}
+
+func (u *spannerUser) ptrs() []interface{} {
+	return append(append([]interface{}{&u.id, &u.username, &u.email, &u.meta, &u.createdAt}, u.audit.ptrs()...), &u.verified, &u.status, &u.tenantID)
+}
+
+func (u *spannerUser) user() (*User, error) {
+	out := &User{ID: u.id, Username: u.username, Email: u.email.StringVal, EmailVerified: u.verified.Bool, Status: UserStatus(u.status.StringVal),
+		TenantID: u.tenantID.StringVal, CreatedAt: u.createdAt}
+	u.audit.fill(&out.UpdatedAt, &out.CreatedBy, &out.UpdatedBy)
+	if u.meta.Valid {
+		m, ok := u.meta.Value.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("failed to decode user meta: unexpected %T", u.meta.Value)
+		}
+		out.Meta = m
+	}
+	return out, nil
+}
+
+// spannerAudit reads the updated_at, created_by and updated_by columns,
+// which are NULL in rows written before they were added.
+type spannerAudit struct {
+	updatedAt            spanner.NullInt64
+	createdBy, updatedBy spanner.NullString
+}
+
+func (a *spannerAudit) ptrs() []interface{} {
+	return []interface{}{&a.updatedAt, &a.createdBy, &a.updatedBy}
+}
+
+func (a *spannerAudit) fill(updatedAt *int64, createdBy, updatedBy *string) {
+	*updatedAt = a.updatedAt.Int64
+	*createdBy = a.createdBy.StringVal
+	*updatedBy = a.updatedBy.StringVal
+}
+
+//
+// ---------- PermissionRepo ----------
+//
+
+var spannerPermissionCols = []string{"id", "resource", "action", "effect", "condition", "created_at", "updated_at", "created_by", "updated_by", "name", "description", "labels", "tenant_id"}
+
+func (s *SpannerStore) GetPermissionByID(ctx context.Context, id string) (*Permission, error) {
+	var p spannerPermission
+	ok, err := s.readRow(ctx, "permissions", spanner.Key{id}, spannerPermissionCols, p.ptrs()...)
+	if err != nil || !ok {
+		return nil, err
+	}
+	return p.permission()
+}
+
+func (s *SpannerStore) ListAllPermissions(ctx context.Context) ([]*Permission, error) {
+	var out []*Permission
+	err := s.client.Single().Query(ctx, spanner.Statement{
+		SQL: "SELECT " + strings.Join(spannerPermissionCols, ", ") + " FROM permissions",
+	}).Do(func(row *spanner.Row) error {
+		var p spannerPermission
+		if err := row.Columns(p.ptrs()...); err != nil {
+			return fmt.Errorf("failed to decode permission: %w", err)
+		}
+		perm, err := p.permission()
+		if err != nil {
+			return err
+		}
+		out = append(out, perm)
+		return nil
+	})
+	return out, err
+}
+
+func (s *SpannerStore) GetPermissionByResource(ctx context.Context, resource string, action Action) (*Permission, error) {
+	return s.permissionByResource(ctx, s.client.Single(), resource, action)
+}
+
+func (s *SpannerStore) permissionByResource(ctx context.Context, q spannerQuerier, resource string, action Action) (*Permission, error) {
+	var p spannerPermission
+	ok, err := queryFirst(ctx, q, spanner.Statement{
+		SQL:    "SELECT " + strings.Join(spannerPermissionCols, ", ") + " FROM permissions WHERE resource = @resource AND action = @action",
+		Params: map[string]interface{}{"resource": resource, "action": string(action)},
+	}, p.ptrs()...)
+	if err != nil || !ok {
+		return nil, err
+	}
+	return p.permission()
+}
+
+func (s *SpannerStore) CreatePermission(ctx context.Context, p *Permission) error {
+	if p.ID == "" {
+		p.ID = generateID(s.ids, KindPermission)
+	}
+	p.CreatedAt = time.Now().Unix()
+	labels, err := encodeLabels(p.Labels)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.client.ReadWriteTransaction(ctx, func(ctx context.Context, tx *spanner.ReadWriteTransaction) error {
+		existing, err := s.permissionByResource(ctx, tx, p.Resource, p.Action)
+		if err != nil {
+			return err
+		}
+		if existing != nil {
+			*p = *existing
+			return nil
+		}
+		return tx.BufferWrite([]*spanner.Mutation{
+			spanner.Insert("permissions", spannerPermissionCols, []interface{}{p.ID, p.Resource, string(p.Action), string(p.Effect), p.Condition, p.CreatedAt, p.UpdatedAt, p.CreatedBy, p.UpdatedBy, p.Name, p.Description, labels, p.TenantID}),
+		})
+	})
+	return err
+}
+
+func (s *SpannerStore) DeletePermission(ctx context.Context, id string) error {
+	// role_permissions is interleaved in roles, not permissions, so the
+	// bindings are found through the secondary index and removed explicitly.
+	_, err := s.client.ReadWriteTransaction(ctx, func(ctx context.Context, tx *spanner.ReadWriteTransaction) error {
+		ms := []*spanner.Mutation{spanner.Delete("permissions", spanner.Key{id})}
+		err := tx.Query(ctx, spanner.Statement{
+			SQL:    `SELECT role_id FROM role_permissions@{FORCE_INDEX=role_permissions_by_permission} WHERE permission_id = @id`,
+			Params: map[string]interface{}{"id": id},
+		}).Do(func(r *spanner.Row) error {
+			var roleID string
+			if err := r.Columns(&roleID); err != nil {
+				return err
+			}
+			ms = append(ms, spanner.Delete("role_permissions", spanner.Key{roleID, id}))
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		return tx.BufferWrite(ms)
+	})
+	return err
+}
+
+type spannerPermission struct {
+	id, resource, action      string
+	effect, condition         spanner.NullString
+	createdAt                 int64
+	audit                     spannerAudit
+	name, description, labels spanner.NullString
+	tenantID                  spanner.NullString
+}
+
+func (p *spannerPermission) ptrs() []interface{} {
+	return append(append([]interface{}{&p.id, &p.resource, &p.action, &p.effect, &p.condition, &p.createdAt}, p.audit.ptrs()...), &p.name, &p.description, &p.labels, &p.tenantID)
+}
+
+func (p *spannerPermission) permission() (*Permission, error) {
+	out := &Permission{ID: p.id, Name: p.name.StringVal, Description: p.description.StringVal, Resource: p.resource, Action: Action(p.action),
+		Effect: Effect(p.effect.StringVal), Condition: p.condition.StringVal, TenantID: p.tenantID.StringVal, CreatedAt: p.createdAt}
+	p.audit.fill(&out.UpdatedAt, &out.CreatedBy, &out.UpdatedBy)
+	if err := decodeLabels(p.labels.StringVal, &out.Labels); err != nil {
+		return nil, fmt.Errorf("failed to decode permission labels: %w", err)
+	}
+	return out, nil
+}
+
+//
+// ---------- RoleRepo ----------
+//
+
+var spannerRoleCols = []string{"id", "name", "description", "meta", "created_at", "updated_at", "created_by", "updated_by", "template", "priority", "generators", "tenant_id"}
+
+func (s *SpannerStore) CreateRole(ctx context.Context, r *Role) error {
+	if r.ID == "" {
+		r.ID = generateID(s.ids, KindRole)
+	}
+	r.CreatedAt = time.Now().Unix()
+
+	meta := spanner.NullJSON{Value: r.Meta, Valid: len(r.Meta) > 0}
+	generators, err := encodeGenerators(r.Generators)
+	if err != nil {
+		return err
+	}
+	_, err = s.client.Apply(ctx, []*spanner.Mutation{
+		spanner.Insert("roles", spannerRoleCols, []interface{}{r.ID, r.Name, r.Description, meta, r.CreatedAt, r.UpdatedAt, r.CreatedBy, r.UpdatedBy, r.Template, int64(r.Priority), generators, r.TenantID}),
+	})
+	if spanner.ErrCode(err) == codes.AlreadyExists {
+		return fmt.Errorf("spanner_store: role %q already exists: %w", r.Name, err)
+	}
+	return err
+}
+
+func (s *SpannerStore) GetRoleByName(ctx context.Context, name string) (*Role, error) {
+	var r spannerRole
+	ok, err := queryFirst(ctx, s.client.Single(), spanner.Statement{
+		SQL:    `SELECT id, name, description, meta, created_at, updated_at, created_by, updated_by, template, priority, generators, tenant_id FROM roles@{FORCE_INDEX=roles_by_name} WHERE name = @name`,
+		Params: map[string]interface{}{"name": name},
+	}, r.ptrs()...)
+	if err != nil || !ok {
+		return nil, err
+	}
+	return r.role()
+}
+
+func (s *SpannerStore) GetRoleByID(ctx context.Context, id string) (*Role, error) {
+	var r spannerRole
+	ok, err := s.readRow(ctx, "roles", spanner.Key{id}, spannerRoleCols, r.ptrs()...)
+	if err != nil || !ok {
+		return nil, err
+	}
+	return r.role()
+}
+
+func (s *SpannerStore) DeleteRole(ctx context.Context, id string) error {
+	// role_permissions rows are removed by the interleave cascade.
+	_, err := s.client.Apply(ctx, []*spanner.Mutation{spanner.Delete("roles", spanner.Key{id})})
+	return err
+}
+
+func (s *SpannerStore) ListAllRoles(ctx context.Context) ([]*Role, error) {
+	var out []*Role
+	err := s.client.Single().Query(ctx, spanner.Statement{
+		SQL: `SELECT id, name, description, meta, created_at, updated_at, created_by, updated_by, template, priority, generators, tenant_id FROM roles`,
+	}).Do(func(row *spanner.Row) error {
+		var r spannerRole
+		if err := row.Columns(r.ptrs()...); err != nil {
+			return fmt.Errorf("failed to decode role: %w", err)
+		}
+		role, err := r.role()
+		if err != nil {
+			return err
+		}
+		out = append(out, role)
+		return nil
+	})
+	return out, err
+}
+
+type spannerRole struct {
+	id, name    string
+	description spanner.NullString
+	meta        spanner.NullJSON
+	createdAt   int64
+	audit       spannerAudit
+	template    spanner.NullBool
+	priority    spanner.NullInt64
+	generators  spanner.NullString
+	tenantID    spanner.NullString
+}
+
+func (r *spannerRole) ptrs() []interface{} {
+	return append(append([]interface{}{&r.id, &r.name, &r.description, &r.meta, &r.createdAt}, r.audit.ptrs()...), &r.template, &r.priority, &r.generators, &r.tenantID)
+}
+
+func (r *spannerRole) role() (*Role, error) {
+	out := &Role{ID: r.id, Name: r.name, Description: r.description.StringVal, Template: r.template.Bool, Priority: int(r.priority.Int64),
+		TenantID: r.tenantID.StringVal, CreatedAt: r.createdAt}
+	r.audit.fill(&out.UpdatedAt, &out.CreatedBy, &out.UpdatedBy)
+	if r.meta.Valid {
+		m, ok := r.meta.Value.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("failed to decode role meta: unexpected %T", r.meta.Value)
+		}
+		out.Meta = m
+	}
+	if err := decodeGenerators(r.generators.StringVal, &out.Generators); err != nil {
+		return nil, fmt.Errorf("failed to decode role generators: %w", err)
+	}
+	return out, nil
+}
+
+//
+// ---------- RolePermissionRepo ----------
+//
+
+// AddRP binds a permission to a role. The role must exist, since bindings are
+// interleaved in their role row.
+func (s *SpannerStore) AddRP(ctx context.Context, roleID, permID string) error {
+	_, err := s.client.Apply(ctx, []*spanner.Mutation{
+		spanner.InsertOrUpdate("role_permissions",
+			[]string{"role_id", "permission_id", "created_at"},
+			[]interface{}{roleID, permID, time.Now().Unix()}),
+	})
+	return err
+}
+
+func (s *SpannerStore) Remove(ctx context.Context, roleID, permID string) error {
+	_, err := s.client.Apply(ctx, []*spanner.Mutation{
+		spanner.Delete("role_permissions", spanner.Key{roleID, permID}),
+	})
+	return err
+}
+
+func (s *SpannerStore) ListPermissions(ctx context.Context, roleID string) ([]string, error) {
+	return s.queryStrings(ctx, spanner.Statement{
+		SQL:    `SELECT permission_id FROM role_permissions WHERE role_id = @id`,
+		Params: map[string]interface{}{"id": roleID},
+	})
+}
+
+//
+// ---------- UserRoleRepo ----------
+//
+
+// AddUR assigns a role to a user. The user must exist, since assignments are
+// interleaved in their user row.
+func (s *SpannerStore) AddUR(ctx context.Context, userID, roleID string) error {
+	_, err := s.client.Apply(ctx, []*spanner.Mutation{
+		spanner.InsertOrUpdate("user_roles",
+			[]string{"user_id", "role_id", "assigned_at"},
+			[]interface{}{userID, roleID, time.Now().Unix()}),
+	})
+	return err
+}
+
+func (s *SpannerStore) RemoveUR(ctx context.Context, userID, roleID string) error {
+	_, err := s.client.Apply(ctx, []*spanner.Mutation{
+		spanner.Delete("user_roles", spanner.Key{userID, roleID}),
+	})
+	return err
+}
+
+func (s *SpannerStore) ListRoles(ctx context.Context, userID string) ([]string, error) {
+	return s.queryStrings(ctx, spanner.Statement{
+		SQL:    `SELECT role_id FROM user_roles WHERE user_id = @id`,
+		Params: map[string]interface{}{"id": userID},
+	})
+}
+
+func (s *SpannerStore) ListUsersForRole(ctx context.Context, roleID string) ([]string, error) {
+	return s.queryStrings(ctx, spanner.Statement{
+		SQL:    `SELECT user_id FROM user_roles@{FORCE_INDEX=user_roles_by_role} WHERE role_id = @id ORDER BY user_id`,
+		Params: map[string]interface{}{"id": roleID},
+	})
+}
+
+//
+// ---------- UserGroupRepo ----------
+//
+
+func (s *SpannerStore) AddUserToGroup(ctx context.Context, ug *UserGroup) error {
+	if ug.UserID == "" {
+		return errors.New("user id is empty")
+	}
+
+	if ug.ID == "" {
+		ug.ID = generateID(s.ids, KindUserGroup)
+	}
+	ug.CreatedAt = time.Now().Unix()
+
+	_, err := s.client.Apply(ctx, []*spanner.Mutation{
+		spanner.InsertOrUpdate("user_groups",
+			[]string{"user_id", "group_name", "id", "created_at", "updated_at", "created_by", "updated_by", "level", "expires_at", "tenant_id"},
+			[]interface{}{ug.UserID, ug.GroupName, ug.ID, ug.CreatedAt, ug.UpdatedAt, ug.CreatedBy, ug.UpdatedBy, string(ug.Level), ug.ExpiresAt, ug.TenantID}),
+	})
+	return err
+}
+
+func (s *SpannerStore) RemoveUserFromGroup(ctx context.Context, groupName string, ug *UserGroup) error {
+	if ug.UserID == "" {
+		return errors.New("user id is empty")
+	}
+
+	_, err := s.client.Apply(ctx, []*spanner.Mutation{
+		spanner.Delete("user_groups", spanner.Key{ug.UserID, groupName}),
+	})
+	return err
+}
+
+func (s *SpannerStore) GetUsersByGroupID(ctx context.Context, groupName string) ([]*UserGroup, error) {
+	return s.listUserGroups(ctx, spanner.Statement{
+		SQL:    `SELECT id, user_id, group_name, created_at, updated_at, created_by, updated_by, level, expires_at, tenant_id FROM user_groups@{FORCE_INDEX=user_groups_by_group} WHERE group_name = @name`,
+		Params: map[string]interface{}{"name": groupName},
+	})
+}
+
+func (s *SpannerStore) ListExpiringMemberships(ctx context.Context, after, before int64) ([]*UserGroup, error) {
+	return s.listUserGroups(ctx, spanner.Statement{
+		SQL:    `SELECT id, user_id, group_name, created_at, updated_at, created_by, updated_by, level, expires_at, tenant_id FROM user_groups@{FORCE_INDEX=user_groups_by_expiry} WHERE expires_at > @after AND expires_at <= @before`,
+		Params: map[string]interface{}{"after": after, "before": before},
+	})
+}
+
+func (s *SpannerStore) listUserGroups(ctx context.Context, stmt spanner.Statement) ([]*UserGroup, error) {
+	var out []*UserGroup
+	err := s.client.Single().Query(ctx, stmt).Do(func(r *spanner.Row) error {
+		ug := &UserGroup{}
+		var audit spannerAudit
+		var level spanner.NullString
+		var expiresAt spanner.NullInt64
+		var tenantID spanner.NullString
+		if err := r.Columns(append(append([]interface{}{&ug.ID, &ug.UserID, &ug.GroupName, &ug.CreatedAt}, audit.ptrs()...), &level, &expiresAt, &tenantID)...); err != nil {
+			return err
+		}
+		audit.fill(&ug.UpdatedAt, &ug.CreatedBy, &ug.UpdatedBy)
+		ug.Level, ug.ExpiresAt, ug.TenantID = MembershipLevel(level.StringVal), expiresAt.Int64, tenantID.StringVal
+		out = append(out, ug)
+		return nil
+	})
+	return out, err
+}
+
+//
+// ---------- GroupRoleRepo ----------
+//
+
+func (s *SpannerStore) AddRoleToGroup(ctx context.Context, groupID, roleID string) error {
+	_, err := s.client.Apply(ctx, []*spanner.Mutation{
+		spanner.InsertOrUpdate("group_roles",
+			[]string{"group_name", "role_id", "created_at"},
+			[]interface{}{groupID, roleID, time.Now().Unix()}),
+	})
+	return err
+}
+
+func (s *SpannerStore) RemoveRoleFromGroup(ctx context.Context, groupID, roleID string) error {
+	_, err := s.client.Apply(ctx, []*spanner.Mutation{
+		spanner.Delete("group_roles", spanner.Key{groupID, roleID}),
+	})
+	return err
+}
+
+func (s *SpannerStore) ListRolesForGroup(ctx context.Context, groupID string) ([]string, error) {
+	return s.queryStrings(ctx, spanner.Statement{
+		SQL:    `SELECT role_id FROM group_roles WHERE group_name = @id`,
+		Params: map[string]interface{}{"id": groupID},
+	})
+}
+
+func (s *SpannerStore) ListGroupsForRole(ctx context.Context, roleID string) ([]string, error) {
+	return s.queryStrings(ctx, spanner.Statement{
+		SQL:    `SELECT group_name FROM group_roles@{FORCE_INDEX=group_roles_by_role} WHERE role_id = @id ORDER BY group_name`,
+		Params: map[string]interface{}{"id": roleID},
+	})
+}
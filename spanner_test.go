@@ -0,0 +1,122 @@
+package rbac
+
+import (
+	"context"
+	"testing"
+
+	"cloud.google.com/go/spanner"
+	database "cloud.google.com/go/spanner/admin/database/apiv1"
+	"cloud.google.com/go/spanner/admin/database/apiv1/databasepb"
+	instance "cloud.google.com/go/spanner/admin/instance/apiv1"
+	"cloud.google.com/go/spanner/admin/instance/apiv1/instancepb"
+	tcspanner "github.com/testcontainers/testcontainers-go/modules/gcloud/spanner"
+	"google.golang.org/api/option"
+	"google.golang.org/api/option/internaloption"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+func newSpannerStore(t *testing.T) *SpannerStore {
+	t.Helper()
+	ctx := context.Background()
+
+	ctr, err := tcspanner.Run(ctx, "gcr.io/cloud-spanner-emulator/emulator:1.5.28",
+		tcspanner.WithProjectID("rbac-test"))
+	if err != nil {
+		t.Fatalf("start spanner emulator: %v", err)
+	}
+	t.Cleanup(func() { _ = ctr.Terminate(ctx) })
+
+	opts := []option.ClientOption{
+		option.WithEndpoint(ctr.URI()),
+		option.WithGRPCDialOption(grpc.WithTransportCredentials(insecure.NewCredentials())),
+		option.WithoutAuthentication(),
+		internaloption.SkipDialSettingsValidation(),
+	}
+	project := "projects/" + ctr.ProjectID()
+	inst := project + "/instances/rbac"
+	db := inst + "/databases/rbac"
+
+	instAdmin, err := instance.NewInstanceAdminClient(ctx, opts...)
+	if err != nil {
+		t.Fatalf("instance admin client: %v", err)
+	}
+	t.Cleanup(func() { _ = instAdmin.Close() })
+	instOp, err := instAdmin.CreateInstance(ctx, &instancepb.CreateInstanceRequest{
+		Parent:     project,
+		InstanceId: "rbac",
+		Instance:   &instancepb.Instance{DisplayName: "rbac"},
+	})
+	if err != nil {
+		t.Fatalf("CreateInstance: %v", err)
+	}
+	if _, err := instOp.Wait(ctx); err != nil {
+		t.Fatalf("wait for instance: %v", err)
+	}
+
+	dbAdmin, err := database.NewDatabaseAdminClient(ctx, opts...)
+	if err != nil {
+		t.Fatalf("database admin client: %v", err)
+	}
+	t.Cleanup(func() { _ = dbAdmin.Close() })
+	dbOp, err := dbAdmin.CreateDatabase(ctx, &databasepb.CreateDatabaseRequest{
+		Parent:          inst,
+		CreateStatement: "CREATE DATABASE rbac",
+	})
+	if err != nil {
+		t.Fatalf("CreateDatabase: %v", err)
+	}
+	if _, err := dbOp.Wait(ctx); err != nil {
+		t.Fatalf("wait for database: %v", err)
+	}
+
+	client, err := spanner.NewClient(ctx, db, opts...)
+	if err != nil {
+		t.Fatalf("spanner.NewClient: %v", err)
+	}
+	t.Cleanup(client.Close)
+
+	store, err := NewSpannerStore(ctx, client, dbAdmin)
+	if err != nil {
+		t.Fatalf("NewSpannerStore: %v", err)
+	}
+	// A second call must find the schema complete and change nothing.
+	if err := EnsureSpannerSchema(ctx, client, dbAdmin); err != nil {
+		t.Fatalf("EnsureSpannerSchema on existing schema: %v", err)
+	}
+	return store
+}
+
+func TestSpannerStore(t *testing.T) {
+	s := newSpannerStore(t)
+	runSuite(t, s)
+
+	t.Run("InterleavedCascade", func(t *testing.T) {
+		ctx := context.Background()
+		u := &User{Username: "cascade-user"}
+		if err := s.CreateUser(ctx, u); err != nil {
+			t.Fatalf("CreateUser: %v", err)
+		}
+		r := &Role{Name: "cascade-role"}
+		if err := s.CreateRole(ctx, r); err != nil {
+			t.Fatalf("CreateRole: %v", err)
+		}
+		if err := s.AddUR(ctx, u.ID, r.ID); err != nil {
+			t.Fatalf("AddUR: %v", err)
+		}
+		if err := s.AddUR(ctx, "missing-user", r.ID); err == nil {
+			t.Error("expected AddUR to fail for a user that does not exist")
+		}
+
+		if err := s.DeleteUser(ctx, u.ID); err != nil {
+			t.Fatalf("DeleteUser: %v", err)
+		}
+		roles, err := s.ListRoles(ctx, u.ID)
+		if err != nil {
+			t.Fatalf("ListRoles: %v", err)
+		}
+		if containsStr(roles, r.ID) {
+			t.Errorf("expected user_roles to be removed with the user, got %v", roles)
+		}
+	})
+}
@@ -0,0 +1,246 @@
+package rbac
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// StatsTopN is how many roles and groups PolicyStats ranks.
+const StatsTopN = 10
+
+// PolicyStats counts the policy in a store, as returned by Manager.Stats.
+type PolicyStats struct {
+	Users       int `json:"users"`
+	Roles       int `json:"roles"`
+	Permissions int `json:"permissions"`
+	Groups      int `json:"groups"`
+	// DeletedRoles and DeletedPermissions count the soft-deleted ones,
+	// which Roles and Permissions leave out.
+	DeletedRoles       int `json:"deleted_roles"`
+	DeletedPermissions int `json:"deleted_permissions"`
+
+	RolePermissions int `json:"role_permissions"`
+	UserRoles       int `json:"user_roles"`
+	GroupRoles      int `json:"group_roles"`
+	Memberships     int `json:"memberships"`
+
+	// LargestRoles are the StatsTopN roles held directly by the most users,
+	// then by the most groups; LargestGroups are the StatsTopN groups with
+	// the most members.
+	LargestRoles  []RoleStats  `json:"largest_roles"`
+	LargestGroups []GroupStats `json:"largest_groups"`
+}
+
+// RoleStats counts a role's direct holders and permissions.
+type RoleStats struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Users       int    `json:"users"`
+	Groups      int    `json:"groups"`
+	Permissions int    `json:"permissions"`
+}
+
+// GroupStats counts a group's members and roles.
+type GroupStats struct {
+	Name    string `json:"name"`
+	Members int    `json:"members"`
+	Roles   int    `json:"roles"`
+}
+
+// Stats counts the users, roles, permissions, groups and assignments in the
+// store and ranks the largest roles and groups. Assignments are read with
+// ExportPager where the repo implements it and role by role otherwise; then
+// only groups that hold a role or have a record in the Manager's GroupRepo
+// have their members counted.
+func (m *Manager) Stats(ctx context.Context) (*PolicyStats, error) {
+	start := time.Now()
+	ctx, span := m.startSpan(ctx, "Stats")
+	defer span.End()
+	s, err := m.stats(ctx)
+	m.record(ctx, start, "Stats", err)
+	return s, err
+}
+
+func (m *Manager) stats(ctx context.Context) (*PolicyStats, error) {
+	s := &PolicyStats{}
+	perms, err := m.Perms.ListAllPermissions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range perms {
+		if p.DeletedAt != 0 {
+			s.DeletedPermissions++
+		} else {
+			s.Permissions++
+		}
+	}
+	roles, err := m.Roles.ListAllRoles(ctx)
+	if err != nil {
+		return nil, err
+	}
+	roleIDs := make([]string, 0, len(roles))
+	byRole := make(map[string]*RoleStats, len(roles))
+	for _, r := range roles {
+		roleIDs = append(roleIDs, r.ID)
+		if r.DeletedAt != 0 {
+			s.DeletedRoles++
+			continue
+		}
+		s.Roles++
+		byRole[r.ID] = &RoleStats{ID: r.ID, Name: r.Name}
+	}
+	users, err := m.Users.ListAllUsers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	s.Users = len(users)
+
+	byGroup := map[string]*GroupStats{}
+	group := func(name string) *GroupStats {
+		g := byGroup[name]
+		if g == nil {
+			g = &GroupStats{Name: name}
+			byGroup[name] = g
+		}
+		return g
+	}
+	if m.Groups != nil {
+		groups, err := m.Groups.ListGroups(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, g := range groups {
+			group(g.Name)
+		}
+	}
+
+	err = m.statsEdges(ctx, KindRolePermission, roleIDs, nil, func(roleID, _ string) {
+		s.RolePermissions++
+		if r := byRole[roleID]; r != nil {
+			r.Permissions++
+		}
+	})
+	if err == nil {
+		err = m.statsEdges(ctx, KindUserRole, roleIDs, nil, func(_, roleID string) {
+			s.UserRoles++
+			if r := byRole[roleID]; r != nil {
+				r.Users++
+			}
+		})
+	}
+	if err == nil {
+		err = m.statsEdges(ctx, KindGroupRole, roleIDs, nil, func(name, roleID string) {
+			s.GroupRoles++
+			group(name).Roles++
+			if r := byRole[roleID]; r != nil {
+				r.Groups++
+			}
+		})
+	}
+	if err == nil {
+		names := make([]string, 0, len(byGroup))
+		for name := range byGroup {
+			names = append(names, name)
+		}
+		err = m.statsEdges(ctx, KindUserGroup, nil, names, func(_, name string) {
+			s.Memberships++
+			group(name).Members++
+		})
+	}
+	if err != nil {
+		return nil, err
+	}
+	s.Groups = len(byGroup)
+
+	s.LargestRoles = make([]RoleStats, 0, len(byRole))
+	for _, r := range byRole {
+		s.LargestRoles = append(s.LargestRoles, *r)
+	}
+	slices.SortFunc(s.LargestRoles, func(a, b RoleStats) int {
+		if c := cmp.Compare(b.Users, a.Users); c != 0 {
+			return c
+		}
+		if c := cmp.Compare(b.Groups, a.Groups); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.ID, b.ID)
+	})
+	s.LargestRoles = s.LargestRoles[:min(len(s.LargestRoles), StatsTopN)]
+
+	s.LargestGroups = make([]GroupStats, 0, len(byGroup))
+	for _, g := range byGroup {
+		s.LargestGroups = append(s.LargestGroups, *g)
+	}
+	slices.SortFunc(s.LargestGroups, func(a, b GroupStats) int {
+		if c := cmp.Compare(b.Members, a.Members); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Name, b.Name)
+	})
+	s.LargestGroups = s.LargestGroups[:min(len(s.LargestGroups), StatsTopN)]
+	return s, nil
+}
+
+// statsEdges passes the ends of every assignment of kind to fn, in the order
+// of ExportEdge. Without an ExportPager, role assignments are listed for
+// each of roleIDs and memberships for each of groups.
+func (m *Manager) statsEdges(ctx context.Context, kind string, roleIDs, groups []string, fn func(from, to string)) error {
+	if pager, ok := m.edgeRepo(kind).(ExportPager); ok {
+		err := eachExportItem(ctx, pager, kind, func(it ExportItem) error {
+			switch v := it.Value.(type) {
+			case *ExportEdge:
+				fn(v.From, v.To)
+			case *UserGroup:
+				fn(v.UserID, v.GroupName)
+			}
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("rbac: stats %s: %w", kind, err)
+		}
+		return nil
+	}
+
+	for _, roleID := range roleIDs {
+		var (
+			ends []string
+			err  error
+		)
+		switch kind {
+		case KindRolePermission:
+			if ends, err = m.RP.ListPermissions(ctx, roleID); err == nil {
+				for _, permID := range ends {
+					fn(roleID, permID)
+				}
+			}
+		case KindUserRole:
+			if ends, err = m.UR.ListUsersForRole(ctx, roleID); err == nil {
+				for _, userID := range ends {
+					fn(userID, roleID)
+				}
+			}
+		case KindGroupRole:
+			if ends, err = m.GR.ListGroupsForRole(ctx, roleID); err == nil {
+				for _, name := range ends {
+					fn(name, roleID)
+				}
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+	for _, name := range groups {
+		members, err := m.UG.GetUsersByGroupID(ctx, name)
+		if err != nil {
+			return err
+		}
+		for _, ug := range members {
+			fn(ug.UserID, name)
+		}
+	}
+	return nil
+}
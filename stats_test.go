@@ -0,0 +1,75 @@
+package rbac
+
+import (
+	"context"
+	"testing"
+)
+
+func TestStats(t *testing.T) {
+	ctx := context.Background()
+	memory, err := NewMemoryStoreManager(ctx, "", 0)
+	if err != nil {
+		t.Fatalf("NewMemoryStoreManager: %v", err)
+	}
+	for name, mgr := range map[string]*Manager{
+		"memory": memory,
+		"mock":   NewMockRepoManager(NewMockRepo()),
+	} {
+		t.Run(name, func(t *testing.T) {
+			seeded, err := mgr.Stats(ctx)
+			if err != nil {
+				t.Fatalf("Stats: %v", err)
+			}
+			must := func(err error) {
+				t.Helper()
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+			must(mgr.CreatePermission(ctx, &Permission{ID: "docs-read", Resource: "docs/*", Action: ActionRead}))
+			must(mgr.CreatePermission(ctx, &Permission{ID: "docs-write", Resource: "docs/*", Action: ActionUpdate}))
+			for _, id := range []string{"viewer", "editor", "old"} {
+				must(mgr.CreateRole(ctx, &Role{ID: id, Name: id}))
+			}
+			must(mgr.DeleteRole(ctx, "old"))
+			for _, id := range []string{"alice", "bob"} {
+				must(mgr.CreateUser(ctx, &User{ID: id, Username: id, Email: id + "@example.com"}))
+			}
+			must(mgr.AssignPermissionToRole(ctx, "viewer", "docs-read"))
+			must(mgr.AssignPermissionToRole(ctx, "editor", "docs-read"))
+			must(mgr.AssignPermissionToRole(ctx, "editor", "docs-write"))
+			must(mgr.AssignRoleToUser(ctx, "alice", "viewer"))
+			must(mgr.AssignRoleToUser(ctx, "bob", "viewer"))
+			must(mgr.AssignRoleToUser(ctx, "alice", "editor"))
+			must(mgr.AssignRoleToGroup(ctx, "staff", "editor"))
+			must(mgr.AddUserToGroup(ctx, &UserGroup{UserID: "alice", GroupName: "staff"}))
+			must(mgr.AddUserToGroup(ctx, &UserGroup{UserID: "carol", GroupName: "staff"}))
+
+			s, err := mgr.Stats(ctx)
+			if err != nil {
+				t.Fatalf("Stats: %v", err)
+			}
+			// without a SoftDeleteRepo, DeleteRole removes the role for good
+			deleted := 0
+			if _, ok := mgr.Roles.(SoftDeleteRepo); ok {
+				deleted = 1
+			}
+			if s.Users != seeded.Users+2 || s.Roles != seeded.Roles+2 || s.DeletedRoles != deleted ||
+				s.Permissions != seeded.Permissions+2 || s.Groups != seeded.Groups+1 {
+				t.Errorf("counts = %+v; seeded %+v", s, seeded)
+			}
+			if s.RolePermissions != 3 || s.UserRoles != 3 || s.GroupRoles != 1 || s.Memberships != 2 {
+				t.Errorf("bindings = %d role permissions, %d user roles, %d group roles, %d memberships",
+					s.RolePermissions, s.UserRoles, s.GroupRoles, s.Memberships)
+			}
+			if len(s.LargestRoles) < 2 ||
+				s.LargestRoles[0] != (RoleStats{ID: "viewer", Name: "viewer", Users: 2, Permissions: 1}) ||
+				s.LargestRoles[1] != (RoleStats{ID: "editor", Name: "editor", Users: 1, Groups: 1, Permissions: 2}) {
+				t.Errorf("LargestRoles = %+v", s.LargestRoles)
+			}
+			if len(s.LargestGroups) == 0 || s.LargestGroups[0] != (GroupStats{Name: "staff", Members: 2, Roles: 1}) {
+				t.Errorf("LargestGroups = %+v", s.LargestGroups)
+			}
+		})
+	}
+}
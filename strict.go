@@ -0,0 +1,105 @@
+package rbac
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// Errors wrapped by StrictError; match them with errors.Is.
+var (
+	ErrUnknownUser       = errors.New("rbac: unknown user")
+	ErrUnknownRole       = errors.New("rbac: unknown role")
+	ErrUnknownPermission = errors.New("rbac: unknown permission")
+)
+
+// StrictError is returned by checks in strict mode when they meet an entity
+// that does not exist. Ref says what referenced it, e.g. "user alice" for a
+// role assigned to alice.
+type StrictError struct {
+	Err error
+	ID  string
+	Ref string
+}
+
+func (e *StrictError) Error() string {
+	if e.Ref == "" {
+		return fmt.Sprintf("%v %q", e.Err, e.ID)
+	}
+	return fmt.Sprintf("%v %q referenced by %s", e.Err, e.ID, e.Ref)
+}
+
+func (e *StrictError) Unwrap() error { return e.Err }
+
+var strictViolations metric.Int64Counter
+
+func init() {
+	strictViolations, _ = meter.Int64Counter(
+		"rbac_manager_strict_violations_total",
+		metric.WithDescription("Checks rejected in strict mode because they referenced a missing user, role or permission"),
+	)
+}
+
+// strictCheck verifies, when the Manager is strict, that userID exists and
+// that every role in roleIDs and every permission bound to them does too.
+// It does a lookup per entity, so strict mode is meant for development and
+// integration tests rather than hot paths.
+func (m *Manager) strictCheck(ctx context.Context, userID string, roleIDs []string) error {
+	if !m.Strict {
+		return nil
+	}
+	err := m.findMissing(ctx, userID, roleIDs)
+	if se, ok := err.(*StrictError); ok {
+		return m.strictViolation(ctx, se)
+	}
+	return err
+}
+
+// strictViolation counts se in the strict violations metric and returns it.
+func (m *Manager) strictViolation(ctx context.Context, se *StrictError) error {
+	kind := map[error]string{ErrUnknownUser: KindUser, ErrUnknownRole: KindRole, ErrUnknownPermission: KindPermission}[se.Err]
+	strictViolations.Add(ctx, 1, metric.WithAttributes(attribute.String("kind", kind)))
+	return se
+}
+
+func (m *Manager) findMissing(ctx context.Context, userID string, roleIDs []string) error {
+	u, err := m.Users.GetUserByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if u == nil {
+		return &StrictError{Err: ErrUnknownUser, ID: userID}
+	}
+
+	seen := map[string]bool{}
+	for _, rid := range roleIDs {
+		if seen[rid] {
+			continue
+		}
+		seen[rid] = true
+		r, err := m.Roles.GetRoleByID(ctx, rid)
+		if err != nil {
+			return err
+		}
+		if r == nil {
+			return &StrictError{Err: ErrUnknownRole, ID: rid, Ref: "user " + userID}
+		}
+		permIDs, err := m.RP.ListPermissions(ctx, rid)
+		if err != nil {
+			return err
+		}
+		for _, pid := range permIDs {
+			p, err := m.Perms.GetPermissionByID(ctx, pid)
+			if err != nil {
+				return err
+			}
+			if p == nil {
+				return &StrictError{Err: ErrUnknownPermission, ID: pid, Ref: "role " + rid}
+			}
+		}
+	}
+	return nil
+}
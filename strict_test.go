@@ -0,0 +1,63 @@
+package rbac
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestStrictMode(t *testing.T) {
+	ctx := context.Background()
+	mgr, err := NewMemoryStoreManager(ctx, "", 0)
+	if err != nil {
+		t.Fatalf("NewMemoryStoreManager: %v", err)
+	}
+	if err := mgr.CreateUser(ctx, &User{ID: "alice"}); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	if err := mgr.CreatePermission(ctx, &Permission{ID: "p1", Resource: "survey", Action: ActionRead}); err != nil {
+		t.Fatalf("CreatePermission: %v", err)
+	}
+	if err := mgr.CreateRole(ctx, &Role{ID: "r1", Name: "reader"}); err != nil {
+		t.Fatalf("CreateRole: %v", err)
+	}
+	_ = mgr.AssignPermissionToRole(ctx, "r1", "p1")
+	_ = mgr.AssignRoleToUser(ctx, "alice", "r1")
+
+	// Lenient by default.
+	if ok, err := mgr.Can(ctx, "ghost", "survey", ActionRead); ok || err != nil {
+		t.Fatalf("expected a quiet deny outside strict mode, got %v, %v", ok, err)
+	}
+
+	mgr.Strict = true
+	if ok, err := mgr.Can(ctx, "alice", "survey", ActionRead); !ok || err != nil {
+		t.Fatalf("expected a consistent policy to pass, got %v, %v", ok, err)
+	}
+
+	var se *StrictError
+	_, err = mgr.Can(ctx, "ghost", "survey", ActionRead)
+	if !errors.Is(err, ErrUnknownUser) || !errors.As(err, &se) || se.ID != "ghost" {
+		t.Errorf("expected ErrUnknownUser for ghost, got %v", err)
+	}
+
+	_ = mgr.AssignRoleToUser(ctx, "alice", "r-missing")
+	_, err = mgr.Can(ctx, "alice", "survey", ActionRead)
+	if !errors.Is(err, ErrUnknownRole) || !errors.As(err, &se) || se.ID != "r-missing" || se.Ref != "user alice" {
+		t.Errorf("expected ErrUnknownRole for r-missing, got %v", err)
+	}
+	_ = mgr.UnassignRoleFromUser(ctx, "alice", "r-missing")
+
+	_ = mgr.AssignPermissionToRole(ctx, "r1", "p-missing")
+	_, err = mgr.HasPermission(ctx, "alice", "p1")
+	if !errors.Is(err, ErrUnknownPermission) || !errors.As(err, &se) || se.Ref != "role r1" {
+		t.Errorf("expected ErrUnknownPermission for p-missing, got %v", err)
+	}
+	_ = mgr.RemovePermissionFromRole(ctx, "r1", "p-missing")
+
+	if _, err := mgr.HasPermission(ctx, "alice", "p-other"); !errors.Is(err, ErrUnknownPermission) {
+		t.Errorf("expected ErrUnknownPermission for the checked permission, got %v", err)
+	}
+	if ok, err := mgr.HasPermission(ctx, "alice", "p1"); !ok || err != nil {
+		t.Errorf("expected HasPermission to pass, got %v, %v", ok, err)
+	}
+}
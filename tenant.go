@@ -0,0 +1,330 @@
+package rbac
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// KindTenant is the IDGenerator kind used for tenants.
+const KindTenant = "tenant"
+
+// Tenant is an isolated customer or workspace. Entities that belong to a
+// tenant carry its ID in their TenantID field.
+type Tenant struct {
+	ID        string `bson:"id" json:"id,omitempty"`
+	Name      string `bson:"name" json:"name,omitempty"`
+	CreatedAt int64  `bson:"created_at" json:"created_at,omitempty"`
+}
+
+// TenantRepo stores tenants and finds the entities stamped with a tenant ID.
+type TenantRepo interface {
+	CreateTenant(ctx context.Context, t *Tenant) error
+	DeleteTenant(ctx context.Context, id string) error
+	GetTenantByID(ctx context.Context, id string) (*Tenant, error)
+	ListTenants(ctx context.Context) ([]*Tenant, error)
+
+	ListPermissionsByTenant(ctx context.Context, tenantID string) ([]*Permission, error)
+	ListRolesByTenant(ctx context.Context, tenantID string) ([]*Role, error)
+	ListUsersByTenant(ctx context.Context, tenantID string) ([]*User, error)
+}
+
+// TenantRoleTemplate describes a role provisioned for every new tenant.
+// Permission resources are relative to the tenant; see CreateTenant.
+type TenantRoleTemplate struct {
+	Name        string       `json:"name"`
+	Description string       `json:"description,omitempty"`
+	Permissions []Permission `json:"permissions,omitempty"`
+}
+
+// TenantTemplate is the set of roles and permissions CreateTenant provisions.
+type TenantTemplate struct {
+	Roles []TenantRoleTemplate `json:"roles"`
+}
+
+// DefaultTenantTemplate gives each tenant an admin role with every action on
+// its resources and a member role that can read them.
+var DefaultTenantTemplate = TenantTemplate{
+	Roles: []TenantRoleTemplate{
+		{
+			Name:        "admin",
+			Description: "Tenant administrator",
+			Permissions: []Permission{{Resource: "**", Action: ActionAll}},
+		},
+		{
+			Name:        "member",
+			Description: "Tenant member",
+			Permissions: []Permission{{Resource: "**", Action: ActionRead}},
+		},
+	},
+}
+
+// TenantRoleName returns the stored name of a role provisioned for a tenant.
+// Role names are unique per store, so tenant roles are qualified.
+func TenantRoleName(tenantID, name string) string {
+	return tenantID + ":" + name
+}
+
+// TenantResource returns the stored resource of a permission provisioned for
+// a tenant. Resources are prefixed with the tenant ID so that tenant
+// permissions never match another tenant's resources; "**" (or an empty
+// resource) covers everything under the tenant.
+func TenantResource(tenantID, resource string) string {
+	if resource == "" {
+		resource = "**"
+	}
+	return tenantID + "." + resource
+}
+
+// TenantExport is the full definition of a tenant, written out before the
+// tenant is deleted. Edge maps are keyed by role, user and group respectively.
+type TenantExport struct {
+	Tenant          *Tenant             `json:"tenant"`
+	Permissions     []*Permission       `json:"permissions"`
+	Roles           []*Role             `json:"roles"`
+	Users           []*User             `json:"users"`
+	RolePermissions map[string][]string `json:"role_permissions"`
+	UserRoles       map[string][]string `json:"user_roles"`
+	UserGroups      []*UserGroup        `json:"user_groups"`
+	GroupRoles      map[string][]string `json:"group_roles"`
+	ExportedAt      int64               `json:"exported_at"`
+}
+
+// CreateTenant stores t and provisions the roles and permissions of the
+// Manager's TenantTemplate (DefaultTenantTemplate when unset). Provisioned
+// roles are named TenantRoleName(t.ID, name) and their permissions use
+// TenantResource(t.ID, resource); all of them carry t.ID as TenantID.
+func (m *Manager) CreateTenant(ctx context.Context, t *Tenant) error {
+	start := time.Now()
+	ctx, span := m.startSpan(ctx, "CreateTenant")
+	defer span.End()
+	err := m.createTenant(ctx, t)
+	m.record(ctx, start, "CreateTenant", err)
+	m.changed(ctx, "CreateTenant", t.ID, err)
+	return err
+}
+
+func (m *Manager) createTenant(ctx context.Context, t *Tenant) error {
+	if m.Tenants == nil {
+		return errors.New("rbac: no TenantRepo configured")
+	}
+	if t.ID == "" {
+		t.ID = generateID(m.IDs, KindTenant)
+	}
+	if existing, err := m.Tenants.GetTenantByID(ctx, t.ID); err != nil {
+		return err
+	} else if existing != nil {
+		return fmt.Errorf("rbac: tenant %q already exists", t.ID)
+	}
+	now := time.Now()
+	t.CreatedAt = now.Unix()
+	if err := m.Tenants.CreateTenant(ctx, t); err != nil {
+		return err
+	}
+
+	tmpl := DefaultTenantTemplate
+	if m.TenantTemplate != nil {
+		tmpl = *m.TenantTemplate
+	}
+	for _, rt := range tmpl.Roles {
+		role := &Role{
+			Name:        TenantRoleName(t.ID, rt.Name),
+			Description: rt.Description,
+			TenantID:    t.ID,
+		}
+		m.assignID(&role.ID, KindRole)
+		stampCreated(ctx, now, &role.CreatedBy, &role.UpdatedBy, &role.UpdatedAt)
+		if err := m.Roles.CreateRole(ctx, role); err != nil {
+			return fmt.Errorf("rbac: provision role %q: %w", rt.Name, err)
+		}
+		for _, pt := range rt.Permissions {
+			perm := &Permission{
+				Resource: TenantResource(t.ID, pt.Resource),
+				Action:   pt.Action,
+				Effect:   pt.Effect,
+				TenantID: t.ID,
+			}
+			m.assignID(&perm.ID, KindPermission)
+			stampCreated(ctx, now, &perm.CreatedBy, &perm.UpdatedBy, &perm.UpdatedAt)
+			if err := m.Perms.CreatePermission(ctx, perm); err != nil {
+				return fmt.Errorf("rbac: provision permission %q: %w", perm.Resource, err)
+			}
+			if err := m.RP.AddRP(ctx, role.ID, perm.ID); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// ExportTenant collects the tenant's entities and every assignment that
+// touches them.
+func (m *Manager) ExportTenant(ctx context.Context, tenantID string) (*TenantExport, error) {
+	start := time.Now()
+	ctx, span := m.startSpan(ctx, "ExportTenant")
+	defer span.End()
+	exp, err := m.exportTenant(ctx, tenantID)
+	m.record(ctx, start, "ExportTenant", err)
+	return exp, err
+}
+
+func (m *Manager) exportTenant(ctx context.Context, tenantID string) (*TenantExport, error) {
+	if m.Tenants == nil {
+		return nil, errors.New("rbac: no TenantRepo configured")
+	}
+	t, err := m.Tenants.GetTenantByID(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	if t == nil {
+		return nil, fmt.Errorf("rbac: tenant %q not found", tenantID)
+	}
+
+	exp := &TenantExport{
+		Tenant:          t,
+		RolePermissions: map[string][]string{},
+		UserRoles:       map[string][]string{},
+		GroupRoles:      map[string][]string{},
+		ExportedAt:      time.Now().Unix(),
+	}
+	if exp.Permissions, err = m.Tenants.ListPermissionsByTenant(ctx, tenantID); err != nil {
+		return nil, err
+	}
+	if exp.Roles, err = m.Tenants.ListRolesByTenant(ctx, tenantID); err != nil {
+		return nil, err
+	}
+	if exp.Users, err = m.Tenants.ListUsersByTenant(ctx, tenantID); err != nil {
+		return nil, err
+	}
+
+	tenantRoles := make(map[string]bool, len(exp.Roles))
+	for _, r := range exp.Roles {
+		tenantRoles[r.ID] = true
+		perms, err := m.RP.ListPermissions(ctx, r.ID)
+		if err != nil {
+			return nil, err
+		}
+		if len(perms) > 0 {
+			exp.RolePermissions[r.ID] = perms
+		}
+	}
+
+	groups := map[string]bool{}
+	for _, u := range exp.Users {
+		roles, err := m.UR.ListRoles(ctx, u.ID)
+		if err != nil {
+			return nil, err
+		}
+		if len(roles) > 0 {
+			exp.UserRoles[u.ID] = roles
+		}
+
+		ugs, err := m.UG.GetGroupsByUserID(ctx, u.ID)
+		if err != nil {
+			return nil, err
+		}
+		for _, ug := range ugs {
+			exp.UserGroups = append(exp.UserGroups, ug)
+			groups[ug.GroupName] = true
+		}
+	}
+
+	// Group names are not tenant-scoped, so only bindings to the tenant's
+	// own roles are part of the export.
+	if m.GR != nil {
+		for g := range groups {
+			roles, err := m.GR.ListRolesForGroup(ctx, g)
+			if err != nil {
+				return nil, err
+			}
+			for _, rid := range roles {
+				if tenantRoles[rid] {
+					exp.GroupRoles[g] = append(exp.GroupRoles[g], rid)
+				}
+			}
+		}
+	}
+	return exp, nil
+}
+
+// DeleteTenant removes a tenant and everything that belongs to it. The
+// tenant is first exported as JSON to backup; nothing is deleted unless the
+// export was written successfully, and a nil backup is rejected.
+func (m *Manager) DeleteTenant(ctx context.Context, tenantID string, backup io.Writer) error {
+	start := time.Now()
+	ctx, span := m.startSpan(ctx, "DeleteTenant")
+	defer span.End()
+	err := m.deleteTenant(ctx, tenantID, backup)
+	m.record(ctx, start, "DeleteTenant", err)
+	m.changed(ctx, "DeleteTenant", tenantID, err)
+	return err
+}
+
+func (m *Manager) deleteTenant(ctx context.Context, tenantID string, backup io.Writer) error {
+	if backup == nil {
+		return errors.New("rbac: DeleteTenant requires a backup writer for the tenant export")
+	}
+	exp, err := m.exportTenant(ctx, tenantID)
+	if err != nil {
+		return err
+	}
+	if err := json.NewEncoder(backup).Encode(exp); err != nil {
+		return fmt.Errorf("rbac: write tenant export: %w", err)
+	}
+
+	for g, roles := range exp.GroupRoles {
+		for _, rid := range roles {
+			if err := m.GR.RemoveRoleFromGroup(ctx, g, rid); err != nil {
+				return err
+			}
+		}
+	}
+	for _, ug := range exp.UserGroups {
+		if err := m.UG.RemoveUserFromGroup(ctx, ug.GroupName, ug); err != nil {
+			return err
+		}
+	}
+	for uid, roles := range exp.UserRoles {
+		for _, rid := range roles {
+			if err := m.UR.RemoveUR(ctx, uid, rid); err != nil {
+				return err
+			}
+		}
+	}
+	for rid, perms := range exp.RolePermissions {
+		for _, pid := range perms {
+			if err := m.RP.Remove(ctx, rid, pid); err != nil {
+				return err
+			}
+		}
+	}
+	for _, u := range exp.Users {
+		if err := m.Users.DeleteUser(ctx, u.ID); err != nil {
+			return err
+		}
+	}
+	for _, r := range exp.Roles {
+		if err := m.Roles.DeleteRole(ctx, r.ID); err != nil {
+			return err
+		}
+	}
+	for _, p := range exp.Permissions {
+		if err := m.Perms.DeletePermission(ctx, p.ID); err != nil {
+			return err
+		}
+	}
+	return m.Tenants.DeleteTenant(ctx, tenantID)
+}
+
+func removeStr(list []string, s string) []string {
+	out := list[:0]
+	for _, v := range list {
+		if v != s {
+			out = append(out, v)
+		}
+	}
+	return out
+}
@@ -0,0 +1,717 @@
+package rbac
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrTenantMismatch is returned by a tenant-scoped Manager for writes that
+// reference an entity of another tenant, or no tenant.
+var ErrTenantMismatch = errors.New("rbac: entity belongs to another tenant")
+
+// TenantGroupName returns the stored name of a tenant's group. Group names
+// are unique per store, so tenant groups are qualified like roles.
+func TenantGroupName(tenantID, name string) string {
+	return TenantRoleName(tenantID, name)
+}
+
+// ForTenant returns a Manager that sees only tenantID's entities, so each
+// tenant works in its own namespace on a shared store:
+//
+//   - created permissions, roles, users, groups and memberships are stamped with the
+//     tenant ID, and role names, group names and permission resources are
+//     stored qualified (TenantRoleName, TenantGroupName, TenantResource)
+//     and returned unqualified, so tenants may reuse them;
+//   - reads return nothing for other tenants' entities, and a user's roles
+//     and groups are filtered to the tenant's;
+//   - assignments between entities of different tenants fail with
+//     ErrTenantMismatch.
+//
+// Can on the returned Manager therefore takes tenant-relative resources and
+// only considers the tenant's roles. The roles provisioned by CreateTenant
+// are visible under their template names. Role hierarchy is supported;
+// other optional capabilities (scheduling, scoped roles, export, ...) are
+// not available through a tenant-scoped Manager. Changes made through it
+// count towards this Manager's PolicyVersion.
+func (m *Manager) ForTenant(tenantID string) *Manager {
+	base := m
+	if m.base != nil {
+		base = m.base
+	}
+	ts := &tenantScope{
+		tenant: tenantID,
+		perms:  base.Perms,
+		roles:  base.Roles,
+		users:  base.Users,
+		rp:     base.RP,
+		ur:     base.UR,
+		ug:     base.UG,
+		gr:     base.GR,
+		groups: base.Groups,
+	}
+	tm := &Manager{
+		Perms:                ts,
+		Roles:                ts,
+		Users:                ts,
+		RP:                   ts,
+		UR:                   ts,
+		UG:                   ts,
+		GR:                   ts,
+		DefaultRoleName:      base.DefaultRoleName,
+		IDs:                  base.IDs,
+		Usage:                base.Usage,
+		Pool:                 base.Pool,
+		Catalog:              base.Catalog,
+		Notifier:             base.Notifier,
+		Listeners:            base.Listeners,
+		Resources:            base.Resources,
+		Actions:              base.Actions,
+		SoD:                  base.SoD,
+		Limits:               base.Limits,
+		TenantLimits:         base.TenantLimits,
+		Strict:               base.Strict,
+		RequireVerifiedEmail: base.RequireVerifiedEmail,
+		Authorizers:          base.Authorizers,
+		base:                 base,
+	}
+	if base.Groups != nil {
+		tm.Groups = ts
+	}
+	if limits, ok := base.TenantLimits[tenantID]; ok {
+		tm.Limits = limits
+	}
+	return tm
+}
+
+// Tenant returns the tenant a Manager returned by ForTenant is scoped to,
+// or "" for an unscoped Manager.
+func (m *Manager) Tenant() string {
+	if ts, ok := m.Perms.(*tenantScope); ok {
+		return ts.tenant
+	}
+	return ""
+}
+
+// tenantScope wraps a Manager's repos for ForTenant.
+type tenantScope struct {
+	tenant string
+	perms  PermissionRepo
+	roles  RoleRepo
+	users  UserRepo
+	rp     RolePermissionRepo
+	ur     UserRoleRepo
+	ug     UserGroupRepo
+	gr     GroupRoleRepo
+	groups GroupRepo
+}
+
+var (
+	_ Store                  = (*tenantScope)(nil)
+	_ RolePermissionDetailer = (*tenantScope)(nil)
+	_ RoleHierarchyRepo      = (*tenantScope)(nil)
+	_ GroupRepo              = (*tenantScope)(nil)
+	_ PermissionNameGetter   = (*tenantScope)(nil)
+)
+
+func (t *tenantScope) prefix() string { return t.tenant + ":" }
+
+// unqualifyPermission returns a copy of p with its resource relative to the
+// tenant, or nil when p is not the tenant's.
+func (t *tenantScope) unqualifyPermission(p *Permission) *Permission {
+	if p == nil || p.TenantID != t.tenant {
+		return nil
+	}
+	cp := *p
+	cp.Resource = strings.TrimPrefix(cp.Resource, t.tenant+".")
+	cp.Name = strings.TrimPrefix(cp.Name, t.prefix())
+	return &cp
+}
+
+func (t *tenantScope) unqualifyRole(r *Role) *Role {
+	if r == nil || r.TenantID != t.tenant {
+		return nil
+	}
+	cp := *r
+	cp.Name = strings.TrimPrefix(cp.Name, t.prefix())
+	return &cp
+}
+
+func (t *tenantScope) unqualifyGroup(ug *UserGroup) *UserGroup {
+	if ug == nil || !strings.HasPrefix(ug.GroupName, t.prefix()) {
+		return nil
+	}
+	cp := *ug
+	cp.GroupName = strings.TrimPrefix(cp.GroupName, t.prefix())
+	return &cp
+}
+
+func (t *tenantScope) checkRole(ctx context.Context, id string) error {
+	r, err := t.roles.GetRoleByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if r == nil || r.TenantID != t.tenant {
+		return fmt.Errorf("%w: role %q", ErrTenantMismatch, id)
+	}
+	return nil
+}
+
+func (t *tenantScope) checkUser(ctx context.Context, id string) error {
+	u, err := t.users.GetUserByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if u == nil || u.TenantID != t.tenant {
+		return fmt.Errorf("%w: user %q", ErrTenantMismatch, id)
+	}
+	return nil
+}
+
+func (t *tenantScope) checkPermission(ctx context.Context, id string) error {
+	p, err := t.perms.GetPermissionByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if p == nil || p.TenantID != t.tenant {
+		return fmt.Errorf("%w: permission %q", ErrTenantMismatch, id)
+	}
+	return nil
+}
+
+// stamp claims an entity for the tenant; one already stamped with another
+// tenant is rejected.
+func (t *tenantScope) stamp(tenantID *string) error {
+	if *tenantID != "" && *tenantID != t.tenant {
+		return fmt.Errorf("%w: %q", ErrTenantMismatch, *tenantID)
+	}
+	*tenantID = t.tenant
+	return nil
+}
+
+//
+// ---------- PermissionRepo ----------
+//
+
+func (t *tenantScope) CreatePermission(ctx context.Context, p *Permission) error {
+	if err := t.stamp(&p.TenantID); err != nil {
+		return err
+	}
+	resource, name := p.Resource, p.Name
+	p.Resource = TenantResource(t.tenant, resource)
+	if name != "" {
+		p.Name = TenantRoleName(t.tenant, name)
+	}
+	err := t.perms.CreatePermission(ctx, p)
+	p.Resource, p.Name = resource, name
+	return err
+}
+
+func (t *tenantScope) DeletePermission(ctx context.Context, id string) error {
+	if err := t.checkPermission(ctx, id); err != nil {
+		return err
+	}
+	return t.perms.DeletePermission(ctx, id)
+}
+
+func (t *tenantScope) GetPermissionByID(ctx context.Context, id string) (*Permission, error) {
+	p, err := t.perms.GetPermissionByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return t.unqualifyPermission(p), nil
+}
+
+func (t *tenantScope) ListAllPermissions(ctx context.Context) ([]*Permission, error) {
+	all, err := t.perms.ListAllPermissions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var out []*Permission
+	for _, p := range all {
+		if p = t.unqualifyPermission(p); p != nil {
+			out = append(out, p)
+		}
+	}
+	return out, nil
+}
+
+func (t *tenantScope) GetPermissionByResource(ctx context.Context, resource string, action Action) (*Permission, error) {
+	p, err := t.perms.GetPermissionByResource(ctx, TenantResource(t.tenant, resource), action)
+	if err != nil {
+		return nil, err
+	}
+	return t.unqualifyPermission(p), nil
+}
+
+// GetPermissionByName implements PermissionNameGetter when the wrapped repo
+// does; names are qualified like role names.
+func (t *tenantScope) GetPermissionByName(ctx context.Context, name string) (*Permission, error) {
+	g, ok := t.perms.(PermissionNameGetter)
+	if !ok {
+		return nil, errPermissionNameUnsupported
+	}
+	p, err := g.GetPermissionByName(ctx, TenantRoleName(t.tenant, name))
+	if err != nil {
+		return nil, err
+	}
+	return t.unqualifyPermission(p), nil
+}
+
+//
+// ---------- RoleRepo ----------
+//
+
+func (t *tenantScope) CreateRole(ctx context.Context, r *Role) error {
+	if err := t.stamp(&r.TenantID); err != nil {
+		return err
+	}
+	name := r.Name
+	r.Name = TenantRoleName(t.tenant, name)
+	err := t.roles.CreateRole(ctx, r)
+	r.Name = name
+	return err
+}
+
+func (t *tenantScope) DeleteRole(ctx context.Context, id string) error {
+	if err := t.checkRole(ctx, id); err != nil {
+		return err
+	}
+	return t.roles.DeleteRole(ctx, id)
+}
+
+func (t *tenantScope) GetRoleByID(ctx context.Context, id string) (*Role, error) {
+	r, err := t.roles.GetRoleByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return t.unqualifyRole(r), nil
+}
+
+func (t *tenantScope) GetRoleByName(ctx context.Context, name string) (*Role, error) {
+	r, err := t.roles.GetRoleByName(ctx, TenantRoleName(t.tenant, name))
+	if err != nil {
+		return nil, err
+	}
+	return t.unqualifyRole(r), nil
+}
+
+func (t *tenantScope) ListAllRoles(ctx context.Context) ([]*Role, error) {
+	all, err := t.roles.ListAllRoles(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var out []*Role
+	for _, r := range all {
+		if r = t.unqualifyRole(r); r != nil {
+			out = append(out, r)
+		}
+	}
+	return out, nil
+}
+
+//
+// ---------- RoleHierarchyRepo ----------
+//
+
+func (t *tenantScope) hierarchy() (RoleHierarchyRepo, error) {
+	repo, ok := t.roles.(RoleHierarchyRepo)
+	if !ok {
+		return nil, errHierarchyUnsupported
+	}
+	return repo, nil
+}
+
+func (t *tenantScope) AddRoleParent(ctx context.Context, roleID, parentID string) error {
+	repo, err := t.hierarchy()
+	if err != nil {
+		return err
+	}
+	if err := t.checkRole(ctx, roleID); err != nil {
+		return err
+	}
+	if err := t.checkRole(ctx, parentID); err != nil {
+		return err
+	}
+	return repo.AddRoleParent(ctx, roleID, parentID)
+}
+
+func (t *tenantScope) RemoveRoleParent(ctx context.Context, roleID, parentID string) error {
+	repo, err := t.hierarchy()
+	if err != nil {
+		return err
+	}
+	if err := t.checkRole(ctx, roleID); err != nil {
+		return err
+	}
+	return repo.RemoveRoleParent(ctx, roleID, parentID)
+}
+
+func (t *tenantScope) ListRoleParents(ctx context.Context, roleID string) ([]string, error) {
+	repo, err := t.hierarchy()
+	if err != nil {
+		return nil, err
+	}
+	parents, err := repo.ListRoleParents(ctx, roleID)
+	if err != nil {
+		return nil, err
+	}
+	return t.tenantRoles(ctx, parents)
+}
+
+// tenantRoles keeps the IDs of the tenant's roles.
+func (t *tenantScope) tenantRoles(ctx context.Context, ids []string) ([]string, error) {
+	out := make([]string, 0, len(ids))
+	for _, id := range ids {
+		r, err := t.roles.GetRoleByID(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if r != nil && r.TenantID == t.tenant {
+			out = append(out, id)
+		}
+	}
+	return out, nil
+}
+
+//
+// ---------- UserRepo ----------
+//
+
+func (t *tenantScope) CreateUser(ctx context.Context, u *User) error {
+	if err := t.stamp(&u.TenantID); err != nil {
+		return err
+	}
+	return t.users.CreateUser(ctx, u)
+}
+
+func (t *tenantScope) DeleteUser(ctx context.Context, id string) error {
+	if err := t.checkUser(ctx, id); err != nil {
+		return err
+	}
+	return t.users.DeleteUser(ctx, id)
+}
+
+func (t *tenantScope) GetUserByID(ctx context.Context, id string) (*User, error) {
+	u, err := t.users.GetUserByID(ctx, id)
+	if err != nil || u == nil || u.TenantID != t.tenant {
+		return nil, err
+	}
+	return u, nil
+}
+
+func (t *tenantScope) ListAllUsers(ctx context.Context) ([]*User, error) {
+	all, err := t.users.ListAllUsers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var out []*User
+	for _, u := range all {
+		if u.TenantID == t.tenant {
+			out = append(out, u)
+		}
+	}
+	return out, nil
+}
+
+func (t *tenantScope) GetUserByMeta(ctx context.Context, meta map[string]interface{}) (*User, error) {
+	u, err := t.users.GetUserByMeta(ctx, meta)
+	if err != nil || u == nil || u.TenantID != t.tenant {
+		return nil, err
+	}
+	return u, nil
+}
+
+func (t *tenantScope) GetGroupsByUserID(ctx context.Context, userID string) ([]*UserGroup, error) {
+	groups, err := t.ug.GetGroupsByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	var out []*UserGroup
+	for _, ug := range groups {
+		if ug = t.unqualifyGroup(ug); ug != nil {
+			out = append(out, ug)
+		}
+	}
+	return out, nil
+}
+
+//
+// ---------- RolePermissionRepo ----------
+//
+
+func (t *tenantScope) AddRP(ctx context.Context, roleID, permID string) error {
+	if err := t.checkRole(ctx, roleID); err != nil {
+		return err
+	}
+	if err := t.checkPermission(ctx, permID); err != nil {
+		return err
+	}
+	return t.rp.AddRP(ctx, roleID, permID)
+}
+
+func (t *tenantScope) Remove(ctx context.Context, roleID, permID string) error {
+	if err := t.checkRole(ctx, roleID); err != nil {
+		return err
+	}
+	return t.rp.Remove(ctx, roleID, permID)
+}
+
+func (t *tenantScope) ListPermissions(ctx context.Context, roleID string) ([]string, error) {
+	if r, err := t.GetRoleByID(ctx, roleID); err != nil || r == nil {
+		return nil, err
+	}
+	return t.rp.ListPermissions(ctx, roleID)
+}
+
+// ListPermissionDetails returns the tenant's permissions bound to one of
+// its roles, with tenant-relative resources.
+func (t *tenantScope) ListPermissionDetails(ctx context.Context, roleID string) ([]*Permission, error) {
+	if r, err := t.GetRoleByID(ctx, roleID); err != nil || r == nil {
+		return nil, err
+	}
+	var perms []*Permission
+	if d, ok := t.rp.(RolePermissionDetailer); ok {
+		var err error
+		if perms, err = d.ListPermissionDetails(ctx, roleID); err != nil {
+			return nil, err
+		}
+	} else {
+		ids, err := t.rp.ListPermissions(ctx, roleID)
+		if err != nil {
+			return nil, err
+		}
+		for _, id := range ids {
+			p, err := t.perms.GetPermissionByID(ctx, id)
+			if err != nil {
+				return nil, err
+			}
+			perms = append(perms, p)
+		}
+	}
+	out := make([]*Permission, 0, len(perms))
+	for _, p := range perms {
+		if p = t.unqualifyPermission(p); p != nil {
+			out = append(out, p)
+		}
+	}
+	return out, nil
+}
+
+//
+// ---------- UserRoleRepo ----------
+//
+
+func (t *tenantScope) AddUR(ctx context.Context, userID, roleID string) error {
+	if err := t.checkUser(ctx, userID); err != nil {
+		return err
+	}
+	if err := t.checkRole(ctx, roleID); err != nil {
+		return err
+	}
+	return t.ur.AddUR(ctx, userID, roleID)
+}
+
+func (t *tenantScope) RemoveUR(ctx context.Context, userID, roleID string) error {
+	if err := t.checkUser(ctx, userID); err != nil {
+		return err
+	}
+	return t.ur.RemoveUR(ctx, userID, roleID)
+}
+
+// ListRoles returns the user's roles that belong to the tenant; global roles
+// such as the default role are left out.
+func (t *tenantScope) ListRoles(ctx context.Context, userID string) ([]string, error) {
+	if u, err := t.GetUserByID(ctx, userID); err != nil || u == nil {
+		return nil, err
+	}
+	roles, err := t.ur.ListRoles(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return t.tenantRoles(ctx, roles)
+}
+
+// ListUsersForRole returns the tenant's users holding one of its roles.
+func (t *tenantScope) ListUsersForRole(ctx context.Context, roleID string) ([]string, error) {
+	if r, err := t.GetRoleByID(ctx, roleID); err != nil || r == nil {
+		return nil, err
+	}
+	ids, err := t.ur.ListUsersForRole(ctx, roleID)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]string, 0, len(ids))
+	for _, id := range ids {
+		u, err := t.users.GetUserByID(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if u != nil && u.TenantID == t.tenant {
+			out = append(out, id)
+		}
+	}
+	return out, nil
+}
+
+//
+// ---------- UserGroupRepo ----------
+//
+
+func (t *tenantScope) AddUserToGroup(ctx context.Context, ug *UserGroup) error {
+	if err := t.checkUser(ctx, ug.UserID); err != nil {
+		return err
+	}
+	if err := t.stamp(&ug.TenantID); err != nil {
+		return err
+	}
+	name := ug.GroupName
+	ug.GroupName = TenantGroupName(t.tenant, name)
+	err := t.ug.AddUserToGroup(ctx, ug)
+	ug.GroupName = name
+	return err
+}
+
+func (t *tenantScope) RemoveUserFromGroup(ctx context.Context, groupName string, ug *UserGroup) error {
+	if err := t.checkUser(ctx, ug.UserID); err != nil {
+		return err
+	}
+	cp := *ug
+	cp.GroupName = TenantGroupName(t.tenant, ug.GroupName)
+	return t.ug.RemoveUserFromGroup(ctx, TenantGroupName(t.tenant, groupName), &cp)
+}
+
+func (t *tenantScope) GetUsersByGroupID(ctx context.Context, groupName string) ([]*UserGroup, error) {
+	members, err := t.ug.GetUsersByGroupID(ctx, TenantGroupName(t.tenant, groupName))
+	if err != nil {
+		return nil, err
+	}
+	out := make([]*UserGroup, 0, len(members))
+	for _, ug := range members {
+		if ug = t.unqualifyGroup(ug); ug != nil {
+			out = append(out, ug)
+		}
+	}
+	return out, nil
+}
+
+//
+// ---------- GroupRoleRepo ----------
+//
+
+func (t *tenantScope) AddRoleToGroup(ctx context.Context, groupID, roleID string) error {
+	if err := t.checkRole(ctx, roleID); err != nil {
+		return err
+	}
+	return t.gr.AddRoleToGroup(ctx, TenantGroupName(t.tenant, groupID), roleID)
+}
+
+func (t *tenantScope) RemoveRoleFromGroup(ctx context.Context, groupID, roleID string) error {
+	return t.gr.RemoveRoleFromGroup(ctx, TenantGroupName(t.tenant, groupID), roleID)
+}
+
+func (t *tenantScope) ListRolesForGroup(ctx context.Context, groupID string) ([]string, error) {
+	return t.gr.ListRolesForGroup(ctx, TenantGroupName(t.tenant, groupID))
+}
+
+// ListGroupsForRole returns the tenant's groups holding one of its roles,
+// unqualified.
+func (t *tenantScope) ListGroupsForRole(ctx context.Context, roleID string) ([]string, error) {
+	if r, err := t.GetRoleByID(ctx, roleID); err != nil || r == nil {
+		return nil, err
+	}
+	names, err := t.gr.ListGroupsForRole(ctx, roleID)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]string, 0, len(names))
+	for _, name := range names {
+		if strings.HasPrefix(name, t.prefix()) {
+			out = append(out, strings.TrimPrefix(name, t.prefix()))
+		}
+	}
+	return out, nil
+}
+
+//
+// ---------- GroupRepo ----------
+//
+
+func (t *tenantScope) unqualifyGroupEntity(g *Group) *Group {
+	if g == nil || g.TenantID != t.tenant {
+		return nil
+	}
+	cp := *g
+	cp.Name = strings.TrimPrefix(cp.Name, t.prefix())
+	return &cp
+}
+
+func (t *tenantScope) checkGroup(ctx context.Context, id string) error {
+	g, err := t.groups.GetGroupByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if g == nil || g.TenantID != t.tenant {
+		return fmt.Errorf("%w: group %q", ErrTenantMismatch, id)
+	}
+	return nil
+}
+
+func (t *tenantScope) CreateGroup(ctx context.Context, g *Group) error {
+	if err := t.stamp(&g.TenantID); err != nil {
+		return err
+	}
+	name := g.Name
+	g.Name = TenantGroupName(t.tenant, name)
+	err := t.groups.CreateGroup(ctx, g)
+	g.Name = name
+	return err
+}
+
+func (t *tenantScope) UpdateGroup(ctx context.Context, g *Group) error {
+	if err := t.checkGroup(ctx, g.ID); err != nil {
+		return err
+	}
+	cp := *g
+	cp.TenantID = t.tenant
+	cp.Name = TenantGroupName(t.tenant, g.Name)
+	return t.groups.UpdateGroup(ctx, &cp)
+}
+
+func (t *tenantScope) DeleteGroup(ctx context.Context, id string) error {
+	if err := t.checkGroup(ctx, id); err != nil {
+		return err
+	}
+	return t.groups.DeleteGroup(ctx, id)
+}
+
+func (t *tenantScope) GetGroupByID(ctx context.Context, id string) (*Group, error) {
+	g, err := t.groups.GetGroupByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return t.unqualifyGroupEntity(g), nil
+}
+
+func (t *tenantScope) GetGroupByName(ctx context.Context, name string) (*Group, error) {
+	g, err := t.groups.GetGroupByName(ctx, TenantGroupName(t.tenant, name))
+	if err != nil {
+		return nil, err
+	}
+	return t.unqualifyGroupEntity(g), nil
+}
+
+func (t *tenantScope) ListGroups(ctx context.Context) ([]*Group, error) {
+	all, err := t.groups.ListGroups(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var out []*Group
+	for _, g := range all {
+		if g = t.unqualifyGroupEntity(g); g != nil {
+			out = append(out, g)
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,103 @@
+package rbac
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestForTenant(t *testing.T) {
+	ctx := context.Background()
+	mgr, err := NewMemoryStoreManager(ctx, "", 0)
+	if err != nil {
+		t.Fatalf("NewMemoryStoreManager: %v", err)
+	}
+	for _, id := range []string{"acme", "globex"} {
+		if err := mgr.CreateTenant(ctx, &Tenant{ID: id}); err != nil {
+			t.Fatalf("CreateTenant(%s): %v", id, err)
+		}
+	}
+	acme, globex := mgr.ForTenant("acme"), mgr.ForTenant("globex")
+	if acme.Tenant() != "acme" || mgr.Tenant() != "" {
+		t.Fatalf("unexpected tenants %q and %q", acme.Tenant(), mgr.Tenant())
+	}
+
+	// Both tenants use the same names without colliding.
+	editors := map[string]*Role{}
+	for name, tm := range map[string]*Manager{"acme": acme, "globex": globex} {
+		role := &Role{Name: "editor"}
+		if err := tm.CreateRole(ctx, role); err != nil {
+			t.Fatalf("%s CreateRole: %v", name, err)
+		}
+		if role.Name != "editor" || role.TenantID != name {
+			t.Fatalf("%s: expected the caller's role to stay unqualified and stamped, got %+v", name, role)
+		}
+		perm := &Permission{Resource: "docs/*", Action: ActionUpdate}
+		if err := tm.CreatePermission(ctx, perm); err != nil {
+			t.Fatalf("%s CreatePermission: %v", name, err)
+		}
+		if err := tm.AssignPermissionToRole(ctx, role.ID, perm.ID); err != nil {
+			t.Fatalf("%s AssignPermissionToRole: %v", name, err)
+		}
+		editors[name] = role
+	}
+	if editors["acme"].ID == editors["globex"].ID {
+		t.Fatal("expected each tenant to get its own editor role")
+	}
+	got, err := acme.Roles.GetRoleByName(ctx, "editor")
+	if err != nil || got == nil || got.ID != editors["acme"].ID || got.Name != "editor" {
+		t.Fatalf("GetRoleByName = %+v, %v", got, err)
+	}
+	if admin, _ := acme.Roles.GetRoleByName(ctx, "admin"); admin == nil {
+		t.Error("expected the provisioned admin role under its template name")
+	}
+	if r, _ := globex.GetRole(ctx, editors["acme"].ID); r != nil {
+		t.Error("expected another tenant's role to be invisible")
+	}
+
+	alice := &User{Username: "alice"}
+	if err := acme.CreateUser(ctx, alice); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	if err := acme.AddUserToGroup(ctx, &UserGroup{UserID: alice.ID, GroupName: "writers"}); err != nil {
+		t.Fatalf("AddUserToGroup: %v", err)
+	}
+	if err := acme.AssignRoleToGroup(ctx, "writers", editors["acme"].ID); err != nil {
+		t.Fatalf("AssignRoleToGroup: %v", err)
+	}
+	groups, err := acme.GetGroupsByUserID(ctx, alice.ID)
+	if err != nil || len(groups) != 1 || groups[0].GroupName != "writers" {
+		t.Fatalf("GetGroupsByUserID = %+v, %v", groups, err)
+	}
+	if roles, _ := globex.ListRolesForGroup(ctx, "writers"); len(roles) != 0 {
+		t.Errorf("expected globex's writers group to be empty, got %v", roles)
+	}
+
+	if ok, err := acme.Can(ctx, alice.ID, "docs/readme", ActionUpdate); err != nil || !ok {
+		t.Errorf("acme Can = %v, %v; want true", ok, err)
+	}
+	if ok, _ := globex.Can(ctx, alice.ID, "docs/readme", ActionUpdate); ok {
+		t.Error("expected alice to have no access through another tenant")
+	}
+	if ok, _ := mgr.Can(ctx, alice.ID, "acme.docs/readme", ActionUpdate); !ok {
+		t.Error("expected the unscoped Manager to see the qualified resource")
+	}
+
+	if err := globex.AssignRoleToUser(ctx, alice.ID, editors["globex"].ID); !errors.Is(err, ErrTenantMismatch) {
+		t.Errorf("expected a cross-tenant user assignment to fail, got %v", err)
+	}
+	if err := acme.AssignRoleToUser(ctx, alice.ID, editors["globex"].ID); !errors.Is(err, ErrTenantMismatch) {
+		t.Errorf("expected a cross-tenant role assignment to fail, got %v", err)
+	}
+	if err := globex.CreateRole(ctx, &Role{Name: "spy", TenantID: "acme"}); !errors.Is(err, ErrTenantMismatch) {
+		t.Errorf("expected an entity of another tenant to be rejected, got %v", err)
+	}
+
+	before, _ := mgr.PolicyVersion(ctx)
+	if err := acme.DeleteRole(ctx, editors["acme"].ID); err != nil {
+		t.Fatalf("DeleteRole: %v", err)
+	}
+	if after, _ := mgr.PolicyVersion(ctx); after == before {
+		t.Error("expected changes through a tenant Manager to bump the policy version")
+	}
+}
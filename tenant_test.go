@@ -0,0 +1,99 @@
+package rbac
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+)
+
+func TestTenantLifecycle(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMockRepo()
+	mgr := NewMockRepoManager(repo)
+
+	acme := &Tenant{ID: "acme", Name: "Acme"}
+	if err := mgr.CreateTenant(ctx, acme); err != nil {
+		t.Fatalf("CreateTenant: %v", err)
+	}
+	if err := mgr.CreateTenant(ctx, &Tenant{ID: "acme"}); err == nil {
+		t.Error("expected duplicate tenant to be rejected")
+	}
+
+	roles, _ := repo.ListRolesByTenant(ctx, "acme")
+	if len(roles) != len(DefaultTenantTemplate.Roles) {
+		t.Fatalf("expected %d provisioned roles, got %d", len(DefaultTenantTemplate.Roles), len(roles))
+	}
+	admin, _ := repo.GetRoleByName(ctx, TenantRoleName("acme", "admin"))
+	if admin == nil || admin.TenantID != "acme" {
+		t.Fatalf("expected tenant admin role, got %+v", admin)
+	}
+
+	// A tenant user plus a global user and role that must survive teardown.
+	alice := &User{ID: "alice", Username: "alice", TenantID: "acme"}
+	if err := mgr.CreateUser(ctx, alice); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	if err := mgr.AssignRoleToUser(ctx, alice.ID, admin.ID); err != nil {
+		t.Fatalf("AssignRoleToUser: %v", err)
+	}
+	if err := mgr.AddUserToGroup(ctx, &UserGroup{UserID: alice.ID, GroupName: "ops", TenantID: "acme"}); err != nil {
+		t.Fatalf("AddUserToGroup: %v", err)
+	}
+	if err := mgr.AssignRoleToGroup(ctx, "ops", admin.ID); err != nil {
+		t.Fatalf("AssignRoleToGroup: %v", err)
+	}
+	global := &Role{ID: "global", Name: "global"}
+	if err := mgr.CreateRole(ctx, global); err != nil {
+		t.Fatalf("CreateRole: %v", err)
+	}
+	if err := mgr.AssignRoleToGroup(ctx, "ops", global.ID); err != nil {
+		t.Fatalf("AssignRoleToGroup: %v", err)
+	}
+
+	for resource, want := range map[string]bool{"acme.survey.1": true, "other.survey.1": false} {
+		ok, err := mgr.Can(ctx, alice.ID, resource, ActionDelete)
+		if err != nil {
+			t.Fatalf("Can: %v", err)
+		}
+		if ok != want {
+			t.Errorf("Can(%s) = %v, want %v", resource, ok, want)
+		}
+	}
+
+	if err := mgr.DeleteTenant(ctx, "acme", nil); err == nil {
+		t.Fatal("expected DeleteTenant without a backup to fail")
+	}
+	if got, _ := repo.GetUserByID(ctx, alice.ID); got == nil {
+		t.Fatal("nothing should be deleted when the export is refused")
+	}
+
+	var backup bytes.Buffer
+	if err := mgr.DeleteTenant(ctx, "acme", &backup); err != nil {
+		t.Fatalf("DeleteTenant: %v", err)
+	}
+
+	var exp TenantExport
+	if err := json.Unmarshal(backup.Bytes(), &exp); err != nil {
+		t.Fatalf("decode export: %v", err)
+	}
+	if exp.Tenant == nil || exp.Tenant.ID != "acme" || len(exp.Users) != 1 || len(exp.Roles) != 2 {
+		t.Errorf("unexpected export: %+v", exp)
+	}
+	if got := exp.GroupRoles["ops"]; len(got) != 1 || got[0] != admin.ID {
+		t.Errorf("expected only the tenant role in group bindings, got %v", got)
+	}
+
+	if got, _ := repo.GetTenantByID(ctx, "acme"); got != nil {
+		t.Error("expected tenant to be deleted")
+	}
+	if got, _ := repo.GetUserByID(ctx, alice.ID); got != nil {
+		t.Error("expected tenant user to be deleted")
+	}
+	if perms, _ := repo.ListPermissionsByTenant(ctx, "acme"); len(perms) != 0 {
+		t.Errorf("expected tenant permissions to be deleted, got %d", len(perms))
+	}
+	if groups, _ := repo.ListRolesForGroup(ctx, "ops"); len(groups) != 1 || groups[0] != global.ID {
+		t.Errorf("expected global group binding to survive, got %v", groups)
+	}
+}
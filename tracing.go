@@ -0,0 +1,176 @@
+package rbac
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("rbac/manager")
+
+// methodSpan is the span a Manager method started, kept in its context so
+// record can mark it failed and an access check can still find the
+// caller's span.
+type methodSpan struct {
+	method string
+	span   trace.Span
+	parent trace.Span
+}
+
+type methodSpanKey struct{}
+
+// startSpan starts the span of a Manager method, named rbac.<method>, as a
+// child of the span in ctx. The caller ends it.
+func (m *Manager) startSpan(ctx context.Context, method string) (context.Context, trace.Span) {
+	parent := trace.SpanFromContext(ctx)
+	ctx, span := tracer.Start(ctx, "rbac."+method)
+	if span.IsRecording() {
+		span.SetAttributes(
+			attribute.String("rbac.method", method),
+			attribute.String("rbac.store", fmt.Sprintf("%T", m.Perms)),
+		)
+	}
+	return context.WithValue(ctx, methodSpanKey{}, &methodSpan{method: method, span: span, parent: parent}), span
+}
+
+func methodSpanFrom(ctx context.Context) *methodSpan {
+	ms, _ := ctx.Value(methodSpanKey{}).(*methodSpan)
+	return ms
+}
+
+// spanError marks the span of method in ctx failed with err.
+func spanError(ctx context.Context, method string, err error) {
+	ms := methodSpanFrom(ctx)
+	if ms == nil || ms.method != method || !ms.span.IsRecording() {
+		return
+	}
+	ms.span.RecordError(err)
+	ms.span.SetStatus(codes.Error, err.Error())
+}
+
+// decisionTrace annotates the caller's active span with how an access check
+// was decided, and starts a child of the check's own span for each store
+// read. It is nil when neither span is recording, and every method is a
+// no-op on nil.
+type decisionTrace struct {
+	// span is the caller's span, nil when it is not recording.
+	span trace.Span
+	// ctx carries the check's own span.
+	ctx    context.Context
+	events bool
+
+	hits, misses atomic.Int64
+}
+
+type decisionTraceKey struct{}
+
+// startDecisionTrace returns a decisionTrace for the spans in ctx, and ctx
+// carrying it so a CachedStore can count its hits, when either records.
+func (m *Manager) startDecisionTrace(ctx context.Context) (context.Context, *decisionTrace) {
+	span, own := trace.SpanFromContext(ctx), false
+	if ms := methodSpanFrom(ctx); ms != nil && ms.span.SpanContext().Equal(span.SpanContext()) {
+		span, own = ms.parent, ms.span.IsRecording()
+	}
+	if !span.IsRecording() {
+		if !own {
+			return ctx, nil
+		}
+		span = nil
+	}
+	tr := &decisionTrace{span: span, ctx: ctx, events: m.TraceStoreCalls}
+	return context.WithValue(ctx, decisionTraceKey{}, tr), tr
+}
+
+func decisionTraceFrom(ctx context.Context) *decisionTrace {
+	tr, _ := ctx.Value(decisionTraceKey{}).(*decisionTrace)
+	return tr
+}
+
+func (t *decisionTrace) cacheHit() {
+	if t != nil {
+		t.hits.Add(1)
+	}
+}
+
+func (t *decisionTrace) cacheMiss() {
+	if t != nil {
+		t.misses.Add(1)
+	}
+}
+
+// storeCall records one store read made by the check, from start until now:
+// as a child span of the check's span, and as an event on the caller's span
+// when Manager.TraceStoreCalls is set.
+func (t *decisionTrace) storeCall(op string, start time.Time, err error, attrs ...attribute.KeyValue) {
+	if t == nil {
+		return
+	}
+	attrs = append(attrs, attribute.String("rbac.store.op", op))
+	if trace.SpanFromContext(t.ctx).IsRecording() {
+		_, span := tracer.Start(t.ctx, "rbac.store."+op, trace.WithTimestamp(start), trace.WithAttributes(attrs...))
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}
+	if t.span == nil || !t.events {
+		return
+	}
+	attrs = append(attrs, attribute.Float64("rbac.store.duration_ms", float64(time.Since(start).Microseconds())/1000))
+	if err != nil {
+		attrs = append(attrs, attribute.String("rbac.store.error", err.Error()))
+	}
+	t.span.AddEvent("rbac.store_call", trace.WithAttributes(attrs...))
+}
+
+// finish sets the decision attributes on the caller's span.
+func (t *decisionTrace) finish(resource string, action Action, d *Decision, err error) {
+	if t == nil || t.span == nil {
+		return
+	}
+	attrs := decisionAttributes(resource, action, d, err)
+	if err != nil {
+		t.span.RecordError(err)
+	}
+	if hits, misses := t.hits.Load(), t.misses.Load(); hits+misses > 0 {
+		attrs = append(attrs,
+			attribute.Bool("rbac.cache_hit", misses == 0),
+			attribute.Int64("rbac.cache.hits", hits),
+			attribute.Int64("rbac.cache.misses", misses),
+		)
+	}
+	t.span.SetAttributes(attrs...)
+}
+
+// decisionAttributes describes the outcome of a check on resource.
+func decisionAttributes(resource string, action Action, d *Decision, err error) []attribute.KeyValue {
+	attrs := []attribute.KeyValue{
+		attribute.String("rbac.resource", resource),
+		attribute.String("rbac.action", string(action)),
+	}
+	switch {
+	case err != nil:
+		attrs = append(attrs, attribute.String("rbac.decision", "error"))
+	case d.Allowed:
+		attrs = append(attrs, attribute.String("rbac.decision", "allow"))
+	default:
+		attrs = append(attrs, attribute.String("rbac.decision", "deny"))
+	}
+	if d != nil && d.PermissionID != "" {
+		attrs = append(attrs, attribute.String("rbac.permission_id", d.PermissionID))
+	}
+	if d != nil && d.RoleID != "" {
+		attrs = append(attrs, attribute.String("rbac.role_id", d.RoleID))
+	}
+	if d != nil && d.SuperAdmin {
+		attrs = append(attrs, attribute.Bool("rbac.super_admin", true))
+	}
+	return attrs
+}
@@ -0,0 +1,172 @@
+package rbac
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestCanAnnotatesActiveSpan(t *testing.T) {
+	ctx := context.Background()
+	inner, err := NewMemoryStoreManager(ctx, "", 0)
+	if err != nil {
+		t.Fatalf("NewMemoryStoreManager: %v", err)
+	}
+	mgr := NewCachedStoreManager(inner.Perms.(Store), time.Minute)
+	mgr.TraceStoreCalls = true
+	role := &Role{Name: "reader"}
+	if err := mgr.CreateRole(ctx, role); err != nil {
+		t.Fatalf("CreateRole: %v", err)
+	}
+	perm := &Permission{Resource: "docs/*", Action: ActionRead}
+	if err := mgr.CreatePermission(ctx, perm); err != nil {
+		t.Fatalf("CreatePermission: %v", err)
+	}
+	if err := mgr.AssignPermissionToRole(ctx, role.ID, perm.ID); err != nil {
+		t.Fatalf("AssignPermissionToRole: %v", err)
+	}
+	if err := mgr.AssignRoleToUser(ctx, "alice", role.ID); err != nil {
+		t.Fatalf("AssignRoleToUser: %v", err)
+	}
+
+	rec := tracetest.NewSpanRecorder()
+	tracer := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec)).Tracer("test")
+	check := func(resource string) map[attribute.Key]attribute.Value {
+		t.Helper()
+		spanCtx, span := tracer.Start(ctx, "request")
+		if _, err := mgr.Can(spanCtx, "alice", resource, ActionRead); err != nil {
+			t.Fatalf("Can: %v", err)
+		}
+		span.End()
+		ended := rec.Ended()
+		s := ended[len(ended)-1]
+		if len(s.Events()) == 0 {
+			t.Error("expected span events for the store calls")
+		}
+		attrs := map[attribute.Key]attribute.Value{}
+		for _, kv := range s.Attributes() {
+			attrs[kv.Key] = kv.Value
+		}
+		return attrs
+	}
+
+	attrs := check("docs/readme")
+	if attrs["rbac.decision"].AsString() != "allow" || attrs["rbac.permission_id"].AsString() != perm.ID || attrs["rbac.role_id"].AsString() != role.ID {
+		t.Errorf("unexpected allow attributes %v", attrs)
+	}
+	if attrs["rbac.cache_hit"].AsBool() {
+		t.Error("expected the first check to miss the cache")
+	}
+
+	attrs = check("wiki/home")
+	if attrs["rbac.decision"].AsString() != "deny" {
+		t.Errorf("expected a deny, got %v", attrs)
+	}
+	if _, ok := attrs["rbac.permission_id"]; ok {
+		t.Errorf("expected no permission on a default deny, got %v", attrs)
+	}
+	if !attrs["rbac.cache_hit"].AsBool() {
+		t.Errorf("expected the second check to be served from the cache, got %v", attrs)
+	}
+
+	// without a recording span Can adds nothing and must not fail
+	if ok, err := mgr.Can(ctx, "alice", "docs/readme", ActionRead); err != nil || !ok {
+		t.Errorf("Can without a span = %v, %v", ok, err)
+	}
+}
+
+var (
+	globalSpansOnce sync.Once
+	globalSpans     *tracetest.SpanRecorder
+)
+
+// recordGlobalSpans installs a global tracer provider, once per test binary,
+// that records the spans of traces a test started itself and no others.
+func recordGlobalSpans() *tracetest.SpanRecorder {
+	globalSpansOnce.Do(func() {
+		globalSpans = tracetest.NewSpanRecorder()
+		otel.SetTracerProvider(sdktrace.NewTracerProvider(
+			sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.NeverSample())),
+			sdktrace.WithSpanProcessor(globalSpans),
+		))
+	})
+	return globalSpans
+}
+
+func TestManagerSpans(t *testing.T) {
+	ctx := context.Background()
+	rec := recordGlobalSpans()
+	mgr := NewMockRepoManager(NewMockRepo())
+	mgr.PolicyVersions = nil
+	if err := mgr.CreatePermission(ctx, &Permission{ID: "docs-read", Resource: "docs/*", Action: ActionRead}); err != nil {
+		t.Fatalf("CreatePermission: %v", err)
+	}
+	if err := mgr.CreateRole(ctx, &Role{ID: "viewer", Name: "viewer"}); err != nil {
+		t.Fatalf("CreateRole: %v", err)
+	}
+	if err := mgr.AssignPermissionToRole(ctx, "viewer", "docs-read"); err != nil {
+		t.Fatalf("AssignPermissionToRole: %v", err)
+	}
+	if err := mgr.AssignRoleToUser(ctx, "alice", "viewer"); err != nil {
+		t.Fatalf("AssignRoleToUser: %v", err)
+	}
+
+	reqCtx, req := sdktrace.NewTracerProvider().Tracer("test").Start(ctx, "request")
+	if ok, err := mgr.Can(reqCtx, "alice", "docs/1", ActionRead); err != nil || !ok {
+		t.Fatalf("Can = %v, %v", ok, err)
+	}
+	if _, err := mgr.SnapshotPolicy(reqCtx, ""); err == nil {
+		t.Fatal("SnapshotPolicy without a PolicyVersionRepo succeeded")
+	}
+	req.End()
+
+	spans := map[string]sdktrace.ReadOnlySpan{}
+	for _, s := range rec.Ended() {
+		if s.SpanContext().TraceID() == req.SpanContext().TraceID() {
+			spans[s.Name()] = s
+		}
+	}
+	can := spans["rbac.Can"]
+	if can == nil {
+		t.Fatalf("no rbac.Can span among %v", spans)
+	}
+	if can.Parent().SpanID() != req.SpanContext().SpanID() {
+		t.Error("rbac.Can is not a child of the caller's span")
+	}
+	attrs := map[attribute.Key]attribute.Value{}
+	for _, kv := range can.Attributes() {
+		attrs[kv.Key] = kv.Value
+	}
+	if attrs["rbac.method"].AsString() != "Can" || attrs["rbac.store"].AsString() != "*rbac.MockRepo" ||
+		attrs["rbac.decision"].AsString() != "allow" || attrs["rbac.permission_id"].AsString() != "docs-read" {
+		t.Errorf("unexpected rbac.Can attributes %v", attrs)
+	}
+	for _, name := range []string{"rbac.store.ListRoles", "rbac.store.GetRoleByID", "rbac.store.RolePermissions"} {
+		s := spans[name]
+		if s == nil {
+			t.Errorf("no %s span", name)
+			continue
+		}
+		if s.Parent().SpanID() != can.SpanContext().SpanID() {
+			t.Errorf("%s is not a child of rbac.Can", name)
+		}
+		if s.EndTime().Before(s.StartTime()) || s.StartTime().Before(can.StartTime()) {
+			t.Errorf("%s runs from %v to %v, outside rbac.Can", name, s.StartTime(), s.EndTime())
+		}
+	}
+
+	snap := spans["rbac.SnapshotPolicy"]
+	if snap == nil {
+		t.Fatal("no rbac.SnapshotPolicy span")
+	}
+	if snap.Status().Code != codes.Error || snap.Status().Description != errNoPolicyVersionRepo.Error() {
+		t.Errorf("rbac.SnapshotPolicy status = %+v; want the error", snap.Status())
+	}
+}
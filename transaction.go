@@ -0,0 +1,32 @@
+package rbac
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// Transactor is implemented by stores that can run several operations
+// atomically. Repo calls made with the ctx passed to fn join the transaction;
+// if fn returns an error, none of them take effect.
+type Transactor interface {
+	WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+var errTransactionsUnsupported = errors.New("rbac: store does not support transactions")
+
+// WithTransaction runs fn in a transaction of the Manager's store, so
+// multi-step changes such as creating a role, granting it permissions and
+// assigning it to a group commit together. Manager methods called from fn
+// must be given the ctx fn receives.
+func (m *Manager) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	start := time.Now()
+	ctx, span := m.startSpan(ctx, "WithTransaction")
+	defer span.End()
+	err := errTransactionsUnsupported
+	if tx, ok := m.Perms.(Transactor); ok {
+		err = tx.WithTransaction(ctx, fn)
+	}
+	m.record(ctx, start, "WithTransaction", err)
+	return err
+}
@@ -0,0 +1,45 @@
+package rbac
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// txStore records whether calls ran inside WithTransaction.
+type txStore struct {
+	*MockRepo
+	committed bool
+}
+
+type txKey struct{}
+
+func (s *txStore) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if err := fn(context.WithValue(ctx, txKey{}, true)); err != nil {
+		return err
+	}
+	s.committed = true
+	return nil
+}
+
+func TestManagerWithTransaction(t *testing.T) {
+	ctx := context.Background()
+
+	mgr := NewMockRepoManager(NewMockRepo())
+	if err := mgr.WithTransaction(ctx, func(context.Context) error { return nil }); err != errTransactionsUnsupported {
+		t.Fatalf("expected errTransactionsUnsupported, got %v", err)
+	}
+
+	store := &txStore{MockRepo: NewMockRepo()}
+	mgr = NewMockRepoManager(store.MockRepo)
+	mgr.Perms = store
+	err := mgr.WithTransaction(ctx, func(ctx context.Context) error {
+		if ctx.Value(txKey{}) == nil {
+			return errors.New("fn did not get the transaction context")
+		}
+		return mgr.CreateRole(ctx, &Role{ID: "r1", Name: "analyst"})
+	})
+	if err != nil || !store.committed {
+		t.Fatalf("expected a committed transaction, got %v", err)
+	}
+}
@@ -0,0 +1,81 @@
+package rbac
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// The Manager checks role names, usernames and emails, and the resource and
+// action of permissions, before they reach the store, so stores without
+// unique indexes, such as MockRepo, behave like those with them.
+var (
+	// ErrRoleNameTaken is returned when creating a role whose Name another
+	// role already has.
+	ErrRoleNameTaken = errors.New("rbac: role name is already taken")
+	// ErrUsernameTaken is returned when creating a user whose Username
+	// another user already has.
+	ErrUsernameTaken = errors.New("rbac: username is already taken")
+	// ErrEmailTaken is returned when creating a user whose Email another
+	// user already has.
+	ErrEmailTaken = errors.New("rbac: email is already taken")
+)
+
+// UserLookup is optionally implemented by a UserRepo that can look users up
+// by username and email directly. Repos without it are asked through
+// GetUserByMeta.
+type UserLookup interface {
+	// GetUserByUsername returns the user with the username, or nil, nil.
+	GetUserByUsername(ctx context.Context, username string) (*User, error)
+	// GetUserByEmail returns the user with the email, or nil, nil.
+	GetUserByEmail(ctx context.Context, email string) (*User, error)
+}
+
+// checkRoleName rejects a role whose name is taken.
+func (m *Manager) checkRoleName(ctx context.Context, r *Role) error {
+	existing, err := m.Roles.GetRoleByName(ctx, r.Name)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		return fmt.Errorf("%w: %q", ErrRoleNameTaken, r.Name)
+	}
+	return nil
+}
+
+// checkUserUnique rejects a user whose username or email is taken. Empty
+// values are not checked.
+func (m *Manager) checkUserUnique(ctx context.Context, u *User) error {
+	if u.Username != "" {
+		existing, err := m.lookupUser(ctx, "username", u.Username)
+		if err != nil {
+			return err
+		}
+		if existing != nil {
+			return fmt.Errorf("%w: %q", ErrUsernameTaken, u.Username)
+		}
+	}
+	if u.Email != "" {
+		existing, err := m.lookupUser(ctx, "email", u.Email)
+		if err != nil {
+			return err
+		}
+		if existing != nil {
+			return fmt.Errorf("%w: %q", ErrEmailTaken, u.Email)
+		}
+	}
+	return nil
+}
+
+// lookupUser finds a user by "username" or "email".
+func (m *Manager) lookupUser(ctx context.Context, field, value string) (*User, error) {
+	l, ok := m.Users.(UserLookup)
+	switch {
+	case !ok:
+		return m.Users.GetUserByMeta(ctx, map[string]interface{}{field: value})
+	case field == "email":
+		return l.GetUserByEmail(ctx, value)
+	default:
+		return l.GetUserByUsername(ctx, value)
+	}
+}
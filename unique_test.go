@@ -0,0 +1,51 @@
+package rbac
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestManagerUniqueness(t *testing.T) {
+	ctx := context.Background()
+	memory, err := NewMemoryStoreManager(ctx, "", 0)
+	if err != nil {
+		t.Fatalf("NewMemoryStoreManager: %v", err)
+	}
+	managers := map[string]*Manager{
+		"mock":   NewMockRepoManager(NewMockRepo()),
+		"memory": memory,
+	}
+	for name, mgr := range managers {
+		t.Run(name, func(t *testing.T) {
+			if err := mgr.CreateRole(ctx, &Role{Name: "editor"}); err != nil {
+				t.Fatalf("CreateRole: %v", err)
+			}
+			if err := mgr.CreateRole(ctx, &Role{Name: "editor"}); !errors.Is(err, ErrRoleNameTaken) {
+				t.Errorf("expected ErrRoleNameTaken, got %v", err)
+			}
+
+			if err := mgr.CreateUser(ctx, &User{Username: "alice", Email: "alice@example.com"}); err != nil {
+				t.Fatalf("CreateUser: %v", err)
+			}
+			if err := mgr.CreateUser(ctx, &User{Username: "alice"}); !errors.Is(err, ErrUsernameTaken) {
+				t.Errorf("expected ErrUsernameTaken, got %v", err)
+			}
+			if err := mgr.CreateUser(ctx, &User{Username: "alice2", Email: "alice@example.com"}); !errors.Is(err, ErrEmailTaken) {
+				t.Errorf("expected ErrEmailTaken, got %v", err)
+			}
+			if err := mgr.CreateUser(ctx, &User{Username: "bob"}); err != nil {
+				t.Errorf("CreateUser without an email: %v", err)
+			}
+
+			first := &Permission{Resource: "docs", Action: ActionRead}
+			if err := mgr.CreatePermission(ctx, first); err != nil {
+				t.Fatalf("CreatePermission: %v", err)
+			}
+			again := &Permission{Resource: "docs", Action: ActionRead}
+			if err := mgr.CreatePermission(ctx, again); err != nil || again.ID != first.ID {
+				t.Errorf("expected creating the same permission to yield %s, got %s, %v", first.ID, again.ID, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,202 @@
+package rbac
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"sort"
+	"sync"
+	"time"
+)
+
+// UsageTracker counts how often each permission let Can allow a request,
+// in fixed-size time buckets. Set one on Manager.Usage to enable tracking
+// and Manager.PermissionUsage.
+type UsageTracker struct {
+	bucket time.Duration
+	keep   int
+	now    func() time.Time
+
+	mu      sync.Mutex
+	start   time.Time         // start of the open bucket
+	current map[string]uint64 // hits in the open bucket
+	closed  []UsageBucket     // oldest first, at most keep-1 entries
+}
+
+// UsageBucket holds the hits per permission ID recorded in the bucket that
+// began at Start.
+type UsageBucket struct {
+	Start time.Time
+	Hits  map[string]uint64
+}
+
+// NewUsageTracker aggregates hits into buckets of the given size and keeps
+// the most recent keep of them. Zero values default to hourly buckets kept
+// for a week.
+func NewUsageTracker(bucket time.Duration, keep int) *UsageTracker {
+	if bucket <= 0 {
+		bucket = time.Hour
+	}
+	if keep <= 0 {
+		keep = 7 * 24
+	}
+	return &UsageTracker{
+		bucket:  bucket,
+		keep:    keep,
+		now:     time.Now,
+		current: map[string]uint64{},
+	}
+}
+
+// Record counts one allow decision made by permID.
+func (u *UsageTracker) Record(permID string) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.roll(u.now())
+	u.current[permID]++
+}
+
+// Buckets returns the retained buckets, oldest first, including the open one.
+// Buckets without hits are omitted.
+func (u *UsageTracker) Buckets() []UsageBucket {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.roll(u.now())
+
+	out := make([]UsageBucket, 0, len(u.closed)+1)
+	for _, b := range u.closed {
+		out = append(out, UsageBucket{Start: b.Start, Hits: maps.Clone(b.Hits)})
+	}
+	if len(u.current) > 0 {
+		out = append(out, UsageBucket{Start: u.start, Hits: maps.Clone(u.current)})
+	}
+	return out
+}
+
+// roll closes the open bucket once now has moved past it and drops buckets
+// that fell out of the retention window.
+func (u *UsageTracker) roll(now time.Time) {
+	start := now.Truncate(u.bucket)
+	if !start.After(u.start) {
+		return
+	}
+	if len(u.current) > 0 {
+		u.closed = append(u.closed, UsageBucket{Start: u.start, Hits: u.current})
+		u.current = map[string]uint64{}
+	}
+	u.start = start
+
+	oldest := start.Add(-time.Duration(u.keep-1) * u.bucket)
+	drop := 0
+	for drop < len(u.closed) && u.closed[drop].Start.Before(oldest) {
+		drop++
+	}
+	u.closed = append(u.closed[:0], u.closed[drop:]...)
+}
+
+// UsageHeatmap is a permissions × time matrix of allow hits. Buckets holds
+// the unix start time of each column; every row's Hits lines up with it.
+type UsageHeatmap struct {
+	BucketSeconds int64             `json:"bucket_seconds"`
+	Buckets       []int64           `json:"buckets"`
+	Permissions   []PermissionUsage `json:"permissions"`
+}
+
+// PermissionUsage is one row of a UsageHeatmap. A permission that is bound
+// to a role but has a zero Total granted nothing in the window.
+type PermissionUsage struct {
+	PermissionID string   `json:"permission_id"`
+	Resource     string   `json:"resource,omitempty"`
+	Action       Action   `json:"action,omitempty"`
+	Hits         []uint64 `json:"hits"`
+	Total        uint64   `json:"total"`
+}
+
+var errUsageNotTracked = errors.New("rbac: permission usage is not tracked; set Manager.Usage")
+
+// PermissionUsage returns the hits recorded since the given time, one row per
+// permission that was hit or is bound to a role, hottest first. A zero since
+// covers the whole retention window.
+func (m *Manager) PermissionUsage(ctx context.Context, since time.Time) (*UsageHeatmap, error) {
+	start := time.Now()
+	ctx, span := m.startSpan(ctx, "PermissionUsage")
+	defer span.End()
+	h, err := m.permissionUsage(ctx, since)
+	m.record(ctx, start, "PermissionUsage", err)
+	return h, err
+}
+
+func (m *Manager) permissionUsage(ctx context.Context, since time.Time) (*UsageHeatmap, error) {
+	u := m.Usage
+	if u == nil {
+		return nil, errUsageNotTracked
+	}
+
+	last := u.now().Truncate(u.bucket)
+	first := last.Add(-time.Duration(u.keep-1) * u.bucket)
+	if s := since.Truncate(u.bucket); s.After(first) {
+		first = s
+	}
+	h := &UsageHeatmap{BucketSeconds: int64(u.bucket / time.Second)}
+	column := map[int64]int{}
+	for t := first; !t.After(last); t = t.Add(u.bucket) {
+		column[t.Unix()] = len(h.Buckets)
+		h.Buckets = append(h.Buckets, t.Unix())
+	}
+
+	rows := map[string]*PermissionUsage{}
+	row := func(id string) *PermissionUsage {
+		r, ok := rows[id]
+		if !ok {
+			r = &PermissionUsage{PermissionID: id, Hits: make([]uint64, len(h.Buckets))}
+			rows[id] = r
+		}
+		return r
+	}
+	for _, b := range u.Buckets() {
+		col, ok := column[b.Start.Unix()]
+		if !ok {
+			continue
+		}
+		for id, n := range b.Hits {
+			r := row(id)
+			r.Hits[col] += n
+			r.Total += n
+		}
+	}
+
+	// Bound permissions without hits are the dead weight the heatmap is
+	// meant to surface, so every role's permissions get a row.
+	roles, err := m.Roles.ListAllRoles(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, role := range roles {
+		perms, err := m.rolePermissions(ctx, time.Now(), role.ID)
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range perms {
+			r := row(p.ID)
+			r.Resource, r.Action = p.Resource, p.Action
+		}
+	}
+	for id, r := range rows {
+		if r.Resource != "" {
+			continue
+		}
+		if p, err := m.Perms.GetPermissionByID(ctx, id); err == nil && p != nil {
+			r.Resource, r.Action = p.Resource, p.Action
+		}
+	}
+
+	h.Permissions = make([]PermissionUsage, 0, len(rows))
+	for _, r := range rows {
+		h.Permissions = append(h.Permissions, *r)
+	}
+	sort.Slice(h.Permissions, func(i, j int) bool {
+		a, b := h.Permissions[i], h.Permissions[j]
+		return a.Total > b.Total || (a.Total == b.Total && a.PermissionID < b.PermissionID)
+	})
+	return h, nil
+}
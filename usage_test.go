@@ -0,0 +1,84 @@
+package rbac
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestPermissionUsage(t *testing.T) {
+	ctx := context.Background()
+	mgr, err := NewMemoryStoreManager(ctx, "", 0)
+	if err != nil {
+		t.Fatalf("NewMemoryStoreManager: %v", err)
+	}
+	if _, err := mgr.PermissionUsage(ctx, time.Time{}); err != errUsageNotTracked {
+		t.Fatalf("expected errUsageNotTracked without a tracker, got %v", err)
+	}
+
+	now := time.Date(2025, 1, 1, 10, 30, 0, 0, time.UTC)
+	mgr.Usage = NewUsageTracker(time.Hour, 3)
+	mgr.Usage.now = func() time.Time { return now }
+
+	read := &Permission{ID: "read", Resource: "survey", Action: ActionRead}
+	unused := &Permission{ID: "unused", Resource: "billing", Action: ActionRead}
+	role := &Role{ID: "r1", Name: "reader"}
+	for _, p := range []*Permission{read, unused} {
+		if err := mgr.CreatePermission(ctx, p); err != nil {
+			t.Fatalf("CreatePermission: %v", err)
+		}
+	}
+	if err := mgr.CreateRole(ctx, role); err != nil {
+		t.Fatalf("CreateRole: %v", err)
+	}
+	for _, p := range []*Permission{read, unused} {
+		if err := mgr.AssignPermissionToRole(ctx, role.ID, p.ID); err != nil {
+			t.Fatalf("AssignPermissionToRole: %v", err)
+		}
+	}
+	if err := mgr.AssignRoleToUser(ctx, "alice", role.ID); err != nil {
+		t.Fatalf("AssignRoleToUser: %v", err)
+	}
+
+	can := func() {
+		t.Helper()
+		if ok, err := mgr.Can(ctx, "alice", "survey", ActionRead); err != nil || !ok {
+			t.Fatalf("Can = %v, %v", ok, err)
+		}
+	}
+	can()
+	can()
+	now = now.Add(time.Hour)
+	can()
+	if ok, _ := mgr.Can(ctx, "alice", "survey", ActionDelete); ok {
+		t.Fatal("expected delete to be denied")
+	}
+
+	h, err := mgr.PermissionUsage(ctx, time.Time{})
+	if err != nil {
+		t.Fatalf("PermissionUsage: %v", err)
+	}
+	if h.BucketSeconds != 3600 || len(h.Buckets) != 3 {
+		t.Fatalf("expected 3 hourly columns, got %d of %ds", len(h.Buckets), h.BucketSeconds)
+	}
+	if len(h.Permissions) != 2 {
+		t.Fatalf("expected a row per bound permission, got %+v", h.Permissions)
+	}
+	hot, dead := h.Permissions[0], h.Permissions[1]
+	if hot.PermissionID != "read" || hot.Total != 3 || hot.Hits[1] != 2 || hot.Hits[2] != 1 {
+		t.Errorf("unexpected hot row %+v", hot)
+	}
+	if dead.PermissionID != "unused" || dead.Total != 0 || dead.Resource != "billing" {
+		t.Errorf("expected the unused permission as a zero row, got %+v", dead)
+	}
+
+	// Old buckets age out of the retention window.
+	now = now.Add(3 * time.Hour)
+	h, _ = mgr.PermissionUsage(ctx, time.Time{})
+	if h.Permissions[0].Total != 0 {
+		t.Errorf("expected expired hits to be dropped, got %+v", h.Permissions[0])
+	}
+	if got := mgr.Usage.Buckets(); len(got) != 0 {
+		t.Errorf("expected no retained buckets, got %+v", got)
+	}
+}
@@ -0,0 +1,187 @@
+package rbac
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// MetaPrefix starts the GetUserByMeta filter keys that address User.Meta:
+// "meta.department" matches Meta["department"], and "meta.address.city"
+// the "city" key of the map in Meta["address"].
+const MetaPrefix = "meta."
+
+// ErrInvalidUserFilter is returned by GetUserByMeta for a filter it cannot
+// run.
+var ErrInvalidUserFilter = errors.New("rbac: invalid user filter")
+
+// UserMetaIndexer is optionally implemented by a UserRepo that can index
+// User.Meta keys for GetUserByMeta. Keys are given without MetaPrefix.
+type UserMetaIndexer interface {
+	IndexUserMeta(ctx context.Context, keys ...string) error
+}
+
+// userFilterTerm is one key of a GetUserByMeta filter: the user matches when
+// the field at key equals any of values.
+type userFilterTerm struct {
+	key    string
+	path   []string // the Meta path, for keys under MetaPrefix
+	values []any
+	in     bool // values came from $in
+}
+
+// parseUserFilter validates a GetUserByMeta filter. Keys are "id",
+// "username", "email", or MetaPrefix followed by a dotted Meta path. A value
+// is matched for equality, or is a map with a single "$eq" or "$in"
+// operator; "$in" takes a list and matches any of its values.
+func parseUserFilter(filter map[string]interface{}) ([]userFilterTerm, error) {
+	if len(filter) == 0 {
+		return nil, fmt.Errorf("%w: empty", ErrInvalidUserFilter)
+	}
+	terms := make([]userFilterTerm, 0, len(filter))
+	for k, v := range filter {
+		t := userFilterTerm{key: k}
+		switch {
+		case k == "id" || k == "username" || k == "email":
+		case strings.HasPrefix(k, MetaPrefix):
+			path, err := metaPath(strings.TrimPrefix(k, MetaPrefix))
+			if err != nil {
+				return nil, fmt.Errorf("%w: field %q: %w", ErrInvalidUserFilter, k, err)
+			}
+			t.path = path
+		default:
+			return nil, fmt.Errorf("%w: unsupported field %q", ErrInvalidUserFilter, k)
+		}
+
+		op, ok := v.(map[string]interface{})
+		if !ok {
+			t.values = []any{v}
+			terms = append(terms, t)
+			continue
+		}
+		if len(op) != 1 {
+			return nil, fmt.Errorf("%w: field %q: expected one operator", ErrInvalidUserFilter, k)
+		}
+		switch {
+		case op["$eq"] != nil:
+			t.values = []any{op["$eq"]}
+		case op["$in"] != nil:
+			list, err := anySlice(op["$in"])
+			if err != nil {
+				return nil, fmt.Errorf("%w: field %q: $in: %w", ErrInvalidUserFilter, k, err)
+			}
+			t.values, t.in = list, true
+		default:
+			return nil, fmt.Errorf("%w: field %q: unsupported operator", ErrInvalidUserFilter, k)
+		}
+		terms = append(terms, t)
+	}
+	return terms, nil
+}
+
+// metaPath splits a dotted Meta path, rejecting empty segments and ones a
+// document store would read as operators.
+func metaPath(key string) ([]string, error) {
+	path := strings.Split(key, ".")
+	for _, seg := range path {
+		if seg == "" || strings.HasPrefix(seg, "$") {
+			return nil, fmt.Errorf("invalid meta path %q", key)
+		}
+	}
+	return path, nil
+}
+
+func anySlice(v any) ([]any, error) {
+	switch list := v.(type) {
+	case []any:
+		return list, nil
+	case []string:
+		out := make([]any, len(list))
+		for i, s := range list {
+			out[i] = s
+		}
+		return out, nil
+	}
+	return nil, fmt.Errorf("expected a list, got %T", v)
+}
+
+// matchUser reports whether u satisfies every term, comparing values by
+// their text so numbers decoded from JSON match the ints callers pass.
+func matchUser(u *User, terms []userFilterTerm) bool {
+	for _, t := range terms {
+		var got any
+		switch t.key {
+		case "id":
+			got = u.ID
+		case "username":
+			got = u.Username
+		case "email":
+			got = u.Email
+		default:
+			var ok bool
+			if got, ok = metaValue(u.Meta, t.path); !ok {
+				return false
+			}
+		}
+		if !t.matches(got) {
+			return false
+		}
+	}
+	return true
+}
+
+// matches reports whether got equals one of the term's values; a list
+// matches when any of its elements does, as in a document store.
+func (t userFilterTerm) matches(got any) bool {
+	if list, ok := got.([]any); ok {
+		for _, g := range list {
+			if t.matches(g) {
+				return true
+			}
+		}
+		return false
+	}
+	s := fmt.Sprint(got)
+	for _, want := range t.values {
+		if fmt.Sprint(want) == s {
+			return true
+		}
+	}
+	return false
+}
+
+func metaValue(meta map[string]interface{}, path []string) (any, bool) {
+	var cur any = meta
+	for _, seg := range path {
+		m, ok := cur.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if cur, ok = m[seg]; !ok {
+			return nil, false
+		}
+	}
+	return cur, true
+}
+
+// IndexUserMeta asks the UserRepo to index the given Meta keys, given
+// without MetaPrefix, so GetUserByMeta filters on them stay fast as users
+// grow. Repos without indexes search every user and ignore it.
+func (m *Manager) IndexUserMeta(ctx context.Context, keys ...string) error {
+	start := time.Now()
+	ctx, span := m.startSpan(ctx, "IndexUserMeta")
+	defer span.End()
+	var err error
+	for _, k := range keys {
+		if _, err = metaPath(k); err != nil {
+			break
+		}
+	}
+	if idx, ok := m.Users.(UserMetaIndexer); ok && err == nil {
+		err = idx.IndexUserMeta(ctx, keys...)
+	}
+	m.record(ctx, start, "IndexUserMeta", err)
+	return err
+}
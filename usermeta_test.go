@@ -0,0 +1,70 @@
+package rbac
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestGetUserByMetaFilters(t *testing.T) {
+	ctx := context.Background()
+	memory, err := NewMemoryStore(ctx, "")
+	if err != nil {
+		t.Fatalf("NewMemoryStore: %v", err)
+	}
+	for name, repo := range map[string]UserRepo{"memory": memory, "mock": NewMockRepo()} {
+		t.Run(name, func(t *testing.T) {
+			users := map[string]*User{
+				"alice": {Username: "alice", Meta: map[string]interface{}{
+					"team":    "core",
+					"level":   3,
+					"tags":    []interface{}{"oncall", "admin"},
+					"address": map[string]interface{}{"city": "Oslo"},
+				}},
+				"bob": {Username: "bob", Email: "bob@example.com", Meta: map[string]interface{}{"team": "infra"}},
+			}
+			for _, u := range users {
+				if err := repo.CreateUser(ctx, u); err != nil {
+					t.Fatalf("CreateUser: %v", err)
+				}
+			}
+
+			for _, c := range []struct {
+				filter map[string]interface{}
+				want   string
+			}{
+				{map[string]interface{}{"meta.address.city": "Oslo"}, "alice"},
+				{map[string]interface{}{"meta.level": float64(3)}, "alice"},
+				{map[string]interface{}{"meta.tags": "admin"}, "alice"},
+				{map[string]interface{}{"meta.team": map[string]interface{}{"$in": []interface{}{"infra", "ops"}}}, "bob"},
+				{map[string]interface{}{"email": map[string]interface{}{"$eq": "bob@example.com"}}, "bob"},
+				{map[string]interface{}{"username": "alice", "meta.team": "infra"}, ""},
+				{map[string]interface{}{"meta.address.zip": "0150"}, ""},
+			} {
+				got, err := repo.GetUserByMeta(ctx, c.filter)
+				if err != nil {
+					t.Fatalf("GetUserByMeta(%v): %v", c.filter, err)
+				}
+				switch {
+				case c.want == "" && got != nil:
+					t.Errorf("GetUserByMeta(%v) = %s, want no user", c.filter, got.Username)
+				case c.want != "" && (got == nil || got.ID != users[c.want].ID):
+					t.Errorf("GetUserByMeta(%v) = %+v, want %s", c.filter, got, c.want)
+				}
+			}
+
+			for _, bad := range []map[string]interface{}{
+				{},
+				{"team": "core"},
+				{"meta.": "x"},
+				{"meta.$where": "x"},
+				{"meta.team": map[string]interface{}{"$ne": "core"}},
+				{"meta.team": map[string]interface{}{"$in": "core"}},
+			} {
+				if _, err := repo.GetUserByMeta(ctx, bad); !errors.Is(err, ErrInvalidUserFilter) {
+					t.Errorf("GetUserByMeta(%v): expected ErrInvalidUserFilter, got %v", bad, err)
+				}
+			}
+		})
+	}
+}
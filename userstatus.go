@@ -0,0 +1,83 @@
+package rbac
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// UserStatus is the state of a user's account. Only active users are
+// authorized; the empty status of users created before it existed counts as
+// active.
+type UserStatus string
+
+const (
+	UserActive    UserStatus = "active"
+	UserSuspended UserStatus = "suspended"
+	UserLocked    UserStatus = "locked"
+)
+
+// Active reports whether s lets the user be authorized.
+func (s UserStatus) Active() bool { return s == "" || s == UserActive }
+
+// UserStatusRepo is optionally implemented by a UserRepo that can change a
+// stored user's Status.
+type UserStatusRepo interface {
+	SetUserStatus(ctx context.Context, id string, status UserStatus) error
+}
+
+// ErrUserNotFound is returned when changing the status of an unknown user.
+var ErrUserNotFound = errors.New("rbac: user not found")
+
+var errUserStatusUnsupported = errors.New("rbac: repo does not support user status")
+
+// SuspendUser stops Can, CanForSession and HasPermission from granting the
+// user anything, while keeping their roles and groups for ReactivateUser.
+func (m *Manager) SuspendUser(ctx context.Context, id string) error {
+	return m.setUserStatus(ctx, "SuspendUser", id, UserSuspended)
+}
+
+// ReactivateUser makes a suspended or locked user active again.
+func (m *Manager) ReactivateUser(ctx context.Context, id string) error {
+	return m.setUserStatus(ctx, "ReactivateUser", id, UserActive)
+}
+
+// SetUserStatus sets the user's status, e.g. to UserLocked after too many
+// failed logins.
+func (m *Manager) SetUserStatus(ctx context.Context, id string, status UserStatus) error {
+	return m.setUserStatus(ctx, "SetUserStatus", id, status)
+}
+
+func (m *Manager) setUserStatus(ctx context.Context, method, id string, status UserStatus) error {
+	start := time.Now()
+	ctx, span := m.startSpan(ctx, method)
+	defer span.End()
+	err := errUserStatusUnsupported
+	if repo, ok := m.Users.(UserStatusRepo); ok {
+		var u *User
+		if u, err = m.Users.GetUserByID(ctx, id); err == nil && u == nil {
+			err = fmt.Errorf("%w: %q", ErrUserNotFound, id)
+		}
+		if err == nil {
+			err = repo.SetUserStatus(ctx, id, status)
+		}
+	}
+	m.record(ctx, start, method, err)
+	m.changedUser(ctx, method, id, err)
+	return err
+}
+
+// userActive reports whether userID may be authorized: they are active and,
+// under RequireVerifiedEmail, verified their email. Users the repo does not
+// know are left to Strict.
+func (m *Manager) userActive(ctx context.Context, userID string) (bool, error) {
+	u, err := m.Users.GetUserByID(ctx, userID)
+	if err != nil || u == nil {
+		return true, err
+	}
+	if m.RequireVerifiedEmail && !u.EmailVerified {
+		return false, nil
+	}
+	return u.Status.Active(), nil
+}
@@ -0,0 +1,104 @@
+package rbac
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestUserStatus(t *testing.T) {
+	ctx := context.Background()
+	memory, err := NewMemoryStoreManager(ctx, "", 0)
+	if err != nil {
+		t.Fatalf("NewMemoryStoreManager: %v", err)
+	}
+	for name, mgr := range map[string]*Manager{"memory": memory, "mock": NewMockRepoManager(NewMockRepo())} {
+		t.Run(name, func(t *testing.T) {
+			user := &User{Username: "alice"}
+			if err := mgr.CreateUser(ctx, user); err != nil {
+				t.Fatalf("CreateUser: %v", err)
+			}
+			role := &Role{Name: "reader"}
+			if err := mgr.CreateRole(ctx, role); err != nil {
+				t.Fatalf("CreateRole: %v", err)
+			}
+			perm := &Permission{Resource: "docs/*", Action: ActionRead}
+			if err := mgr.CreatePermission(ctx, perm); err != nil {
+				t.Fatalf("CreatePermission: %v", err)
+			}
+			if err := mgr.AssignPermissionToRole(ctx, role.ID, perm.ID); err != nil {
+				t.Fatalf("AssignPermissionToRole: %v", err)
+			}
+			if err := mgr.AssignRoleToUser(ctx, user.ID, role.ID); err != nil {
+				t.Fatalf("AssignRoleToUser: %v", err)
+			}
+			check := func(want bool) {
+				t.Helper()
+				if ok, err := mgr.Can(ctx, user.ID, "docs/1", ActionRead); err != nil || ok != want {
+					t.Errorf("Can = %v, %v; want %v", ok, err, want)
+				}
+				if ok, err := mgr.HasPermission(ctx, user.ID, perm.ID); err != nil || ok != want {
+					t.Errorf("HasPermission = %v, %v; want %v", ok, err, want)
+				}
+			}
+
+			check(true)
+			if err := mgr.SuspendUser(ctx, user.ID); err != nil {
+				t.Fatalf("SuspendUser: %v", err)
+			}
+			check(false)
+			if roles, _ := mgr.ListRolesForUser(ctx, user.ID); len(roles) == 0 {
+				t.Error("expected suspension to keep the user's roles")
+			}
+			if err := mgr.ReactivateUser(ctx, user.ID); err != nil {
+				t.Fatalf("ReactivateUser: %v", err)
+			}
+			check(true)
+			if err := mgr.SetUserStatus(ctx, user.ID, UserLocked); err != nil {
+				t.Fatalf("SetUserStatus: %v", err)
+			}
+			check(false)
+
+			if err := mgr.SuspendUser(ctx, "missing"); !errors.Is(err, ErrUserNotFound) {
+				t.Errorf("expected ErrUserNotFound, got %v", err)
+			}
+		})
+	}
+}
+
+func TestSuspendedUserSession(t *testing.T) {
+	ctx := context.Background()
+	mgr, err := NewMemoryStoreManager(ctx, "", 0)
+	if err != nil {
+		t.Fatalf("NewMemoryStoreManager: %v", err)
+	}
+	user := &User{Username: "bob"}
+	if err := mgr.CreateUser(ctx, user); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	perm := &Permission{Resource: "docs/*", Action: ActionRead}
+	role := &Role{Name: "reader"}
+	if err := mgr.CreateRole(ctx, role); err != nil {
+		t.Fatalf("CreateRole: %v", err)
+	}
+	if err := mgr.CreatePermission(ctx, perm); err != nil {
+		t.Fatalf("CreatePermission: %v", err)
+	}
+	if err := mgr.AssignPermissionToRole(ctx, role.ID, perm.ID); err != nil {
+		t.Fatalf("AssignPermissionToRole: %v", err)
+	}
+	if err := mgr.AssignRoleToUser(ctx, user.ID, role.ID); err != nil {
+		t.Fatalf("AssignRoleToUser: %v", err)
+	}
+	sess, err := mgr.CreateSession(ctx, user.ID, time.Hour)
+	if err != nil {
+		t.Fatalf("CreateSession: %v", err)
+	}
+	if err := mgr.SuspendUser(ctx, user.ID); err != nil {
+		t.Fatalf("SuspendUser: %v", err)
+	}
+	if ok, err := mgr.CanForSession(ctx, sess.ID, "docs/1", ActionRead); err != nil || ok {
+		t.Errorf("CanForSession for a suspended user = %v, %v; want false", ok, err)
+	}
+}
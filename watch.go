@@ -0,0 +1,43 @@
+package rbac
+
+import (
+	"context"
+	"time"
+)
+
+// Kinds for the join records reported in ChangeEvents.
+const (
+	KindRolePermission = "role_permission"
+	KindUserRole       = "user_role"
+	KindGroupRole      = "group_role"
+)
+
+// ChangeOp describes what happened to the record in a ChangeEvent.
+type ChangeOp string
+
+const (
+	ChangeCreate ChangeOp = "create"
+	ChangeUpdate ChangeOp = "update"
+	ChangeDelete ChangeOp = "delete"
+)
+
+// ChangeEvent describes a single mutation observed in a store. Entity events
+// (permissions, roles, users) carry the entity ID; join record events carry
+// the IDs of both ends instead. At is when the store committed the change,
+// for stores that report it.
+type ChangeEvent struct {
+	Kind         string    `json:"kind"`
+	Op           ChangeOp  `json:"op"`
+	ID           string    `json:"id,omitempty"`
+	RoleID       string    `json:"role_id,omitempty"`
+	PermissionID string    `json:"permission_id,omitempty"`
+	UserID       string    `json:"user_id,omitempty"`
+	GroupName    string    `json:"group_name,omitempty"`
+	At           time.Time `json:"at,omitzero"`
+}
+
+// Watcher is implemented by stores that can stream their changes. The
+// returned channel is closed when ctx is cancelled or the stream fails.
+type Watcher interface {
+	Watch(ctx context.Context) (<-chan ChangeEvent, error)
+}
@@ -0,0 +1,43 @@
+package rbac
+
+import (
+	"context"
+	"time"
+)
+
+// WhoCan returns a page of the users allowed to perform action on resource,
+// e.g. everyone who can delete billing records. Each user is decided as Can
+// decides, so roles held directly, through groups, in a scope, by
+// delegation or by inheritance all count, and denies and suspensions are
+// honoured.
+//
+// The page walks page.Limit users of the user store and returns those
+// allowed, so it may come up short or empty while NextCursor is still set;
+// keep paging until it is empty. Users that hold roles without a record in
+// the user store are not found.
+func (m *Manager) WhoCan(ctx context.Context, resource string, action Action, page PageRequest) (PageResult[*User], error) {
+	start := time.Now()
+	ctx, span := m.startSpan(ctx, "WhoCan")
+	defer span.End()
+	res, err := m.whoCan(ctx, resource, action, page)
+	m.record(ctx, start, "WhoCan", err)
+	return res, err
+}
+
+func (m *Manager) whoCan(ctx context.Context, resource string, action Action, page PageRequest) (PageResult[*User], error) {
+	users, err := m.listUsersPage(ctx, page)
+	if err != nil {
+		return PageResult[*User]{}, err
+	}
+	res := PageResult[*User]{Items: []*User{}, NextCursor: users.NextCursor}
+	for _, u := range users.Items {
+		d, err := m.decide(ctx, "WhoCan", u.ID, resource, action, nil)
+		if err != nil {
+			return PageResult[*User]{}, err
+		}
+		if d.Allowed {
+			res.Items = append(res.Items, u)
+		}
+	}
+	return res, nil
+}
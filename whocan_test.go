@@ -0,0 +1,78 @@
+package rbac
+
+import (
+	"context"
+	"testing"
+)
+
+func TestWhoCan(t *testing.T) {
+	ctx := context.Background()
+	memory, err := NewMemoryStoreManager(ctx, "", 0)
+	if err != nil {
+		t.Fatalf("NewMemoryStoreManager: %v", err)
+	}
+	for name, mgr := range map[string]*Manager{"memory": memory, "mock": NewMockRepoManager(NewMockRepo())} {
+		t.Run(name, func(t *testing.T) {
+			admin := &Role{Name: "billing-admin"}
+			if err := mgr.CreateRole(ctx, admin); err != nil {
+				t.Fatalf("CreateRole: %v", err)
+			}
+			del := &Permission{Resource: "billing/*", Action: ActionDelete}
+			if err := mgr.CreatePermission(ctx, del); err != nil {
+				t.Fatalf("CreatePermission: %v", err)
+			}
+			if err := mgr.AssignPermissionToRole(ctx, admin.ID, del.ID); err != nil {
+				t.Fatalf("AssignPermissionToRole: %v", err)
+			}
+			if err := mgr.AssignRoleToGroup(ctx, "finance", admin.ID); err != nil {
+				t.Fatalf("AssignRoleToGroup: %v", err)
+			}
+			users := map[string]*User{}
+			for _, name := range []string{"alice", "bob", "carol", "dave"} {
+				u := &User{ID: name, Username: name, Email: name + "@example.com"}
+				if err := mgr.CreateUser(ctx, u); err != nil {
+					t.Fatalf("CreateUser: %v", err)
+				}
+				users[name] = u
+			}
+			if err := mgr.AssignRoleToUser(ctx, "alice", admin.ID); err != nil {
+				t.Fatalf("AssignRoleToUser: %v", err)
+			}
+			for _, name := range []string{"carol", "dave"} {
+				if err := mgr.AddUserToGroup(ctx, &UserGroup{UserID: name, GroupName: "finance"}); err != nil {
+					t.Fatalf("AddUserToGroup: %v", err)
+				}
+			}
+			if err := mgr.SuspendUser(ctx, "dave"); err != nil {
+				t.Fatalf("SuspendUser: %v", err)
+			}
+
+			var got []string
+			page := PageRequest{Limit: 1}
+			for pages := 0; ; pages++ {
+				if pages > len(users) {
+					t.Fatalf("paging did not end")
+				}
+				res, err := mgr.WhoCan(ctx, "billing/invoices", ActionDelete, page)
+				if err != nil {
+					t.Fatalf("WhoCan: %v", err)
+				}
+				for _, u := range res.Items {
+					got = append(got, u.ID)
+				}
+				if res.NextCursor == "" {
+					break
+				}
+				page.Cursor = res.NextCursor
+			}
+			if len(got) != 2 || got[0] != "alice" || got[1] != "carol" {
+				t.Errorf("WhoCan = %v; want [alice carol]", got)
+			}
+
+			res, err := mgr.WhoCan(ctx, "billing/invoices", ActionRead, PageRequest{})
+			if err != nil || len(res.Items) != 0 {
+				t.Errorf("WhoCan(read) = %v, %v; want nobody", res.Items, err)
+			}
+		})
+	}
+}